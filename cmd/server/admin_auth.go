@@ -7,13 +7,15 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/ngaddam369/svid-exchange/internal/audit"
 	"github.com/ngaddam369/svid-exchange/internal/server"
 )
 
 // newAdminAuthInterceptor returns a gRPC unary interceptor that enforces an
 // allowlist of SPIFFE IDs on the admin API. When allowedSubjects is empty the
 // interceptor is a no-op and any authenticated peer may call admin endpoints.
-func newAdminAuthInterceptor(allowedSubjects []string, ext server.IDExtractor) grpc.UnaryServerInterceptor {
+// A refused caller's ID is rewritten by redact in the error.
+func newAdminAuthInterceptor(allowedSubjects []string, ext server.IDExtractor, redact *audit.Redactor) grpc.UnaryServerInterceptor {
 	allowed := make(map[string]struct{}, len(allowedSubjects))
 	for _, s := range allowedSubjects {
 		allowed[s] = struct{}{}
@@ -27,7 +29,7 @@ func newAdminAuthInterceptor(allowedSubjects []string, ext server.IDExtractor) g
 			return nil, status.Error(codes.PermissionDenied, "no SPIFFE identity")
 		}
 		if _, ok := allowed[id]; !ok {
-			return nil, status.Errorf(codes.PermissionDenied, "caller %q is not an authorized admin subject", id)
+			return nil, status.Errorf(codes.PermissionDenied, "caller %q is not an authorized admin subject", redact.ID(id))
 		}
 		return handler(ctx, req)
 	}
@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
 )
 
 const (
@@ -45,7 +48,7 @@ var nopHandler grpc.UnaryHandler = func(_ context.Context, _ any) (any, error) {
 func TestAdminAuthInterceptor(t *testing.T) {
 	t.Run("empty allowlist allows any caller without extracting ID", func(t *testing.T) {
 		ext := &mockIDExtractor{id: adminSubjectA}
-		interceptor := newAdminAuthInterceptor(nil, ext)
+		interceptor := newAdminAuthInterceptor(nil, ext, nil)
 		resp, err := interceptor(context.Background(), nil, nil, nopHandler)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
@@ -60,7 +63,7 @@ func TestAdminAuthInterceptor(t *testing.T) {
 
 	t.Run("listed subject is allowed", func(t *testing.T) {
 		ext := &mockIDExtractor{id: adminSubjectA}
-		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext)
+		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext, nil)
 		_, err := interceptor(context.Background(), nil, nil, nopHandler)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
@@ -72,16 +75,28 @@ func TestAdminAuthInterceptor(t *testing.T) {
 
 	t.Run("unlisted subject is denied", func(t *testing.T) {
 		ext := &mockIDExtractor{id: adminSubjectB}
-		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext)
+		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext, nil)
 		_, err := interceptor(context.Background(), nil, nil, nopHandler)
 		if code := status.Code(err); code != codes.PermissionDenied {
 			t.Errorf("expected PermissionDenied, got %v: %v", code, err)
 		}
 	})
 
+	t.Run("denied subject is redacted in the error", func(t *testing.T) {
+		redact, err := audit.NewRedactor(audit.RedactRemove, audit.RedactNone, nil)
+		if err != nil {
+			t.Fatalf("NewRedactor: %v", err)
+		}
+		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, &mockIDExtractor{id: adminSubjectB}, redact)
+		_, err = interceptor(context.Background(), nil, nil, nopHandler)
+		if msg := status.Convert(err).Message(); strings.Contains(msg, adminSubjectB) || !strings.Contains(msg, "[redacted]") {
+			t.Errorf("message = %q, want the caller redacted", msg)
+		}
+	})
+
 	t.Run("extraction failure is denied when allowlist is set", func(t *testing.T) {
 		ext := &mockIDExtractor{err: errors.New("no cert")}
-		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext)
+		interceptor := newAdminAuthInterceptor([]string{adminSubjectA}, ext, nil)
 		_, err := interceptor(context.Background(), nil, nil, nopHandler)
 		if code := status.Code(err); code != codes.PermissionDenied {
 			t.Errorf("expected PermissionDenied, got %v: %v", code, err)
@@ -90,7 +105,7 @@ func TestAdminAuthInterceptor(t *testing.T) {
 
 	t.Run("second of multiple allowed subjects is permitted", func(t *testing.T) {
 		ext := &mockIDExtractor{id: adminSubjectB}
-		interceptor := newAdminAuthInterceptor([]string{adminSubjectA, adminSubjectB}, ext)
+		interceptor := newAdminAuthInterceptor([]string{adminSubjectA, adminSubjectB}, ext, nil)
 		_, err := interceptor(context.Background(), nil, nil, nopHandler)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// approvalWebhookApprover is the approver recorded for decisions the
+// approval webhook returns.
+const approvalWebhookApprover = "webhook"
+
+// approvalDecisions counts decided approvals by who decided and the outcome.
+var approvalDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_approval_decisions_total",
+	Help: "Exchanges parked by requires_approval policies that were decided, by source (admin or webhook) and decision (approved or denied).",
+}, []string{"source", "decision"})
+
+// approvalAdmin serves the approval admin RPCs from approvals, recording the
+// admin caller actor identifies as the approver.
+type approvalAdmin struct {
+	approvals *server.Approvals
+	actor     server.IDExtractor
+}
+
+func (a approvalAdmin) Pending() []server.PendingApproval { return a.approvals.Pending() }
+
+func (a approvalAdmin) Decide(ctx context.Context, id string, approve bool, reason string) error {
+	approver, _ := a.actor.ExtractID(ctx)
+	err := a.approvals.Decide(id, server.ApprovalDecision{Approved: approve, Approver: approver, Reason: reason})
+	if err == nil {
+		approvalDecisions.WithLabelValues("admin", decisionLabel(approve)).Inc()
+	}
+	return err
+}
+
+func decisionLabel(approved bool) string {
+	if approved {
+		return "approved"
+	}
+	return "denied"
+}
+
+// approvalWebhook asks an external service to decide each parked exchange.
+// It POSTs the exchange as JSON, signed as the denial webhook's deliveries
+// are. A 200 response carrying {"approved": bool, "reason": string} decides
+// the exchange at once; any other 2xx leaves it pending for a decision
+// through the admin API.
+type approvalWebhook struct {
+	url       string
+	secret    []byte
+	client    *http.Client
+	approvals *server.Approvals
+	log       zerolog.Logger
+}
+
+// approvalRequest is the body of an approval webhook call.
+type approvalRequest struct {
+	ID          string   `json:"id"`
+	RequestID   string   `json:"request_id"`
+	Subject     string   `json:"subject"`
+	Target      string   `json:"target"`
+	Scopes      []string `json:"scopes"`
+	TTL         int32    `json:"ttl"`
+	PolicyRules []string `json:"policy_rules"`
+	RequestedAt int64    `json:"requested_at"`
+	ExpiresAt   int64    `json:"expires_at"`
+}
+
+// approvalResponse is the body of a 200 response to an approval webhook
+// call.
+type approvalResponse struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+// notify calls the webhook for p and applies its decision, if any. It is
+// the notifier passed to server.NewApprovals.
+func (w *approvalWebhook) notify(p server.PendingApproval) {
+	ctx, cancel := context.WithDeadline(context.Background(), p.Expires)
+	defer cancel()
+	d, ok, err := w.call(ctx, p)
+	if err != nil {
+		w.log.Error().Err(err).Str("request_id", p.RequestID).Str("approval_id", p.ID).Msg("approval webhook call failed")
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := w.approvals.Decide(p.ID, d); err != nil {
+		// Decided through the admin API, timed out, or abandoned meanwhile.
+		if !errors.Is(err, server.ErrApprovalNotFound) {
+			w.log.Error().Err(err).Str("approval_id", p.ID).Msg("apply approval webhook decision")
+		}
+		return
+	}
+	approvalDecisions.WithLabelValues(approvalWebhookApprover, decisionLabel(d.Approved)).Inc()
+}
+
+// call POSTs p to the webhook and returns its decision, with ok false when
+// the webhook left the exchange pending.
+func (w *approvalWebhook) call(ctx context.Context, p server.PendingApproval) (d server.ApprovalDecision, ok bool, err error) {
+	body, err := json.Marshal(approvalRequest{
+		ID:          p.ID,
+		RequestID:   p.RequestID,
+		Subject:     p.Subject,
+		Target:      p.Target,
+		Scopes:      p.Scopes,
+		TTL:         p.TTL,
+		PolicyRules: p.PolicyRules,
+		RequestedAt: p.Requested.Unix(),
+		ExpiresAt:   p.Expires.Unix(),
+	})
+	if err != nil {
+		return d, false, fmt.Errorf("marshal request: %w", err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return d, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(audit.WebhookTimestampHeader, ts)
+	req.Header.Set(audit.WebhookSignatureHeader, audit.SignWebhook(w.secret, ts, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return d, false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var r approvalResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&r); err != nil {
+			return d, false, fmt.Errorf("decode response: %w", err)
+		}
+		return server.ApprovalDecision{Approved: r.Approved, Approver: approvalWebhookApprover, Reason: r.Reason}, true, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return d, false, nil
+	default:
+		return d, false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestApprovalWebhook(t *testing.T) {
+	secret := []byte("s3cret")
+	pending := server.PendingApproval{
+		ID:        "a1",
+		RequestID: "req-1",
+		Subject:   "spiffe://example.org/order",
+		Target:    "spiffe://example.org/payment",
+		Scopes:    []string{"payments:refund"},
+		TTL:       60,
+		Requested: time.Now(),
+		Expires:   time.Now().Add(time.Minute),
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		wantOK bool
+		want   server.ApprovalDecision
+	}{
+		{name: "approved", status: http.StatusOK, body: `{"approved": true}`, wantOK: true, want: server.ApprovalDecision{Approved: true, Approver: "webhook"}},
+		{name: "denied", status: http.StatusOK, body: `{"approved": false, "reason": "no ticket"}`, wantOK: true, want: server.ApprovalDecision{Approver: "webhook", Reason: "no ticket"}},
+		{name: "left pending", status: http.StatusAccepted},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				ts := r.Header.Get(audit.WebhookTimestampHeader)
+				if r.Header.Get(audit.WebhookSignatureHeader) != audit.SignWebhook(secret, ts, body) {
+					t.Error("request signature does not verify")
+				}
+				var req approvalRequest
+				if err := json.Unmarshal(body, &req); err != nil || req.ID != "a1" || req.Subject != pending.Subject || req.TTL != 60 {
+					t.Errorf("request = %+v, %v", req, err)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			hook := &approvalWebhook{url: srv.URL, secret: secret, client: srv.Client(), log: zerolog.Nop()}
+			d, ok, err := hook.call(t.Context(), pending)
+			if err != nil {
+				t.Fatalf("call: %v", err)
+			}
+			if ok != tc.wantOK || d != tc.want {
+				t.Errorf("decision = %+v, %t; want %+v, %t", d, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+
+	t.Run("error status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+		hook := &approvalWebhook{url: srv.URL, secret: secret, client: srv.Client(), log: zerolog.Nop()}
+		if _, _, err := hook.call(t.Context(), pending); err == nil {
+			t.Error("call succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/admin"
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+)
+
+var (
+	// auditFailClosed reports the audit failure mode in effect.
+	auditFailClosed = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "svid_exchange_audit_fail_closed",
+		Help: "1 while grants whose audit event cannot be spooled fail with UNAVAILABLE, 0 while they are returned anyway (fail open).",
+	})
+	// auditSpoolFailures counts exchange events the audit spool could not
+	// take, whatever the mode; while failing open each is a grant that was
+	// returned without a durable audit record.
+	auditSpoolFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "svid_exchange_audit_spool_failures_total",
+		Help: "Exchange audit events that could not be written to the audit spool and were delivered to the sinks directly.",
+	})
+)
+
+// auditRecorder is the audit logger the exchange server records events
+// with. It counts the events that could not be spooled.
+type auditRecorder struct {
+	*audit.Logger
+}
+
+func (r auditRecorder) RecordExchange(e audit.ExchangeEvent) error {
+	err := r.Logger.RecordExchange(e)
+	if err != nil {
+		auditSpoolFailures.Inc()
+	}
+	return err
+}
+
+// auditRequirer is the exchange server's audit failure mode.
+type auditRequirer interface {
+	SetAuditRequired(required bool)
+	AuditRequired() bool
+}
+
+// auditMode switches the exchange server between failing closed and
+// failing open when an audit event cannot be spooled. It starts at the
+// configured mode, audit_spool_required, and the SetAuditFailureMode admin
+// RPC can change it, for example to keep issuing tokens during an audit
+// sink outage.
+type auditMode struct {
+	configured bool // fail closed
+	svc        auditRequirer
+	log        zerolog.Logger
+
+	mu sync.Mutex
+}
+
+// newAuditMode puts svc in the configured mode.
+func newAuditMode(svc auditRequirer, configured bool, log zerolog.Logger) *auditMode {
+	svc.SetAuditRequired(configured)
+	auditFailClosed.Set(gaugeValue(configured))
+	return &auditMode{configured: configured, svc: svc, log: log}
+}
+
+// SetAuditFailureMode implements admin.AuditModeSwitcher. Switching to fail
+// open is logged as a warning, since grants may then go unaudited.
+func (m *auditMode) SetAuditFailureMode(mode string) string {
+	closed := m.configured
+	switch mode {
+	case admin.AuditFailOpen:
+		closed = false
+	case admin.AuditFailClosed:
+		closed = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.svc.AuditRequired()
+	m.svc.SetAuditRequired(closed)
+	auditFailClosed.Set(gaugeValue(closed))
+
+	ev := m.log.Info()
+	if !closed {
+		ev = m.log.Warn()
+	}
+	ev.Str("from", auditModeName(prev)).Str("mode", auditModeName(closed)).Msg("audit failure mode changed")
+	return auditModeName(prev)
+}
+
+func auditModeName(closed bool) string {
+	if closed {
+		return admin.AuditFailClosed
+	}
+	return admin.AuditFailOpen
+}
+
+func gaugeValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/admin"
+)
+
+// fakeRequirer holds an audit failure mode.
+type fakeRequirer struct{ required bool }
+
+func (f *fakeRequirer) SetAuditRequired(required bool) { f.required = required }
+func (f *fakeRequirer) AuditRequired() bool            { return f.required }
+
+func TestAuditMode(t *testing.T) {
+	svc := &fakeRequirer{}
+	m := newAuditMode(svc, true, zerolog.Nop())
+	if !svc.required || testutil.ToFloat64(auditFailClosed) != 1 {
+		t.Fatal("configured fail-closed mode not applied")
+	}
+
+	steps := []struct {
+		mode, wantPrev string
+		wantClosed     bool
+	}{
+		{mode: admin.AuditFailOpen, wantPrev: admin.AuditFailClosed},
+		{mode: admin.AuditFailOpen, wantPrev: admin.AuditFailOpen},
+		{mode: "", wantPrev: admin.AuditFailOpen, wantClosed: true},
+	}
+	for _, s := range steps {
+		if prev := m.SetAuditFailureMode(s.mode); prev != s.wantPrev {
+			t.Errorf("SetAuditFailureMode(%q) previous = %q, want %q", s.mode, prev, s.wantPrev)
+		}
+		if svc.required != s.wantClosed {
+			t.Errorf("after %q: required = %t, want %t", s.mode, svc.required, s.wantClosed)
+		}
+		if got := testutil.ToFloat64(auditFailClosed); got != gaugeValue(s.wantClosed) {
+			t.Errorf("after %q: gauge = %v", s.mode, got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+)
+
+// Authentication methods accepted in auth_methods. Each names an
+// IDExtractor and is recorded as auth_method in audit events.
+const (
+	authMethodX509SVID = "x509-svid"
+	authMethodSAToken  = "k8s-sa-token"
+	authMethodJWTSVID  = "jwt-svid"
+)
+
+// authMethods lists every known authentication method.
+var authMethods = []string{authMethodX509SVID, authMethodSAToken, authMethodJWTSVID}
+
+// acceptsTokens reports whether methods include one that identifies callers
+// without a client certificate.
+func acceptsTokens(methods []string) bool {
+	return slices.ContainsFunc(methods, func(m string) bool { return m != authMethodX509SVID })
+}
+
+// optionalClientCert returns a copy of an mTLS server config that also
+// accepts connections without a client certificate. A certificate that is
+// presented is still verified against the trust bundle and authorized as
+// before; callers without one must authenticate with a token instead.
+func optionalClientCert(tc *tls.Config) *tls.Config {
+	out := tc.Clone()
+	out.ClientAuth = tls.RequestClientCert
+	verify := tc.VerifyPeerCertificate
+	// crypto/tls calls VerifyPeerCertificate even when the client sent no
+	// certificate, and go-spiffe's verifier rejects an empty chain.
+	out.VerifyPeerCertificate = func(raw [][]byte, chains [][]*x509.Certificate) error {
+		if len(raw) == 0 {
+			return nil
+		}
+		return verify(raw, chains)
+	}
+	return out
+}
+
+// newClientCertRequiredInterceptor returns a gRPC unary interceptor that
+// rejects calls from connections without a client certificate with
+// Unauthenticated, except to tokenMethods. It keeps optionalClientCert from
+// opening every other RPC on the listener, such as ext_authz Check, to
+// callers that authenticate with nothing at all.
+func newClientCertRequiredInterceptor(tokenMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(tokenMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if _, err := spiffe.ExtractID(ctx); errors.Is(err, spiffe.ErrNoCerts) {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		return handler(ctx, req)
+	}
+}
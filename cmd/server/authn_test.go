@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ctxWithPeerCerts returns a context whose peer completed a TLS handshake
+// presenting certs.
+func ctxWithPeerCerts(certs ...*x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: certs}},
+	})
+}
+
+func TestAcceptsTokens(t *testing.T) {
+	tests := []struct {
+		methods []string
+		want    bool
+	}{
+		{methods: []string{authMethodX509SVID}, want: false},
+		{methods: []string{authMethodX509SVID, authMethodSAToken}, want: true},
+		{methods: []string{authMethodSAToken}, want: true},
+	}
+	for _, tc := range tests {
+		if got := acceptsTokens(tc.methods); got != tc.want {
+			t.Errorf("acceptsTokens(%v) = %v, want %v", tc.methods, got, tc.want)
+		}
+	}
+}
+
+func TestOptionalClientCert(t *testing.T) {
+	errVerify := errors.New("untrusted")
+	base := &tls.Config{
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func([][]byte, [][]*x509.Certificate) error { return errVerify },
+	}
+	got := optionalClientCert(base)
+	if got.ClientAuth != tls.RequestClientCert {
+		t.Errorf("ClientAuth = %v, want RequestClientCert", got.ClientAuth)
+	}
+	if base.ClientAuth != tls.RequireAnyClientCert {
+		t.Error("base config was modified")
+	}
+	if err := got.VerifyPeerCertificate(nil, nil); err != nil {
+		t.Errorf("no certificate: %v, want nil", err)
+	}
+	if err := got.VerifyPeerCertificate([][]byte{{0x30}}, nil); !errors.Is(err, errVerify) {
+		t.Errorf("presented certificate: %v, want the base verifier's error", err)
+	}
+}
+
+func TestClientCertRequiredInterceptor(t *testing.T) {
+	svidCert := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "cluster.local", Path: "/ns/default/sa/order"}}}
+	const checkMethod = "/envoy.service.auth.v3.Authorization/Check"
+
+	interceptor := newClientCertRequiredInterceptor(exchangeInfo.FullMethod)
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "token method without certificate", ctx: ctxWithPeerCerts(), method: exchangeInfo.FullMethod, wantCode: codes.OK},
+		{name: "other method without certificate", ctx: ctxWithPeerCerts(), method: checkMethod, wantCode: codes.Unauthenticated},
+		{name: "other method with certificate", ctx: ctxWithPeerCerts(svidCert), method: checkMethod, wantCode: codes.OK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := interceptor(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, okHandler)
+			if status.Code(err) != tc.wantCode {
+				t.Errorf("code = %v, want %v", status.Code(err), tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// externalAuthorizerRequests counts external authorizer calls by outcome.
+var externalAuthorizerRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_external_authorizer_requests_total",
+	Help: "Policy decisions requested from the external authorizer, by outcome (result=allowed|denied|error).",
+}, []string{"result"})
+
+// externalPolicy is a PolicyEvaluator that decides with an external
+// authorizer. When the authorizer cannot decide, it fails the exchange or,
+// when fallback is set, decides with fallback instead. Failing open therefore
+// never grants more than the local policy would.
+type externalPolicy struct {
+	remote   server.PolicyEvaluator
+	fallback server.PolicyEvaluator
+	log      zerolog.Logger
+}
+
+// newExternalPolicy returns an externalPolicy deciding with remote, falling
+// back to fallback when it is non-nil.
+func newExternalPolicy(remote, fallback server.PolicyEvaluator, log zerolog.Logger) *externalPolicy {
+	return &externalPolicy{remote: remote, fallback: fallback, log: log}
+}
+
+// Evaluate returns the authorizer's decision, or the fallback's when the
+// authorizer fails and a fallback is set.
+func (ep *externalPolicy) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	res, err := ep.remote.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+	if err == nil {
+		result := "denied"
+		if res.Allowed {
+			result = "allowed"
+		}
+		externalAuthorizerRequests.WithLabelValues(result).Inc()
+		return res, nil
+	}
+	externalAuthorizerRequests.WithLabelValues("error").Inc()
+	if ep.fallback == nil {
+		return policy.EvalResult{}, err
+	}
+	ep.log.Warn().Err(err).Str("subject", subject).Str("target", target).Msg("external authorizer failed; deciding with local policy")
+	return ep.fallback.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestExternalPolicy(t *testing.T) {
+	const (
+		sub = "spiffe://cluster.local/ns/default/sa/order"
+		tgt = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	local := newAtomicPolicy(loadTestPolicy(t, sub, tgt), zerolog.Nop())
+	down := failingEvaluator{err: errors.New("authorizer unreachable")}
+
+	tests := []struct {
+		name        string
+		remote      server.PolicyEvaluator
+		fallback    server.PolicyEvaluator
+		target      string
+		wantResult  string
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{name: "authorizer allows", remote: local, target: tgt, wantResult: "allowed", wantAllowed: true},
+		{name: "authorizer denies", remote: local, fallback: local, target: "spiffe://cluster.local/ns/default/sa/admin", wantResult: "denied"},
+		{name: "fail closed", remote: down, target: tgt, wantResult: "error", wantErr: true},
+		{name: "fail open grants what local policy grants", remote: down, fallback: local, target: tgt, wantResult: "error", wantAllowed: true},
+		{name: "fail open denies what local policy denies", remote: down, fallback: local, target: "spiffe://cluster.local/ns/default/sa/admin", wantResult: "error"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ep := newExternalPolicy(tc.remote, tc.fallback, zerolog.New(&buf))
+			before := testutil.ToFloat64(externalAuthorizerRequests.WithLabelValues(tc.wantResult))
+
+			res, err := ep.Evaluate(context.Background(), sub, tc.target, []string{"r:w"}, 30)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tc.wantErr)
+			}
+			if res.Allowed != tc.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", res.Allowed, tc.wantAllowed)
+			}
+			if got := testutil.ToFloat64(externalAuthorizerRequests.WithLabelValues(tc.wantResult)) - before; got != 1 {
+				t.Errorf("result=%s counter rose by %v, want 1", tc.wantResult, got)
+			}
+			if fellBack := strings.Contains(buf.String(), "deciding with local policy"); fellBack != (tc.fallback != nil && tc.remote == down) {
+				t.Errorf("fallback logged = %t:\n%s", fellBack, buf.String())
+			}
+		})
+	}
+}
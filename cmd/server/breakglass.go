@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/breakglass"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+var (
+	// breakGlassActive is 1 while an emergency override is in force.
+	breakGlassActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "svid_exchange_break_glass_active",
+		Help: "1 while a break-glass emergency override is in force.",
+	})
+	// breakGlassGrants counts exchanges granted by an override.
+	breakGlassGrants = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "svid_exchange_break_glass_grants_total",
+		Help: "Policy decisions granted by a break-glass override after the regular policy denied or failed.",
+	})
+)
+
+// breakGlassAuditor writes break-glass audit entries; *audit.Logger
+// implements it.
+type breakGlassAuditor interface {
+	LogBreakGlass(e audit.BreakGlassEvent)
+}
+
+// breakGlassPolicy is a PolicyEvaluator that grants from the active
+// break-glass override what next denies or cannot decide. Overrides are
+// activated through the admin API, expire on their own, and are kept in
+// memory only. Every activation, rejected document, grant, and expiry is
+// audited.
+type breakGlassPolicy struct {
+	next        server.PolicyEvaluator
+	key         crypto.PublicKey
+	maxDuration time.Duration
+	audit       breakGlassAuditor
+	actor       server.IDExtractor
+	now         func() time.Time
+
+	active atomic.Pointer[breakglass.Override]
+	// mu serializes activation, deactivation, and expiry.
+	mu         sync.Mutex
+	timer      *time.Timer
+	onActivate []func()
+}
+
+// newBreakGlassPolicy returns a breakGlassPolicy in front of next, accepting
+// overrides signed with key that expire within maxDuration. actor identifies
+// the admin caller in audit entries.
+func newBreakGlassPolicy(next server.PolicyEvaluator, key crypto.PublicKey, maxDuration time.Duration, a breakGlassAuditor, actor server.IDExtractor) *breakGlassPolicy {
+	return &breakGlassPolicy{next: next, key: key, maxDuration: maxDuration, audit: a, actor: actor, now: time.Now}
+}
+
+// Evaluate returns next's decision unless next denies the request, or fails
+// while ctx is still live, and the active override grants it.
+func (bp *breakGlassPolicy) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	res, err := bp.next.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+	if err == nil && res.Allowed {
+		return res, nil
+	}
+	o := bp.active.Load()
+	if o == nil || ctx.Err() != nil {
+		return res, err
+	}
+	override := o.Evaluate(bp.now(), subject, target, scopes, ttlSeconds)
+	if !override.Allowed {
+		return res, err
+	}
+	breakGlassGrants.Inc()
+	bp.audit.LogBreakGlass(audit.BreakGlassEvent{
+		Action:        audit.BreakGlassGrant,
+		ID:            o.ID,
+		Subject:       subject,
+		Target:        target,
+		ScopesGranted: override.GrantedScopes,
+		Rules:         override.MatchedRules,
+	})
+	return override, nil
+}
+
+// notifyActivate registers f to be called after every activation, so that
+// caches of denials can be dropped.
+func (bp *breakGlassPolicy) notifyActivate(f func()) {
+	bp.mu.Lock()
+	bp.onActivate = append(bp.onActivate, f)
+	bp.mu.Unlock()
+}
+
+// Activate verifies document and puts it in force, replacing any active
+// override, until it expires.
+func (bp *breakGlassPolicy) Activate(ctx context.Context, document, signature []byte) (string, time.Time, error) {
+	actor, _ := bp.actor.ExtractID(ctx)
+	o, err := breakglass.Parse(document, signature, bp.key, bp.now(), bp.maxDuration)
+	if err != nil {
+		bp.audit.LogBreakGlass(audit.BreakGlassEvent{Action: audit.BreakGlassRejected, Actor: actor, Error: err.Error()})
+		return "", time.Time{}, err
+	}
+
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	prev := bp.active.Swap(o)
+	if bp.timer != nil {
+		bp.timer.Stop()
+	}
+	bp.timer = time.AfterFunc(o.ExpiresAt.Sub(bp.now()), func() { bp.expire(o) })
+	ev := audit.BreakGlassEvent{
+		Action:    audit.BreakGlassActivated,
+		ID:        o.ID,
+		Reason:    o.Reason,
+		IssuedBy:  o.IssuedBy,
+		ExpiresAt: o.ExpiresAt,
+		Actor:     actor,
+	}
+	for _, p := range o.Policies {
+		ev.Rules = append(ev.Rules, p.Name)
+	}
+	if prev != nil {
+		ev.Replaced = prev.ID
+	}
+	bp.audit.LogBreakGlass(ev)
+	breakGlassActive.Set(1)
+	for _, f := range bp.onActivate {
+		f()
+	}
+	return o.ID, o.ExpiresAt, nil
+}
+
+// Deactivate ends the active override and returns its ID, or "" when none
+// is active.
+func (bp *breakGlassPolicy) Deactivate(ctx context.Context) (string, error) {
+	actor, _ := bp.actor.ExtractID(ctx)
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	o := bp.active.Swap(nil)
+	if o == nil {
+		return "", nil
+	}
+	bp.timer.Stop()
+	bp.audit.LogBreakGlass(audit.BreakGlassEvent{Action: audit.BreakGlassDeactivated, ID: o.ID, Reason: o.Reason, Actor: actor})
+	breakGlassActive.Set(0)
+	return o.ID, nil
+}
+
+// expire ends o when it is still the active override. Evaluate already stops
+// granting from o at its expiry; expire records the end in the audit log
+// without waiting for traffic.
+func (bp *breakGlassPolicy) expire(o *breakglass.Override) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if !bp.active.CompareAndSwap(o, nil) {
+		return
+	}
+	bp.audit.LogBreakGlass(audit.BreakGlassEvent{Action: audit.BreakGlassExpired, ID: o.ID, Reason: o.Reason, ExpiresAt: o.ExpiresAt})
+	breakGlassActive.Set(0)
+}
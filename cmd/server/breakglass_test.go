@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+)
+
+// recordingBreakGlassAudit keeps every break-glass event it is given.
+type recordingBreakGlassAudit struct {
+	events []audit.BreakGlassEvent
+}
+
+func (r *recordingBreakGlassAudit) LogBreakGlass(e audit.BreakGlassEvent) {
+	r.events = append(r.events, e)
+}
+
+func (r *recordingBreakGlassAudit) actions() []string {
+	var out []string
+	for _, e := range r.events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestBreakGlassPolicy(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+		ledger  = "spiffe://cluster.local/ns/default/sa/ledger"
+		admin   = "spiffe://cluster.local/ns/ops/sa/admin"
+	)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// order → payment is in the regular policy; the override adds
+	// order → ledger.
+	doc := func(id string, exp time.Time) []byte {
+		return fmt.Appendf(nil, "id: %s\nreason: ledger outage\nexpires_at: %s\npolicies:\n"+
+			"  - name: order-to-ledger\n    subject: %q\n    target: %q\n    allowed_scopes: [r:w]\n    max_ttl: 600\n",
+			id, exp.Format(time.RFC3339), order, ledger)
+	}
+	newPolicy := func(t *testing.T) (*breakGlassPolicy, *recordingBreakGlassAudit) {
+		t.Helper()
+		rec := &recordingBreakGlassAudit{}
+		ap := newAtomicPolicy(loadTestPolicy(t, order, payment), zerolog.Nop())
+		bp := newBreakGlassPolicy(ap, pub, time.Hour, rec, &mockIDExtractor{id: admin})
+		bp.now = func() time.Time { return now }
+		return bp, rec
+	}
+	activate := func(t *testing.T, bp *breakGlassPolicy, id string, exp time.Time) {
+		t.Helper()
+		d := doc(id, exp)
+		if _, _, err := bp.Activate(context.Background(), d, ed25519.Sign(priv, d)); err != nil {
+			t.Fatalf("Activate: %v", err)
+		}
+	}
+	eval := func(t *testing.T, bp *breakGlassPolicy, target string) bool {
+		t.Helper()
+		res, err := bp.Evaluate(context.Background(), order, target, []string{"r:w"}, 0)
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		return res.Allowed
+	}
+
+	t.Run("no override defers to the regular policy", func(t *testing.T) {
+		bp, rec := newPolicy(t)
+		if !eval(t, bp, payment) || eval(t, bp, ledger) {
+			t.Error("decisions differ from the regular policy")
+		}
+		if len(rec.events) != 0 {
+			t.Errorf("unexpected audit events %v", rec.events)
+		}
+	})
+
+	t.Run("override grants what the regular policy denies", func(t *testing.T) {
+		bp, rec := newPolicy(t)
+		reset := 0
+		bp.notifyActivate(func() { reset++ })
+		activate(t, bp, "inc-1", now.Add(10*time.Minute))
+		if reset != 1 || testutil.ToFloat64(breakGlassActive) != 1 {
+			t.Errorf("activation hooks ran %d times, active gauge %v", reset, testutil.ToFloat64(breakGlassActive))
+		}
+		before := testutil.ToFloat64(breakGlassGrants)
+
+		res, err := bp.Evaluate(context.Background(), order, ledger, []string{"r:w"}, 0)
+		if err != nil || !res.Allowed || res.GrantedTTL != 600 || !slices.Equal(res.MatchedRules, []string{"break-glass/inc-1/order-to-ledger"}) {
+			t.Fatalf("Evaluate = %+v, %v; want the override's grant", res, err)
+		}
+		if !eval(t, bp, payment) {
+			t.Error("regular grant lost")
+		}
+		if got := testutil.ToFloat64(breakGlassGrants) - before; got != 1 {
+			t.Errorf("grant counter rose by %v, want 1 (regular grants are not counted)", got)
+		}
+		if !slices.Equal(rec.actions(), []string{audit.BreakGlassActivated, audit.BreakGlassGrant}) {
+			t.Fatalf("audit actions = %v", rec.actions())
+		}
+		if a := rec.events[0]; a.ID != "inc-1" || a.Actor != admin || a.Reason != "ledger outage" || len(a.Rules) != 1 {
+			t.Errorf("activation event = %+v", a)
+		}
+		if g := rec.events[1]; g.Subject != order || g.Target != ledger || g.ID != "inc-1" {
+			t.Errorf("grant event = %+v", g)
+		}
+	})
+
+	t.Run("override grants when the regular policy fails", func(t *testing.T) {
+		rec := &recordingBreakGlassAudit{}
+		bp := newBreakGlassPolicy(failingEvaluator{errors.New("authorizer down")}, pub, time.Hour, rec, &mockIDExtractor{id: admin})
+		bp.now = func() time.Time { return now }
+		activate(t, bp, "inc-2", now.Add(10*time.Minute))
+		if !eval(t, bp, ledger) {
+			t.Error("override did not grant")
+		}
+		if _, err := bp.Evaluate(context.Background(), order, payment, []string{"r:w"}, 0); err == nil {
+			t.Error("expected the regular policy's error for a request the override does not cover")
+		}
+	})
+
+	t.Run("expiry and deactivation end the override", func(t *testing.T) {
+		bp, rec := newPolicy(t)
+		activate(t, bp, "inc-3", now.Add(10*time.Minute))
+		first := bp.active.Load()
+		activate(t, bp, "inc-4", now.Add(20*time.Minute))
+		if e := rec.events[1]; e.Replaced != "inc-3" {
+			t.Errorf("second activation replaced %q, want inc-3", e.Replaced)
+		}
+		// The replaced override's expiry does nothing.
+		bp.expire(first)
+		if !eval(t, bp, ledger) {
+			t.Fatal("replaced override's expiry ended its replacement")
+		}
+		bp.expire(bp.active.Load())
+		if eval(t, bp, ledger) || testutil.ToFloat64(breakGlassActive) != 0 {
+			t.Error("expired override still grants")
+		}
+
+		activate(t, bp, "inc-5", now.Add(10*time.Minute))
+		id, err := bp.Deactivate(context.Background())
+		if err != nil || id != "inc-5" {
+			t.Fatalf("Deactivate = %q, %v", id, err)
+		}
+		if id, _ := bp.Deactivate(context.Background()); id != "" {
+			t.Errorf("second Deactivate = %q, want none active", id)
+		}
+		want := []string{audit.BreakGlassActivated, audit.BreakGlassActivated, audit.BreakGlassGrant, audit.BreakGlassExpired, audit.BreakGlassActivated, audit.BreakGlassDeactivated}
+		if !slices.Equal(rec.actions(), want) {
+			t.Errorf("audit actions = %v, want %v", rec.actions(), want)
+		}
+	})
+
+	t.Run("rejected document is audited", func(t *testing.T) {
+		bp, rec := newPolicy(t)
+		d := doc("inc-6", now.Add(10*time.Minute))
+		if _, _, err := bp.Activate(context.Background(), d, []byte("forged")); err == nil {
+			t.Fatal("expected a forged document to be rejected")
+		}
+		if len(rec.events) != 1 || rec.events[0].Action != audit.BreakGlassRejected || rec.events[0].Actor != admin || rec.events[0].Error == "" {
+			t.Errorf("audit events = %+v, want one rejection", rec.events)
+		}
+		if eval(t, bp, ledger) {
+			t.Error("rejected override grants")
+		}
+	})
+}
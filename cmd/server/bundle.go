@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// defaultBundleRefreshHint is advertised when key rotation is disabled.
+const defaultBundleRefreshHint = 5 * time.Minute
+
+// spiffeBundle is a SPIFFE trust bundle document (SPIFFE Trust Domain and
+// Bundle specification §4) carrying only JWT-SVID authorities.
+type spiffeBundle struct {
+	Keys        []jwk.Key `json:"keys"`
+	RefreshHint int64     `json:"spiffe_refresh_hint"`
+}
+
+// bundleRefreshHint returns how often bundle consumers should re-fetch: half
+// the rotation interval so a new key is seen well before the old one is
+// evicted, or defaultBundleRefreshHint when rotation is disabled.
+func bundleRefreshHint(rotation time.Duration) time.Duration {
+	if rotation <= 0 {
+		return defaultBundleRefreshHint
+	}
+	return max(rotation/2, time.Second)
+}
+
+// newSPIFFEBundleHandler serves the active signing keys as a SPIFFE bundle
+// with use "jwt-svid", so SPIFFE-native validators can load it (for example
+// via SPIRE federation) and accept jwt-svid tokens. Keys whose algorithm the
+// JWT-SVID specification does not permit are omitted.
+func newSPIFFEBundleHandler(kp keyProvider, refreshHint time.Duration, log zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		doc := spiffeBundle{Keys: make([]jwk.Key, 0), RefreshHint: int64(refreshHint / time.Second)}
+		for _, pub := range kp.PublicKeys() {
+			if !token.SVIDCompatible(pub) {
+				continue
+			}
+			k, err := pubToJWK(pub)
+			if err != nil {
+				log.Error().Err(err).Msg("spiffe bundle: build key entry")
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			k.Use = "jwt-svid"
+			doc.Keys = append(doc.Keys, k)
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			log.Error().Err(err).Msg("spiffe bundle: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("spiffe bundle: write response")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestSPIFFEBundleHandler(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+
+	tests := []struct {
+		alg      token.Algorithm
+		wantKeys int
+	}{
+		{alg: token.ES256, wantKeys: 1},
+		{alg: token.RS256, wantKeys: 1},
+		{alg: token.EdDSA, wantKeys: 0}, // not a permitted JWT-SVID algorithm
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.alg), func(t *testing.T) {
+			m, err := token.NewMinterWithAlgorithm(tc.alg)
+			if err != nil {
+				t.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			newSPIFFEBundleHandler(m, time.Minute, zerolog.Nop())(rec, httptest.NewRequest(http.MethodGet, "/jwt-svid-bundle", nil))
+			body, err := io.ReadAll(rec.Body)
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+
+			// go-spiffe must be able to parse the document as a SPIFFE bundle.
+			b, err := spiffebundle.Parse(td, body)
+			if err != nil {
+				t.Fatalf("spiffebundle.Parse: %v", err)
+			}
+			if got := len(b.JWTAuthorities()); got != tc.wantKeys {
+				t.Fatalf("JWT authorities = %d, want %d", got, tc.wantKeys)
+			}
+			if hint, ok := b.RefreshHint(); !ok || hint != time.Minute {
+				t.Errorf("refresh hint = %v (%v), want 1m", hint, ok)
+			}
+			if tc.wantKeys == 0 {
+				return
+			}
+
+			res, err := token.NewSVIDMinter(m).Mint(context.Background(), subject, target, []string{"payments:charge"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			if _, err := jwtsvid.ParseAndValidate(res.Token, b, []string{target}); err != nil {
+				t.Errorf("jwtsvid.ParseAndValidate: %v", err)
+			}
+		})
+	}
+}
+
+func TestBundleRefreshHint(t *testing.T) {
+	tests := []struct {
+		rotation time.Duration
+		want     time.Duration
+	}{
+		{rotation: 0, want: defaultBundleRefreshHint},
+		{rotation: 24 * time.Hour, want: 12 * time.Hour},
+		{rotation: time.Second, want: time.Second},
+	}
+	for _, tc := range tests {
+		if got := bundleRefreshHint(tc.rotation); got != tc.want {
+			t.Errorf("bundleRefreshHint(%v) = %v, want %v", tc.rotation, got, tc.want)
+		}
+	}
+}
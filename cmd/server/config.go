@@ -1,13 +1,28 @@
 package main
 
 import (
+	"cmp"
 	"encoding/hex"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/rs/zerolog"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"gopkg.in/yaml.v3"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/httpserv"
+	"github.com/ngaddam369/svid-exchange/internal/kube"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+	"github.com/ngaddam369/svid-exchange/internal/token"
 )
 
 const (
@@ -17,6 +32,72 @@ const (
 	defaultAdminAddr  = ":8082"
 	defaultPolicyFile = "config/policy.example.yaml"
 	defaultPolicyDB   = "data/policy.db"
+
+	// defaultPolicyEvalTimeout and defaultMintTimeout bound the Exchange
+	// stages when the config file does not. Minting gets longer because a
+	// KMS-backed signer makes a network round trip.
+	defaultPolicyEvalTimeout = 2 * time.Second
+	defaultMintTimeout       = 5 * time.Second
+
+	defaultAnomalyDenialWindow = time.Minute
+
+	// defaultIssuanceStatsRetention and defaultIssuanceReportInterval apply
+	// when issuance_stats is enabled without them: a week of history, and
+	// a daily report of the last day.
+	defaultIssuanceStatsRetention = 7 * 24 * time.Hour
+	defaultIssuanceReportInterval = 24 * time.Hour
+
+	// defaultExternalAuthorizerTimeout bounds each external authorizer call
+	// when external_authorizer_url is set and external_authorizer_timeout is
+	// not.
+	defaultExternalAuthorizerTimeout = time.Second
+
+	// defaultNonceWindow is how long a request nonce is remembered when
+	// nonce_window is not set.
+	defaultNonceWindow = 5 * time.Minute
+	// defaultApprovalTimeout is how long a parked exchange waits for a
+	// decision when approval_timeout is not set.
+	defaultApprovalTimeout = 5 * time.Minute
+
+	// defaultBreakGlassMaxDuration is how far in the future a break-glass
+	// override may expire when break_glass_max_duration is not set.
+	defaultBreakGlassMaxDuration = time.Hour
+
+	// maxTokenTTLLimit is the largest max_token_ttl accepted: a ceiling
+	// longer than a day no longer bounds anything worth bounding.
+	maxTokenTTLLimit = 24 * time.Hour
+
+	// defaultDecisionCacheSize bounds the decision cache when
+	// decision_cache_ttl is set and decision_cache_size is not.
+	defaultDecisionCacheSize = 10000
+
+	// defaultDenialCacheMaxTTL caps the denial cache's backoff when
+	// denial_cache_ttl is set and denial_cache_max_ttl is not.
+	defaultDenialCacheMaxTTL = time.Minute
+
+	// defaultExchangeQueueTimeout is how long an Exchange waits for a
+	// concurrency slot before being shed, when max_concurrent_exchanges is
+	// set and exchange_queue_timeout is not.
+	defaultExchangeQueueTimeout = 100 * time.Millisecond
+
+	// defaultGRPCMaxExchangeMsgSizeKB bounds a single ExchangeRequest. A valid
+	// request (50 scopes and an on_behalf_of token) is a few KiB at most, far
+	// below the server-wide receive limit.
+	defaultGRPCMaxExchangeMsgSizeKB = 64
+
+	// maxGRPCBufferKB and maxGRPCWindowKB bound the transport tuning keys:
+	// a buffer is allocated per connection, and HTTP/2 caps a flow control
+	// window at 2^31-1 bytes.
+	maxGRPCBufferKB = 16 * 1024
+	maxGRPCWindowKB = (1<<31 - 1) / 1024
+
+	// defaultKubeSATokenAudience is the audience a ServiceAccount token must
+	// be issued for when kube_sa_token_audiences is not set.
+	defaultKubeSATokenAudience = "svid-exchange"
+
+	// defaultTargetRefresh is how often the SPIRE entries file is re-read
+	// when target_registry is spire and target_registry_refresh is not set.
+	defaultTargetRefresh = time.Minute
 )
 
 // Config holds all resolved configuration values for the server.
@@ -24,38 +105,345 @@ const (
 // deployment-specific paths come from environment variables only.
 type Config struct {
 	GRPCAddr                 string
+	GRPCExtraAddrs           []string
 	HealthAddr               string
+	HTTPListeners            map[string]httpserv.Config
+	HealthEndpointAuth       map[string]string
+	HealthBearerToken        string
+	CORSAllowedOrigins       []string
+	DebugAddr                string
+	DebugAllowRemote         bool
 	AdminAddr                string
+	RESTGatewayAddr          string
+	ConnectAddr              string
+	BundleEndpointAddr       string
+	BundleEndpointProfile    string
+	BundleCertFile           string
+	BundleKeyFile            string
+	BundleCertRefresh        time.Duration
 	PolicyFile               string
+	ShadowPolicyFile         string
 	PolicyDB                 string
+	PolicyConflicts          policy.ConflictMode
 	GRPCReflection           bool
 	OTLPEndpoint             string
 	OTLPInsecure             bool
 	GRPCMaxConcurrentStreams uint32
+	GRPCKeepalive            grpcKeepalive
+	GRPCTransport            grpcTransport
+	GRPCTLSSessions          grpcTLSSessions
+	GRPCXDS                  bool
 	GRPCMaxRecvMsgSizeKB     int
+	GRPCMaxExchangeMsgSizeKB int
+	GRPCAccessLog            bool
+	RequestLimits            server.Limits
 	RateLimitRPS             float64
 	RateLimitBurst           int
+	MaxConcurrentExchanges   int
+	ExchangeQueueTimeout     time.Duration
 	KeyRotationInterval      time.Duration
+	SigningAlgorithm         token.Algorithm
+	SigningWorkers           int
+	SigningQueueSize         int
+	PolicyEvalTimeout        time.Duration
+	MintTimeout              time.Duration
+	MaxOutstandingTokens     int
+	AnomalyDetection         bool
+	AnomalyDenialBurst       int
+	AnomalyDenialWindow      time.Duration
+	DenialWebhookURL         string
+	DenialWebhookSecret      []byte
+	IssuanceStats            bool
+	IssuanceStatsRetention   time.Duration
+	IssuanceReportDir        string
+	IssuanceReportInterval   time.Duration
+	IssuanceReportFormat     string
+	RuleUsageTracking        bool
 	SpiffeSocket             string
+	SVIDDir                  string
 	AuditHMACKey             []byte
+	MacaroonRootKey          []byte
 	AdminSubjects            []string
+	AllowedTrustDomains      []spiffeid.TrustDomain
+	X509MultiURI             spiffe.MultiURIMode
+	X509URITrustDomain       spiffeid.TrustDomain
+	AuthMethods              []string
+	KubePolicySource         bool
+	KubePolicyNamespace      string
+	KubeWebhookAddr          string
+	KubeWebhookCertFile      string
+	KubeWebhookKeyFile       string
+	KubeWebhookCertRefresh   time.Duration
+	KubeSATokenAudiences     []string
+	KubeSATokenTrustDomain   spiffeid.TrustDomain
+	ExtAuthz                 bool
+	// TokenReuseDetection alerts when ext_authz or /introspect sees a token
+	// presented from more than one source; TokenReuseRevoke also revokes it.
+	TokenReuseDetection bool
+	TokenReuseRevoke    bool
+	// JWTSVIDAudiences is empty when unset; main then accepts JWT-SVIDs
+	// issued for the server's own SPIFFE ID.
+	JWTSVIDAudiences []string
+	// JWTSVIDBundleFile, when set, is a JWKS used instead of the Workload
+	// API to verify JWT-SVIDs from JWTSVIDBundleTrustDomain.
+	JWTSVIDBundleFile        string
+	JWTSVIDBundleTrustDomain spiffeid.TrustDomain
+	// SigningKeySecret, when set, names the Kubernetes Secret in
+	// SigningKeySecretNamespace that holds the JWT signing keys shared by
+	// all replicas.
+	SigningKeySecret          string
+	SigningKeySecretNamespace string
+	// NextSigningKeyFile, when set, is a PKCS #8 PEM key advertised in the
+	// JWKS alongside the current key and signed with from
+	// NextSigningKeyCutover on.
+	NextSigningKeyFile    string
+	NextSigningKeyCutover time.Time
+	// LeaderElectionLease, when set, names the Lease in
+	// LeaderElectionNamespace that replicas campaign for; only the holder
+	// runs jobs that must not run on every replica at once.
+	LeaderElectionLease     string
+	LeaderElectionNamespace string
+	// DecisionCacheTTL, when positive, caches policy decisions for that
+	// long, holding at most DecisionCacheSize of them.
+	DecisionCacheTTL  time.Duration
+	DecisionCacheSize int
+	// DenialCacheTTL, when positive, refuses a repeated denied request
+	// outright for that long, doubling with each further denial up to
+	// DenialCacheMaxTTL.
+	DenialCacheTTL    time.Duration
+	DenialCacheMaxTTL time.Duration
+	// AuditGrantSampleRate is the fraction of grants written to the audit
+	// log; denials and anomalies are always written. AuditLevels maps an
+	// audit event kind to the severity it is written at.
+	AuditGrantSampleRate float64
+	AuditLevels          map[string]zerolog.Level
+	// AuditLogOutput is where the JSON audit log is written: "stdout",
+	// "stderr", or a file path appended to.
+	AuditLogOutput string
+	// AccessLogOutput, when set, writes the gRPC access log as its own JSON
+	// stream, to "stdout", "stderr", or a file path appended to, instead of
+	// the server log. It never shares AuditLogOutput. AccessLogSampleRate is
+	// the fraction of successful calls logged; failed calls always are.
+	AccessLogOutput     string
+	AccessLogSampleRate float64
+	// AuditProtoFile, when set, is appended every exchange event as a
+	// length-delimited audit.v1.ExchangeEvent.
+	AuditProtoFile string
+	// AuditSpoolDir, when set, holds a local spool every exchange event is
+	// synced to before sinks receive it, so events reach them at least once
+	// across restarts. AuditSpoolRequired fails a grant whose event cannot
+	// be spooled.
+	AuditSpoolDir      string
+	AuditSpoolRequired bool
+	// AuditMetadataKeys lists the gRPC metadata keys, lowercase, whose
+	// values the caller sent are recorded in every exchange audit event.
+	AuditMetadataKeys []string
+	// TrackGrants records every issued token in the policy database so
+	// callers can list and revoke their own with ListGrants and RevokeGrant.
+	TrackGrants bool
+	// DecisionReceipts signs a decision receipt for every v2 exchange that
+	// asks for one.
+	DecisionReceipts bool
+	// ExternalAuthorizerURL, when set, delegates policy decisions to the
+	// authorizer there, over mTLS as ExternalAuthorizerSPIFFEID when that is
+	// set. ExternalAuthorizerFailOpen falls back to the local policy when
+	// the authorizer cannot decide, instead of failing the exchange.
+	ExternalAuthorizerURL      string
+	ExternalAuthorizerSPIFFEID spiffeid.ID
+	ExternalAuthorizerTimeout  time.Duration
+	ExternalAuthorizerFailOpen bool
+	// SlowExchangeThreshold, when positive, logs a warning with the stage
+	// breakdown of every exchange that takes at least that long.
+	SlowExchangeThreshold time.Duration
+	// NonceWindow is how long a request nonce is remembered: a request
+	// reusing a nonce within the window is refused.
+	NonceWindow time.Duration
+	// BreakGlassKeyFile, when set, enables break-glass overrides: documents
+	// signed with the private half of the PEM public key at this path may be
+	// activated through the admin API. BreakGlassMaxDuration bounds how far
+	// in the future an override may expire.
+	BreakGlassKeyFile     string
+	BreakGlassMaxDuration time.Duration
+	// Approvals parks exchanges granted by requires_approval policies until
+	// an approver decides them through the admin API or the webhook at
+	// ApprovalWebhookURL, denying them after ApprovalTimeout.
+	Approvals             bool
+	ApprovalTimeout       time.Duration
+	ApprovalWebhookURL    string
+	ApprovalWebhookSecret []byte
+	// DrainPeriod is how long the server keeps running after draining
+	// starts, on SIGTERM or the Drain admin RPC, before it stops accepting
+	// calls and finishes the in-flight ones. Zero stops at once.
+	DrainPeriod time.Duration
+	// LogLevel is the minimum level of the server log, which the
+	// SetLogLevel admin RPC can change at runtime. LogFormat is "json" or
+	// "console". LogDebugPerSecond, when positive, caps trace and debug
+	// entries at that many per second. None of them affect the audit log.
+	LogLevel          zerolog.Level
+	LogFormat         string
+	LogDebugPerSecond int
+	// RedactSPIFFEIDs and RedactScopes are audit.RedactNone, RedactHash, or
+	// RedactRemove, applied to SPIFFE IDs and scopes in the audit log and
+	// in error messages. RedactionHashKey is the HMAC key for RedactHash.
+	RedactSPIFFEIDs  string
+	RedactScopes     string
+	RedactionHashKey []byte
+	// MaxTokenTTL, when positive, caps the TTL of every token issued: a
+	// grant whose policy allows longer is clamped to it. Zero leaves the TTL
+	// to policy.
+	MaxTokenTTL time.Duration
+	// TTLFromDeadline caps the requested TTL of an exchange to the time left
+	// before the call's gRPC deadline.
+	TTLFromDeadline bool
+	// TokenSourceBinding binds every token to the caller's peer address and,
+	// for pod-bound ServiceAccount tokens, pod, in the src claim.
+	TokenSourceBinding bool
+	// NotBeforeSkew backdates the nbf claim of JWT and PASETO tokens, for
+	// consumers whose clocks run behind the server's.
+	NotBeforeSkew time.Duration
+	// TargetRegistry, when set, refuses exchanges for targets no known
+	// workload holds: "static" knows TargetIDs, "kube" looks up the
+	// ServiceAccount of IDs in TargetTrustDomain, and "spire" reads the
+	// registration entries exported to TargetEntriesFile, re-reading it
+	// every TargetRefresh.
+	TargetRegistry    string
+	TargetIDs         []string
+	TargetTrustDomain spiffeid.TrustDomain
+	TargetEntriesFile string
+	TargetRefresh     time.Duration
 }
 
 // configFile mirrors the YAML structure of config/server.yaml.
 // KeyRotationInterval is kept as a string for parsing via time.ParseDuration.
 type configFile struct {
-	GRPCAddr                 string   `yaml:"grpc_addr"`
-	HealthAddr               string   `yaml:"health_addr"`
-	AdminAddr                string   `yaml:"admin_addr"`
-	GRPCReflection           bool     `yaml:"grpc_reflection"`
-	OTLPEndpoint             string   `yaml:"otlp_endpoint"`
-	OTLPInsecure             bool     `yaml:"otlp_insecure"`
-	GRPCMaxConcurrentStreams uint32   `yaml:"grpc_max_concurrent_streams"`
-	GRPCMaxRecvMsgSizeKB     int      `yaml:"grpc_max_recv_msg_size_kb"`
-	RateLimitRPS             float64  `yaml:"rate_limit_rps"`
-	RateLimitBurst           int      `yaml:"rate_limit_burst"`
-	KeyRotationInterval      string   `yaml:"key_rotation_interval"`
-	AdminSubjects            []string `yaml:"admin_subjects"`
+	GRPCAddr                 string                      `yaml:"grpc_addr"`
+	GRPCExtraAddrs           []string                    `yaml:"grpc_extra_addrs"`
+	HealthAddr               string                      `yaml:"health_addr"`
+	HealthExtraAddrs         []string                    `yaml:"health_extra_addrs"`
+	HealthTLS                bool                        `yaml:"health_tls"`
+	HTTPListeners            map[string]httpListenerFile `yaml:"http_listeners"`
+	HealthEndpointAuth       map[string]string           `yaml:"health_endpoint_auth"`
+	HTTPClientCARefresh      string                      `yaml:"http_client_ca_refresh"`
+	TLSCertRefresh           string                      `yaml:"tls_cert_refresh"`
+	CORSAllowedOrigins       []string                    `yaml:"cors_allowed_origins"`
+	DebugAddr                string                      `yaml:"debug_addr"`
+	DebugAllowRemote         bool                        `yaml:"debug_allow_remote"`
+	AdminAddr                string                      `yaml:"admin_addr"`
+	RESTGatewayAddr          string                      `yaml:"rest_gateway_addr"`
+	ConnectAddr              string                      `yaml:"connect_addr"`
+	BundleEndpointAddr       string                      `yaml:"bundle_endpoint_addr"`
+	BundleEndpointProfile    string                      `yaml:"bundle_endpoint_profile"`
+	PolicyConflicts          string                      `yaml:"policy_conflicts"`
+	GRPCReflection           bool                        `yaml:"grpc_reflection"`
+	OTLPEndpoint             string                      `yaml:"otlp_endpoint"`
+	OTLPInsecure             bool                        `yaml:"otlp_insecure"`
+	GRPCMaxConcurrentStreams uint32                      `yaml:"grpc_max_concurrent_streams"`
+	GRPCKeepaliveTime        string                      `yaml:"grpc_keepalive_time"`
+	GRPCKeepaliveTimeout     string                      `yaml:"grpc_keepalive_timeout"`
+	GRPCKeepaliveMinTime     string                      `yaml:"grpc_keepalive_min_time"`
+	GRPCKeepalivePermitIdle  *bool                       `yaml:"grpc_keepalive_permit_without_stream"`
+	GRPCMaxConnectionIdle    string                      `yaml:"grpc_max_connection_idle"`
+	GRPCMaxConnectionAge     string                      `yaml:"grpc_max_connection_age"`
+	GRPCMaxConnectionGrace   string                      `yaml:"grpc_max_connection_age_grace"`
+	GRPCXDS                  bool                        `yaml:"grpc_xds"`
+	GRPCMaxRecvMsgSizeKB     int                         `yaml:"grpc_max_recv_msg_size_kb"`
+	GRPCMaxExchangeMsgSizeKB int                         `yaml:"grpc_max_exchange_msg_size_kb"`
+	GRPCAccessLog            bool                        `yaml:"grpc_access_log"`
+	GRPCTLSSessionTickets    *bool                       `yaml:"grpc_tls_session_tickets"`
+	GRPCTLSTicketLifetime    string                      `yaml:"grpc_tls_session_ticket_lifetime"`
+	AccessLogOutput          string                      `yaml:"access_log_output"`
+	AccessLogSampleRate      *float64                    `yaml:"access_log_sample_rate"`
+	GRPCCompression          string                      `yaml:"grpc_compression"`
+	GRPCWriteBufferKB        int                         `yaml:"grpc_write_buffer_size_kb"`
+	GRPCReadBufferKB         int                         `yaml:"grpc_read_buffer_size_kb"`
+	GRPCSharedWriteBuffer    bool                        `yaml:"grpc_shared_write_buffer"`
+	GRPCInitialWindowKB      int                         `yaml:"grpc_initial_window_size_kb"`
+	GRPCInitialConnWindowKB  int                         `yaml:"grpc_initial_conn_window_size_kb"`
+	GRPCStreamWorkers        uint32                      `yaml:"grpc_stream_workers"`
+	MaxScopesPerRequest      int                         `yaml:"max_scopes_per_request"`
+	MaxScopeLength           int                         `yaml:"max_scope_length"`
+	MaxTargetLength          int                         `yaml:"max_target_length"`
+	RateLimitRPS             float64                     `yaml:"rate_limit_rps"`
+	RateLimitBurst           int                         `yaml:"rate_limit_burst"`
+	MaxConcurrentExchanges   int                         `yaml:"max_concurrent_exchanges"`
+	ExchangeQueueTimeout     string                      `yaml:"exchange_queue_timeout"`
+	KeyRotationInterval      string                      `yaml:"key_rotation_interval"`
+	SigningAlgorithm         string                      `yaml:"signing_algorithm"`
+	SigningWorkers           int                         `yaml:"signing_workers"`
+	SigningQueueSize         int                         `yaml:"signing_queue_size"`
+	PolicyEvalTimeout        string                      `yaml:"policy_eval_timeout"`
+	MintTimeout              string                      `yaml:"mint_timeout"`
+	MaxOutstandingTokens     int                         `yaml:"max_outstanding_tokens"`
+	AnomalyDetection         bool                        `yaml:"anomaly_detection"`
+	AnomalyDenialBurst       int                         `yaml:"anomaly_denial_burst"`
+	AnomalyDenialWindow      string                      `yaml:"anomaly_denial_window"`
+	DenialWebhookURL         string                      `yaml:"denial_webhook_url"`
+	IssuanceStats            bool                        `yaml:"issuance_stats"`
+	IssuanceStatsRetention   string                      `yaml:"issuance_stats_retention"`
+	IssuanceReportDir        string                      `yaml:"issuance_report_dir"`
+	IssuanceReportInterval   string                      `yaml:"issuance_report_interval"`
+	IssuanceReportFormat     string                      `yaml:"issuance_report_format"`
+	RuleUsageTracking        bool                        `yaml:"rule_usage_tracking"`
+	AdminSubjects            []string                    `yaml:"admin_subjects"`
+	AllowedTrustDomains      []string                    `yaml:"allowed_trust_domains"`
+	X509MultiURIMode         string                      `yaml:"x509_multi_uri_mode"`
+	X509URITrustDomain       string                      `yaml:"x509_uri_trust_domain"`
+	AuthMethods              []string                    `yaml:"auth_methods"`
+	KubePolicySource         bool                        `yaml:"kube_policy_source"`
+	KubePolicyNamespace      string                      `yaml:"kube_policy_namespace"`
+	KubeWebhookAddr          string                      `yaml:"kube_webhook_addr"`
+	KubeSATokenAudiences     []string                    `yaml:"kube_sa_token_audiences"`
+	KubeSATokenTrustDomain   string                      `yaml:"kube_sa_token_trust_domain"`
+	ExtAuthz                 bool                        `yaml:"ext_authz"`
+	TokenReuseDetection      bool                        `yaml:"token_reuse_detection"`
+	TokenReuseRevoke         bool                        `yaml:"token_reuse_revoke"`
+	JWTSVIDAudiences         []string                    `yaml:"jwt_svid_audiences"`
+	JWTSVIDBundleTrustDomain string                      `yaml:"jwt_svid_bundle_trust_domain"`
+	SigningKeySecret         string                      `yaml:"signing_key_secret"`
+	SigningKeySecretNS       string                      `yaml:"signing_key_secret_namespace"`
+	NextSigningKeyFile       string                      `yaml:"next_signing_key_file"`
+	NextSigningKeyCutover    string                      `yaml:"next_signing_key_cutover"`
+	LeaderElectionLease      string                      `yaml:"leader_election_lease"`
+	LeaderElectionNamespace  string                      `yaml:"leader_election_namespace"`
+	DecisionCacheTTL         string                      `yaml:"decision_cache_ttl"`
+	DecisionCacheSize        int                         `yaml:"decision_cache_size"`
+	DenialCacheTTL           string                      `yaml:"denial_cache_ttl"`
+	DenialCacheMaxTTL        string                      `yaml:"denial_cache_max_ttl"`
+	AuditGrantSampleRate     *float64                    `yaml:"audit_grant_sample_rate"`
+	AuditLevels              map[string]string           `yaml:"audit_levels"`
+	AuditLogOutput           string                      `yaml:"audit_log_output"`
+	AuditProtoFile           string                      `yaml:"audit_proto_file"`
+	AuditSpoolDir            string                      `yaml:"audit_spool_dir"`
+	AuditSpoolRequired       bool                        `yaml:"audit_spool_required"`
+	AuditMetadataKeys        []string                    `yaml:"audit_metadata_keys"`
+	TrackGrants              bool                        `yaml:"track_grants"`
+	DecisionReceipts         bool                        `yaml:"decision_receipts"`
+	AuthorizerURL            string                      `yaml:"external_authorizer_url"`
+	AuthorizerSPIFFEID       string                      `yaml:"external_authorizer_spiffe_id"`
+	AuthorizerTimeout        string                      `yaml:"external_authorizer_timeout"`
+	AuthorizerFailureMode    string                      `yaml:"external_authorizer_failure_mode"`
+	SlowExchangeThreshold    string                      `yaml:"slow_exchange_threshold"`
+	NonceWindow              string                      `yaml:"nonce_window"`
+	BreakGlassPublicKey      string                      `yaml:"break_glass_public_key"`
+	BreakGlassMaxDuration    string                      `yaml:"break_glass_max_duration"`
+	Approvals                bool                        `yaml:"approvals"`
+	ApprovalTimeout          string                      `yaml:"approval_timeout"`
+	ApprovalWebhookURL       string                      `yaml:"approval_webhook_url"`
+	DrainPeriod              string                      `yaml:"drain_period"`
+	LogLevel                 string                      `yaml:"log_level"`
+	LogFormat                string                      `yaml:"log_format"`
+	LogDebugPerSecond        int                         `yaml:"log_debug_per_second"`
+	RedactSPIFFEIDs          string                      `yaml:"redact_spiffe_ids"`
+	RedactScopes             string                      `yaml:"redact_scopes"`
+	MaxTokenTTL              string                      `yaml:"max_token_ttl"`
+	TTLFromDeadline          bool                        `yaml:"ttl_from_deadline"`
+	TokenSourceBinding       bool                        `yaml:"token_source_binding"`
+	NotBeforeSkew            string                      `yaml:"not_before_skew"`
+	TargetRegistry           string                      `yaml:"target_registry"`
+	TargetIDs                []string                    `yaml:"target_registry_ids"`
+	TargetTrustDomain        string                      `yaml:"target_registry_trust_domain"`
+	TargetEntriesFile        string                      `yaml:"target_registry_entries_file"`
+	TargetRefresh            string                      `yaml:"target_registry_refresh"`
 }
 
 // loadConfig reads the YAML config file (path from CONFIG_FILE env var,
@@ -81,16 +469,51 @@ func loadConfig() (Config, error) {
 
 	cfg := Config{
 		GRPCAddr:                 f.GRPCAddr,
+		GRPCExtraAddrs:           f.GRPCExtraAddrs,
 		HealthAddr:               f.HealthAddr,
+		HealthEndpointAuth:       f.HealthEndpointAuth,
+		CORSAllowedOrigins:       f.CORSAllowedOrigins,
+		DebugAddr:                f.DebugAddr,
+		DebugAllowRemote:         f.DebugAllowRemote,
 		AdminAddr:                f.AdminAddr,
+		RESTGatewayAddr:          f.RESTGatewayAddr,
+		ConnectAddr:              f.ConnectAddr,
+		BundleEndpointAddr:       f.BundleEndpointAddr,
+		BundleEndpointProfile:    f.BundleEndpointProfile,
 		GRPCReflection:           f.GRPCReflection,
 		OTLPEndpoint:             f.OTLPEndpoint,
 		OTLPInsecure:             f.OTLPInsecure,
 		GRPCMaxConcurrentStreams: f.GRPCMaxConcurrentStreams,
 		GRPCMaxRecvMsgSizeKB:     f.GRPCMaxRecvMsgSizeKB,
+		GRPCMaxExchangeMsgSizeKB: f.GRPCMaxExchangeMsgSizeKB,
+		GRPCAccessLog:            f.GRPCAccessLog,
+		GRPCXDS:                  f.GRPCXDS,
 		RateLimitRPS:             f.RateLimitRPS,
 		RateLimitBurst:           f.RateLimitBurst,
+		MaxConcurrentExchanges:   f.MaxConcurrentExchanges,
+		MaxOutstandingTokens:     f.MaxOutstandingTokens,
+		TrackGrants:              f.TrackGrants,
+		DecisionReceipts:         f.DecisionReceipts,
+		ExternalAuthorizerURL:    f.AuthorizerURL,
+		AnomalyDetection:         f.AnomalyDetection,
+		AnomalyDenialBurst:       f.AnomalyDenialBurst,
+		DenialWebhookURL:         f.DenialWebhookURL,
+		IssuanceStats:            f.IssuanceStats,
+		IssuanceReportDir:        f.IssuanceReportDir,
+		AuditProtoFile:           f.AuditProtoFile,
+		AuditSpoolDir:            f.AuditSpoolDir,
+		AuditSpoolRequired:       f.AuditSpoolRequired,
+		RuleUsageTracking:        f.RuleUsageTracking,
+		TTLFromDeadline:          f.TTLFromDeadline,
+		TokenSourceBinding:       f.TokenSourceBinding,
 		AdminSubjects:            f.AdminSubjects,
+		KubePolicySource:         f.KubePolicySource,
+		KubePolicyNamespace:      f.KubePolicyNamespace,
+		KubeWebhookAddr:          f.KubeWebhookAddr,
+		KubeSATokenAudiences:     f.KubeSATokenAudiences,
+		ExtAuthz:                 f.ExtAuthz,
+		TokenReuseDetection:      f.TokenReuseDetection,
+		TokenReuseRevoke:         f.TokenReuseRevoke,
 		PolicyFile:               defaultPolicyFile,
 		PolicyDB:                 defaultPolicyDB,
 	}
@@ -101,6 +524,390 @@ func loadConfig() (Config, error) {
 			return Config{}, fmt.Errorf("invalid key_rotation_interval %q: %w", v, err)
 		}
 	}
+	if cfg.SigningAlgorithm, err = token.ParseAlgorithm(f.SigningAlgorithm); err != nil {
+		return Config{}, fmt.Errorf("invalid signing_algorithm: %w", err)
+	}
+	if f.SigningWorkers < 0 {
+		return Config{}, fmt.Errorf("invalid signing_workers %d: must not be negative", f.SigningWorkers)
+	}
+	if f.SigningQueueSize < 0 {
+		return Config{}, fmt.Errorf("invalid signing_queue_size %d: must not be negative", f.SigningQueueSize)
+	}
+	if f.SigningQueueSize > 0 && f.SigningWorkers == 0 {
+		return Config{}, fmt.Errorf("signing_workers must be set when signing_queue_size is set")
+	}
+	cfg.SigningWorkers, cfg.SigningQueueSize = f.SigningWorkers, f.SigningQueueSize
+	cfg.SigningKeySecret, cfg.SigningKeySecretNamespace = f.SigningKeySecret, f.SigningKeySecretNS
+	if cfg.SigningKeySecret != "" && cfg.SigningKeySecretNamespace == "" {
+		return Config{}, fmt.Errorf("signing_key_secret_namespace must be set when signing_key_secret is configured")
+	}
+	if (f.NextSigningKeyFile == "") != (f.NextSigningKeyCutover == "") {
+		return Config{}, fmt.Errorf("next_signing_key_file and next_signing_key_cutover must be set together")
+	}
+	if f.NextSigningKeyFile != "" {
+		switch {
+		case cfg.SigningKeySecret != "":
+			return Config{}, fmt.Errorf("next_signing_key_file cannot be combined with signing_key_secret")
+		case cfg.KeyRotationInterval > 0:
+			return Config{}, fmt.Errorf("next_signing_key_file cannot be combined with key_rotation_interval: rotation would replace the new key")
+		}
+		cfg.NextSigningKeyFile = f.NextSigningKeyFile
+		if cfg.NextSigningKeyCutover, err = time.Parse(time.RFC3339, f.NextSigningKeyCutover); err != nil {
+			return Config{}, fmt.Errorf("invalid next_signing_key_cutover %q: must be an RFC 3339 time", f.NextSigningKeyCutover)
+		}
+	}
+	cfg.LeaderElectionLease, cfg.LeaderElectionNamespace = f.LeaderElectionLease, f.LeaderElectionNamespace
+	if cfg.LeaderElectionLease != "" && cfg.LeaderElectionNamespace == "" {
+		return Config{}, fmt.Errorf("leader_election_namespace must be set when leader_election_lease is configured")
+	}
+	if cfg.PolicyConflicts, err = policy.ParseConflictMode(f.PolicyConflicts); err != nil {
+		return Config{}, fmt.Errorf("invalid policy_conflicts: %w", err)
+	}
+	for _, v := range f.AllowedTrustDomains {
+		td, err := spiffeid.TrustDomainFromString(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid allowed_trust_domains entry %q: %w", v, err)
+		}
+		cfg.AllowedTrustDomains = append(cfg.AllowedTrustDomains, td)
+	}
+	if cfg.X509MultiURI, err = spiffe.ParseMultiURIMode(f.X509MultiURIMode); err != nil {
+		return Config{}, fmt.Errorf("invalid x509_multi_uri_mode: %w", err)
+	}
+	// trust-domain mode picks the caller's ID by x509_uri_trust_domain, so
+	// a domain the allowlist would reject could never authenticate anyone.
+	if cfg.X509MultiURI == spiffe.MultiURITrustDomain {
+		if f.X509URITrustDomain == "" {
+			return Config{}, fmt.Errorf("x509_uri_trust_domain must be set when x509_multi_uri_mode is %s", spiffe.MultiURITrustDomain)
+		}
+		if cfg.X509URITrustDomain, err = spiffeid.TrustDomainFromString(f.X509URITrustDomain); err != nil {
+			return Config{}, fmt.Errorf("invalid x509_uri_trust_domain %q: %w", f.X509URITrustDomain, err)
+		}
+		if len(cfg.AllowedTrustDomains) > 0 && !slices.Contains(cfg.AllowedTrustDomains, cfg.X509URITrustDomain) {
+			return Config{}, fmt.Errorf("x509_uri_trust_domain %q is not in allowed_trust_domains", f.X509URITrustDomain)
+		}
+	}
+	cfg.AuthMethods = []string{authMethodX509SVID}
+	if len(f.AuthMethods) > 0 {
+		cfg.AuthMethods = f.AuthMethods
+	}
+	seenMethods := make(map[string]bool, len(cfg.AuthMethods))
+	for _, m := range cfg.AuthMethods {
+		if !slices.Contains(authMethods, m) {
+			return Config{}, fmt.Errorf("invalid auth_methods entry %q (must be one of %s)", m, strings.Join(authMethods, ", "))
+		}
+		if seenMethods[m] {
+			return Config{}, fmt.Errorf("invalid auth_methods: %q listed twice", m)
+		}
+		seenMethods[m] = true
+	}
+	// ServiceAccount callers get IDs in kube_sa_token_trust_domain, so it has
+	// no default: guessing would let them match policies meant for SVIDs
+	// from another trust domain.
+	if seenMethods[authMethodSAToken] {
+		if cfg.KubeSATokenTrustDomain, err = spiffeid.TrustDomainFromString(f.KubeSATokenTrustDomain); err != nil {
+			return Config{}, fmt.Errorf("invalid kube_sa_token_trust_domain %q: %w", f.KubeSATokenTrustDomain, err)
+		}
+		if len(cfg.KubeSATokenAudiences) == 0 {
+			cfg.KubeSATokenAudiences = []string{defaultKubeSATokenAudience}
+		}
+	}
+	// JWT-SVIDs are verified against the Workload API's JWT bundles unless
+	// JWT_SVID_BUNDLE_FILE names a JWKS, which then needs the trust domain
+	// it holds keys for.
+	if seenMethods[authMethodJWTSVID] {
+		cfg.JWTSVIDAudiences = f.JWTSVIDAudiences
+		if cfg.JWTSVIDBundleFile = os.Getenv("JWT_SVID_BUNDLE_FILE"); cfg.JWTSVIDBundleFile != "" {
+			if cfg.JWTSVIDBundleTrustDomain, err = spiffeid.TrustDomainFromString(f.JWTSVIDBundleTrustDomain); err != nil {
+				return Config{}, fmt.Errorf("invalid jwt_svid_bundle_trust_domain %q: %w", f.JWTSVIDBundleTrustDomain, err)
+			}
+		}
+	}
+
+	// Per-stage Exchange timeouts. Unset uses the defaults; "0" leaves the
+	// stage bounded only by the caller's gRPC deadline.
+	cfg.PolicyEvalTimeout = defaultPolicyEvalTimeout
+	if v := f.PolicyEvalTimeout; v != "" {
+		if cfg.PolicyEvalTimeout, err = time.ParseDuration(v); err != nil || cfg.PolicyEvalTimeout < 0 {
+			return Config{}, fmt.Errorf("invalid policy_eval_timeout %q", v)
+		}
+	}
+	cfg.MintTimeout = defaultMintTimeout
+	if v := f.MintTimeout; v != "" {
+		if cfg.MintTimeout, err = time.ParseDuration(v); err != nil || cfg.MintTimeout < 0 {
+			return Config{}, fmt.Errorf("invalid mint_timeout %q", v)
+		}
+	}
+
+	// Zero keeps a limit's default; see server.DefaultLimits.
+	cfg.RequestLimits = server.Limits{
+		MaxScopes:       f.MaxScopesPerRequest,
+		MaxScopeLength:  f.MaxScopeLength,
+		MaxTargetLength: f.MaxTargetLength,
+	}
+	for _, l := range []struct {
+		key string
+		v   int
+	}{
+		{"max_scopes_per_request", cfg.RequestLimits.MaxScopes},
+		{"max_scope_length", cfg.RequestLimits.MaxScopeLength},
+		{"max_target_length", cfg.RequestLimits.MaxTargetLength},
+	} {
+		if l.v < 0 {
+			return Config{}, fmt.Errorf("invalid %s %d: must be non-negative", l.key, l.v)
+		}
+	}
+
+	if cfg.MaxOutstandingTokens < 0 {
+		return Config{}, fmt.Errorf("invalid max_outstanding_tokens %d: must be non-negative", cfg.MaxOutstandingTokens)
+	}
+
+	if cfg.MaxConcurrentExchanges < 0 {
+		return Config{}, fmt.Errorf("invalid max_concurrent_exchanges %d: must be non-negative", cfg.MaxConcurrentExchanges)
+	}
+	// "0" sheds immediately when every slot is busy.
+	cfg.ExchangeQueueTimeout = defaultExchangeQueueTimeout
+	if v := f.ExchangeQueueTimeout; v != "" {
+		if cfg.ExchangeQueueTimeout, err = time.ParseDuration(v); err != nil || cfg.ExchangeQueueTimeout < 0 {
+			return Config{}, fmt.Errorf("invalid exchange_queue_timeout %q", v)
+		}
+	}
+
+	if cfg.AnomalyDenialBurst < 0 {
+		return Config{}, fmt.Errorf("invalid anomaly_denial_burst %d: must be non-negative", cfg.AnomalyDenialBurst)
+	}
+	cfg.AnomalyDenialWindow = defaultAnomalyDenialWindow
+	if v := f.AnomalyDenialWindow; v != "" {
+		if cfg.AnomalyDenialWindow, err = time.ParseDuration(v); err != nil || cfg.AnomalyDenialWindow <= 0 {
+			return Config{}, fmt.Errorf("invalid anomaly_denial_window %q", v)
+		}
+	}
+
+	cfg.IssuanceStatsRetention = defaultIssuanceStatsRetention
+	if v := f.IssuanceStatsRetention; v != "" {
+		if cfg.IssuanceStatsRetention, err = time.ParseDuration(v); err != nil || cfg.IssuanceStatsRetention < time.Hour {
+			return Config{}, fmt.Errorf("invalid issuance_stats_retention %q: must be at least 1h", v)
+		}
+	}
+	cfg.IssuanceReportInterval = defaultIssuanceReportInterval
+	if v := f.IssuanceReportInterval; v != "" {
+		if cfg.IssuanceReportInterval, err = time.ParseDuration(v); err != nil || cfg.IssuanceReportInterval <= 0 {
+			return Config{}, fmt.Errorf("invalid issuance_report_interval %q", v)
+		}
+	}
+	cfg.IssuanceReportFormat = "json"
+	if v := f.IssuanceReportFormat; v != "" {
+		if v != "json" && v != "csv" {
+			return Config{}, fmt.Errorf("invalid issuance_report_format %q: must be json or csv", v)
+		}
+		cfg.IssuanceReportFormat = v
+	}
+	if cfg.TokenReuseDetection && !cfg.ExtAuthz && cfg.HealthEndpointAuth[introspectPath] == "" {
+		return Config{}, fmt.Errorf("token_reuse_detection requires ext_authz or health_endpoint_auth for %s", introspectPath)
+	}
+	if cfg.TokenReuseRevoke && !cfg.TokenReuseDetection {
+		return Config{}, fmt.Errorf("token_reuse_revoke requires token_reuse_detection")
+	}
+	if cfg.AuditSpoolRequired && cfg.AuditSpoolDir == "" {
+		return Config{}, fmt.Errorf("audit_spool_required requires audit_spool_dir")
+	}
+	if cfg.AuditMetadataKeys, err = parseAuditMetadataKeys(f.AuditMetadataKeys); err != nil {
+		return Config{}, err
+	}
+	if cfg.IssuanceReportDir != "" {
+		if !cfg.IssuanceStats {
+			return Config{}, fmt.Errorf("issuance_report_dir requires issuance_stats")
+		}
+		if cfg.IssuanceReportInterval > cfg.IssuanceStatsRetention {
+			return Config{}, fmt.Errorf("issuance_report_interval %s exceeds issuance_stats_retention %s", cfg.IssuanceReportInterval, cfg.IssuanceStatsRetention)
+		}
+	}
+
+	if v := f.DecisionCacheTTL; v != "" {
+		if cfg.DecisionCacheTTL, err = time.ParseDuration(v); err != nil || cfg.DecisionCacheTTL < 0 {
+			return Config{}, fmt.Errorf("invalid decision_cache_ttl %q", v)
+		}
+	}
+	if f.DecisionCacheSize < 0 {
+		return Config{}, fmt.Errorf("invalid decision_cache_size %d: must be non-negative", f.DecisionCacheSize)
+	}
+	cfg.DecisionCacheSize = defaultDecisionCacheSize
+	if f.DecisionCacheSize > 0 {
+		cfg.DecisionCacheSize = f.DecisionCacheSize
+	}
+
+	if v := f.DenialCacheTTL; v != "" {
+		if cfg.DenialCacheTTL, err = time.ParseDuration(v); err != nil || cfg.DenialCacheTTL < 0 {
+			return Config{}, fmt.Errorf("invalid denial_cache_ttl %q", v)
+		}
+	}
+	cfg.DenialCacheMaxTTL = defaultDenialCacheMaxTTL
+	if v := f.DenialCacheMaxTTL; v != "" {
+		if cfg.DenialCacheMaxTTL, err = time.ParseDuration(v); err != nil || cfg.DenialCacheMaxTTL <= 0 {
+			return Config{}, fmt.Errorf("invalid denial_cache_max_ttl %q", v)
+		}
+	}
+	if cfg.DenialCacheTTL > cfg.DenialCacheMaxTTL {
+		return Config{}, fmt.Errorf("denial_cache_ttl %s exceeds denial_cache_max_ttl %s", cfg.DenialCacheTTL, cfg.DenialCacheMaxTTL)
+	}
+
+	cfg.AuditGrantSampleRate = 1
+	if r := f.AuditGrantSampleRate; r != nil {
+		if *r < 0 || *r > 1 || math.IsNaN(*r) {
+			return Config{}, fmt.Errorf("invalid audit_grant_sample_rate %v: must be between 0 and 1", *r)
+		}
+		cfg.AuditGrantSampleRate = *r
+	}
+	cfg.AuditLogOutput = cmp.Or(f.AuditLogOutput, logOutputStdout)
+	if err := loadAccessLog(&cfg, f); err != nil {
+		return Config{}, err
+	}
+	for kind, v := range f.AuditLevels {
+		if !slices.Contains(audit.EventKinds, kind) {
+			return Config{}, fmt.Errorf("invalid audit_levels: unknown event kind %q (must be one of %v)", kind, audit.EventKinds)
+		}
+		level, err := zerolog.ParseLevel(v)
+		if err != nil || level < zerolog.DebugLevel || level > zerolog.ErrorLevel {
+			return Config{}, fmt.Errorf("invalid audit_levels for %s: %q (must be debug, info, warn, or error)", kind, v)
+		}
+		if cfg.AuditLevels == nil {
+			cfg.AuditLevels = make(map[string]zerolog.Level, len(f.AuditLevels))
+		}
+		cfg.AuditLevels[kind] = level
+	}
+
+	if v := f.SlowExchangeThreshold; v != "" {
+		if cfg.SlowExchangeThreshold, err = time.ParseDuration(v); err != nil || cfg.SlowExchangeThreshold < 0 {
+			return Config{}, fmt.Errorf("invalid slow_exchange_threshold %q", v)
+		}
+	}
+	cfg.NonceWindow = defaultNonceWindow
+	if v := f.NonceWindow; v != "" {
+		if cfg.NonceWindow, err = time.ParseDuration(v); err != nil || cfg.NonceWindow <= 0 {
+			return Config{}, fmt.Errorf("invalid nonce_window %q: must be a positive duration", v)
+		}
+	}
+	cfg.BreakGlassKeyFile = f.BreakGlassPublicKey
+	cfg.BreakGlassMaxDuration = defaultBreakGlassMaxDuration
+	if v := f.BreakGlassMaxDuration; v != "" {
+		if cfg.BreakGlassKeyFile == "" {
+			return Config{}, fmt.Errorf("break_glass_public_key must be set when break_glass_max_duration is configured")
+		}
+		if cfg.BreakGlassMaxDuration, err = time.ParseDuration(v); err != nil || cfg.BreakGlassMaxDuration <= 0 {
+			return Config{}, fmt.Errorf("invalid break_glass_max_duration %q: must be a positive duration", v)
+		}
+	}
+	cfg.Approvals = f.Approvals
+	cfg.ApprovalTimeout = defaultApprovalTimeout
+	if v := f.ApprovalTimeout; v != "" {
+		if !cfg.Approvals {
+			return Config{}, fmt.Errorf("approvals must be enabled when approval_timeout is configured")
+		}
+		if cfg.ApprovalTimeout, err = time.ParseDuration(v); err != nil || cfg.ApprovalTimeout <= 0 {
+			return Config{}, fmt.Errorf("invalid approval_timeout %q: must be a positive duration", v)
+		}
+	}
+	if v := f.DrainPeriod; v != "" {
+		if cfg.DrainPeriod, err = time.ParseDuration(v); err != nil || cfg.DrainPeriod < 0 {
+			return Config{}, fmt.Errorf("invalid drain_period %q", v)
+		}
+	}
+	if v := f.MaxTokenTTL; v != "" {
+		cfg.MaxTokenTTL, err = time.ParseDuration(v)
+		if err != nil || cfg.MaxTokenTTL <= 0 || cfg.MaxTokenTTL%time.Second != 0 || cfg.MaxTokenTTL > maxTokenTTLLimit {
+			return Config{}, fmt.Errorf("invalid max_token_ttl %q: must be a whole number of seconds between 1s and %v", v, maxTokenTTLLimit)
+		}
+	}
+	if v := f.NotBeforeSkew; v != "" {
+		cfg.NotBeforeSkew, err = time.ParseDuration(v)
+		if err != nil || cfg.NotBeforeSkew < 0 || cfg.NotBeforeSkew > token.MaxNotBeforeSkew {
+			return Config{}, fmt.Errorf("invalid not_before_skew %q: must be a duration between 0s and %v", v, token.MaxNotBeforeSkew)
+		}
+	}
+
+	// Each target registry needs its own source of workloads, and nothing
+	// else: a key for another registry is a sign of a half-edited config.
+	cfg.TargetRegistry = f.TargetRegistry
+	switch cfg.TargetRegistry {
+	case "":
+	case targetRegistryStatic:
+		if len(f.TargetIDs) == 0 {
+			return Config{}, fmt.Errorf("target_registry_ids must be set when target_registry is %s", targetRegistryStatic)
+		}
+		for _, v := range f.TargetIDs {
+			id, err := policy.NormalizeSPIFFEID(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid target_registry_ids entry %q: %w", v, err)
+			}
+			cfg.TargetIDs = append(cfg.TargetIDs, id)
+		}
+	case targetRegistryKube:
+		if cfg.TargetTrustDomain, err = spiffeid.TrustDomainFromString(f.TargetTrustDomain); err != nil {
+			return Config{}, fmt.Errorf("invalid target_registry_trust_domain %q: %w", f.TargetTrustDomain, err)
+		}
+	case targetRegistrySPIRE:
+		if cfg.TargetEntriesFile = f.TargetEntriesFile; cfg.TargetEntriesFile == "" {
+			return Config{}, fmt.Errorf("target_registry_entries_file must be set when target_registry is %s", targetRegistrySPIRE)
+		}
+		cfg.TargetRefresh = defaultTargetRefresh
+		if v := f.TargetRefresh; v != "" {
+			if cfg.TargetRefresh, err = time.ParseDuration(v); err != nil || cfg.TargetRefresh <= 0 {
+				return Config{}, fmt.Errorf("invalid target_registry_refresh %q", v)
+			}
+		}
+	default:
+		return Config{}, fmt.Errorf("invalid target_registry %q (must be one of %s)", f.TargetRegistry, strings.Join(targetRegistries, ", "))
+	}
+	if cfg.TargetRegistry != targetRegistryStatic && len(f.TargetIDs) > 0 {
+		return Config{}, fmt.Errorf("target_registry_ids is only used when target_registry is %s", targetRegistryStatic)
+	}
+	if cfg.TargetRegistry != targetRegistryKube && f.TargetTrustDomain != "" {
+		return Config{}, fmt.Errorf("target_registry_trust_domain is only used when target_registry is %s", targetRegistryKube)
+	}
+	if cfg.TargetRegistry != targetRegistrySPIRE && (f.TargetEntriesFile != "" || f.TargetRefresh != "") {
+		return Config{}, fmt.Errorf("target_registry_entries_file and target_registry_refresh are only used when target_registry is %s", targetRegistrySPIRE)
+	}
+
+	// LOG_LEVEL and LOG_FORMAT override the file, so that one instance can
+	// be switched to debug logging without changing shared configuration.
+	cfg.LogLevel = zerolog.InfoLevel
+	if v := cmp.Or(os.Getenv("LOG_LEVEL"), f.LogLevel); v != "" {
+		if cfg.LogLevel, err = parseLogLevel(v); err != nil {
+			return Config{}, err
+		}
+	}
+	cfg.LogFormat = cmp.Or(os.Getenv("LOG_FORMAT"), f.LogFormat, logFormatJSON)
+	if !slices.Contains(logFormats, cfg.LogFormat) {
+		return Config{}, fmt.Errorf("invalid log_format %q: must be one of %v", cfg.LogFormat, logFormats)
+	}
+	if cfg.LogDebugPerSecond = f.LogDebugPerSecond; cfg.LogDebugPerSecond < 0 {
+		return Config{}, fmt.Errorf("invalid log_debug_per_second %d: must not be negative", cfg.LogDebugPerSecond)
+	}
+
+	// REDACTION_HASH_KEY — secret keying the hashes, required when either
+	// setting hashes so that the hashes cannot be reversed by guessing.
+	cfg.RedactSPIFFEIDs = cmp.Or(f.RedactSPIFFEIDs, audit.RedactNone)
+	cfg.RedactScopes = cmp.Or(f.RedactScopes, audit.RedactNone)
+	for _, m := range []struct{ key, mode string }{{"redact_spiffe_ids", cfg.RedactSPIFFEIDs}, {"redact_scopes", cfg.RedactScopes}} {
+		if !slices.Contains(audit.RedactionModes, m.mode) {
+			return Config{}, fmt.Errorf("invalid %s %q: must be one of %v", m.key, m.mode, audit.RedactionModes)
+		}
+	}
+	if cfg.RedactSPIFFEIDs == audit.RedactHash || cfg.RedactScopes == audit.RedactHash {
+		v := os.Getenv("REDACTION_HASH_KEY")
+		if v == "" {
+			return Config{}, fmt.Errorf("REDACTION_HASH_KEY must be set when redact_spiffe_ids or redact_scopes is %s", audit.RedactHash)
+		}
+		if cfg.RedactionHashKey, err = hex.DecodeString(v); err != nil {
+			return Config{}, fmt.Errorf("invalid REDACTION_HASH_KEY: must be hex-encoded")
+		}
+		if len(cfg.RedactionHashKey) < audit.MinRedactionKeySize {
+			return Config{}, fmt.Errorf("REDACTION_HASH_KEY must be at least %d bytes (%d hex chars), got %d bytes", audit.MinRedactionKeySize, 2*audit.MinRedactionKeySize, len(cfg.RedactionHashKey))
+		}
+	}
+
+	if err = loadExternalAuthorizer(&cfg, f); err != nil {
+		return Config{}, err
+	}
 
 	// Deployment-specific path overrides via env vars.
 	if v := os.Getenv("POLICY_FILE"); v != "" {
@@ -109,6 +916,7 @@ func loadConfig() (Config, error) {
 	if v := os.Getenv("POLICY_DB"); v != "" {
 		cfg.PolicyDB = v
 	}
+	cfg.ShadowPolicyFile = os.Getenv("SHADOW_POLICY_FILE")
 
 	// Default burst to ceil(rps) when unset.
 	if cfg.RateLimitBurst <= 0 && cfg.RateLimitRPS > 0 {
@@ -122,11 +930,227 @@ func loadConfig() (Config, error) {
 	if cfg.GRPCMaxRecvMsgSizeKB == 0 {
 		cfg.GRPCMaxRecvMsgSizeKB = 4096
 	}
+	if cfg.GRPCMaxExchangeMsgSizeKB == 0 {
+		cfg.GRPCMaxExchangeMsgSizeKB = defaultGRPCMaxExchangeMsgSizeKB
+	}
+	if cfg.GRPCMaxExchangeMsgSizeKB < 0 || cfg.GRPCMaxExchangeMsgSizeKB > cfg.GRPCMaxRecvMsgSizeKB {
+		return Config{}, fmt.Errorf("invalid grpc_max_exchange_msg_size_kb %d: must be positive and at most grpc_max_recv_msg_size_kb (%d)", cfg.GRPCMaxExchangeMsgSizeKB, cfg.GRPCMaxRecvMsgSizeKB)
+	}
 
-	// SPIFFE_ENDPOINT_SOCKET — required, infrastructure-specific.
+	// Ping timing must be positive; "0s" turns a connection limit off.
+	cfg.GRPCKeepalive = defaultGRPCKeepalive
+	if p := f.GRPCKeepalivePermitIdle; p != nil {
+		cfg.GRPCKeepalive.PermitWithoutStream = *p
+	}
+	for _, d := range []struct {
+		key, v   string
+		dst      *time.Duration
+		positive bool
+	}{
+		{"grpc_keepalive_time", f.GRPCKeepaliveTime, &cfg.GRPCKeepalive.Time, true},
+		{"grpc_keepalive_timeout", f.GRPCKeepaliveTimeout, &cfg.GRPCKeepalive.Timeout, true},
+		{"grpc_keepalive_min_time", f.GRPCKeepaliveMinTime, &cfg.GRPCKeepalive.MinTime, true},
+		{"grpc_max_connection_idle", f.GRPCMaxConnectionIdle, &cfg.GRPCKeepalive.MaxConnectionIdle, false},
+		{"grpc_max_connection_age", f.GRPCMaxConnectionAge, &cfg.GRPCKeepalive.MaxConnectionAge, false},
+		{"grpc_max_connection_age_grace", f.GRPCMaxConnectionGrace, &cfg.GRPCKeepalive.MaxConnectionAgeGrace, false},
+	} {
+		if d.v == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.v)
+		if err != nil || v < 0 || (d.positive && v == 0) {
+			return Config{}, fmt.Errorf("invalid %s %q", d.key, d.v)
+		}
+		*d.dst = v
+	}
+
+	if err := loadGRPCTLSSessions(&cfg, f); err != nil {
+		return Config{}, err
+	}
+
+	// Transport tuning; zero keeps the gRPC default. Sizes are in KiB and
+	// the flow control windows cannot go below HTTP/2's 64 KiB.
+	cfg.GRPCTransport = grpcTransport{
+		Compression:       f.GRPCCompression,
+		SharedWriteBuffer: f.GRPCSharedWriteBuffer,
+		NumStreamWorkers:  f.GRPCStreamWorkers,
+	}
+	if cfg.GRPCTransport.Compression == "" {
+		cfg.GRPCTransport.Compression = compressionNone
+	}
+	if !slices.Contains(compressions, cfg.GRPCTransport.Compression) {
+		return Config{}, fmt.Errorf("invalid grpc_compression %q: must be one of %s", f.GRPCCompression, strings.Join(compressions, ", "))
+	}
+	for _, b := range []struct {
+		key string
+		kb  int
+		dst *int
+	}{
+		{"grpc_write_buffer_size_kb", f.GRPCWriteBufferKB, &cfg.GRPCTransport.WriteBufferSize},
+		{"grpc_read_buffer_size_kb", f.GRPCReadBufferKB, &cfg.GRPCTransport.ReadBufferSize},
+	} {
+		if b.kb < 0 || b.kb > maxGRPCBufferKB {
+			return Config{}, fmt.Errorf("invalid %s %d: must be between 0 and %d", b.key, b.kb, maxGRPCBufferKB)
+		}
+		*b.dst = b.kb * 1024
+	}
+	for _, w := range []struct {
+		key string
+		kb  int
+		dst *int32
+	}{
+		{"grpc_initial_window_size_kb", f.GRPCInitialWindowKB, &cfg.GRPCTransport.InitialWindowSize},
+		{"grpc_initial_conn_window_size_kb", f.GRPCInitialConnWindowKB, &cfg.GRPCTransport.InitialConnWindowSize},
+	} {
+		if w.kb != 0 && (w.kb < 64 || w.kb > maxGRPCWindowKB) {
+			return Config{}, fmt.Errorf("invalid %s %d: must be 0 or between 64 and %d", w.key, w.kb, maxGRPCWindowKB)
+		}
+		*w.dst = int32(w.kb * 1024)
+	}
+
+	// SPIFFE_ENDPOINT_SOCKET — required, infrastructure-specific. In
+	// development SPIFFE_SVID_DIR may name a directory holding the SVID
+	// instead, such as one written by svidx dev ca.
 	cfg.SpiffeSocket = os.Getenv("SPIFFE_ENDPOINT_SOCKET")
-	if cfg.SpiffeSocket == "" {
+	cfg.SVIDDir = os.Getenv("SPIFFE_SVID_DIR")
+	switch {
+	case cfg.SpiffeSocket != "" && cfg.SVIDDir != "":
+		return Config{}, fmt.Errorf("SPIFFE_ENDPOINT_SOCKET and SPIFFE_SVID_DIR cannot both be set")
+	case cfg.SpiffeSocket == "" && cfg.SVIDDir == "":
 		return Config{}, fmt.Errorf("SPIFFE_ENDPOINT_SOCKET must be set")
+	case cfg.SVIDDir != "" && slices.Contains(cfg.AuthMethods, authMethodJWTSVID) && cfg.JWTSVIDBundleFile == "":
+		return Config{}, fmt.Errorf("auth method %s requires JWT_SVID_BUNDLE_FILE when SPIFFE_SVID_DIR is set", authMethodJWTSVID)
+	}
+
+	seen := map[string]bool{cfg.GRPCAddr: true}
+	for _, a := range cfg.GRPCExtraAddrs {
+		if a == "" || seen[a] {
+			return Config{}, fmt.Errorf("invalid grpc_extra_addrs: address %q is empty or repeated", a)
+		}
+		seen[a] = true
+	}
+
+	var certRefresh time.Duration
+	if v := f.TLSCertRefresh; v != "" {
+		if certRefresh, err = time.ParseDuration(v); err != nil || certRefresh < 0 {
+			return Config{}, fmt.Errorf("invalid tls_cert_refresh %q", v)
+		}
+	}
+
+	// WEBHOOK_TLS_CERT / WEBHOOK_TLS_KEY, or WEBHOOK_TLS_DIR — required when
+	// the admission webhook is enabled; the API server only calls webhooks
+	// over HTTPS.
+	if cfg.KubeWebhookAddr != "" {
+		var fromDir bool
+		if cfg.KubeWebhookCertFile, cfg.KubeWebhookKeyFile, fromDir, err = tlsFiles("WEBHOOK"); err != nil {
+			return Config{}, err
+		}
+		if cfg.KubeWebhookCertFile == "" || cfg.KubeWebhookKeyFile == "" {
+			return Config{}, fmt.Errorf("WEBHOOK_TLS_CERT and WEBHOOK_TLS_KEY, or WEBHOOK_TLS_DIR, must be set when kube_webhook_addr is configured")
+		}
+		cfg.KubeWebhookCertRefresh = tlsRefresh(certRefresh, f.TLSCertRefresh, fromDir)
+	}
+
+	// BUNDLE_TLS_CERT / BUNDLE_TLS_KEY, or BUNDLE_TLS_DIR — required for the
+	// https_web bundle endpoint profile, which presents a Web PKI certificate
+	// instead of the server's SVID.
+	if cfg.BundleEndpointAddr != "" {
+		switch cfg.BundleEndpointProfile {
+		case "":
+			cfg.BundleEndpointProfile = bundleProfileSPIFFE
+		case bundleProfileSPIFFE, bundleProfileWeb:
+		default:
+			return Config{}, fmt.Errorf("invalid bundle_endpoint_profile %q: must be %s or %s", cfg.BundleEndpointProfile, bundleProfileSPIFFE, bundleProfileWeb)
+		}
+		if cfg.BundleEndpointProfile == bundleProfileWeb {
+			var fromDir bool
+			if cfg.BundleCertFile, cfg.BundleKeyFile, fromDir, err = tlsFiles("BUNDLE"); err != nil {
+				return Config{}, err
+			}
+			if cfg.BundleCertFile == "" || cfg.BundleKeyFile == "" {
+				return Config{}, fmt.Errorf("BUNDLE_TLS_CERT and BUNDLE_TLS_KEY, or BUNDLE_TLS_DIR, must be set when bundle_endpoint_profile is %s", bundleProfileWeb)
+			}
+			cfg.BundleCertRefresh = tlsRefresh(certRefresh, f.TLSCertRefresh, fromDir)
+		}
+	} else if cfg.BundleEndpointProfile != "" {
+		return Config{}, fmt.Errorf("bundle_endpoint_profile requires bundle_endpoint_addr")
+	}
+
+	// The health listener is built from health_addr and validated with the
+	// other HTTP listeners, so it needs a concrete address.
+	if cfg.HealthAddr == "" {
+		cfg.HealthAddr = defaultHealthAddr
+	}
+	if err = loadHTTPListeners(&cfg, f, certRefresh); err != nil {
+		return Config{}, err
+	}
+
+	// The debug listener exposes pprof and runtime internals, so it binds to
+	// loopback unless remote access is explicitly allowed.
+	if cfg.DebugAddr != "" {
+		if err = checkDebugAddr(cfg.DebugAddr); err != nil && !cfg.DebugAllowRemote {
+			return Config{}, err
+		}
+		for name, lc := range cfg.HTTPListeners {
+			if slices.Contains(lc.Addrs(), cfg.DebugAddr) {
+				return Config{}, fmt.Errorf("debug_addr %q is already used by the %s listener", cfg.DebugAddr, name)
+			}
+		}
+	}
+
+	// The REST gateway, Connect, and the bundle endpoint are listeners of
+	// their own; none can share a port.
+	ownListeners := map[string]string{}
+	for _, l := range []struct{ key, addr string }{
+		{"rest_gateway_addr", cfg.RESTGatewayAddr},
+		{"connect_addr", cfg.ConnectAddr},
+		{"bundle_endpoint_addr", cfg.BundleEndpointAddr},
+	} {
+		a := l.addr
+		if a == "" {
+			continue
+		}
+		if prev, ok := ownListeners[a]; ok {
+			return Config{}, fmt.Errorf("%s %q is already used by %s", l.key, a, prev)
+		}
+		ownListeners[a] = l.key
+		if a == cfg.AdminAddr || a == cfg.DebugAddr || a == cfg.KubeWebhookAddr || a == cfg.GRPCAddr || slices.Contains(cfg.GRPCExtraAddrs, a) {
+			return Config{}, fmt.Errorf("%s %q is already used by another listener", l.key, a)
+		}
+		for name, lc := range cfg.HTTPListeners {
+			if slices.Contains(lc.Addrs(), a) {
+				return Config{}, fmt.Errorf("%s %q is already used by the %s listener", l.key, a, name)
+			}
+		}
+	}
+
+	// DENIAL_WEBHOOK_SECRET — required when the denial webhook is enabled so
+	// every delivery is signed.
+	if cfg.DenialWebhookURL != "" {
+		u, err := url.Parse(cfg.DenialWebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid denial_webhook_url %q: must be an absolute http or https URL", cfg.DenialWebhookURL)
+		}
+		cfg.DenialWebhookSecret = []byte(os.Getenv("DENIAL_WEBHOOK_SECRET"))
+		if len(cfg.DenialWebhookSecret) == 0 {
+			return Config{}, fmt.Errorf("DENIAL_WEBHOOK_SECRET must be set when denial_webhook_url is configured")
+		}
+	}
+
+	// APPROVAL_WEBHOOK_SECRET — required when the approval webhook is
+	// enabled, so that every request for a decision is signed.
+	if cfg.ApprovalWebhookURL = f.ApprovalWebhookURL; cfg.ApprovalWebhookURL != "" {
+		if !cfg.Approvals {
+			return Config{}, fmt.Errorf("approvals must be enabled when approval_webhook_url is configured")
+		}
+		u, err := url.Parse(cfg.ApprovalWebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid approval_webhook_url %q: must be an absolute http or https URL", cfg.ApprovalWebhookURL)
+		}
+		cfg.ApprovalWebhookSecret = []byte(os.Getenv("APPROVAL_WEBHOOK_SECRET"))
+		if len(cfg.ApprovalWebhookSecret) == 0 {
+			return Config{}, fmt.Errorf("APPROVAL_WEBHOOK_SECRET must be set when approval_webhook_url is configured")
+		}
 	}
 
 	// AUDIT_HMAC_KEY — optional secret, never in a config file.
@@ -140,5 +1164,282 @@ func loadConfig() (Config, error) {
 		}
 	}
 
+	// MACAROON_ROOT_KEY — optional secret enabling the macaroon token format.
+	if v := os.Getenv("MACAROON_ROOT_KEY"); v != "" {
+		cfg.MacaroonRootKey, err = hex.DecodeString(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid MACAROON_ROOT_KEY: must be hex-encoded")
+		}
+		if len(cfg.MacaroonRootKey) < token.MinMacaroonKeySize {
+			return Config{}, fmt.Errorf("MACAROON_ROOT_KEY must be at least %d bytes (%d hex chars), got %d bytes", token.MinMacaroonKeySize, 2*token.MinMacaroonKeySize, len(cfg.MacaroonRootKey))
+		}
+	}
+
 	return cfg, nil
 }
+
+// httpListenerFile mirrors one entry under http_listeners.
+type httpListenerFile struct {
+	Addr       string   `yaml:"addr"`
+	ExtraAddrs []string `yaml:"extra_addrs"`
+	TLS        bool     `yaml:"tls"`
+	ClientAuth string   `yaml:"client_auth"`
+}
+
+// File names within a <NAME>_TLS_DIR directory: the layout of a Kubernetes
+// TLS secret as written by cert-manager and mounted by the SPIFFE CSI driver.
+const (
+	tlsDirCert = "tls.crt"
+	tlsDirKey  = "tls.key"
+	tlsDirCA   = "ca.crt"
+)
+
+// defaultTLSDirRefresh is how often material from a <NAME>_TLS_DIR directory
+// is re-read when tls_cert_refresh or http_client_ca_refresh is unset, so
+// that a renewed secret is picked up without further configuration.
+const defaultTLSDirRefresh = time.Minute
+
+// tlsFiles returns the certificate and key paths for the listener whose
+// environment prefix is env: <ENV>_TLS_CERT and <ENV>_TLS_KEY, or tls.crt
+// and tls.key in <ENV>_TLS_DIR. fromDir reports the latter. Both empty
+// means neither is set.
+func tlsFiles(env string) (cert, key string, fromDir bool, err error) {
+	cert, key = os.Getenv(env+"_TLS_CERT"), os.Getenv(env+"_TLS_KEY")
+	dir := os.Getenv(env + "_TLS_DIR")
+	if dir == "" {
+		return cert, key, false, nil
+	}
+	if cert != "" || key != "" {
+		return "", "", false, fmt.Errorf("%s_TLS_DIR cannot be combined with %s_TLS_CERT or %s_TLS_KEY", env, env, env)
+	}
+	return filepath.Join(dir, tlsDirCert), filepath.Join(dir, tlsDirKey), true, nil
+}
+
+// tlsRefresh returns the reload interval configured as raw (parsed into
+// configured), defaulting to defaultTLSDirRefresh for material read from a
+// directory.
+func tlsRefresh(configured time.Duration, raw string, fromDir bool) time.Duration {
+	if raw == "" && fromDir {
+		return defaultTLSDirRefresh
+	}
+	return configured
+}
+
+// loadHTTPListeners builds the HTTP listener set: health on health_addr plus
+// any metrics or keys listener under http_listeners. Certificate paths come
+// from <NAME>_TLS_CERT, <NAME>_TLS_KEY, and <NAME>_TLS_CLIENT_CA, where NAME
+// is the upper-cased listener name, or from tls.crt, tls.key, and ca.crt in
+// <NAME>_TLS_DIR; an explicit <NAME>_TLS_CLIENT_CA still takes precedence
+// over the directory's ca.crt. Certificates are re-read at certRefresh.
+// A listener with an mtls endpoint and no
+// explicit client_auth verifies client certificates when presented. Every
+// listener that verifies clients re-reads its CA bundle at
+// http_client_ca_refresh when set. It also
+// validates health_endpoint_auth, which may only set bearer or mtls for
+// /introspect, and reads HEALTH_BEARER_TOKEN when any endpoint uses bearer
+// auth.
+func loadHTTPListeners(cfg *Config, f configFile, certRefresh time.Duration) error {
+	files := map[string]httpListenerFile{
+		listenerHealth: {Addr: cfg.HealthAddr, ExtraAddrs: f.HealthExtraAddrs, TLS: f.HealthTLS},
+	}
+	for name, l := range f.HTTPListeners {
+		if name != listenerMetrics && name != listenerKeys {
+			return fmt.Errorf("invalid http_listeners: unknown listener %q (must be %s or %s)", name, listenerMetrics, listenerKeys)
+		}
+		files[name] = l
+	}
+
+	var caRefresh time.Duration
+	if v := f.HTTPClientCARefresh; v != "" {
+		var err error
+		if caRefresh, err = time.ParseDuration(v); err != nil || caRefresh < 0 {
+			return fmt.Errorf("invalid http_client_ca_refresh %q", v)
+		}
+	}
+
+	cfg.HTTPListeners = make(map[string]httpserv.Config, len(files))
+	for name := range files {
+		cfg.HTTPListeners[name] = httpserv.Config{}
+	}
+	var needBearer bool
+	needMTLS := map[string]bool{}
+	for path, mode := range cfg.HealthEndpointAuth {
+		if _, ok := httpEndpoints[path]; !ok {
+			return fmt.Errorf("invalid health_endpoint_auth: unknown endpoint %q", path)
+		}
+		if !httpserv.ValidAuthMode(mode) {
+			return fmt.Errorf("invalid health_endpoint_auth for %s: %q (must be %s, %s, or %s)", path, mode, httpserv.AuthNone, httpserv.AuthBearer, httpserv.AuthMTLS)
+		}
+		// Introspection consumes single-use tokens, so it is never open.
+		if path == introspectPath && mode != httpserv.AuthBearer && mode != httpserv.AuthMTLS {
+			return fmt.Errorf("invalid health_endpoint_auth for %s: %q (must be %s or %s)", path, mode, httpserv.AuthBearer, httpserv.AuthMTLS)
+		}
+		needBearer = needBearer || mode == httpserv.AuthBearer
+		if mode == httpserv.AuthMTLS {
+			needMTLS[listenerFor(cfg.HTTPListeners, path)] = true
+		}
+	}
+
+	addrs := map[string]string{}
+	for name, l := range files {
+		env := strings.ToUpper(name)
+		lc := httpserv.Config{Name: name, Addr: l.Addr, ExtraAddrs: l.ExtraAddrs, ClientAuth: l.ClientAuth}
+		for _, a := range lc.Addrs() {
+			if other, ok := addrs[a]; ok && other != name {
+				return fmt.Errorf("http listeners %s and %s share address %q", other, name, a)
+			}
+			addrs[a] = name
+		}
+		var fromDir bool
+		if l.TLS {
+			var err error
+			if lc.CertFile, lc.KeyFile, fromDir, err = tlsFiles(env); err != nil {
+				return err
+			}
+			if lc.CertFile == "" || lc.KeyFile == "" {
+				return fmt.Errorf("%s_TLS_CERT and %s_TLS_KEY, or %s_TLS_DIR, must be set when the %s listener uses TLS", env, env, env, name)
+			}
+			lc.CertRefresh = tlsRefresh(certRefresh, f.TLSCertRefresh, fromDir)
+		}
+		if lc.ClientAuth == "" && needMTLS[name] {
+			lc.ClientAuth = httpserv.ClientAuthOptional
+		}
+		if lc.VerifiesClients() {
+			lc.ClientCAFile = os.Getenv(env + "_TLS_CLIENT_CA")
+			caFromDir := lc.ClientCAFile == "" && fromDir
+			if caFromDir {
+				lc.ClientCAFile = filepath.Join(filepath.Dir(lc.CertFile), tlsDirCA)
+			}
+			if lc.ClientCAFile == "" && lc.TLS() {
+				return fmt.Errorf("%s_TLS_CLIENT_CA must be set when the %s listener verifies client certificates", env, name)
+			}
+			lc.ClientCARefresh = tlsRefresh(caRefresh, f.HTTPClientCARefresh, caFromDir)
+		}
+		if needMTLS[name] && !lc.VerifiesClients() {
+			return fmt.Errorf("%s listener serves an mtls endpoint but its client_auth is %q", name, lc.ClientAuth)
+		}
+		if err := lc.Validate(); err != nil {
+			return err
+		}
+		cfg.HTTPListeners[name] = lc
+	}
+
+	if needBearer {
+		cfg.HealthBearerToken = os.Getenv("HEALTH_BEARER_TOKEN")
+		if cfg.HealthBearerToken == "" {
+			return fmt.Errorf("HEALTH_BEARER_TOKEN must be set when an endpoint uses bearer auth")
+		}
+	}
+	return nil
+}
+
+// loadExternalAuthorizer validates the external_authorizer_* keys into cfg.
+// The timeout must leave room within policy_eval_timeout for the local
+// fallback of the open failure mode.
+func loadExternalAuthorizer(cfg *Config, f configFile) error {
+	if cfg.ExternalAuthorizerURL == "" {
+		if f.AuthorizerSPIFFEID != "" || f.AuthorizerTimeout != "" || f.AuthorizerFailureMode != "" {
+			return fmt.Errorf("external_authorizer_url must be set when other external_authorizer keys are configured")
+		}
+		return nil
+	}
+	u, err := url.Parse(cfg.ExternalAuthorizerURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "grpc") {
+		return fmt.Errorf("invalid external_authorizer_url %q: must be an http, https, or grpc URL with a host", cfg.ExternalAuthorizerURL)
+	}
+	if v := f.AuthorizerSPIFFEID; v != "" {
+		if cfg.ExternalAuthorizerSPIFFEID, err = spiffeid.FromString(v); err != nil {
+			return fmt.Errorf("invalid external_authorizer_spiffe_id %q: %w", v, err)
+		}
+		if u.Scheme == "http" {
+			return fmt.Errorf("external_authorizer_spiffe_id requires an https or grpc external_authorizer_url")
+		}
+	}
+	cfg.ExternalAuthorizerTimeout = defaultExternalAuthorizerTimeout
+	if v := f.AuthorizerTimeout; v != "" {
+		if cfg.ExternalAuthorizerTimeout, err = time.ParseDuration(v); err != nil || cfg.ExternalAuthorizerTimeout <= 0 {
+			return fmt.Errorf("invalid external_authorizer_timeout %q", v)
+		}
+	}
+	if cfg.PolicyEvalTimeout > 0 && cfg.ExternalAuthorizerTimeout >= cfg.PolicyEvalTimeout {
+		return fmt.Errorf("external_authorizer_timeout %s must be less than policy_eval_timeout %s", cfg.ExternalAuthorizerTimeout, cfg.PolicyEvalTimeout)
+	}
+	switch f.AuthorizerFailureMode {
+	case "", "closed":
+	case "open":
+		cfg.ExternalAuthorizerFailOpen = true
+	default:
+		return fmt.Errorf("invalid external_authorizer_failure_mode %q (must be closed or open)", f.AuthorizerFailureMode)
+	}
+	return nil
+}
+
+// maxAuditMetadataKeys bounds audit_metadata_keys, which is meant for a few
+// attribution keys such as a client build version, not whole requests.
+const maxAuditMetadataKeys = 16
+
+// parseAuditMetadataKeys validates audit_metadata_keys and lowercases them,
+// as gRPC does metadata keys. Binary keys, reserved grpc- keys, and the
+// headers callers authenticate with are refused, so that credentials cannot
+// be copied into the audit log.
+func parseAuditMetadataKeys(keys []string) ([]string, error) {
+	if len(keys) > maxAuditMetadataKeys {
+		return nil, fmt.Errorf("audit_metadata_keys has %d entries, more than the maximum of %d", len(keys), maxAuditMetadataKeys)
+	}
+	var out []string
+	for _, v := range keys {
+		k := strings.ToLower(v)
+		switch {
+		case k == "" || strings.Trim(k, "abcdefghijklmnopqrstuvwxyz0123456789-_.") != "":
+			return nil, fmt.Errorf("invalid audit_metadata_keys entry %q: must be a gRPC metadata key", v)
+		case strings.HasSuffix(k, "-bin") || strings.HasPrefix(k, "grpc-"):
+			return nil, fmt.Errorf("invalid audit_metadata_keys entry %q: binary and grpc- keys cannot be recorded", v)
+		case k == spiffe.JWTSVIDHeader || k == kube.SATokenHeader:
+			return nil, fmt.Errorf("invalid audit_metadata_keys entry %q: carries caller credentials", v)
+		case slices.Contains(out, k):
+			return nil, fmt.Errorf("duplicate audit_metadata_keys entry %q", v)
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// loadAccessLog validates the access_log_* keys into cfg. They need
+// grpc_access_log, and the access log may not be written where the audit
+// log is: the two are kept apart so that each can be retained on its own
+// terms.
+func loadAccessLog(cfg *Config, f configFile) error {
+	cfg.AccessLogOutput = f.AccessLogOutput
+	cfg.AccessLogSampleRate = 1
+	if r := f.AccessLogSampleRate; r != nil {
+		if *r < 0 || *r > 1 || math.IsNaN(*r) {
+			return fmt.Errorf("invalid access_log_sample_rate %v: must be between 0 and 1", *r)
+		}
+		cfg.AccessLogSampleRate = *r
+	}
+	if !cfg.GRPCAccessLog && (cfg.AccessLogOutput != "" || cfg.AccessLogSampleRate < 1) {
+		return fmt.Errorf("access_log_output and access_log_sample_rate require grpc_access_log")
+	}
+	if cfg.AccessLogOutput != "" && filepath.Clean(cfg.AccessLogOutput) == filepath.Clean(cfg.AuditLogOutput) {
+		return fmt.Errorf("access_log_output %q is also audit_log_output: the access and audit logs must be written to different outputs", cfg.AccessLogOutput)
+	}
+	return nil
+}
+
+// loadGRPCTLSSessions validates the grpc_tls_session_* keys into cfg. A
+// ticket lifetime beyond crypto/tls's own seven-day limit is refused rather
+// than silently shortened.
+func loadGRPCTLSSessions(cfg *Config, f configFile) error {
+	cfg.GRPCTLSSessions = defaultGRPCTLSSessions
+	if t := f.GRPCTLSSessionTickets; t != nil {
+		cfg.GRPCTLSSessions.Tickets = *t
+	}
+	if v := f.GRPCTLSTicketLifetime; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxTLSSessionTicketLifetime {
+			return fmt.Errorf("invalid grpc_tls_session_ticket_lifetime %q: must be positive and at most %s", v, maxTLSSessionTicketLifetime)
+		}
+		cfg.GRPCTLSSessions.TicketLifetime = d
+	}
+	return nil
+}
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/httpserv"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+	"github.com/ngaddam369/svid-exchange/internal/token"
 )
 
 // writeConfigFile writes content to a temp file and returns its path.
@@ -34,8 +46,58 @@ otlp_insecure:                false
 rate_limit_rps:               5.0
 rate_limit_burst:             10
 key_rotation_interval:        "12h"
+signing_algorithm:            "EdDSA"
 grpc_max_concurrent_streams:  200
+grpc_keepalive_time:          "1m"
+grpc_keepalive_timeout:       "10s"
+grpc_keepalive_min_time:      "15s"
+grpc_keepalive_permit_without_stream: false
+grpc_max_connection_idle:     "0s"
+grpc_max_connection_age:      "10m"
+grpc_max_connection_age_grace: "30s"
+grpc_xds:                     true
 grpc_max_recv_msg_size_kb:    8192
+grpc_max_exchange_msg_size_kb: 16
+grpc_access_log:              true
+grpc_compression:             "gzip"
+grpc_write_buffer_size_kb:    64
+grpc_read_buffer_size_kb:     128
+grpc_shared_write_buffer:     true
+grpc_initial_window_size_kb:  1024
+grpc_stream_workers:          8
+signing_workers:              4
+signing_queue_size:           256
+max_scopes_per_request:       10
+allowed_trust_domains:        ["cluster.local", "spiffe://partner.example"]
+max_scope_length:             64
+ext_authz:                    true
+policy_eval_timeout:          "250ms"
+mint_timeout:                 "0"
+max_outstanding_tokens:       20
+track_grants:                 true
+decision_receipts:            true
+external_authorizer_url:      "grpc://authz.internal:8443"
+external_authorizer_spiffe_id: "spiffe://cluster.local/authz"
+external_authorizer_timeout:  "200ms"
+external_authorizer_failure_mode: "open"
+slow_exchange_threshold:      "750ms"
+nonce_window:                 "2m"
+break_glass_public_key:       "/etc/svid-exchange/break-glass.pub"
+break_glass_max_duration:     "30m"
+drain_period:                 "15s"
+log_level:                    "warn"
+log_format:                   "console"
+log_debug_per_second:         20
+redact_spiffe_ids:            "hash"
+redact_scopes:                "redact"
+max_token_ttl:                "15m"
+not_before_skew:              "30s"
+anomaly_detection:            true
+anomaly_denial_burst:         5
+anomaly_denial_window:        "30s"
+max_concurrent_exchanges:     64
+exchange_queue_timeout:       "50ms"
+policy_conflicts:             "merge-union"
 `
 	minimalYAML := "grpc_reflection: true\n"
 
@@ -49,7 +111,7 @@ grpc_max_recv_msg_size_kb:    8192
 		{
 			name: "all fields parsed from YAML",
 			yaml: validYAML,
-			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_LEVEL": "", "LOG_FORMAT": "", "REDACTION_HASH_KEY": strings.Repeat("ab", 32)},
 			checkCfg: func(t *testing.T, cfg Config) {
 				t.Helper()
 				if cfg.GRPCAddr != ":9090" {
@@ -82,23 +144,168 @@ grpc_max_recv_msg_size_kb:    8192
 				if cfg.GRPCMaxConcurrentStreams != 200 {
 					t.Errorf("GRPCMaxConcurrentStreams = %d, want 200", cfg.GRPCMaxConcurrentStreams)
 				}
+				wantKeepalive := grpcKeepalive{
+					Time:                  time.Minute,
+					Timeout:               10 * time.Second,
+					MinTime:               15 * time.Second,
+					MaxConnectionAge:      10 * time.Minute,
+					MaxConnectionAgeGrace: 30 * time.Second,
+				}
+				if cfg.GRPCKeepalive != wantKeepalive {
+					t.Errorf("GRPCKeepalive = %+v, want %+v", cfg.GRPCKeepalive, wantKeepalive)
+				}
+				wantTransport := grpcTransport{
+					Compression:       compressionGzip,
+					WriteBufferSize:   64 * 1024,
+					ReadBufferSize:    128 * 1024,
+					SharedWriteBuffer: true,
+					InitialWindowSize: 1024 * 1024,
+					NumStreamWorkers:  8,
+				}
+				if cfg.GRPCTransport != wantTransport {
+					t.Errorf("GRPCTransport = %+v, want %+v", cfg.GRPCTransport, wantTransport)
+				}
+				if cfg.SigningWorkers != 4 || cfg.SigningQueueSize != 256 {
+					t.Errorf("signing pipeline = %d workers, %d queue; want 4, 256", cfg.SigningWorkers, cfg.SigningQueueSize)
+				}
+				if !cfg.GRPCXDS {
+					t.Error("GRPCXDS = false, want true")
+				}
 				if cfg.GRPCMaxRecvMsgSizeKB != 8192 {
 					t.Errorf("GRPCMaxRecvMsgSizeKB = %d, want 8192", cfg.GRPCMaxRecvMsgSizeKB)
 				}
+				if got := fmt.Sprint(cfg.AllowedTrustDomains); got != "[cluster.local partner.example]" {
+					t.Errorf("AllowedTrustDomains = %s, want [cluster.local partner.example]", got)
+				}
+				if want := (server.Limits{MaxScopes: 10, MaxScopeLength: 64}); cfg.RequestLimits != want {
+					t.Errorf("RequestLimits = %+v, want %+v", cfg.RequestLimits, want)
+				}
+				if cfg.GRPCMaxExchangeMsgSizeKB != 16 || !cfg.GRPCAccessLog {
+					t.Errorf("GRPCMaxExchangeMsgSizeKB, GRPCAccessLog = %d, %v; want 16, true", cfg.GRPCMaxExchangeMsgSizeKB, cfg.GRPCAccessLog)
+				}
+				if !cfg.ExtAuthz {
+					t.Error("ExtAuthz = false, want true")
+				}
+				if cfg.SigningAlgorithm != token.EdDSA {
+					t.Errorf("SigningAlgorithm = %q, want EdDSA", cfg.SigningAlgorithm)
+				}
+				if cfg.PolicyEvalTimeout != 250*time.Millisecond {
+					t.Errorf("PolicyEvalTimeout = %v, want 250ms", cfg.PolicyEvalTimeout)
+				}
+				if cfg.MintTimeout != 0 {
+					t.Errorf("MintTimeout = %v, want 0 (disabled)", cfg.MintTimeout)
+				}
+				if cfg.MaxOutstandingTokens != 20 {
+					t.Errorf("MaxOutstandingTokens = %d, want 20", cfg.MaxOutstandingTokens)
+				}
+				if !cfg.TrackGrants || !cfg.DecisionReceipts {
+					t.Errorf("TrackGrants, DecisionReceipts = %v, %v; want true, true", cfg.TrackGrants, cfg.DecisionReceipts)
+				}
+				if cfg.ExternalAuthorizerURL != "grpc://authz.internal:8443" || cfg.ExternalAuthorizerSPIFFEID.String() != "spiffe://cluster.local/authz" ||
+					cfg.ExternalAuthorizerTimeout != 200*time.Millisecond || !cfg.ExternalAuthorizerFailOpen {
+					t.Errorf("external authorizer = %q, %s, %v, %v", cfg.ExternalAuthorizerURL, cfg.ExternalAuthorizerSPIFFEID, cfg.ExternalAuthorizerTimeout, cfg.ExternalAuthorizerFailOpen)
+				}
+				if cfg.SlowExchangeThreshold != 750*time.Millisecond {
+					t.Errorf("SlowExchangeThreshold = %v, want 750ms", cfg.SlowExchangeThreshold)
+				}
+				if cfg.NonceWindow != 2*time.Minute {
+					t.Errorf("NonceWindow = %v, want 2m", cfg.NonceWindow)
+				}
+				if cfg.BreakGlassKeyFile != "/etc/svid-exchange/break-glass.pub" || cfg.BreakGlassMaxDuration != 30*time.Minute {
+					t.Errorf("break-glass settings = %q, %v; want /etc/svid-exchange/break-glass.pub, 30m", cfg.BreakGlassKeyFile, cfg.BreakGlassMaxDuration)
+				}
+				if cfg.DrainPeriod != 15*time.Second {
+					t.Errorf("DrainPeriod = %v, want 15s", cfg.DrainPeriod)
+				}
+				if cfg.LogLevel != zerolog.WarnLevel || cfg.LogFormat != logFormatConsole || cfg.LogDebugPerSecond != 20 {
+					t.Errorf("logging = %v, %q, %d; want warn, console, 20", cfg.LogLevel, cfg.LogFormat, cfg.LogDebugPerSecond)
+				}
+				if cfg.RedactSPIFFEIDs != audit.RedactHash || cfg.RedactScopes != audit.RedactRemove || len(cfg.RedactionHashKey) != 32 {
+					t.Errorf("redaction = %q, %q, %d-byte key; want hash, redact, 32-byte key", cfg.RedactSPIFFEIDs, cfg.RedactScopes, len(cfg.RedactionHashKey))
+				}
+				if cfg.MaxTokenTTL != 15*time.Minute {
+					t.Errorf("MaxTokenTTL = %v, want 15m", cfg.MaxTokenTTL)
+				}
+				if cfg.NotBeforeSkew != 30*time.Second {
+					t.Errorf("NotBeforeSkew = %v, want 30s", cfg.NotBeforeSkew)
+				}
+				if !cfg.AnomalyDetection || cfg.AnomalyDenialBurst != 5 || cfg.AnomalyDenialWindow != 30*time.Second {
+					t.Errorf("anomaly settings = %v, %d, %v; want true, 5, 30s", cfg.AnomalyDetection, cfg.AnomalyDenialBurst, cfg.AnomalyDenialWindow)
+				}
+				if cfg.MaxConcurrentExchanges != 64 || cfg.ExchangeQueueTimeout != 50*time.Millisecond {
+					t.Errorf("load shedding = %d, %v; want 64, 50ms", cfg.MaxConcurrentExchanges, cfg.ExchangeQueueTimeout)
+				}
+				if cfg.PolicyConflicts != policy.ConflictMergeUnion {
+					t.Errorf("PolicyConflicts = %q, want merge-union", cfg.PolicyConflicts)
+				}
 			},
 		},
 		{
 			name: "gRPC limit defaults applied when zero",
 			yaml: "grpc_reflection: false\n",
-			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_LEVEL": "", "LOG_FORMAT": ""},
 			checkCfg: func(t *testing.T, cfg Config) {
 				t.Helper()
+				if cfg.SigningAlgorithm != token.ES256 {
+					t.Errorf("SigningAlgorithm = %q, want ES256 (default)", cfg.SigningAlgorithm)
+				}
+				if cfg.PolicyEvalTimeout != defaultPolicyEvalTimeout || cfg.MintTimeout != defaultMintTimeout {
+					t.Errorf("stage timeouts = %v, %v; want defaults", cfg.PolicyEvalTimeout, cfg.MintTimeout)
+				}
+				if cfg.AuditGrantSampleRate != 1 {
+					t.Errorf("AuditGrantSampleRate = %v, want 1 (log every grant)", cfg.AuditGrantSampleRate)
+				}
+				if cfg.MaxConcurrentExchanges != 0 || cfg.ExchangeQueueTimeout != defaultExchangeQueueTimeout {
+					t.Errorf("load shedding = %d, %v; want disabled with default queue timeout", cfg.MaxConcurrentExchanges, cfg.ExchangeQueueTimeout)
+				}
+				if cfg.PolicyConflicts != policy.ConflictWarn {
+					t.Errorf("PolicyConflicts = %q, want warn (default)", cfg.PolicyConflicts)
+				}
 				if cfg.GRPCMaxConcurrentStreams != 100 {
 					t.Errorf("GRPCMaxConcurrentStreams = %d, want 100 (default)", cfg.GRPCMaxConcurrentStreams)
 				}
+				if cfg.GRPCKeepalive != defaultGRPCKeepalive {
+					t.Errorf("GRPCKeepalive = %+v, want %+v (default)", cfg.GRPCKeepalive, defaultGRPCKeepalive)
+				}
+				if cfg.GRPCTransport != (grpcTransport{Compression: compressionNone}) {
+					t.Errorf("GRPCTransport = %+v, want gRPC defaults without compression", cfg.GRPCTransport)
+				}
+				if cfg.SigningWorkers != 0 || cfg.SigningQueueSize != 0 {
+					t.Errorf("signing pipeline = %d workers, %d queue; want disabled (default)", cfg.SigningWorkers, cfg.SigningQueueSize)
+				}
+				if cfg.GRPCXDS {
+					t.Error("GRPCXDS = true, want false (default)")
+				}
+				if cfg.GRPCMaxExchangeMsgSizeKB != defaultGRPCMaxExchangeMsgSizeKB || cfg.GRPCAccessLog {
+					t.Errorf("GRPCMaxExchangeMsgSizeKB, GRPCAccessLog = %d, %v; want %d, false", cfg.GRPCMaxExchangeMsgSizeKB, cfg.GRPCAccessLog, defaultGRPCMaxExchangeMsgSizeKB)
+				}
 				if cfg.GRPCMaxRecvMsgSizeKB != 4096 {
 					t.Errorf("GRPCMaxRecvMsgSizeKB = %d, want 4096 (default)", cfg.GRPCMaxRecvMsgSizeKB)
 				}
+				if len(cfg.AuthMethods) != 1 || cfg.AuthMethods[0] != authMethodX509SVID {
+					t.Errorf("AuthMethods = %v, want [x509-svid] (default)", cfg.AuthMethods)
+				}
+				if cfg.NonceWindow != defaultNonceWindow {
+					t.Errorf("NonceWindow = %v, want %v (default)", cfg.NonceWindow, defaultNonceWindow)
+				}
+				if cfg.BreakGlassKeyFile != "" || cfg.BreakGlassMaxDuration != defaultBreakGlassMaxDuration {
+					t.Errorf("break-glass settings = %q, %v; want disabled, %v (default)", cfg.BreakGlassKeyFile, cfg.BreakGlassMaxDuration, defaultBreakGlassMaxDuration)
+				}
+				if cfg.DrainPeriod != 0 {
+					t.Errorf("DrainPeriod = %v, want 0 (default)", cfg.DrainPeriod)
+				}
+				if cfg.LogLevel != zerolog.InfoLevel || cfg.LogFormat != logFormatJSON || cfg.LogDebugPerSecond != 0 {
+					t.Errorf("logging = %v, %q, %d; want info, json, 0 (defaults)", cfg.LogLevel, cfg.LogFormat, cfg.LogDebugPerSecond)
+				}
+				if cfg.RedactSPIFFEIDs != audit.RedactNone || cfg.RedactScopes != audit.RedactNone || cfg.RedactionHashKey != nil {
+					t.Errorf("redaction = %q, %q; want none, none (defaults)", cfg.RedactSPIFFEIDs, cfg.RedactScopes)
+				}
+				if cfg.MaxTokenTTL != 0 {
+					t.Errorf("MaxTokenTTL = %v, want 0 (default)", cfg.MaxTokenTTL)
+				}
+				if cfg.NotBeforeSkew != 0 {
+					t.Errorf("NotBeforeSkew = %v, want 0 (default)", cfg.NotBeforeSkew)
+				}
 			},
 		},
 		{
@@ -108,12 +315,16 @@ grpc_max_recv_msg_size_kb:    8192
 				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
 				"POLICY_FILE":            "",
 				"POLICY_DB":              "",
+				"SHADOW_POLICY_FILE":     "",
 			},
 			checkCfg: func(t *testing.T, cfg Config) {
 				t.Helper()
 				if cfg.PolicyFile != defaultPolicyFile {
 					t.Errorf("PolicyFile = %q, want %q", cfg.PolicyFile, defaultPolicyFile)
 				}
+				if cfg.ShadowPolicyFile != "" {
+					t.Errorf("ShadowPolicyFile = %q, want empty (disabled)", cfg.ShadowPolicyFile)
+				}
 				if cfg.PolicyDB != defaultPolicyDB {
 					t.Errorf("PolicyDB = %q, want %q", cfg.PolicyDB, defaultPolicyDB)
 				}
@@ -126,12 +337,16 @@ grpc_max_recv_msg_size_kb:    8192
 				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
 				"POLICY_FILE":            "/custom/policy.yaml",
 				"POLICY_DB":              "/custom/policy.db",
+				"SHADOW_POLICY_FILE":     "/custom/candidate.yaml",
 			},
 			checkCfg: func(t *testing.T, cfg Config) {
 				t.Helper()
 				if cfg.PolicyFile != "/custom/policy.yaml" {
 					t.Errorf("PolicyFile = %q, want /custom/policy.yaml", cfg.PolicyFile)
 				}
+				if cfg.ShadowPolicyFile != "/custom/candidate.yaml" {
+					t.Errorf("ShadowPolicyFile = %q, want /custom/candidate.yaml", cfg.ShadowPolicyFile)
+				}
 				if cfg.PolicyDB != "/custom/policy.db" {
 					t.Errorf("PolicyDB = %q, want /custom/policy.db", cfg.PolicyDB)
 				}
@@ -163,12 +378,87 @@ grpc_max_recv_msg_size_kb:    8192
 				}
 			},
 		},
+		{
+			name: "valid MACAROON_ROOT_KEY is decoded",
+			yaml: minimalYAML,
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"MACAROON_ROOT_KEY":      "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.MacaroonRootKey) != 32 {
+					t.Errorf("MacaroonRootKey len = %d, want 32", len(cfg.MacaroonRootKey))
+				}
+			},
+		},
+		{
+			name: "kube policy source settings parsed",
+			yaml: "kube_policy_source: true\nkube_policy_namespace: \"policies\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.KubePolicySource {
+					t.Error("KubePolicySource = false, want true")
+				}
+				if cfg.KubePolicyNamespace != "policies" {
+					t.Errorf("KubePolicyNamespace = %q, want policies", cfg.KubePolicyNamespace)
+				}
+			},
+		},
+		{
+			name: "webhook TLS files read from env",
+			yaml: "kube_webhook_addr: \":8443\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"WEBHOOK_TLS_CERT":       "/tls/tls.crt",
+				"WEBHOOK_TLS_KEY":        "/tls/tls.key",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.KubeWebhookAddr != ":8443" {
+					t.Errorf("KubeWebhookAddr = %q, want :8443", cfg.KubeWebhookAddr)
+				}
+				if cfg.KubeWebhookCertFile != "/tls/tls.crt" || cfg.KubeWebhookKeyFile != "/tls/tls.key" {
+					t.Errorf("webhook TLS files = %q/%q", cfg.KubeWebhookCertFile, cfg.KubeWebhookKeyFile)
+				}
+			},
+		},
+		{
+			name:    "webhook without TLS files returns error",
+			yaml:    "kube_webhook_addr: \":8443\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "WEBHOOK_TLS_CERT": "", "WEBHOOK_TLS_KEY": ""},
+			wantErr: true,
+		},
 		{
 			name:    "missing SPIFFE_ENDPOINT_SOCKET returns error",
 			yaml:    minimalYAML,
 			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": ""},
 			wantErr: true,
 		},
+		{
+			name: "SPIFFE_SVID_DIR replaces the Workload API",
+			yaml: minimalYAML,
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "", "SPIFFE_SVID_DIR": "/dev-ca/server"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.SVIDDir != "/dev-ca/server" || cfg.SpiffeSocket != "" {
+					t.Errorf("SVIDDir = %q, SpiffeSocket = %q", cfg.SVIDDir, cfg.SpiffeSocket)
+				}
+			},
+		},
+		{
+			name:    "SPIFFE_SVID_DIR with SPIFFE_ENDPOINT_SOCKET returns error",
+			yaml:    minimalYAML,
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "SPIFFE_SVID_DIR": "/dev-ca/server"},
+			wantErr: true,
+		},
+		{
+			name:    "SPIFFE_SVID_DIR with jwt-svid auth and no bundle file returns error",
+			yaml:    "auth_methods: [x509-svid, jwt-svid]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "", "SPIFFE_SVID_DIR": "/dev-ca/server"},
+			wantErr: true,
+		},
 		{
 			name:    "missing config file returns error",
 			yaml:    "", // signal to use a nonexistent path
@@ -188,40 +478,1399 @@ grpc_max_recv_msg_size_kb:    8192
 			wantErr: true,
 		},
 		{
-			name:    "invalid key_rotation_interval returns error",
-			yaml:    "key_rotation_interval: \"notaduration\"\n",
+			name:    "short MACAROON_ROOT_KEY returns error",
+			yaml:    minimalYAML,
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "MACAROON_ROOT_KEY": "deadbeef"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported signing_algorithm returns error",
+			yaml:    "signing_algorithm: \"HS256\"\n",
 			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
 			wantErr: true,
 		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			var cfgPath string
-			if tc.yaml == "" {
-				cfgPath = filepath.Join(t.TempDir(), "nonexistent.yaml")
-			} else {
-				cfgPath = writeConfigFile(t, tc.yaml)
-			}
-			t.Setenv("CONFIG_FILE", cfgPath)
-
-			for k, v := range tc.env {
-				t.Setenv(k, v)
-			}
-
-			cfg, err := loadConfig()
-			if tc.wantErr {
-				if err == nil {
-					t.Fatal("expected error, got nil")
+		{
+			name:    "negative mint_timeout returns error",
+			yaml:    "mint_timeout: \"-1s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative max_outstanding_tokens returns error",
+			yaml:    "max_outstanding_tokens: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy_conflicts returns error",
+			yaml:    "policy_conflicts: \"first-wins\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative max_concurrent_exchanges returns error",
+			yaml:    "max_concurrent_exchanges: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "ServiceAccount token auth defaults its audience",
+			yaml: "auth_methods: [x509-svid, k8s-sa-token]\nkube_sa_token_trust_domain: cluster.local\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.AuthMethods) != 2 || cfg.AuthMethods[1] != authMethodSAToken || cfg.KubeSATokenTrustDomain.Name() != "cluster.local" {
+					t.Errorf("AuthMethods = %v, trust domain = %q", cfg.AuthMethods, cfg.KubeSATokenTrustDomain.Name())
 				}
-				return
-			}
-			if err != nil {
-				t.Fatalf("loadConfig: %v", err)
-			}
-			if tc.checkCfg != nil {
-				tc.checkCfg(t, cfg)
-			}
-		})
+				if len(cfg.KubeSATokenAudiences) != 1 || cfg.KubeSATokenAudiences[0] != defaultKubeSATokenAudience {
+					t.Errorf("KubeSATokenAudiences = %v, want [%s]", cfg.KubeSATokenAudiences, defaultKubeSATokenAudience)
+				}
+			},
+		},
+		{
+			name: "ServiceAccount token audiences",
+			yaml: "auth_methods: [k8s-sa-token]\nkube_sa_token_trust_domain: cluster.local\nkube_sa_token_audiences: [\"token-exchange\", \"vault\"]\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.KubeSATokenAudiences) != 2 || cfg.KubeSATokenAudiences[1] != "vault" {
+					t.Errorf("KubeSATokenAudiences = %v", cfg.KubeSATokenAudiences)
+				}
+			},
+		},
+		{
+			name:    "ServiceAccount token auth without trust domain returns error",
+			yaml:    "auth_methods: [k8s-sa-token]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "JWT-SVID auth with a JWKS bundle file",
+			yaml: "auth_methods: [x509-svid, jwt-svid]\njwt_svid_audiences: [\"spiffe://cluster.local/svid-exchange\"]\njwt_svid_bundle_trust_domain: cluster.local\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "JWT_SVID_BUNDLE_FILE": "/etc/svid-exchange/bundle.jwks"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.JWTSVIDAudiences) != 1 || cfg.JWTSVIDBundleFile != "/etc/svid-exchange/bundle.jwks" || cfg.JWTSVIDBundleTrustDomain.Name() != "cluster.local" {
+					t.Errorf("JWTSVIDAudiences = %v, bundle file = %q, trust domain = %q", cfg.JWTSVIDAudiences, cfg.JWTSVIDBundleFile, cfg.JWTSVIDBundleTrustDomain.Name())
+				}
+			},
+		},
+		{
+			name: "JWT-SVID auth defaults to the Workload API",
+			yaml: "auth_methods: [jwt-svid]\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.JWTSVIDBundleFile != "" || len(cfg.JWTSVIDAudiences) != 0 {
+					t.Errorf("JWTSVIDBundleFile = %q, JWTSVIDAudiences = %v; want both unset", cfg.JWTSVIDBundleFile, cfg.JWTSVIDAudiences)
+				}
+			},
+		},
+		{
+			name:    "JWT-SVID bundle file without trust domain returns error",
+			yaml:    "auth_methods: [jwt-svid]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "JWT_SVID_BUNDLE_FILE": "/etc/svid-exchange/bundle.jwks"},
+			wantErr: true,
+		},
+		{
+			name: "shared signing key secret",
+			yaml: "signing_key_secret: svid-exchange-signing-key\nsigning_key_secret_namespace: svid-exchange\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.SigningKeySecret != "svid-exchange-signing-key" || cfg.SigningKeySecretNamespace != "svid-exchange" {
+					t.Errorf("SigningKeySecret = %q in %q", cfg.SigningKeySecret, cfg.SigningKeySecretNamespace)
+				}
+			},
+		},
+		{
+			name:    "signing_key_secret without namespace returns error",
+			yaml:    "signing_key_secret: svid-exchange-signing-key\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "next signing key",
+			yaml: "next_signing_key_file: /etc/next.pem\nnext_signing_key_cutover: \"2026-11-02T09:00:00Z\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.NextSigningKeyFile != "/etc/next.pem" || !cfg.NextSigningKeyCutover.Equal(time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)) {
+					t.Errorf("next signing key = %q at %v", cfg.NextSigningKeyFile, cfg.NextSigningKeyCutover)
+				}
+			},
+		},
+		{
+			name:    "next signing key without cutover returns error",
+			yaml:    "next_signing_key_file: /etc/next.pem\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "next signing key with invalid cutover returns error",
+			yaml:    "next_signing_key_file: /etc/next.pem\nnext_signing_key_cutover: tomorrow\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "next signing key with rotation returns error",
+			yaml:    "next_signing_key_file: /etc/next.pem\nnext_signing_key_cutover: \"2026-11-02T09:00:00Z\"\nkey_rotation_interval: 24h\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "next signing key with shared keys returns error",
+			yaml:    "next_signing_key_file: /etc/next.pem\nnext_signing_key_cutover: \"2026-11-02T09:00:00Z\"\nsigning_key_secret: s\nsigning_key_secret_namespace: ns\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "leader election lease",
+			yaml: "leader_election_lease: svid-exchange\nleader_election_namespace: svid-exchange\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.LeaderElectionLease != "svid-exchange" || cfg.LeaderElectionNamespace != "svid-exchange" {
+					t.Errorf("LeaderElectionLease = %q in %q", cfg.LeaderElectionLease, cfg.LeaderElectionNamespace)
+				}
+			},
+		},
+		{
+			name:    "leader_election_lease without namespace returns error",
+			yaml:    "leader_election_lease: svid-exchange\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "decision cache defaults size",
+			yaml: "decision_cache_ttl: \"5s\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.DecisionCacheTTL != 5*time.Second || cfg.DecisionCacheSize != defaultDecisionCacheSize {
+					t.Errorf("DecisionCacheTTL = %v, DecisionCacheSize = %d", cfg.DecisionCacheTTL, cfg.DecisionCacheSize)
+				}
+			},
+		},
+		{
+			name:    "invalid decision_cache_ttl returns error",
+			yaml:    "decision_cache_ttl: \"-1s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative decision_cache_size returns error",
+			yaml:    "decision_cache_size: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "denial cache defaults max TTL",
+			yaml: "denial_cache_ttl: \"2s\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.DenialCacheTTL != 2*time.Second || cfg.DenialCacheMaxTTL != defaultDenialCacheMaxTTL {
+					t.Errorf("DenialCacheTTL = %v, DenialCacheMaxTTL = %v", cfg.DenialCacheTTL, cfg.DenialCacheMaxTTL)
+				}
+			},
+		},
+		{
+			name:    "denial_cache_ttl above max returns error",
+			yaml:    "denial_cache_ttl: \"2m\"\ndenial_cache_max_ttl: \"1m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid denial_cache_max_ttl returns error",
+			yaml:    "denial_cache_max_ttl: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "external authorizer defaults to a closed 1s timeout",
+			yaml: "external_authorizer_url: \"https://authz.internal/authorize\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.ExternalAuthorizerTimeout != time.Second || cfg.ExternalAuthorizerFailOpen || !cfg.ExternalAuthorizerSPIFFEID.IsZero() {
+					t.Errorf("external authorizer = %v, %v, %s; want 1s, closed, no SPIFFE ID", cfg.ExternalAuthorizerTimeout, cfg.ExternalAuthorizerFailOpen, cfg.ExternalAuthorizerSPIFFEID)
+				}
+			},
+		},
+		{
+			name:    "invalid slow_exchange_threshold returns error",
+			yaml:    "slow_exchange_threshold: \"-1s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero nonce_window returns error",
+			yaml:    "nonce_window: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "break_glass_max_duration without public key returns error",
+			yaml:    "break_glass_max_duration: \"30m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero break_glass_max_duration returns error",
+			yaml:    "break_glass_public_key: \"/tmp/bg.pub\"\nbreak_glass_max_duration: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "LOG_LEVEL and LOG_FORMAT override the file",
+			yaml: "log_level: \"warn\"\nlog_format: \"console\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_LEVEL": "debug", "LOG_FORMAT": "json"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.LogLevel != zerolog.DebugLevel || cfg.LogFormat != logFormatJSON {
+					t.Errorf("logging = %v, %q; want debug, json", cfg.LogLevel, cfg.LogFormat)
+				}
+			},
+		},
+		{
+			name:    "unknown log_level returns error",
+			yaml:    "log_level: \"verbose\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_LEVEL": ""},
+			wantErr: true,
+		},
+		{
+			name:    "LOG_LEVEL that silences errors returns error",
+			yaml:    minimalYAML,
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_LEVEL": "disabled"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown LOG_FORMAT returns error",
+			yaml:    minimalYAML,
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "LOG_FORMAT": "logfmt"},
+			wantErr: true,
+		},
+		{
+			name:    "negative log_debug_per_second returns error",
+			yaml:    "log_debug_per_second: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown redact_scopes returns error",
+			yaml:    "redact_scopes: \"mask\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "redact_spiffe_ids hash without REDACTION_HASH_KEY returns error",
+			yaml:    "redact_spiffe_ids: \"hash\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "REDACTION_HASH_KEY": ""},
+			wantErr: true,
+		},
+		{
+			name:    "short REDACTION_HASH_KEY returns error",
+			yaml:    "redact_scopes: \"hash\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "REDACTION_HASH_KEY": "abcd"},
+			wantErr: true,
+		},
+		{
+			name: "redact_spiffe_ids redact needs no key",
+			yaml: "redact_spiffe_ids: \"redact\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "REDACTION_HASH_KEY": ""},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.RedactSPIFFEIDs != audit.RedactRemove || cfg.RedactionHashKey != nil {
+					t.Errorf("redaction = %q with %d-byte key, want redact without a key", cfg.RedactSPIFFEIDs, len(cfg.RedactionHashKey))
+				}
+			},
+		},
+		{
+			name:    "zero grpc_keepalive_time returns error",
+			yaml:    "grpc_keepalive_time: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative grpc_max_connection_age returns error",
+			yaml:    "grpc_max_connection_age: \"-1m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed grpc_keepalive_min_time returns error",
+			yaml:    "grpc_keepalive_min_time: \"soon\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown grpc_compression returns error",
+			yaml:    "grpc_compression: \"zstd\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative grpc_write_buffer_size_kb returns error",
+			yaml:    "grpc_write_buffer_size_kb: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "grpc_initial_conn_window_size_kb below the HTTP/2 minimum returns error",
+			yaml:    "grpc_initial_conn_window_size_kb: 32\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative signing_workers returns error",
+			yaml:    "signing_workers: -2\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "signing_queue_size without signing_workers returns error",
+			yaml:    "signing_queue_size: 100\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative signing_queue_size returns error",
+			yaml:    "signing_workers: 4\nsigning_queue_size: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative drain_period returns error",
+			yaml:    "drain_period: \"-5s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero max_token_ttl returns error",
+			yaml:    "max_token_ttl: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "max_token_ttl above the limit returns error",
+			yaml:    "max_token_ttl: \"25h\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "fractional max_token_ttl returns error",
+			yaml:    "max_token_ttl: \"1.5s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative not_before_skew returns error",
+			yaml:    "not_before_skew: \"-1s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "not_before_skew above the limit returns error",
+			yaml:    "not_before_skew: \"10m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "external authorizer key without url returns error",
+			yaml:    "external_authorizer_failure_mode: \"open\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid external_authorizer_url returns error",
+			yaml:    "external_authorizer_url: \"ftp://authz\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "external_authorizer_spiffe_id with http url returns error",
+			yaml:    "external_authorizer_url: \"http://localhost:9000\"\nexternal_authorizer_spiffe_id: \"spiffe://td/authz\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "external_authorizer_timeout not below policy_eval_timeout returns error",
+			yaml:    "external_authorizer_url: \"grpc://authz:8443\"\nexternal_authorizer_timeout: \"2s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid external_authorizer_failure_mode returns error",
+			yaml:    "external_authorizer_url: \"grpc://authz:8443\"\nexternal_authorizer_failure_mode: \"maybe\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "audit sampling and levels",
+			yaml: "audit_grant_sample_rate: 0.1\naudit_levels:\n  denial: warn\n  anomaly: error\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AuditGrantSampleRate != 0.1 {
+					t.Errorf("AuditGrantSampleRate = %v, want 0.1", cfg.AuditGrantSampleRate)
+				}
+				if cfg.AuditLevels["denial"] != zerolog.WarnLevel || cfg.AuditLevels["anomaly"] != zerolog.ErrorLevel {
+					t.Errorf("AuditLevels = %v", cfg.AuditLevels)
+				}
+			},
+		},
+		{
+			name: "zero audit_grant_sample_rate drops every grant",
+			yaml: "audit_grant_sample_rate: 0\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AuditGrantSampleRate != 0 {
+					t.Errorf("AuditGrantSampleRate = %v, want 0", cfg.AuditGrantSampleRate)
+				}
+			},
+		},
+		{
+			name:    "audit_grant_sample_rate above 1 returns error",
+			yaml:    "audit_grant_sample_rate: 1.5\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "separate access and audit log outputs",
+			yaml: "grpc_access_log: true\naccess_log_output: /var/log/svid-exchange/access.log\naccess_log_sample_rate: 0.25\naudit_log_output: /var/log/svid-exchange/audit.log\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AccessLogOutput != "/var/log/svid-exchange/access.log" || cfg.AccessLogSampleRate != 0.25 {
+					t.Errorf("AccessLogOutput, AccessLogSampleRate = %q, %v", cfg.AccessLogOutput, cfg.AccessLogSampleRate)
+				}
+				if cfg.AuditLogOutput != "/var/log/svid-exchange/audit.log" {
+					t.Errorf("AuditLogOutput = %q", cfg.AuditLogOutput)
+				}
+			},
+		},
+		{
+			name: "log outputs default to stdout and the server log",
+			yaml: "grpc_access_log: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AuditLogOutput != logOutputStdout || cfg.AccessLogOutput != "" || cfg.AccessLogSampleRate != 1 {
+					t.Errorf("AuditLogOutput, AccessLogOutput, AccessLogSampleRate = %q, %q, %v", cfg.AuditLogOutput, cfg.AccessLogOutput, cfg.AccessLogSampleRate)
+				}
+			},
+		},
+		{
+			name:    "access log sharing the audit log output returns error",
+			yaml:    "grpc_access_log: true\naccess_log_output: stdout\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "access log sharing the audit log file returns error",
+			yaml:    "grpc_access_log: true\naccess_log_output: /var/log/./audit.log\naudit_log_output: /var/log/audit.log\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "access_log_output without grpc_access_log returns error",
+			yaml:    "access_log_output: stderr\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "access_log_sample_rate above 1 returns error",
+			yaml:    "grpc_access_log: true\naccess_log_sample_rate: 2\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "TLS session resumption defaults",
+			yaml: "grpc_access_log: false\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.GRPCTLSSessions != defaultGRPCTLSSessions {
+					t.Errorf("GRPCTLSSessions = %+v, want %+v", cfg.GRPCTLSSessions, defaultGRPCTLSSessions)
+				}
+			},
+		},
+		{
+			name: "TLS session resumption configured",
+			yaml: "grpc_tls_session_tickets: false\ngrpc_tls_session_ticket_lifetime: \"10m\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if want := (grpcTLSSessions{TicketLifetime: 10 * time.Minute}); cfg.GRPCTLSSessions != want {
+					t.Errorf("GRPCTLSSessions = %+v, want %+v", cfg.GRPCTLSSessions, want)
+				}
+			},
+		},
+		{
+			name:    "TLS session ticket lifetime beyond seven days returns error",
+			yaml:    "grpc_tls_session_ticket_lifetime: \"200h\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero TLS session ticket lifetime returns error",
+			yaml:    "grpc_tls_session_ticket_lifetime: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown audit_levels kind returns error",
+			yaml:    "audit_levels:\n  revocation: info\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "fatal audit level returns error",
+			yaml:    "audit_levels:\n  grant: fatal\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown auth method returns error",
+			yaml:    "auth_methods: [x509-svid, password]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate auth method returns error",
+			yaml:    "auth_methods: [x509-svid, x509-svid]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "x509_multi_uri_mode defaults to reject",
+			yaml: "grpc_addr: \":8080\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.X509MultiURI != spiffe.MultiURIReject {
+					t.Errorf("X509MultiURI = %q, want %q", cfg.X509MultiURI, spiffe.MultiURIReject)
+				}
+			},
+		},
+		{
+			name: "x509_multi_uri_mode trust-domain parsed",
+			yaml: "allowed_trust_domains: [cluster.local, partner.example]\nx509_multi_uri_mode: trust-domain\nx509_uri_trust_domain: cluster.local\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.X509MultiURI != spiffe.MultiURITrustDomain {
+					t.Errorf("X509MultiURI = %q, want %q", cfg.X509MultiURI, spiffe.MultiURITrustDomain)
+				}
+				if got := cfg.X509URITrustDomain.Name(); got != "cluster.local" {
+					t.Errorf("X509URITrustDomain = %q, want cluster.local", got)
+				}
+			},
+		},
+		{
+			name:    "unknown x509_multi_uri_mode returns error",
+			yaml:    "x509_multi_uri_mode: last\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "x509_multi_uri_mode trust-domain without a trust domain returns error",
+			yaml:    "x509_multi_uri_mode: trust-domain\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "x509_uri_trust_domain outside allowed_trust_domains returns error",
+			yaml:    "allowed_trust_domains: [cluster.local]\nx509_multi_uri_mode: trust-domain\nx509_uri_trust_domain: partner.example\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "target_registry static parsed",
+			yaml: "target_registry: static\ntarget_registry_ids: [\"spiffe://Cluster.Local/ns/default/sa/payment\"]\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.TargetRegistry != targetRegistryStatic {
+					t.Errorf("TargetRegistry = %q, want static", cfg.TargetRegistry)
+				}
+				if want := []string{"spiffe://cluster.local/ns/default/sa/payment"}; !slices.Equal(cfg.TargetIDs, want) {
+					t.Errorf("TargetIDs = %v, want %v", cfg.TargetIDs, want)
+				}
+			},
+		},
+		{
+			name: "target_registry kube parsed",
+			yaml: "target_registry: kube\ntarget_registry_trust_domain: cluster.local\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if got := cfg.TargetTrustDomain.Name(); got != "cluster.local" {
+					t.Errorf("TargetTrustDomain = %q, want cluster.local", got)
+				}
+			},
+		},
+		{
+			name: "target_registry spire defaults refresh",
+			yaml: "target_registry: spire\ntarget_registry_entries_file: /run/spire/entries.json\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.TargetEntriesFile != "/run/spire/entries.json" {
+					t.Errorf("TargetEntriesFile = %q", cfg.TargetEntriesFile)
+				}
+				if cfg.TargetRefresh != defaultTargetRefresh {
+					t.Errorf("TargetRefresh = %v, want %v", cfg.TargetRefresh, defaultTargetRefresh)
+				}
+			},
+		},
+		{
+			name:    "unknown target_registry returns error",
+			yaml:    "target_registry: dns\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "target_registry static without ids returns error",
+			yaml:    "target_registry: static\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid target_registry_ids entry returns error",
+			yaml:    "target_registry: static\ntarget_registry_ids: [\"https://payment\"]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "target_registry kube without trust domain returns error",
+			yaml:    "target_registry: kube\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "target_registry spire without entries file returns error",
+			yaml:    "target_registry: spire\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid target_registry_refresh returns error",
+			yaml:    "target_registry: spire\ntarget_registry_entries_file: e.json\ntarget_registry_refresh: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "target_registry_ids without static registry returns error",
+			yaml:    "target_registry_ids: [\"spiffe://cluster.local/payment\"]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "target_registry_entries_file without spire registry returns error",
+			yaml:    "target_registry: kube\ntarget_registry_trust_domain: cluster.local\ntarget_registry_entries_file: e.json\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid allowed_trust_domains entry returns error",
+			yaml:    "allowed_trust_domains: [\"Cluster Local\"]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative max_scope_length returns error",
+			yaml:    "max_scope_length: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "negative grpc_max_exchange_msg_size_kb returns error",
+			yaml:    "grpc_max_exchange_msg_size_kb: -1\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "grpc_max_exchange_msg_size_kb above grpc_max_recv_msg_size_kb returns error",
+			yaml:    "grpc_max_recv_msg_size_kb: 32\ngrpc_max_exchange_msg_size_kb: 64\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid exchange_queue_timeout returns error",
+			yaml:    "exchange_queue_timeout: \"-5ms\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero anomaly_denial_window returns error",
+			yaml:    "anomaly_denial_window: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "denial webhook with secret",
+			yaml: "denial_webhook_url: \"https://soc.example.com/hooks/svid\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"DENIAL_WEBHOOK_SECRET":  "s3cret",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.DenialWebhookURL != "https://soc.example.com/hooks/svid" || string(cfg.DenialWebhookSecret) != "s3cret" {
+					t.Errorf("denial webhook = %q, %q", cfg.DenialWebhookURL, cfg.DenialWebhookSecret)
+				}
+			},
+		},
+		{
+			name:    "denial webhook without secret returns error",
+			yaml:    "denial_webhook_url: \"https://soc.example.com/hooks/svid\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "relative denial webhook URL returns error",
+			yaml: "denial_webhook_url: \"/hooks/svid\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"DENIAL_WEBHOOK_SECRET":  "s3cret",
+			},
+			wantErr: true,
+		},
+		{
+			name: "approvals with webhook",
+			yaml: "approvals: true\napproval_timeout: \"2m\"\napproval_webhook_url: \"https://approvals.example.com/svid\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET":  "unix:///tmp/agent.sock",
+				"APPROVAL_WEBHOOK_SECRET": "s3cret",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.Approvals || cfg.ApprovalTimeout != 2*time.Minute {
+					t.Errorf("approvals = %t, %v; want enabled, 2m", cfg.Approvals, cfg.ApprovalTimeout)
+				}
+				if cfg.ApprovalWebhookURL != "https://approvals.example.com/svid" || string(cfg.ApprovalWebhookSecret) != "s3cret" {
+					t.Errorf("approval webhook = %q, %q", cfg.ApprovalWebhookURL, cfg.ApprovalWebhookSecret)
+				}
+			},
+		},
+		{
+			name: "approvals default timeout",
+			yaml: "approvals: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.Approvals || cfg.ApprovalTimeout != defaultApprovalTimeout || cfg.ApprovalWebhookURL != "" {
+					t.Errorf("approvals = %t, %v, %q; want enabled, %v, no webhook", cfg.Approvals, cfg.ApprovalTimeout, cfg.ApprovalWebhookURL, defaultApprovalTimeout)
+				}
+			},
+		},
+		{
+			name:    "approval_timeout without approvals returns error",
+			yaml:    "approval_timeout: \"2m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "zero approval_timeout returns error",
+			yaml:    "approvals: true\napproval_timeout: \"0s\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "approval webhook without secret returns error",
+			yaml:    "approvals: true\napproval_webhook_url: \"https://approvals.example.com/svid\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "approval webhook without approvals returns error",
+			yaml: "approval_webhook_url: \"https://approvals.example.com/svid\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET":  "unix:///tmp/agent.sock",
+				"APPROVAL_WEBHOOK_SECRET": "s3cret",
+			},
+			wantErr: true,
+		},
+		{
+			name: "health listener TLS and endpoint auth",
+			yaml: "health_tls: true\ncors_allowed_origins: [\"https://app.example.com\"]\nhealth_endpoint_auth:\n  /metrics: mtls\n  /jwks: bearer\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"HEALTH_TLS_CERT":        "/tls/tls.crt",
+				"HEALTH_TLS_KEY":         "/tls/tls.key",
+				"HEALTH_TLS_CLIENT_CA":   "/tls/ca.crt",
+				"HEALTH_BEARER_TOKEN":    "s3cret",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				h := cfg.HTTPListeners[listenerHealth]
+				if h.CertFile != "/tls/tls.crt" || h.KeyFile != "/tls/tls.key" || h.ClientCAFile != "/tls/ca.crt" || h.ClientAuth != httpserv.ClientAuthOptional {
+					t.Errorf("health listener = %+v", h)
+				}
+				if cfg.HealthEndpointAuth["/metrics"] != httpserv.AuthMTLS || cfg.HealthBearerToken != "s3cret" {
+					t.Errorf("endpoint auth = %v, bearer = %q", cfg.HealthEndpointAuth, cfg.HealthBearerToken)
+				}
+				if len(cfg.CORSAllowedOrigins) != 1 || cfg.CORSAllowedOrigins[0] != "https://app.example.com" {
+					t.Errorf("CORSAllowedOrigins = %v", cfg.CORSAllowedOrigins)
+				}
+			},
+		},
+		{
+			name:    "health_tls without cert returns error",
+			yaml:    "health_tls: true\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "mtls endpoint auth without health_tls returns error",
+			yaml:    "health_endpoint_auth:\n  /metrics: mtls\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "HEALTH_TLS_CLIENT_CA": "/tls/ca.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "bearer endpoint auth without token returns error",
+			yaml:    "health_endpoint_auth:\n  /metrics: bearer\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown endpoint in health_endpoint_auth returns error",
+			yaml:    "health_endpoint_auth:\n  /debug: none\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "open introspection endpoint returns error",
+			yaml:    "health_endpoint_auth:\n  /introspect: none\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "introspection endpoint behind bearer auth",
+			yaml: "health_endpoint_auth:\n  /introspect: bearer\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "HEALTH_BEARER_TOKEN": "s3cret"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				if got := cfg.HealthEndpointAuth["/introspect"]; got != "bearer" {
+					t.Errorf("HealthEndpointAuth[/introspect] = %q, want bearer", got)
+				}
+			},
+		},
+		{
+			name:    "unknown auth mode returns error",
+			yaml:    "health_endpoint_auth:\n  /metrics: basic\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "separate metrics and keys listeners",
+			yaml: "http_listeners:\n  metrics:\n    addr: \":9100\"\n    tls: true\n    client_auth: require\n  keys:\n    addr: \":8443\"\n    tls: true\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"METRICS_TLS_CERT":       "/m/tls.crt",
+				"METRICS_TLS_KEY":        "/m/tls.key",
+				"METRICS_TLS_CLIENT_CA":  "/m/ca.crt",
+				"KEYS_TLS_CERT":          "/k/tls.crt",
+				"KEYS_TLS_KEY":           "/k/tls.key",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.HTTPListeners) != 3 {
+					t.Fatalf("HTTPListeners = %+v, want health, metrics, keys", cfg.HTTPListeners)
+				}
+				m := cfg.HTTPListeners[listenerMetrics]
+				if m.Addr != ":9100" || m.ClientAuth != httpserv.ClientAuthRequire || m.ClientCAFile != "/m/ca.crt" {
+					t.Errorf("metrics listener = %+v", m)
+				}
+				if k := cfg.HTTPListeners[listenerKeys]; k.Addr != ":8443" || !k.TLS() || k.VerifiesClients() {
+					t.Errorf("keys listener = %+v", k)
+				}
+				if h := cfg.HTTPListeners[listenerHealth]; h.Addr != defaultHealthAddr || h.TLS() {
+					t.Errorf("health listener = %+v", h)
+				}
+			},
+		},
+		{
+			name: "client CA refresh applies to verifying listeners",
+			yaml: "http_client_ca_refresh: \"1h\"\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n    tls: true\n    client_auth: require\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"METRICS_TLS_CERT":       "/m/tls.crt",
+				"METRICS_TLS_KEY":        "/m/tls.key",
+				"METRICS_TLS_CLIENT_CA":  "/m/ca.crt",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if got := cfg.HTTPListeners[listenerMetrics].ClientCARefresh; got != time.Hour {
+					t.Errorf("metrics ClientCARefresh = %v, want 1h", got)
+				}
+				if got := cfg.HTTPListeners[listenerHealth].ClientCARefresh; got != 0 {
+					t.Errorf("health ClientCARefresh = %v, want 0 without client auth", got)
+				}
+			},
+		},
+		{
+			name: "listeners bound to extra addresses",
+			yaml: "grpc_addr: \":8080\"\ngrpc_extra_addrs: [\"[::1]:8080\"]\nhealth_extra_addrs: [\"127.0.0.1:9081\"]\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n    extra_addrs: [\"10.0.0.1:9100\"]\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if len(cfg.GRPCExtraAddrs) != 1 || cfg.GRPCExtraAddrs[0] != "[::1]:8080" {
+					t.Errorf("GRPCExtraAddrs = %v", cfg.GRPCExtraAddrs)
+				}
+				if got := cfg.HTTPListeners[listenerHealth].ExtraAddrs; len(got) != 1 || got[0] != "127.0.0.1:9081" {
+					t.Errorf("health ExtraAddrs = %v", got)
+				}
+				if got := cfg.HTTPListeners[listenerMetrics].ExtraAddrs; len(got) != 1 || got[0] != "10.0.0.1:9100" {
+					t.Errorf("metrics ExtraAddrs = %v", got)
+				}
+			},
+		},
+		{
+			name:    "repeated grpc_extra_addrs returns error",
+			yaml:    "grpc_addr: \":8080\"\ngrpc_extra_addrs: [\":8080\"]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "extra address shared by two listeners returns error",
+			yaml:    "health_extra_addrs: [\":9100\"]\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "TLS material read from a directory",
+			yaml: "http_listeners:\n  metrics:\n    addr: \":9100\"\n    tls: true\n    client_auth: require\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"METRICS_TLS_DIR":        "/m",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				m := cfg.HTTPListeners[listenerMetrics]
+				if m.CertFile != "/m/tls.crt" || m.KeyFile != "/m/tls.key" || m.ClientCAFile != "/m/ca.crt" {
+					t.Errorf("metrics TLS files = %q/%q/%q", m.CertFile, m.KeyFile, m.ClientCAFile)
+				}
+				if m.CertRefresh != defaultTLSDirRefresh || m.ClientCARefresh != defaultTLSDirRefresh {
+					t.Errorf("metrics refresh = %v/%v, want %v", m.CertRefresh, m.ClientCARefresh, defaultTLSDirRefresh)
+				}
+			},
+		},
+		{
+			name: "explicit refresh and client CA override a TLS directory",
+			yaml: "tls_cert_refresh: \"0s\"\nhttp_client_ca_refresh: \"1h\"\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n    tls: true\n    client_auth: require\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"METRICS_TLS_DIR":        "/m",
+				"METRICS_TLS_CLIENT_CA":  "/ca/bundle.pem",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				m := cfg.HTTPListeners[listenerMetrics]
+				if m.ClientCAFile != "/ca/bundle.pem" {
+					t.Errorf("ClientCAFile = %q, want /ca/bundle.pem", m.ClientCAFile)
+				}
+				if m.CertRefresh != 0 || m.ClientCARefresh != time.Hour {
+					t.Errorf("metrics refresh = %v/%v, want 0s/1h", m.CertRefresh, m.ClientCARefresh)
+				}
+			},
+		},
+		{
+			name:    "TLS directory combined with a certificate path returns error",
+			yaml:    "health_tls: true\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "HEALTH_TLS_DIR": "/h", "HEALTH_TLS_CERT": "/h/tls.crt"},
+			wantErr: true,
+		},
+		{
+			name: "webhook TLS material read from a directory",
+			yaml: "kube_webhook_addr: \":8443\"\n",
+			env: map[string]string{
+				"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock",
+				"WEBHOOK_TLS_DIR":        "/w",
+			},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.KubeWebhookCertFile != "/w/tls.crt" || cfg.KubeWebhookKeyFile != "/w/tls.key" {
+					t.Errorf("webhook TLS files = %q/%q", cfg.KubeWebhookCertFile, cfg.KubeWebhookKeyFile)
+				}
+				if cfg.KubeWebhookCertRefresh != defaultTLSDirRefresh {
+					t.Errorf("KubeWebhookCertRefresh = %v, want %v", cfg.KubeWebhookCertRefresh, defaultTLSDirRefresh)
+				}
+			},
+		},
+		{
+			name:    "invalid tls_cert_refresh returns error",
+			yaml:    "tls_cert_refresh: \"soon\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid http_client_ca_refresh returns error",
+			yaml:    "http_client_ca_refresh: \"-1m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown http listener returns error",
+			yaml:    "http_listeners:\n  debug:\n    addr: \":6060\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "listeners sharing an address return error",
+			yaml:    "health_addr: \":9100\"\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "mtls endpoint on a listener without client auth returns error",
+			yaml:    "health_endpoint_auth:\n  /metrics: mtls\nhttp_listeners:\n  metrics:\n    addr: \":9100\"\n    tls: true\n    client_auth: none\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "METRICS_TLS_CERT": "/m/tls.crt", "METRICS_TLS_KEY": "/m/tls.key"},
+			wantErr: true,
+		},
+		{
+			name: "loopback debug listener",
+			yaml: "debug_addr: \"127.0.0.1:6060\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.DebugAddr != "127.0.0.1:6060" || cfg.DebugAllowRemote {
+					t.Errorf("debug = %q, allow remote %v", cfg.DebugAddr, cfg.DebugAllowRemote)
+				}
+			},
+		},
+		{
+			name:    "non-loopback debug listener returns error",
+			yaml:    "debug_addr: \":6060\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "non-loopback debug listener with debug_allow_remote",
+			yaml: "debug_addr: \":6060\"\ndebug_allow_remote: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.DebugAddr != ":6060" || !cfg.DebugAllowRemote {
+					t.Errorf("debug = %q, allow remote %v", cfg.DebugAddr, cfg.DebugAllowRemote)
+				}
+			},
+		},
+		{
+			name:    "debug listener sharing the health address returns error",
+			yaml:    "health_addr: \"127.0.0.1:9100\"\ndebug_addr: \"127.0.0.1:9100\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "REST gateway address",
+			yaml: "rest_gateway_addr: \":8443\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.RESTGatewayAddr != ":8443" {
+					t.Errorf("RESTGatewayAddr = %q, want :8443", cfg.RESTGatewayAddr)
+				}
+			},
+		},
+		{
+			name:    "REST gateway sharing the admin address returns error",
+			yaml:    "admin_addr: \":9443\"\nrest_gateway_addr: \":9443\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "REST gateway sharing the health address returns error",
+			yaml:    "health_addr: \":9100\"\nrest_gateway_addr: \":9100\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "Connect address",
+			yaml: "connect_addr: \":8444\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.ConnectAddr != ":8444" {
+					t.Errorf("ConnectAddr = %q, want :8444", cfg.ConnectAddr)
+				}
+			},
+		},
+		{
+			name:    "Connect sharing the REST gateway address returns error",
+			yaml:    "rest_gateway_addr: \":8443\"\nconnect_addr: \":8443\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "Connect sharing the gRPC address returns error",
+			yaml:    "grpc_addr: \":8080\"\nconnect_addr: \":8080\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "bundle endpoint defaults to https_spiffe",
+			yaml: "bundle_endpoint_addr: \":8445\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.BundleEndpointAddr != ":8445" || cfg.BundleEndpointProfile != bundleProfileSPIFFE {
+					t.Errorf("bundle endpoint = %q %q, want :8445 https_spiffe", cfg.BundleEndpointAddr, cfg.BundleEndpointProfile)
+				}
+			},
+		},
+		{
+			name: "https_web bundle endpoint reads BUNDLE_TLS_DIR",
+			yaml: "bundle_endpoint_addr: \":8445\"\nbundle_endpoint_profile: https_web\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "BUNDLE_TLS_DIR": "/b"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.BundleCertFile != "/b/tls.crt" || cfg.BundleKeyFile != "/b/tls.key" {
+					t.Errorf("bundle TLS files = %q/%q", cfg.BundleCertFile, cfg.BundleKeyFile)
+				}
+				if cfg.BundleCertRefresh <= 0 {
+					t.Errorf("BundleCertRefresh = %v, want a refresh interval for a TLS directory", cfg.BundleCertRefresh)
+				}
+			},
+		},
+		{
+			name:    "https_web bundle endpoint without a certificate returns error",
+			yaml:    "bundle_endpoint_addr: \":8445\"\nbundle_endpoint_profile: https_web\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown bundle_endpoint_profile returns error",
+			yaml:    "bundle_endpoint_addr: \":8445\"\nbundle_endpoint_profile: https\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "bundle_endpoint_profile without an address returns error",
+			yaml:    "bundle_endpoint_profile: https_spiffe\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "bundle endpoint sharing the Connect address returns error",
+			yaml:    "connect_addr: \":8444\"\nbundle_endpoint_addr: \":8444\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "issuance statistics defaults",
+			yaml: "issuance_stats: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.IssuanceStats || cfg.IssuanceStatsRetention != 7*24*time.Hour {
+					t.Errorf("IssuanceStats, retention = %v, %v; want true, 168h", cfg.IssuanceStats, cfg.IssuanceStatsRetention)
+				}
+				if cfg.IssuanceReportInterval != 24*time.Hour || cfg.IssuanceReportFormat != "json" {
+					t.Errorf("report interval, format = %v, %q; want 24h, json", cfg.IssuanceReportInterval, cfg.IssuanceReportFormat)
+				}
+			},
+		},
+		{
+			name: "scheduled issuance reports",
+			yaml: "issuance_stats: true\nissuance_stats_retention: \"720h\"\nissuance_report_dir: \"/var/lib/svid-exchange/reports\"\nissuance_report_interval: \"168h\"\nissuance_report_format: \"csv\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.IssuanceReportDir != "/var/lib/svid-exchange/reports" || cfg.IssuanceReportInterval != 168*time.Hour || cfg.IssuanceReportFormat != "csv" {
+					t.Errorf("report dir, interval, format = %q, %v, %q", cfg.IssuanceReportDir, cfg.IssuanceReportInterval, cfg.IssuanceReportFormat)
+				}
+			},
+		},
+		{
+			name:    "issuance report directory without statistics returns error",
+			yaml:    "issuance_report_dir: \"/tmp/reports\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "issuance report interval beyond retention returns error",
+			yaml:    "issuance_stats: true\nissuance_report_dir: \"/tmp/reports\"\nissuance_report_interval: \"240h\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "issuance retention below an hour returns error",
+			yaml:    "issuance_stats: true\nissuance_stats_retention: \"30m\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown issuance report format returns error",
+			yaml:    "issuance_stats: true\nissuance_report_format: \"xml\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "rule usage tracking",
+			yaml: "rule_usage_tracking: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.RuleUsageTracking {
+					t.Error("RuleUsageTracking = false, want true")
+				}
+			},
+		},
+		{
+			name: "token reuse detection",
+			yaml: "ext_authz: true\ntoken_reuse_detection: true\ntoken_reuse_revoke: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.TokenReuseDetection || !cfg.TokenReuseRevoke {
+					t.Errorf("token reuse = %v, %v; want true, true", cfg.TokenReuseDetection, cfg.TokenReuseRevoke)
+				}
+			},
+		},
+		{
+			name: "token reuse detection on introspection",
+			yaml: "token_reuse_detection: true\nhealth_endpoint_auth:\n  /introspect: bearer\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock", "HEALTH_BEARER_TOKEN": "s3cret"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.TokenReuseDetection {
+					t.Error("TokenReuseDetection = false, want true")
+				}
+			},
+		},
+		{
+			name:    "token reuse detection without ext_authz or introspection returns error",
+			yaml:    "token_reuse_detection: true\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "token reuse revoke without detection returns error",
+			yaml:    "ext_authz: true\ntoken_reuse_revoke: true\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "audit proto file",
+			yaml: "audit_proto_file: \"/var/log/svid-exchange/audit.binpb\"\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AuditProtoFile != "/var/log/svid-exchange/audit.binpb" {
+					t.Errorf("AuditProtoFile = %q", cfg.AuditProtoFile)
+				}
+			},
+		},
+		{
+			name: "audit spool",
+			yaml: "audit_spool_dir: \"/var/lib/svid-exchange/audit-spool\"\naudit_spool_required: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.AuditSpoolDir != "/var/lib/svid-exchange/audit-spool" || !cfg.AuditSpoolRequired {
+					t.Errorf("audit spool = %q, %v", cfg.AuditSpoolDir, cfg.AuditSpoolRequired)
+				}
+			},
+		},
+		{
+			name:    "audit spool required without directory returns error",
+			yaml:    "audit_spool_required: true\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "audit metadata keys",
+			yaml: "audit_metadata_keys: [X-Client-Version, x-deployment-id]\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !slices.Equal(cfg.AuditMetadataKeys, []string{"x-client-version", "x-deployment-id"}) {
+					t.Errorf("AuditMetadataKeys = %v", cfg.AuditMetadataKeys)
+				}
+			},
+		},
+		{
+			name:    "audit metadata key carrying credentials returns error",
+			yaml:    "audit_metadata_keys: [Authorization]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name:    "binary audit metadata key returns error",
+			yaml:    "audit_metadata_keys: [x-trace-bin]\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+		{
+			name: "token source binding",
+			yaml: "token_source_binding: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.TokenSourceBinding {
+					t.Error("TokenSourceBinding = false, want true")
+				}
+			},
+		},
+		{
+			name: "ttl from deadline",
+			yaml: "ttl_from_deadline: true\n",
+			env:  map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			checkCfg: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.TTLFromDeadline {
+					t.Error("TTLFromDeadline = false, want true")
+				}
+			},
+		},
+		{
+			name:    "invalid key_rotation_interval returns error",
+			yaml:    "key_rotation_interval: \"notaduration\"\n",
+			env:     map[string]string{"SPIFFE_ENDPOINT_SOCKET": "unix:///tmp/agent.sock"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfgPath string
+			if tc.yaml == "" {
+				cfgPath = filepath.Join(t.TempDir(), "nonexistent.yaml")
+			} else {
+				cfgPath = writeConfigFile(t, tc.yaml)
+			}
+			t.Setenv("CONFIG_FILE", cfgPath)
+
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := loadConfig()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if tc.checkCfg != nil {
+				tc.checkCfg(t, cfg)
+			}
+		})
+	}
+}
+
+// TestShippedConfigLoads checks that the sample config/server.yaml loads
+// with only the required environment set.
+func TestShippedConfigLoads(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join("..", "..", "config", "server.yaml"))
+	t.Setenv("SPIFFE_ENDPOINT_SOCKET", "unix:///tmp/agent.sock")
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig: %v", err)
 	}
 }
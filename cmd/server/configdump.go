@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// redactedValue replaces a secret in the effective configuration dump.
+const redactedValue = "[REDACTED]"
+
+// secretConfigFields names the Config fields that are never logged or
+// served. A set secret is shown as redactedValue and an unset one as "", so
+// operators can still tell whether it is configured.
+var secretConfigFields = map[string]bool{
+	"HealthBearerToken":     true,
+	"DenialWebhookSecret":   true,
+	"ApprovalWebhookSecret": true,
+	"AuditHMACKey":          true,
+	"MacaroonRootKey":       true,
+	"RedactionHashKey":      true,
+}
+
+// effectiveConfig returns cfg keyed by Config field name with secrets
+// redacted, for the startup log and /debug/config. Durations are rendered
+// as strings ("30s") and credentials embedded in the webhook URLs are
+// masked.
+func effectiveConfig(cfg Config) map[string]any {
+	v := reflect.ValueOf(cfg)
+	out := make(map[string]any, v.NumField())
+	for i := range v.NumField() {
+		name, f := v.Type().Field(i).Name, v.Field(i)
+		switch {
+		case secretConfigFields[name]:
+			out[name] = ""
+			if f.Len() > 0 {
+				out[name] = redactedValue
+			}
+		case f.Type() == reflect.TypeFor[time.Duration]():
+			out[name] = time.Duration(f.Int()).String()
+		default:
+			out[name] = f.Interface()
+		}
+	}
+	for name, raw := range map[string]string{"DenialWebhookURL": cfg.DenialWebhookURL, "ApprovalWebhookURL": cfg.ApprovalWebhookURL} {
+		if raw == "" {
+			continue
+		}
+		out[name] = redactedValue
+		if u, err := url.Parse(raw); err == nil {
+			out[name] = u.Redacted()
+		}
+	}
+	return out
+}
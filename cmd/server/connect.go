@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/ngaddam369/svid-exchange/internal/connect"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// newConnectHandler returns the handler serving exchange.v1 and exchange.v2
+// over the Connect protocol, at the same paths as their gRPC methods. It
+// shares the gRPC service implementations and runs every call through
+// unary, the data plane's interceptor chain, with the caller identified
+// from its client certificate.
+func newConnectHandler(v1 exchangev1.TokenExchangeServer, v2 exchangev2.TokenExchangeServer, unary grpc.UnaryServerInterceptor) http.Handler {
+	h := connect.New(unary)
+	exchangev1.RegisterTokenExchangeServer(h, v1)
+	exchangev2.RegisterTokenExchangeServer(h, v2)
+	return withTLSPeer(h)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// peerWhoAmI reports the caller's URI SAN from the gRPC peer.
+type peerWhoAmI struct {
+	exchangev2.UnimplementedTokenExchangeServer
+}
+
+func (peerWhoAmI) WhoAmI(ctx context.Context, _ *exchangev2.WhoAmIRequest) (*exchangev2.WhoAmIResponse, error) {
+	p, _ := peer.FromContext(ctx)
+	ti, _ := p.AuthInfo.(credentials.TLSInfo)
+	return &exchangev2.WhoAmIResponse{SpiffeId: ti.State.PeerCertificates[0].URIs[0].String(), AuthMethod: "x509-svid"}, nil
+}
+
+func TestConnectHandler(t *testing.T) {
+	caller, _ := url.Parse("spiffe://cluster.local/ns/default/sa/order")
+	callerState := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{URIs: []*url.URL{caller}}}}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		tls        *tls.ConnectionState
+		wantStatus int
+		wantField  string
+		wantValue  string
+	}{
+		{
+			name:       "v1 Exchange",
+			path:       exchangev1.TokenExchange_Exchange_FullMethodName,
+			body:       `{"targetService":"spiffe://cluster.local/ns/default/sa/payment"}`,
+			tls:        callerState,
+			wantStatus: http.StatusOK,
+			wantField:  "token",
+			wantValue:  "tok-for-spiffe://cluster.local/ns/default/sa/payment",
+		},
+		{
+			name:       "v2 WhoAmI sees the client certificate",
+			path:       exchangev2.TokenExchange_WhoAmI_FullMethodName,
+			body:       `{}`,
+			tls:        callerState,
+			wantStatus: http.StatusOK,
+			wantField:  "spiffeId",
+			wantValue:  caller.String(),
+		},
+		{
+			name:       "no client certificate",
+			path:       exchangev1.TokenExchange_Exchange_FullMethodName,
+			body:       `{}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				calls++
+				return handler(ctx, req)
+			}
+			h := newConnectHandler(echoExchange{}, peerWhoAmI{}, unary)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.TLS = tc.tls
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantField == "" {
+				if calls != 0 {
+					t.Error("request without a client certificate reached the interceptor chain")
+				}
+				return
+			}
+			if calls != 1 {
+				t.Errorf("interceptor ran %d times, want 1", calls)
+			}
+			var resp map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response %s: %v", rec.Body, err)
+			}
+			if resp[tc.wantField] != tc.wantValue {
+				t.Errorf("%s = %v, want %s", tc.wantField, resp[tc.wantField], tc.wantValue)
+			}
+		})
+	}
+}
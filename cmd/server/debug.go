@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// debugWriteTimeout lets /debug/pprof/profile and /debug/pprof/trace run
+// for up to a minute; pprof rejects durations past the server's deadline.
+const debugWriteTimeout = 70 * time.Second
+
+// runtimeStats is the /debug/runtime document.
+type runtimeStats struct {
+	GoVersion     string  `json:"go_version"`
+	GOMAXPROCS    int     `json:"gomaxprocs"`
+	NumCPU        int     `json:"num_cpu"`
+	Goroutines    int     `json:"goroutines"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	HeapAlloc     uint64  `json:"heap_alloc_bytes"`
+	HeapInuse     uint64  `json:"heap_inuse_bytes"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	Sys           uint64  `json:"sys_bytes"`
+	NumGC         uint32  `json:"num_gc"`
+	PauseTotalNs  uint64  `json:"gc_pause_total_ns"`
+	LastGC        string  `json:"last_gc,omitempty"`
+}
+
+// newDebugMux returns the handlers served on debug_addr: the standard
+// net/http/pprof endpoints under /debug/pprof/, a JSON runtime summary at
+// /debug/runtime, and the redacted effective configuration at
+// /debug/config. They are registered explicitly rather than through
+// pprof's init side effect on http.DefaultServeMux.
+func newDebugMux(started time.Time, config map[string]any, log zerolog.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, _ *http.Request) {
+		body, err := json.Marshal(config)
+		if err != nil {
+			log.Error().Err(err).Msg("debug config: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("debug config: write response")
+		}
+	})
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, _ *http.Request) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		stats := runtimeStats{
+			GoVersion:     runtime.Version(),
+			GOMAXPROCS:    runtime.GOMAXPROCS(0),
+			NumCPU:        runtime.NumCPU(),
+			Goroutines:    runtime.NumGoroutine(),
+			UptimeSeconds: time.Since(started).Seconds(),
+			HeapAlloc:     ms.HeapAlloc,
+			HeapInuse:     ms.HeapInuse,
+			HeapObjects:   ms.HeapObjects,
+			Sys:           ms.Sys,
+			NumGC:         ms.NumGC,
+			PauseTotalNs:  ms.PauseTotalNs,
+		}
+		if ms.LastGC > 0 {
+			stats.LastGC = time.Unix(0, int64(ms.LastGC)).UTC().Format(time.RFC3339Nano)
+		}
+		body, err := json.Marshal(stats)
+		if err != nil {
+			log.Error().Err(err).Msg("debug runtime: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("debug runtime: write response")
+		}
+	})
+	return mux
+}
+
+// checkDebugAddr returns an error unless addr binds only to a loopback
+// interface. An empty host (":6060") listens on every interface.
+func checkDebugAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid debug_addr %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("debug_addr %q is not a loopback address; set debug_allow_remote to expose it", addr)
+}
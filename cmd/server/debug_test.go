@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestCheckDebugAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "127.0.0.1:6060"},
+		{addr: "[::1]:6060"},
+		{addr: "localhost:6060"},
+		{addr: ":6060", wantErr: true},
+		{addr: "0.0.0.0:6060", wantErr: true},
+		{addr: "10.0.0.5:6060", wantErr: true},
+		{addr: "6060", wantErr: true},
+	}
+	for _, tc := range tests {
+		if err := checkDebugAddr(tc.addr); (err != nil) != tc.wantErr {
+			t.Errorf("checkDebugAddr(%q) error = %v, wantErr %v", tc.addr, err, tc.wantErr)
+		}
+	}
+}
+
+func TestDebugMux(t *testing.T) {
+	config := effectiveConfig(Config{PolicyFile: "config/policy.yaml", MacaroonRootKey: []byte("root-key")})
+	mux := newDebugMux(time.Now().Add(-time.Minute), config, zerolog.Nop())
+
+	t.Run("runtime stats", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var stats runtimeStats
+		if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if stats.Goroutines == 0 || stats.GOMAXPROCS == 0 || stats.UptimeSeconds < 60 || stats.GoVersion == "" {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("effective config", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if got["PolicyFile"] != "config/policy.yaml" || got["MacaroonRootKey"] != redactedValue {
+			t.Errorf("config = %v", got)
+		}
+	})
+
+	t.Run("pprof index and named profiles", func(t *testing.T) {
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/pprof/heap"} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET %s: status = %d, want 200", path, rec.Code)
+			}
+		}
+	})
+}
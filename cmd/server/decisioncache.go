@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// decisionCacheLookups counts decision cache lookups by outcome.
+var decisionCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_decision_cache_lookups_total",
+	Help: "Policy decision cache lookups, by outcome (result=hit|miss).",
+}, []string{"result"})
+
+// decisionKey identifies a request whose policy decision is cached. Scopes
+// are joined in request order, since the granted scopes preserve it.
+type decisionKey struct {
+	subject, target, scopes string
+	ttlSeconds              int32
+}
+
+type decisionEntry struct {
+	res     policy.EvalResult
+	expires time.Time
+}
+
+// decisionCache is a PolicyEvaluator that caches next's results for ttl, so
+// that a caller repeating the same exchange at high rate is evaluated once
+// per ttl. Only decisions are cached, never tokens; every request is still
+// authenticated, rate limited, minted, and audited. invalidate drops every
+// entry and must be called whenever the policy changes. Errors are not
+// cached, and neither are results that evaluated a policy condition: a
+// condition may depend on the time, which the key does not capture.
+type decisionCache struct {
+	next       server.PolicyEvaluator
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	gen     uint64 // incremented by invalidate
+	entries map[decisionKey]decisionEntry
+}
+
+// newDecisionCache returns a decisionCache in front of next holding at most
+// maxEntries decisions for ttl each.
+func newDecisionCache(next server.PolicyEvaluator, ttl time.Duration, maxEntries int) *decisionCache {
+	return &decisionCache{
+		next:       next,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[decisionKey]decisionEntry),
+	}
+}
+
+// Evaluate returns the cached decision for the request if one is fresh, and
+// otherwise evaluates it with next and caches the result.
+func (dc *decisionCache) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	key := decisionKey{subject: subject, target: target, scopes: strings.Join(scopes, "\x00"), ttlSeconds: ttlSeconds}
+	now := dc.now()
+
+	dc.mu.Lock()
+	e, ok := dc.entries[key]
+	gen := dc.gen
+	dc.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		decisionCacheLookups.WithLabelValues("hit").Inc()
+		return cloneEvalResult(e.res), nil
+	}
+	decisionCacheLookups.WithLabelValues("miss").Inc()
+
+	res, err := dc.next.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+	if err != nil || res.Conditional {
+		return res, err
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	// A result evaluated against a policy that was replaced meanwhile must
+	// not outlive the invalidation.
+	if gen != dc.gen {
+		return res, nil
+	}
+	if len(dc.entries) >= dc.maxEntries {
+		dc.evictExpired(now)
+	}
+	if len(dc.entries) < dc.maxEntries {
+		dc.entries[key] = decisionEntry{res: cloneEvalResult(res), expires: now.Add(dc.ttl)}
+	}
+	return res, nil
+}
+
+// invalidate drops every cached decision. Called on every policy swap.
+func (dc *decisionCache) invalidate() {
+	dc.mu.Lock()
+	dc.gen++
+	clear(dc.entries)
+	dc.mu.Unlock()
+}
+
+// evictExpired removes entries that expired by now. dc.mu must be held.
+func (dc *decisionCache) evictExpired(now time.Time) {
+	for k, e := range dc.entries {
+		if !now.Before(e.expires) {
+			delete(dc.entries, k)
+		}
+	}
+}
+
+// cloneEvalResult copies res so that a caller modifying its slices cannot
+// change a cached entry.
+func cloneEvalResult(res policy.EvalResult) policy.EvalResult {
+	res.GrantedScopes = slices.Clone(res.GrantedScopes)
+	res.MatchedRules = slices.Clone(res.MatchedRules)
+	return res
+}
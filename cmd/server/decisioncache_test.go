@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// countingEvaluator evaluates with a Loader and counts the calls.
+type countingEvaluator struct {
+	*atomicPolicy
+	calls int
+}
+
+func (c *countingEvaluator) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	c.calls++
+	return c.atomicPolicy.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+}
+
+func TestDecisionCache(t *testing.T) {
+	const (
+		sub = "spiffe://cluster.local/ns/default/sa/order"
+		tgt = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	scopes := []string{"payments:charge"}
+	newLoader := func(t *testing.T, maxTTL int32) *policy.Loader {
+		t.Helper()
+		l, err := policy.NewLoader([]policy.Policy{{Name: "order-to-payment", Subject: sub, Target: tgt, AllowedScopes: scopes, MaxTTL: maxTTL}})
+		if err != nil {
+			t.Fatalf("NewLoader: %v", err)
+		}
+		return l
+	}
+	setup := func(t *testing.T, maxEntries int) (*decisionCache, *countingEvaluator, *time.Time) {
+		t.Helper()
+		ap := newAtomicPolicy(newLoader(t, 300), zerolog.Nop())
+		next := &countingEvaluator{atomicPolicy: ap}
+		dc := newDecisionCache(next, time.Minute, maxEntries)
+		ap.notifySwap(dc.invalidate)
+		now := time.Now()
+		dc.now = func() time.Time { return now }
+		return dc, next, &now
+	}
+	eval := func(t *testing.T, dc *decisionCache, scopes []string) policy.EvalResult {
+		t.Helper()
+		res, err := dc.Evaluate(context.Background(), sub, tgt, scopes, 0)
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		return res
+	}
+
+	t.Run("repeated request is evaluated once", func(t *testing.T) {
+		dc, next, _ := setup(t, 10)
+		first := eval(t, dc, scopes)
+		first.GrantedScopes[0] = "tampered"
+		if res := eval(t, dc, scopes); !res.Allowed || res.GrantedScopes[0] != "payments:charge" {
+			t.Errorf("cached result = %+v", res)
+		}
+		eval(t, dc, []string{"payments:refund"})
+		if next.calls != 2 {
+			t.Errorf("evaluations = %d, want 2 (one per distinct request)", next.calls)
+		}
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		dc, next, now := setup(t, 10)
+		eval(t, dc, scopes)
+		*now = now.Add(time.Minute)
+		eval(t, dc, scopes)
+		if next.calls != 2 {
+			t.Errorf("evaluations = %d, want 2", next.calls)
+		}
+	})
+
+	t.Run("policy swap invalidates", func(t *testing.T) {
+		dc, next, _ := setup(t, 10)
+		eval(t, dc, scopes)
+		next.swap(newLoader(t, 60))
+		if res := eval(t, dc, scopes); res.GrantedTTL != 60 {
+			t.Errorf("GrantedTTL = %d after reload, want 60", res.GrantedTTL)
+		}
+		if next.calls != 2 {
+			t.Errorf("evaluations = %d, want 2", next.calls)
+		}
+	})
+
+	t.Run("full cache stops caching", func(t *testing.T) {
+		dc, next, _ := setup(t, 1)
+		eval(t, dc, scopes)
+		eval(t, dc, []string{"payments:refund"})
+		eval(t, dc, []string{"payments:refund"})
+		eval(t, dc, scopes)
+		if next.calls != 3 {
+			t.Errorf("evaluations = %d, want 3", next.calls)
+		}
+	})
+
+	t.Run("conditional decisions are not cached", func(t *testing.T) {
+		l, err := policy.NewLoader([]policy.Policy{{
+			Name:          "order-to-payment",
+			Subject:       sub,
+			Target:        tgt,
+			AllowedScopes: scopes,
+			MaxTTL:        300,
+			Condition:     `now > timestamp("2000-01-01T00:00:00Z")`,
+		}})
+		if err != nil {
+			t.Fatalf("NewLoader: %v", err)
+		}
+		next := &countingEvaluator{atomicPolicy: newAtomicPolicy(l, zerolog.Nop())}
+		dc := newDecisionCache(next, time.Minute, 10)
+		for range 2 {
+			if res := eval(t, dc, scopes); !res.Allowed {
+				t.Fatalf("result = %+v, want allowed", res)
+			}
+		}
+		if next.calls != 2 || len(dc.entries) != 0 {
+			t.Errorf("evaluations = %d, entries = %d; want 2 and 0", next.calls, len(dc.entries))
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		dc := newDecisionCache(failingEvaluator{err: errors.New("engine unavailable")}, time.Minute, 10)
+		for range 2 {
+			if _, err := dc.Evaluate(context.Background(), sub, tgt, scopes, 0); err == nil {
+				t.Fatal("Evaluate error = nil, want error")
+			}
+		}
+		if len(dc.entries) != 0 {
+			t.Errorf("entries = %d, want 0", len(dc.entries))
+		}
+	})
+}
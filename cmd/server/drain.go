@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// drainRetryAfter is advertised to exchanges refused while draining. The
+// caller should retry on another replica, which its load balancer finds once
+// this one reports not ready.
+const drainRetryAfter = time.Second
+
+// draining is 1 once the instance has started draining.
+var draining = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "svid_exchange_draining",
+	Help: "1 once the server has started draining before exit.",
+})
+
+// drainer takes the instance out of service ahead of exit, whether asked to
+// by the Drain admin RPC or by a shutdown signal. Once draining, new
+// exchanges are refused and main waits for period before stopping the
+// listeners, which lets in-flight calls finish.
+type drainer struct {
+	period time.Duration
+	now    func() time.Time
+
+	once    sync.Once
+	started chan struct{}
+	exitAt  time.Time // set once started is closed
+}
+
+// newDrainer returns a drainer that keeps the instance up for period after
+// draining starts.
+func newDrainer(period time.Duration) *drainer {
+	return &drainer{period: period, now: time.Now, started: make(chan struct{})}
+}
+
+// begin starts draining, if it has not started yet, and returns the time the
+// process will exit.
+func (d *drainer) begin() time.Time {
+	d.once.Do(func() {
+		d.exitAt = d.now().Add(d.period)
+		draining.Set(1)
+		close(d.started)
+	})
+	return d.exitAt
+}
+
+// Drain implements admin.Drainer.
+func (d *drainer) Drain(context.Context) (time.Time, error) {
+	return d.begin(), nil
+}
+
+// done is closed once draining starts.
+func (d *drainer) done() <-chan struct{} {
+	return d.started
+}
+
+// wait blocks until the drain period has passed or ctx is done. It must only
+// be called after begin.
+func (d *drainer) wait(ctx context.Context) {
+	t := time.NewTimer(d.exitAt.Sub(d.now()))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// interceptor returns a gRPC unary interceptor that refuses Exchange calls
+// once draining has started, with Unavailable, a retry-after header (whole
+// seconds), and a grpc-retry-pushback-ms trailer. Calls already past the
+// interceptor are unaffected, and other methods are not refused.
+func (d *drainer) interceptor() grpc.UnaryServerInterceptor {
+	retryAfter := strconv.Itoa(int(drainRetryAfter / time.Second))
+	pushback := strconv.FormatInt(drainRetryAfter.Milliseconds(), 10)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(exchangeMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		select {
+		case <-d.started:
+			// Best effort: SetHeader and SetTrailer only fail outside a
+			// real RPC.
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+			_ = grpc.SetTrailer(ctx, metadata.Pairs(server.RetryPushbackTrailer, pushback))
+			return nil, status.Error(codes.Unavailable, "server is draining, retry on another instance")
+		default:
+			return handler(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// trailerStream captures headers and trailers set by a handler.
+type trailerStream struct {
+	headerStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestDrainer(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := newDrainer(30 * time.Second)
+	d.now = func() time.Time { return now }
+	interceptor := d.interceptor()
+
+	if _, err := interceptor(context.Background(), nil, exchangeInfo, okHandler); err != nil {
+		t.Fatalf("exchange refused before draining: %v", err)
+	}
+	select {
+	case <-d.done():
+		t.Fatal("done closed before draining")
+	default:
+	}
+
+	exitAt, err := d.Drain(context.Background())
+	if err != nil || !exitAt.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("Drain = %v, %v; want %v", exitAt, err, now.Add(30*time.Second))
+	}
+	now = now.Add(10 * time.Second)
+	if again := d.begin(); !again.Equal(exitAt) {
+		t.Errorf("second begin moved the exit time to %v", again)
+	}
+	<-d.done()
+	if testutil.ToFloat64(draining) != 1 {
+		t.Error("draining gauge not set")
+	}
+
+	stream := &trailerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	_, err = interceptor(ctx, nil, exchangeInfo, okHandler)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("exchange while draining: got %v, want Unavailable", err)
+	}
+	if got := stream.header.Get("retry-after"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("retry-after = %v, want [1]", got)
+	}
+	if got := stream.trailer.Get(server.RetryPushbackTrailer); len(got) != 1 || got[0] != "1000" {
+		t.Errorf("%s = %v, want [1000]", server.RetryPushbackTrailer, got)
+	}
+
+	admin := &grpc.UnaryServerInfo{FullMethod: adminv1.PolicyAdmin_ListPolicies_FullMethodName}
+	if _, err := interceptor(context.Background(), nil, admin, okHandler); err != nil {
+		t.Errorf("non-exchange method refused while draining: %v", err)
+	}
+}
+
+func TestDrainerWait(t *testing.T) {
+	t.Run("returns after the drain period", func(t *testing.T) {
+		d := newDrainer(10 * time.Millisecond)
+		d.begin()
+		d.wait(context.Background())
+		if time.Now().Before(d.exitAt) {
+			t.Error("wait returned before the exit time")
+		}
+	})
+
+	t.Run("returns when ctx is done", func(t *testing.T) {
+		d := newDrainer(time.Hour)
+		d.begin()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		d.wait(ctx)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// Bundle endpoint profiles (SPIFFE Trust Domain and Bundle specification
+// §5.2). With https_spiffe the endpoint authenticates with the server's own
+// X.509-SVID; with https_web it presents a Web PKI certificate.
+const (
+	bundleProfileSPIFFE = "https_spiffe"
+	bundleProfileWeb    = "https_web"
+)
+
+// x509Source supplies the server's X.509-SVID and the X.509 authorities of
+// its trust domain, as the Workload API source does.
+type x509Source interface {
+	x509svid.Source
+	x509bundle.Source
+}
+
+// trustBundleSource builds the trust bundle of the server's trust domain:
+// the X.509 authorities it verifies client certificates against and its
+// active JWT-SVID signing keys.
+type trustBundleSource struct {
+	src         x509Source
+	keys        keyProvider
+	refreshHint time.Duration
+}
+
+// bundle returns the current trust bundle. It is rebuilt on every call so
+// CA and signing key rotations are published immediately.
+func (s trustBundleSource) bundle() (*spiffebundle.Bundle, error) {
+	svid, err := s.src.GetX509SVID()
+	if err != nil {
+		return nil, fmt.Errorf("get X.509-SVID: %w", err)
+	}
+	td := svid.ID.TrustDomain()
+	x509b, err := s.src.GetX509BundleForTrustDomain(td)
+	if err != nil {
+		return nil, fmt.Errorf("get X.509 bundle: %w", err)
+	}
+	b := spiffebundle.FromX509Bundle(x509b)
+	jwtKeys := make(map[string]crypto.PublicKey)
+	for _, pub := range s.keys.PublicKeys() {
+		if !token.SVIDCompatible(pub) {
+			continue
+		}
+		kid, err := token.KeyID(pub)
+		if err != nil {
+			return nil, fmt.Errorf("key ID: %w", err)
+		}
+		jwtKeys[kid] = pub
+	}
+	b.SetJWTAuthorities(jwtKeys)
+	b.SetRefreshHint(s.refreshHint)
+	return b, nil
+}
+
+// newTrustBundleHandler serves the trust bundle as a SPIFFE bundle endpoint,
+// so federated trust domains (for example SPIRE with a federates_with
+// relationship) can fetch and refresh it.
+func newTrustBundleHandler(s trustBundleSource, log zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := s.bundle()
+		if err != nil {
+			log.Error().Err(err).Msg("bundle endpoint: build bundle")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		body, err := b.Marshal()
+		if err != nil {
+			log.Error().Err(err).Msg("bundle endpoint: marshal bundle")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("bundle endpoint: write response")
+		}
+	}
+}
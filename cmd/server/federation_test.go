@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// staticX509Source is an x509Source with a fixed SVID and bundle.
+type staticX509Source struct {
+	svid   *x509svid.SVID
+	bundle *x509bundle.Bundle
+}
+
+func (s staticX509Source) GetX509SVID() (*x509svid.SVID, error) { return s.svid, nil }
+
+func (s staticX509Source) GetX509BundleForTrustDomain(spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	return s.bundle, nil
+}
+
+func testCACert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestTrustBundleHandler(t *testing.T) {
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+	ca := testCACert(t)
+	src := staticX509Source{
+		svid:   &x509svid.SVID{ID: spiffeid.RequireFromPath(td, "/ns/spire/sa/svid-exchange")},
+		bundle: x509bundle.FromX509Authorities(td, []*x509.Certificate{ca}),
+	}
+	m, err := token.NewMinterWithAlgorithm(token.ES256)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	h := newTrustBundleHandler(trustBundleSource{src: src, keys: m, refreshHint: time.Minute}, zerolog.Nop())
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	b, err := spiffebundle.Parse(td, rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("spiffebundle.Parse: %v", err)
+	}
+	if got := b.X509Authorities(); len(got) != 1 || !got[0].Equal(ca) {
+		t.Errorf("X.509 authorities = %d certificates, want the CA", len(got))
+	}
+	kid, err := token.KeyID(m.PublicKeys()[0])
+	if err != nil {
+		t.Fatalf("KeyID: %v", err)
+	}
+	if !b.HasJWTAuthority(kid) || len(b.JWTAuthorities()) != 1 {
+		t.Errorf("JWT authorities = %v, want only %s", b.JWTAuthorities(), kid)
+	}
+	if hint, ok := b.RefreshHint(); !ok || hint != time.Minute {
+		t.Errorf("refresh hint = %v (%v), want 1m", hint, ok)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
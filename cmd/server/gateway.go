@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+// restGatewayPath is the REST route for exchange.v1 Exchange, as mapped in
+// proto/exchange/v1/exchange_gateway.yaml.
+const restGatewayPath = "/v1/exchange"
+
+// newRESTGateway returns the grpc-gateway handler serving the REST+JSON
+// mapping of exchange.v1 (POST /v1/exchange). Every call runs through unary,
+// the data plane's interceptor chain, with the HTTPS connection presented as
+// the gRPC peer: the caller is identified from its client certificate, and
+// rate limits, load shedding, draining, metrics, and the access log apply
+// exactly as they do to a gRPC call.
+func newRESTGateway(ctx context.Context, svc exchangev1.TokenExchangeServer, unary grpc.UnaryServerInterceptor) (http.Handler, error) {
+	mux := runtime.NewServeMux()
+	if err := exchangev1.RegisterTokenExchangeHandlerServer(ctx, mux, gatewayExchange{svc: svc, unary: unary}); err != nil {
+		return nil, err
+	}
+	return withTLSPeer(mux), nil
+}
+
+// withTLSPeer presents each request's HTTPS connection to next as the gRPC
+// peer, so that the data plane's interceptors and handlers identify the
+// caller from its client certificate exactly as they would on a gRPC
+// connection. Requests without a client certificate are rejected.
+func withTLSPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		p := &peer.Peer{
+			Addr: gatewayAddr(r.RemoteAddr),
+			AuthInfo: credentials.TLSInfo{
+				State:          *r.TLS,
+				CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+			},
+		}
+		next.ServeHTTP(w, r.WithContext(peer.NewContext(r.Context(), p)))
+	})
+}
+
+// dataPlaneHTTPSTLS returns the TLS configuration for the data plane's HTTPS
+// listeners (the REST gateway and Connect): the data plane's SPIFFE mTLS
+// configuration, which requires and authorizes a client certificate,
+// offering HTTP/2 and HTTP/1.1.
+func dataPlaneHTTPSTLS(tc *tls.Config) *tls.Config {
+	out := tc.Clone()
+	out.NextProtos = []string{"h2", "http/1.1"}
+	return out
+}
+
+// gatewayExchange runs Exchange calls from the gateway through unary, which
+// the gRPC server would otherwise apply.
+type gatewayExchange struct {
+	exchangev1.UnimplementedTokenExchangeServer
+	svc   exchangev1.TokenExchangeServer
+	unary grpc.UnaryServerInterceptor
+}
+
+func (g gatewayExchange) Exchange(ctx context.Context, req *exchangev1.ExchangeRequest) (*exchangev1.ExchangeResponse, error) {
+	info := &grpc.UnaryServerInfo{Server: g.svc, FullMethod: exchangev1.TokenExchange_Exchange_FullMethodName}
+	resp, err := g.unary(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		return g.svc.Exchange(ctx, req.(*exchangev1.ExchangeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*exchangev1.ExchangeResponse), nil
+}
+
+// gatewayAddr is a gateway caller's remote address as a net.Addr.
+type gatewayAddr string
+
+func (gatewayAddr) Network() string  { return "tcp" }
+func (a gatewayAddr) String() string { return string(a) }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+// echoExchange grants whatever target it is asked for.
+type echoExchange struct {
+	exchangev1.UnimplementedTokenExchangeServer
+}
+
+func (echoExchange) Exchange(_ context.Context, req *exchangev1.ExchangeRequest) (*exchangev1.ExchangeResponse, error) {
+	return &exchangev1.ExchangeResponse{Token: "tok-for-" + req.GetTargetService(), ExpiresAt: 1700000000, GrantedScopes: req.GetScopes(), TokenId: "jti-1"}, nil
+}
+
+func TestRESTGateway(t *testing.T) {
+	caller, _ := url.Parse("spiffe://cluster.local/ns/default/sa/order")
+	callerState := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{URIs: []*url.URL{caller}}}}
+	const body = `{"targetService":"spiffe://cluster.local/ns/default/sa/payment","scopes":["payments:charge"],"ttlSeconds":60}`
+
+	tests := []struct {
+		name     string
+		tls      *tls.ConnectionState
+		deny     error
+		wantCode int
+		wantTok  string
+	}{
+		{name: "granted", tls: callerState, wantCode: http.StatusOK, wantTok: "tok-for-spiffe://cluster.local/ns/default/sa/payment"},
+		{name: "interceptor denial maps to HTTP status", tls: callerState, deny: status.Error(codes.ResourceExhausted, "rate limited"), wantCode: http.StatusTooManyRequests},
+		{name: "no client certificate", tls: &tls.ConnectionState{}, wantCode: http.StatusUnauthorized},
+		{name: "plain HTTP", wantCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotMethod, gotPeer string
+			unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				gotMethod = info.FullMethod
+				if p, ok := peer.FromContext(ctx); ok {
+					if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(ti.State.PeerCertificates) > 0 {
+						gotPeer = ti.State.PeerCertificates[0].URIs[0].String()
+					}
+				}
+				if tc.deny != nil {
+					return nil, tc.deny
+				}
+				return handler(ctx, req)
+			}
+			gw, err := newRESTGateway(context.Background(), echoExchange{}, unary)
+			if err != nil {
+				t.Fatalf("newRESTGateway: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, restGatewayPath, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.TLS = tc.tls
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.wantCode, rec.Body)
+			}
+			if tc.tls == nil || len(tc.tls.PeerCertificates) == 0 {
+				if gotMethod != "" {
+					t.Error("request without a client certificate reached the interceptor chain")
+				}
+				return
+			}
+			if gotMethod != exchangev1.TokenExchange_Exchange_FullMethodName || gotPeer != caller.String() {
+				t.Errorf("interceptor saw method %q, peer %q; want %q, %q", gotMethod, gotPeer, exchangev1.TokenExchange_Exchange_FullMethodName, caller)
+			}
+			if tc.wantTok == "" {
+				return
+			}
+			var resp struct {
+				Token         string   `json:"token"`
+				ExpiresAt     string   `json:"expiresAt"`
+				GrantedScopes []string `json:"grantedScopes"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response %s: %v", rec.Body, err)
+			}
+			if resp.Token != tc.wantTok || resp.ExpiresAt != "1700000000" || len(resp.GrantedScopes) != 1 {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestDataPlaneHTTPSTLS(t *testing.T) {
+	base := &tls.Config{MinVersion: tls.VersionTLS13, ClientAuth: tls.RequireAnyClientCert}
+	got := dataPlaneHTTPSTLS(base)
+	if got.ClientAuth != tls.RequireAnyClientCert || got.MinVersion != tls.VersionTLS13 {
+		t.Errorf("client auth %v, min version %x not kept from the data plane config", got.ClientAuth, got.MinVersion)
+	}
+	if len(got.NextProtos) != 2 || got.NextProtos[0] != "h2" || got.NextProtos[1] != "http/1.1" {
+		t.Errorf("NextProtos = %v, want [h2 http/1.1]", got.NextProtos)
+	}
+	if base.NextProtos != nil {
+		t.Error("dataPlaneHTTPSTLS modified the data plane config")
+	}
+}
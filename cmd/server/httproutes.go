@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/ngaddam369/svid-exchange/internal/httpserv"
+)
+
+// HTTP listener names. The health listener always runs on health_addr; the
+// others run only when configured under http_listeners, and their endpoints
+// fall back to the health listener otherwise.
+const (
+	listenerHealth  = "health"
+	listenerMetrics = "metrics"
+	listenerKeys    = "keys"
+)
+
+// introspectPath is the token introspection endpoint. It is served only when
+// health_endpoint_auth requires bearer or mtls for it.
+const introspectPath = "/introspect"
+
+// httpEndpoints maps every HTTP endpoint to the listener that serves it.
+var httpEndpoints = map[string]string{
+	"/health/live":     listenerHealth,
+	"/health/ready":    listenerHealth,
+	"/metrics":         listenerMetrics,
+	"/jwks":            listenerKeys,
+	introspectPath:     listenerKeys,
+	"/paseto-keys":     listenerKeys,
+	"/jwt-svid-bundle": listenerKeys,
+}
+
+// listenerFor returns the name of the listener configured to serve path.
+func listenerFor(listeners map[string]httpserv.Config, path string) string {
+	if name := httpEndpoints[path]; name != "" {
+		if _, ok := listeners[name]; ok {
+			return name
+		}
+	}
+	return listenerHealth
+}
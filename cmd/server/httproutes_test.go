@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/httpserv"
+)
+
+func TestListenerFor(t *testing.T) {
+	onlyHealth := map[string]httpserv.Config{listenerHealth: {}}
+	split := map[string]httpserv.Config{listenerHealth: {}, listenerMetrics: {}, listenerKeys: {}}
+
+	tests := []struct {
+		listeners map[string]httpserv.Config
+		path      string
+		want      string
+	}{
+		{listeners: onlyHealth, path: "/metrics", want: listenerHealth},
+		{listeners: onlyHealth, path: "/jwks", want: listenerHealth},
+		{listeners: split, path: "/metrics", want: listenerMetrics},
+		{listeners: split, path: "/jwks", want: listenerKeys},
+		{listeners: split, path: "/jwt-svid-bundle", want: listenerKeys},
+		{listeners: split, path: "/introspect", want: listenerKeys},
+		{listeners: split, path: "/health/ready", want: listenerHealth},
+	}
+	for _, tc := range tests {
+		if got := listenerFor(tc.listeners, tc.path); got != tc.want {
+			t.Errorf("listenerFor(%d listeners, %q) = %q, want %q", len(tc.listeners), tc.path, got, tc.want)
+		}
+	}
+}
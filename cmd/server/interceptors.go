@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// newRecoveryInterceptor returns a gRPC unary interceptor that turns a panic
+// in any later interceptor or the handler into an Internal error, logging
+// the panic value and stack to log. gRPC does not recover handler panics
+// itself, so without it one bad request takes down the whole process. Panics
+// on goroutines the handler starts are not covered.
+func newRecoveryInterceptor(log zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().Str("method", info.FullMethod).Str("panic", fmt.Sprint(r)).
+					Str("stack", string(debug.Stack())).Msg("recovered from panic in gRPC handler")
+				resp, err = nil, status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// newAccessLogInterceptor returns a gRPC unary interceptor that logs one
+// line per RPC with its method, status code, duration, peer address, and the
+// caller's SPIFFE ID when ext can extract one. Only a sampleRate fraction of
+// successful calls, chosen at random, is logged, each with its
+// "sample_rate"; failed calls always are. When enabled is false the
+// interceptor is a no-op pass-through.
+func newAccessLogInterceptor(enabled bool, log zerolog.Logger, sampleRate float64, ext server.IDExtractor) grpc.UnaryServerInterceptor {
+	if !enabled {
+		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(ctx, req)
+		}
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		sampled := code != codes.OK || sampleRate >= 1
+		if !sampled && rand.Float64() >= sampleRate {
+			return resp, err
+		}
+		ev := log.Info().
+			Str("event", "grpc.access").
+			Str("method", info.FullMethod).
+			Str("code", code.String()).
+			Dur("duration", time.Since(start))
+		if !sampled {
+			ev = ev.Float64("sample_rate", sampleRate)
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ev = ev.Str("peer_addr", p.Addr.String())
+		}
+		if id, idErr := ext.ExtractID(ctx); idErr == nil {
+			ev = ev.Str("caller", id)
+		}
+		ev.Send()
+		return resp, err
+	}
+}
+
+// exchangeMethods are the full method names of every version of Exchange.
+// Interceptors that single out the data-plane call match all of them.
+var exchangeMethods = []string{
+	exchangev1.TokenExchange_Exchange_FullMethodName,
+	exchangev2.TokenExchange_Exchange_FullMethodName,
+}
+
+// tokenAuthMethods are the methods open to callers authenticated by a token
+// rather than a client certificate: Exchange, WhoAmI so those callers can
+// check the identity their token resolves to, and the RPCs that manage a
+// caller's own tokens.
+var tokenAuthMethods = slices.Concat(exchangeMethods, []string{
+	exchangev2.TokenExchange_WhoAmI_FullMethodName,
+	exchangev2.TokenExchange_ListGrants_FullMethodName,
+	exchangev2.TokenExchange_RevokeGrant_FullMethodName,
+})
+
+// newRequestSizeInterceptor returns a gRPC unary interceptor that rejects a
+// call to one of methods whose request encodes to more than maxBytes with
+// ResourceExhausted, the code gRPC itself uses for oversized messages. It
+// gives those methods a tighter bound than the server-wide receive limit.
+// Other methods are not checked. When maxBytes ≤ 0 the interceptor is a
+// no-op pass-through.
+func newRequestSizeInterceptor(maxBytes int, methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if maxBytes <= 0 || !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if m, ok := req.(proto.Message); ok {
+			if n := proto.Size(m); n > maxBytes {
+				return nil, status.Errorf(codes.ResourceExhausted, "request is %d bytes, exceeds limit of %d", n, maxBytes)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
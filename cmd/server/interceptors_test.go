@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+func TestRecoveryInterceptor(t *testing.T) {
+	t.Run("panic becomes Internal and is logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		interceptor := newRecoveryInterceptor(zerolog.New(&buf))
+		resp, err := interceptor(context.Background(), nil, exchangeInfo, func(context.Context, any) (any, error) {
+			panic("nil map write")
+		})
+		if resp != nil || status.Code(err) != codes.Internal {
+			t.Fatalf("resp, err = %v, %v; want nil, Internal", resp, err)
+		}
+		if strings.Contains(status.Convert(err).Message(), "nil map write") {
+			t.Error("panic value leaked to the caller")
+		}
+		if !strings.Contains(buf.String(), "nil map write") || !strings.Contains(buf.String(), exchangeInfo.FullMethod) {
+			t.Errorf("log %q lacks the panic value or method", buf.String())
+		}
+	})
+
+	t.Run("normal calls pass through", func(t *testing.T) {
+		var buf bytes.Buffer
+		interceptor := newRecoveryInterceptor(zerolog.New(&buf))
+		resp, err := interceptor(context.Background(), nil, exchangeInfo, okHandler)
+		if err != nil || resp != "ok" {
+			t.Errorf("resp, err = %v, %v; want ok, nil", resp, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("unexpected log output: %s", buf.String())
+		}
+	})
+}
+
+func TestAccessLogInterceptor(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 40000}})
+	denied := func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.PermissionDenied, "no policy")
+	}
+
+	tests := []struct {
+		name       string
+		ext        *mockIDExtractor
+		handler    func(context.Context, any) (any, error)
+		wantFields map[string]string
+		absentKey  string
+	}{
+		{
+			name:    "logs method, code, peer, and caller",
+			ext:     &mockIDExtractor{id: adminSubjectA},
+			handler: okHandler,
+			wantFields: map[string]string{
+				"event":     "grpc.access",
+				"method":    exchangeInfo.FullMethod,
+				"code":      "OK",
+				"peer_addr": "10.0.0.7:40000",
+				"caller":    adminSubjectA,
+			},
+		},
+		{
+			name:       "failed call logs its code",
+			ext:        &mockIDExtractor{id: adminSubjectA},
+			handler:    denied,
+			wantFields: map[string]string{"code": "PermissionDenied"},
+		},
+		{
+			name:       "caller omitted without a SPIFFE ID",
+			ext:        &mockIDExtractor{err: errors.New("no peer certificate")},
+			handler:    okHandler,
+			wantFields: map[string]string{"code": "OK"},
+			absentKey:  "caller",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			interceptor := newAccessLogInterceptor(true, zerolog.New(&buf), 1, tc.ext)
+			_, _ = interceptor(ctx, nil, exchangeInfo, tc.handler)
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("output is not one JSON line: %v\noutput: %s", err, buf.String())
+			}
+			for k, want := range tc.wantFields {
+				if got := entry[k]; got != want {
+					t.Errorf("field %q = %v, want %q", k, got, want)
+				}
+			}
+			if _, ok := entry["duration"]; !ok {
+				t.Error("duration missing")
+			}
+			if _, ok := entry[tc.absentKey]; tc.absentKey != "" && ok {
+				t.Errorf("field %q should not be present", tc.absentKey)
+			}
+		})
+	}
+
+	t.Run("sampling drops successful calls only", func(t *testing.T) {
+		var buf bytes.Buffer
+		interceptor := newAccessLogInterceptor(true, zerolog.New(&buf), 0, &mockIDExtractor{})
+		_, _ = interceptor(ctx, nil, exchangeInfo, okHandler)
+		if buf.Len() != 0 {
+			t.Errorf("successful call logged at sample rate 0: %s", buf.String())
+		}
+		_, _ = interceptor(ctx, nil, exchangeInfo, func(context.Context, any) (any, error) {
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		})
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("failed call not logged: %v\noutput: %s", err, buf.String())
+		}
+		if entry["code"] != "PermissionDenied" {
+			t.Errorf("code = %v, want PermissionDenied", entry["code"])
+		}
+		if _, ok := entry["sample_rate"]; ok {
+			t.Error("sample_rate set on an unsampled line")
+		}
+	})
+
+	t.Run("sampled lines record the rate", func(t *testing.T) {
+		var buf bytes.Buffer
+		interceptor := newAccessLogInterceptor(true, zerolog.New(&buf), 0.5, &mockIDExtractor{})
+		for range 1000 {
+			_, _ = interceptor(ctx, nil, exchangeInfo, okHandler)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) < 100 || len(lines) > 900 {
+			t.Fatalf("%d of 1000 calls logged at sample rate 0.5", len(lines))
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+			t.Fatalf("line is not JSON: %v", err)
+		}
+		if entry["sample_rate"] != 0.5 {
+			t.Errorf("sample_rate = %v, want 0.5", entry["sample_rate"])
+		}
+	})
+
+	t.Run("disabled logs nothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		interceptor := newAccessLogInterceptor(false, zerolog.New(&buf), 1, &mockIDExtractor{})
+		if _, err := interceptor(ctx, nil, exchangeInfo, okHandler); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("unexpected log output: %s", buf.String())
+		}
+	})
+}
+
+func TestRequestSizeInterceptor(t *testing.T) {
+	small := &exchangev1.ExchangeRequest{TargetService: "spiffe://cluster.local/ns/default/sa/payment", Scopes: []string{"payments:charge"}}
+	large := &exchangev1.ExchangeRequest{TargetService: "spiffe://cluster.local/ns/default/sa/payment", OnBehalfOf: strings.Repeat("x", 2048)}
+
+	tests := []struct {
+		name     string
+		maxBytes int
+		method   string
+		req      any
+		wantCode codes.Code
+	}{
+		{name: "request within limit", maxBytes: 1024, method: exchangeInfo.FullMethod, req: small, wantCode: codes.OK},
+		{name: "oversized request rejected", maxBytes: 1024, method: exchangeInfo.FullMethod, req: large, wantCode: codes.ResourceExhausted},
+		{name: "other methods not checked", maxBytes: 1024, method: adminv1.PolicyAdmin_ListPolicies_FullMethodName, req: large, wantCode: codes.OK},
+		{name: "zero limit disables the check", maxBytes: 0, method: exchangeInfo.FullMethod, req: large, wantCode: codes.OK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			interceptor := newRequestSizeInterceptor(tc.maxBytes, tc.method)
+			_, err := interceptor(context.Background(), tc.req, exchangeInfo, okHandler)
+			if status.Code(err) != tc.wantCode {
+				t.Errorf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/extauthz"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// singleUseRefused counts presentations of single-use tokens that had
+// already been consumed, by the endpoint that refused them.
+var singleUseRefused = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_single_use_refused_total",
+	Help: "Presentations of single_use tokens refused because the token was already consumed, by endpoint (introspect or ext_authz).",
+}, []string{"endpoint"})
+
+// tokenConsumer records single-use token IDs as consumed; see
+// policy.Store.ConsumeToken.
+type tokenConsumer interface {
+	ConsumeToken(jti string, expiresAt int64) (bool, error)
+}
+
+// consumeFunc adapts c for the endpoint it serves, counting refused reuses
+// under that endpoint's label.
+func consumeFunc(c tokenConsumer, endpoint string) func(jti string, expiresAt time.Time) (bool, error) {
+	return func(jti string, expiresAt time.Time) (bool, error) {
+		ok, err := c.ConsumeToken(jti, expiresAt.Unix())
+		if err == nil && !ok {
+			singleUseRefused.WithLabelValues(endpoint).Inc()
+		}
+		return ok, err
+	}
+}
+
+// inactive is the RFC 7662 response for a token that is not active, whatever
+// the reason: the endpoint does not tell callers why.
+var inactive = []byte(`{"active":false}` + "\n")
+
+// newIntrospectionHandler returns an RFC 7662 token introspection endpoint
+// for JWT and JWT-SVID tokens signed with kp's keys. A token that verifies and
+// is not revoked is reported active with its claims; the caller checks the
+// audience. The first introspection of a single_use token consumes it, and
+// every later one reports it inactive. Other token formats are reported
+// inactive. observeKey receives the kid of every token whose signature
+// verifies, revoked or not. When reuse is non-nil, it is passed the caller
+// of every introspection of an unrevoked token as the token's source, and a
+// token it revokes is reported inactive.
+func newIntrospectionHandler(kp keyProvider, isRevoked func(jti string) bool, consume func(jti string, expiresAt time.Time) (bool, error), observeKey func(kid string), reuse *extauthz.ReuseDetector, log zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+		raw := r.PostFormValue("token")
+		if raw == "" {
+			http.Error(w, "token parameter is required", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		claims, err := token.VerifyClaims(raw, kp.PublicKeys(), "")
+		if err != nil {
+			_, _ = w.Write(inactive)
+			return
+		}
+		observeKey(token.HeaderKeyID(raw))
+		jti, _ := claims["jti"].(string)
+		if jti != "" && isRevoked(jti) {
+			_, _ = w.Write(inactive)
+			return
+		}
+		if reuse != nil && jti != "" {
+			sub, _ := claims["sub"].(string)
+			aud, _ := claims.GetAudience()
+			exp, _ := claims.GetExpirationTime()
+			revoked, err := reuse.Observe(extauthz.Reuse{JTI: jti, Subject: sub, Audience: strings.Join(aud, " "), ExpiresAt: exp.Time, Source: introspectionSource(r)})
+			if err != nil {
+				log.Error().Err(err).Str("jti", jti).Msg("introspect: record token source")
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				_, _ = w.Write(inactive)
+				return
+			}
+		}
+		if once, _ := claims[token.ClaimSingleUse].(bool); once {
+			exp, _ := claims.GetExpirationTime()
+			fresh, err := consume(jti, exp.Time)
+			if err != nil {
+				log.Error().Err(err).Str("jti", jti).Msg("introspect: consume single-use token")
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if !fresh {
+				_, _ = w.Write(inactive)
+				return
+			}
+		}
+
+		claims["active"] = true
+		body, err := json.Marshal(claims)
+		if err != nil {
+			log.Error().Err(err).Msg("introspect: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("introspect: write response")
+		}
+	}
+}
+
+// introspectionSource identifies the caller of the introspection endpoint:
+// the SPIFFE ID of its verified client certificate, or its IP address when
+// it presented none.
+func introspectionSource(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if leaf := r.TLS.VerifiedChains[0][0]; len(leaf.URIs) > 0 {
+			return leaf.URIs[0].String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/extauthz"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// memConsumer is an in-memory tokenConsumer.
+type memConsumer map[string]bool
+
+func (c memConsumer) ConsumeToken(jti string, _ int64) (bool, error) {
+	if c[jti] {
+		return false, nil
+	}
+	c[jti] = true
+	return true, nil
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	revoked := map[string]bool{}
+	h := newIntrospectionHandler(m, func(jti string) bool { return revoked[jti] }, consumeFunc(memConsumer{}, "introspect"), observeVerificationKey(m), nil, zerolog.Nop())
+
+	mint := func(ctx context.Context) token.MintResult {
+		t.Helper()
+		res, err := m.Mint(ctx, "spiffe://example.org/order", "spiffe://example.org/payment", []string{"payments:charge"}, 60, "")
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		return res
+	}
+	introspect := func(raw string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(url.Values{"token": {raw}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body
+	}
+
+	t.Run("valid token is active with its claims", func(t *testing.T) {
+		res := mint(context.Background())
+		before := testutil.ToFloat64(verificationKey.WithLabelValues("current"))
+		for range 2 {
+			body := introspect(res.Token)
+			if body["active"] != true || body["sub"] != "spiffe://example.org/order" || body["jti"] != res.TokenID {
+				t.Errorf("response = %v, want the active token's claims", body)
+			}
+		}
+		if got := testutil.ToFloat64(verificationKey.WithLabelValues("current")); got != before+2 {
+			t.Errorf("current key verifications = %v, want %v", got, before+2)
+		}
+	})
+
+	t.Run("single-use token is active once", func(t *testing.T) {
+		res := mint(token.WithSingleUse(context.Background()))
+		if body := introspect(res.Token); body["active"] != true {
+			t.Fatalf("first introspection = %v, want active", body)
+		}
+		before := testutil.ToFloat64(singleUseRefused.WithLabelValues("introspect"))
+		if body := introspect(res.Token); body["active"] != false || len(body) != 1 {
+			t.Errorf("second introspection = %v, want only active false", body)
+		}
+		if got := testutil.ToFloat64(singleUseRefused.WithLabelValues("introspect")); got != before+1 {
+			t.Errorf("refused counter = %v, want %v", got, before+1)
+		}
+	})
+
+	t.Run("revoked and foreign tokens are inactive", func(t *testing.T) {
+		res := mint(context.Background())
+		revoked[res.TokenID] = true
+		if body := introspect(res.Token); body["active"] != false {
+			t.Errorf("revoked token = %v, want inactive", body)
+		}
+		other, err := token.NewMinter()
+		if err != nil {
+			t.Fatalf("NewMinter: %v", err)
+		}
+		foreign, err := other.Mint(context.Background(), "spiffe://example.org/order", "spiffe://example.org/payment", []string{"payments:charge"}, 60, "")
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		if body := introspect(foreign.Token); body["active"] != false {
+			t.Errorf("foreign token = %v, want inactive", body)
+		}
+	})
+
+	t.Run("only POST with a token is accepted", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/introspect", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET status = %d, want 405", rec.Code)
+		}
+		rec = httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/introspect", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("empty POST status = %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestIntrospectionReuse(t *testing.T) {
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	store, err := policy.OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var reuses []extauthz.Reuse
+	revoke := false
+	// Two handlers sharing the store stand in for two replicas, or for one
+	// before and after a restart.
+	newHandler := func() http.HandlerFunc {
+		d := extauthz.NewReuseDetector(store, func(r extauthz.Reuse) bool {
+			reuses = append(reuses, r)
+			return revoke
+		})
+		return newIntrospectionHandler(m, func(string) bool { return false }, consumeFunc(memConsumer{}, "introspect"), func(string) {}, d, zerolog.Nop())
+	}
+	first, second := newHandler(), newHandler()
+
+	res, err := m.Mint(context.Background(), "spiffe://example.org/order", "spiffe://example.org/payment", []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	introspect := func(h http.HandlerFunc, addr string) bool {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(url.Values{"token": {res.Token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		var body map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body["active"] == true
+	}
+
+	if !introspect(first, "10.0.0.1:4000") || !introspect(second, "10.0.0.1:4001") || len(reuses) != 0 {
+		t.Fatalf("introspection from the first source: %d reuses, want none", len(reuses))
+	}
+	if !introspect(second, "10.0.0.2:4000") {
+		t.Error("reuse without revocation reported the token inactive")
+	}
+	if len(reuses) != 1 || reuses[0].JTI != res.TokenID || reuses[0].FirstSource != "10.0.0.1" || reuses[0].Source != "10.0.0.2" ||
+		reuses[0].Subject != "spiffe://example.org/order" || reuses[0].Audience != "spiffe://example.org/payment" {
+		t.Errorf("reuses = %+v", reuses)
+	}
+
+	revoke = true
+	if introspect(first, "10.0.0.3:4000") {
+		t.Error("reuse with revocation reported the token active")
+	}
+}
@@ -1,41 +1,21 @@
 package main
 
 import (
-	"crypto/ecdsa"
-	"encoding/base64"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/rs/zerolog"
 
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
 	"github.com/ngaddam369/svid-exchange/internal/token"
 )
 
-// p256UncompressedLen is the expected length of a P-256 uncompressed public key
-// point (0x04 || X || Y, each coordinate 32 bytes).
-const p256UncompressedLen = 65
-
 // keyProvider returns the set of currently active public signing keys.
 // During a rotation window more than one key may be active.
 type keyProvider interface {
-	PublicKeys() []*ecdsa.PublicKey
-}
-
-// jwkKey is a single JSON Web Key (RFC 7517).
-type jwkKey struct {
-	Kty string `json:"kty"`
-	Crv string `json:"crv"`
-	X   string `json:"x"`
-	Y   string `json:"y"`
-	Alg string `json:"alg"`
-	Use string `json:"use"`
-	Kid string `json:"kid"`
-}
-
-// jwkSet is the JWKS document returned by /jwks.
-type jwkSet struct {
-	Keys []jwkKey `json:"keys"`
+	PublicKeys() []crypto.PublicKey
 }
 
 // newJWKSHandler returns an http.HandlerFunc that serves all active public keys
@@ -43,7 +23,7 @@ type jwkSet struct {
 // rotations are reflected immediately without a server restart.
 func newJWKSHandler(kp keyProvider, log zerolog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
-		set := jwkSet{Keys: make([]jwkKey, 0)}
+		set := jwk.Set{Keys: make([]jwk.Key, 0)}
 		for _, pub := range kp.PublicKeys() {
 			k, err := pubToJWK(pub)
 			if err != nil {
@@ -66,35 +46,23 @@ func newJWKSHandler(kp keyProvider, log zerolog.Logger) http.HandlerFunc {
 	}
 }
 
-// pubToJWK converts an ECDSA P-256 public key to a JSON Web Key.
-// kid is the RFC 7638 SHA-256 thumbprint of the key.
-func pubToJWK(pub *ecdsa.PublicKey) (jwkKey, error) {
-	// pub.Bytes() returns the uncompressed point: 0x04 || X || Y.
-	// We assert the expected format so a future API change cannot silently
-	// produce incorrect coordinates.
-	raw, err := pub.Bytes()
+// pubToJWK converts a signing public key to a JSON Web Key. alg is derived
+// from the key type and kid is the RFC 7638 SHA-256 thumbprint of the key.
+func pubToJWK(pub crypto.PublicKey) (jwk.Key, error) {
+	alg, err := token.AlgorithmFor(pub)
 	if err != nil {
-		return jwkKey{}, fmt.Errorf("encode public key: %w", err)
+		return jwk.Key{}, err
 	}
-	if len(raw) != p256UncompressedLen || raw[0] != 0x04 {
-		return jwkKey{}, fmt.Errorf("unexpected public key encoding: want 65-byte uncompressed P-256 point, got %d bytes with prefix 0x%02x", len(raw), raw[0])
+	k, err := jwk.FromPublicKey(pub)
+	if err != nil {
+		return jwk.Key{}, err
 	}
-	byteLen := (len(raw) - 1) / 2
-	x := base64.RawURLEncoding.EncodeToString(raw[1 : 1+byteLen])
-	y := base64.RawURLEncoding.EncodeToString(raw[1+byteLen:])
-
 	kid, err := token.KeyID(pub)
 	if err != nil {
-		return jwkKey{}, fmt.Errorf("compute kid: %w", err)
+		return jwk.Key{}, fmt.Errorf("compute kid: %w", err)
 	}
-
-	return jwkKey{
-		Kty: "EC",
-		Crv: "P-256",
-		X:   x,
-		Y:   y,
-		Alg: "ES256",
-		Use: "sig",
-		Kid: kid,
-	}, nil
+	k.Alg = string(alg)
+	k.Use = "sig"
+	k.Kid = kid
+	return k, nil
 }
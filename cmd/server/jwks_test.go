@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
+	"crypto"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
 	"github.com/ngaddam369/svid-exchange/internal/token"
 	"github.com/rs/zerolog"
 )
@@ -33,7 +36,7 @@ func TestNewJWKSHandler(t *testing.T) {
 		{
 			name: "response contains exactly one key",
 			check: func(t *testing.T, resp *http.Response) {
-				var doc jwkSet
+				var doc jwk.Set
 				if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 					t.Fatalf("decode: %v", err)
 				}
@@ -45,7 +48,7 @@ func TestNewJWKSHandler(t *testing.T) {
 		{
 			name: "key fields have correct fixed values",
 			check: func(t *testing.T, resp *http.Response) {
-				var doc jwkSet
+				var doc jwk.Set
 				if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 					t.Fatalf("decode: %v", err)
 				}
@@ -67,7 +70,7 @@ func TestNewJWKSHandler(t *testing.T) {
 		{
 			name: "x and y are 32-byte base64url coordinates",
 			check: func(t *testing.T, resp *http.Response) {
-				var doc jwkSet
+				var doc jwk.Set
 				if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 					t.Fatalf("decode: %v", err)
 				}
@@ -91,7 +94,7 @@ func TestNewJWKSHandler(t *testing.T) {
 		{
 			name: "kid is a 32-byte RFC 7638 SHA-256 thumbprint",
 			check: func(t *testing.T, resp *http.Response) {
-				var doc jwkSet
+				var doc jwk.Set
 				if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 					t.Fatalf("decode: %v", err)
 				}
@@ -107,7 +110,7 @@ func TestNewJWKSHandler(t *testing.T) {
 		{
 			name: "response body is stable across requests",
 			check: func(t *testing.T, resp *http.Response) {
-				var doc1 jwkSet
+				var doc1 jwk.Set
 				if err := json.NewDecoder(resp.Body).Decode(&doc1); err != nil {
 					t.Fatalf("decode first: %v", err)
 				}
@@ -119,7 +122,7 @@ func TestNewJWKSHandler(t *testing.T) {
 					t.Fatalf("second request: %v", err)
 				}
 				defer resp2.Body.Close()
-				var doc2 jwkSet
+				var doc2 jwk.Set
 				if err := json.NewDecoder(resp2.Body).Decode(&doc2); err != nil {
 					t.Fatalf("decode second: %v", err)
 				}
@@ -165,7 +168,7 @@ func TestJWKSHandlerTwoKeys(t *testing.T) {
 	}
 	defer resp.Body.Close()
 
-	var doc jwkSet
+	var doc jwk.Set
 	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
@@ -193,14 +196,14 @@ func TestJWKSHandlerAfterRotation(t *testing.T) {
 	srv := httptest.NewServer(h)
 	defer srv.Close()
 
-	get := func(t *testing.T) jwkSet {
+	get := func(t *testing.T) jwk.Set {
 		t.Helper()
 		resp, err := http.Get(srv.URL)
 		if err != nil {
 			t.Fatalf("GET: %v", err)
 		}
 		defer resp.Body.Close()
-		var doc jwkSet
+		var doc jwk.Set
 		if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
 			t.Fatalf("decode: %v", err)
 		}
@@ -234,3 +237,47 @@ func TestJWKSHandlerAfterRotation(t *testing.T) {
 		t.Error("pre-rotation kid not present in post-rotation JWKS")
 	}
 }
+
+func TestJWKSHandlerAlgorithms(t *testing.T) {
+	wantKty := map[token.Algorithm]string{
+		token.ES256: "EC",
+		token.ES384: "EC",
+		token.RS256: "RSA",
+		token.EdDSA: "OKP",
+	}
+	for _, alg := range token.Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			m, err := token.NewMinterWithAlgorithm(alg)
+			if err != nil {
+				t.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			newJWKSHandler(m, zerolog.Nop())(rec, httptest.NewRequest(http.MethodGet, "/jwks", nil))
+
+			var doc jwk.Set
+			if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(doc.Keys) != 1 {
+				t.Fatalf("got %d keys, want 1", len(doc.Keys))
+			}
+			k := doc.Keys[0]
+			if k.Alg != string(alg) || k.Kty != wantKty[alg] {
+				t.Errorf("alg/kty = %q/%q, want %q/%q", k.Alg, k.Kty, alg, wantKty[alg])
+			}
+			// A verifier decoding the advertised key must be able to check a
+			// freshly minted token.
+			pub, err := k.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey: %v", err)
+			}
+			res, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			if _, err := token.VerifyClaims(res.Token, []crypto.PublicKey{pub}, "spiffe://b"); err != nil {
+				t.Errorf("VerifyClaims with advertised key: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// grpcKeepalive is the connection management applied to both gRPC servers.
+// Zero MaxConnectionIdle, MaxConnectionAge, or MaxConnectionAgeGrace means
+// no limit, as in keepalive.ServerParameters.
+type grpcKeepalive struct {
+	// Time and Timeout: the server pings a connection idle for Time and
+	// closes it if the ping is not answered within Timeout.
+	Time    time.Duration
+	Timeout time.Duration
+	// MinTime and PermitWithoutStream are the enforcement policy: a client
+	// pinging more often than MinTime, or with no active stream when
+	// PermitWithoutStream is false, is disconnected.
+	MinTime             time.Duration
+	PermitWithoutStream bool
+	// MaxConnectionIdle closes a connection with no active stream for that
+	// long. MaxConnectionAge sends GOAWAY to a connection that old, so that
+	// long-lived clients reconnect and are spread across replicas again
+	// behind an L4 load balancer; in-flight calls get MaxConnectionAgeGrace
+	// to finish.
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+}
+
+// defaultGRPCKeepalive is used for every setting the config file leaves out.
+var defaultGRPCKeepalive = grpcKeepalive{
+	Time:                2 * time.Minute,
+	Timeout:             20 * time.Second,
+	MinTime:             30 * time.Second,
+	PermitWithoutStream: true,
+	MaxConnectionIdle:   5 * time.Minute,
+	MaxConnectionAge:    30 * time.Minute,
+}
+
+// serverOptions returns the gRPC server options applying k.
+func (k grpcKeepalive) serverOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     k.MaxConnectionIdle,
+			MaxConnectionAge:      k.MaxConnectionAge,
+			MaxConnectionAgeGrace: k.MaxConnectionAgeGrace,
+			Time:                  k.Time,
+			Timeout:               k.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             k.MinTime,
+			PermitWithoutStream: k.PermitWithoutStream,
+		}),
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestGRPCKeepaliveServerOptions(t *testing.T) {
+	k := defaultGRPCKeepalive
+	k.MaxConnectionAge = time.Minute
+	k.MaxConnectionAgeGrace = 10 * time.Second
+	opts := k.serverOptions()
+	if len(opts) != 2 {
+		t.Fatalf("serverOptions() returned %d options, want keepalive params and enforcement policy", len(opts))
+	}
+	// grpc.NewServer panics on options it cannot apply.
+	grpc.NewServer(opts...).Stop()
+}
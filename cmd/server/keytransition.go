@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// verificationKey counts tokens ext_authz and the introspection endpoint
+// verified, by the role of the key that signed them, so a key transition can
+// be watched: once no token verifies with the previous key, it is no longer
+// in use.
+var verificationKey = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_verification_key_total",
+	Help: "Tokens verified by ext_authz or /introspect, by the signing key that signed them (key=current|previous|next|unknown).",
+}, []string{"key"})
+
+// observeVerificationKey returns a key observer for ext_authz and the
+// introspection endpoint that counts verified tokens in verificationKey by
+// their key's role in m.
+func observeVerificationKey(m *token.Minter) func(kid string) {
+	return func(kid string) {
+		role := m.KeyRole(kid)
+		if role == "" {
+			role = "unknown"
+		}
+		verificationKey.WithLabelValues(role).Inc()
+	}
+}
+
+// loadNextSigner reads the PKCS #8 PEM signing key at path.
+func loadNextSigner(path string) (token.AlgorithmSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := token.ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestKeyTransition(t *testing.T) {
+	key, err := token.GenerateKey(token.ES384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "next.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next, err := loadNextSigner(path)
+	if err != nil {
+		t.Fatalf("loadNextSigner: %v", err)
+	}
+	if _, err := loadNextSigner(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("loadNextSigner accepted a missing file")
+	}
+
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetNextSigner(next, time.Now().Add(time.Hour))
+	currentKid, _ := token.KeyID(m.PublicKey())
+	nextKid, _ := token.KeyID(next.Public())
+
+	before := map[string]float64{}
+	for _, role := range []string{"current", "next", "unknown"} {
+		before[role] = testutil.ToFloat64(verificationKey.WithLabelValues(role))
+	}
+	observe := observeVerificationKey(m)
+	observe(currentKid)
+	observe(nextKid)
+	observe(nextKid)
+	observe("retired")
+	for role, want := range map[string]float64{"current": 1, "next": 2, "unknown": 1} {
+		if got := testutil.ToFloat64(verificationKey.WithLabelValues(role)) - before[role]; got != want {
+			t.Errorf("verification_key_total{key=%q} grew by %v, want %v", role, got, want)
+		}
+	}
+}
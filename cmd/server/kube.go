@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	authnv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeRESTConfig returns the Kubernetes client configuration. When
+// KUBECONFIG is set the named kubeconfig is used (handy for running the
+// server outside a cluster during development); otherwise the in-cluster
+// service account credentials are used.
+func kubeRESTConfig() (*rest.Config, error) {
+	var (
+		restCfg *rest.Config
+		err     error
+	)
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		restCfg, err = clientcmd.BuildConfigFromFlags("", path)
+	} else {
+		restCfg, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load kubernetes config: %w", err)
+	}
+	return restCfg, nil
+}
+
+// newKubeClient returns a dynamic Kubernetes client.
+func newKubeClient() (dynamic.Interface, error) {
+	restCfg, err := kubeRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// newKubeClientset returns a typed Kubernetes client.
+func newKubeClientset() (*kubernetes.Clientset, error) {
+	restCfg, err := kubeRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// newTokenReviewClient returns a client for the TokenReview API. The
+// server's ServiceAccount needs the system:auth-delegator ClusterRole.
+func newTokenReviewClient() (authnv1client.TokenReviewInterface, error) {
+	client, err := newKubeClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.AuthenticationV1().TokenReviews(), nil
+}
+
+// newLeasesClient returns a client for coordination.k8s.io Leases. The
+// server's ServiceAccount needs get, create, and update on them.
+func newLeasesClient() (coordinationv1client.LeasesGetter, error) {
+	client, err := newKubeClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoordinationV1(), nil
+}
+
+// newSecretsClient returns a client for Secrets in namespace. The server's
+// ServiceAccount needs get, create, and update on them.
+func newSecretsClient(namespace string) (corev1client.SecretInterface, error) {
+	client, err := newKubeClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Secrets(namespace), nil
+}
+
+// newServiceAccountsClient returns a client for ServiceAccounts. The
+// server's ServiceAccount needs get on them in every namespace exchange
+// targets live in.
+func newServiceAccountsClient() (corev1client.ServiceAccountsGetter, error) {
+	client, err := newKubeClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1(), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestLatencyObserver(t *testing.T) {
+	fast := server.ExchangeLatency{
+		RequestID: "req-1",
+		Stages:    server.StageTimings{Extract: time.Millisecond, Evaluate: time.Millisecond, Total: 3 * time.Millisecond},
+	}
+	slow := server.ExchangeLatency{
+		RequestID: "req-2",
+		Subject:   "spiffe://cluster.local/ns/default/sa/order",
+		Target:    "spiffe://cluster.local/ns/default/sa/payment",
+		Code:      codes.OK,
+		Stages: server.StageTimings{
+			Extract: time.Millisecond, Evaluate: 2 * time.Millisecond, Mint: 900 * time.Millisecond,
+			Audit: time.Millisecond, Total: 905 * time.Millisecond,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		lat       server.ExchangeLatency
+		wantLog   bool
+	}{
+		{name: "fast exchange is not logged", threshold: 500 * time.Millisecond, lat: fast},
+		{name: "slow exchange is logged", threshold: 500 * time.Millisecond, lat: slow, wantLog: true},
+		{name: "zero threshold disables logging", lat: slow},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			mintBefore := sampleCount(t, "mint")
+			totalBefore := sampleCount(t, "total")
+
+			newLatencyObserver(tc.threshold, zerolog.New(&buf))(tc.lat)
+
+			wantMint := uint64(0)
+			if tc.lat.Stages.Mint > 0 {
+				wantMint = 1
+			}
+			if got := sampleCount(t, "mint") - mintBefore; got != wantMint {
+				t.Errorf("mint observations = %d, want %d", got, wantMint)
+			}
+			if got := sampleCount(t, "total") - totalBefore; got != 1 {
+				t.Errorf("total observations = %d, want 1", got)
+			}
+
+			if !tc.wantLog {
+				if buf.Len() != 0 {
+					t.Errorf("unexpected log: %s", buf.String())
+				}
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decode log %q: %v", buf.String(), err)
+			}
+			if entry["level"] != "warn" || entry["message"] != "slow exchange" || entry["request_id"] != "req-2" ||
+				entry["subject"] != tc.lat.Subject || entry["code"] != "OK" || entry["mint"] != 900.0 || entry["total"] != 905.0 {
+				t.Errorf("log entry = %v", entry)
+			}
+		})
+	}
+}
+
+// sampleCount returns how many observations exchangeStageDuration holds for
+// stage.
+func sampleCount(t *testing.T, stage string) uint64 {
+	t.Helper()
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "svid_exchange_stage_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "stage" && l.GetValue() == stage {
+					return m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// shedRetryAfter is advertised to shed callers in the retry-after response
+// header. Exchanges complete in milliseconds, so a saturated signer usually
+// has capacity again well within a second.
+const shedRetryAfter = time.Second
+
+// newConcurrencyLimitInterceptor returns a gRPC unary interceptor that caps
+// in-flight Exchange calls at limit. A call arriving when every slot is taken
+// waits up to queueTimeout for one to free up; if none does, it is shed with
+// Unavailable and a retry-after header (whole seconds) instead of queueing
+// behind a saturated signer. Other methods are not limited. When limit ≤ 0
+// the interceptor is a no-op pass-through.
+func newConcurrencyLimitInterceptor(limit int, queueTimeout time.Duration) grpc.UnaryServerInterceptor {
+	if limit <= 0 {
+		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(ctx, req)
+		}
+	}
+
+	slots := make(chan struct{}, limit)
+	retryAfter := strconv.Itoa(int(shedRetryAfter / time.Second))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !slices.Contains(exchangeMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			if !waitForSlot(ctx, slots, queueTimeout) {
+				if ctx.Err() != nil {
+					return nil, status.FromContextError(ctx.Err()).Err()
+				}
+				// Best effort: SetHeader only fails outside a real RPC.
+				_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+				return nil, status.Error(codes.Unavailable, "server overloaded: too many concurrent exchanges, retry later")
+			}
+		}
+		defer func() { <-slots }()
+		return handler(ctx, req)
+	}
+}
+
+// waitForSlot blocks until a slot is acquired, timeout elapses, or ctx is
+// done, and reports whether a slot was acquired.
+func waitForSlot(ctx context.Context, slots chan struct{}, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// headerStream captures headers set with grpc.SetHeader.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return exchangev1.TokenExchange_Exchange_FullMethodName }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+var exchangeInfo = &grpc.UnaryServerInfo{FullMethod: exchangev1.TokenExchange_Exchange_FullMethodName}
+
+// occupy runs a call through interceptor that holds its slot until release
+// is closed, and returns once the slot is taken.
+func occupy(t *testing.T, interceptor grpc.UnaryServerInterceptor, release <-chan struct{}) {
+	t.Helper()
+	entered := make(chan struct{})
+	go func() {
+		_, _ = interceptor(context.Background(), nil, exchangeInfo, func(_ context.Context, _ any) (any, error) {
+			close(entered)
+			<-release
+			return "ok", nil
+		})
+	}()
+	<-entered
+}
+
+func okHandler(_ context.Context, _ any) (any, error) { return "ok", nil }
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	interceptor := newConcurrencyLimitInterceptor(0, 0)
+	if _, err := interceptor(context.Background(), nil, exchangeInfo, okHandler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Run("sheds with retry-after when saturated", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 10*time.Millisecond)
+		release := make(chan struct{})
+		defer close(release)
+		occupy(t, interceptor, release)
+
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		_, err := interceptor(ctx, nil, exchangeInfo, okHandler)
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("code = %v, want Unavailable", status.Code(err))
+		}
+		if got := stream.header.Get("retry-after"); len(got) != 1 || got[0] != "1" {
+			t.Errorf("retry-after = %v, want [1]", got)
+		}
+	})
+
+	t.Run("queued call proceeds when a slot frees", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 5*time.Second)
+		release := make(chan struct{})
+		occupy(t, interceptor, release)
+		time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+		if _, err := interceptor(context.Background(), nil, exchangeInfo, okHandler); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("zero queue timeout sheds immediately", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 0)
+		release := make(chan struct{})
+		defer close(release)
+		occupy(t, interceptor, release)
+
+		if _, err := interceptor(context.Background(), nil, exchangeInfo, okHandler); status.Code(err) != codes.Unavailable {
+			t.Fatalf("code = %v, want Unavailable", status.Code(err))
+		}
+	})
+
+	t.Run("caller cancellation while queued", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 5*time.Second)
+		release := make(chan struct{})
+		defer close(release)
+		occupy(t, interceptor, release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := interceptor(ctx, nil, exchangeInfo, okHandler); status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("code = %v, want DeadlineExceeded", status.Code(err))
+		}
+	})
+
+	t.Run("exchange versions share the limit", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 0)
+		release := make(chan struct{})
+		defer close(release)
+		occupy(t, interceptor, release)
+
+		info := &grpc.UnaryServerInfo{FullMethod: exchangev2.TokenExchange_Exchange_FullMethodName}
+		if _, err := interceptor(context.Background(), nil, info, okHandler); status.Code(err) != codes.Unavailable {
+			t.Fatalf("v2 call while v1 holds the slot: code = %v, want Unavailable", status.Code(err))
+		}
+	})
+
+	t.Run("other methods are not limited", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 0)
+		release := make(chan struct{})
+		defer close(release)
+		occupy(t, interceptor, release)
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/envoy.service.auth.v3.Authorization/Check"}
+		if _, err := interceptor(context.Background(), nil, info, okHandler); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("slot released after handler returns", func(t *testing.T) {
+		interceptor := newConcurrencyLimitInterceptor(1, 0)
+		for i := range 3 {
+			if _, err := interceptor(context.Background(), nil, exchangeInfo, okHandler); err != nil {
+				t.Fatalf("call %d: unexpected error: %v", i, err)
+			}
+		}
+	})
+}
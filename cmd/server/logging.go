@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Formats accepted by log_format and LOG_FORMAT.
+const (
+	logFormatJSON    = "json"
+	logFormatConsole = "console"
+)
+
+var logFormats = []string{logFormatJSON, logFormatConsole}
+
+// Outputs accepted by audit_log_output and access_log_output besides a file
+// path.
+const (
+	logOutputStdout = "stdout"
+	logOutputStderr = "stderr"
+)
+
+// openLogOutput opens a log output: standard output, standard error, or a
+// file appended to and created with owner-only permissions. The returned
+// close func is a no-op for the standard streams.
+func openLogOutput(output string) (io.Writer, func() error, error) {
+	switch output {
+	case logOutputStdout:
+		return os.Stdout, func() error { return nil }, nil
+	case logOutputStderr:
+		return os.Stderr, func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// newAccessLogger returns the access log written as its own stream to w,
+// always as JSON and unaffected by the server log level.
+func newAccessLogger(w io.Writer) zerolog.Logger {
+	return zerolog.New(w).With().Timestamp().Str("service", "svid-exchange").Logger()
+}
+
+// parseLogLevel parses a log_level value: trace, debug, info, warn, or
+// error. Levels that would hide errors are refused.
+func parseLogLevel(s string) (zerolog.Level, error) {
+	level, err := zerolog.ParseLevel(s)
+	if err != nil || s == "" || level < zerolog.TraceLevel || level > zerolog.ErrorLevel {
+		return zerolog.NoLevel, fmt.Errorf("invalid log_level %q: must be trace, debug, info, warn, or error", s)
+	}
+	return level, nil
+}
+
+// logLevel is the minimum level of the server log. It starts at the
+// configured level, and the SetLogLevel admin RPC can change it until the
+// next change or for a limited time.
+type logLevel struct {
+	configured zerolog.Level
+	current    atomic.Int32
+	log        zerolog.Logger // reports changes; set once the server log exists
+
+	mu     sync.Mutex
+	now    func() time.Time
+	revert *time.Timer // restores configured; nil when no change is timed
+}
+
+func newLogLevel(configured zerolog.Level) *logLevel {
+	l := &logLevel{configured: configured, log: zerolog.Nop(), now: time.Now}
+	l.current.Store(int32(configured))
+	return l
+}
+
+func (l *logLevel) get() zerolog.Level {
+	return zerolog.Level(l.current.Load())
+}
+
+// SetLogLevel implements admin.LogLeveler. A change is reported in the
+// server log whatever the new level.
+func (l *logLevel) SetLogLevel(level zerolog.Level, d time.Duration) (zerolog.Level, time.Time) {
+	if level == zerolog.NoLevel {
+		level = l.configured
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prev := l.get()
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+	l.current.Store(int32(level))
+
+	var revertAt time.Time
+	if d > 0 && level != l.configured {
+		revertAt = l.now().Add(d)
+		var t *time.Timer
+		t = time.AfterFunc(d, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.revert != t {
+				return // replaced by a later change
+			}
+			l.revert = nil
+			l.current.Store(int32(l.configured))
+			l.log.Log().Str("level", l.configured.String()).Msg("log level restored")
+		})
+		l.revert = t
+	}
+	ev := l.log.Log().Str("from", prev.String()).Str("level", level.String())
+	if !revertAt.IsZero() {
+		ev = ev.Time("revert_at", revertAt)
+	}
+	ev.Msg("log level changed")
+	return prev, revertAt
+}
+
+// levelFilter is a zerolog.LevelWriter that drops entries below level's
+// current value. Entries logged without a level are always written.
+type levelFilter struct {
+	w     io.Writer
+	level *logLevel
+}
+
+func (f levelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f levelFilter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	if l < f.level.get() {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
+
+// newLogger returns the server log, written to w in format and filtered at
+// level. When debugPerSecond is positive, at most that many trace and debug
+// entries are written each second, so that debug logging can be turned on
+// under load; other levels are never sampled. level reports its changes to
+// the returned log.
+func newLogger(w io.Writer, format string, debugPerSecond int, level *logLevel) zerolog.Logger {
+	if format == logFormatConsole {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	}
+	log := zerolog.New(levelFilter{w: w, level: level}).Level(zerolog.TraceLevel).
+		With().Timestamp().Str("service", "svid-exchange").Logger()
+	if debugPerSecond > 0 {
+		burst := uint32(debugPerSecond)
+		log = log.Sample(zerolog.LevelSampler{
+			TraceSampler: &zerolog.BurstSampler{Burst: burst, Period: time.Second},
+			DebugSampler: &zerolog.BurstSampler{Burst: burst, Period: time.Second},
+		})
+	}
+	level.log = log
+	return log
+}
+
+// grpcLogger is a grpclog.LoggerV2 that writes gRPC's internal logs to the
+// server log. gRPC's info messages, mostly connection state changes, are
+// logged at debug; its verbose messages only at trace.
+type grpcLogger struct {
+	log   zerolog.Logger
+	level *logLevel
+}
+
+func (g grpcLogger) Info(args ...any)                    { g.log.Debug().Msg(fmt.Sprint(args...)) }
+func (g grpcLogger) Infoln(args ...any)                  { g.log.Debug().Msg(sprintln(args...)) }
+func (g grpcLogger) Infof(format string, args ...any)    { g.log.Debug().Msgf(format, args...) }
+func (g grpcLogger) Warning(args ...any)                 { g.log.Warn().Msg(fmt.Sprint(args...)) }
+func (g grpcLogger) Warningln(args ...any)               { g.log.Warn().Msg(sprintln(args...)) }
+func (g grpcLogger) Warningf(format string, args ...any) { g.log.Warn().Msgf(format, args...) }
+func (g grpcLogger) Error(args ...any)                   { g.log.Error().Msg(fmt.Sprint(args...)) }
+func (g grpcLogger) Errorln(args ...any)                 { g.log.Error().Msg(sprintln(args...)) }
+func (g grpcLogger) Errorf(format string, args ...any)   { g.log.Error().Msgf(format, args...) }
+func (g grpcLogger) Fatal(args ...any)                   { g.log.Fatal().Msg(fmt.Sprint(args...)) }
+func (g grpcLogger) Fatalln(args ...any)                 { g.log.Fatal().Msg(sprintln(args...)) }
+func (g grpcLogger) Fatalf(format string, args ...any)   { g.log.Fatal().Msgf(format, args...) }
+
+// V reports whether gRPC messages at verbosity v are logged: all of them at
+// trace, and otherwise only the unconditional ones.
+func (g grpcLogger) V(v int) bool {
+	return v <= 0 || g.level.get() <= zerolog.TraceLevel
+}
+
+// sprintln formats args as fmt.Sprintln does, without the newline.
+func sprintln(args ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    zerolog.Level
+		wantErr bool
+	}{
+		{in: "trace", want: zerolog.TraceLevel},
+		{in: "debug", want: zerolog.DebugLevel},
+		{in: "info", want: zerolog.InfoLevel},
+		{in: "warn", want: zerolog.WarnLevel},
+		{in: "error", want: zerolog.ErrorLevel},
+		{in: "", wantErr: true},
+		{in: "verbose", wantErr: true},
+		{in: "fatal", wantErr: true},
+		{in: "disabled", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseLogLevel(tc.in)
+		if (err != nil) != tc.wantErr || (!tc.wantErr && got != tc.want) {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v, error %t", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("filters at the current level", func(t *testing.T) {
+		var buf bytes.Buffer
+		level := newLogLevel(zerolog.InfoLevel)
+		log := newLogger(&buf, logFormatJSON, 0, level)
+		log.Debug().Msg("hidden")
+		log.Info().Msg("shown")
+		if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, `"message":"shown"`) || !strings.Contains(out, `"service":"svid-exchange"`) {
+			t.Fatalf("output = %q", out)
+		}
+
+		buf.Reset()
+		level.SetLogLevel(zerolog.DebugLevel, 0)
+		log.Debug().Msg("now shown")
+		if out := buf.String(); !strings.Contains(out, "now shown") || !strings.Contains(out, "log level changed") {
+			t.Errorf("output after change = %q", out)
+		}
+	})
+
+	t.Run("console format", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newLogger(&buf, logFormatConsole, 0, newLogLevel(zerolog.InfoLevel))
+		log.Info().Str("k", "v").Msg("hello")
+		if out := buf.String(); strings.HasPrefix(out, "{") || !strings.Contains(out, "INF") || !strings.Contains(out, "hello") || !strings.Contains(out, "k=") {
+			t.Errorf("output = %q", out)
+		}
+	})
+
+	t.Run("samples debug but not warnings", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := newLogger(&buf, logFormatJSON, 2, newLogLevel(zerolog.DebugLevel))
+		for range 5 {
+			log.Debug().Msg("debug")
+			log.Warn().Msg("warn")
+		}
+		out := buf.String()
+		if n := strings.Count(out, `"message":"debug"`); n != 2 {
+			t.Errorf("%d debug entries written, want 2", n)
+		}
+		if n := strings.Count(out, `"message":"warn"`); n != 5 {
+			t.Errorf("%d warn entries written, want 5", n)
+		}
+	})
+}
+
+func TestLogLevelSetLogLevel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("until changed", func(t *testing.T) {
+		l := newLogLevel(zerolog.InfoLevel)
+		prev, revertAt := l.SetLogLevel(zerolog.DebugLevel, 0)
+		if prev != zerolog.InfoLevel || !revertAt.IsZero() || l.get() != zerolog.DebugLevel {
+			t.Errorf("SetLogLevel = %v, %v; level %v", prev, revertAt, l.get())
+		}
+		if prev, _ := l.SetLogLevel(zerolog.NoLevel, 0); prev != zerolog.DebugLevel || l.get() != zerolog.InfoLevel {
+			t.Errorf("restore: previous %v, level %v; want debug, info", prev, l.get())
+		}
+	})
+
+	t.Run("reverts after the duration", func(t *testing.T) {
+		l := newLogLevel(zerolog.InfoLevel)
+		l.now = func() time.Time { return now }
+		_, revertAt := l.SetLogLevel(zerolog.TraceLevel, 20*time.Millisecond)
+		if !revertAt.Equal(now.Add(20 * time.Millisecond)) {
+			t.Errorf("revertAt = %v, want %v", revertAt, now.Add(20*time.Millisecond))
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for l.get() != zerolog.InfoLevel {
+			if time.Now().After(deadline) {
+				t.Fatalf("level still %v after the duration", l.get())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("a later change cancels the revert", func(t *testing.T) {
+		l := newLogLevel(zerolog.InfoLevel)
+		l.SetLogLevel(zerolog.DebugLevel, 10*time.Millisecond)
+		l.SetLogLevel(zerolog.WarnLevel, 0)
+		time.Sleep(50 * time.Millisecond)
+		if l.get() != zerolog.WarnLevel {
+			t.Errorf("level = %v, want warn", l.get())
+		}
+	})
+}
+
+func TestGRPCLogger(t *testing.T) {
+	var buf bytes.Buffer
+	level := newLogLevel(zerolog.InfoLevel)
+	g := grpcLogger{log: newLogger(&buf, logFormatJSON, 0, level).With().Str("component", "grpc").Logger(), level: level}
+
+	g.Info("subchannel ", "ready")
+	g.Warningf("retrying in %dms", 50)
+	g.Errorln("transport", "closed")
+	out := buf.String()
+	if strings.Contains(out, "subchannel") {
+		t.Error("gRPC info written at info level")
+	}
+	if !strings.Contains(out, `"level":"warn"`) || !strings.Contains(out, "retrying in 50ms") {
+		t.Errorf("warning missing: %q", out)
+	}
+	if !strings.Contains(out, `"message":"transport closed"`) || !strings.Contains(out, `"component":"grpc"`) {
+		t.Errorf("error missing: %q", out)
+	}
+	if !g.V(0) || g.V(2) {
+		t.Errorf("V(0), V(2) = %t, %t at info; want true, false", g.V(0), g.V(2))
+	}
+
+	buf.Reset()
+	level.SetLogLevel(zerolog.TraceLevel, 0)
+	g.Info("subchannel ", "ready")
+	if !strings.Contains(buf.String(), `"message":"subchannel ready"`) || !g.V(2) {
+		t.Errorf("at trace: output %q, V(2) = %t", buf.String(), g.V(2))
+	}
+}
+
+func TestOpenLogOutput(t *testing.T) {
+	for _, output := range []string{logOutputStdout, logOutputStderr} {
+		w, closeFn, err := openLogOutput(output)
+		if err != nil || w == nil {
+			t.Fatalf("openLogOutput(%q) = %v, %v", output, w, err)
+		}
+		if err := closeFn(); err != nil {
+			t.Errorf("close %s: %v", output, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	for _, line := range []string{"one", "two"} {
+		w, closeFn, err := openLogOutput(path)
+		if err != nil {
+			t.Fatalf("openLogOutput: %v", err)
+		}
+		log := newAccessLogger(w)
+		log.Info().Msg(line)
+		if err := closeFn(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("file has %d lines, want 2 appended: %s", n, data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %v, want 0600", perm)
+	}
+
+	if _, _, err := openLogOutput(filepath.Join(t.TempDir(), "missing", "access.log")); err == nil {
+		t.Error("openLogOutput succeeded in a missing directory")
+	}
+}
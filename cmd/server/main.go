@@ -2,6 +2,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -15,26 +16,41 @@ import (
 	"syscall"
 	"time"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/rs/zerolog"
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
 	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
 	"github.com/spiffe/go-spiffe/v2/workloadapi"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/ngaddam369/svid-exchange/internal/admin"
 	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/authorizer"
+	"github.com/ngaddam369/svid-exchange/internal/breakglass"
+	"github.com/ngaddam369/svid-exchange/internal/extauthz"
+	"github.com/ngaddam369/svid-exchange/internal/httpserv"
+	"github.com/ngaddam369/svid-exchange/internal/kube"
 	"github.com/ngaddam369/svid-exchange/internal/policy"
 	"github.com/ngaddam369/svid-exchange/internal/server"
 	"github.com/ngaddam369/svid-exchange/internal/spiffe"
 	"github.com/ngaddam369/svid-exchange/internal/token"
 	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
 	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
 )
 
+// shutdownTimeout bounds shutdown after the drain period: draining the
+// listeners and in-flight calls, flushing the audit spool, releasing the
+// leader lease, and flushing traces together.
 const shutdownTimeout = 10 * time.Second
 
+// signingKeySyncInterval is how often a replica re-reads shared signing keys
+// from signing_key_secret. A rotation reaches every replica within this long.
+const signingKeySyncInterval = 30 * time.Second
+
 func main() {
 	log := zerolog.New(os.Stdout).With().Timestamp().Str("service", "svid-exchange").Logger()
 
@@ -43,16 +59,110 @@ func main() {
 		log.Fatal().Err(err).Msg("load config")
 	}
 
+	// --- Logging ---
+	// The server log is filtered at a level the SetLogLevel admin RPC can
+	// change at runtime. gRPC's own logs are routed into it; the audit log
+	// is written separately and is unaffected.
+	logLvl := newLogLevel(cfg.LogLevel)
+	log = newLogger(os.Stdout, cfg.LogFormat, cfg.LogDebugPerSecond, logLvl)
+	grpclog.SetLoggerV2(grpcLogger{log: log.With().Str("component", "grpc").Logger(), level: logLvl})
+	effective := effectiveConfig(cfg)
+	log.Info().Interface("config", effective).Msg("effective configuration")
+
 	rootCtx, rootCancel := context.WithCancel(context.Background())
 	defer rootCancel()
 
+	// --- Service manager ---
+	// Under systemd (Type=notify) or as a Windows service, the supervisor is
+	// told when the server is ready and stopping, and its stop requests
+	// arrive on quit like SIGTERM. The watchdog, when enabled, is fed only
+	// while both gRPC listeners are serving.
+	var grpcServing, adminServing atomic.Bool
+	quit := make(chan os.Signal, 1)
+	supervisors := detectServiceManagers(rootCtx, quit, func() bool {
+		return grpcServing.Load() && adminServing.Load()
+	}, log)
+
+	// --- SPIFFE identity ---
+	// SPIFFE_ENDPOINT_SOCKET must point to the SPIRE Workload API socket.
+	// X509Source fetches and rotates the SVID automatically; every TLS
+	// handshake picks up the latest certificate without a process restart.
+	// SPIFFE_SVID_DIR replaces it in development with an SVID read once from
+	// disk, which nothing rotates.
+	var src svidSource
+	if cfg.SVIDDir != "" {
+		if src, err = loadSVIDDir(cfg.SVIDDir); err != nil {
+			log.Fatal().Err(err).Str("dir", cfg.SVIDDir).Msg("load SVID directory")
+		}
+		log.Warn().Str("dir", cfg.SVIDDir).Msg("mTLS via a static SVID directory; for development only")
+	} else {
+		log.Info().Str("socket", cfg.SpiffeSocket).Msg("mTLS via SPIRE Workload API")
+		if src, err = workloadapi.NewX509Source(
+			rootCtx,
+			workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.SpiffeSocket)),
+		); err != nil {
+			log.Fatal().Err(err).Str("socket", cfg.SpiffeSocket).Msg("connect to SPIRE Workload API")
+		}
+	}
+
 	// --- Policy ---
-	pl, err := policy.LoadFile(cfg.PolicyFile)
+	pl, err := policy.LoadFileWithConflictMode(cfg.PolicyFile, cfg.PolicyConflicts)
 	if err != nil {
 		log.Fatal().Err(err).Str("path", cfg.PolicyFile).Msg("load policy")
 	}
-	log.Info().Str("path", cfg.PolicyFile).Msg("policy loaded")
-	ap := newAtomicPolicy(pl)
+	st := pl.Stats()
+	log.Info().Str("path", cfg.PolicyFile).Int("rules", st.Rules).Int("pattern_rules", st.PatternRules).
+		Int("conflicts", st.Conflicts).Str("conflict_mode", string(cfg.PolicyConflicts)).Dur("index_build", st.IndexBuildTime).Msg("policy loaded")
+	ap := newAtomicPolicy(pl, log)
+	var evaluator server.PolicyEvaluator = ap
+
+	// --- External authorizer ---
+	// Decisions come from a central authorization service instead of the
+	// policy file, which then only decides when the service cannot and
+	// external_authorizer_failure_mode is open.
+	var extAuthorizer *authorizer.Client
+	if cfg.ExternalAuthorizerURL != "" {
+		var clientTLS *tls.Config
+		if !cfg.ExternalAuthorizerSPIFFEID.IsZero() {
+			clientTLS = tlsconfig.MTLSClientConfig(src, src, tlsconfig.AuthorizeID(cfg.ExternalAuthorizerSPIFFEID))
+		}
+		extAuthorizer, err = authorizer.New(cfg.ExternalAuthorizerURL, clientTLS, cfg.ExternalAuthorizerTimeout)
+		if err != nil {
+			log.Fatal().Err(err).Str("url", cfg.ExternalAuthorizerURL).Msg("create external authorizer client")
+		}
+		var fallback server.PolicyEvaluator
+		if cfg.ExternalAuthorizerFailOpen {
+			fallback = ap
+		}
+		evaluator = newExternalPolicy(extAuthorizer, fallback, log)
+		log.Info().Str("url", cfg.ExternalAuthorizerURL).Dur("timeout", cfg.ExternalAuthorizerTimeout).
+			Bool("fail_open", cfg.ExternalAuthorizerFailOpen).Msg("external authorizer enabled")
+	}
+
+	// --- Decision cache ---
+	// Repeated (subject, target, scopes, ttl) requests reuse the decision
+	// for decision_cache_ttl; every policy swap drops the cache.
+	if cfg.DecisionCacheTTL > 0 {
+		cache := newDecisionCache(evaluator, cfg.DecisionCacheTTL, cfg.DecisionCacheSize)
+		ap.notifySwap(cache.invalidate)
+		evaluator = cache
+		log.Info().Dur("ttl", cfg.DecisionCacheTTL).Int("size", cfg.DecisionCacheSize).Msg("policy decision cache enabled")
+	}
+
+	// --- Shadow policy ---
+	// A candidate policy file evaluated alongside the active policy; requests
+	// on which the two disagree are logged and counted, but the active policy
+	// always decides.
+	var shadow *shadowPolicy
+	if cfg.ShadowPolicyFile != "" {
+		sl, err := policy.LoadFileWithConflictMode(cfg.ShadowPolicyFile, cfg.PolicyConflicts)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.ShadowPolicyFile).Msg("load shadow policy")
+		}
+		shadow = newShadowPolicy(evaluator, sl, log)
+		evaluator = shadow
+		log.Info().Str("path", cfg.ShadowPolicyFile).Int("rules", sl.Stats().Rules).Msg("shadow policy loaded")
+	}
 
 	// --- Policy store (BoltDB) ---
 	// Dynamic policies added via the admin API are persisted here and merged
@@ -69,6 +179,46 @@ func main() {
 	if err = ap.rebuild(store); err != nil {
 		log.Fatal().Err(err).Msg("merge policy store")
 	}
+	// Every later reload is fail-static: one that fails leaves the
+	// last-known-good policy serving and is reported here.
+	policyState := newPolicyStatus(time.Now)
+
+	// --- Kubernetes policy source ---
+	// ExchangePolicy resources are merged alongside the YAML base. Start blocks
+	// until the informer has synced so the first request already sees them.
+	if cfg.KubePolicySource {
+		kubeClient, err := newKubeClient()
+		if err != nil {
+			log.Fatal().Err(err).Msg("init kubernetes client")
+		}
+		reserved := func() []policy.Policy {
+			dynamic, err := store.List()
+			if err != nil {
+				log.Error().Err(err).Msg("list policy store for ExchangePolicy conflict check")
+			}
+			return append(ap.yamlPolicies(), dynamic...)
+		}
+		apply := func(ps []policy.Policy) (err error) {
+			defer func() { policyState.record(policySourceKube, err) }()
+			if cfg.KeyRotationInterval > 0 {
+				if err := checkRotationInvariant(ps, cfg.KeyRotationInterval); err != nil {
+					return err
+				}
+			}
+			prev := ap.setCRD(ps)
+			if err := ap.rebuild(store); err != nil {
+				ap.setCRD(prev)
+				return err
+			}
+			log.Info().Int("count", len(ps)).Msg("ExchangePolicy resources applied")
+			return nil
+		}
+		policySrc := kube.NewSource(kubeClient, cfg.KubePolicyNamespace, reserved, apply, log)
+		if err = policySrc.Start(rootCtx); err != nil {
+			log.Fatal().Err(err).Msg("start ExchangePolicy source")
+		}
+		log.Info().Str("namespace", cfg.KubePolicyNamespace).Msg("ExchangePolicy source started")
+	}
 
 	// --- Token minter ---
 	// Validate the rotation-vs-TTL invariant before starting the key rotation
@@ -81,15 +231,137 @@ func main() {
 		}
 	}
 
-	minter, err := token.NewMinter()
+	minter, err := token.NewMinterWithAlgorithm(cfg.SigningAlgorithm)
 	if err != nil {
 		log.Fatal().Err(err).Msg("init minter")
 	}
+	log.Info().Str("alg", string(cfg.SigningAlgorithm)).Msg("token signing algorithm")
+	if !token.SVIDCompatible(minter.PublicKey()) {
+		log.Warn().Str("alg", string(cfg.SigningAlgorithm)).Msg("signing algorithm is not permitted for JWT-SVIDs; policies with token_format jwt-svid will fail to mint")
+	}
+	// A scheduled key transition publishes the next key now and signs with
+	// it from the cutover on.
+	if cfg.NextSigningKeyFile != "" {
+		next, err := loadNextSigner(cfg.NextSigningKeyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("load next signing key")
+		}
+		minter.SetNextSigner(next, cfg.NextSigningKeyCutover)
+		log.Info().Str("path", cfg.NextSigningKeyFile).Str("alg", string(next.Algorithm())).Time("cutover", cfg.NextSigningKeyCutover).
+			Msg("signing key transition scheduled")
+		if !token.SVIDCompatible(next.Public()) {
+			log.Warn().Str("alg", string(next.Algorithm())).Msg("next signing key is not permitted for JWT-SVIDs; policies with token_format jwt-svid will fail to mint after the cutover")
+		}
+	}
+
+	// PASETO v4.public tokens are signed with a dedicated Ed25519 key: the
+	// PASETO specification forbids sharing a key with another protocol.
+	pasetoKeys, err := token.NewMinterWithAlgorithm(token.EdDSA)
+	if err != nil {
+		log.Fatal().Err(err).Msg("init paseto minter")
+	}
+	pasetoMinter, err := token.NewPASETOMinter(pasetoKeys)
+	if err != nil {
+		log.Fatal().Err(err).Msg("init paseto minter")
+	}
+	if cfg.NotBeforeSkew > 0 {
+		for _, m := range []*token.Minter{minter, pasetoKeys} {
+			if err := m.SetNotBeforeSkew(cfg.NotBeforeSkew); err != nil {
+				log.Fatal().Err(err).Msg("init minter")
+			}
+		}
+		log.Info().Dur("skew", cfg.NotBeforeSkew).Msg("token nbf backdated")
+	}
+	// With signing_workers set, every signature goes through one worker
+	// pool shared by the JWT and PASETO keys.
+	var signingPipeline *token.SigningPipeline
+	if cfg.SigningWorkers > 0 {
+		signingPipeline, err = token.NewSigningPipeline(cfg.SigningWorkers, cfg.SigningQueueSize)
+		if err != nil {
+			log.Fatal().Err(err).Msg("init signing pipeline")
+		}
+		for _, m := range []*token.Minter{minter, pasetoKeys} {
+			m.SetSigningPipeline(signingPipeline)
+		}
+		log.Info().Int("workers", cfg.SigningWorkers).Int("queue", cfg.SigningQueueSize).Msg("token signing pipeline enabled")
+	}
+
+	// --- Leader election ---
+	// With leader_election_lease set, replicas campaign for a Lease and only
+	// the holder rotates the shared signing key. The lease is released on
+	// shutdown so another replica takes over without waiting for it to
+	// expire.
+	var leader *kube.Leader
+	leaderDone := make(chan struct{})
+	if cfg.LeaderElectionLease != "" {
+		leases, err := newLeasesClient()
+		if err != nil {
+			log.Fatal().Err(err).Msg("init kubernetes Leases client")
+		}
+		identity, err := os.Hostname()
+		if err != nil {
+			log.Fatal().Err(err).Msg("leader election identity")
+		}
+		if leader, err = kube.NewLeader(leases, cfg.LeaderElectionNamespace, cfg.LeaderElectionLease, identity, log); err != nil {
+			log.Fatal().Err(err).Msg("init leader election")
+		}
+		go func() {
+			leader.Run(rootCtx)
+			close(leaderDone)
+		}()
+		log.Info().Str("namespace", cfg.LeaderElectionNamespace).Str("lease", cfg.LeaderElectionLease).Str("identity", identity).
+			Msg("leader election started")
+	} else {
+		close(leaderDone)
+	}
+
+	// --- Shared signing keys ---
+	// With signing_key_secret set, every replica signs with the keys in one
+	// Kubernetes Secret instead of its own ephemeral key, so any replica's
+	// JWKS verifies any replica's tokens. Whichever replica first finds the
+	// key due rotates it in the Secret, or only the leader when leader
+	// election is enabled; the rest pick it up on their next sync.
+	var keySecret *kube.SigningKeySecret
+	if cfg.SigningKeySecret != "" {
+		secrets, err := newSecretsClient(cfg.SigningKeySecretNamespace)
+		if err != nil {
+			log.Fatal().Err(err).Msg("init kubernetes Secrets client")
+		}
+		keySecret = kube.NewSigningKeySecret(secrets, cfg.SigningKeySecret, cfg.SigningAlgorithm)
+		if leader != nil {
+			keySecret.SetLeader(leader.IsLeader)
+		}
+		if _, err := keySecret.Sync(rootCtx, minter, cfg.KeyRotationInterval); err != nil {
+			log.Fatal().Err(err).Msg("load shared signing keys")
+		}
+		log.Info().Str("namespace", cfg.SigningKeySecretNamespace).Str("secret", cfg.SigningKeySecret).
+			Msg("signing with shared keys from Kubernetes Secret")
+		go func() {
+			ticker := time.NewTicker(signingKeySyncInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					switch res, err := keySecret.Sync(rootCtx, minter, cfg.KeyRotationInterval); {
+					case err != nil:
+						log.Error().Err(err).Msg("shared signing key sync failed; keeping current keys")
+					case res == kube.SyncRotated:
+						log.Info().Msg("shared signing key rotated")
+					case res == kube.SyncLoaded:
+						log.Info().Msg("shared signing keys reloaded")
+					}
+				case <-rootCtx.Done():
+					return
+				}
+			}
+		}()
+	}
 
 	// --- Signing key rotation ---
 	// key_rotation_interval controls how often a new signing key is generated.
 	// The outgoing key is retained for one interval so that tokens signed just
-	// before a rotation remain verifiable. Zero disables rotation.
+	// before a rotation remain verifiable. Zero disables rotation. Shared keys
+	// are rotated by the sync loop above instead.
 	if cfg.KeyRotationInterval > 0 {
 		log.Info().Dur("interval", cfg.KeyRotationInterval).Msg("signing key rotation enabled")
 		go func() {
@@ -98,9 +370,14 @@ func main() {
 			for {
 				select {
 				case <-ticker.C:
-					if err := minter.Rotate(); err != nil {
-						log.Error().Err(err).Msg("signing key rotation failed")
-						continue
+					if keySecret == nil {
+						if err := minter.Rotate(); err != nil {
+							log.Error().Err(err).Msg("signing key rotation failed")
+							continue
+						}
+					}
+					if err := pasetoKeys.Rotate(); err != nil {
+						log.Error().Err(err).Msg("paseto signing key rotation failed")
 					}
 					log.Info().Msg("signing key rotated")
 				case <-rootCtx.Done():
@@ -114,7 +391,114 @@ func main() {
 	if len(cfg.AuditHMACKey) > 0 {
 		log.Info().Msg("audit log HMAC signing enabled")
 	}
-	auditLog := audit.NewWithHMAC(os.Stdout, cfg.AuditHMACKey)
+	auditOut, closeAuditOut, err := openLogOutput(cfg.AuditLogOutput)
+	if err != nil {
+		log.Fatal().Err(err).Str("output", cfg.AuditLogOutput).Msg("open audit log output")
+	}
+	defer closeAuditOut()
+	if cfg.AuditLogOutput != logOutputStdout {
+		log.Info().Str("output", cfg.AuditLogOutput).Msg("audit log written to its own output")
+	}
+	auditLog := audit.NewWithHMAC(auditOut, cfg.AuditHMACKey)
+	auditLog.SetSampling(cfg.AuditGrantSampleRate, observeAuditEvent)
+	// SPIFFE IDs and scopes are redacted in the audit log and in error
+	// messages alike, for deployments whose logs leave the security boundary.
+	redactor, err := audit.NewRedactor(cfg.RedactSPIFFEIDs, cfg.RedactScopes, cfg.RedactionHashKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("configure redaction")
+	}
+	if redactor != nil {
+		auditLog.SetRedactor(redactor)
+		log.Info().Str("spiffe_ids", cfg.RedactSPIFFEIDs).Str("scopes", cfg.RedactScopes).Msg("audit and error redaction enabled")
+	}
+	if cfg.AuditGrantSampleRate < 1 {
+		log.Info().Float64("rate", cfg.AuditGrantSampleRate).Msg("audit grant sampling enabled")
+	}
+	for kind, level := range cfg.AuditLevels {
+		if err := auditLog.SetLevel(kind, level); err != nil {
+			log.Fatal().Err(err).Msg("set audit level")
+		}
+	}
+	if cfg.AnomalyDetection {
+		auditLog.AddAnalyzer(audit.NewPairAnalyzer(10_000))
+		log.Info().Msg("anomaly detection enabled for new pairs and scope escalation")
+	}
+	if cfg.AnomalyDenialBurst > 0 {
+		auditLog.AddAnalyzer(audit.NewDenialBurstAnalyzer(cfg.AnomalyDenialBurst, cfg.AnomalyDenialWindow))
+		log.Info().Int("threshold", cfg.AnomalyDenialBurst).Dur("window", cfg.AnomalyDenialWindow).Msg("denial burst detection enabled")
+	}
+	if cfg.DenialWebhookURL != "" {
+		sink := audit.NewWebhookSink(audit.WebhookConfig{URL: cfg.DenialWebhookURL, Secret: cfg.DenialWebhookSecret}, log)
+		sink.Start(rootCtx)
+		auditLog.AddSink(sink)
+		log.Info().Str("url", cfg.DenialWebhookURL).Msg("denial webhook enabled")
+	}
+	if cfg.AuditProtoFile != "" {
+		f, err := os.OpenFile(cfg.AuditProtoFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			log.Fatal().Err(err).Msg("open protobuf audit file")
+		}
+		defer f.Close()
+		auditLog.AddSink(audit.NewProtoSink(f, log))
+		log.Info().Str("path", cfg.AuditProtoFile).Int("schema_version", audit.SchemaVersion).Msg("protobuf audit sink enabled")
+	}
+	// Issuance statistics count every audited exchange, after redaction,
+	// for the admin GetStats RPC and the scheduled access-review reports.
+	var issuanceStats *audit.IssuanceStats
+	if cfg.IssuanceStats {
+		issuanceStats = audit.NewIssuanceStats(cfg.IssuanceStatsRetention)
+		auditLog.AddSink(issuanceStats)
+		log.Info().Dur("retention", cfg.IssuanceStatsRetention).Msg("issuance statistics enabled")
+		if cfg.IssuanceReportDir != "" {
+			if err := os.MkdirAll(cfg.IssuanceReportDir, 0o750); err != nil {
+				log.Fatal().Err(err).Str("dir", cfg.IssuanceReportDir).Msg("create issuance report directory")
+			}
+			go runIssuanceReports(rootCtx, issuanceStats, cfg.IssuanceReportDir, cfg.IssuanceReportFormat, cfg.IssuanceReportInterval, log)
+			log.Info().Str("dir", cfg.IssuanceReportDir).Dur("interval", cfg.IssuanceReportInterval).Str("format", cfg.IssuanceReportFormat).Msg("scheduled issuance reports enabled")
+		}
+	}
+	// Rule usage counts the policy rules behind every grant in the policy
+	// store, so the admin ListUnusedPolicies RPC can find stale rules.
+	var ruleUsage *policy.UsageTracker
+	if cfg.RuleUsageTracking {
+		ruleUsage = policy.NewUsageTracker(store, ap.ruleNames)
+		if err := ruleUsage.Flush(time.Now()); err != nil {
+			log.Fatal().Err(err).Msg("initialize policy rule usage")
+		}
+		auditLog.AddSink(ruleUsageSink{tracker: ruleUsage})
+		go runRuleUsageFlush(rootCtx, ruleUsage, ruleUsageFlushInterval, log)
+		log.Info().Msg("policy rule usage tracking enabled")
+	}
+	// The spool is shipped to the sinks registered above, so it must be set
+	// up after the last AddSink.
+	if cfg.AuditSpoolDir != "" {
+		spool, err := audit.OpenSpool(cfg.AuditSpoolDir, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("open audit spool")
+		}
+		auditLog.SetSpool(spool)
+		go auditLog.ShipSpool(rootCtx)
+		log.Info().Str("dir", cfg.AuditSpoolDir).Bool("required", cfg.AuditSpoolRequired).Msg("audit spool enabled")
+	}
+
+	// certExtractor identifies callers by their X.509-SVID on the Exchange,
+	// admin, and break-glass paths alike, so x509_multi_uri_mode governs
+	// every one of them.
+	certExtractor := spiffe.Extractor{MultiURI: cfg.X509MultiURI, TrustDomain: cfg.X509URITrustDomain}
+
+	// --- Break-glass ---
+	// Signed emergency overrides activated through the admin API grant what
+	// the evaluators above deny or cannot decide, until they expire.
+	var breakGlass *breakGlassPolicy
+	if cfg.BreakGlassKeyFile != "" {
+		key, err := breakglass.LoadPublicKey(cfg.BreakGlassKeyFile)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.BreakGlassKeyFile).Msg("load break-glass public key")
+		}
+		breakGlass = newBreakGlassPolicy(evaluator, key, cfg.BreakGlassMaxDuration, auditLog, certExtractor)
+		evaluator = breakGlass
+		log.Info().Str("key", cfg.BreakGlassKeyFile).Dur("max_duration", cfg.BreakGlassMaxDuration).Msg("break-glass overrides enabled")
+	}
 
 	// --- Tracing ---
 	tracingShutdown, err := initTracing(rootCtx, cfg.OTLPEndpoint, cfg.OTLPInsecure)
@@ -129,75 +513,346 @@ func main() {
 	if cfg.RateLimitRPS > 0 {
 		log.Info().Float64("rps", cfg.RateLimitRPS).Int("burst", cfg.RateLimitBurst).Msg("rate limiting enabled")
 	}
+	if cfg.MaxConcurrentExchanges > 0 {
+		log.Info().Int("limit", cfg.MaxConcurrentExchanges).Dur("queue_timeout", cfg.ExchangeQueueTimeout).Msg("exchange load shedding enabled")
+	}
 
 	// --- gRPC server ---
-	// mTLS is mandatory — the service is SPIFFE-native.
-	// SPIFFE_ENDPOINT_SOCKET must point to the SPIRE Workload API socket.
-	// X509Source fetches and rotates the SVID automatically; every TLS
-	// handshake picks up the latest certificate without a process restart.
-	log.Info().Str("socket", cfg.SpiffeSocket).Msg("mTLS via SPIRE Workload API")
-	src, err := workloadapi.NewX509Source(
-		rootCtx,
-		workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.SpiffeSocket)),
-	)
-	if err != nil {
-		log.Fatal().Err(err).Str("socket", cfg.SpiffeSocket).Msg("connect to SPIRE Workload API")
-	}
+	// mTLS is mandatory — the service is SPIFFE-native, serving the SVID
+	// from the source opened above.
 
-	tlsCfg := tlsconfig.MTLSServerConfig(src, src, tlsconfig.AuthorizeAny())
+	// Clients outside allowed_trust_domains fail the handshake, before any
+	// RPC is read, on both the data-plane and admin listeners.
+	authorizer := newTrustDomainAuthorizer(cfg.AllowedTrustDomains)
+	tlsCfg := tlsconfig.MTLSServerConfig(src, src, authorizer)
 	tlsCfg.MinVersion = tls.VersionTLS13
+	// go-spiffe fails the handshake for any certificate with more than one
+	// URI SAN, which is what reject needs; the other modes admit such
+	// certificates and authenticate the ID certExtractor selects.
+	if cfg.X509MultiURI != spiffe.MultiURIReject {
+		tlsCfg.VerifyPeerCertificate = certExtractor.VerifyPeerCertificate(src, authorizer)
+		log.Info().Str("mode", string(cfg.X509MultiURI)).Str("trust_domain", cfg.X509URITrustDomain.Name()).
+			Msg("certificates with several SPIFFE IDs accepted")
+	}
+	if len(cfg.AllowedTrustDomains) > 0 {
+		tds := make([]string, len(cfg.AllowedTrustDomains))
+		for i, td := range cfg.AllowedTrustDomains {
+			tds[i] = td.Name()
+		}
+		log.Info().Strs("trust_domains", tds).Msg("client trust domain allowlist active")
+	}
+
+	// --- Caller identity ---
+	// auth_methods is tried in order; the first method that finds a
+	// credential identifies the caller. When any method other than x509-svid
+	// is enabled, Exchange also accepts connections without a client
+	// certificate.
+	extractor := make(server.ExtractorChain, 0, len(cfg.AuthMethods))
+	var jwtSrc *workloadapi.JWTSource
+	for _, m := range cfg.AuthMethods {
+		var ext server.IDExtractor
+		switch m {
+		case authMethodX509SVID:
+			ext = certExtractor
+		case authMethodSAToken:
+			reviews, err := newTokenReviewClient()
+			if err != nil {
+				log.Fatal().Err(err).Msg("init kubernetes TokenReview client")
+			}
+			ext = kube.NewSATokenExtractor(reviews, cfg.KubeSATokenTrustDomain, cfg.KubeSATokenAudiences)
+			log.Info().Str("trust_domain", cfg.KubeSATokenTrustDomain.Name()).Strs("audiences", cfg.KubeSATokenAudiences).
+				Msg("Kubernetes ServiceAccount token authentication enabled")
+		case authMethodJWTSVID:
+			var bundles jwtbundle.Source
+			if cfg.JWTSVIDBundleFile != "" {
+				b, err := jwtbundle.Load(cfg.JWTSVIDBundleTrustDomain, cfg.JWTSVIDBundleFile)
+				if err != nil {
+					log.Fatal().Err(err).Str("file", cfg.JWTSVIDBundleFile).Msg("load JWT-SVID bundle")
+				}
+				bundles = b
+			} else {
+				jwtSrc, err = workloadapi.NewJWTSource(
+					rootCtx,
+					workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.SpiffeSocket)),
+				)
+				if err != nil {
+					log.Fatal().Err(err).Str("socket", cfg.SpiffeSocket).Msg("fetch JWT bundles from SPIRE Workload API")
+				}
+				bundles = jwtSrc
+			}
+			// By default a JWT-SVID must name this server as its audience,
+			// so tokens minted for other services cannot be replayed here.
+			audiences := cfg.JWTSVIDAudiences
+			if len(audiences) == 0 {
+				own, err := src.GetX509SVID()
+				if err != nil {
+					log.Fatal().Err(err).Msg("read own X509-SVID")
+				}
+				audiences = []string{own.ID.String()}
+			}
+			ext = spiffe.NewJWTSVIDExtractor(bundles, audiences)
+			log.Info().Strs("audiences", audiences).Str("bundle_file", cfg.JWTSVIDBundleFile).
+				Msg("JWT-SVID authentication enabled")
+		}
+		extractor = append(extractor, server.ChainedExtractor{Method: m, IDExtractor: ext})
+	}
+	log.Info().Strs("methods", cfg.AuthMethods).Msg("caller authentication methods")
+	dataTLSCfg := tlsCfg
+	if acceptsTokens(cfg.AuthMethods) {
+		dataTLSCfg = optionalClientCert(tlsCfg)
+	}
 
 	metricsInterceptor := initMetrics()
-	rateLimiter := newRateLimitInterceptor(rootCtx, cfg.RateLimitRPS, cfg.RateLimitBurst)
-	kpParams := keepalive.ServerParameters{
-		MaxConnectionIdle: 5 * time.Minute,
-		MaxConnectionAge:  30 * time.Minute,
-		Time:              2 * time.Minute,
-		Timeout:           20 * time.Second,
+	recovery := newRecoveryInterceptor(log)
+	accessLogger := log
+	if cfg.AccessLogOutput != "" {
+		accessOut, closeAccessOut, err := openLogOutput(cfg.AccessLogOutput)
+		if err != nil {
+			log.Fatal().Err(err).Str("output", cfg.AccessLogOutput).Msg("open access log output")
+		}
+		defer closeAccessOut()
+		accessLogger = newAccessLogger(accessOut)
 	}
-	kpPolicy := keepalive.EnforcementPolicy{
-		MinTime:             30 * time.Second,
-		PermitWithoutStream: true,
+	accessLog := newAccessLogInterceptor(cfg.GRPCAccessLog, accessLogger, cfg.AccessLogSampleRate, extractor)
+	sizeLimiter := newRequestSizeInterceptor(cfg.GRPCMaxExchangeMsgSizeKB*1024, exchangeMethods...)
+	rateLimiter := newRateLimitInterceptor(rootCtx, cfg.RateLimitRPS, cfg.RateLimitBurst, extractor, redactor)
+	loadShedder := newConcurrencyLimitInterceptor(cfg.MaxConcurrentExchanges, cfg.ExchangeQueueTimeout)
+	// Outermost first: metrics and the access log see the final status code,
+	// including Internal for a recovered panic; recovery covers the limiters
+	// and the handler.
+	drain := newDrainer(cfg.DrainPeriod)
+	interceptors := chainUnary(rateLimiter, loadShedder)
+	// Draining refuses exchanges before they take a rate-limit token or a
+	// concurrency slot.
+	interceptors = chainUnary(drain.interceptor(), interceptors)
+	interceptors = chainUnary(sizeLimiter, interceptors)
+	if acceptsTokens(cfg.AuthMethods) {
+		interceptors = chainUnary(newClientCertRequiredInterceptor(tokenAuthMethods...), interceptors)
+	}
+	interceptors = chainUnary(recovery, interceptors)
+	interceptors = chainUnary(accessLog, interceptors)
+	interceptors = chainUnary(metricsInterceptor, interceptors)
+	if cfg.GRPCAccessLog {
+		log.Info().Str("output", cmp.Or(cfg.AccessLogOutput, "server log")).Float64("sample_rate", cfg.AccessLogSampleRate).Msg("gRPC access logging enabled")
 	}
 	serverOpts := []grpc.ServerOption{
-		grpc.Creds(credentials.NewTLS(tlsCfg)),
-		grpc.UnaryInterceptor(chainUnary(metricsInterceptor, rateLimiter)),
+		grpc.UnaryInterceptor(interceptors),
 		newTracingServerOption(),
 		grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSizeKB * 1024),
 		grpc.MaxConcurrentStreams(cfg.GRPCMaxConcurrentStreams),
-		grpc.KeepaliveParams(kpParams),
-		grpc.KeepaliveEnforcementPolicy(kpPolicy),
+	}
+	serverOpts = append(serverOpts, cfg.GRPCKeepalive.serverOptions()...)
+	serverOpts = append(serverOpts, cfg.GRPCTransport.serverOptions()...)
+	if cfg.GRPCTLSSessions.Tickets {
+		log.Info().Dur("ticket_lifetime", cfg.GRPCTLSSessions.TicketLifetime).Msg("TLS session resumption enabled")
+	}
+	if cfg.GRPCTransport.Compression != compressionNone {
+		log.Info().Str("compressor", cfg.GRPCTransport.Compression).Msg("gRPC response compression enabled")
 	}
 
-	grpcServer := grpc.NewServer(serverOpts...)
-	svc := server.New(spiffe.Extractor{}, ap, minter, auditLog)
+	dataCreds := observeHandshakes(credentials.NewTLS(cfg.GRPCTLSSessions.apply(dataTLSCfg)), handshakeServerData, log)
+	grpcServer, err := newDataPlaneServer(cfg.GRPCXDS, dataCreds, serverOpts, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("init grpc server")
+	}
+	if cfg.GRPCXDS {
+		log.Info().Msg("data-plane server configured by xDS")
+	}
+	svc := server.New(extractor, evaluator, minter, auditRecorder{auditLog})
+	svc.SetRedactor(redactor)
+	svc.SetStageTimeouts(cfg.PolicyEvalTimeout, cfg.MintTimeout)
+	svc.SetLimits(cfg.RequestLimits)
+	svc.SetLatencyObserver(newLatencyObserver(cfg.SlowExchangeThreshold, log))
+	if cfg.SlowExchangeThreshold > 0 {
+		log.Info().Dur("threshold", cfg.SlowExchangeThreshold).Msg("slow exchange logging enabled")
+	}
+	if cfg.MaxTokenTTL > 0 {
+		svc.SetMaxTTL(cfg.MaxTokenTTL)
+		log.Info().Dur("max_token_ttl", cfg.MaxTokenTTL).Msg("token TTL ceiling enabled")
+	}
+	svc.SetDeadlineTTL(cfg.TTLFromDeadline)
+	auditFailureMode := newAuditMode(svc, cfg.AuditSpoolRequired, log)
+	if len(cfg.AuditMetadataKeys) > 0 {
+		svc.SetAuditMetadataKeys(cfg.AuditMetadataKeys)
+		log.Info().Strs("keys", cfg.AuditMetadataKeys).Msg("audit metadata recording enabled")
+	}
+	if cfg.TokenSourceBinding {
+		svc.SetSourceBinding(true)
+		log.Info().Msg("token source binding enabled")
+	}
+	if cfg.DenialCacheTTL > 0 {
+		svc.SetDenialCache(cfg.DenialCacheTTL, cfg.DenialCacheMaxTTL)
+		ap.notifySwap(svc.ResetDenialCache)
+		if breakGlass != nil {
+			breakGlass.notifyActivate(svc.ResetDenialCache)
+		}
+		log.Info().Dur("ttl", cfg.DenialCacheTTL).Dur("max_ttl", cfg.DenialCacheMaxTTL).Msg("denial cache enabled")
+	}
+	if cfg.MaxOutstandingTokens > 0 {
+		// Records for pairs that stopped exchanging are only reclaimed here;
+		// active pairs prune their own expired records on every exchange.
+		pruned, err := store.PruneIssuedTokens(time.Now().Unix())
+		if err != nil {
+			log.Fatal().Err(err).Msg("prune issued token records")
+		}
+		svc.SetTokenQuota(store, cfg.MaxOutstandingTokens)
+		log.Info().Int("limit", cfg.MaxOutstandingTokens).Int("pruned", pruned).Msg("per-subject token quota enabled")
+	}
+	if cfg.TrackGrants {
+		pruned, err := store.PruneGrants(time.Now().Unix())
+		if err != nil {
+			log.Fatal().Err(err).Msg("prune grant records")
+		}
+		svc.SetGrantStore(store)
+		log.Info().Int("pruned", pruned).Msg("grant tracking enabled")
+	}
+	// Nonces are always accepted; the ticker reclaims records of callers
+	// that stopped sending them, since UseNonce only prunes its own caller's.
+	pruned, err := store.PruneNonces(time.Now().Unix())
+	if err != nil {
+		log.Fatal().Err(err).Msg("prune nonce records")
+	}
+	svc.SetNonceStore(store, cfg.NonceWindow)
+	log.Info().Dur("window", cfg.NonceWindow).Int("pruned", pruned).Msg("request nonces enabled")
+	// Consumed single-use tokens and the first sources of presented tokens
+	// are pruned on the same ticker once they expire.
+	if _, err := store.PruneConsumedTokens(time.Now().Unix()); err != nil {
+		log.Fatal().Err(err).Msg("prune consumed token records")
+	}
+	if _, err := store.PruneTokenSources(time.Now().Unix()); err != nil {
+		log.Fatal().Err(err).Msg("prune token source records")
+	}
+	go func() {
+		ticker := time.NewTicker(cfg.NonceWindow)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := store.PruneNonces(time.Now().Unix()); err != nil {
+					log.Error().Err(err).Msg("prune nonce records")
+				}
+				if _, err := store.PruneConsumedTokens(time.Now().Unix()); err != nil {
+					log.Error().Err(err).Msg("prune consumed token records")
+				}
+				if _, err := store.PruneTokenSources(time.Now().Unix()); err != nil {
+					log.Error().Err(err).Msg("prune token source records")
+				}
+			case <-rootCtx.Done():
+				return
+			}
+		}
+	}()
+	if cfg.TargetRegistry != "" {
+		targets, err := newTargetValidator(rootCtx, cfg, log)
+		if err != nil {
+			log.Fatal().Err(err).Str("registry", cfg.TargetRegistry).Msg("init target registry")
+		}
+		svc.SetTargetValidator(targets)
+		log.Info().Str("registry", cfg.TargetRegistry).Msg("target validation enabled")
+	}
+	var approvals *server.Approvals
+	if cfg.Approvals {
+		if cfg.ApprovalWebhookURL != "" {
+			hook := &approvalWebhook{url: cfg.ApprovalWebhookURL, secret: cfg.ApprovalWebhookSecret, client: &http.Client{}, log: log}
+			approvals = server.NewApprovals(cfg.ApprovalTimeout, hook.notify)
+			hook.approvals = approvals
+		} else {
+			approvals = server.NewApprovals(cfg.ApprovalTimeout, nil)
+		}
+		svc.SetApprovals(approvals)
+		log.Info().Dur("timeout", cfg.ApprovalTimeout).Str("webhook", cfg.ApprovalWebhookURL).Msg("approvals enabled")
+	}
+	if cfg.DecisionReceipts {
+		svc.SetReceiptSigner(minter)
+		log.Info().Msg("decision receipts enabled")
+	}
+	svc.RegisterFormat(policy.FormatJWTSVID, token.NewSVIDMinter(minter))
+	svc.RegisterFormat(policy.FormatPASETO, pasetoMinter)
+	if cfg.MacaroonRootKey != nil {
+		mm, err := token.NewMacaroonMinter(cfg.MacaroonRootKey)
+		if err != nil {
+			log.Fatal().Err(err).Msg("init macaroon minter")
+		}
+		svc.RegisterFormat(policy.FormatMacaroon, mm)
+		log.Info().Msg("macaroon token format enabled")
+	}
+	// Both API versions share svc, so limits, caches, and quotas apply to
+	// their combined traffic.
 	exchangev1.RegisterTokenExchangeServer(grpcServer, svc)
+	exchangev2.RegisterTokenExchangeServer(grpcServer, svc.V2())
+	// The ext_authz service shares the data-plane listener and its mTLS: Envoy
+	// sidecars call it with their own SVID, exactly like any other workload.
+	// Token reuse detection keeps first sources in the policy store, so
+	// ext_authz and /introspect share them and they survive restarts.
+	var reuse *extauthz.ReuseDetector
+	if cfg.TokenReuseDetection {
+		h := reuseHandler{audit: auditLog, store: store, log: log}
+		if cfg.TokenReuseRevoke {
+			h.revoke = svc.Revoke
+		}
+		reuse = extauthz.NewReuseDetector(store, h.handle)
+		log.Info().Bool("revoke", cfg.TokenReuseRevoke).Msg("token reuse detection enabled")
+	}
+	if cfg.ExtAuthz {
+		authz := extauthz.New(minter, svc.IsRevoked)
+		authz.SetKeyObserver(observeVerificationKey(minter))
+		authz.SetSingleUseStore(consumeFunc(store, "ext_authz"))
+		if reuse != nil {
+			authz.SetReuseDetector(reuse)
+		}
+		authv3.RegisterAuthorizationServer(grpcServer, authz)
+		log.Info().Msg("Envoy ext_authz service enabled")
+	}
 	registerMetrics(grpcServer)
 
 	if cfg.GRPCReflection {
 		reflection.Register(grpcServer)
 	}
 
-	grpcLis, err := net.Listen("tcp", cfg.GRPCAddr)
-	if err != nil {
-		log.Fatal().Err(err).Str("addr", cfg.GRPCAddr).Msg("listen gRPC")
+	grpcAddrs := append([]string{cfg.GRPCAddr}, cfg.GRPCExtraAddrs...)
+	grpcListeners := make([]net.Listener, 0, len(grpcAddrs))
+	for _, addr := range grpcAddrs {
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal().Err(err).Str("addr", addr).Msg("listen gRPC")
+		}
+		grpcListeners = append(grpcListeners, lis)
 	}
 
-	// reloadPolicy re-reads the YAML file and merges it with dynamic policies.
-	// Called by the ReloadPolicy admin RPC.
-	reloadPolicy := func() error {
-		newPolicy, err := policy.LoadFile(cfg.PolicyFile)
+	// reloadPolicy re-reads the YAML file and merges it with dynamic policies,
+	// then re-reads the shadow policy file if one is configured. Called by the
+	// ReloadPolicy admin RPC. A file that fails to load, merge, or satisfy the
+	// rotation invariant leaves the last-known-good policy serving.
+	reloadPolicy := func() (err error) {
+		defer func() {
+			policyState.record(policySourceFile, err)
+			if err != nil {
+				log.Error().Err(err).Str("path", cfg.PolicyFile).Msg("reload policy; keeping last-known-good policy")
+			}
+		}()
+		newPolicy, err := policy.LoadFileWithConflictMode(cfg.PolicyFile, cfg.PolicyConflicts)
 		if err != nil {
 			return err
 		}
+		var check func([]policy.Policy) error
+		if cfg.KeyRotationInterval > 0 {
+			check = func(ps []policy.Policy) error { return checkRotationInvariant(ps, cfg.KeyRotationInterval) }
+		}
+		prevBase := ap.yamlPolicies()
 		ap.setBase(newPolicy.Policies())
-		if err = ap.rebuild(store); err != nil {
+		if err = ap.rebuildChecked(store, check); err != nil {
+			ap.setBase(prevBase)
 			return err
 		}
-		if cfg.KeyRotationInterval > 0 {
-			if err = checkRotationInvariant(ap.ptr.Load().Policies(), cfg.KeyRotationInterval); err != nil {
-				return err
+		// The active policy is already swapped in, so a broken shadow file
+		// only keeps the previous shadow rather than failing the reload.
+		if shadow != nil {
+			sl, err := policy.LoadFileWithConflictMode(cfg.ShadowPolicyFile, cfg.PolicyConflicts)
+			if err != nil {
+				log.Error().Err(err).Str("path", cfg.ShadowPolicyFile).Msg("reload shadow policy; keeping previous shadow")
+				return nil
 			}
+			shadow.swap(sl)
 		}
 		return nil
 	}
@@ -234,15 +889,32 @@ func main() {
 	} else {
 		log.Info().Strs("subjects", cfg.AdminSubjects).Msg("admin API RBAC allowlist active")
 	}
-	adminServer := grpc.NewServer(
-		grpc.Creds(credentials.NewTLS(tlsCfg)),
-		grpc.UnaryInterceptor(newAdminAuthInterceptor(cfg.AdminSubjects, spiffe.Extractor{})),
-		grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSizeKB*1024),
+	adminServer := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.Creds(observeHandshakes(credentials.NewTLS(cfg.GRPCTLSSessions.apply(tlsCfg)), handshakeServerAdmin, log)),
+		grpc.UnaryInterceptor(chainUnary(accessLog, chainUnary(recovery, newAdminAuthInterceptor(cfg.AdminSubjects, certExtractor, redactor)))),
+		grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSizeKB * 1024),
 		grpc.MaxConcurrentStreams(cfg.GRPCMaxConcurrentStreams),
-		grpc.KeepaliveParams(kpParams),
-		grpc.KeepaliveEnforcementPolicy(kpPolicy),
-	)
-	adminSvc := admin.New(store, ap.yamlPolicies, ap.swap, reloadPolicy, svc.Revoke)
+	}, append(cfg.GRPCKeepalive.serverOptions(), cfg.GRPCTransport.serverOptions()...)...)...)
+	// ExchangePolicy resources are passed alongside the YAML base so the admin
+	// API rejects conflicting dynamic policies and keeps them in rebuilt loaders.
+	adminSvc := admin.New(store, ap.staticPolicies, ap.newLoader, ap.swap, reloadPolicy, svc.Revoke)
+	if breakGlass != nil {
+		adminSvc.SetBreakGlass(breakGlass)
+	}
+	if approvals != nil {
+		adminSvc.SetApprovals(approvalAdmin{approvals: approvals, actor: certExtractor})
+	}
+	adminSvc.SetDrainer(drain)
+	adminSvc.SetLogLeveler(logLvl)
+	if cfg.AuditSpoolDir != "" {
+		adminSvc.SetAuditModeSwitcher(auditFailureMode)
+	}
+	if issuanceStats != nil {
+		adminSvc.SetStats(issuanceStats)
+	}
+	if ruleUsage != nil {
+		adminSvc.SetRuleUsage(ruleUsage)
+	}
 	adminv1.RegisterPolicyAdminServer(adminServer, adminSvc)
 	if cfg.GRPCReflection {
 		reflection.Register(adminServer)
@@ -252,80 +924,248 @@ func main() {
 		log.Fatal().Err(err).Str("addr", cfg.AdminAddr).Msg("listen admin gRPC")
 	}
 
-	// --- Health HTTP server ---
-	var ready atomic.Bool
-	ready.Store(true) // ready once policy + minter are initialised (already done above)
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health/live", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
-	mux.HandleFunc("/health/ready", func(w http.ResponseWriter, _ *http.Request) {
-		if ready.Load() {
-			w.WriteHeader(http.StatusOK)
-			return
+	// --- HTTP listeners ---
+	// Health always listens on health_addr; metrics and key distribution get
+	// their own listeners when configured under http_listeners, each with its
+	// own TLS and client-certificate requirements.
+	// /health/ready probes each dependency on every request; the instance
+	// is ready only when all pass.
+	ready := &readiness{}
+	// A stale policy still passes: the last-known-good set keeps serving,
+	// and the detail reports its age and the failed reloads.
+	ready.addWithDetail("policy", func(context.Context) error {
+		if ap.ptr.Load() == nil {
+			return errors.New("no policy loaded")
 		}
-		w.WriteHeader(http.StatusServiceUnavailable)
+		return nil
+	}, func() any { return policyState.report() })
+	ready.add("signer", minter.Ping)
+	ready.add("store", func(context.Context) error { return store.Ping() })
+	ready.add("svid", func(context.Context) error {
+		_, err := src.GetX509SVID()
+		return err
 	})
-	mux.HandleFunc("/jwks", newJWKSHandler(minter, log))
-	mux.Handle("/metrics", newMetricsHandler())
-	healthServer := &http.Server{
-		Addr:              cfg.HealthAddr,
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      10 * time.Second,
-		IdleTimeout:       60 * time.Second,
+	ready.add("grpc_listener", servingProbe(&grpcServing))
+	ready.add("admin_listener", servingProbe(&adminServing))
+	httpServers := make(map[string]*httpserv.Server, len(cfg.HTTPListeners))
+	for name, lc := range cfg.HTTPListeners {
+		if httpServers[name], err = httpserv.New(lc); err != nil {
+			log.Fatal().Err(err).Msg("init HTTP listener")
+		}
+	}
+	// handle routes path to its listener and applies the endpoint's access
+	// mode and CORS policy. CORS wraps auth so browser preflights, which
+	// carry no credentials, are answered before authentication.
+	handle := func(path string, h http.Handler) {
+		h = httpserv.WithEndpointAuth(cfg.HealthEndpointAuth[path], cfg.HealthBearerToken, h)
+		httpServers[listenerFor(cfg.HTTPListeners, path)].Handle(path, httpserv.WithCORS(cfg.CORSAllowedOrigins, h))
 	}
+	handle("/health/live", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handle("/health/ready", ready.handler(log))
+	handle("/jwks", newJWKSHandler(minter, log))
+	if cfg.HealthEndpointAuth[introspectPath] != "" {
+		handle(introspectPath, newIntrospectionHandler(minter, svc.IsRevoked, consumeFunc(store, "introspect"), observeVerificationKey(minter), reuse, log))
+	} else {
+		log.Info().Msg("introspection endpoint disabled: health_endpoint_auth does not set bearer or mtls for /introspect")
+	}
+	handle("/paseto-keys", newPASETOKeysHandler(pasetoKeys, log))
+	handle("/jwt-svid-bundle", newSPIFFEBundleHandler(minter, bundleRefreshHint(cfg.KeyRotationInterval), log))
+	handle("/metrics", newMetricsHandler())
 
-	// --- Start ---
-	go func() {
-		log.Info().Str("addr", cfg.GRPCAddr).Msg("gRPC listening")
-		if err := grpcServer.Serve(grpcLis); err != nil {
-			log.Error().Err(err).Msg("gRPC serve error")
+	// --- REST gateway ---
+	// Serves the grpc-gateway REST+JSON mapping of Exchange for callers
+	// without a gRPC stack, over the data plane's SPIFFE mTLS.
+	if cfg.RESTGatewayAddr != "" {
+		gw, err := newRESTGateway(rootCtx, svc, interceptors)
+		if err != nil {
+			log.Fatal().Err(err).Msg("init REST gateway")
 		}
-	}()
+		gatewayServer, err := httpserv.New(httpserv.Config{Name: "rest", Addr: cfg.RESTGatewayAddr, TLSConfig: dataPlaneHTTPSTLS(tlsCfg)})
+		if err != nil {
+			log.Fatal().Err(err).Msg("init REST gateway listener")
+		}
+		gatewayServer.Handle(restGatewayPath, gw)
+		httpServers["rest"] = gatewayServer
+		log.Info().Str("path", restGatewayPath).Msg("REST gateway enabled")
+	}
+
+	// --- Connect ---
+	// Serves both exchange API versions over the Connect protocol, sharing
+	// the gRPC handlers, for Connect-native and browser-adjacent clients.
+	if cfg.ConnectAddr != "" {
+		connectServer, err := httpserv.New(httpserv.Config{Name: "connect", Addr: cfg.ConnectAddr, TLSConfig: dataPlaneHTTPSTLS(tlsCfg)})
+		if err != nil {
+			log.Fatal().Err(err).Msg("init Connect listener")
+		}
+		connectServer.Handle("/", newConnectHandler(svc, svc.V2(), interceptors))
+		httpServers["connect"] = connectServer
+		log.Info().Str("addr", cfg.ConnectAddr).Msg("Connect protocol enabled")
+	}
+
+	// --- SPIFFE bundle endpoint ---
+	// Publishes the trust domain's X.509 authorities and JWT-SVID signing
+	// keys for federated trust domains. Clients are not authenticated;
+	// with https_spiffe the server authenticates with its own SVID.
+	if cfg.BundleEndpointAddr != "" {
+		lc := httpserv.Config{Name: "bundle", Addr: cfg.BundleEndpointAddr}
+		if cfg.BundleEndpointProfile == bundleProfileWeb {
+			lc.CertFile, lc.KeyFile, lc.CertRefresh = cfg.BundleCertFile, cfg.BundleKeyFile, cfg.BundleCertRefresh
+		} else {
+			lc.TLSConfig = tlsconfig.TLSServerConfig(src)
+		}
+		bundleServer, err := httpserv.New(lc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("init bundle endpoint listener")
+		}
+		bundleSrc := trustBundleSource{src: src, keys: minter, refreshHint: bundleRefreshHint(cfg.KeyRotationInterval)}
+		bundleServer.Handle("/", newTrustBundleHandler(bundleSrc, log))
+		httpServers["bundle"] = bundleServer
+		log.Info().Str("addr", cfg.BundleEndpointAddr).Str("profile", cfg.BundleEndpointProfile).Msg("SPIFFE bundle endpoint enabled")
+	}
+
+	// --- Debug listener ---
+	// Opt-in pprof and runtime stats for profiling; loopback-only unless
+	// debug_allow_remote is set.
+	if cfg.DebugAddr != "" {
+		debugServer, err := httpserv.New(httpserv.Config{Name: "debug", Addr: cfg.DebugAddr, WriteTimeout: debugWriteTimeout})
+		if err != nil {
+			log.Fatal().Err(err).Msg("init debug listener")
+		}
+		debugServer.Handle("/", newDebugMux(time.Now(), effective, log))
+		httpServers["debug"] = debugServer
+		if cfg.DebugAllowRemote {
+			log.Warn().Str("addr", cfg.DebugAddr).Msg("debug listener may be reachable off-host")
+		}
+	}
+
+	// --- Admission webhook ---
+	// Validates ExchangePolicy resources before the API server stores them.
+	// Served on its own HTTPS listener because the API server only calls
+	// webhooks over TLS with a certificate it trusts via caBundle.
+	if cfg.KubeWebhookAddr != "" {
+		webhookServer, err := httpserv.New(httpserv.Config{
+			Name:        "webhook",
+			Addr:        cfg.KubeWebhookAddr,
+			CertFile:    cfg.KubeWebhookCertFile,
+			KeyFile:     cfg.KubeWebhookKeyFile,
+			CertRefresh: cfg.KubeWebhookCertRefresh,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("init admission webhook listener")
+		}
+		webhookServer.Handle("/validate-exchangepolicy", kube.NewWebhookHandler(log))
+		httpServers["webhook"] = webhookServer
+	}
+
+	// --- Start ---
+	// One server serves every address; GracefulStop closes them all.
+	// Readiness reflects the primary address.
+	for i, lis := range grpcListeners {
+		go func() {
+			log.Info().Str("addr", grpcAddrs[i]).Msg("gRPC listening")
+			if i == 0 {
+				grpcServing.Store(true)
+				defer grpcServing.Store(false)
+			}
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Error().Err(err).Str("addr", grpcAddrs[i]).Msg("gRPC serve error")
+			}
+		}()
+	}
 
 	go func() {
 		log.Info().Str("addr", cfg.AdminAddr).Msg("admin gRPC listening")
+		adminServing.Store(true)
+		defer adminServing.Store(false)
 		if err := adminServer.Serve(adminLis); err != nil {
 			log.Error().Err(err).Msg("admin gRPC serve error")
 		}
 	}()
 
-	go func() {
-		log.Info().Str("addr", cfg.HealthAddr).Msg("health HTTP listening")
-		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error().Err(err).Msg("health serve error")
-		}
-	}()
+	for _, hs := range httpServers {
+		hs.Start(log)
+	}
+	supervisors.ready()
 
 	// --- Graceful shutdown ---
-	quit := make(chan os.Signal, 1)
+	// SIGTERM and the Drain admin RPC both drain: readiness fails and new
+	// exchanges are refused for drain_period, so load balancers move traffic
+	// away, before the listeners stop. A second signal skips the wait.
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case sig := <-quit:
+		log.Info().Str("signal", sig.String()).Msg("shutdown signal received")
+	case <-drain.done():
+		log.Info().Msg("drain requested via admin API")
+	}
+	exitAt := drain.begin()
+	ready.shutdown()
+	supervisors.stopping()
+	if cfg.DrainPeriod > 0 {
+		log.Info().Time("exit_at", exitAt).Msg("draining")
+		waitCtx, stopWait := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		drain.wait(waitCtx)
+		stopWait()
+	}
 
-	log.Info().Msg("shutting down")
-	ready.Store(false)
-	grpcServer.GracefulStop()  // drain in-flight RPCs (source still serves from cache)
-	adminServer.GracefulStop() // drain in-flight admin RPCs
-	rootCancel()               // stop Workload API watcher and rotation goroutine
+	// Every step below shares shutdownTimeout, so a stuck subsystem cannot
+	// hold the process past it. In-flight calls finish, and their audit
+	// events, grants, and revocations are written, before the stores close.
+	log.Info().Int("in_flight", svc.InFlight()).Msg("shutting down")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+	stopGRPC(shutdownCtx, grpcServer, "grpc", log)   // drain in-flight RPCs (source still serves from cache)
+	stopGRPC(shutdownCtx, adminServer, "admin", log) // drain in-flight admin RPCs
+	for name, hs := range httpServers {              // drain REST gateway calls
+		if err := hs.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Str("listener", name).Msg("HTTP server shutdown error")
+		}
+	}
+	if err := svc.Wait(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("abandoning in-flight exchanges")
+	}
+	if err := auditLog.FlushSpool(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("flush audit spool; the rest is shipped on restart")
+	}
+	rootCancel() // stop Workload API watcher, rotation goroutine, and spool shipper
+	if ruleUsage != nil {
+		if err := ruleUsage.Flush(time.Now()); err != nil {
+			log.Error().Err(err).Msg("flush policy rule usage")
+		}
+	}
 	if err := store.Close(); err != nil {
 		log.Error().Err(err).Msg("close policy store")
 	}
+	if extAuthorizer != nil {
+		if err := extAuthorizer.Close(); err != nil {
+			log.Error().Err(err).Msg("close external authorizer client")
+		}
+	}
 	if err := src.Close(); err != nil {
 		log.Error().Err(err).Msg("close X509Source")
 	}
+	if jwtSrc != nil {
+		if err := jwtSrc.Close(); err != nil {
+			log.Error().Err(err).Msg("close JWTSource")
+		}
+	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
-	defer shutdownCancel()
+	select {
+	case <-leaderDone: // lease released
+	case <-shutdownCtx.Done():
+	}
 	if err := tracingShutdown(shutdownCtx); err != nil {
 		log.Error().Err(err).Msg("flush traces")
 	}
-	if err := healthServer.Shutdown(shutdownCtx); err != nil {
-		log.Error().Err(err).Msg("health server shutdown error")
+
+	if signingPipeline != nil {
+		signingPipeline.Close()
 	}
 
 	log.Info().Msg("stopped")
+	supervisors.stopped()
 }
 
 // checkRotationInvariant returns an error if any policy's max_ttl exceeds the
@@ -2,12 +2,47 @@ package main
 
 import (
 	"net/http"
+	"strconv"
 
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
 )
 
+// auditEvents counts exchange audit events by outcome and by whether grant
+// sampling wrote them to the audit log, so that exact totals survive
+// sampling.
+var auditEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_audit_events_total",
+	Help: "Exchange audit events, by outcome (granted|denied) and whether they were written to the audit log (written=true|false).",
+}, []string{"outcome", "written"})
+
+// ttlClamped counts grants whose policy TTL exceeded max_token_ttl and was
+// cut down to it, a sign that a policy's max_ttl is out of line with the
+// ceiling.
+var ttlClamped = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "svid_exchange_ttl_clamped_total",
+	Help: "Grants whose TTL was clamped to the max_token_ttl ceiling.",
+})
+
+// observeAuditEvent records e in auditEvents and, when its TTL was clamped,
+// in ttlClamped. It is the audit logger's
+// sampling observer.
+func observeAuditEvent(e audit.ExchangeEvent, written bool) {
+	outcome := "denied"
+	if e.Granted {
+		outcome = "granted"
+	}
+	auditEvents.WithLabelValues(outcome, strconv.FormatBool(written)).Inc()
+	if e.Granted && e.TTLClampedFrom > 0 {
+		ttlClamped.Inc()
+	}
+}
+
 // initMetrics enables per-RPC latency histograms and returns the unary server
 // interceptor that records counts, status codes, and latencies for every RPC.
 // Must be called before grpc.NewServer so the histogram options take effect.
@@ -19,8 +54,27 @@ func initMetrics() grpc.UnaryServerInterceptor {
 // registerMetrics pre-populates per-method series at zero for every service
 // registered on s. Without this, a method only appears in /metrics after its
 // first call, which makes alerting on absence unreliable.
-func registerMetrics(s *grpc.Server) {
-	grpc_prometheus.Register(s)
+func registerMetrics(s grpcServer) {
+	if gs, ok := s.(*grpc.Server); ok {
+		grpc_prometheus.Register(gs)
+		return
+	}
+	// grpc_prometheus only accepts a *grpc.Server and reads nothing from it
+	// but method names, so the services of any other server, such as an xDS
+	// one, are mirrored onto a server that is never started.
+	mirror := grpc.NewServer()
+	for name, info := range s.GetServiceInfo() {
+		desc := grpc.ServiceDesc{ServiceName: name, HandlerType: (*any)(nil)}
+		for _, m := range info.Methods {
+			if m.IsClientStream || m.IsServerStream {
+				desc.Streams = append(desc.Streams, grpc.StreamDesc{StreamName: m.Name, ServerStreams: m.IsServerStream, ClientStreams: m.IsClientStream})
+			} else {
+				desc.Methods = append(desc.Methods, grpc.MethodDesc{MethodName: m.Name})
+			}
+		}
+		mirror.RegisterService(&desc, nil)
+	}
+	grpc_prometheus.Register(mirror)
 }
 
 // newMetricsHandler returns an HTTP handler that serves the Prometheus text
@@ -5,6 +5,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
 )
 
 func TestInitMetrics(t *testing.T) {
@@ -34,3 +40,71 @@ func TestNewMetricsHandler(t *testing.T) {
 		t.Errorf("Content-Type = %q, want text/plain", ct)
 	}
 }
+
+func TestObserveAuditEvent(t *testing.T) {
+	dropped := auditEvents.WithLabelValues("granted", "false")
+	written := auditEvents.WithLabelValues("denied", "true")
+	beforeDropped, beforeWritten := testutil.ToFloat64(dropped), testutil.ToFloat64(written)
+
+	observeAuditEvent(audit.ExchangeEvent{Granted: true}, false)
+	observeAuditEvent(audit.ExchangeEvent{Granted: false}, true)
+
+	if got := testutil.ToFloat64(dropped) - beforeDropped; got != 1 {
+		t.Errorf("dropped grants = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(written) - beforeWritten; got != 1 {
+		t.Errorf("written denials = %v, want 1", got)
+	}
+}
+
+func TestObserveAuditEventTTLClamped(t *testing.T) {
+	before := testutil.ToFloat64(ttlClamped)
+
+	observeAuditEvent(audit.ExchangeEvent{Granted: true, TTL: 600, TTLClampedFrom: 3600}, true)
+	observeAuditEvent(audit.ExchangeEvent{Granted: true, TTL: 300}, false)
+
+	if got := testutil.ToFloat64(ttlClamped) - before; got != 1 {
+		t.Errorf("clamped grants = %v, want 1", got)
+	}
+}
+
+// wrappedServer is a grpcServer that is not a *grpc.Server, as an xDS
+// server is not.
+type wrappedServer struct{ *grpc.Server }
+
+func TestRegisterMetricsMirrorsServices(t *testing.T) {
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Mirror",
+		HandlerType: (*any)(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Ping"}},
+		Streams:     []grpc.StreamDesc{{StreamName: "Watch", ServerStreams: true}},
+	}, nil)
+
+	registerMetrics(wrappedServer{s})
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	found := map[string]bool{}
+	for _, f := range families {
+		if f.GetName() != "grpc_server_started_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["grpc_service"] == "test.Mirror" {
+				found[labels["grpc_method"]+" "+labels["grpc_type"]] = true
+			}
+		}
+	}
+	for _, want := range []string{"Ping unary", "Watch server_stream"} {
+		if !found[want] {
+			t.Errorf("no pre-registered series for %s; got %v", want, found)
+		}
+	}
+}
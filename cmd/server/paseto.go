@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// pasetoKey is one entry of the /paseto-keys document.
+type pasetoKey struct {
+	Kid     string `json:"kid"`
+	Version string `json:"version"`
+	Purpose string `json:"purpose"`
+	// PASERK is the key in PASERK k4.public form.
+	PASERK string `json:"paserk"`
+}
+
+type pasetoKeySet struct {
+	Keys []pasetoKey `json:"keys"`
+}
+
+// newPASETOKeysHandler serves the PASETO v4.public verification keys. It is
+// the PASETO counterpart of /jwks: during a rotation window both keys are
+// listed, and kid matches the kid in each token's footer.
+func newPASETOKeysHandler(kp keyProvider, log zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		doc := pasetoKeySet{Keys: make([]pasetoKey, 0)}
+		for _, pub := range kp.PublicKeys() {
+			edPub, ok := pub.(ed25519.PublicKey)
+			if !ok {
+				continue
+			}
+			kid, err := token.KeyID(edPub)
+			if err != nil {
+				log.Error().Err(err).Msg("paseto keys: compute key id")
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			doc.Keys = append(doc.Keys, pasetoKey{Kid: kid, Version: "v4", Purpose: "public", PASERK: token.PASERKPublic(edPub)})
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			log.Error().Err(err).Msg("paseto keys: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("paseto keys: write response")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestPASETOKeysHandler(t *testing.T) {
+	m, err := token.NewMinterWithAlgorithm(token.EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	p, err := token.NewPASETOMinter(m)
+	if err != nil {
+		t.Fatalf("NewPASETOMinter: %v", err)
+	}
+	res, err := p.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if err := m.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	newPASETOKeysHandler(m, zerolog.Nop())(rec, httptest.NewRequest(http.MethodGet, "/paseto-keys", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var doc pasetoKeySet
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(doc.Keys) != 2 {
+		t.Fatalf("keys = %d, want 2 during rotation window", len(doc.Keys))
+	}
+
+	// A consumer that only has the document must be able to verify a token
+	// signed by the previous key.
+	var keys []crypto.PublicKey
+	for _, k := range doc.Keys {
+		if k.Version != "v4" || k.Purpose != "public" {
+			t.Errorf("key %s: version/purpose = %s/%s, want v4/public", k.Kid, k.Version, k.Purpose)
+		}
+		raw, ok := strings.CutPrefix(k.PASERK, token.PASERKPublicPrefix)
+		if !ok {
+			t.Fatalf("paserk = %q, want k4.public. prefix", k.PASERK)
+		}
+		b, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil {
+			t.Fatalf("decode paserk: %v", err)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	if _, err := token.VerifyPASETO(res.Token, keys, "spiffe://td/b"); err != nil {
+		t.Errorf("VerifyPASETO with published keys: %v", err)
+	}
+}
+
+func TestPASETOKeysHandlerSkipsNonEd25519(t *testing.T) {
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	newPASETOKeysHandler(m, zerolog.Nop())(rec, httptest.NewRequest(http.MethodGet, "/paseto-keys", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"keys":[]}` {
+		t.Errorf("body = %s, want empty key list", got)
+	}
+}
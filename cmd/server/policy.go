@@ -1,36 +1,73 @@
 package main
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 
+	"github.com/rs/zerolog"
+
 	"github.com/ngaddam369/svid-exchange/internal/policy"
 )
 
 // atomicPolicy is a PolicyEvaluator whose underlying policy can be swapped
 // atomically at runtime without disrupting in-flight requests.
-// It also tracks the YAML-sourced base policies separately from dynamic
-// policies so that the ReloadPolicy RPC and the admin API can merge them correctly.
+// It also tracks the YAML-sourced base policies and the ExchangePolicy
+// resources separately from dynamic policies so that the ReloadPolicy RPC,
+// the Kubernetes policy source, and the admin API can merge them correctly.
+// Every rebuilt loader keeps the initial loader's conflict mode.
 type atomicPolicy struct {
 	ptr  atomic.Pointer[policy.Loader]
 	mu   sync.RWMutex
 	base []policy.Policy // YAML-sourced policies; updated on ReloadPolicy
+	crd  []policy.Policy // ExchangePolicy resources; updated by the kube source
+	log  zerolog.Logger
+	// onSwap is called after every swap, so that caches of policy
+	// decisions can be dropped. Guarded by mu.
+	onSwap []func()
 }
 
-func newAtomicPolicy(initial *policy.Loader) *atomicPolicy {
-	ap := &atomicPolicy{base: initial.Policies()}
-	ap.ptr.Store(initial)
+// newAtomicPolicy returns an atomicPolicy serving initial. Conflicting rules
+// in initial and in every loader swapped in later are logged to log.
+func newAtomicPolicy(initial *policy.Loader, log zerolog.Logger) *atomicPolicy {
+	ap := &atomicPolicy{base: initial.Policies(), log: log}
+	ap.swap(initial)
 	return ap
 }
 
 // Evaluate delegates to the currently loaded policy. Safe for concurrent use.
-func (ap *atomicPolicy) Evaluate(subject, target string, scopes []string, ttlSeconds int32) policy.EvalResult {
-	return ap.ptr.Load().Evaluate(subject, target, scopes, ttlSeconds)
+// Evaluation is in-memory and never blocks, so ctx is only checked up front.
+func (ap *atomicPolicy) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	if err := ctx.Err(); err != nil {
+		return policy.EvalResult{}, err
+	}
+	return ap.ptr.Load().Evaluate(subject, target, scopes, ttlSeconds), nil
 }
 
 // swap replaces the active policy atomically.
 func (ap *atomicPolicy) swap(p *policy.Loader) {
+	for _, c := range p.Conflicts() {
+		ap.log.Warn().Str("first", c.FirstName).Str("second", c.SecondName).Strs("differences", c.Differences).
+			Str("mode", string(p.ConflictMode())).Msg("conflicting policy rules")
+	}
 	ap.ptr.Store(p)
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	for _, f := range ap.onSwap {
+		f()
+	}
+}
+
+// notifySwap registers f to be called after every subsequent swap.
+func (ap *atomicPolicy) notifySwap(f func()) {
+	ap.mu.Lock()
+	ap.onSwap = append(ap.onSwap, f)
+	ap.mu.Unlock()
+}
+
+// newLoader builds a Loader for ps with the active conflict mode.
+func (ap *atomicPolicy) newLoader(ps []policy.Policy) (*policy.Loader, error) {
+	return policy.NewLoaderWithConflictMode(ps, ap.ptr.Load().ConflictMode())
 }
 
 // setBase updates the YAML-sourced base policies. Called after a successful
@@ -41,6 +78,27 @@ func (ap *atomicPolicy) setBase(ps []policy.Policy) {
 	ap.mu.Unlock()
 }
 
+// setCRD updates the policies sourced from ExchangePolicy resources and
+// returns the previous set so that a failed rebuild can be rolled back.
+// Called by the kube source after every reconcile.
+func (ap *atomicPolicy) setCRD(ps []policy.Policy) []policy.Policy {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	prev := ap.crd
+	ap.crd = ps
+	return prev
+}
+
+// staticPolicies returns a copy of every policy that is not managed through
+// the admin API: the YAML base followed by the ExchangePolicy resources.
+func (ap *atomicPolicy) staticPolicies() []policy.Policy {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	out := make([]policy.Policy, 0, len(ap.base)+len(ap.crd))
+	out = append(out, ap.base...)
+	return append(out, ap.crd...)
+}
+
 // yamlPolicies returns a copy of the current YAML-sourced base policies.
 func (ap *atomicPolicy) yamlPolicies() []policy.Policy {
 	ap.mu.RLock()
@@ -50,18 +108,29 @@ func (ap *atomicPolicy) yamlPolicies() []policy.Policy {
 	return out
 }
 
-// rebuild merges the current YAML base with all dynamic store policies and
-// swaps the result in atomically.
+// rebuild merges the current YAML base and ExchangePolicy resources with all
+// dynamic store policies and swaps the result in atomically.
 func (ap *atomicPolicy) rebuild(store *policy.Store) error {
+	return ap.rebuildChecked(store, nil)
+}
+
+// rebuildChecked is rebuild, but when check is non-nil it must accept the
+// merged policies first; otherwise the serving policy is left in place.
+func (ap *atomicPolicy) rebuildChecked(store *policy.Store, check func([]policy.Policy) error) error {
 	dynamic, err := store.List()
 	if err != nil {
 		return err
 	}
-	yaml := ap.yamlPolicies()
-	merged := make([]policy.Policy, 0, len(yaml)+len(dynamic))
-	merged = append(merged, yaml...)
+	static := ap.staticPolicies()
+	merged := make([]policy.Policy, 0, len(static)+len(dynamic))
+	merged = append(merged, static...)
 	merged = append(merged, dynamic...)
-	loader, err := policy.NewLoader(merged)
+	if check != nil {
+		if err := check(merged); err != nil {
+			return err
+		}
+	}
+	loader, err := ap.newLoader(merged)
 	if err != nil {
 		return err
 	}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
+
 	"github.com/ngaddam369/svid-exchange/internal/policy"
 )
 
@@ -35,6 +38,17 @@ func loadTestPolicy(t *testing.T, subject, target string) *policy.Loader {
 	return pl
 }
 
+// mustEvaluate evaluates a fixed "r:w" request against ap and fails the test
+// if evaluation itself errors.
+func mustEvaluate(t *testing.T, ap *atomicPolicy, subject, target string) policy.EvalResult {
+	t.Helper()
+	res, err := ap.Evaluate(context.Background(), subject, target, []string{"r:w"}, 30)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	return res
+}
+
 func newTestStore(t *testing.T) *policy.Store {
 	t.Helper()
 	s, err := policy.OpenStore(filepath.Join(t.TempDir(), "test.db"))
@@ -57,26 +71,26 @@ func TestAtomicPolicy(t *testing.T) {
 	)
 
 	t.Run("evaluates against initial policy", func(t *testing.T) {
-		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 
-		res := ap.Evaluate(subA, tgt, []string{"r:w"}, 30)
+		res := mustEvaluate(t, ap, subA, tgt)
 		if !res.Allowed {
 			t.Error("expected Allowed=true for initial policy")
 		}
-		res = ap.Evaluate(subB, tgt, []string{"r:w"}, 30)
+		res = mustEvaluate(t, ap, subB, tgt)
 		if res.Allowed {
 			t.Error("expected Allowed=false for subB not in initial policy")
 		}
 	})
 
 	t.Run("swap changes which policy is active", func(t *testing.T) {
-		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 
 		// Before swap: subA allowed, subB denied.
-		if !ap.Evaluate(subA, tgt, []string{"r:w"}, 30).Allowed {
+		if !mustEvaluate(t, ap, subA, tgt).Allowed {
 			t.Fatal("subA should be allowed before swap")
 		}
-		if ap.Evaluate(subB, tgt, []string{"r:w"}, 30).Allowed {
+		if mustEvaluate(t, ap, subB, tgt).Allowed {
 			t.Fatal("subB should be denied before swap")
 		}
 
@@ -84,10 +98,10 @@ func TestAtomicPolicy(t *testing.T) {
 		ap.swap(loadTestPolicy(t, subB, tgt))
 
 		// After swap: subB allowed, subA denied.
-		if ap.Evaluate(subA, tgt, []string{"r:w"}, 30).Allowed {
+		if mustEvaluate(t, ap, subA, tgt).Allowed {
 			t.Error("subA should be denied after swap")
 		}
-		if !ap.Evaluate(subB, tgt, []string{"r:w"}, 30).Allowed {
+		if !mustEvaluate(t, ap, subB, tgt).Allowed {
 			t.Error("subB should be allowed after swap")
 		}
 	})
@@ -99,7 +113,7 @@ func TestAtomicPolicySetBase(t *testing.T) {
 		subB = "spiffe://cluster.local/ns/default/sa/b"
 		tgt  = "spiffe://cluster.local/ns/default/sa/target"
 	)
-	ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+	ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 
 	// Replace base with a new set of policies.
 	newBase := loadTestPolicy(t, subB, tgt).Policies()
@@ -125,19 +139,19 @@ func TestAtomicPolicyRebuild(t *testing.T) {
 	)
 
 	t.Run("empty store preserves YAML policies", func(t *testing.T) {
-		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 		store := newTestStore(t)
 
 		if err := ap.rebuild(store); err != nil {
 			t.Fatalf("rebuild: %v", err)
 		}
-		if !ap.Evaluate(subA, tgt, []string{"r:w"}, 30).Allowed {
+		if !mustEvaluate(t, ap, subA, tgt).Allowed {
 			t.Error("subA should still be allowed after rebuild with empty store")
 		}
 	})
 
 	t.Run("dynamic policies are merged with YAML", func(t *testing.T) {
-		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 		store := newTestStore(t)
 
 		if err := store.Save(policy.Policy{
@@ -153,16 +167,16 @@ func TestAtomicPolicyRebuild(t *testing.T) {
 		if err := ap.rebuild(store); err != nil {
 			t.Fatalf("rebuild: %v", err)
 		}
-		if !ap.Evaluate(subA, tgt, []string{"r:w"}, 30).Allowed {
+		if !mustEvaluate(t, ap, subA, tgt).Allowed {
 			t.Error("subA should be allowed after rebuild")
 		}
-		if !ap.Evaluate(subB, tgt, []string{"r:w"}, 30).Allowed {
+		if !mustEvaluate(t, ap, subB, tgt).Allowed {
 			t.Error("subB should be allowed after rebuild with dynamic policy")
 		}
 	})
 
 	t.Run("duplicate subject-target pair returns error", func(t *testing.T) {
-		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+		ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 		store := newTestStore(t)
 
 		// Dynamic policy duplicates the YAML policy's (subject, target) pair.
@@ -180,6 +194,73 @@ func TestAtomicPolicyRebuild(t *testing.T) {
 			t.Error("expected error for duplicate (subject, target) pair, got nil")
 		}
 	})
+
+	t.Run("rule order does not depend on store write order", func(t *testing.T) {
+		dynamic := []policy.Policy{
+			{Name: "dyn-b", Subject: subB, Target: tgt, AllowedScopes: []string{"r:w"}, MaxTTL: 60},
+			{Name: "dyn-any", Subject: "spiffe://cluster.local/ns/default/sa/*", Target: tgt, AllowedScopes: []string{"r:w"}, MaxTTL: 30},
+		}
+		var digests []string
+		for _, order := range [][]int{{0, 1}, {1, 0}} {
+			ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
+			store := newTestStore(t)
+			for _, i := range order {
+				if err := store.Save(dynamic[i]); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+			}
+			if err := ap.rebuild(store); err != nil {
+				t.Fatalf("rebuild: %v", err)
+			}
+			digests = append(digests, ap.ptr.Load().Digest())
+		}
+		if digests[0] != digests[1] {
+			t.Errorf("digests differ by write order: %s vs %s", digests[0], digests[1])
+		}
+	})
+}
+
+func TestAtomicPolicySetCRD(t *testing.T) {
+	const (
+		subA = "spiffe://cluster.local/ns/default/sa/a"
+		subB = "spiffe://cluster.local/ns/default/sa/b"
+		tgt  = "spiffe://cluster.local/ns/default/sa/target"
+	)
+	ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
+	store := newTestStore(t)
+
+	crd := []policy.Policy{{
+		Name:          "default/b",
+		Subject:       subB,
+		Target:        tgt,
+		AllowedScopes: []string{"r:w"},
+		MaxTTL:        60,
+	}}
+	if prev := ap.setCRD(crd); prev != nil {
+		t.Errorf("setCRD returned %v on first call, want nil", prev)
+	}
+	if err := ap.rebuild(store); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if !mustEvaluate(t, ap, subB, tgt).Allowed {
+		t.Error("subB should be allowed via ExchangePolicy resource")
+	}
+	if got := ap.staticPolicies(); len(got) != 2 || got[1].Name != "default/b" {
+		t.Errorf("staticPolicies = %v, want YAML policy followed by default/b", got)
+	}
+	if got := ap.yamlPolicies(); len(got) != 1 {
+		t.Errorf("yamlPolicies len = %d, want 1 (CRD policies must not leak into the YAML base)", len(got))
+	}
+
+	if prev := ap.setCRD(nil); len(prev) != 1 {
+		t.Errorf("setCRD returned %d previous policies, want 1", len(prev))
+	}
+	if err := ap.rebuild(store); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if mustEvaluate(t, ap, subB, tgt).Allowed {
+		t.Error("subB should be denied after its ExchangePolicy resource is removed")
+	}
 }
 
 func TestAtomicPolicyConcurrentRebuildEvaluate(t *testing.T) {
@@ -187,7 +268,7 @@ func TestAtomicPolicyConcurrentRebuildEvaluate(t *testing.T) {
 		subA = "spiffe://cluster.local/ns/default/sa/a"
 		tgt  = "spiffe://cluster.local/ns/default/sa/target"
 	)
-	ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt))
+	ap := newAtomicPolicy(loadTestPolicy(t, subA, tgt), zerolog.Nop())
 	store := newTestStore(t)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
@@ -207,7 +288,7 @@ func TestAtomicPolicyConcurrentRebuildEvaluate(t *testing.T) {
 				default:
 				}
 				// Must not panic regardless of concurrent rebuilds.
-				_ = ap.Evaluate(subA, tgt, []string{"r:w"}, 30)
+				_, _ = ap.Evaluate(context.Background(), subA, tgt, []string{"r:w"}, 30)
 			}
 		}()
 	}
@@ -230,3 +311,27 @@ func TestAtomicPolicyConcurrentRebuildEvaluate(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestAtomicPolicyRebuildChecked(t *testing.T) {
+	const (
+		sub = "spiffe://cluster.local/ns/default/sa/a"
+		tgt = "spiffe://cluster.local/ns/default/sa/target"
+	)
+	ap := newAtomicPolicy(loadTestPolicy(t, sub, tgt), zerolog.Nop())
+	store := newTestStore(t)
+	serving := ap.ptr.Load()
+
+	ap.setCRD([]policy.Policy{{Name: "default/b", Subject: "spiffe://cluster.local/ns/default/sa/b", Target: tgt, AllowedScopes: []string{"r:w"}, MaxTTL: 60}})
+	err := ap.rebuildChecked(store, func(ps []policy.Policy) error {
+		if len(ps) != 2 {
+			t.Errorf("check saw %d policies, want the merged 2", len(ps))
+		}
+		return errors.New("rejected")
+	})
+	if err == nil {
+		t.Fatal("rebuildChecked succeeded despite a failing check")
+	}
+	if ap.ptr.Load() != serving {
+		t.Error("a rejected rebuild replaced the serving policy")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Policy sources whose reloads policyStatus tracks.
+const (
+	policySourceFile = "file"
+	policySourceKube = "kube"
+)
+
+var (
+	// policyReloads counts policy reloads by source and result.
+	policyReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "svid_exchange_policy_reloads_total",
+		Help: "Policy reloads by source (file|kube) and result (success|failure). A failed reload leaves the last-known-good policy serving.",
+	}, []string{"source", "result"})
+	// policyLastLoad is when the serving policy was last loaded successfully.
+	policyLastLoad = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "svid_exchange_policy_last_load_timestamp_seconds",
+		Help: "Unix time of the last successful load of the policy file or ExchangePolicy resources.",
+	})
+	// policyStale is 1 for each source whose latest reload failed.
+	policyStale = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "svid_exchange_policy_stale",
+		Help: "1 when the latest reload from the source (file|kube) failed and the last-known-good policy is serving in its place.",
+	}, []string{"source"})
+)
+
+// policyStatus tracks how fresh the serving policy is. A reload that fails
+// leaves the last-known-good policy serving, so rather than failing readiness
+// the failure is recorded here, until the source's next successful reload,
+// and reported in metrics and the /health/ready policy detail.
+type policyStatus struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	failures map[string]reloadFailure // by source
+	now      func() time.Time
+}
+
+// reloadFailure is the latest failed reload from one source.
+type reloadFailure struct {
+	err string
+	at  time.Time
+}
+
+// newPolicyStatus returns a policyStatus for a policy loaded at startup.
+func newPolicyStatus(now func() time.Time) *policyStatus {
+	s := &policyStatus{failures: make(map[string]reloadFailure), now: now}
+	s.loadedAt = now()
+	policyLastLoad.Set(float64(s.loadedAt.Unix()))
+	return s
+}
+
+// record notes the outcome of a reload from source: err is nil when the
+// reloaded policy was swapped in.
+func (s *policyStatus) record(source string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if err != nil {
+		s.failures[source] = reloadFailure{err: err.Error(), at: now}
+		policyReloads.WithLabelValues(source, "failure").Inc()
+		policyStale.WithLabelValues(source).Set(1)
+		return
+	}
+	delete(s.failures, source)
+	s.loadedAt = now
+	policyReloads.WithLabelValues(source, "success").Inc()
+	policyLastLoad.Set(float64(now.Unix()))
+	policyStale.WithLabelValues(source).Set(0)
+}
+
+// policyStatusReport is the /health/ready detail of the policy check.
+type policyStatusReport struct {
+	LoadedAt   time.Time             `json:"loaded_at"`
+	AgeSeconds int64                 `json:"age_seconds"`
+	Stale      bool                  `json:"stale"`
+	Failures   []reloadFailureReport `json:"failures,omitempty"`
+}
+
+// reloadFailureReport describes one source's failed reload.
+type reloadFailureReport struct {
+	Source string    `json:"source"`
+	Error  string    `json:"error"`
+	At     time.Time `json:"at"`
+}
+
+// report returns the current status, with failures ordered by source.
+func (s *policyStatus) report() policyStatusReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rep := policyStatusReport{
+		LoadedAt:   s.loadedAt,
+		AgeSeconds: int64(s.now().Sub(s.loadedAt) / time.Second),
+		Stale:      len(s.failures) > 0,
+	}
+	for source, f := range s.failures {
+		rep.Failures = append(rep.Failures, reloadFailureReport{Source: source, Error: f.err, At: f.at})
+	}
+	slices.SortFunc(rep.Failures, func(a, b reloadFailureReport) int {
+		return cmp.Compare(a.Source, b.Source)
+	})
+	return rep
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPolicyStatus(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start
+	s := newPolicyStatus(func() time.Time { return now })
+
+	now = start.Add(30 * time.Second)
+	if rep := s.report(); rep.Stale || rep.AgeSeconds != 30 || !rep.LoadedAt.Equal(start) || len(rep.Failures) != 0 {
+		t.Errorf("fresh report = %+v", rep)
+	}
+
+	failuresBefore := testutil.ToFloat64(policyReloads.WithLabelValues(policySourceFile, "failure"))
+	s.record(policySourceKube, errors.New("invalid ExchangePolicy"))
+	s.record(policySourceFile, errors.New("parse policy.yaml"))
+	now = start.Add(time.Minute)
+	rep := s.report()
+	if !rep.Stale || rep.AgeSeconds != 60 || !rep.LoadedAt.Equal(start) {
+		t.Errorf("stale report = %+v, want age 60 from the last-known-good load", rep)
+	}
+	if len(rep.Failures) != 2 || rep.Failures[0].Source != policySourceFile || rep.Failures[0].Error != "parse policy.yaml" ||
+		!rep.Failures[0].At.Equal(start.Add(30*time.Second)) || rep.Failures[1].Source != policySourceKube {
+		t.Errorf("failures = %+v, want file then kube", rep.Failures)
+	}
+	if got := testutil.ToFloat64(policyReloads.WithLabelValues(policySourceFile, "failure")) - failuresBefore; got != 1 {
+		t.Errorf("file failure counter rose by %v, want 1", got)
+	}
+	if testutil.ToFloat64(policyStale.WithLabelValues(policySourceFile)) != 1 {
+		t.Error("file source not marked stale")
+	}
+
+	// A successful reload clears only its own source's failure.
+	s.record(policySourceFile, nil)
+	rep = s.report()
+	if !rep.Stale || rep.AgeSeconds != 0 || len(rep.Failures) != 1 || rep.Failures[0].Source != policySourceKube {
+		t.Errorf("report after file reload = %+v, want only the kube failure", rep)
+	}
+	if testutil.ToFloat64(policyStale.WithLabelValues(policySourceFile)) != 0 {
+		t.Error("file source still marked stale after a successful reload")
+	}
+	if got := testutil.ToFloat64(policyLastLoad); got != float64(now.Unix()) {
+		t.Errorf("last load gauge = %v, want %d", got, now.Unix())
+	}
+	s.record(policySourceKube, nil)
+	if rep := s.report(); rep.Stale || rep.Failures != nil {
+		t.Errorf("report after both reloads = %+v, want fresh", rep)
+	}
+}
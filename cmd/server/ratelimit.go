@@ -10,7 +10,9 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+	"github.com/ngaddam369/svid-exchange/internal/server"
 )
 
 // limiterIdleTTL is how long a SPIFFE ID must be idle before its bucket is
@@ -30,6 +32,15 @@ type limiterStore struct {
 	m     map[string]*limiterEntry
 	rps   rate.Limit
 	burst int
+	// clock refills buckets and ages entries. Nil is the system clock.
+	clock clock.Clock
+}
+
+func (s *limiterStore) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
 }
 
 // get returns the existing limiter for id, creating one on first call.
@@ -38,20 +49,26 @@ func (s *limiterStore) get(id string) *rate.Limiter {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if e, ok := s.m[id]; ok {
-		e.lastSeen = time.Now()
+		e.lastSeen = s.now()
 		return e.limiter
 	}
 	e := &limiterEntry{
 		limiter:  rate.NewLimiter(s.rps, s.burst),
-		lastSeen: time.Now(),
+		lastSeen: s.now(),
 	}
 	s.m[id] = e
 	return e.limiter
 }
 
+// allow reports whether id may make a call now, taking a token from its
+// bucket if so.
+func (s *limiterStore) allow(id string) bool {
+	return s.get(id).AllowN(s.now(), 1)
+}
+
 // sweep removes entries that have been idle for longer than idleTTL.
 func (s *limiterStore) sweep(idleTTL time.Duration) {
-	cutoff := time.Now().Add(-idleTTL)
+	cutoff := s.now().Add(-idleTTL)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for id, e := range s.m {
@@ -62,11 +79,12 @@ func (s *limiterStore) sweep(idleTTL time.Duration) {
 }
 
 // newRateLimitInterceptor returns a gRPC unary interceptor that enforces a
-// per-SPIFFE-ID token-bucket rate limit. When rps ≤ 0 the interceptor is a
+// per-SPIFFE-ID token-bucket rate limit, keyed by the ID ext extracts. When rps ≤ 0 the interceptor is a
 // no-op pass-through so rate limiting can be disabled without a rebuild.
 // The context controls the background sweep goroutine; pass rootCtx so it
-// stops cleanly on server shutdown.
-func newRateLimitInterceptor(ctx context.Context, rps float64, burst int) grpc.UnaryServerInterceptor {
+// stops cleanly on server shutdown. A limited caller's ID is rewritten by
+// redact in the error.
+func newRateLimitInterceptor(ctx context.Context, rps float64, burst int, ext server.IDExtractor, redact *audit.Redactor) grpc.UnaryServerInterceptor {
 	if rps <= 0 {
 		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 			return handler(ctx, req)
@@ -93,13 +111,13 @@ func newRateLimitInterceptor(ctx context.Context, rps float64, burst int) grpc.U
 	}()
 
 	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		id, err := spiffe.ExtractID(ctx)
+		id, err := ext.ExtractID(ctx)
 		if err != nil {
 			// No SPIFFE ID present — let the handler surface the auth error.
 			return handler(ctx, req)
 		}
-		if !store.get(id).Allow() {
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", id)
+		if !store.allow(id) {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", redact.ID(id))
 		}
 		return handler(ctx, req)
 	}
@@ -12,10 +12,13 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
 )
 
 func TestNewRateLimitInterceptorDisabled(t *testing.T) {
-	interceptor := newRateLimitInterceptor(context.Background(), 0, 0)
+	interceptor := newRateLimitInterceptor(context.Background(), 0, 0, spiffe.Extractor{}, nil)
 	if interceptor == nil {
 		t.Fatal("expected non-nil interceptor")
 	}
@@ -36,7 +39,7 @@ func TestNewRateLimitInterceptorDisabled(t *testing.T) {
 }
 
 func TestNewRateLimitInterceptorEnabled(t *testing.T) {
-	interceptor := newRateLimitInterceptor(context.Background(), 10, 1)
+	interceptor := newRateLimitInterceptor(context.Background(), 10, 1, spiffe.Extractor{}, nil)
 	if interceptor == nil {
 		t.Fatal("expected non-nil interceptor")
 	}
@@ -96,20 +99,55 @@ func TestLimiterStoreSweep(t *testing.T) {
 	}
 }
 
-func TestRateLimitInterceptorDenied(t *testing.T) {
-	// burst=1 so the second call from the same identity is rejected.
-	interceptor := newRateLimitInterceptor(context.Background(), 100, 1)
+func TestLimiterStoreClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	store := &limiterStore{
+		m:     make(map[string]*limiterEntry),
+		rps:   rate.Limit(1),
+		burst: 1,
+		clock: fake,
+	}
+	id := "spiffe://example.org/ns/default/sa/order"
+
+	if !store.allow(id) || store.allow(id) {
+		t.Fatal("want the first call allowed and the second limited")
+	}
+	fake.Advance(time.Second)
+	if !store.allow(id) {
+		t.Error("bucket did not refill after one second")
+	}
 
+	fake.Advance(2 * time.Hour)
+	store.sweep(time.Hour)
+	if len(store.m) != 0 {
+		t.Errorf("%d entries left after idling past the TTL, want 0", len(store.m))
+	}
+}
+
+func TestRateLimitInterceptorDenied(t *testing.T) {
 	handler := func(_ context.Context, _ any) (any, error) {
 		return "ok", nil
 	}
 
-	// First call passes; we cannot inject a peer ctx easily without a full
-	// gRPC stack, so we verify the "no SPIFFE ID → pass through" path here.
-	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
-	if err != nil {
-		t.Fatalf("expected pass-through on missing SPIFFE ID, got: %v", err)
-	}
+	t.Run("no SPIFFE ID passes through", func(t *testing.T) {
+		interceptor := newRateLimitInterceptor(context.Background(), 100, 1, spiffe.Extractor{}, nil)
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+		if err != nil {
+			t.Fatalf("expected pass-through on missing SPIFFE ID, got: %v", err)
+		}
+	})
+
+	t.Run("second call from the same identity is rejected", func(t *testing.T) {
+		// burst=1 so the second call from the same identity is rejected.
+		interceptor := newRateLimitInterceptor(context.Background(), 100, 1, &mockIDExtractor{id: "spiffe://example.org/ns/default/sa/order"}, nil)
+		if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+			t.Fatalf("first call: %v", err)
+		}
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("second call code = %v, want ResourceExhausted", status.Code(err))
+		}
+	})
 }
 
 func TestRateLimitInterceptorResourceExhausted(t *testing.T) {
@@ -135,7 +173,7 @@ func TestRateLimitInterceptorResourceExhausted(t *testing.T) {
 
 func TestRateLimitInterceptorSweepOnShutdown(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
-	interceptor := newRateLimitInterceptor(ctx, 10, 10)
+	interceptor := newRateLimitInterceptor(ctx, 10, 10, spiffe.Extractor{}, nil)
 	if interceptor == nil {
 		t.Fatal("expected non-nil interceptor")
 	}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// readinessProbeTimeout bounds each dependency probe, so one hung backend
+// cannot stall /health/ready past the kubelet's probe timeout.
+const readinessProbeTimeout = 2 * time.Second
+
+// errShuttingDown is reported by every check once shutdown has begun.
+var errShuttingDown = errors.New("shutting down")
+
+// readinessCheck probes one dependency. It returns nil when the dependency
+// is usable. detail, when set, adds state worth reporting whether or not the
+// probe passes.
+type readinessCheck struct {
+	name   string
+	probe  func(ctx context.Context) error
+	detail func() any
+}
+
+// readiness aggregates dependency probes into the /health/ready response.
+// The instance is ready only when every probe passes and shutdown has not
+// begun. Checks must be added before the handler starts serving.
+type readiness struct {
+	checks       []readinessCheck
+	shuttingDown atomic.Bool
+}
+
+// add registers a probe reported under name.
+func (r *readiness) add(name string, probe func(ctx context.Context) error) {
+	r.checks = append(r.checks, readinessCheck{name: name, probe: probe})
+}
+
+// addWithDetail registers a probe reported under name along with detail.
+func (r *readiness) addWithDetail(name string, probe func(ctx context.Context) error, detail func() any) {
+	r.checks = append(r.checks, readinessCheck{name: name, probe: probe, detail: detail})
+}
+
+// shutdown marks the instance not ready, so load balancers stop routing to
+// it while in-flight calls drain.
+func (r *readiness) shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// checkResult is one entry of the /health/ready response.
+type checkResult struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Detail any    `json:"detail,omitempty"`
+}
+
+// readinessReport is the /health/ready response body.
+type readinessReport struct {
+	Ready  bool          `json:"ready"`
+	Checks []checkResult `json:"checks"`
+}
+
+// report runs every probe concurrently and collects the results in
+// registration order.
+func (r *readiness) report(ctx context.Context) readinessReport {
+	rep := readinessReport{Ready: true, Checks: make([]checkResult, len(r.checks))}
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		rep.Checks[i] = checkResult{Name: c.name}
+		if c.detail != nil {
+			rep.Checks[i].Detail = c.detail()
+		}
+		if r.shuttingDown.Load() {
+			rep.Checks[i].Error = errShuttingDown.Error()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, readinessProbeTimeout)
+			defer cancel()
+			if err := c.probe(pctx); err != nil {
+				rep.Checks[i].Error = err.Error()
+				return
+			}
+			rep.Checks[i].OK = true
+		}()
+	}
+	wg.Wait()
+	for _, c := range rep.Checks {
+		rep.Ready = rep.Ready && c.OK
+	}
+	return rep
+}
+
+// handler serves the aggregated report: 200 when ready, 503 otherwise, with
+// a JSON body naming each check and the error of any that failed.
+func (r *readiness) handler(log zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rep := r.report(req.Context())
+		body, err := json.Marshal(rep)
+		if err != nil {
+			log.Error().Err(err).Msg("readiness: marshal response")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if rep.Ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("readiness: write response")
+		}
+	}
+}
+
+// servingProbe returns a probe that passes while serving is set. main sets
+// it just before a listener's Serve loop starts and clears it when Serve
+// returns.
+func servingProbe(serving *atomic.Bool) func(context.Context) error {
+	return func(context.Context) error {
+		if !serving.Load() {
+			return errors.New("not serving")
+		}
+		return nil
+	}
+}
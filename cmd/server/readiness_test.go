@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("kms unavailable") }
+	hung := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name       string
+		checks     map[string]func(context.Context) error
+		order      []string
+		shutdown   bool
+		wantStatus int
+		wantErrors map[string]string // check name → error; others must be OK
+	}{
+		{
+			name:       "all dependencies up",
+			checks:     map[string]func(context.Context) error{"policy": ok, "signer": ok},
+			order:      []string{"policy", "signer"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "one dependency down",
+			checks:     map[string]func(context.Context) error{"policy": ok, "signer": failing},
+			order:      []string{"policy", "signer"},
+			wantStatus: http.StatusServiceUnavailable,
+			wantErrors: map[string]string{"signer": "kms unavailable"},
+		},
+		{
+			name:       "hung probe times out",
+			checks:     map[string]func(context.Context) error{"store": hung},
+			order:      []string{"store"},
+			wantStatus: http.StatusServiceUnavailable,
+			wantErrors: map[string]string{"store": context.DeadlineExceeded.Error()},
+		},
+		{
+			name:       "shutting down",
+			checks:     map[string]func(context.Context) error{"policy": ok},
+			order:      []string{"policy"},
+			shutdown:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantErrors: map[string]string{"policy": errShuttingDown.Error()},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &readiness{}
+			for _, name := range tc.order {
+				r.add(name, tc.checks[name])
+			}
+			if tc.shutdown {
+				r.shutdown()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), readinessProbeTimeout+time.Second)
+			defer cancel()
+			rec := httptest.NewRecorder()
+			r.handler(zerolog.Nop())(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil).WithContext(ctx))
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			var rep readinessReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+				t.Fatalf("decode body %q: %v", rec.Body, err)
+			}
+			if rep.Ready != (tc.wantStatus == http.StatusOK) || len(rep.Checks) != len(tc.order) {
+				t.Fatalf("report = %+v", rep)
+			}
+			for i, c := range rep.Checks {
+				if c.Name != tc.order[i] {
+					t.Errorf("checks[%d] = %q, want %q", i, c.Name, tc.order[i])
+				}
+				if want := tc.wantErrors[c.Name]; c.Error != want || c.OK != (want == "") {
+					t.Errorf("check %q = ok %v, error %q; want error %q", c.Name, c.OK, c.Error, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServingProbe(t *testing.T) {
+	var serving atomic.Bool
+	probe := servingProbe(&serving)
+	if probe(context.Background()) == nil {
+		t.Error("probe passed before Serve started")
+	}
+	serving.Store(true)
+	if err := probe(context.Background()); err != nil {
+		t.Errorf("probe = %v while serving", err)
+	}
+}
+
+func TestReadinessDetail(t *testing.T) {
+	r := &readiness{}
+	r.addWithDetail("policy", func(context.Context) error { return nil }, func() any { return map[string]bool{"stale": true} })
+	r.add("store", func(context.Context) error { return nil })
+
+	rep := r.report(context.Background())
+	if !rep.Ready {
+		t.Errorf("report = %+v, want ready: detail must not affect readiness", rep)
+	}
+	body, err := json.Marshal(rep)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"ready":true,"checks":[{"name":"policy","ok":true,"detail":{"stale":true}},{"name":"store","ok":true}]}`
+	if string(body) != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
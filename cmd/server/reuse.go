@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/extauthz"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// tokenReuse counts tokens ext_authz or /introspect saw presented from a
+// second source, a sign that the token leaked.
+var tokenReuse = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_token_reuse_total",
+	Help: "Tokens presented to ext_authz or /introspect from more than one source, by whether they were revoked (revoked=true|false).",
+}, []string{"revoked"})
+
+// reuseHandler reports token reuse detected by ext_authz or /introspect in
+// the audit log
+// and, when revoke is set, revokes the token the way the admin RevokeToken
+// RPC does: persisted in the policy store, then applied in memory.
+type reuseHandler struct {
+	audit  *audit.Logger
+	store  *policy.Store
+	revoke func(jti string, expiresAt time.Time) bool
+	log    zerolog.Logger
+}
+
+// handle is the extauthz.ReuseDetector callback. It reports whether the
+// token was revoked.
+func (h reuseHandler) handle(r extauthz.Reuse) bool {
+	revoked := h.revoke != nil && h.revokeToken(r)
+	tokenReuse.WithLabelValues(strconv.FormatBool(revoked)).Inc()
+	h.audit.LogTokenReuse(audit.TokenReuseEvent{
+		JTI:         r.JTI,
+		Subject:     r.Subject,
+		Audience:    r.Audience,
+		ExpiresAt:   r.ExpiresAt,
+		FirstSource: r.FirstSource,
+		Source:      r.Source,
+		Revoked:     revoked,
+	})
+	return revoked
+}
+
+func (h reuseHandler) revokeToken(r extauthz.Reuse) bool {
+	if err := h.store.SaveRevocation(r.JTI, r.ExpiresAt.Unix()); err != nil {
+		h.log.Error().Err(err).Str("jti", r.JTI).Msg("save revocation of reused token")
+		return false
+	}
+	if !h.revoke(r.JTI, r.ExpiresAt) {
+		h.log.Warn().Str("jti", r.JTI).Msg("revocation list full; reused token was persisted but not revoked in memory")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/extauthz"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+func TestReuseHandler(t *testing.T) {
+	store, err := policy.OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	exp := time.Now().Add(time.Minute).Truncate(time.Second)
+	r := extauthz.Reuse{JTI: "tok-1", Subject: "spiffe://td/a", Audience: "spiffe://td/b", ExpiresAt: exp,
+		FirstSource: "spiffe://td/a", Source: "10.0.0.9"}
+
+	t.Run("alert only", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := reuseHandler{audit: audit.New(&buf), store: store, log: zerolog.Nop()}
+		if h.handle(r) {
+			t.Error("handle revoked the token without revoke set")
+		}
+		if !strings.Contains(buf.String(), `"event":"token.reuse"`) || !strings.Contains(buf.String(), `"revoked":false`) {
+			t.Errorf("audit log = %s", buf.String())
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		var buf bytes.Buffer
+		revoked := map[string]time.Time{}
+		h := reuseHandler{audit: audit.New(&buf), store: store, log: zerolog.Nop(), revoke: func(jti string, at time.Time) bool {
+			revoked[jti] = at
+			return true
+		}}
+		if !h.handle(r) {
+			t.Error("handle did not revoke the token")
+		}
+		if !revoked["tok-1"].Equal(exp) {
+			t.Errorf("in-memory revocations = %v", revoked)
+		}
+		saved, err := store.ListRevocations()
+		if err != nil {
+			t.Fatalf("list revocations: %v", err)
+		}
+		if len(saved) != 1 || saved[0].JTI != "tok-1" || saved[0].ExpiresAt != exp.Unix() {
+			t.Errorf("persisted revocations = %+v", saved)
+		}
+		if !strings.Contains(buf.String(), `"revoked":true`) {
+			t.Errorf("audit log = %s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// ruleUsageFlushInterval is how often recorded rule matches are added to the
+// counters in the policy store.
+const ruleUsageFlushInterval = time.Minute
+
+// ruleUsageSink is an audit Sink that records the policy rules behind every
+// granted exchange. Preflights issue nothing and are not counted.
+type ruleUsageSink struct {
+	tracker *policy.UsageTracker
+}
+
+// Deliver implements audit.Sink.
+func (s ruleUsageSink) Deliver(e audit.ExchangeEvent) {
+	if e.Granted && !e.Preflight {
+		s.tracker.Record(e.PolicyRules)
+	}
+}
+
+// ruleNames returns the names of the rules in the active policy.
+func (ap *atomicPolicy) ruleNames() []string {
+	ps := ap.ptr.Load().Policies()
+	names := make([]string, len(ps))
+	for i, p := range ps {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// runRuleUsageFlush flushes tracker every interval until ctx is done. The
+// caller flushes once more after the last exchange has been served.
+func runRuleUsageFlush(ctx context.Context, tracker *policy.UsageTracker, interval time.Duration, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := tracker.Flush(now); err != nil {
+				log.Error().Err(err).Msg("flush policy rule usage")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
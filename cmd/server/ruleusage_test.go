@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+func TestRuleUsageSink(t *testing.T) {
+	store, err := policy.OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ap := newAtomicPolicy(loadTestPolicy(t, "spiffe://td/a", "spiffe://td/b"), zerolog.Nop())
+	tracker := policy.NewUsageTracker(store, ap.ruleNames)
+	sink := ruleUsageSink{tracker: tracker}
+	sink.Deliver(audit.ExchangeEvent{Granted: true, PolicyRules: []string{"test"}})
+	sink.Deliver(audit.ExchangeEvent{Granted: true, Preflight: true, PolicyRules: []string{"test"}})
+	sink.Deliver(audit.ExchangeEvent{Granted: false, PolicyRules: []string{"test"}})
+	if err := tracker.Flush(time.Now()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	usage, err := store.ListRuleUsage()
+	if err != nil {
+		t.Fatalf("list rule usage: %v", err)
+	}
+	if len(usage) != 1 || usage["test"].Matches != 1 {
+		t.Errorf("usage = %+v, want one match of test", usage)
+	}
+}
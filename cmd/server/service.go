@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/systemd"
+)
+
+// serviceManager is a supervisor the process runs under outside Kubernetes,
+// such as systemd or the Windows service control manager, which is told
+// about the server's lifecycle.
+type serviceManager interface {
+	// ready reports that the listeners are serving.
+	ready()
+	// stopping reports that the server has begun to drain.
+	stopping()
+	// stopped reports that shutdown is complete. The process exits next.
+	stopped()
+}
+
+// serviceManagers reports each lifecycle change to every supervisor found.
+type serviceManagers []serviceManager
+
+func (m serviceManagers) ready() {
+	for _, s := range m {
+		s.ready()
+	}
+}
+
+func (m serviceManagers) stopping() {
+	for _, s := range m {
+		s.stopping()
+	}
+}
+
+func (m serviceManagers) stopped() {
+	for _, s := range m {
+		s.stopped()
+	}
+}
+
+// detectServiceManagers returns the supervisors the process runs under. A
+// stop request from a supervisor is delivered to quit as SIGTERM. healthy
+// gates systemd watchdog keep-alives, so that systemd restarts a server
+// whose listeners have stopped. It must be called early in startup: the
+// Windows service control manager gives a service only a short time to
+// connect.
+func detectServiceManagers(ctx context.Context, quit chan<- os.Signal, healthy func() bool, log zerolog.Logger) serviceManagers {
+	var m serviceManagers
+	if n := systemd.FromEnv(); n != nil {
+		interval, err := systemd.WatchdogInterval()
+		if err != nil {
+			log.Warn().Err(err).Msg("systemd watchdog disabled")
+		}
+		m = append(m, &systemdService{ctx: ctx, n: n, watchdog: interval, healthy: healthy, log: log})
+		log.Info().Dur("watchdog", interval).Msg("systemd notification enabled")
+	}
+	if w := startWindowsService(quit, log); w != nil {
+		m = append(m, w)
+		log.Info().Msg("running as a Windows service")
+	}
+	return m
+}
+
+// systemdService reports the lifecycle to systemd over sd_notify and, when
+// systemd's watchdog is enabled, sends keep-alives while healthy holds.
+type systemdService struct {
+	ctx      context.Context
+	n        *systemd.Notifier
+	watchdog time.Duration
+	healthy  func() bool
+	log      zerolog.Logger
+}
+
+func (s *systemdService) ready() {
+	s.notify(systemd.Ready, systemd.Status("serving"))
+	if s.watchdog > 0 {
+		go s.keepAlive()
+	}
+}
+
+func (s *systemdService) stopping() {
+	s.notify(systemd.Stopping, systemd.Status("draining"))
+}
+
+func (s *systemdService) stopped() {}
+
+// keepAlive sends a watchdog keep-alive every half timeout until ctx is
+// done, skipping any tick at which the server is unhealthy.
+func (s *systemdService) keepAlive() {
+	t := time.NewTicker(s.watchdog / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if s.healthy() {
+				s.notify(systemd.Watchdog)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// notify sends states to systemd. A failure is logged and otherwise
+// ignored: systemd acts on the missing notification itself.
+func (s *systemdService) notify(states ...string) {
+	if err := s.n.Notify(states...); err != nil {
+		s.log.Warn().Err(err).Strs("states", states).Msg("systemd notify failed")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// startWindowsService returns nil: the process only runs as a Windows
+// service on Windows.
+func startWindowsService(chan<- os.Signal, zerolog.Logger) serviceManager {
+	return nil
+}
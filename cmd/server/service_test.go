@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/systemd"
+)
+
+// listenNotify binds a notification socket, points NOTIFY_SOCKET at it, and
+// returns a function reading the next datagram.
+func listenNotify(t *testing.T) func() string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return func() string {
+		t.Helper()
+		buf := make([]byte, 256)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("read notification: %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestDetectServiceManagers(t *testing.T) {
+	t.Run("none outside a supervisor", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		m := detectServiceManagers(context.Background(), make(chan<- os.Signal, 1), func() bool { return true }, zerolog.Nop())
+		if len(m) != 0 {
+			t.Fatalf("managers = %v, want none", m)
+		}
+		// Reporting to no supervisor is a no-op.
+		m.ready()
+		m.stopping()
+		m.stopped()
+	})
+
+	t.Run("systemd lifecycle and watchdog", func(t *testing.T) {
+		next := listenNotify(t)
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var healthy atomic.Bool
+		m := detectServiceManagers(ctx, make(chan<- os.Signal, 1), healthy.Load, zerolog.Nop())
+		if len(m) != 1 {
+			t.Fatalf("managers = %v, want systemd only", m)
+		}
+
+		m.ready()
+		if got := next(); got != systemd.Ready+"\n"+systemd.Status("serving") {
+			t.Errorf("ready sent %q", got)
+		}
+		// Keep-alives only flow while healthy.
+		healthy.Store(true)
+		if got := next(); got != systemd.Watchdog {
+			t.Errorf("keep-alive sent %q", got)
+		}
+		healthy.Store(false)
+		m.stopping()
+		for got := next(); got != systemd.Stopping+"\n"+systemd.Status("draining"); got = next() {
+			if !strings.HasPrefix(got, systemd.Watchdog) {
+				t.Fatalf("stopping sent %q", got)
+			}
+		}
+		m.stopped()
+	})
+}
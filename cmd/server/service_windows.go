@@ -0,0 +1,91 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/windows/svc"
+)
+
+// windowsServiceName is the name svc.Run registers under. The service
+// control manager starts a single-service process whatever the name.
+const windowsServiceName = "svid-exchange"
+
+// startWindowsService connects to the Windows service control manager when
+// the process was started as a service, and returns nil otherwise. Stop and
+// shutdown requests are delivered to quit as SIGTERM.
+func startWindowsService(quit chan<- os.Signal, log zerolog.Logger) serviceManager {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		log.Warn().Err(err).Msg("detect Windows service")
+		return nil
+	}
+	if !isService {
+		return nil
+	}
+	w := &windowsService{
+		quit:    quit,
+		running: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		if err := svc.Run(windowsServiceName, w); err != nil {
+			log.Error().Err(err).Msg("Windows service control")
+		}
+	}()
+	return w
+}
+
+// windowsService is an svc.Handler that reports the server's lifecycle to
+// the service control manager.
+type windowsService struct {
+	quit    chan<- os.Signal
+	running chan struct{} // closed by ready
+	stop    chan struct{} // closed by stopped
+	done    chan struct{} // closed when svc.Run returns
+}
+
+func (w *windowsService) ready() { close(w.running) }
+
+func (w *windowsService) stopping() {}
+
+// stopped ends Execute and waits for svc.Run to report the service stopped,
+// which must happen before the process exits.
+func (w *windowsService) stopped() {
+	close(w.stop)
+	<-w.done
+}
+
+// Execute implements svc.Handler. It reports StartPending until the server
+// is serving, forwards stop and shutdown requests to quit, and returns once
+// shutdown is complete.
+func (w *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+	changes <- svc.Status{State: svc.StartPending}
+	running := w.running
+	for {
+		select {
+		case <-running:
+			running = nil
+			changes <- svc.Status{State: svc.Running, Accepts: accepts}
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				changes <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				select {
+				case w.quit <- syscall.SIGTERM:
+				default: // a stop is already pending
+				}
+			}
+		case <-w.stop:
+			return false, 0
+		}
+	}
+}
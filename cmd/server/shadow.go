@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// shadowEvaluations counts shadow policy evaluations by whether the shadow
+// decision matched the active one.
+var shadowEvaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "svid_exchange_shadow_policy_evaluations_total",
+	Help: "Exchange requests evaluated against the shadow policy, by whether its decision matched the active policy (result=match|mismatch).",
+}, []string{"result"})
+
+// shadowPolicy is a PolicyEvaluator that decides with active and also
+// evaluates every request against a shadow loader, logging and counting each
+// request on which the two disagree. It lets a candidate policy file be
+// validated against production traffic before it replaces the active one.
+// The shadow loader is in-memory, so it is evaluated inline after the active
+// decision; its result never reaches the caller.
+type shadowPolicy struct {
+	active server.PolicyEvaluator
+	shadow atomic.Pointer[policy.Loader]
+	log    zerolog.Logger
+}
+
+// newShadowPolicy returns a shadowPolicy deciding with active and comparing
+// against shadow. Disagreements are logged to log.
+func newShadowPolicy(active server.PolicyEvaluator, shadow *policy.Loader, log zerolog.Logger) *shadowPolicy {
+	sp := &shadowPolicy{active: active, log: log}
+	sp.shadow.Store(shadow)
+	return sp
+}
+
+// Evaluate returns the active policy's result. When the active evaluator
+// fails, the shadow is not consulted.
+func (sp *shadowPolicy) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	res, err := sp.active.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+	if err != nil {
+		return res, err
+	}
+	shadow := sp.shadow.Load().Evaluate(subject, target, scopes, ttlSeconds)
+	diffs := evalDifferences(res, shadow)
+	if len(diffs) == 0 {
+		shadowEvaluations.WithLabelValues("match").Inc()
+		return res, nil
+	}
+	shadowEvaluations.WithLabelValues("mismatch").Inc()
+	sp.log.Info().Str("subject", subject).Str("target", target).Strs("scopes_requested", scopes).
+		Strs("active_rules", res.MatchedRules).Strs("shadow_rules", shadow.MatchedRules).
+		Strs("differences", diffs).Msg("shadow policy decision differs")
+	return res, nil
+}
+
+// swap replaces the shadow loader atomically.
+func (sp *shadowPolicy) swap(l *policy.Loader) {
+	sp.shadow.Store(l)
+}
+
+// evalDifferences lists what the active and shadow results disagree on, in
+// the same "<field> <active> vs <shadow>" form as policy conflicts. The rules
+// that produced each result are not compared: renaming a rule is not a
+// change in outcome.
+func evalDifferences(active, shadow policy.EvalResult) []string {
+	if active.Allowed != shadow.Allowed {
+		return []string{fmt.Sprintf("allowed %t vs %t", active.Allowed, shadow.Allowed)}
+	}
+	if !active.Allowed {
+		return nil
+	}
+	var diffs []string
+	if !slices.Equal(active.GrantedScopes, shadow.GrantedScopes) {
+		diffs = append(diffs, fmt.Sprintf("granted_scopes %v vs %v", active.GrantedScopes, shadow.GrantedScopes))
+	}
+	if active.GrantedTTL != shadow.GrantedTTL {
+		diffs = append(diffs, fmt.Sprintf("ttl %d vs %d", active.GrantedTTL, shadow.GrantedTTL))
+	}
+	if active.TokenFormat != shadow.TokenFormat {
+		diffs = append(diffs, fmt.Sprintf("token_format %s vs %s", active.TokenFormat, shadow.TokenFormat))
+	}
+	if active.RequireNonce != shadow.RequireNonce {
+		diffs = append(diffs, fmt.Sprintf("require_nonce %t vs %t", active.RequireNonce, shadow.RequireNonce))
+	}
+	if active.RequiresApproval != shadow.RequiresApproval {
+		diffs = append(diffs, fmt.Sprintf("requires_approval %t vs %t", active.RequiresApproval, shadow.RequiresApproval))
+	}
+	if active.SingleUse != shadow.SingleUse {
+		diffs = append(diffs, fmt.Sprintf("single_use %t vs %t", active.SingleUse, shadow.SingleUse))
+	}
+	if !slices.Equal(active.Audience, shadow.Audience) {
+		diffs = append(diffs, fmt.Sprintf("audience %v vs %v", active.Audience, shadow.Audience))
+	}
+	if active.Issuer != shadow.Issuer {
+		diffs = append(diffs, fmt.Sprintf("issuer %q vs %q", active.Issuer, shadow.Issuer))
+	}
+	return diffs
+}
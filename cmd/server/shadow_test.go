@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// failingEvaluator fails every evaluation with err.
+type failingEvaluator struct{ err error }
+
+func (f failingEvaluator) Evaluate(context.Context, string, string, []string, int32) (policy.EvalResult, error) {
+	return policy.EvalResult{}, f.err
+}
+
+func TestShadowPolicy(t *testing.T) {
+	const (
+		sub = "spiffe://cluster.local/ns/default/sa/order"
+		tgt = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	rule := func(name string, scopes []string, ttl int32) policy.Policy {
+		return policy.Policy{Name: name, Subject: sub, Target: tgt, AllowedScopes: scopes, MaxTTL: ttl}
+	}
+	newLoader := func(t *testing.T, ps ...policy.Policy) *policy.Loader {
+		t.Helper()
+		l, err := policy.NewLoader(ps)
+		if err != nil {
+			t.Fatalf("NewLoader: %v", err)
+		}
+		return l
+	}
+	active := rule("active", []string{"payments:charge", "payments:refund"}, 300)
+	nonceRule := rule("candidate", active.AllowedScopes, 300)
+	nonceRule.RequireNonce = true
+
+	tests := []struct {
+		name       string
+		shadow     []policy.Policy
+		wantResult string // "match" or "mismatch"
+		wantDiff   string // substring of the logged differences
+	}{
+		{name: "renamed rule matches", shadow: []policy.Policy{rule("candidate", active.AllowedScopes, 300)}, wantResult: "match"},
+		{name: "shadow denies", wantResult: "mismatch", wantDiff: "allowed true vs false"},
+		{name: "shadow narrows scopes", shadow: []policy.Policy{rule("candidate", []string{"payments:charge"}, 300)}, wantResult: "mismatch", wantDiff: "granted_scopes"},
+		{name: "shadow lowers TTL", shadow: []policy.Policy{rule("candidate", active.AllowedScopes, 60)}, wantResult: "mismatch", wantDiff: "ttl 300 vs 60"},
+		{name: "shadow requires a nonce", shadow: []policy.Policy{nonceRule}, wantResult: "mismatch", wantDiff: "require_nonce false vs true"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ap := newAtomicPolicy(newLoader(t, active), zerolog.Nop())
+			sp := newShadowPolicy(ap, newLoader(t, tc.shadow...), zerolog.New(&buf))
+			before := testutil.ToFloat64(shadowEvaluations.WithLabelValues(tc.wantResult))
+
+			res, err := sp.Evaluate(context.Background(), sub, tgt, active.AllowedScopes, 0)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !res.Allowed || res.GrantedTTL != 300 || len(res.GrantedScopes) != 2 {
+				t.Errorf("result = %+v, want the active policy's full grant", res)
+			}
+			if got := testutil.ToFloat64(shadowEvaluations.WithLabelValues(tc.wantResult)) - before; got != 1 {
+				t.Errorf("%s counter increased by %v, want 1", tc.wantResult, got)
+			}
+			if tc.wantDiff == "" {
+				if buf.Len() != 0 {
+					t.Errorf("unexpected log output: %s", buf.String())
+				}
+				return
+			}
+			if !strings.Contains(buf.String(), tc.wantDiff) {
+				t.Errorf("log %q does not contain %q", buf.String(), tc.wantDiff)
+			}
+		})
+	}
+
+	t.Run("swap replaces the shadow", func(t *testing.T) {
+		var buf bytes.Buffer
+		sp := newShadowPolicy(newAtomicPolicy(newLoader(t, active), zerolog.Nop()), newLoader(t), zerolog.New(&buf))
+		sp.swap(newLoader(t, rule("candidate", active.AllowedScopes, 300)))
+		if _, err := sp.Evaluate(context.Background(), sub, tgt, active.AllowedScopes, 0); err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("swapped shadow still disagrees: %s", buf.String())
+		}
+	})
+
+	t.Run("active error skips the shadow", func(t *testing.T) {
+		var buf bytes.Buffer
+		want := errors.New("engine down")
+		sp := newShadowPolicy(failingEvaluator{want}, newLoader(t), zerolog.New(&buf))
+		if _, err := sp.Evaluate(context.Background(), sub, tgt, active.AllowedScopes, 0); !errors.Is(err, want) {
+			t.Errorf("err = %v, want %v", err, want)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("unexpected log output: %s", buf.String())
+		}
+	})
+}
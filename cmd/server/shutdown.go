@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// stopGRPC stops srv gracefully, letting in-flight RPCs finish, and closes
+// its remaining connections once ctx is done. It returns when srv has
+// stopped.
+func stopGRPC(ctx context.Context, srv grpcServer, name string, log zerolog.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn().Str("server", name).Msg("shutdown timeout reached; closing connections with RPCs in flight")
+		srv.Stop()
+		<-stopped
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestStopGRPC(t *testing.T) {
+	t.Run("idle server stops gracefully", func(t *testing.T) {
+		srv := grpc.NewServer()
+		go func() { _ = srv.Serve(bufconn.Listen(1 << 20)) }()
+		stopGRPC(context.Background(), srv, "test", zerolog.Nop())
+	})
+
+	t.Run("timeout closes streams in flight", func(t *testing.T) {
+		lis := bufconn.Listen(1 << 20)
+		srv := grpc.NewServer()
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		go func() { _ = srv.Serve(lis) }()
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		defer conn.Close()
+		// Watch streams until cancelled, so GracefulStop alone never returns.
+		stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		stopGRPC(ctx, srv, "test", zerolog.Nop())
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("stopGRPC took %s", elapsed)
+		}
+		if _, err := stream.Recv(); err == nil {
+			t.Error("stream still open after stopGRPC")
+		}
+	})
+}
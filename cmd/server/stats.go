@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+)
+
+// reportTopScopes is the number of scopes listed in a scheduled issuance
+// report.
+const reportTopScopes = 50
+
+// writeIssuanceReport writes the report of the last window to dir as
+// issuance-<end>.<format>, where end is the report's end time in UTC. The
+// file is written under a temporary name and renamed, so readers never see
+// a partial report.
+func writeIssuanceReport(stats *audit.IssuanceStats, dir, format string, window time.Duration) (string, error) {
+	r := stats.Report(window, reportTopScopes)
+	name := filepath.Join(dir, fmt.Sprintf("issuance-%s.%s", r.End.UTC().Format("20060102T150405Z"), format))
+	f, err := os.CreateTemp(dir, ".issuance-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if format == "csv" {
+		err = r.WriteCSV(f)
+	} else {
+		err = r.WriteJSON(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// runIssuanceReports writes a report of the preceding interval to dir every
+// interval until ctx is done.
+func runIssuanceReports(ctx context.Context, stats *audit.IssuanceStats, dir, format string, interval time.Duration, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			name, err := writeIssuanceReport(stats, dir, format, interval)
+			if err != nil {
+				log.Error().Err(err).Str("dir", dir).Msg("write issuance report")
+				continue
+			}
+			log.Info().Str("file", name).Msg("issuance report written")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+)
+
+func TestWriteIssuanceReport(t *testing.T) {
+	stats := audit.NewIssuanceStats(24 * time.Hour)
+	stats.Deliver(audit.ExchangeEvent{Subject: "spiffe://td/order", Target: "spiffe://td/payment", Granted: true, ScopesGranted: []string{"read"}, TTL: 60})
+
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			name, err := writeIssuanceReport(stats, dir, format, time.Hour)
+			if err != nil {
+				t.Fatalf("writeIssuanceReport: %v", err)
+			}
+			if filepath.Dir(name) != dir || !strings.HasPrefix(filepath.Base(name), "issuance-") || filepath.Ext(name) != "."+format {
+				t.Errorf("report written to %s", name)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("directory holds %d files, want only the report", len(entries))
+			}
+			body, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("read report: %v", err)
+			}
+			if format == "json" {
+				var r audit.IssuanceReport
+				if err := json.Unmarshal(body, &r); err != nil || len(r.Pairs) != 1 || r.Pairs[0].Grants != 1 {
+					t.Errorf("JSON report = %s (%v)", body, err)
+				}
+				return
+			}
+			rows, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+			if err != nil || len(rows) < 2 || rows[1][0] != "pair" || rows[1][5] != "1" {
+				t.Errorf("CSV report = %q (%v)", rows, err)
+			}
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		if _, err := writeIssuanceReport(stats, filepath.Join(t.TempDir(), "missing"), "json", time.Hour); err == nil {
+			t.Error("writeIssuanceReport succeeded without a directory")
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// svidSource is where the server gets its X.509-SVID and trust bundle: the
+// SPIRE Workload API, or a directory of files in development.
+type svidSource interface {
+	x509Source
+	Close() error
+}
+
+// fileSVIDSource is an svidSource read once from a directory laid out as a
+// Kubernetes TLS secret, such as those written by svidx dev ca. Nothing
+// rotates the SVID, so it is for development only.
+type fileSVIDSource struct {
+	svid   *x509svid.SVID
+	bundle *x509bundle.Bundle
+}
+
+// loadSVIDDir reads the SVID from tls.crt and tls.key in dir and the
+// trust domain's CA certificates from ca.crt.
+func loadSVIDDir(dir string) (*fileSVIDSource, error) {
+	svid, err := x509svid.Load(filepath.Join(dir, tlsDirCert), filepath.Join(dir, tlsDirKey))
+	if err != nil {
+		return nil, fmt.Errorf("load X.509-SVID: %w", err)
+	}
+	bundle, err := x509bundle.Load(svid.ID.TrustDomain(), filepath.Join(dir, tlsDirCA))
+	if err != nil {
+		return nil, fmt.Errorf("load X.509 bundle: %w", err)
+	}
+	return &fileSVIDSource{svid: svid, bundle: bundle}, nil
+}
+
+func (s *fileSVIDSource) GetX509SVID() (*x509svid.SVID, error) { return s.svid, nil }
+
+func (s *fileSVIDSource) GetX509BundleForTrustDomain(td spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	return s.bundle.GetX509BundleForTrustDomain(td)
+}
+
+func (s *fileSVIDSource) Close() error { return nil }
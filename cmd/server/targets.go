@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/kube"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+)
+
+// Target registries accepted in target_registry.
+const (
+	targetRegistryStatic = "static"
+	targetRegistryKube   = "kube"
+	targetRegistrySPIRE  = "spire"
+)
+
+// targetRegistries lists every known target registry.
+var targetRegistries = []string{targetRegistryStatic, targetRegistryKube, targetRegistrySPIRE}
+
+// newTargetValidator returns the server.TargetValidator cfg.TargetRegistry
+// names, or nil when none is configured. A SPIRE entries file is re-read
+// every cfg.TargetRefresh until ctx is done; a failed read is logged and the
+// entries loaded before stay in use.
+func newTargetValidator(ctx context.Context, cfg Config, log zerolog.Logger) (server.TargetValidator, error) {
+	switch cfg.TargetRegistry {
+	case targetRegistryStatic:
+		return server.NewStaticTargets(cfg.TargetIDs), nil
+	case targetRegistryKube:
+		accounts, err := newServiceAccountsClient()
+		if err != nil {
+			return nil, err
+		}
+		return kube.NewServiceAccountTargets(accounts, cfg.TargetTrustDomain), nil
+	case targetRegistrySPIRE:
+		entries, err := spiffe.LoadEntryTargets(cfg.TargetEntriesFile)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			ticker := time.NewTicker(cfg.TargetRefresh)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := entries.Reload(); err != nil {
+						log.Error().Err(err).Msg("reload SPIRE entries; keeping the previous ones")
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return entries, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestNewTargetValidator(t *testing.T) {
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+	entries := filepath.Join(t.TempDir(), "entries.json")
+	if err := os.WriteFile(entries, []byte(`{"entries": [{"spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/payment"}}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "none configured", wantNil: true},
+		{name: "static", cfg: Config{TargetRegistry: targetRegistryStatic, TargetIDs: []string{target}}},
+		{name: "spire", cfg: Config{TargetRegistry: targetRegistrySPIRE, TargetEntriesFile: entries, TargetRefresh: time.Hour}},
+		{
+			name:    "spire with a missing file",
+			cfg:     Config{TargetRegistry: targetRegistrySPIRE, TargetEntriesFile: filepath.Join(t.TempDir(), "missing.json"), TargetRefresh: time.Hour},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			v, err := newTargetValidator(ctx, tc.cfg, zerolog.Nop())
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTargetValidator: %v", err)
+			}
+			if tc.wantNil {
+				if v != nil {
+					t.Errorf("validator = %T, want nil", v)
+				}
+				return
+			}
+			if err := v.ValidateTarget(ctx, target); err != nil {
+				t.Errorf("known target: %v", err)
+			}
+			if err := v.ValidateTarget(ctx, "spiffe://cluster.local/ns/default/sa/paymnet"); !errors.Is(err, server.ErrUnknownTarget) {
+				t.Errorf("unknown target: err = %v, want ErrUnknownTarget", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/credentials"
+)
+
+// maxTLSSessionTicketLifetime is crypto/tls's own limit on ticket age.
+const maxTLSSessionTicketLifetime = 7 * 24 * time.Hour
+
+// Values of the server label on the TLS handshake metrics.
+const (
+	handshakeServerData  = "data"
+	handshakeServerAdmin = "admin"
+)
+
+// Reasons a TLS handshake failed, the reason label of
+// svid_exchange_tls_handshake_failures_total.
+const (
+	handshakeNoCertificate      = "no_certificate"
+	handshakeExpiredCertificate = "expired_certificate"
+	handshakeUntrusted          = "untrusted_certificate"
+	handshakeInvalidCertificate = "invalid_certificate"
+	handshakeUnauthorized       = "unauthorized"
+	handshakeClientClosed       = "client_closed"
+	handshakeTimeout            = "timeout"
+	handshakeOther              = "other"
+)
+
+var handshakeFailureReasons = []string{
+	handshakeNoCertificate, handshakeExpiredCertificate, handshakeUntrusted, handshakeInvalidCertificate,
+	handshakeUnauthorized, handshakeClientClosed, handshakeTimeout, handshakeOther,
+}
+
+var (
+	// tlsHandshakeDuration times successful handshakes; resumed ones skip
+	// the certificate exchange and the SPIFFE chain verification.
+	tlsHandshakeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "svid_exchange_tls_handshake_duration_seconds",
+		Help:    "Duration of successful TLS handshakes on the gRPC servers, by server (data, admin) and whether the session was resumed.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"server", "resumed"})
+	tlsHandshakeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "svid_exchange_tls_handshake_failures_total",
+		Help: "Failed TLS handshakes on the gRPC servers, by server (data, admin) and reason.",
+	}, []string{"server", "reason"})
+)
+
+// grpcTLSSessions is the TLS session resumption applied to both gRPC
+// servers.
+type grpcTLSSessions struct {
+	// Tickets issues TLS 1.3 session tickets, so a reconnecting client can
+	// resume its session instead of repeating the full handshake.
+	Tickets bool
+	// TicketLifetime is how long after it is issued a ticket is accepted.
+	TicketLifetime time.Duration
+}
+
+// defaultGRPCTLSSessions is used for every setting the config file leaves
+// out.
+var defaultGRPCTLSSessions = grpcTLSSessions{
+	Tickets:        true,
+	TicketLifetime: time.Hour,
+}
+
+// apply returns a copy of tc with s applied. A resumed session carries the
+// client certificate of the handshake that issued its ticket, and crypto/tls
+// does not call VerifyPeerCertificate for it, so tc's VerifyPeerCertificate
+// is run again on every resumption: a client whose trust bundle or trust
+// domain is no longer accepted must do a full handshake, and fails it.
+// crypto/tls itself refuses to resume once that certificate has expired.
+func (s grpcTLSSessions) apply(tc *tls.Config) *tls.Config {
+	out := tc.Clone()
+	if !s.Tickets {
+		out.SessionTicketsDisabled = true
+		return out
+	}
+	if verify := out.VerifyPeerCertificate; verify != nil {
+		out.VerifyConnection = func(cs tls.ConnectionState) error {
+			if !cs.DidResume {
+				return nil
+			}
+			raw := make([][]byte, len(cs.PeerCertificates))
+			for i, c := range cs.PeerCertificates {
+				raw[i] = c.Raw
+			}
+			return verify(raw, nil)
+		}
+	}
+	// gRPC clones the config for every connection; the tickets must be
+	// sealed and opened with out's keys, not a clone's.
+	out.WrapSession = func(cs tls.ConnectionState, ss *tls.SessionState) ([]byte, error) {
+		ss.Extra = append(ss.Extra, ticketIssuedAt(time.Now()))
+		return out.EncryptTicket(cs, ss)
+	}
+	out.UnwrapSession = func(identity []byte, cs tls.ConnectionState) (*tls.SessionState, error) {
+		ss, err := out.DecryptTicket(identity, cs)
+		if err != nil || ss == nil {
+			return nil, err
+		}
+		issued, ok := ticketIssued(ss.Extra)
+		if !ok || time.Since(issued) > s.TicketLifetime {
+			return nil, nil // full handshake
+		}
+		return ss, nil
+	}
+	return out
+}
+
+// ticketIssuedPrefix marks the session ticket field holding its issue time.
+var ticketIssuedPrefix = []byte("svid-exchange/issued:")
+
+func ticketIssuedAt(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(bytes.Clone(ticketIssuedPrefix), uint64(t.Unix()))
+}
+
+// ticketIssued returns the issue time recorded in a ticket's extra fields.
+func ticketIssued(extra [][]byte) (time.Time, bool) {
+	for _, e := range extra {
+		if v, ok := bytes.CutPrefix(e, ticketIssuedPrefix); ok && len(v) == 8 {
+			return time.Unix(int64(binary.BigEndian.Uint64(v)), 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// handshakeObserver records the duration of every successful server TLS
+// handshake, and counts and logs the failed ones.
+type handshakeObserver struct {
+	credentials.TransportCredentials
+	server string
+	log    zerolog.Logger
+}
+
+// observeHandshakes wraps creds to record the handshakes of the named
+// server. Failures are logged at debug, since load balancer health checks
+// and port scanners cause them as well as misconfigured clients.
+func observeHandshakes(creds credentials.TransportCredentials, server string, log zerolog.Logger) credentials.TransportCredentials {
+	for _, reason := range handshakeFailureReasons {
+		tlsHandshakeFailures.WithLabelValues(server, reason)
+	}
+	return handshakeObserver{TransportCredentials: creds, server: server, log: log}
+}
+
+func (h handshakeObserver) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	c, info, err := h.TransportCredentials.ServerHandshake(conn)
+	if err != nil {
+		reason := handshakeFailureReason(err)
+		tlsHandshakeFailures.WithLabelValues(h.server, reason).Inc()
+		h.log.Debug().Err(err).Str("server", h.server).Str("reason", reason).
+			Stringer("peer_addr", conn.RemoteAddr()).Msg("TLS handshake failed")
+		return c, info, err
+	}
+	resumed := false
+	if ti, ok := info.(credentials.TLSInfo); ok {
+		resumed = ti.State.DidResume
+	}
+	tlsHandshakeDuration.WithLabelValues(h.server, strconv.FormatBool(resumed)).Observe(time.Since(start).Seconds())
+	return c, info, nil
+}
+
+func (h handshakeObserver) Clone() credentials.TransportCredentials {
+	return handshakeObserver{TransportCredentials: h.TransportCredentials.Clone(), server: h.server, log: h.log}
+}
+
+// handshakeFailureReason classifies a failed server handshake.
+func handshakeFailureReason(err error) string {
+	var invalid x509.CertificateInvalidError
+	var unknown x509.UnknownAuthorityError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errTrustDomainNotAllowed):
+		return handshakeUnauthorized
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return handshakeExpiredCertificate
+	case errors.As(err, &unknown):
+		return handshakeUntrusted
+	case strings.Contains(err.Error(), "didn't provide a certificate"):
+		return handshakeNoCertificate
+	case strings.HasPrefix(err.Error(), "x509svid:"):
+		return handshakeInvalidCertificate
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return handshakeTimeout
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET):
+		return handshakeClientClosed
+	}
+	return handshakeOther
+}
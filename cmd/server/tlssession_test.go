@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/credentials"
+)
+
+// selfSignedTLSCert returns a certificate usable on either side of a test
+// handshake.
+func selfSignedTLSCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// handshake connects a client configured with client to a server configured
+// with srv over loopback, and reports whether the session was resumed. The
+// client reads one byte so that it receives the server's session ticket.
+func handshake(t *testing.T, srv, client *tls.Config) (bool, error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		if err := conn.(*tls.Conn).Handshake(); err != nil {
+			errc <- err
+			return
+		}
+		_, err = conn.Write([]byte{1})
+		errc <- err
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err != nil {
+		<-errc
+		return false, err
+	}
+	defer conn.Close()
+	_, readErr := io.ReadFull(conn, make([]byte, 1))
+	if err := <-errc; err != nil {
+		return false, err
+	}
+	if readErr != nil {
+		return false, fmt.Errorf("read: %w", readErr)
+	}
+	return conn.ConnectionState().DidResume, nil
+}
+
+func TestGRPCTLSSessions(t *testing.T) {
+	cert := selfSignedTLSCert(t)
+	newConfigs := func(s grpcTLSSessions, verify func([][]byte, [][]*x509.Certificate) error) (*tls.Config, *tls.Config) {
+		srv := s.apply(&tls.Config{
+			MinVersion:            tls.VersionTLS13,
+			Certificates:          []tls.Certificate{cert},
+			ClientAuth:            tls.RequireAnyClientCert,
+			VerifyPeerCertificate: verify,
+		})
+		client := &tls.Config{
+			MinVersion:         tls.VersionTLS13,
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: true,
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		}
+		return srv, client
+	}
+
+	t.Run("resumes and verifies again", func(t *testing.T) {
+		var verified atomic.Int32
+		srv, client := newConfigs(defaultGRPCTLSSessions, func(raw [][]byte, _ [][]*x509.Certificate) error {
+			verified.Add(1)
+			if len(raw) != 1 {
+				return errors.New("no client certificate")
+			}
+			return nil
+		})
+		for i, want := range []bool{false, true} {
+			resumed, err := handshake(t, srv, client)
+			if err != nil {
+				t.Fatalf("handshake %d: %v", i, err)
+			}
+			if resumed != want {
+				t.Errorf("handshake %d resumed = %t, want %t", i, resumed, want)
+			}
+		}
+		if got := verified.Load(); got != 2 {
+			t.Errorf("client certificate verified %d times, want 2", got)
+		}
+	})
+
+	t.Run("resumption fails once the client is no longer accepted", func(t *testing.T) {
+		var reject atomic.Bool
+		srv, client := newConfigs(defaultGRPCTLSSessions, func([][]byte, [][]*x509.Certificate) error {
+			if reject.Load() {
+				return errTrustDomainNotAllowed
+			}
+			return nil
+		})
+		if _, err := handshake(t, srv, client); err != nil {
+			t.Fatalf("first handshake: %v", err)
+		}
+		reject.Store(true)
+		if _, err := handshake(t, srv, client); err == nil {
+			t.Error("resumed handshake succeeded for a rejected client")
+		}
+	})
+
+	t.Run("expired ticket", func(t *testing.T) {
+		srv, client := newConfigs(grpcTLSSessions{Tickets: true, TicketLifetime: time.Nanosecond}, nil)
+		for i := range 2 {
+			resumed, err := handshake(t, srv, client)
+			if err != nil {
+				t.Fatalf("handshake %d: %v", i, err)
+			}
+			if resumed {
+				t.Errorf("handshake %d resumed with an expired ticket", i)
+			}
+		}
+	})
+
+	t.Run("tickets disabled", func(t *testing.T) {
+		srv, client := newConfigs(grpcTLSSessions{}, nil)
+		for i := range 2 {
+			resumed, err := handshake(t, srv, client)
+			if err != nil {
+				t.Fatalf("handshake %d: %v", i, err)
+			}
+			if resumed {
+				t.Errorf("handshake %d resumed with tickets disabled", i)
+			}
+		}
+	})
+}
+
+func TestHandshakeFailureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: %q", errTrustDomainNotAllowed, "partner.example"), handshakeUnauthorized},
+		{fmt.Errorf("x509svid: could not verify leaf certificate: %w", x509.CertificateInvalidError{Reason: x509.Expired}), handshakeExpiredCertificate},
+		{fmt.Errorf("x509svid: could not verify leaf certificate: %w", x509.UnknownAuthorityError{}), handshakeUntrusted},
+		{errors.New("x509svid: could not get leaf SPIFFE ID: certificate contains no URI SAN"), handshakeInvalidCertificate},
+		{errors.New("tls: client didn't provide a certificate"), handshakeNoCertificate},
+		{context.DeadlineExceeded, handshakeTimeout},
+		{io.EOF, handshakeClientClosed},
+		{errors.New("tls: unsupported protocol version"), handshakeOther},
+	}
+	for _, tc := range tests {
+		if got := handshakeFailureReason(tc.err); got != tc.want {
+			t.Errorf("handshakeFailureReason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+// failingCreds fails every server handshake with err.
+type failingCreds struct {
+	credentials.TransportCredentials
+	err error
+}
+
+func (f failingCreds) ServerHandshake(net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, f.err
+}
+
+func TestObserveHandshakesCountsFailures(t *testing.T) {
+	creds := observeHandshakes(failingCreds{err: io.EOF}, "test", zerolog.Nop())
+	if got := testutil.ToFloat64(tlsHandshakeFailures.WithLabelValues("test", handshakeTimeout)); got != 0 {
+		t.Errorf("timeout series = %v before any failure, want 0", got)
+	}
+	c, _ := net.Pipe()
+	defer c.Close()
+	if _, _, err := creds.ServerHandshake(c); err == nil {
+		t.Fatal("ServerHandshake succeeded")
+	}
+	if got := testutil.ToFloat64(tlsHandshakeFailures.WithLabelValues("test", handshakeClientClosed)); got != 1 {
+		t.Errorf("client_closed failures = %v, want 1", got)
+	}
+}
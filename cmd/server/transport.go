@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Response compression settings for grpc_compression.
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+)
+
+// compressions lists the accepted grpc_compression values.
+var compressions = []string{compressionNone, compressionGzip}
+
+// grpcTransport is the HTTP/2 transport tuning applied to both gRPC servers.
+// A zero size or worker count keeps the gRPC default.
+type grpcTransport struct {
+	// Compression names the compressor for responses to clients that
+	// advertise it in grpc-accept-encoding. Requests compressed with any
+	// registered codec are accepted whatever this is set to.
+	Compression string
+	// WriteBufferSize and ReadBufferSize are the per-connection buffers in
+	// bytes. SharedWriteBuffer releases the write buffer between flushes,
+	// which saves memory with many idle connections at some cost in
+	// allocations.
+	WriteBufferSize   int
+	ReadBufferSize    int
+	SharedWriteBuffer bool
+	// InitialWindowSize and InitialConnWindowSize are the HTTP/2 flow
+	// control windows per stream and per connection in bytes. Setting
+	// either turns off gRPC's bandwidth-delay estimation for that window.
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+	// NumStreamWorkers serves streams on a fixed pool of goroutines instead
+	// of one new goroutine per stream.
+	NumStreamWorkers uint32
+}
+
+// serverOptions returns the gRPC server options applying t.
+func (t grpcTransport) serverOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{grpc.SharedWriteBuffer(t.SharedWriteBuffer)}
+	if t.WriteBufferSize > 0 {
+		opts = append(opts, grpc.WriteBufferSize(t.WriteBufferSize))
+	}
+	if t.ReadBufferSize > 0 {
+		opts = append(opts, grpc.ReadBufferSize(t.ReadBufferSize))
+	}
+	if t.InitialWindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(t.InitialWindowSize))
+	}
+	if t.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(t.InitialConnWindowSize))
+	}
+	if t.NumStreamWorkers > 0 {
+		opts = append(opts, grpc.NumStreamWorkers(t.NumStreamWorkers))
+	}
+	if t.Compression == compressionGzip {
+		opts = append(opts, grpc.ChainUnaryInterceptor(newCompressionInterceptor(gzip.Name)))
+	}
+	return opts
+}
+
+// newCompressionInterceptor compresses each response with the named
+// compressor when the client has said it can decompress it. A client that
+// does not list it gets an uncompressed response, as it would without the
+// interceptor.
+func newCompressionInterceptor(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if supported, err := grpc.ClientSupportedCompressors(ctx); err == nil && slices.Contains(supported, name) {
+			// Only fails outside a server stream, which cannot happen here.
+			_ = grpc.SetSendCompressor(ctx, name)
+		}
+		return handler(ctx, req)
+	}
+}
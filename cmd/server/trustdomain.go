@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+)
+
+// rejectedPeers counts TLS handshakes refused because the client's trust
+// domain is not allowed. The trust domain is not a label: it is chosen by
+// the client, so it would let any federated peer create series.
+var rejectedPeers = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "svid_exchange_tls_peers_rejected_total",
+	Help: "TLS handshakes rejected because the client SVID's trust domain is not in allowed_trust_domains.",
+})
+
+// errTrustDomainNotAllowed fails the handshake of a client outside
+// allowed_trust_domains.
+var errTrustDomainNotAllowed = errors.New("trust domain is not allowed")
+
+// newTrustDomainAuthorizer returns a tlsconfig.Authorizer that accepts a
+// client only if its SPIFFE ID belongs to one of allowed. go-spiffe runs it
+// from VerifyPeerCertificate after the chain has been verified against the
+// trust bundle, so a rejected client fails the handshake and never reaches
+// an interceptor or handler. With no allowed trust domains every verified
+// client is accepted.
+func newTrustDomainAuthorizer(allowed []spiffeid.TrustDomain) tlsconfig.Authorizer {
+	if len(allowed) == 0 {
+		return tlsconfig.AuthorizeAny()
+	}
+	set := make(map[spiffeid.TrustDomain]struct{}, len(allowed))
+	for _, td := range allowed {
+		set[td] = struct{}{}
+	}
+	return func(id spiffeid.ID, _ [][]*x509.Certificate) error {
+		if _, ok := set[id.TrustDomain()]; ok {
+			return nil
+		}
+		rejectedPeers.Inc()
+		return fmt.Errorf("%w: %q", errTrustDomainNotAllowed, id.TrustDomain())
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+func TestTrustDomainAuthorizer(t *testing.T) {
+	local := spiffeid.RequireTrustDomainFromString("cluster.local")
+	partner := spiffeid.RequireTrustDomainFromString("partner.example")
+
+	tests := []struct {
+		name    string
+		allowed []spiffeid.TrustDomain
+		peer    string
+		wantErr bool
+	}{
+		{name: "empty allowlist accepts any trust domain", peer: "spiffe://other.example/ns/a/sa/b"},
+		{name: "allowed trust domain", allowed: []spiffeid.TrustDomain{local, partner}, peer: "spiffe://partner.example/ns/a/sa/b"},
+		{name: "other trust domain rejected", allowed: []spiffeid.TrustDomain{local}, peer: "spiffe://partner.example/ns/a/sa/b", wantErr: true},
+		{name: "subdomain is a different trust domain", allowed: []spiffeid.TrustDomain{local}, peer: "spiffe://eu.cluster.local/ns/a/sa/b", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(rejectedPeers)
+			err := newTrustDomainAuthorizer(tc.allowed)(spiffeid.RequireFromString(tc.peer), nil)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			want := 0.0
+			if tc.wantErr {
+				want = 1
+			}
+			if got := testutil.ToFloat64(rejectedPeers) - before; got != want {
+				t.Errorf("rejected counter increased by %v, want %v", got, want)
+			}
+		})
+	}
+}
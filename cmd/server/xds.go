@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	xdscreds "google.golang.org/grpc/credentials/xds"
+	"google.golang.org/grpc/xds"
+)
+
+// grpcServer is the data-plane server: a *grpc.Server, or an
+// *xds.GRPCServer when grpc_xds is set.
+type grpcServer interface {
+	grpc.ServiceRegistrar
+	GetServiceInfo() map[string]grpc.ServiceInfo
+	Serve(net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// newDataPlaneServer returns the data-plane gRPC server with opts, secured
+// by creds. With useXDS the server takes its listener configuration from an
+// xDS control plane, located by the bootstrap file named in
+// GRPC_XDS_BOOTSTRAP (or the GRPC_XDS_BOOTSTRAP_CONFIG contents), and
+// serves only while the control plane says it may. The control plane may
+// supply the transport security; where it does not, creds, the SPIFFE mTLS
+// configuration, is used as before.
+func newDataPlaneServer(useXDS bool, creds credentials.TransportCredentials, opts []grpc.ServerOption, log zerolog.Logger) (grpcServer, error) {
+	if !useXDS {
+		return grpc.NewServer(append(opts, grpc.Creds(creds))...), nil
+	}
+	xc, err := xdscreds.NewServerCredentials(xdscreds.ServerOptions{FallbackCreds: creds})
+	if err != nil {
+		return nil, fmt.Errorf("xds credentials: %w", err)
+	}
+	s, err := xds.NewGRPCServer(append(opts,
+		grpc.Creds(xc),
+		xds.ServingModeCallback(func(addr net.Addr, args xds.ServingModeChangeArgs) {
+			ev := log.Info()
+			if args.Err != nil {
+				ev = log.Warn().Err(args.Err)
+			}
+			ev.Stringer("addr", addr).Stringer("mode", args.Mode).Msg("xds serving mode changed")
+		}),
+	)...)
+	if err != nil {
+		return nil, fmt.Errorf("xds server: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestNewDataPlaneServer(t *testing.T) {
+	t.Run("plain gRPC server", func(t *testing.T) {
+		s, err := newDataPlaneServer(false, insecure.NewCredentials(), nil, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("newDataPlaneServer: %v", err)
+		}
+		if _, ok := s.(*grpc.Server); !ok {
+			t.Errorf("server is %T, want *grpc.Server", s)
+		}
+	})
+
+	t.Run("xds without a bootstrap fails", func(t *testing.T) {
+		if os.Getenv("GRPC_XDS_BOOTSTRAP") != "" || os.Getenv("GRPC_XDS_BOOTSTRAP_CONFIG") != "" {
+			t.Skip("an xDS bootstrap is configured in the environment")
+		}
+		if _, err := newDataPlaneServer(true, insecure.NewCredentials(), nil, zerolog.Nop()); err == nil {
+			t.Error("newDataPlaneServer succeeded without an xDS bootstrap")
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+
+	"github.com/ngaddam369/svid-exchange/internal/policyimport"
+)
+
+// devCAHeader heads the policy file written by svidx dev ca.
+const devCAHeader = `Generated by svidx dev ca for %s.
+Every identity may exchange for every other. For development only.`
+
+// Files of an identity directory, laid out as a Kubernetes TLS secret so the
+// server's SPIFFE_SVID_DIR and <NAME>_TLS_DIR settings read it directly.
+const (
+	devCertFile = "tls.crt"
+	devKeyFile  = "tls.key"
+	devCAFile   = "ca.crt"
+)
+
+// devServerDir is the directory of the server's own SVID.
+const devServerDir = "server"
+
+// devIdentity is an identity to issue an SVID for and the directory it is
+// written to.
+type devIdentity struct {
+	id  spiffeid.ID
+	dir string
+}
+
+// devCA implements "svidx dev ca": it creates a throwaway CA in a new
+// directory, issues an SVID for the server and for each named workload, and
+// writes a policy file letting the workloads exchange for one another.
+func devCA(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dev ca", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("out", "dev-ca", "directory to create and write the CA, SVIDs, and policy to")
+	trustDomain := fs.String("trust-domain", "dev.local", "trust domain of the issued SVIDs")
+	server := fs.String("server", "svid-exchange", "SPIFFE ID, or path within the trust domain, of the server")
+	ttl := fs.Duration("ttl", 24*time.Hour, "validity of the CA and SVIDs")
+	var scopes listFlag
+	fs.Var(&scopes, "scopes", "comma-separated allowed scopes of every generated policy (default \"all\")")
+	maxTTL := fs.Int("max-ttl", 300, "max_ttl of every generated policy, in seconds")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *ttl <= 0 || *maxTTL <= 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if len(scopes) == 0 {
+		scopes = listFlag{"all"}
+	}
+	td, err := spiffeid.TrustDomainFromString(*trustDomain)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid trust domain %q: %v\n", *trustDomain, err)
+		return 2
+	}
+
+	serverID, err := devID(td, *server)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid server identity: %v\n", err)
+		return 2
+	}
+	idents := []devIdentity{{id: serverID, dir: devServerDir}}
+	dirs := map[string]string{devServerDir: serverID.String()}
+	var workloads []string
+	for _, arg := range fs.Args() {
+		id, err := devID(td, arg)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid identity: %v\n", err)
+			return 2
+		}
+		dir := path.Base(id.Path())
+		if prev, ok := dirs[dir]; ok {
+			fmt.Fprintf(stderr, "%s and %s would share directory %s\n", prev, id, dir)
+			return 2
+		}
+		dirs[dir] = id.String()
+		idents = append(idents, devIdentity{id: id, dir: dir})
+		workloads = append(workloads, id.String())
+	}
+
+	if err := writeDevCA(*out, td, idents, *ttl); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	f, err := os.Create(filepath.Join(*out, "policy.yaml"))
+	if err != nil {
+		fmt.Fprintf(stderr, "create policy file: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	ps := policyimport.AllPairs(workloads, scopes, int32(*maxTTL))
+	if len(ps) == 0 {
+		fmt.Fprintln(stderr, "warning: a single workload gets no policies; name at least two to exchange between")
+	}
+	if err := policyimport.WriteYAML(f, fmt.Sprintf(devCAHeader, td.IDString()), ps); err != nil {
+		fmt.Fprintf(stderr, "write policy file: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(stderr, "write policy file: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Wrote a %s CA valid for %s to %s. Start the server with:\n\n", td.Name(), *ttl, *out)
+	fmt.Fprintf(stdout, "  SPIFFE_SVID_DIR=%s POLICY_FILE=%s svid-exchange\n\n",
+		filepath.Join(*out, devServerDir), filepath.Join(*out, "policy.yaml"))
+	fmt.Fprintln(stdout, "Workload SVIDs:")
+	for _, ident := range idents[1:] {
+		fmt.Fprintf(stdout, "  %s\t%s\n", ident.id, filepath.Join(*out, ident.dir))
+	}
+	return 0
+}
+
+// devID parses s as a SPIFFE ID in td, or as a path within td when it has
+// no spiffe:// scheme.
+func devID(td spiffeid.TrustDomain, s string) (spiffeid.ID, error) {
+	if strings.HasPrefix(s, "spiffe://") {
+		id, err := spiffeid.FromString(s)
+		if err != nil {
+			return spiffeid.ID{}, fmt.Errorf("%q: %w", s, err)
+		}
+		if !id.MemberOf(td) {
+			return spiffeid.ID{}, fmt.Errorf("%s is not in trust domain %s", id, td.Name())
+		}
+		if id.Path() == "" {
+			return spiffeid.ID{}, fmt.Errorf("%s has no path", id)
+		}
+		return id, nil
+	}
+	id, err := spiffeid.FromPath(td, "/"+strings.Trim(s, "/"))
+	if err != nil {
+		return spiffeid.ID{}, fmt.Errorf("%q: %w", s, err)
+	}
+	return id, nil
+}
+
+// writeDevCA creates dir, which must not exist or be empty, and writes the
+// CA certificate to it and an SVID for each identity to its subdirectory.
+// The CA key is not kept, so nothing more can be issued from the CA.
+func writeDevCA(dir string, td spiffeid.TrustDomain, idents []devIdentity, ttl time.Duration) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate CA key: %w", err)
+	}
+	caTmpl := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"svidx dev ca"}, CommonName: td.Name()},
+		URIs:                  []*url.URL{td.ID().URL()},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(ttl),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caCert, caPEM, err := signCert(caTmpl, nil, &caKey.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("create CA certificate: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, devCAFile), caPEM, 0o644); err != nil {
+		return err
+	}
+
+	for _, ident := range idents {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generate key for %s: %w", ident.id, err)
+		}
+		tmpl := &x509.Certificate{
+			Subject:     pkix.Name{Organization: []string{"svidx dev ca"}},
+			URIs:        []*url.URL{ident.id.URL()},
+			NotBefore:   now.Add(-time.Minute),
+			NotAfter:    now.Add(ttl),
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		_, certPEM, err := signCert(tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			return fmt.Errorf("issue SVID for %s: %w", ident.id, err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return fmt.Errorf("encode key for %s: %w", ident.id, err)
+		}
+		sub := filepath.Join(dir, ident.dir)
+		if err := os.Mkdir(sub, 0o755); err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			name string
+			data []byte
+			perm os.FileMode
+		}{
+			{devCertFile, certPEM, 0o644},
+			{devKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600},
+			{devCAFile, caPEM, 0o644},
+		} {
+			if err := os.WriteFile(filepath.Join(sub, f.name), f.data, f.perm); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// signCert signs tmpl with signer as parent, or self-signs it when parent is
+// nil, giving it a random serial number. It returns the certificate and its
+// PEM encoding.
+func signCert(tmpl, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, []byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.SerialNumber = serial
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+func TestDevCA(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "dev-ca")
+	var stdout, stderr bytes.Buffer
+	args := []string{"dev", "ca", "-out", out, "-trust-domain", "cluster.local", "-scopes", "payments:charge", order, "ns/default/sa/payment"}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "SPIFFE_SVID_DIR="+filepath.Join(out, "server")) {
+		t.Errorf("output lacks the server command:\n%s", stdout.String())
+	}
+
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+	bundle, err := x509bundle.Load(td, filepath.Join(out, "ca.crt"))
+	if err != nil {
+		t.Fatalf("load CA: %v", err)
+	}
+	for dir, want := range map[string]string{
+		"server":  "spiffe://cluster.local/svid-exchange",
+		"order":   order,
+		"payment": payment,
+	} {
+		svid, err := x509svid.Load(filepath.Join(out, dir, "tls.crt"), filepath.Join(out, dir, "tls.key"))
+		if err != nil {
+			t.Fatalf("load %s SVID: %v", dir, err)
+		}
+		id, _, err := x509svid.Verify(svid.Certificates, bundle)
+		if err != nil {
+			t.Fatalf("verify %s SVID: %v", dir, err)
+		}
+		if id.String() != want {
+			t.Errorf("%s SVID ID = %s, want %s", dir, id, want)
+		}
+	}
+
+	l, err := policy.LoadFile(filepath.Join(out, "policy.yaml"))
+	if err != nil {
+		t.Fatalf("load generated policy: %v", err)
+	}
+	if r := l.Evaluate(order, payment, []string{"payments:charge"}, 0); !r.Allowed {
+		t.Errorf("order → payment denied: %+v", r)
+	}
+	if r := l.Evaluate(payment, order, []string{"payments:charge"}, 0); !r.Allowed {
+		t.Errorf("payment → order denied: %+v", r)
+	}
+
+	// The output directory is never overwritten.
+	stderr.Reset()
+	if code := run(args, nil, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "is not empty") {
+		t.Errorf("second run: exit code %d, stderr %q; want 1 and a non-empty directory error", code, stderr.String())
+	}
+}
+
+func TestDevCAUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no identities", args: []string{"dev", "ca"}},
+		{name: "other trust domain", args: []string{"dev", "ca", "spiffe://other.local/order"}},
+		{name: "shared directory", args: []string{"dev", "ca", "a/order", "b/order"}},
+		{name: "identity named server", args: []string{"dev", "ca", "server"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append(tc.args[:2:2], append([]string{"-out", t.TempDir()}, tc.args[2:]...)...)
+			var stdout, stderr bytes.Buffer
+			if code := run(args, nil, &stdout, &stderr); code != 2 {
+				t.Errorf("exit code %d, want 2; stderr %q", code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// policyDiff implements "svidx policy diff".
+func policyDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("policy diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	conflicts := fs.String("conflicts", string(policy.ConflictWarn), "conflict handling the server uses: warn, error, merge-union, or merge-intersection")
+	auditLog := fs.String("audit-log", "", "JSON audit log whose exchanges are replayed against both files; - reads standard input")
+	since := fs.Duration("since", 0, "replay only exchanges logged within this long; 0 replays the whole log")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	mode, err := policy.ParseConflictMode(*conflicts)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid -conflicts: %v\n", err)
+		return 2
+	}
+
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+	oldL, err := policy.LoadFileWithConflictMode(oldPath, mode)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid policy %q: %v\n", oldPath, err)
+		return 1
+	}
+	newL, err := policy.LoadFileWithConflictMode(newPath, mode)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid policy %q: %v\n", newPath, err)
+		return 1
+	}
+
+	writeGrantChanges(stdout, policy.DiffPolicies(oldL.Policies(), newL.Policies()))
+
+	if *auditLog == "" {
+		return 0
+	}
+	in := stdin
+	if *auditLog != "-" {
+		f, err := os.Open(*auditLog)
+		if err != nil {
+			fmt.Fprintf(stderr, "open audit log: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	res, err := replay(in, oldL, newL, from)
+	if err != nil {
+		fmt.Fprintf(stderr, "replay audit log: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout)
+	writeReplay(stdout, res)
+	return 0
+}
+
+// writeGrantChanges prints one block per change: "+" for an added grant,
+// "-" for a removed one, and "~" for a changed one, followed by its details.
+func writeGrantChanges(w io.Writer, changes []policy.GrantChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No grant changes.")
+		return
+	}
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c.Kind {
+		case policy.GrantAdded:
+			added++
+			fmt.Fprintf(w, "+ %s → %s (%s): %s\n", c.Subject, c.Target, c.NewName, strings.Join(c.Scopes, ", "))
+		case policy.GrantRemoved:
+			removed++
+			fmt.Fprintf(w, "- %s → %s (%s): %s\n", c.Subject, c.Target, c.OldName, strings.Join(c.Scopes, ", "))
+		default:
+			changed++
+			fmt.Fprintf(w, "~ %s → %s (%s)\n", c.Subject, c.Target, c.NewName)
+			if c.OldName != c.NewName {
+				fmt.Fprintf(w, "    renamed from %s\n", c.OldName)
+			}
+			for _, s := range c.AddedScopes {
+				fmt.Fprintf(w, "    + %s\n", s)
+			}
+			for _, s := range c.RemovedScopes {
+				fmt.Fprintf(w, "    - %s\n", s)
+			}
+			for _, d := range c.Details {
+				fmt.Fprintf(w, "    %s\n", d)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d added, %d removed, %d changed\n", added, removed, changed)
+}
+
+// writeReplay prints the replayed exchanges whose outcome would change.
+func writeReplay(w io.Writer, res replayResult) {
+	fmt.Fprintf(w, "Replayed %d exchanges (%d skipped); %d would change outcome.\n", res.Replayed, res.Skipped, len(res.Changes))
+	for _, c := range res.Changes {
+		at := "-"
+		if !c.Time.IsZero() {
+			at = c.Time.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s %s → %s [%s]: %s ⇒ %s\n", at, c.Subject, c.Target, strings.Join(c.Scopes, ", "), outcome(c.Old), outcome(c.New))
+	}
+}
+
+// outcome describes an evaluation result as "denied" or the grant it makes;
+// the token format is named unless it is the default.
+func outcome(r policy.EvalResult) string {
+	if !r.Allowed {
+		return "denied"
+	}
+	out := fmt.Sprintf("granted [%s] ttl %d", strings.Join(r.GrantedScopes, ", "), r.GrantedTTL)
+	if r.TokenFormat != policy.FormatJWT {
+		out += " as " + r.TokenFormat
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	order   = "spiffe://cluster.local/ns/default/sa/order"
+	payment = "spiffe://cluster.local/ns/default/sa/payment"
+	ledger  = "spiffe://cluster.local/ns/default/sa/ledger"
+)
+
+// writeFile writes body to name in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestPolicyDiff(t *testing.T) {
+	oldPolicy := writeFile(t, "old.yaml", `policies:
+  - name: order-payment
+    subject: "`+order+`"
+    target: "`+payment+`"
+    allowed_scopes: ["payments:charge", "payments:read"]
+    max_ttl: 300
+  - name: order-ledger
+    subject: "`+order+`"
+    target: "`+ledger+`"
+    allowed_scopes: ["ledger:read"]
+    max_ttl: 60
+`)
+	newPolicy := writeFile(t, "new.yaml", `policies:
+  - name: order-payment
+    subject: "`+order+`"
+    target: "`+payment+`"
+    allowed_scopes: ["payments:read"]
+    max_ttl: 300
+  - name: ledger-payment
+    subject: "`+ledger+`"
+    target: "`+payment+`"
+    allowed_scopes: ["payments:read"]
+    max_ttl: 60
+`)
+	now := time.Now().UTC()
+	auditLog := writeFile(t, "audit.log", strings.Join([]string{
+		`{"level":"info","event":"token.exchange","time":"` + now.Format(time.RFC3339) + `","subject":"` + order + `","target":"` + payment + `","scopes_requested":["payments:charge"],"granted":true,"scopes_granted":["payments:charge"],"ttl":300}`,
+		`{"level":"info","event":"token.exchange","time":"` + now.Format(time.RFC3339) + `","subject":"` + order + `","target":"` + payment + `","scopes_requested":["payments:read"],"granted":true,"scopes_granted":["payments:read"],"ttl":60}`,
+		`{"level":"warn","event":"token.exchange","time":"` + now.Format(time.RFC3339) + `","subject":"` + ledger + `","target":"` + payment + `","scopes_requested":["payments:read"],"granted":false}`,
+		`{"level":"info","event":"token.exchange","time":"` + now.Add(-48*time.Hour).Format(time.RFC3339) + `","subject":"` + order + `","target":"` + ledger + `","scopes_requested":["ledger:read"],"granted":true,"ttl":60}`,
+		`{"level":"info","event":"token.exchange","time":"` + now.Format(time.RFC3339) + `","subject":"[redacted]","target":"` + payment + `","scopes_requested":["payments:read"],"granted":false}`,
+		`{"level":"info","message":"server started"}`,
+		`not json`,
+	}, "\n")+"\n")
+
+	t.Run("grant changes", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"policy", "diff", oldPolicy, newPolicy}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+		want := strings.Join([]string{
+			"+ " + ledger + " → " + payment + " (ledger-payment): payments:read",
+			"- " + order + " → " + ledger + " (order-ledger): ledger:read",
+			"~ " + order + " → " + payment + " (order-payment)",
+			"    - payments:charge",
+			"1 added, 1 removed, 1 changed",
+		}, "\n") + "\n"
+		if stdout.String() != want {
+			t.Errorf("output =\n%s\nwant\n%s", stdout.String(), want)
+		}
+	})
+
+	t.Run("audit replay", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"policy", "diff", "-audit-log", auditLog, "-since", "24h", oldPolicy, newPolicy}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+		out := stdout.String()
+		if !strings.Contains(out, "Replayed 3 exchanges (1 skipped); 2 would change outcome.") {
+			t.Errorf("output lacks the replay summary:\n%s", out)
+		}
+		if !strings.Contains(out, order+" → "+payment+" [payments:charge]: granted [payments:charge] ttl 300 ⇒ denied") {
+			t.Errorf("output lacks the revoked charge:\n%s", out)
+		}
+		if !strings.Contains(out, ledger+" → "+payment+" [payments:read]: denied ⇒ granted [payments:read] ttl 60") {
+			t.Errorf("output lacks the new ledger grant:\n%s", out)
+		}
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		for _, args := range [][]string{
+			{},
+			{"policy", "lint"},
+			{"policy", "diff", oldPolicy},
+			{"policy", "diff", "-conflicts", "bogus", oldPolicy, newPolicy},
+		} {
+			var stdout, stderr bytes.Buffer
+			if code := run(args, nil, &stdout, &stderr); code != 2 {
+				t.Errorf("run(%q) = %d, want 2", args, code)
+			}
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"policy", "diff", oldPolicy, filepath.Join(t.TempDir(), "missing.yaml")}, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("exit code %d, want 1", code)
+		}
+	})
+}
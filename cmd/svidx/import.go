@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/policyimport"
+)
+
+// importHeader heads every generated policy file.
+const importHeader = `Generated by svidx policy import from %s.
+Review every rule before use: scopes are derived mechanically and rules
+that could not be converted are missing.`
+
+// listFlag is a flag that may be repeated, or given a comma-separated list.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+// importIstio implements "svidx policy import istio".
+func importIstio(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("policy import istio", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts := policyimport.IstioOptions{}
+	fs.StringVar(&opts.TrustDomain, "trust-domain", "cluster.local", "mesh trust domain, for source namespaces and target workloads")
+	fs.StringVar(&opts.ServiceAccountLabel, "sa-label", "app", "selector label whose value is the target workload's service account")
+	fs.StringVar(&opts.DefaultScope, "default-scope", "all", "scope granted by rules without operations")
+	maxTTL := fs.Int("max-ttl", 300, "max_ttl of every generated policy, in seconds")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 || *maxTTL <= 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	opts.MaxTTL = int32(*maxTTL)
+
+	in, source := stdin, "standard input"
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "open AuthorizationPolicies: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in, source = f, fs.Arg(0)
+	}
+	ps, warnings, err := policyimport.FromIstio(in, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	return writeImport(stdout, stderr, "Istio AuthorizationPolicies in "+source, ps)
+}
+
+// importSPIRE implements "svidx policy import spire".
+func importSPIRE(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("policy import spire", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts policyimport.SPIREOptions
+	fs.Var((*listFlag)(&opts.Targets), "target", "SPIFFE ID or pattern every workload may exchange for; repeatable")
+	fs.Var((*listFlag)(&opts.Scopes), "scopes", "comma-separated allowed scopes of every generated policy")
+	maxTTL := fs.Int("max-ttl", 300, "max_ttl of every generated policy, in seconds")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || len(opts.Targets) == 0 || len(opts.Scopes) == 0 || *maxTTL <= 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	opts.MaxTTL = int32(*maxTTL)
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "read SPIRE entries: %v\n", err)
+		return 1
+	}
+	ps, err := policyimport.FromSPIRE(data, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	return writeImport(stdout, stderr, "SPIRE registration entries in "+fs.Arg(0), ps)
+}
+
+// writeImport checks that ps loads as the server would load it and writes it
+// as a policy file.
+func writeImport(stdout, stderr io.Writer, source string, ps []policy.Policy) int {
+	if len(ps) == 0 {
+		fmt.Fprintln(stderr, "no policies could be generated")
+		return 1
+	}
+	l, err := policy.NewLoader(ps)
+	if err != nil {
+		fmt.Fprintf(stderr, "generated policies are invalid: %v\n", err)
+		return 1
+	}
+	for _, c := range l.Conflicts() {
+		fmt.Fprintf(stderr, "conflict: %s\n", c)
+	}
+	if err := policyimport.WriteYAML(stdout, fmt.Sprintf(importHeader, source), ps); err != nil {
+		fmt.Fprintf(stderr, "write policies: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+func TestPolicyImport(t *testing.T) {
+	t.Run("istio from standard input", func(t *testing.T) {
+		in := strings.NewReader(`kind: AuthorizationPolicy
+metadata: {name: payment, namespace: default}
+spec:
+  selector: {matchLabels: {app: payment}}
+  rules:
+    - from: [{source: {principals: ["cluster.local/ns/default/sa/order"]}}]
+      to: [{operation: {methods: ["POST"], paths: ["/charge"]}}]
+    - from: [{source: {principals: ["*"]}}]
+`)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"policy", "import", "istio", "-max-ttl", "120"}, in, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+		if !strings.HasPrefix(stdout.String(), "# Generated by svidx policy import from Istio AuthorizationPolicies in standard input.") {
+			t.Errorf("output lacks the header:\n%s", stdout.String())
+		}
+		path := writeFile(t, "policy.yaml", stdout.String())
+		l, err := policy.LoadFile(path)
+		if err != nil {
+			t.Fatalf("load generated policy: %v", err)
+		}
+		if r := l.Evaluate(order, payment, []string{"POST:/charge"}, 0); !r.Allowed || r.GrantedTTL != 120 {
+			t.Errorf("Evaluate = %+v, want POST:/charge granted for 120s", r)
+		}
+		if !strings.Contains(stderr.String(), `warning: default/payment: rule 1: principal "*"`) {
+			t.Errorf("stderr lacks the skipped rule:\n%s", stderr.String())
+		}
+	})
+
+	t.Run("spire", func(t *testing.T) {
+		entries := writeFile(t, "entries.json", `{"entries": [
+  {"spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/order"}},
+  {"spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/payment"}}
+]}`)
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"policy", "import", "spire", "-target", payment, "-scopes", "payments:read,payments:charge", entries}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+		l, err := policy.LoadFile(writeFile(t, "policy.yaml", stdout.String()))
+		if err != nil {
+			t.Fatalf("load generated policy: %v", err)
+		}
+		if ps := l.Policies(); len(ps) != 1 || ps[0].Name != "order-to-payment" || len(ps[0].AllowedScopes) != 2 {
+			t.Errorf("policies = %+v, want order-to-payment with two scopes", ps)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			args []string
+			in   string
+			want int
+		}{
+			{args: []string{"policy", "import", "spire", "-scopes", "read", "entries.json"}, want: 2},
+			{args: []string{"policy", "import", "spire", "-target", payment, "entries.json"}, want: 2},
+			{args: []string{"policy", "import", "istio", "-max-ttl", "0"}, want: 2},
+			{args: []string{"policy", "import", "spire", "-target", payment, "-scopes", "read", "missing.json"}, want: 1},
+			{args: []string{"policy", "import", "istio"}, in: "kind: AuthorizationPolicy\nspec:\n  action: DENY\n", want: 1},
+		}
+		for _, tc := range tests {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, strings.NewReader(tc.in), &stdout, &stderr); code != tc.want {
+				t.Errorf("run(%q) = %d, want %d", tc.args, code, tc.want)
+			}
+		}
+	})
+}
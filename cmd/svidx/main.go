@@ -0,0 +1,61 @@
+// Command svidx is the operator CLI for svid-exchange. It works offline on
+// policy files, audit logs, and certificates and never contacts a running
+// server.
+//
+// Usage:
+//
+//	svidx policy diff [-conflicts mode] [-audit-log file] [-since duration] old.yaml new.yaml
+//	svidx policy import istio [-trust-domain td] [-sa-label label] [-default-scope scope] [-max-ttl seconds] [file]
+//	svidx policy import spire -target id... -scopes list [-max-ttl seconds] entries.json
+//	svidx dev ca [-out dir] [-trust-domain td] [-server id] [-ttl duration] [-scopes list] [-max-ttl seconds] id...
+//
+// policy diff reports the grants a policy change adds, removes, or changes,
+// as subject → target and allowed scopes. With -audit-log it also replays the
+// token.exchange events of a JSON audit log against both files and lists the
+// requests whose outcome would change.
+//
+// policy import writes a policy file generated from Istio
+// AuthorizationPolicies (read from standard input without a file) or SPIRE
+// registration entries to standard output, and lists what it could not
+// convert on standard error.
+//
+// dev ca sets up a throwaway trust domain for local experiments. It creates
+// a CA, issues X.509-SVIDs for the server and each named workload (SPIFFE
+// IDs, or paths within the trust domain) into subdirectories of -out, and
+// writes a policy file letting every workload exchange for every other. The
+// server reads its SVID from the "server" subdirectory via SPIFFE_SVID_DIR.
+//
+// Exits 0 on success, 1 on any error, and 2 on a usage error.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: svidx policy diff [-conflicts mode] [-audit-log file] [-since duration] old.yaml new.yaml
+       svidx policy import istio [-trust-domain td] [-sa-label label] [-default-scope scope] [-max-ttl seconds] [file]
+       svidx policy import spire -target id... -scopes list [-max-ttl seconds] entries.json
+       svidx dev ca [-out dir] [-trust-domain td] [-server id] [-ttl duration] [-scopes list] [-max-ttl seconds] id...
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch {
+	case len(args) >= 2 && args[0] == "policy" && args[1] == "diff":
+		return policyDiff(args[2:], stdin, stdout, stderr)
+	case len(args) >= 3 && args[0] == "policy" && args[1] == "import" && args[2] == "istio":
+		return importIstio(args[3:], stdin, stdout, stderr)
+	case len(args) >= 3 && args[0] == "policy" && args[1] == "import" && args[2] == "spire":
+		return importSPIRE(args[3:], stdout, stderr)
+	case len(args) >= 2 && args[0] == "dev" && args[1] == "ca":
+		return devCA(args[2:], stdout, stderr)
+	}
+	fmt.Fprint(stderr, usage)
+	return 2
+}
@@ -3,13 +3,20 @@
 //
 // Usage:
 //
-//	svid-exchange-validate [policy-file]
+//	svid-exchange-validate [-conflicts mode] [policy-file]
 //
 // If no argument is given the POLICY_FILE env var is used, falling back to
 // config/policy.example.yaml.
+//
+// Conflicting rules — two policies that can match the same subject and
+// target with different grants — are always listed. -conflicts takes the
+// server's policy_conflicts values: with "error" any conflict fails
+// validation, and the merge modes fail on conflicts they cannot merge.
+// The default, "warn", reports conflicts without failing.
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -17,9 +24,27 @@ import (
 )
 
 func main() {
-	path := policyPath()
+	conflicts := flag.String("conflicts", string(policy.ConflictWarn), "conflict handling: warn, error, merge-union, or merge-intersection")
+	flag.Parse()
+
+	mode, err := policy.ParseConflictMode(*conflicts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -conflicts: %v\n", err)
+		os.Exit(2)
+	}
+	path := policyPath(flag.Args())
 
-	if _, err := policy.LoadFile(path); err != nil {
+	// Load with ConflictWarn first so every conflict is listed, not just the
+	// first one the requested mode rejects.
+	l, err := policy.LoadFileWithConflictMode(path, policy.ConflictWarn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid policy %q: %v\n", path, err)
+		os.Exit(1)
+	}
+	for _, c := range l.Conflicts() {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+	}
+	if _, err := policy.NewLoaderWithConflictMode(l.Policies(), mode); err != nil {
 		fmt.Fprintf(os.Stderr, "invalid policy %q: %v\n", path, err)
 		os.Exit(1)
 	}
@@ -27,9 +52,9 @@ func main() {
 	fmt.Printf("policy %q is valid\n", path)
 }
 
-func policyPath() string {
-	if len(os.Args) > 1 {
-		return os.Args[1]
+func policyPath(args []string) string {
+	if len(args) > 0 {
+		return args[0]
 	}
 	if p := os.Getenv("POLICY_FILE"); p != "" {
 		return p
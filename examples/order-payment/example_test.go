@@ -0,0 +1,40 @@
+//go:build example
+
+// Package example_test runs the order-payment example end to end in Docker
+// Compose, building every image from the working tree. Run it with:
+//
+//	go test -v -tags example -timeout 300s ./examples/order-payment/
+package example_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestOrderPayment brings the example up and passes when order exits 0:
+// svid-exchange accepted order's SVID, minted a payments:charge token for
+// payment, and payment accepted the token and rejected a call without one.
+func TestOrderPayment(t *testing.T) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker not found")
+	}
+	// Working directory for go test is the package directory.
+	compose := func(args ...string) *exec.Cmd {
+		cmd := exec.Command("docker", append([]string{"compose", "-f", "docker-compose.yml"}, args...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd
+	}
+
+	// Removing the volumes discards the throwaway CA.
+	t.Cleanup(func() {
+		if err := compose("down", "-v").Run(); err != nil {
+			t.Logf("cleanup: %v", err)
+		}
+	})
+
+	if err := compose("up", "--build", "--abort-on-container-exit", "--exit-code-from", "order").Run(); err != nil {
+		t.Fatalf("order-payment example failed: %v", err)
+	}
+}
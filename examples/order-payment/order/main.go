@@ -0,0 +1,137 @@
+// Binary order is the client of the order-payment example. It authenticates
+// to svid-exchange with its X.509-SVID, exchanges it for a token scoped to
+// the payment service, and calls POST /charge with it. It also checks that
+// payment turns away a call without a token. It exits 0 when both calls
+// behave as expected.
+//
+// Configuration comes from the environment:
+//
+//	SVID_DIR       directory holding tls.crt, tls.key, and ca.crt, as written
+//	               by svidx dev ca (default /certs/order)
+//	EXCHANGE_ADDR  svid-exchange gRPC address (default svid-exchange:8080)
+//	EXCHANGE_ID    svid-exchange's SPIFFE ID (default spiffe://example.local/svid-exchange)
+//	PAYMENT_URL    payment's charge endpoint (default http://payment:8090/charge)
+//	PAYMENT_ID     payment's SPIFFE ID (default spiffe://example.local/payment)
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/ngaddam369/svid-exchange/pkg/client"
+)
+
+func main() {
+	svidDir := envOr("SVID_DIR", "/certs/order")
+	exchangeAddr := envOr("EXCHANGE_ADDR", "svid-exchange:8080")
+	exchangeID := envOr("EXCHANGE_ID", "spiffe://example.local/svid-exchange")
+	paymentURL := envOr("PAYMENT_URL", "http://payment:8090/charge")
+	paymentID := envOr("PAYMENT_ID", "spiffe://example.local/payment")
+
+	// ── Identity: the SVID svidx dev ca issued to order ─────────────────────
+	svid, err := x509svid.Load(filepath.Join(svidDir, "tls.crt"), filepath.Join(svidDir, "tls.key"))
+	if err != nil {
+		fatalf("load SVID: %v", err)
+	}
+	bundle, err := x509bundle.Load(svid.ID.TrustDomain(), filepath.Join(svidDir, "ca.crt"))
+	if err != nil {
+		fatalf("load trust bundle: %v", err)
+	}
+	serverID, err := spiffeid.FromString(exchangeID)
+	if err != nil {
+		fatalf("parse EXCHANGE_ID: %v", err)
+	}
+	fmt.Printf("order is %s\n", svid.ID)
+
+	// ── mTLS to svid-exchange, accepting only its SPIFFE ID ─────────────────
+	tlsCfg := tlsconfig.MTLSClientConfig(svid, bundle, tlsconfig.AuthorizeID(serverID))
+	tlsCfg.MinVersion = tls.VersionTLS13
+	conn, err := grpc.NewClient(exchangeAddr, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	if err != nil {
+		fatalf("dial svid-exchange: %v", err)
+	}
+	defer conn.Close()
+
+	// The client caches the token and refreshes it before expiry; the
+	// transport attaches it to every request as a Bearer token.
+	exc := client.NewFromConn(conn, client.Options{
+		TargetService: paymentID,
+		Scopes:        []string{"payments:charge"},
+		TTLSeconds:    60,
+	})
+	defer exc.Close()
+	withToken := &http.Client{Transport: client.NewHTTPTransport(exc, nil), Timeout: 10 * time.Second}
+	withoutToken := &http.Client{Timeout: 10 * time.Second}
+
+	// svid-exchange and payment may still be starting; retry for a while.
+	var body string
+	deadline := time.Now().Add(time.Minute)
+	for {
+		body, err = charge(withToken, paymentURL, http.StatusOK)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		fmt.Printf("waiting: %v\n", err)
+		time.Sleep(2 * time.Second)
+	}
+	if err != nil {
+		fatalf("charge with token: %v", err)
+	}
+	fmt.Printf("payment answered: %s\n", strings.TrimSpace(body))
+
+	if _, err := charge(withoutToken, paymentURL, http.StatusUnauthorized); err != nil {
+		fatalf("charge without token: %v", err)
+	}
+	fmt.Println("payment rejected a call without a token")
+	fmt.Println("order-payment example OK")
+}
+
+// charge POSTs to url with c and returns the response body, or an error if
+// the status is not want.
+func charge(c *http.Client, url string, want int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != want {
+		return "", fmt.Errorf("status %d, want %d: %s", resp.StatusCode, want, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+func envOr(key, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "order: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,84 @@
+// Binary payment is the resource server of the order-payment example. It
+// serves POST /charge to callers presenting a token svid-exchange minted for
+// it with the payments:charge scope, verified against the server's JWKS.
+//
+// Configuration comes from the environment:
+//
+//	JWKS_URL  svid-exchange JWKS endpoint (default http://svid-exchange:8081/jwks)
+//	AUDIENCE  this service's SPIFFE ID (default spiffe://example.local/payment)
+//	ADDR      listen address (default :8090)
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/pkg/client"
+)
+
+// chargeScope is the scope POST /charge requires.
+const chargeScope = "payments:charge"
+
+func main() {
+	jwksURL := envOr("JWKS_URL", "http://svid-exchange:8081/jwks")
+	audience := envOr("AUDIENCE", "spiffe://example.local/payment")
+	addr := envOr("ADDR", ":8090")
+
+	// svid-exchange may still be starting; wait for its keys.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var v *client.Verifier
+	for {
+		var err error
+		if v, err = client.NewVerifier(ctx, jwksURL); err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			fatalf("fetch JWKS from %s: %v", jwksURL, err)
+		case <-time.After(time.Second):
+		}
+	}
+	// Pick up signing key rotations.
+	v.StartAutoRefresh(context.Background(), time.Minute)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("POST /charge", client.NewMiddleware(v, audience, http.HandlerFunc(charge)))
+
+	fmt.Printf("payment listening on %s\n", addr)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if err := srv.ListenAndServe(); err != nil {
+		fatalf("listen: %v", err)
+	}
+}
+
+// charge runs behind client.NewMiddleware, so the token is already verified
+// for this audience; it checks the scope and names the caller.
+func charge(w http.ResponseWriter, r *http.Request) {
+	claims, _ := client.ClaimsFromContext(r.Context())
+	if !client.HasScope(claims, chargeScope) {
+		http.Error(w, "missing scope "+chargeScope, http.StatusForbidden)
+		return
+	}
+	sub, _ := claims.GetSubject()
+	fmt.Printf("charged on behalf of %s\n", sub)
+	fmt.Fprintf(w, "charged for %s\n", sub)
+}
+
+func envOr(key, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "payment: "+format+"\n", args...)
+	os.Exit(1)
+}
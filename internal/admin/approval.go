@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// Approvals holds the exchanges parked by requires_approval policies.
+// Pending lists them, oldest first. Decide grants or denies one, recording
+// the admin caller identified from ctx as the approver, and returns
+// server.ErrApprovalNotFound when id is not pending.
+type Approvals interface {
+	Pending() []server.PendingApproval
+	Decide(ctx context.Context, id string, approve bool, reason string) error
+}
+
+// SetApprovals enables the ListPendingApprovals and DecideApproval RPCs,
+// served by a. Without it they fail with FAILED_PRECONDITION. It must be
+// called before the server starts handling requests.
+func (s *Server) SetApprovals(a Approvals) {
+	s.approvals = a
+}
+
+// ListPendingApprovals lists the parked exchanges awaiting a decision.
+func (s *Server) ListPendingApprovals(_ context.Context, _ *adminv1.ListPendingApprovalsRequest) (*adminv1.ListPendingApprovalsResponse, error) {
+	if s.approvals == nil {
+		return nil, status.Error(codes.FailedPrecondition, "approvals are not enabled on this server")
+	}
+	resp := &adminv1.ListPendingApprovalsResponse{}
+	for _, p := range s.approvals.Pending() {
+		resp.Approvals = append(resp.Approvals, &adminv1.PendingApproval{
+			Id:          p.ID,
+			RequestId:   p.RequestID,
+			Subject:     p.Subject,
+			Target:      p.Target,
+			Scopes:      p.Scopes,
+			TtlSeconds:  p.TTL,
+			PolicyRules: p.PolicyRules,
+			RequestedAt: p.Requested.Unix(),
+			ExpiresAt:   p.Expires.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// DecideApproval grants or denies a parked exchange.
+func (s *Server) DecideApproval(ctx context.Context, req *adminv1.DecideApprovalRequest) (*adminv1.DecideApprovalResponse, error) {
+	if s.approvals == nil {
+		return nil, status.Error(codes.FailedPrecondition, "approvals are not enabled on this server")
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	err := s.approvals.Decide(ctx, req.Id, req.Approve, req.Reason)
+	if errors.Is(err, server.ErrApprovalNotFound) {
+		return nil, status.Errorf(codes.NotFound, "no exchange %q is pending approval", req.Id)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &adminv1.DecideApprovalResponse{}, nil
+}
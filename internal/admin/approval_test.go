@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeApprovals holds fixed pending exchanges and records decisions.
+type fakeApprovals struct {
+	pending []server.PendingApproval
+	decided map[string]bool
+}
+
+func (f *fakeApprovals) Pending() []server.PendingApproval { return f.pending }
+
+func (f *fakeApprovals) Decide(_ context.Context, id string, approve bool, _ string) error {
+	for i, p := range f.pending {
+		if p.ID == id {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			f.decided[id] = approve
+			return nil
+		}
+	}
+	return server.ErrApprovalNotFound
+}
+
+func TestApprovals(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled without approvals", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.ListPendingApprovals(ctx, &adminv1.ListPendingApprovalsRequest{})
+		assertCode(t, err, codes.FailedPrecondition)
+		_, err = svc.DecideApproval(ctx, &adminv1.DecideApprovalRequest{Id: "a1", Approve: true})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	t.Run("list then decide", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		requested := time.Unix(1700000000, 0)
+		f := &fakeApprovals{
+			pending: []server.PendingApproval{{
+				ID:        "a1",
+				RequestID: "req-1",
+				Subject:   "spiffe://example.org/order",
+				Target:    "spiffe://example.org/payment",
+				Scopes:    []string{"payments:refund"},
+				TTL:       60,
+				Requested: requested,
+				Expires:   requested.Add(5 * time.Minute),
+			}},
+			decided: map[string]bool{},
+		}
+		svc.SetApprovals(f)
+
+		resp, err := svc.ListPendingApprovals(ctx, &adminv1.ListPendingApprovalsRequest{})
+		if err != nil {
+			t.Fatalf("ListPendingApprovals: %v", err)
+		}
+		if len(resp.Approvals) != 1 {
+			t.Fatalf("approvals = %+v, want one", resp.Approvals)
+		}
+		if a := resp.Approvals[0]; a.Id != "a1" || a.RequestId != "req-1" || a.TtlSeconds != 60 || a.RequestedAt != 1700000000 || a.ExpiresAt != 1700000300 {
+			t.Errorf("approval = %+v", a)
+		}
+
+		_, err = svc.DecideApproval(ctx, &adminv1.DecideApprovalRequest{Id: "a1", Approve: true})
+		if err != nil {
+			t.Fatalf("DecideApproval: %v", err)
+		}
+		if approved, ok := f.decided["a1"]; !ok || !approved {
+			t.Errorf("decisions = %v, want a1 approved", f.decided)
+		}
+		_, err = svc.DecideApproval(ctx, &adminv1.DecideApprovalRequest{Id: "a1", Approve: true})
+		assertCode(t, err, codes.NotFound)
+		_, err = svc.DecideApproval(ctx, &adminv1.DecideApprovalRequest{})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// Audit failure modes accepted by SetAuditFailureMode.
+const (
+	AuditFailOpen   = "open"
+	AuditFailClosed = "closed"
+)
+
+// AuditModeSwitcher switches how the server treats grants whose audit event
+// cannot be recorded.
+type AuditModeSwitcher interface {
+	// SetAuditFailureMode sets the mode to AuditFailOpen or
+	// AuditFailClosed, or to the configured mode when mode is empty. It
+	// returns the mode in effect before the call.
+	SetAuditFailureMode(mode string) (previous string)
+}
+
+// SetAuditModeSwitcher enables the SetAuditFailureMode RPC, served by
+// m. Without it SetAuditFailureMode fails with FAILED_PRECONDITION. It must
+// be called before the server starts handling requests.
+func (s *Server) SetAuditModeSwitcher(m AuditModeSwitcher) {
+	s.auditMode = m
+}
+
+// SetAuditFailureMode switches between failing closed and failing open on
+// audit failures.
+func (s *Server) SetAuditFailureMode(_ context.Context, req *adminv1.SetAuditFailureModeRequest) (*adminv1.SetAuditFailureModeResponse, error) {
+	if s.auditMode == nil {
+		return nil, status.Error(codes.FailedPrecondition, "audit failure mode changes need audit_spool_dir")
+	}
+	switch req.Mode {
+	case "", AuditFailOpen, AuditFailClosed:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid mode %q: must be open or closed", req.Mode)
+	}
+	return &adminv1.SetAuditFailureModeResponse{Previous: s.auditMode.SetAuditFailureMode(req.Mode)}, nil
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeAuditMode records the last mode set and reports closed as the
+// previous mode.
+type fakeAuditMode struct{ mode *string }
+
+func (f fakeAuditMode) SetAuditFailureMode(mode string) string {
+	*f.mode = mode
+	return AuditFailClosed
+}
+
+func TestSetAuditFailureMode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unavailable without a switcher", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.SetAuditFailureMode(ctx, &adminv1.SetAuditFailureModeRequest{Mode: AuditFailOpen})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	tests := []struct {
+		name     string
+		mode     string
+		wantCode codes.Code
+	}{
+		{name: "fail open", mode: AuditFailOpen},
+		{name: "fail closed", mode: AuditFailClosed},
+		{name: "empty restores the configured mode", mode: ""},
+		{name: "unknown mode", mode: "sometimes", wantCode: codes.InvalidArgument},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, _ := newTestServer(t)
+			got := "unset"
+			svc.SetAuditModeSwitcher(fakeAuditMode{mode: &got})
+			resp, err := svc.SetAuditFailureMode(ctx, &adminv1.SetAuditFailureModeRequest{Mode: tc.mode})
+			if tc.wantCode != codes.OK {
+				assertCode(t, err, tc.wantCode)
+				if got != "unset" {
+					t.Errorf("mode set to %q on a rejected request", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetAuditFailureMode: %v", err)
+			}
+			if got != tc.mode || resp.Previous != AuditFailClosed {
+				t.Errorf("mode = %q, previous = %q; want %q, %q", got, resp.Previous, tc.mode, AuditFailClosed)
+			}
+		})
+	}
+}
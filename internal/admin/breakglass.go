@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// BreakGlass controls the emergency override policy. Activate verifies a
+// signed override document and puts it in force, returning its id and
+// expiry; any error means the document was rejected. Deactivate ends the
+// active override and returns its id, or "" when none is active. Both are
+// audited by the implementation, which identifies the admin caller from ctx.
+type BreakGlass interface {
+	Activate(ctx context.Context, document, signature []byte) (id string, expiresAt time.Time, err error)
+	Deactivate(ctx context.Context) (id string, err error)
+}
+
+// SetBreakGlass enables the ActivateBreakGlass and DeactivateBreakGlass RPCs,
+// served by b. Without it they fail with FAILED_PRECONDITION. It must be
+// called before the server starts handling requests.
+func (s *Server) SetBreakGlass(b BreakGlass) {
+	s.breakGlass = b
+}
+
+// ActivateBreakGlass puts a signed emergency override in force.
+func (s *Server) ActivateBreakGlass(ctx context.Context, req *adminv1.ActivateBreakGlassRequest) (*adminv1.ActivateBreakGlassResponse, error) {
+	if s.breakGlass == nil {
+		return nil, status.Error(codes.FailedPrecondition, "break-glass is not enabled on this server; set break_glass_public_key")
+	}
+	if len(req.Document) == 0 || len(req.Signature) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "document and signature are required")
+	}
+	id, expiresAt, err := s.breakGlass.Activate(ctx, req.Document, req.Signature)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &adminv1.ActivateBreakGlassResponse{Id: id, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+// DeactivateBreakGlass ends the active emergency override before its expiry.
+func (s *Server) DeactivateBreakGlass(ctx context.Context, _ *adminv1.DeactivateBreakGlassRequest) (*adminv1.DeactivateBreakGlassResponse, error) {
+	if s.breakGlass == nil {
+		return nil, status.Error(codes.FailedPrecondition, "break-glass is not enabled on this server; set break_glass_public_key")
+	}
+	id, err := s.breakGlass.Deactivate(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if id == "" {
+		return nil, status.Error(codes.NotFound, "no break-glass override is active")
+	}
+	return &adminv1.DeactivateBreakGlassResponse{Id: id}, nil
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeBreakGlass records activations and reports a fixed outcome.
+type fakeBreakGlass struct {
+	active string
+	err    error
+}
+
+func (f *fakeBreakGlass) Activate(_ context.Context, document, _ []byte) (string, time.Time, error) {
+	if f.err != nil {
+		return "", time.Time{}, f.err
+	}
+	f.active = string(document)
+	return f.active, time.Unix(1700000000, 0), nil
+}
+
+func (f *fakeBreakGlass) Deactivate(context.Context) (string, error) {
+	id := f.active
+	f.active = ""
+	return id, nil
+}
+
+func TestBreakGlass(t *testing.T) {
+	ctx := context.Background()
+	activate := &adminv1.ActivateBreakGlassRequest{Document: []byte("inc-4711"), Signature: []byte("sig")}
+
+	t.Run("disabled without a controller", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.ActivateBreakGlass(ctx, activate)
+		assertCode(t, err, codes.FailedPrecondition)
+		_, err = svc.DeactivateBreakGlass(ctx, &adminv1.DeactivateBreakGlassRequest{})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	t.Run("activate then deactivate", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		svc.SetBreakGlass(&fakeBreakGlass{})
+		resp, err := svc.ActivateBreakGlass(ctx, activate)
+		if err != nil {
+			t.Fatalf("ActivateBreakGlass: %v", err)
+		}
+		if resp.Id != "inc-4711" || resp.ExpiresAt != 1700000000 {
+			t.Errorf("response = %+v", resp)
+		}
+		deact, err := svc.DeactivateBreakGlass(ctx, &adminv1.DeactivateBreakGlassRequest{})
+		if err != nil || deact.Id != "inc-4711" {
+			t.Fatalf("DeactivateBreakGlass = %+v, %v", deact, err)
+		}
+		_, err = svc.DeactivateBreakGlass(ctx, &adminv1.DeactivateBreakGlassRequest{})
+		assertCode(t, err, codes.NotFound)
+	})
+
+	t.Run("missing signature returns InvalidArgument", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		svc.SetBreakGlass(&fakeBreakGlass{})
+		_, err := svc.ActivateBreakGlass(ctx, &adminv1.ActivateBreakGlassRequest{Document: []byte("inc-4711")})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+
+	t.Run("rejected document returns InvalidArgument", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		svc.SetBreakGlass(&fakeBreakGlass{err: errors.New("override signature is invalid")})
+		_, err := svc.ActivateBreakGlass(ctx, activate)
+		assertCode(t, err, codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// Drainer takes the server out of service. Drain starts draining, if it has
+// not already started, and returns the time the process will exit.
+type Drainer interface {
+	Drain(ctx context.Context) (exitAt time.Time, err error)
+}
+
+// SetDrainer enables the Drain RPC, served by d. Without it Drain fails with
+// FAILED_PRECONDITION. It must be called before the server starts handling
+// requests.
+func (s *Server) SetDrainer(d Drainer) {
+	s.drainer = d
+}
+
+// Drain takes the server out of service and reports when it will exit.
+func (s *Server) Drain(ctx context.Context, _ *adminv1.DrainRequest) (*adminv1.DrainResponse, error) {
+	if s.drainer == nil {
+		return nil, status.Error(codes.FailedPrecondition, "drain is not available on this server")
+	}
+	exitAt, err := s.drainer.Drain(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &adminv1.DrainResponse{ExitAt: exitAt.Unix()}, nil
+}
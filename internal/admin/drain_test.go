@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeDrainer counts Drain calls and reports a fixed exit time.
+type fakeDrainer struct {
+	calls int
+}
+
+func (f *fakeDrainer) Drain(context.Context) (time.Time, error) {
+	f.calls++
+	return time.Unix(1700000030, 0), nil
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unavailable without a drainer", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.Drain(ctx, &adminv1.DrainRequest{})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	t.Run("reports the exit time", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		d := &fakeDrainer{}
+		svc.SetDrainer(d)
+		resp, err := svc.Drain(ctx, &adminv1.DrainRequest{})
+		if err != nil {
+			t.Fatalf("Drain: %v", err)
+		}
+		if resp.ExitAt != 1700000030 || d.calls != 1 {
+			t.Errorf("ExitAt = %d after %d calls, want 1700000030 after 1", resp.ExitAt, d.calls)
+		}
+	})
+}
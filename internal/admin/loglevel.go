@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// LogLeveler changes the server log level at runtime.
+type LogLeveler interface {
+	// SetLogLevel sets the log level to level, or to the configured level
+	// when level is zerolog.NoLevel. When d is positive the configured
+	// level is restored after d, at revertAt. It returns the level in
+	// effect before the call.
+	SetLogLevel(level zerolog.Level, d time.Duration) (previous zerolog.Level, revertAt time.Time)
+}
+
+// SetLogLeveler enables the SetLogLevel RPC, served by l. Without it
+// SetLogLevel fails with FAILED_PRECONDITION. It must be called before the
+// server starts handling requests.
+func (s *Server) SetLogLeveler(l LogLeveler) {
+	s.logLeveler = l
+}
+
+// SetLogLevel changes the server log level, optionally for a limited time.
+func (s *Server) SetLogLevel(_ context.Context, req *adminv1.SetLogLevelRequest) (*adminv1.SetLogLevelResponse, error) {
+	if s.logLeveler == nil {
+		return nil, status.Error(codes.FailedPrecondition, "log level changes are not available on this server")
+	}
+	level := zerolog.NoLevel
+	if req.Level != "" {
+		var err error
+		level, err = zerolog.ParseLevel(req.Level)
+		if err != nil || level < zerolog.TraceLevel || level > zerolog.ErrorLevel {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid level %q: must be trace, debug, info, warn, or error", req.Level)
+		}
+	}
+	if req.DurationSeconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "duration_seconds must not be negative, got %d", req.DurationSeconds)
+	}
+	prev, revertAt := s.logLeveler.SetLogLevel(level, time.Duration(req.DurationSeconds)*time.Second)
+	resp := &adminv1.SetLogLevelResponse{Previous: prev.String()}
+	if !revertAt.IsZero() {
+		resp.RevertAt = revertAt.Unix()
+	}
+	return resp, nil
+}
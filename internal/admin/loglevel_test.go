@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeLogLeveler records the last level set and reports info as the
+// previous level.
+type fakeLogLeveler struct {
+	level zerolog.Level
+	d     time.Duration
+}
+
+func (f *fakeLogLeveler) SetLogLevel(level zerolog.Level, d time.Duration) (zerolog.Level, time.Time) {
+	f.level, f.d = level, d
+	if d > 0 {
+		return zerolog.InfoLevel, time.Unix(1700000000, 0).Add(d)
+	}
+	return zerolog.InfoLevel, time.Time{}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unavailable without a log leveler", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.SetLogLevel(ctx, &adminv1.SetLogLevelRequest{Level: "debug"})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	tests := []struct {
+		name         string
+		req          *adminv1.SetLogLevelRequest
+		wantCode     codes.Code
+		wantLevel    zerolog.Level
+		wantDuration time.Duration
+		wantRevertAt int64
+	}{
+		{name: "until changed", req: &adminv1.SetLogLevelRequest{Level: "debug"}, wantLevel: zerolog.DebugLevel},
+		{name: "for a limited time", req: &adminv1.SetLogLevelRequest{Level: "trace", DurationSeconds: 600}, wantLevel: zerolog.TraceLevel, wantDuration: 10 * time.Minute, wantRevertAt: 1700000600},
+		{name: "empty level restores the configured one", req: &adminv1.SetLogLevelRequest{}, wantLevel: zerolog.NoLevel},
+		{name: "unknown level", req: &adminv1.SetLogLevelRequest{Level: "verbose"}, wantCode: codes.InvalidArgument},
+		{name: "level that silences the log", req: &adminv1.SetLogLevelRequest{Level: "disabled"}, wantCode: codes.InvalidArgument},
+		{name: "negative duration", req: &adminv1.SetLogLevelRequest{Level: "debug", DurationSeconds: -1}, wantCode: codes.InvalidArgument},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, _ := newTestServer(t)
+			l := &fakeLogLeveler{level: -2}
+			svc.SetLogLeveler(l)
+			resp, err := svc.SetLogLevel(ctx, tc.req)
+			if tc.wantCode != codes.OK {
+				assertCode(t, err, tc.wantCode)
+				if l.level != -2 {
+					t.Errorf("level changed to %v on a rejected request", l.level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetLogLevel: %v", err)
+			}
+			if l.level != tc.wantLevel || l.d != tc.wantDuration {
+				t.Errorf("set %v for %v, want %v for %v", l.level, l.d, tc.wantLevel, tc.wantDuration)
+			}
+			if resp.Previous != "info" || resp.RevertAt != tc.wantRevertAt {
+				t.Errorf("response = %+v, want previous info, revert_at %d", resp, tc.wantRevertAt)
+			}
+		})
+	}
+}
@@ -19,24 +19,35 @@ type Server struct {
 	adminv1.UnimplementedPolicyAdminServer
 	store        *policy.Store
 	yamlPolicies func() []policy.Policy
+	build        func([]policy.Policy) (*policy.Loader, error)
 	swap         func(*policy.Loader)
 	reload       func() error
 	revoke       func(jti string, expiresAt time.Time) bool
+	breakGlass   BreakGlass
+	drainer      Drainer
+	logLeveler   LogLeveler
+	stats        StatsReporter
+	ruleUsage    RuleUsageSource
+	approvals    Approvals
+	auditMode    AuditModeSwitcher
 }
 
 // New returns a Server. yamlPolicies must return the current YAML-sourced
-// policies (used for conflict detection). swap is called with the rebuilt
-// Loader after every store mutation. reload is called by ReloadPolicy to
+// policies (used for conflict detection). build validates a merged policy
+// set and returns its Loader, normally policy.NewLoader or a variant with the
+// server's conflict mode; swap is called with the rebuilt Loader after every
+// store mutation. reload is called by ReloadPolicy to
 // re-read the YAML file and merge it with dynamic policies. revoke is called
 // to add a JTI to the in-memory revocation list on the exchange server.
 func New(
 	store *policy.Store,
 	yamlPolicies func() []policy.Policy,
+	build func([]policy.Policy) (*policy.Loader, error),
 	swap func(*policy.Loader),
 	reload func() error,
 	revoke func(jti string, expiresAt time.Time) bool,
 ) *Server {
-	return &Server{store: store, yamlPolicies: yamlPolicies, swap: swap, reload: reload, revoke: revoke}
+	return &Server{store: store, yamlPolicies: yamlPolicies, build: build, swap: swap, reload: reload, revoke: revoke}
 }
 
 // CreatePolicy adds a new dynamic policy. It fails with ALREADY_EXISTS if the
@@ -80,7 +91,7 @@ func (s *Server) CreatePolicy(_ context.Context, req *adminv1.CreatePolicyReques
 	merged = append(merged, yaml...)
 	merged = append(merged, dynamic...)
 	merged = append(merged, p)
-	loader, err := policy.NewLoader(merged)
+	loader, err := s.build(merged)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
@@ -128,7 +139,7 @@ func (s *Server) DeletePolicy(_ context.Context, req *adminv1.DeletePolicyReques
 
 	// Build merged loader before deleting (validates the resulting set).
 	merged := append(yaml, remaining...)
-	loader, err := policy.NewLoader(merged)
+	loader, err := s.build(merged)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -212,20 +223,56 @@ func (s *Server) ListRevokedTokens(_ context.Context, _ *adminv1.ListRevokedToke
 
 func protoToPolicy(r *adminv1.PolicyRule) policy.Policy {
 	return policy.Policy{
-		Name:          r.Name,
-		Subject:       r.Subject,
-		Target:        r.Target,
-		AllowedScopes: r.AllowedScopes,
-		MaxTTL:        r.MaxTtl,
+		Name:             r.Name,
+		Subject:          r.Subject,
+		Target:           r.Target,
+		AllowedScopes:    r.AllowedScopes,
+		MaxTTL:           r.MaxTtl,
+		TokenFormat:      r.TokenFormat,
+		RequireNonce:     r.RequireNonce,
+		RequiresApproval: r.RequiresApproval,
+		SingleUse:        r.SingleUse,
+		Audiences:        protoToAudiences(r.Audiences),
+		Issuer:           r.Issuer,
+		Condition:        r.Condition,
 	}
 }
 
 func policyToProto(p policy.Policy) *adminv1.PolicyRule {
 	return &adminv1.PolicyRule{
-		Name:          p.Name,
-		Subject:       p.Subject,
-		Target:        p.Target,
-		AllowedScopes: p.AllowedScopes,
-		MaxTtl:        p.MaxTTL,
+		Name:             p.Name,
+		Subject:          p.Subject,
+		Target:           p.Target,
+		AllowedScopes:    p.AllowedScopes,
+		MaxTtl:           p.MaxTTL,
+		TokenFormat:      p.TokenFormat,
+		RequireNonce:     p.RequireNonce,
+		RequiresApproval: p.RequiresApproval,
+		SingleUse:        p.SingleUse,
+		Audiences:        audiencesToProto(p.Audiences),
+		Issuer:           p.Issuer,
+		Condition:        p.Condition,
 	}
 }
+
+func protoToAudiences(m map[string]*adminv1.AudienceList) map[string][]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(m))
+	for id, l := range m {
+		out[id] = l.GetValues()
+	}
+	return out
+}
+
+func audiencesToProto(m map[string][]string) map[string]*adminv1.AudienceList {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]*adminv1.AudienceList, len(m))
+	for id, auds := range m {
+		out[id] = &adminv1.AudienceList{Values: auds}
+	}
+	return out
+}
@@ -48,6 +48,7 @@ func newTestServerWithRevoke(t *testing.T, revoke func(string, time.Time) bool)
 	svc := New(
 		store,
 		func() []policy.Policy { return yamlPolicies },
+		policy.NewLoader,
 		func(_ *policy.Loader) {},
 		func() error { return nil },
 		revoke,
@@ -83,6 +84,75 @@ func TestCreatePolicy(t *testing.T) {
 		}
 	})
 
+	t.Run("token_format is persisted", func(t *testing.T) {
+		svc, store := newTestServer(t)
+		rule := newRule("svid-policy", subB, tgt)
+		rule.TokenFormat = policy.FormatJWTSVID
+		if _, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := store.List()
+		if len(got) != 1 || got[0].TokenFormat != policy.FormatJWTSVID {
+			t.Errorf("expected stored token_format %q, got %+v", policy.FormatJWTSVID, got)
+		}
+	})
+
+	t.Run("require_nonce is persisted", func(t *testing.T) {
+		svc, store := newTestServer(t)
+		rule := newRule("nonce-policy", subB, tgt)
+		rule.RequireNonce = true
+		if _, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := store.List()
+		if len(got) != 1 || !got[0].RequireNonce {
+			t.Errorf("expected stored require_nonce, got %+v", got)
+		}
+	})
+
+	t.Run("audiences and issuer are persisted", func(t *testing.T) {
+		svc, store := newTestServer(t)
+		rule := newRule("audience-policy", subB, tgt)
+		rule.Audiences = map[string]*adminv1.AudienceList{tgt: {Values: []string{"payments.example.com"}}}
+		rule.Issuer = "https://issuer.example.com"
+		if _, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := store.List()
+		if len(got) != 1 || len(got[0].Audiences[tgt]) != 1 || got[0].Audiences[tgt][0] != "payments.example.com" || got[0].Issuer != rule.Issuer {
+			t.Errorf("expected stored audiences and issuer, got %+v", got)
+		}
+	})
+
+	t.Run("condition is persisted", func(t *testing.T) {
+		svc, store := newTestServer(t)
+		rule := newRule("conditional-policy", subB, tgt)
+		rule.Condition = "size(scopes) == 1"
+		if _, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := store.List()
+		if len(got) != 1 || got[0].Condition != rule.Condition {
+			t.Errorf("expected stored condition %q, got %+v", rule.Condition, got)
+		}
+	})
+
+	t.Run("invalid condition returns InvalidArgument", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		rule := newRule("bad-condition", subB, tgt)
+		rule.Condition = "ttl >"
+		_, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+
+	t.Run("unknown token_format returns InvalidArgument", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		rule := newRule("bad-format", subB, tgt)
+		rule.TokenFormat = "saml"
+		_, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{Rule: rule})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+
 	t.Run("nil rule returns InvalidArgument", func(t *testing.T) {
 		svc, _ := newTestServer(t)
 		_, err := svc.CreatePolicy(context.Background(), &adminv1.CreatePolicyRequest{})
@@ -204,6 +274,7 @@ func TestReloadPolicy(t *testing.T) {
 		svc := New(
 			store,
 			func() []policy.Policy { return nil },
+			policy.NewLoader,
 			func(_ *policy.Loader) {},
 			func() error { return nil },
 			func(_ string, _ time.Time) bool { return true },
@@ -223,6 +294,7 @@ func TestReloadPolicy(t *testing.T) {
 		svc := New(
 			store,
 			func() []policy.Policy { return nil },
+			policy.NewLoader,
 			func(_ *policy.Loader) {},
 			func() error { return errors.New("bad yaml") },
 			func(_ string, _ time.Time) bool { return true },
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// defaultTopScopes is the number of scopes GetStats lists when the request
+// does not say.
+const defaultTopScopes = 10
+
+// StatsReporter summarizes recent token issuance.
+type StatsReporter interface {
+	// Report summarizes the last window, listing at most topScopes scopes.
+	// A zero window reports everything retained.
+	Report(window time.Duration, topScopes int) audit.IssuanceReport
+}
+
+// SetStats enables the GetStats RPC, served by r. Without it GetStats fails
+// with FAILED_PRECONDITION. It must be called before the server starts
+// handling requests.
+func (s *Server) SetStats(r StatsReporter) {
+	s.stats = r
+}
+
+// GetStats summarizes token issuance over the requested window.
+func (s *Server) GetStats(_ context.Context, req *adminv1.GetStatsRequest) (*adminv1.GetStatsResponse, error) {
+	if s.stats == nil {
+		return nil, status.Error(codes.FailedPrecondition, "issuance statistics are not enabled on this server")
+	}
+	if req.WindowSeconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "window_seconds must not be negative, got %d", req.WindowSeconds)
+	}
+	if req.TopScopes < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "top_scopes must not be negative, got %d", req.TopScopes)
+	}
+	top := int(req.TopScopes)
+	if top == 0 {
+		top = defaultTopScopes
+	}
+	r := s.stats.Report(time.Duration(req.WindowSeconds)*time.Second, top)
+
+	resp := &adminv1.GetStatsResponse{Start: r.Start.Unix(), End: r.End.Unix(), Overflow: r.Overflow}
+	for _, p := range r.Pairs {
+		resp.Pairs = append(resp.Pairs, &adminv1.PairStats{Subject: p.Subject, Target: p.Target, Grants: p.Grants, Denials: p.Denials})
+	}
+	for _, sc := range r.TopScopes {
+		resp.TopScopes = append(resp.TopScopes, &adminv1.ScopeCount{Scope: sc.Scope, Grants: sc.Grants})
+	}
+	for _, t := range r.TTLs {
+		resp.TtlDistribution = append(resp.TtlDistribution, &adminv1.TTLCount{MaxSeconds: t.MaxSeconds, Grants: t.Grants})
+	}
+	return resp, nil
+}
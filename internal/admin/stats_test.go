@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeStats records the arguments of the last Report call and returns a
+// fixed report.
+type fakeStats struct {
+	window time.Duration
+	top    int
+}
+
+func (f *fakeStats) Report(window time.Duration, topScopes int) audit.IssuanceReport {
+	f.window, f.top = window, topScopes
+	return audit.IssuanceReport{
+		Start:     time.Unix(1700000000, 0),
+		End:       time.Unix(1700003600, 0),
+		Pairs:     []audit.PairStats{{Subject: "spiffe://td/order", Target: "spiffe://td/payment", Grants: 4, Denials: 1}},
+		TopScopes: []audit.ScopeCount{{Scope: "payments:charge", Grants: 4}},
+		TTLs:      []audit.TTLCount{{MaxSeconds: 300, Grants: 4}, {Grants: 0}},
+		Overflow:  2,
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unavailable without statistics", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.GetStats(ctx, &adminv1.GetStatsRequest{})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	tests := []struct {
+		name       string
+		req        *adminv1.GetStatsRequest
+		wantCode   codes.Code
+		wantWindow time.Duration
+		wantTop    int
+	}{
+		{name: "defaults", req: &adminv1.GetStatsRequest{}, wantTop: 10},
+		{name: "window and top scopes", req: &adminv1.GetStatsRequest{WindowSeconds: 86400, TopScopes: 3}, wantWindow: 24 * time.Hour, wantTop: 3},
+		{name: "negative window", req: &adminv1.GetStatsRequest{WindowSeconds: -1}, wantCode: codes.InvalidArgument},
+		{name: "negative top scopes", req: &adminv1.GetStatsRequest{TopScopes: -1}, wantCode: codes.InvalidArgument},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, _ := newTestServer(t)
+			f := &fakeStats{}
+			svc.SetStats(f)
+			resp, err := svc.GetStats(ctx, tc.req)
+			if tc.wantCode != codes.OK {
+				assertCode(t, err, tc.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+			if f.window != tc.wantWindow || f.top != tc.wantTop {
+				t.Errorf("Report(%v, %d), want Report(%v, %d)", f.window, f.top, tc.wantWindow, tc.wantTop)
+			}
+			if resp.Start != 1700000000 || resp.End != 1700003600 || resp.Overflow != 2 {
+				t.Errorf("start, end, overflow = %d, %d, %d", resp.Start, resp.End, resp.Overflow)
+			}
+			if len(resp.Pairs) != 1 || resp.Pairs[0].Grants != 4 || resp.Pairs[0].Denials != 1 || resp.Pairs[0].Target != "spiffe://td/payment" {
+				t.Errorf("pairs = %v", resp.Pairs)
+			}
+			if len(resp.TopScopes) != 1 || resp.TopScopes[0].Scope != "payments:charge" {
+				t.Errorf("top scopes = %v", resp.TopScopes)
+			}
+			if len(resp.TtlDistribution) != 2 || resp.TtlDistribution[0].MaxSeconds != 300 || resp.TtlDistribution[0].Grants != 4 {
+				t.Errorf("TTL distribution = %v", resp.TtlDistribution)
+			}
+		})
+	}
+}
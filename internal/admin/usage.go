@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// defaultUnusedDays is the period ListUnusedPolicies uses when the request
+// does not say.
+const defaultUnusedDays = 30
+
+// RuleUsageSource reports how often policy rules have matched.
+type RuleUsageSource interface {
+	// Usage returns the counters of every tracked rule, keyed by rule name.
+	Usage() (map[string]policy.RuleUsage, error)
+}
+
+// SetRuleUsage enables the ListUnusedPolicies RPC, served from u. Without it
+// ListUnusedPolicies fails with FAILED_PRECONDITION. It must be called before
+// the server starts handling requests.
+func (s *Server) SetRuleUsage(u RuleUsageSource) {
+	s.ruleUsage = u
+}
+
+// ListUnusedPolicies lists the active policies that have been tracked for at
+// least the requested number of days without a match in that period.
+func (s *Server) ListUnusedPolicies(_ context.Context, req *adminv1.ListUnusedPoliciesRequest) (*adminv1.ListUnusedPoliciesResponse, error) {
+	if s.ruleUsage == nil {
+		return nil, status.Error(codes.FailedPrecondition, "rule usage tracking is not enabled on this server")
+	}
+	if req.Days < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "days must not be negative, got %d", req.Days)
+	}
+	days := req.Days
+	if days == 0 {
+		days = defaultUnusedDays
+	}
+	cutoff := time.Now().AddDate(0, 0, -int(days)).Unix()
+
+	usage, err := s.ruleUsage.Usage()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list rule usage: %v", err)
+	}
+	dynamic, err := s.store.List()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "list store: "+err.Error())
+	}
+
+	resp := &adminv1.ListUnusedPoliciesResponse{}
+	add := func(p policy.Policy, source string) {
+		u, ok := usage[p.Name]
+		if !ok || u.TrackedSince > cutoff || u.LastMatched > cutoff {
+			return
+		}
+		resp.Policies = append(resp.Policies, &adminv1.UnusedPolicy{
+			Rule:         policyToProto(p),
+			Source:       source,
+			LastMatched:  u.LastMatched,
+			Matches:      u.Matches,
+			TrackedSince: u.TrackedSince,
+		})
+	}
+	for _, p := range s.yamlPolicies() {
+		add(p, "yaml")
+	}
+	for _, p := range dynamic {
+		add(p, "dynamic")
+	}
+	return resp, nil
+}
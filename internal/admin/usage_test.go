@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	adminv1 "github.com/ngaddam369/svid-exchange/proto/admin/v1"
+)
+
+// fakeUsage returns fixed rule counters, or err.
+type fakeUsage struct {
+	usage map[string]policy.RuleUsage
+	err   error
+}
+
+func (f fakeUsage) Usage() (map[string]policy.RuleUsage, error) { return f.usage, f.err }
+
+func TestListUnusedPolicies(t *testing.T) {
+	ctx := context.Background()
+	daysAgo := func(d int) int64 { return time.Now().AddDate(0, 0, -d).Unix() }
+
+	t.Run("unavailable without tracking", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		_, err := svc.ListUnusedPolicies(ctx, &adminv1.ListUnusedPoliciesRequest{})
+		assertCode(t, err, codes.FailedPrecondition)
+	})
+
+	t.Run("negative days", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		svc.SetRuleUsage(fakeUsage{})
+		_, err := svc.ListUnusedPolicies(ctx, &adminv1.ListUnusedPoliciesRequest{Days: -1})
+		assertCode(t, err, codes.InvalidArgument)
+	})
+
+	t.Run("usage error", func(t *testing.T) {
+		svc, _ := newTestServer(t)
+		svc.SetRuleUsage(fakeUsage{err: errors.New("boom")})
+		_, err := svc.ListUnusedPolicies(ctx, &adminv1.ListUnusedPoliciesRequest{})
+		assertCode(t, err, codes.Internal)
+	})
+
+	svc, _ := newTestServer(t)
+	for _, r := range []*adminv1.PolicyRule{
+		newRule("stale", subB, tgt),
+		newRule("recent", subC, tgt),
+		newRule("new", subB, tgt2),
+		newRule("untracked", subC, tgt2),
+	} {
+		if _, err := svc.CreatePolicy(ctx, &adminv1.CreatePolicyRequest{Rule: r}); err != nil {
+			t.Fatalf("create %s: %v", r.Name, err)
+		}
+	}
+	svc.SetRuleUsage(fakeUsage{usage: map[string]policy.RuleUsage{
+		"yaml-policy": {TrackedSince: daysAgo(90)},
+		"stale":       {Matches: 7, LastMatched: daysAgo(40), TrackedSince: daysAgo(90)},
+		"recent":      {Matches: 3, LastMatched: daysAgo(1), TrackedSince: daysAgo(90)},
+		"new":         {TrackedSince: daysAgo(5)},
+	}})
+
+	tests := []struct {
+		name string
+		days int32
+		want []string
+	}{
+		{name: "default period", want: []string{"yaml-policy", "stale"}},
+		{name: "longer period", days: 60, want: []string{"yaml-policy"}},
+		{name: "shorter period", days: 3, want: []string{"yaml-policy", "new", "stale"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := svc.ListUnusedPolicies(ctx, &adminv1.ListUnusedPoliciesRequest{Days: tc.days})
+			if err != nil {
+				t.Fatalf("ListUnusedPolicies: %v", err)
+			}
+			var got []string
+			for _, p := range resp.Policies {
+				got = append(got, p.Rule.Name)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("unused = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("unused = %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+
+	t.Run("entry fields", func(t *testing.T) {
+		resp, err := svc.ListUnusedPolicies(ctx, &adminv1.ListUnusedPoliciesRequest{})
+		if err != nil {
+			t.Fatalf("ListUnusedPolicies: %v", err)
+		}
+		if len(resp.Policies) != 2 {
+			t.Fatalf("got %d entries, want 2", len(resp.Policies))
+		}
+		if p := resp.Policies[0]; p.Source != "yaml" || p.Matches != 0 || p.LastMatched != 0 {
+			t.Errorf("yaml-policy = %v", p)
+		}
+		if p := resp.Policies[1]; p.Source != "dynamic" || p.Matches != 7 || p.LastMatched == 0 || p.TrackedSince == 0 {
+			t.Errorf("stale = %v", p)
+		}
+	})
+}
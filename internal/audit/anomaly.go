@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Anomaly kinds reported by the built-in analyzers.
+const (
+	// AnomalyNewPair flags the first granted exchange for a subject→target
+	// pair since the analyzer started.
+	AnomalyNewPair = "new_pair"
+	// AnomalyScopeEscalation flags a granted exchange whose scopes include
+	// one never granted before for the same pair.
+	AnomalyScopeEscalation = "scope_escalation"
+	// AnomalyDenialBurst flags a subject collecting many denials in a short
+	// window, typical of a workload probing for permitted targets.
+	AnomalyDenialBurst = "denial_burst"
+)
+
+// Anomaly is an unusual exchange pattern reported by an Analyzer.
+type Anomaly struct {
+	Kind   string
+	Detail string
+}
+
+// Analyzer inspects every exchange event the Logger records and reports any
+// anomalies it finds; each is logged as a separate "token.exchange.anomaly"
+// entry. Analyze is called concurrently from request goroutines, so it must
+// be safe for concurrent use and must not block.
+type Analyzer interface {
+	Analyze(e ExchangeEvent) []Anomaly
+}
+
+// PairAnalyzer learns which scopes each subject→target pair is granted and
+// reports AnomalyNewPair for a pair's first grant and AnomalyScopeEscalation
+// when a later grant adds scopes. Denials and preflights are ignored. State
+// is in memory, so after a restart every pair is new again. Once maxPairs
+// pairs are known, further new pairs are neither learned nor reported.
+type PairAnalyzer struct {
+	mu       sync.Mutex
+	pairs    map[string]map[string]struct{} // subject\x00target → granted scopes
+	maxPairs int
+}
+
+// NewPairAnalyzer returns a PairAnalyzer that tracks at most maxPairs pairs.
+func NewPairAnalyzer(maxPairs int) *PairAnalyzer {
+	return &PairAnalyzer{pairs: make(map[string]map[string]struct{}), maxPairs: maxPairs}
+}
+
+// Analyze implements Analyzer.
+func (p *PairAnalyzer) Analyze(e ExchangeEvent) []Anomaly {
+	if !e.Granted || e.Preflight {
+		return nil
+	}
+	key := e.Subject + "\x00" + e.Target
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen, ok := p.pairs[key]
+	if !ok {
+		if len(p.pairs) >= p.maxPairs {
+			return nil
+		}
+		seen = make(map[string]struct{}, len(e.ScopesGranted))
+		for _, s := range e.ScopesGranted {
+			seen[s] = struct{}{}
+		}
+		p.pairs[key] = seen
+		return []Anomaly{{
+			Kind:   AnomalyNewPair,
+			Detail: fmt.Sprintf("first exchange from %s to %s", e.Subject, e.Target),
+		}}
+	}
+	var added []string
+	for _, s := range e.ScopesGranted {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			added = append(added, s)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return []Anomaly{{
+		Kind:   AnomalyScopeEscalation,
+		Detail: fmt.Sprintf("scopes never granted before for this pair: %s", strings.Join(added, " ")),
+	}}
+}
+
+// DenialBurstAnalyzer reports AnomalyDenialBurst when a subject is denied
+// threshold times within window. The count restarts after each report, so a
+// sustained stream of denials is reported once per threshold denials rather
+// than on every one. Denials an event reports as suppressed count as if they
+// had occurred with it. At most maxSubjects subjects are tracked at a time.
+type DenialBurstAnalyzer struct {
+	mu          sync.Mutex
+	threshold   int
+	window      time.Duration
+	denials     map[string][]time.Time // subject → denial times within window
+	maxSubjects int
+}
+
+// NewDenialBurstAnalyzer returns a DenialBurstAnalyzer. threshold must be at
+// least 1 and window positive.
+func NewDenialBurstAnalyzer(threshold int, window time.Duration) *DenialBurstAnalyzer {
+	return &DenialBurstAnalyzer{
+		threshold:   threshold,
+		window:      window,
+		denials:     make(map[string][]time.Time),
+		maxSubjects: 10_000,
+	}
+}
+
+// Analyze implements Analyzer.
+func (d *DenialBurstAnalyzer) Analyze(e ExchangeEvent) []Anomaly {
+	if e.Granted {
+		return nil
+	}
+	now := time.Now()
+	cutoff := now.Add(-d.window)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	recent, ok := d.denials[e.Subject]
+	if !ok && len(d.denials) >= d.maxSubjects {
+		d.sweep(cutoff)
+		if len(d.denials) >= d.maxSubjects {
+			return nil
+		}
+	}
+	i := 0
+	for i < len(recent) && !recent[i].After(cutoff) {
+		i++
+	}
+	recent = recent[i:]
+	for range min(1+e.SuppressedDenials, d.threshold) {
+		recent = append(recent, now)
+	}
+	if len(recent) < d.threshold {
+		d.denials[e.Subject] = recent
+		return nil
+	}
+	delete(d.denials, e.Subject)
+	return []Anomaly{{
+		Kind:   AnomalyDenialBurst,
+		Detail: fmt.Sprintf("%d denials within %s", len(recent), d.window),
+	}}
+}
+
+// sweep drops subjects with no denial after cutoff. Must be called with d.mu
+// held.
+func (d *DenialBurstAnalyzer) sweep(cutoff time.Time) {
+	for subject, times := range d.denials {
+		if !times[len(times)-1].After(cutoff) {
+			delete(d.denials, subject)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func kinds(as []Anomaly) []string {
+	var out []string
+	for _, a := range as {
+		out = append(out, a.Kind)
+	}
+	return out
+}
+
+func TestPairAnalyzer(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+		ledger  = "spiffe://cluster.local/ns/default/sa/ledger"
+	)
+	p := NewPairAnalyzer(2)
+	steps := []struct {
+		name  string
+		event ExchangeEvent
+		want  []string
+	}{
+		{name: "first grant is a new pair", event: ExchangeEvent{Subject: order, Target: payment, ScopesGranted: []string{"read"}, Granted: true}, want: []string{AnomalyNewPair}},
+		{name: "same scopes again is quiet", event: ExchangeEvent{Subject: order, Target: payment, ScopesGranted: []string{"read"}, Granted: true}},
+		{name: "added scope is an escalation", event: ExchangeEvent{Subject: order, Target: payment, ScopesGranted: []string{"read", "write"}, Granted: true}, want: []string{AnomalyScopeEscalation}},
+		{name: "escalated scope is learned", event: ExchangeEvent{Subject: order, Target: payment, ScopesGranted: []string{"write"}, Granted: true}},
+		{name: "denials are ignored", event: ExchangeEvent{Subject: order, Target: ledger, Granted: false}},
+		{name: "preflights are ignored", event: ExchangeEvent{Subject: order, Target: ledger, ScopesGranted: []string{"read"}, Granted: true, Preflight: true}},
+		{name: "second pair is new", event: ExchangeEvent{Subject: order, Target: ledger, ScopesGranted: []string{"read"}, Granted: true}, want: []string{AnomalyNewPair}},
+		{name: "pairs beyond the cap are not tracked", event: ExchangeEvent{Subject: payment, Target: ledger, ScopesGranted: []string{"read"}, Granted: true}},
+	}
+	for _, st := range steps {
+		got := kinds(p.Analyze(st.event))
+		if len(got) != len(st.want) || (len(got) > 0 && got[0] != st.want[0]) {
+			t.Errorf("%s: anomalies = %v, want %v", st.name, got, st.want)
+		}
+	}
+}
+
+func TestDenialBurstAnalyzer(t *testing.T) {
+	const subject = "spiffe://cluster.local/ns/default/sa/order"
+	denied := ExchangeEvent{Subject: subject, Granted: false}
+
+	t.Run("threshold denials within window are a burst", func(t *testing.T) {
+		d := NewDenialBurstAnalyzer(3, time.Minute)
+		for i := range 2 {
+			if got := d.Analyze(denied); len(got) != 0 {
+				t.Fatalf("denial %d: anomalies = %v, want none", i+1, got)
+			}
+		}
+		if got := kinds(d.Analyze(denied)); len(got) != 1 || got[0] != AnomalyDenialBurst {
+			t.Fatalf("third denial: anomalies = %v, want denial_burst", got)
+		}
+		if got := d.Analyze(denied); len(got) != 0 {
+			t.Errorf("count did not restart after a report: %v", got)
+		}
+	})
+
+	t.Run("grants do not count", func(t *testing.T) {
+		d := NewDenialBurstAnalyzer(1, time.Minute)
+		if got := d.Analyze(ExchangeEvent{Subject: subject, Granted: true}); len(got) != 0 {
+			t.Errorf("anomalies = %v, want none", got)
+		}
+	})
+
+	t.Run("suppressed denials count", func(t *testing.T) {
+		d := NewDenialBurstAnalyzer(5, time.Minute)
+		d.Analyze(denied)
+		suppressed := ExchangeEvent{Subject: subject, Granted: false, SuppressedDenials: 3}
+		if got := kinds(d.Analyze(suppressed)); len(got) != 1 || got[0] != AnomalyDenialBurst {
+			t.Errorf("anomalies = %v, want denial_burst after 1+1+3 denials", got)
+		}
+	})
+
+	t.Run("denials outside the window expire", func(t *testing.T) {
+		d := NewDenialBurstAnalyzer(2, 20*time.Millisecond)
+		d.Analyze(denied)
+		time.Sleep(40 * time.Millisecond)
+		if got := d.Analyze(denied); len(got) != 0 {
+			t.Errorf("anomalies = %v, want none after the first denial expired", got)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package audit
+
+import "time"
+
+// Break-glass actions, as recorded in BreakGlassEvent.Action.
+const (
+	// BreakGlassActivated is an override put in force through the admin API.
+	BreakGlassActivated = "activated"
+	// BreakGlassRejected is an override document that failed verification.
+	BreakGlassRejected = "rejected"
+	// BreakGlassGrant is an exchange granted by an override that the
+	// regular policy denied.
+	BreakGlassGrant = "grant"
+	// BreakGlassDeactivated is an override ended early through the admin
+	// API.
+	BreakGlassDeactivated = "deactivated"
+	// BreakGlassExpired is an override that reached its expiry.
+	BreakGlassExpired = "expired"
+)
+
+// BreakGlassEvent is the payload for a break-glass audit log entry. Empty
+// fields are omitted from the log line.
+type BreakGlassEvent struct {
+	Action string
+	// ID, Reason, IssuedBy, and ExpiresAt come from the override document.
+	// They are empty for a rejected document.
+	ID        string
+	Reason    string
+	IssuedBy  string
+	ExpiresAt time.Time
+	// Actor is the SPIFFE ID of the admin caller that activated,
+	// deactivated, or submitted a rejected override.
+	Actor string
+	// Replaced is the ID of the override an activation replaced.
+	Replaced string
+	// Error is why a document was rejected.
+	Error string
+	// Subject, Target, ScopesGranted, and Rules describe a grant.
+	Subject       string
+	Target        string
+	ScopesGranted []string
+	Rules         []string
+}
+
+// LogBreakGlass emits one "break_glass" audit log line. Every break-glass
+// action is written, at error level unless SetLevel overrides it, so that
+// emergency access stands out from routine exchanges.
+func (l *Logger) LogBreakGlass(e BreakGlassEvent) {
+	e.Actor, e.Subject, e.Target = l.redact.ID(e.Actor), l.redact.ID(e.Subject), l.redact.ID(e.Target)
+	e.ScopesGranted = l.redact.Scopes(e.ScopesGranted)
+	ev := l.log.WithLevel(l.levels[KindBreakGlass]).
+		Str("event", "break_glass").
+		Str("action", e.Action)
+	for _, f := range []struct{ key, val string }{
+		{"override_id", e.ID}, {"reason", e.Reason}, {"issued_by", e.IssuedBy}, {"actor", e.Actor},
+		{"replaced", e.Replaced}, {"error", e.Error}, {"subject", e.Subject}, {"target", e.Target},
+	} {
+		if f.val != "" {
+			ev = ev.Str(f.key, f.val)
+		}
+	}
+	if !e.ExpiresAt.IsZero() {
+		ev = ev.Time("expires_at", e.ExpiresAt)
+	}
+	if len(e.ScopesGranted) > 0 {
+		ev = ev.Strs("scopes_granted", e.ScopesGranted)
+	}
+	if len(e.Rules) > 0 {
+		ev = ev.Strs("policy_rules", e.Rules)
+	}
+	ev.Send()
+}
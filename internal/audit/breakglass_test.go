@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogBreakGlass(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		event   BreakGlassEvent
+		want    map[string]any
+		absent  []string
+		level   zerolog.Level // overrides the default when non-zero
+		wantLvl string
+	}{
+		{
+			name: "activation",
+			event: BreakGlassEvent{Action: BreakGlassActivated, ID: "inc-4711", Reason: "payments outage", IssuedBy: "security",
+				ExpiresAt: exp, Actor: "spiffe://cluster.local/ns/ops/sa/admin"},
+			want: map[string]any{"event": "break_glass", "action": "activated", "override_id": "inc-4711", "reason": "payments outage",
+				"issued_by": "security", "expires_at": exp.Format(time.RFC3339), "actor": "spiffe://cluster.local/ns/ops/sa/admin"},
+			absent:  []string{"subject", "error", "replaced", "scopes_granted"},
+			wantLvl: "error",
+		},
+		{
+			name: "grant",
+			event: BreakGlassEvent{Action: BreakGlassGrant, ID: "inc-4711", Subject: "spiffe://td/order", Target: "spiffe://td/payment",
+				ScopesGranted: []string{"payments:charge"}, Rules: []string{"break-glass/inc-4711/order-to-payment"}},
+			want:    map[string]any{"action": "grant", "subject": "spiffe://td/order", "target": "spiffe://td/payment"},
+			absent:  []string{"expires_at", "actor"},
+			wantLvl: "error",
+		},
+		{
+			name:    "rejection at a configured level",
+			event:   BreakGlassEvent{Action: BreakGlassRejected, Actor: "spiffe://td/admin", Error: "override signature is invalid"},
+			want:    map[string]any{"action": "rejected", "error": "override signature is invalid"},
+			absent:  []string{"override_id", "expires_at"},
+			level:   zerolog.WarnLevel,
+			wantLvl: "warn",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := New(&buf)
+			if tc.level != 0 {
+				if err := l.SetLevel(KindBreakGlass, tc.level); err != nil {
+					t.Fatalf("SetLevel: %v", err)
+				}
+			}
+			l.LogBreakGlass(tc.event)
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decode %q: %v", buf.String(), err)
+			}
+			if entry["level"] != tc.wantLvl {
+				t.Errorf("level = %v, want %s", entry["level"], tc.wantLvl)
+			}
+			for k, v := range tc.want {
+				if entry[k] != v {
+					t.Errorf("%s = %v, want %v", k, entry[k], v)
+				}
+			}
+			for _, k := range tc.absent {
+				if _, ok := entry[k]; ok {
+					t.Errorf("unexpected field %s in %v", k, entry)
+				}
+			}
+		})
+	}
+}
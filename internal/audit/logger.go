@@ -2,34 +2,76 @@
 package audit
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"io"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
 // Logger writes audit events as structured JSON.
 type Logger struct {
-	log zerolog.Logger
+	log       zerolog.Logger
+	analyzers []Analyzer
+	sinks     []Sink
+	levels    map[string]zerolog.Level // event kind → severity; see SetLevel
+
+	// grantRate is the fraction of grants written; see SetSampling.
+	grantRate float64
+	observe   func(e ExchangeEvent, written bool)
+	sample    func() float64
+
+	// redact rewrites SPIFFE IDs and scopes before they are written; see
+	// SetRedactor.
+	redact *Redactor
+
+	// spool holds events for the sinks; see SetSpool.
+	spool *Spool
 }
 
 // New creates an audit Logger writing to w.
 func New(w io.Writer) *Logger {
-	return &Logger{
-		log: zerolog.New(w).With().Timestamp().Logger(),
-	}
+	return newLogger(zerolog.New(w))
 }
 
 // NewWithHMAC creates an audit Logger that appends HMAC-SHA256 tamper-evidence
 // fields ("seq", "prev_hmac", "hmac") to every log line. key must be 32 bytes.
 // When key is nil or empty, the logger behaves identically to New.
 func NewWithHMAC(w io.Writer, key []byte) *Logger {
+	return newLogger(zerolog.New(newHMACWriter(w, key)))
+}
+
+func newLogger(log zerolog.Logger) *Logger {
 	return &Logger{
-		log: zerolog.New(newHMACWriter(w, key)).With().Timestamp().Logger(),
+		log:       log.With().Timestamp().Logger(),
+		levels:    maps.Clone(defaultLevels),
+		grantRate: 1,
+		sample:    defaultSample,
 	}
 }
 
+// AddAnalyzer registers a to inspect every subsequent exchange event. It must
+// be called before the Logger is shared between goroutines.
+func (l *Logger) AddAnalyzer(a Analyzer) {
+	l.analyzers = append(l.analyzers, a)
+}
+
+// AddSink registers s to receive every subsequent exchange event. It must be
+// called before the Logger is shared between goroutines.
+func (l *Logger) AddSink(s Sink) {
+	l.sinks = append(l.sinks, s)
+}
+
 // ExchangeEvent is the payload for a token exchange audit log entry.
 type ExchangeEvent struct {
+	// RequestID correlates the entry with the server's response header and
+	// error message for the same call. Omitted from the log line when empty.
+	RequestID       string
 	Subject         string
 	Target          string
 	ScopesRequested []string
@@ -38,25 +80,230 @@ type ExchangeEvent struct {
 	TTL             int32
 	TokenID         string
 	DenialReason    string
+	// PolicyRules names the policy rules that authorized the scopes, as
+	// reported in policy.EvalResult.MatchedRules. Set on grants and on quota
+	// denials, where a rule did match; omitted from the log line when empty.
+	PolicyRules []string
+	// AuthMethod names how the subject was authenticated, e.g. "x509-svid".
+	// Omitted from the log line when empty.
+	AuthMethod string
+	// Cert identifies the certificate the subject authenticated with. Nil
+	// for token methods, and then omitted from the log line.
+	Cert *CertInfo
+	// SuppressedDenials counts identical requests denied from the server's
+	// denial cache, without their own entry, since the previous entry for
+	// this request. Omitted from the log line when zero.
+	SuppressedDenials int
+	// Preflight marks an exchange evaluated without minting a token, so a
+	// granted preflight has no TokenID. Omitted from the log line when false.
+	Preflight bool
+	// TTLClampedFrom is the TTL policy granted before the server's global
+	// ceiling cut it down to TTL. Omitted from the log line when zero, that
+	// is when the grant was not clamped.
+	TTLClampedFrom int32
+	// Time is when the event was recorded, set on events shipped from a
+	// Spool. Zero means now.
+	Time time.Time
+	// TraceID is the hex OpenTelemetry trace ID of the Exchange call.
+	// Omitted from the log line when empty, that is when the call was not
+	// traced.
+	TraceID string
+	// Approver identifies who granted or denied an exchange that a
+	// requires_approval policy parked: an admin caller's SPIFFE ID, or
+	// "webhook". Omitted from the log line when empty.
+	Approver string
+	// Metadata holds the caller's values of the allowlisted gRPC metadata
+	// keys, keyed by lowercase key; see
+	// server.TokenExchangeServer.SetAuditMetadataKeys. Omitted from the log
+	// line when empty.
+	Metadata map[string]string
+}
+
+// CertInfo identifies a single certificate issuance, so an audit entry can
+// be tied to the exact SVID presented rather than just its SPIFFE ID, which
+// is shared by every SVID a workload is issued.
+type CertInfo struct {
+	// Serial is the certificate serial number in hex.
+	Serial string
+	// Fingerprint is the hex SHA-256 of the DER certificate.
+	Fingerprint string
+	NotAfter    time.Time
+	// Issuer is the issuing CA's distinguished name.
+	Issuer string
+	// URISANs lists every URI SAN on the certificate, in order, including
+	// any SPIFFE IDs other than the one the caller was authenticated as.
+	URISANs []string
+}
+
+// NewCertInfo returns the CertInfo for c, or nil when c is nil.
+func NewCertInfo(c *x509.Certificate) *CertInfo {
+	if c == nil {
+		return nil
+	}
+	sum := sha256.Sum256(c.Raw)
+	info := &CertInfo{
+		Serial:      c.SerialNumber.Text(16),
+		Fingerprint: hex.EncodeToString(sum[:]),
+		NotAfter:    c.NotAfter,
+		Issuer:      c.Issuer.String(),
+	}
+	for _, u := range c.URIs {
+		info.URISANs = append(info.URISANs, u.String())
+	}
+	return info
 }
 
-// LogExchange emits one audit log line for a token exchange attempt.
+// LogExchange emits one audit log line for a token exchange attempt, unless
+// sampling drops it, followed by one "token.exchange.anomaly" line per
+// anomaly the registered analyzers report for it, and then hands the event
+// to each registered sink, through the spool if one is set. Everything written or handed on is redacted as
+// set by SetRedactor.
 func (l *Logger) LogExchange(e ExchangeEvent) {
-	ev := l.log.Info().
+	_ = l.RecordExchange(e)
+}
+
+// RecordExchange is LogExchange, reporting whether the event reached the
+// spool. Without a spool it always returns nil. When the spool write fails
+// the event is still delivered to sinks directly.
+func (l *Logger) RecordExchange(e ExchangeEvent) error {
+	written := l.sampled(e)
+	if l.observe != nil {
+		l.observe(e, written)
+	}
+	out := l.redact.event(e)
+	if written {
+		l.writeExchange(out)
+	}
+
+	for _, a := range l.analyzers {
+		for _, an := range a.Analyze(e) {
+			an.Detail = l.redact.Text(an.Detail, []string{e.Subject, e.Target}, slices.Concat(e.ScopesRequested, e.ScopesGranted))
+			l.logAnomaly(out, an)
+		}
+	}
+	if l.spool != nil {
+		err := l.spool.Append(out)
+		if err == nil {
+			return nil
+		}
+		l.log.Error().Err(err).Str("request_id", out.RequestID).Msg("audit spool write failed; delivering to sinks directly")
+		l.deliver(out)
+		return err
+	}
+	l.deliver(out)
+	return nil
+}
+
+// SetSpool routes exchange events for the sinks through s, which ShipSpool
+// then delivers them from. Call before the first event is logged.
+func (l *Logger) SetSpool(s *Spool) {
+	l.spool = s
+}
+
+// ShipSpool delivers spooled events to the sinks until ctx is cancelled. It
+// returns immediately without a spool.
+func (l *Logger) ShipSpool(ctx context.Context) {
+	if l.spool != nil {
+		l.spool.Ship(ctx, l.sinks)
+	}
+}
+
+// FlushSpool blocks until every spooled event has been shipped, or ctx is
+// done. ShipSpool must be running. It returns nil without a spool.
+func (l *Logger) FlushSpool(ctx context.Context) error {
+	if l.spool == nil {
+		return nil
+	}
+	return l.spool.Flush(ctx)
+}
+
+// deliver hands e to every sink.
+func (l *Logger) deliver(e ExchangeEvent) {
+	for _, s := range l.sinks {
+		s.Deliver(e)
+	}
+}
+
+func (l *Logger) writeExchange(e ExchangeEvent) {
+	kind := KindDenial
+	if e.Granted {
+		kind = KindGrant
+	}
+	ev := l.log.WithLevel(l.levels[kind]).
 		Str("event", "token.exchange").
 		Str("subject", e.Subject).
 		Str("target", e.Target).
 		Strs("scopes_requested", e.ScopesRequested).
 		Bool("granted", e.Granted)
+	if e.RequestID != "" {
+		ev = ev.Str("request_id", e.RequestID)
+	}
+	if e.AuthMethod != "" {
+		ev = ev.Str("auth_method", e.AuthMethod)
+	}
+	if e.TraceID != "" {
+		ev = ev.Str("trace_id", e.TraceID)
+	}
+	if c := e.Cert; c != nil {
+		ev = ev.
+			Str("cert_serial", c.Serial).
+			Str("cert_fingerprint", c.Fingerprint).
+			Time("cert_not_after", c.NotAfter).
+			Str("cert_issuer", c.Issuer)
+		if len(c.URISANs) > 0 {
+			ev = ev.Strs("cert_uri_sans", c.URISANs)
+		}
+	}
+	if len(e.PolicyRules) > 0 {
+		ev = ev.Strs("policy_rules", e.PolicyRules)
+	}
+	if e.Preflight {
+		ev = ev.Bool("preflight", true)
+	}
+	if e.Approver != "" {
+		ev = ev.Str("approver", e.Approver)
+	}
+	if len(e.Metadata) > 0 {
+		md := zerolog.Dict()
+		for _, k := range slices.Sorted(maps.Keys(e.Metadata)) {
+			md = md.Str(k, e.Metadata[k])
+		}
+		ev = ev.Dict("metadata", md)
+	}
 
 	if e.Granted {
 		ev = ev.
 			Strs("scopes_granted", e.ScopesGranted).
-			Int32("ttl", e.TTL).
-			Str("token_id", e.TokenID)
+			Int32("ttl", e.TTL)
+		if e.TTLClampedFrom > 0 {
+			ev = ev.Int32("ttl_clamped_from", e.TTLClampedFrom)
+		}
+		if !e.Preflight {
+			ev = ev.Str("token_id", e.TokenID)
+		}
+		if l.grantRate < 1 {
+			ev = ev.Float64("sample_rate", l.grantRate)
+		}
 	} else {
 		ev = ev.Str("denial_reason", e.DenialReason)
+		if e.SuppressedDenials > 0 {
+			ev = ev.Int("suppressed_denials", e.SuppressedDenials)
+		}
 	}
 
 	ev.Send()
 }
+
+func (l *Logger) logAnomaly(e ExchangeEvent, an Anomaly) {
+	ev := l.log.WithLevel(l.levels[KindAnomaly]).
+		Str("event", "token.exchange.anomaly").
+		Str("anomaly", an.Kind).
+		Str("detail", an.Detail).
+		Str("subject", e.Subject).
+		Str("target", e.Target).
+		Bool("granted", e.Granted)
+	if e.RequestID != "" {
+		ev = ev.Str("request_id", e.RequestID)
+	}
+	ev.Send()
+}
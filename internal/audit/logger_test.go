@@ -2,8 +2,17 @@ package audit
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
+	"math/big"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogExchange(t *testing.T) {
@@ -11,11 +20,14 @@ func TestLogExchange(t *testing.T) {
 		name       string
 		event      ExchangeEvent
 		wantFields map[string]any
+		wantRules  []string
 		absentKeys []string
 	}{
 		{
 			name: "granted",
 			event: ExchangeEvent{
+				RequestID:       "req-42",
+				AuthMethod:      "x509-svid",
 				Subject:         "spiffe://cluster.local/ns/default/sa/order",
 				Target:          "spiffe://cluster.local/ns/default/sa/payment",
 				ScopesRequested: []string{"payments:charge"},
@@ -23,16 +35,32 @@ func TestLogExchange(t *testing.T) {
 				Granted:         true,
 				TTL:             300,
 				TokenID:         "test-jti-123",
+				PolicyRules:     []string{"order-to-payment"},
+				TraceID:         "4bf92f3577b34da6a3ce929d0e0e4736",
+				Cert: &CertInfo{
+					Serial:      "2a",
+					Fingerprint: "9f86d081884c7d65",
+					NotAfter:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+					Issuer:      "O=SPIRE,C=US",
+				},
 			},
 			wantFields: map[string]any{
-				"event":    "token.exchange",
-				"subject":  "spiffe://cluster.local/ns/default/sa/order",
-				"target":   "spiffe://cluster.local/ns/default/sa/payment",
-				"granted":  true,
-				"ttl":      float64(300),
-				"token_id": "test-jti-123",
+				"event":            "token.exchange",
+				"subject":          "spiffe://cluster.local/ns/default/sa/order",
+				"target":           "spiffe://cluster.local/ns/default/sa/payment",
+				"granted":          true,
+				"ttl":              float64(300),
+				"token_id":         "test-jti-123",
+				"request_id":       "req-42",
+				"auth_method":      "x509-svid",
+				"trace_id":         "4bf92f3577b34da6a3ce929d0e0e4736",
+				"cert_serial":      "2a",
+				"cert_fingerprint": "9f86d081884c7d65",
+				"cert_not_after":   "2026-01-02T03:04:05Z",
+				"cert_issuer":      "O=SPIRE,C=US",
 			},
-			absentKeys: []string{"denial_reason"},
+			wantRules:  []string{"order-to-payment"},
+			absentKeys: []string{"denial_reason", "ttl_clamped_from", "cert_uri_sans"},
 		},
 		{
 			name: "denied",
@@ -47,7 +75,56 @@ func TestLogExchange(t *testing.T) {
 				"granted":       false,
 				"denial_reason": "no policy permits order → admin",
 			},
-			absentKeys: []string{"token_id", "ttl"},
+			absentKeys: []string{"token_id", "ttl", "request_id", "auth_method", "policy_rules", "cert_serial", "suppressed_denials", "ttl_clamped_from", "trace_id", "metadata"},
+		},
+		{
+			name: "denied after cached denials",
+			event: ExchangeEvent{
+				Subject:           "spiffe://cluster.local/ns/default/sa/order",
+				Target:            "spiffe://cluster.local/ns/default/sa/admin",
+				ScopesRequested:   []string{"admin:delete"},
+				DenialReason:      "no policy permits order → admin",
+				SuppressedDenials: 7,
+			},
+			wantFields: map[string]any{
+				"granted":            false,
+				"suppressed_denials": float64(7),
+			},
+		},
+		{
+			name: "granted preflight",
+			event: ExchangeEvent{
+				Subject:         "spiffe://cluster.local/ns/default/sa/order",
+				Target:          "spiffe://cluster.local/ns/default/sa/payment",
+				ScopesRequested: []string{"payments:charge"},
+				ScopesGranted:   []string{"payments:charge"},
+				Granted:         true,
+				TTL:             300,
+				Preflight:       true,
+			},
+			wantFields: map[string]any{
+				"granted":   true,
+				"preflight": true,
+				"ttl":       float64(300),
+			},
+			absentKeys: []string{"token_id"},
+		},
+		{
+			name: "granted with clamped ttl",
+			event: ExchangeEvent{
+				Subject:         "spiffe://cluster.local/ns/default/sa/order",
+				Target:          "spiffe://cluster.local/ns/default/sa/payment",
+				ScopesRequested: []string{"payments:charge"},
+				ScopesGranted:   []string{"payments:charge"},
+				Granted:         true,
+				TTL:             600,
+				TTLClampedFrom:  3600,
+				TokenID:         "tok-1",
+			},
+			wantFields: map[string]any{
+				"ttl":              float64(600),
+				"ttl_clamped_from": float64(3600),
+			},
 		},
 	}
 
@@ -67,6 +144,17 @@ func TestLogExchange(t *testing.T) {
 					t.Errorf("field %q = %v, want %v", k, got, want)
 				}
 			}
+			if tc.wantRules != nil {
+				got, _ := entry["policy_rules"].([]any)
+				if len(got) != len(tc.wantRules) {
+					t.Errorf("policy_rules = %v, want %v", entry["policy_rules"], tc.wantRules)
+				}
+				for i, r := range tc.wantRules {
+					if i < len(got) && got[i] != r {
+						t.Errorf("policy_rules[%d] = %v, want %q", i, got[i], r)
+					}
+				}
+			}
 			for _, k := range tc.absentKeys {
 				if _, ok := entry[k]; ok {
 					t.Errorf("field %q should not be present", k)
@@ -75,3 +163,124 @@ func TestLogExchange(t *testing.T) {
 		})
 	}
 }
+
+func TestLogExchangeMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf).LogExchange(ExchangeEvent{
+		Subject:  "spiffe://cluster.local/ns/default/sa/order",
+		Target:   "spiffe://cluster.local/ns/default/sa/payment",
+		Metadata: map[string]string{"x-client-version": "1.4.2", "x-deployment-id": "d-81"},
+	})
+	var entry struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if entry.Metadata["x-client-version"] != "1.4.2" || entry.Metadata["x-deployment-id"] != "d-81" || len(entry.Metadata) != 2 {
+		t.Errorf("metadata = %v", entry.Metadata)
+	}
+}
+
+// fixedAnalyzer reports the same anomalies for every event.
+type fixedAnalyzer []Anomaly
+
+func (f fixedAnalyzer) Analyze(ExchangeEvent) []Anomaly { return f }
+
+func TestNewCertInfo(t *testing.T) {
+	if got := NewCertInfo(nil); got != nil {
+		t.Errorf("NewCertInfo(nil) = %+v, want nil", got)
+	}
+	notAfter := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &x509.Certificate{
+		Raw:          []byte("der"),
+		SerialNumber: big.NewInt(0xbeef),
+		NotAfter:     notAfter,
+		Issuer:       pkix.Name{Organization: []string{"SPIRE"}, Country: []string{"US"}},
+	}
+	sum := sha256.Sum256(c.Raw)
+	want := CertInfo{Serial: "beef", Fingerprint: hex.EncodeToString(sum[:]), NotAfter: notAfter, Issuer: "O=SPIRE,C=US"}
+	if got := NewCertInfo(c); got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("NewCertInfo() = %+v, want %+v", got, want)
+	}
+
+	c.URIs = []*url.URL{
+		{Scheme: "spiffe", Host: "cluster.local", Path: "/ns/default/sa/order"},
+		{Scheme: "spiffe", Host: "partner.example", Path: "/order"},
+	}
+	want.URISANs = []string{"spiffe://cluster.local/ns/default/sa/order", "spiffe://partner.example/order"}
+	if got := NewCertInfo(c); got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("NewCertInfo() with URI SANs = %+v, want %+v", got, want)
+	}
+}
+
+func TestLogExchangeCertURISANs(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf).LogExchange(ExchangeEvent{
+		Subject: "spiffe://cluster.local/ns/default/sa/order",
+		Cert:    &CertInfo{URISANs: []string{"spiffe://cluster.local/ns/default/sa/order", "spiffe://partner.example/order"}},
+	})
+	var entry struct {
+		URISANs []string `json:"cert_uri_sans"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not valid JSON: %v\noutput: %s", err, buf.String())
+	}
+	want := []string{"spiffe://cluster.local/ns/default/sa/order", "spiffe://partner.example/order"}
+	if !reflect.DeepEqual(entry.URISANs, want) {
+		t.Errorf("cert_uri_sans = %v, want %v", entry.URISANs, want)
+	}
+}
+
+func TestLogExchangeAnomalies(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.AddAnalyzer(fixedAnalyzer{{Kind: AnomalyNewPair, Detail: "first exchange"}})
+	l.AddAnalyzer(fixedAnalyzer(nil))
+	l.LogExchange(ExchangeEvent{
+		RequestID: "req-7",
+		Subject:   "spiffe://cluster.local/ns/default/sa/order",
+		Target:    "spiffe://cluster.local/ns/default/sa/payment",
+		Granted:   true,
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want exchange + one anomaly:\n%s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("anomaly line is not valid JSON: %v", err)
+	}
+	want := map[string]any{
+		"level":      "warn",
+		"event":      "token.exchange.anomaly",
+		"anomaly":    AnomalyNewPair,
+		"detail":     "first exchange",
+		"subject":    "spiffe://cluster.local/ns/default/sa/order",
+		"request_id": "req-7",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("field %q = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+// recordingSink keeps every event it is given.
+type recordingSink struct {
+	events []ExchangeEvent
+}
+
+func (r *recordingSink) Deliver(e ExchangeEvent) { r.events = append(r.events, e) }
+
+func TestLogExchangeSinks(t *testing.T) {
+	l := New(&bytes.Buffer{})
+	sink := &recordingSink{}
+	l.AddSink(sink)
+	e := ExchangeEvent{Subject: "spiffe://td/a", Target: "spiffe://td/b", DenialReason: "denied"}
+	l.LogExchange(e)
+	if len(sink.events) != 1 || sink.events[0].Subject != e.Subject {
+		t.Errorf("sink events = %+v, want the logged event", sink.events)
+	}
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	auditv1 "github.com/ngaddam369/svid-exchange/proto/audit/v1"
+)
+
+func TestProtoSink(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	s := NewProtoSink(&buf, zerolog.Nop())
+	s.now = func() time.Time { return at }
+
+	s.Deliver(ExchangeEvent{
+		RequestID: "req-1", Subject: "spiffe://td/a", Target: "spiffe://td/b",
+		ScopesRequested: []string{"read"}, ScopesGranted: []string{"read"}, Granted: true, TTL: 60,
+		TokenID: "tok-1", PolicyRules: []string{"a-to-b"}, AuthMethod: "x509-svid", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		Metadata: map[string]string{"x-client-version": "1.4.2"},
+		Cert:     &CertInfo{Serial: "1f", Fingerprint: "ab", NotAfter: at.Add(time.Hour), Issuer: "CN=ca", URISANs: []string{"spiffe://td/a"}},
+	})
+	s.Deliver(ExchangeEvent{Subject: "spiffe://td/a", Target: "spiffe://td/c", ScopesRequested: []string{"write"}, DenialReason: "no policy"})
+
+	r := bufio.NewReader(&buf)
+	var events []*auditv1.ExchangeEvent
+	for {
+		var ev auditv1.ExchangeEvent
+		if err := protodelim.UnmarshalFrom(r, &ev); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		events = append(events, &ev)
+	}
+	if len(events) != 2 {
+		t.Fatalf("read %d events, want 2", len(events))
+	}
+	g := events[0]
+	if g.GetSchemaVersion() != SchemaVersion || g.GetTime() != at.Unix() || !g.GetGranted() || g.GetTtlSeconds() != 60 ||
+		g.GetTokenId() != "tok-1" || g.GetTraceId() != "4bf92f3577b34da6a3ce929d0e0e4736" || g.GetPolicyRules()[0] != "a-to-b" ||
+		g.GetMetadata()["x-client-version"] != "1.4.2" {
+		t.Errorf("grant = %v", g)
+	}
+	if c := g.GetCert(); c.GetFingerprint() != "ab" || c.GetNotAfter() != at.Add(time.Hour).Unix() || c.GetUriSans()[0] != "spiffe://td/a" {
+		t.Errorf("cert = %v", c)
+	}
+	if d := events[1]; d.GetGranted() || d.GetDenialReason() != "no policy" || d.GetCert() != nil {
+		t.Errorf("denial = %v", d)
+	}
+}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Redaction modes for SPIFFE IDs and scopes, as accepted by NewRedactor.
+const (
+	// RedactNone leaves values as they are.
+	RedactNone = "none"
+	// RedactHash replaces a value with a keyed hash of it, so that entries
+	// for the same value can still be correlated by anyone holding the
+	// output, but the value cannot be recovered without the key.
+	RedactHash = "hash"
+	// RedactRemove replaces a value with redactedMarker.
+	RedactRemove = "redact"
+)
+
+// RedactionModes lists every mode accepted by NewRedactor.
+var RedactionModes = []string{RedactNone, RedactHash, RedactRemove}
+
+// MinRedactionKeySize is the shortest key NewRedactor accepts for RedactHash.
+const MinRedactionKeySize = 32
+
+const (
+	redactedMarker = "[redacted]"
+	// hashPrefix marks a hashed value; hashBytes of the HMAC are kept, enough
+	// to make collisions between distinct values negligible in a log.
+	hashPrefix = "hmac:"
+	hashBytes  = 12
+)
+
+// Redactor rewrites SPIFFE IDs and scope values before they leave the
+// process in audit entries and error messages, for deployments whose logs
+// are shipped outside the security boundary. A SPIFFE ID keeps its trust
+// domain, so entries can still be told apart by origin. A nil *Redactor
+// leaves everything unchanged.
+type Redactor struct {
+	ids    string
+	scopes string
+	key    []byte
+}
+
+// NewRedactor returns a Redactor applying idMode to SPIFFE IDs and
+// scopeMode to scope values. key is the HMAC-SHA256 key for RedactHash and
+// must be at least MinRedactionKeySize bytes when either mode hashes. It
+// returns nil when both modes are RedactNone or empty.
+func NewRedactor(idMode, scopeMode string, key []byte) (*Redactor, error) {
+	for _, m := range []string{idMode, scopeMode} {
+		if m != "" && !slices.Contains(RedactionModes, m) {
+			return nil, fmt.Errorf("unknown redaction mode %q (must be one of %v)", m, RedactionModes)
+		}
+	}
+	if idMode == RedactNone {
+		idMode = ""
+	}
+	if scopeMode == RedactNone {
+		scopeMode = ""
+	}
+	if idMode == "" && scopeMode == "" {
+		return nil, nil
+	}
+	if (idMode == RedactHash || scopeMode == RedactHash) && len(key) < MinRedactionKeySize {
+		return nil, fmt.Errorf("redaction hash key must be at least %d bytes, got %d", MinRedactionKeySize, len(key))
+	}
+	return &Redactor{ids: idMode, scopes: scopeMode, key: key}, nil
+}
+
+// ID returns id as it may appear in output. A hashed or redacted SPIFFE ID
+// keeps its spiffe://<trust domain>/ prefix; any other value is replaced
+// whole.
+func (r *Redactor) ID(id string) string {
+	if r == nil || r.ids == "" || id == "" {
+		return id
+	}
+	prefix := ""
+	if rest, ok := strings.CutPrefix(id, "spiffe://"); ok {
+		td, _, _ := strings.Cut(rest, "/")
+		prefix = "spiffe://" + td + "/"
+	}
+	return prefix + r.apply(r.ids, id)
+}
+
+// Scope returns scope as it may appear in output.
+func (r *Redactor) Scope(scope string) string {
+	if r == nil || r.scopes == "" || scope == "" {
+		return scope
+	}
+	return r.apply(r.scopes, scope)
+}
+
+// Scopes returns scopes with Scope applied to each, in the same order.
+func (r *Redactor) Scopes(scopes []string) []string {
+	if r == nil || r.scopes == "" || len(scopes) == 0 {
+		return scopes
+	}
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = r.Scope(s)
+	}
+	return out
+}
+
+// Text rewrites the space-separated words of s that are one of ids or
+// scopes, for free-form messages such as denial reasons and anomaly details
+// that embed them. Words are matched whole, so a scope named "read" does not
+// touch "already".
+func (r *Redactor) Text(s string, ids, scopes []string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	words := strings.Split(s, " ")
+	for i, w := range words {
+		switch {
+		case w == "":
+		case slices.Contains(ids, w):
+			words[i] = r.ID(w)
+		case slices.Contains(scopes, w):
+			words[i] = r.Scope(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (r *Redactor) apply(mode, v string) string {
+	if mode == RedactRemove {
+		return redactedMarker
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(v))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil)[:hashBytes])
+}
+
+// event returns e with its SPIFFE IDs, scopes, and the messages that embed
+// them rewritten by r.
+func (r *Redactor) event(e ExchangeEvent) ExchangeEvent {
+	if r == nil {
+		return e
+	}
+	ids := []string{e.Subject, e.Target}
+	scopes := slices.Concat(e.ScopesRequested, e.ScopesGranted)
+	e.DenialReason = r.Text(e.DenialReason, ids, scopes)
+	e.Subject, e.Target = r.ID(e.Subject), r.ID(e.Target)
+	e.ScopesRequested = r.Scopes(e.ScopesRequested)
+	e.ScopesGranted = r.Scopes(e.ScopesGranted)
+	if strings.HasPrefix(e.Approver, "spiffe://") {
+		e.Approver = r.ID(e.Approver)
+	}
+	return e
+}
+
+// SetRedactor makes the Logger write SPIFFE IDs and scopes as r rewrites
+// them, in exchange, anomaly, and break-glass entries, and hand sinks the
+// rewritten events. Analyzers still see the original values. A nil r
+// disables redaction. It must be called before the Logger is shared between
+// goroutines.
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.redact = r
+}
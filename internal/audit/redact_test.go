@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testRedactionKey = bytes.Repeat([]byte{0x42}, MinRedactionKeySize)
+
+func TestNewRedactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		ids, scopes      string
+		key              []byte
+		wantNil, wantErr bool
+	}{
+		{name: "empty", wantNil: true},
+		{name: "none", ids: RedactNone, scopes: RedactNone, wantNil: true},
+		{name: "redact without key", ids: RedactRemove},
+		{name: "hash", ids: RedactHash, scopes: RedactRemove, key: testRedactionKey},
+		{name: "hash without key", scopes: RedactHash, wantErr: true},
+		{name: "short key", ids: RedactHash, key: []byte("short"), wantErr: true},
+		{name: "unknown mode", ids: "mask", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewRedactor(tc.ids, tc.scopes, tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewRedactor error = %v, want error %t", err, tc.wantErr)
+			}
+			if !tc.wantErr && (r == nil) != tc.wantNil {
+				t.Errorf("NewRedactor = %v, want nil %t", r, tc.wantNil)
+			}
+		})
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	const order = "spiffe://cluster.local/ns/default/sa/order"
+
+	t.Run("nil leaves values unchanged", func(t *testing.T) {
+		var r *Redactor
+		if r.ID(order) != order || r.Scope("read") != "read" || r.Text("a b", []string{"a"}, nil) != "a b" {
+			t.Error("nil Redactor changed a value")
+		}
+	})
+
+	t.Run("hash keeps the trust domain and is stable", func(t *testing.T) {
+		r, _ := NewRedactor(RedactHash, RedactHash, testRedactionKey)
+		got := r.ID(order)
+		if !strings.HasPrefix(got, "spiffe://cluster.local/hmac:") || strings.Contains(got, "order") {
+			t.Errorf("ID = %q", got)
+		}
+		if r.ID(order) != got || r.ID("spiffe://cluster.local/ns/default/sa/payment") == got {
+			t.Error("hash is not a stable, distinct mapping")
+		}
+		other, _ := NewRedactor(RedactHash, RedactNone, bytes.Repeat([]byte{0x43}, MinRedactionKeySize))
+		if other.ID(order) == got {
+			t.Error("hash does not depend on the key")
+		}
+		if s := r.Scope("payments:charge"); !strings.HasPrefix(s, "hmac:") || len(s) != len("hmac:")+2*hashBytes {
+			t.Errorf("Scope = %q", s)
+		}
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		r, _ := NewRedactor(RedactRemove, RedactRemove, nil)
+		if got := r.ID(order); got != "spiffe://cluster.local/[redacted]" {
+			t.Errorf("ID = %q", got)
+		}
+		if got := r.ID("not-a-spiffe-id"); got != "[redacted]" {
+			t.Errorf("ID of a non-SPIFFE value = %q", got)
+		}
+		if got := r.Scopes([]string{"a", "b"}); !slices.Equal(got, []string{"[redacted]", "[redacted]"}) {
+			t.Errorf("Scopes = %v", got)
+		}
+	})
+
+	t.Run("text replaces whole words only", func(t *testing.T) {
+		r, _ := NewRedactor(RedactRemove, RedactRemove, nil)
+		got := r.Text("read by "+order+" already  granted", []string{order}, []string{"read"})
+		want := "[redacted] by spiffe://cluster.local/[redacted] already  granted"
+		if got != want {
+			t.Errorf("Text = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestLogExchangeRedaction(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	var buf bytes.Buffer
+	l := New(&buf)
+	r, _ := NewRedactor(RedactHash, RedactRemove, testRedactionKey)
+	l.SetRedactor(r)
+	analyzer := &recordingAnalyzer{anomalies: []Anomaly{{Kind: AnomalyNewPair, Detail: "first exchange from " + order + " to " + payment}}}
+	l.AddAnalyzer(analyzer)
+	sink := &recordingSink{}
+	l.AddSink(sink)
+
+	l.LogExchange(ExchangeEvent{
+		Subject:         order,
+		Target:          payment,
+		ScopesRequested: []string{"payments:charge"},
+		DenialReason:    "no policy permits " + order + " → " + payment,
+	})
+
+	out := buf.String()
+	for _, raw := range []string{"ns/default", "payments:charge"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("output contains %q:\n%s", raw, out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want exchange + anomaly", len(lines))
+	}
+	var entry, anomaly map[string]any
+	_ = json.Unmarshal([]byte(lines[0]), &entry)
+	_ = json.Unmarshal([]byte(lines[1]), &anomaly)
+	if entry["subject"] != r.ID(order) || anomaly["subject"] != r.ID(order) {
+		t.Errorf("subject = %v, %v; want %v", entry["subject"], anomaly["subject"], r.ID(order))
+	}
+	if want := "no policy permits " + r.ID(order) + " → " + r.ID(payment); entry["denial_reason"] != want {
+		t.Errorf("denial_reason = %v, want %v", entry["denial_reason"], want)
+	}
+	if len(analyzer.events) != 1 || analyzer.events[0].Subject != order {
+		t.Errorf("analyzer saw %+v, want the original event", analyzer.events)
+	}
+	if len(sink.events) != 1 || sink.events[0].Subject != r.ID(order) || sink.events[0].ScopesRequested[0] != redactedMarker {
+		t.Errorf("sink events = %+v, want the redacted event", sink.events)
+	}
+}
+
+// recordingAnalyzer keeps every event it is given and reports anomalies for
+// each.
+type recordingAnalyzer struct {
+	anomalies []Anomaly
+	events    []ExchangeEvent
+}
+
+func (r *recordingAnalyzer) Analyze(e ExchangeEvent) []Anomaly {
+	r.events = append(r.events, e)
+	return r.anomalies
+}
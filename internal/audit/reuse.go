@@ -0,0 +1,34 @@
+package audit
+
+import "time"
+
+// TokenReuseEvent is the payload for a "token.reuse" audit log entry: a
+// token presented to ext_authz from a source other than the one it was first
+// presented from.
+type TokenReuseEvent struct {
+	JTI       string
+	Subject   string
+	Audience  string
+	ExpiresAt time.Time
+	// FirstSource and Source are the SPIFFE IDs or IP addresses the token
+	// was first and is now presented from.
+	FirstSource string
+	Source      string
+	// Revoked reports whether the token was revoked in response.
+	Revoked bool
+}
+
+// LogTokenReuse emits one "token.reuse" audit log line, at error level
+// unless SetLevel overrides it, since a reused token has most likely leaked.
+func (l *Logger) LogTokenReuse(e TokenReuseEvent) {
+	l.log.WithLevel(l.levels[KindTokenReuse]).
+		Str("event", "token.reuse").
+		Str("jti", e.JTI).
+		Str("subject", l.redact.ID(e.Subject)).
+		Str("audience", l.redact.ID(e.Audience)).
+		Time("expires_at", e.ExpiresAt).
+		Str("first_source", l.redact.ID(e.FirstSource)).
+		Str("source", l.redact.ID(e.Source)).
+		Bool("revoked", e.Revoked).
+		Send()
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLogTokenReuse(t *testing.T) {
+	exp := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.LogTokenReuse(TokenReuseEvent{JTI: "tok-1", Subject: "spiffe://td/order", Audience: "spiffe://td/payment", ExpiresAt: exp,
+		FirstSource: "spiffe://td/order", Source: "10.0.0.7", Revoked: true})
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	want := map[string]any{"level": "error", "event": "token.reuse", "jti": "tok-1", "subject": "spiffe://td/order",
+		"audience": "spiffe://td/payment", "expires_at": exp.Format(time.RFC3339), "first_source": "spiffe://td/order",
+		"source": "10.0.0.7", "revoked": true}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
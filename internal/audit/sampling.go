@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/rs/zerolog"
+)
+
+// Event kinds whose severity can be set with SetLevel.
+const (
+	// KindGrant is a "token.exchange" entry for a granted exchange.
+	KindGrant = "grant"
+	// KindDenial is a "token.exchange" entry for a denied exchange.
+	KindDenial = "denial"
+	// KindAnomaly is a "token.exchange.anomaly" entry.
+	KindAnomaly = "anomaly"
+	// KindBreakGlass is a "break_glass" entry.
+	KindBreakGlass = "break_glass"
+	// KindTokenReuse is a "token.reuse" entry.
+	KindTokenReuse = "token_reuse"
+)
+
+// EventKinds lists every kind accepted by SetLevel.
+var EventKinds = []string{KindGrant, KindDenial, KindAnomaly, KindBreakGlass, KindTokenReuse}
+
+// defaultLevels are the severities entries are written at unless SetLevel
+// overrides them.
+var defaultLevels = map[string]zerolog.Level{
+	KindGrant:      zerolog.InfoLevel,
+	KindDenial:     zerolog.InfoLevel,
+	KindAnomaly:    zerolog.WarnLevel,
+	KindBreakGlass: zerolog.ErrorLevel,
+	KindTokenReuse: zerolog.ErrorLevel,
+}
+
+// SetLevel sets the severity entries of kind are written at. Only debug,
+// info, warn, and error are accepted. It must be called before the Logger is
+// shared between goroutines.
+func (l *Logger) SetLevel(kind string, level zerolog.Level) error {
+	if _, ok := defaultLevels[kind]; !ok {
+		return fmt.Errorf("unknown audit event kind %q (must be one of %v)", kind, EventKinds)
+	}
+	if level < zerolog.DebugLevel || level > zerolog.ErrorLevel {
+		return fmt.Errorf("audit level for %s must be debug, info, warn, or error, got %q", kind, level)
+	}
+	l.levels[kind] = level
+	return nil
+}
+
+// SetSampling makes the Logger write only a grantRate fraction of grants,
+// chosen at random, and record each written one with its "sample_rate" so
+// totals can be estimated from the log alone. Denials and anomalies are
+// always written, and analyzers and sinks still see every event. observe,
+// when non-nil, is told for every event whether it was written, so the exact
+// totals can be kept elsewhere. grantRate must be in [0, 1]. It must be
+// called before the Logger is shared between goroutines.
+func (l *Logger) SetSampling(grantRate float64, observe func(e ExchangeEvent, written bool)) {
+	l.grantRate = grantRate
+	l.observe = observe
+}
+
+// sampled reports whether e is written.
+func (l *Logger) sampled(e ExchangeEvent) bool {
+	if !e.Granted || l.grantRate >= 1 {
+		return true
+	}
+	return l.sample() < l.grantRate
+}
+
+// defaultSample draws the random number grants are sampled by.
+func defaultSample() float64 {
+	return rand.Float64()
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// countingSink counts delivered events.
+type countingSink struct{ n int }
+
+func (c *countingSink) Deliver(ExchangeEvent) { c.n++ }
+
+func TestSampling(t *testing.T) {
+	grant := ExchangeEvent{Subject: "spiffe://td/order", Target: "spiffe://td/payment", Granted: true}
+	denial := ExchangeEvent{Subject: "spiffe://td/order", Target: "spiffe://td/admin", DenialReason: "no policy"}
+
+	var buf bytes.Buffer
+	l := New(&buf)
+	written, dropped := 0, 0
+	l.SetSampling(0.25, func(_ ExchangeEvent, w bool) {
+		if w {
+			written++
+		} else {
+			dropped++
+		}
+	})
+	draws := []float64{0.1, 0.5, 0.9, 0.3}
+	l.sample = func() float64 {
+		d := draws[0]
+		draws = draws[1:]
+		return d
+	}
+	sink := &countingSink{}
+	l.AddSink(sink)
+
+	for range 4 {
+		l.LogExchange(grant)
+	}
+	l.LogExchange(denial)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want the sampled grant and the denial:\n%s", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if entry["granted"] != true || entry["sample_rate"] != 0.25 {
+		t.Errorf("grant entry = %v, want sample_rate 0.25", entry)
+	}
+	if strings.Contains(lines[1], "sample_rate") {
+		t.Errorf("denial entry has a sample rate: %s", lines[1])
+	}
+	if written != 2 || dropped != 3 {
+		t.Errorf("observed written %d, dropped %d; want 2 and 3", written, dropped)
+	}
+	if sink.n != 5 {
+		t.Errorf("sink saw %d events, want every one", sink.n)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Run("levels apply per kind", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf)
+		if err := l.SetLevel(KindDenial, zerolog.WarnLevel); err != nil {
+			t.Fatalf("SetLevel: %v", err)
+		}
+		l.LogExchange(ExchangeEvent{Granted: true})
+		l.LogExchange(ExchangeEvent{DenialReason: "no policy"})
+
+		var levels []string
+		for line := range strings.Lines(buf.String()) {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			levels = append(levels, entry["level"].(string))
+		}
+		if len(levels) != 2 || levels[0] != "info" || levels[1] != "warn" {
+			t.Errorf("levels = %v, want [info warn]", levels)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		l := New(&bytes.Buffer{})
+		if err := l.SetLevel("revocation", zerolog.InfoLevel); err == nil {
+			t.Error("unknown kind accepted")
+		}
+		if err := l.SetLevel(KindGrant, zerolog.FatalLevel); err == nil {
+			t.Error("fatal level accepted")
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	auditv1 "github.com/ngaddam369/svid-exchange/proto/audit/v1"
+)
+
+// Spool file names within the spool directory. The spool holds events in
+// the ProtoSink framing; the offset file records how far of it has been
+// shipped.
+const (
+	spoolFile       = "audit.spool"
+	spoolOffsetFile = "audit.spool.offset"
+)
+
+// spoolCompactSize is the spool size past which a fully shipped spool is
+// truncated.
+const spoolCompactSize = 1 << 20
+
+// spoolReadSize is how much of the spool Ship reads at a time, so that a
+// backlog built up during a sink outage is never read into memory at once.
+// A record larger than it is read whole.
+const spoolReadSize = 1 << 20
+
+// RetryableSink is a Sink that reports delivery failures, so a Spool can
+// retry an event until it is delivered.
+type RetryableSink interface {
+	Sink
+	Ship(e ExchangeEvent) error
+}
+
+// Spool is a local append-only file of exchange events awaiting delivery to
+// sinks. Append syncs each event to disk before returning, and Ship delivers
+// spooled events in order, retrying RetryableSinks until they accept each
+// one, and records its progress in an offset file. Events appended but not
+// yet recorded as shipped when the process stops are shipped again on the
+// next start, so delivery is at least once.
+type Spool struct {
+	mu     sync.Mutex
+	f      *os.File
+	dir    string
+	size   int64 // bytes of complete records
+	offset int64 // bytes already shipped
+	notify chan struct{}
+	// shipped is closed, and replaced, whenever offset advances.
+	shipped chan struct{}
+	log     zerolog.Logger
+	now     func() time.Time
+
+	// backoff is the delay before the first retry of a failed delivery; it
+	// doubles per attempt up to maxBackoff.
+	backoff, maxBackoff time.Duration
+	// readSize is spoolReadSize, overridden by tests.
+	readSize int64
+}
+
+// OpenSpool opens or creates the spool in dir. A record left incomplete by
+// a crash during Append is discarded. log receives delivery failures.
+func OpenSpool(dir string, log zerolog.Logger) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create audit spool directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, spoolFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit spool: %w", err)
+	}
+	s := &Spool{
+		f:          f,
+		dir:        dir,
+		notify:     make(chan struct{}, 1),
+		shipped:    make(chan struct{}),
+		log:        log,
+		now:        time.Now,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		readSize:   spoolReadSize,
+	}
+	if s.offset, err = s.readOffset(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	size, boundary, err := s.scan()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.size = size
+	if s.offset > s.size || !boundary {
+		log.Warn().Int64("offset", s.offset).Msg("audit spool offset does not match the spool; shipping it from the start")
+		s.offset = 0
+	}
+	return s, nil
+}
+
+// Append writes e to the spool and syncs it to disk.
+func (s *Spool) Append(e ExchangeEvent) error {
+	at := e.Time
+	if at.IsZero() {
+		at = s.now()
+	}
+	buf, err := marshalDelimited(ToProto(e, at))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.WriteAt(buf, s.size); err != nil {
+		return s.discard(fmt.Errorf("write audit spool: %w", err))
+	}
+	if err := s.f.Sync(); err != nil {
+		return s.discard(fmt.Errorf("sync audit spool: %w", err))
+	}
+	s.size += int64(len(buf))
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// discard truncates a partly written record after a failed Append and
+// returns err. Must be called with s.mu held.
+func (s *Spool) discard(err error) error {
+	if terr := s.f.Truncate(s.size); terr != nil {
+		return errors.Join(err, fmt.Errorf("truncate audit spool: %w", terr))
+	}
+	return err
+}
+
+// Flush blocks until every event appended so far has been shipped, or ctx
+// is done. Ship must be running for it to return nil.
+func (s *Spool) Flush(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		pending, shipped := s.size-s.offset, s.shipped
+		s.mu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-shipped:
+		case <-ctx.Done():
+			return fmt.Errorf("%d bytes of audit events not shipped: %w", pending, ctx.Err())
+		}
+	}
+}
+
+// Ship delivers spooled events to sinks until ctx is cancelled, waiting for
+// Append when it has caught up. An event is delivered to every sink before
+// the next; a RetryableSink that fails is retried with exponential backoff,
+// holding back the events after it.
+func (s *Spool) Ship(ctx context.Context, sinks []Sink) {
+	for {
+		s.mu.Lock()
+		off, end := s.offset, s.size
+		s.mu.Unlock()
+		if off == end {
+			if err := s.compact(); err != nil {
+				s.log.Error().Err(err).Msg("compact audit spool")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.notify:
+				continue
+			}
+		}
+
+		buf, err := s.readChunk(off, end)
+		if err != nil {
+			s.log.Error().Err(err).Msg("read audit spool")
+			if !s.sleep(ctx, s.maxBackoff) {
+				return
+			}
+			continue
+		}
+		for start := off; len(buf) > 0; {
+			e, n, err := decodeRecord(buf)
+			if errors.Is(err, io.ErrUnexpectedEOF) && off > start {
+				// The chunk ends inside this record; the next one starts
+				// with it.
+				break
+			}
+			if err != nil {
+				// Records are validated on open and only appended whole, so
+				// this is corruption; skip the rest rather than stall.
+				s.log.Error().Err(err).Int64("offset", off).Msg("skipping corrupt audit spool records")
+				off = end
+				break
+			}
+			if !s.deliver(ctx, sinks, e) {
+				return
+			}
+			buf = buf[n:]
+			off += int64(n)
+		}
+		if err := s.setOffset(off); err != nil {
+			s.log.Error().Err(err).Msg("record audit spool offset")
+		}
+	}
+}
+
+// readChunk reads the spool from off, up to end, in at most s.readSize
+// bytes unless the record at off is larger, in which case it reads that
+// record whole.
+func (s *Spool) readChunk(off, end int64) ([]byte, error) {
+	buf := make([]byte, min(end-off, s.readSize))
+	if _, err := s.f.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	size, n := protowire.ConsumeVarint(buf)
+	if n < 0 || size > uint64(end-off) {
+		return buf, nil
+	}
+	if whole := int64(n) + int64(size); whole > int64(len(buf)) && whole <= end-off {
+		buf = make([]byte, whole)
+		if _, err := s.f.ReadAt(buf, off); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// deliver hands e to every sink and reports false if ctx was cancelled
+// before each RetryableSink accepted it.
+func (s *Spool) deliver(ctx context.Context, sinks []Sink, e ExchangeEvent) bool {
+	for _, sink := range sinks {
+		r, ok := sink.(RetryableSink)
+		if !ok {
+			sink.Deliver(e)
+			continue
+		}
+		delay := s.backoff
+		for {
+			err := r.Ship(e)
+			if err == nil {
+				break
+			}
+			s.log.Error().Err(err).Str("request_id", e.RequestID).Dur("retry_in", delay).Msg("audit sink delivery failed")
+			if !s.sleep(ctx, delay) {
+				return false
+			}
+			delay = min(2*delay, s.maxBackoff)
+		}
+	}
+	return true
+}
+
+// sleep waits for d and reports false if ctx was cancelled first.
+func (s *Spool) sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// setOffset records that the spool has been shipped up to off.
+func (s *Spool) setOffset(off int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = off
+	close(s.shipped)
+	s.shipped = make(chan struct{})
+	return s.writeOffset()
+}
+
+// compact truncates the spool once everything in it has been shipped and
+// it has grown past spoolCompactSize.
+func (s *Spool) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offset != s.size || s.size < spoolCompactSize {
+		return nil
+	}
+	// Reset the offset first: a crash between the two writes then ships
+	// the old spool again rather than skipping new events.
+	s.offset = 0
+	if err := s.writeOffset(); err != nil {
+		return err
+	}
+	if err := s.f.Truncate(0); err != nil {
+		return err
+	}
+	s.size = 0
+	return nil
+}
+
+// writeOffset replaces the offset file with s.offset. Must be called with
+// s.mu held.
+func (s *Spool) writeOffset() error {
+	path := filepath.Join(s.dir, spoolOffsetFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(s.offset, 10)+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readOffset reads the offset file, returning 0 when there is none.
+func (s *Spool) readOffset() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, spoolOffsetFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read audit spool offset: %w", err)
+	}
+	off, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || off < 0 {
+		s.log.Warn().Str("offset", string(data)).Msg("invalid audit spool offset; shipping the spool from the start")
+		return 0, nil
+	}
+	return off, nil
+}
+
+// scan reads the spool one record at a time and returns the length of the
+// complete records at its start, truncating any incomplete record left by a
+// crash during Append. boundary reports whether s.offset falls on a record
+// boundary.
+func (s *Spool) scan() (size int64, boundary bool, err error) {
+	fi, err := s.f.Stat()
+	if err != nil {
+		return 0, false, fmt.Errorf("read audit spool: %w", err)
+	}
+	end := fi.Size()
+	boundary = s.offset == 0
+	for size < end {
+		buf, err := s.readChunk(size, end)
+		if err != nil {
+			return 0, false, fmt.Errorf("read audit spool: %w", err)
+		}
+		for start := size; len(buf) > 0; {
+			_, n, err := decodeRecord(buf)
+			if errors.Is(err, io.ErrUnexpectedEOF) && size > start {
+				break
+			}
+			if err != nil {
+				s.log.Warn().Err(err).Int64("offset", size).Msg("discarding incomplete audit spool record")
+				if err := s.f.Truncate(size); err != nil {
+					return 0, false, fmt.Errorf("truncate audit spool: %w", err)
+				}
+				return size, boundary, nil
+			}
+			buf = buf[n:]
+			size += int64(n)
+			boundary = boundary || size == s.offset
+		}
+	}
+	return size, boundary, nil
+}
+
+// decodeRecord decodes the length-prefixed event at the start of buf and
+// returns it with the number of bytes it took.
+func decodeRecord(buf []byte) (ExchangeEvent, int, error) {
+	size, n := protowire.ConsumeVarint(buf)
+	if n < 0 {
+		return ExchangeEvent{}, 0, fmt.Errorf("decode audit spool record length: %w", protowire.ParseError(n))
+	}
+	if uint64(len(buf)-n) < size {
+		return ExchangeEvent{}, 0, io.ErrUnexpectedEOF
+	}
+	var msg auditv1.ExchangeEvent
+	if err := proto.Unmarshal(buf[n:n+int(size)], &msg); err != nil {
+		return ExchangeEvent{}, 0, fmt.Errorf("decode audit spool record: %w", err)
+	}
+	return FromProto(&msg), n + int(size), nil
+}
@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// flakySink is a RetryableSink that fails its first fails deliveries.
+type flakySink struct {
+	mu     sync.Mutex
+	fails  int
+	events []ExchangeEvent
+	got    chan struct{}
+}
+
+func newFlakySink(fails int) *flakySink {
+	return &flakySink{fails: fails, got: make(chan struct{}, 100)}
+}
+
+func (s *flakySink) Deliver(e ExchangeEvent) { _ = s.Ship(e) }
+
+func (s *flakySink) Ship(e ExchangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("disk full")
+	}
+	s.events = append(s.events, e)
+	s.got <- struct{}{}
+	return nil
+}
+
+func (s *flakySink) requestIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, e := range s.events {
+		ids = append(ids, e.RequestID)
+	}
+	return ids
+}
+
+// shipUntil ships sp to sink until it has received n more events.
+func shipUntil(t *testing.T, sp *Spool, sink *flakySink, n int) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sp.Ship(ctx, []Sink{sink})
+		close(done)
+	}()
+	for range n {
+		select {
+		case <-sink.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out; delivered %v", sink.requestIDs())
+		}
+	}
+	cancel()
+	<-done
+}
+
+func openTestSpool(t *testing.T, dir string) *Spool {
+	t.Helper()
+	sp, err := OpenSpool(dir, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("OpenSpool: %v", err)
+	}
+	sp.backoff = time.Millisecond
+	t.Cleanup(func() { sp.f.Close() })
+	return sp
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ships in order with retries", func(t *testing.T) {
+		sp := openTestSpool(t, dir)
+		sp.now = func() time.Time { return at }
+		for _, id := range []string{"req-1", "req-2"} {
+			if err := sp.Append(ExchangeEvent{RequestID: id, Subject: "spiffe://td/a", Granted: true, TTL: 60}); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+		sink := newFlakySink(3)
+		shipUntil(t, sp, sink, 2)
+		if ids := sink.requestIDs(); len(ids) != 2 || ids[0] != "req-1" || ids[1] != "req-2" {
+			t.Fatalf("delivered %v, want [req-1 req-2]", ids)
+		}
+		if e := sink.events[0]; !e.Time.Equal(at) || e.Subject != "spiffe://td/a" || !e.Granted || e.TTL != 60 {
+			t.Errorf("event = %+v", e)
+		}
+		// The third event is spooled but never shipped before the restart.
+		if err := sp.Append(ExchangeEvent{RequestID: "req-3"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	})
+
+	t.Run("restart ships what is left", func(t *testing.T) {
+		// A crash mid-Append leaves a partial record behind.
+		f, err := os.OpenFile(filepath.Join(dir, spoolFile), os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte{0x20, 0x0a}); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		sp := openTestSpool(t, dir)
+		if err := sp.Append(ExchangeEvent{RequestID: "req-4"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		sink := newFlakySink(0)
+		shipUntil(t, sp, sink, 2)
+		if ids := sink.requestIDs(); len(ids) != 2 || ids[0] != "req-3" || ids[1] != "req-4" {
+			t.Errorf("delivered %v, want [req-3 req-4]", ids)
+		}
+	})
+
+	t.Run("compacts when shipped", func(t *testing.T) {
+		sp := openTestSpool(t, dir)
+		sp.size, sp.offset = spoolCompactSize, spoolCompactSize
+		if err := sp.compact(); err != nil {
+			t.Fatalf("compact: %v", err)
+		}
+		fi, err := sp.f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != 0 || sp.offset != 0 {
+			t.Errorf("after compact: size %d, offset %d; want 0, 0", fi.Size(), sp.offset)
+		}
+	})
+}
+
+func TestSpoolChunks(t *testing.T) {
+	sp := openTestSpool(t, t.TempDir())
+	sp.readSize = 64
+	ids := []string{"req-1", "req-2", strings.Repeat("long-", 40), "req-4", "req-5"}
+	for _, id := range ids {
+		if err := sp.Append(ExchangeEvent{RequestID: id, Subject: "spiffe://td/a"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	size, boundary, err := sp.scan()
+	if err != nil || size != sp.size || !boundary {
+		t.Errorf("scan = %d, %t, %v; want %d, true", size, boundary, err, sp.size)
+	}
+
+	sink := newFlakySink(0)
+	shipUntil(t, sp, sink, len(ids))
+	if got := sink.requestIDs(); !slices.Equal(got, ids) {
+		t.Errorf("delivered %v, want %v", got, ids)
+	}
+	if sp.offset != sp.size {
+		t.Errorf("offset = %d, want %d", sp.offset, sp.size)
+	}
+}
+
+func TestLoggerSpool(t *testing.T) {
+	sp := openTestSpool(t, t.TempDir())
+	sink := newFlakySink(0)
+	l := New(io.Discard)
+	l.AddSink(sink)
+	l.SetSpool(sp)
+
+	if err := l.RecordExchange(ExchangeEvent{RequestID: "req-1", Granted: true}); err != nil {
+		t.Fatalf("RecordExchange: %v", err)
+	}
+	if len(sink.requestIDs()) != 0 {
+		t.Error("spooled event delivered before shipping")
+	}
+	shipUntil(t, sp, sink, 1)
+
+	// With the spool unwritable the event still reaches the sink.
+	sp.f.Close()
+	if err := l.RecordExchange(ExchangeEvent{RequestID: "req-2"}); err == nil {
+		t.Error("RecordExchange succeeded on a closed spool")
+	}
+	if ids := sink.requestIDs(); len(ids) != 2 || ids[1] != "req-2" {
+		t.Errorf("delivered %v, want [req-1 req-2]", ids)
+	}
+}
+
+func TestSpoolFlush(t *testing.T) {
+	sp := openTestSpool(t, t.TempDir())
+	if err := sp.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush of an empty spool: %v", err)
+	}
+	if err := sp.Append(ExchangeEvent{RequestID: "req-1"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// Nothing ships while the sink fails.
+	sink := newFlakySink(1000)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go sp.Ship(ctx, []Sink{sink})
+	timeout, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sp.Flush(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush with a failing sink = %v, want DeadlineExceeded", err)
+	}
+
+	sink.mu.Lock()
+	sink.fails = 0
+	sink.mu.Unlock()
+	if err := sp.Flush(context.Background()); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	if ids := sink.requestIDs(); len(ids) != 1 {
+		t.Errorf("delivered %v, want [req-1]", ids)
+	}
+}
@@ -0,0 +1,244 @@
+package audit
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatsBucket is the granularity of IssuanceStats: events are counted per
+// hour, so a report's window is rounded out to whole hours.
+const StatsBucket = time.Hour
+
+// TTLBounds are the upper bounds, inclusive, of the TTL distribution in an
+// IssuanceReport. Grants with longer TTLs fall in a final, unbounded bucket.
+var TTLBounds = []int32{60, 300, 900, 1800, 3600, 4 * 3600, 24 * 3600}
+
+// IssuanceStats is a Sink that counts grants and denials per subject→target
+// pair, granted scopes, and granted TTLs for issuance reports. Counts are
+// kept in memory in hourly buckets for the retention period and are lost on
+// restart. Preflights issue nothing and are not counted. Within a bucket at
+// most maxPairs pairs are tracked; events for further pairs still count
+// toward scopes and TTLs and are reported as Overflow.
+type IssuanceStats struct {
+	mu        sync.Mutex
+	retention time.Duration
+	buckets   []*statsBucket // oldest first
+	maxPairs  int
+	now       func() time.Time
+}
+
+type statsBucket struct {
+	start    time.Time
+	pairs    map[statsPair]*PairStats
+	scopes   map[string]int64
+	ttls     []int64 // len(TTLBounds)+1
+	overflow int64
+}
+
+type statsPair struct{ subject, target string }
+
+// NewIssuanceStats returns an IssuanceStats that keeps counts for retention,
+// which must be positive.
+func NewIssuanceStats(retention time.Duration) *IssuanceStats {
+	return &IssuanceStats{retention: retention, maxPairs: 10_000, now: time.Now}
+}
+
+// Retention returns how far back a report can look.
+func (s *IssuanceStats) Retention() time.Duration { return s.retention }
+
+// Deliver implements Sink.
+func (s *IssuanceStats) Deliver(e ExchangeEvent) {
+	if e.Preflight {
+		return
+	}
+	start := s.now().Truncate(StatsBucket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b *statsBucket
+	if n := len(s.buckets); n > 0 && s.buckets[n-1].start.Equal(start) {
+		b = s.buckets[n-1]
+	} else {
+		b = &statsBucket{
+			start:  start,
+			pairs:  make(map[statsPair]*PairStats),
+			scopes: make(map[string]int64),
+			ttls:   make([]int64, len(TTLBounds)+1),
+		}
+		s.buckets = append(s.buckets, b)
+		s.expire(start)
+	}
+
+	key := statsPair{e.Subject, e.Target}
+	ps, ok := b.pairs[key]
+	if !ok && len(b.pairs) < s.maxPairs {
+		ps = &PairStats{Subject: e.Subject, Target: e.Target}
+		b.pairs[key] = ps
+	}
+	if ps == nil {
+		b.overflow += 1 + int64(e.SuppressedDenials)
+	}
+	if !e.Granted {
+		if ps != nil {
+			ps.Denials += 1 + int64(e.SuppressedDenials)
+		}
+		return
+	}
+	if ps != nil {
+		ps.Grants++
+	}
+	for _, sc := range e.ScopesGranted {
+		b.scopes[sc]++
+	}
+	i, _ := slices.BinarySearch(TTLBounds, e.TTL)
+	b.ttls[i]++
+}
+
+// expire drops buckets older than the retention period. Must be called with
+// s.mu held.
+func (s *IssuanceStats) expire(now time.Time) {
+	cutoff := now.Add(-s.retention)
+	i := 0
+	for i < len(s.buckets) && !s.buckets[i].start.After(cutoff) {
+		i++
+	}
+	s.buckets = slices.Delete(s.buckets, 0, i)
+}
+
+// Report summarizes the buckets overlapping the last window, listing at most
+// topScopes scopes. A window that is zero or longer than the retention
+// period reports the whole retention period.
+func (s *IssuanceStats) Report(window time.Duration, topScopes int) IssuanceReport {
+	now := s.now()
+	if window <= 0 || window > s.retention {
+		window = s.retention
+	}
+	from := now.Add(-window).Truncate(StatsBucket)
+	r := IssuanceReport{Start: from, End: now, TTLs: make([]TTLCount, len(TTLBounds)+1)}
+	for i := range r.TTLs {
+		if i < len(TTLBounds) {
+			r.TTLs[i].MaxSeconds = TTLBounds[i]
+		}
+	}
+
+	pairs := make(map[statsPair]*PairStats)
+	scopes := make(map[string]int64)
+	s.mu.Lock()
+	for _, b := range s.buckets {
+		if b.start.Before(from) {
+			continue
+		}
+		for k, ps := range b.pairs {
+			acc, ok := pairs[k]
+			if !ok {
+				acc = &PairStats{Subject: ps.Subject, Target: ps.Target}
+				pairs[k] = acc
+			}
+			acc.Grants += ps.Grants
+			acc.Denials += ps.Denials
+		}
+		for sc, n := range b.scopes {
+			scopes[sc] += n
+		}
+		for i, n := range b.ttls {
+			r.TTLs[i].Grants += n
+		}
+		r.Overflow += b.overflow
+	}
+	s.mu.Unlock()
+
+	for _, ps := range pairs {
+		r.Pairs = append(r.Pairs, *ps)
+	}
+	slices.SortFunc(r.Pairs, func(a, b PairStats) int {
+		return cmp.Or(
+			cmp.Compare(b.Grants+b.Denials, a.Grants+a.Denials),
+			cmp.Compare(a.Subject, b.Subject),
+			cmp.Compare(a.Target, b.Target),
+		)
+	})
+	for sc, n := range scopes {
+		r.TopScopes = append(r.TopScopes, ScopeCount{Scope: sc, Grants: n})
+	}
+	slices.SortFunc(r.TopScopes, func(a, b ScopeCount) int {
+		return cmp.Or(cmp.Compare(b.Grants, a.Grants), cmp.Compare(a.Scope, b.Scope))
+	})
+	if len(r.TopScopes) > topScopes {
+		r.TopScopes = r.TopScopes[:topScopes]
+	}
+	return r
+}
+
+// IssuanceReport summarizes token issuance between Start and End.
+type IssuanceReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Pairs lists every subject→target pair with an exchange in the window,
+	// busiest first.
+	Pairs []PairStats `json:"pairs"`
+	// TopScopes lists the most granted scopes, most granted first.
+	TopScopes []ScopeCount `json:"top_scopes"`
+	// TTLs counts grants by TTL, one entry per TTLBounds and a final
+	// unbounded one.
+	TTLs []TTLCount `json:"ttl_distribution"`
+	// Overflow counts exchanges not attributed to any pair because the pair
+	// limit was reached.
+	Overflow int64 `json:"overflow,omitempty"`
+}
+
+// PairStats counts the exchanges of one subject→target pair. Denials include
+// those suppressed by the denial cache.
+type PairStats struct {
+	Subject string `json:"subject"`
+	Target  string `json:"target"`
+	Grants  int64  `json:"grants"`
+	Denials int64  `json:"denials"`
+}
+
+// ScopeCount counts the grants that included a scope.
+type ScopeCount struct {
+	Scope  string `json:"scope"`
+	Grants int64  `json:"grants"`
+}
+
+// TTLCount counts the grants whose TTL is at most MaxSeconds and above the
+// previous bucket's. MaxSeconds is zero for the unbounded bucket.
+type TTLCount struct {
+	MaxSeconds int32 `json:"max_seconds"`
+	Grants     int64 `json:"grants"`
+}
+
+// WriteJSON writes r as indented JSON.
+func (r IssuanceReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes r as one CSV table whose section column is "pair",
+// "scope", or "ttl"; each row fills only the columns of its section.
+func (r IssuanceReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"section", "subject", "target", "scope", "ttl_max_seconds", "grants", "denials"})
+	for _, p := range r.Pairs {
+		_ = cw.Write([]string{"pair", p.Subject, p.Target, "", "", strconv.FormatInt(p.Grants, 10), strconv.FormatInt(p.Denials, 10)})
+	}
+	for _, sc := range r.TopScopes {
+		_ = cw.Write([]string{"scope", "", "", sc.Scope, "", strconv.FormatInt(sc.Grants, 10), ""})
+	}
+	for _, t := range r.TTLs {
+		bound := ""
+		if t.MaxSeconds > 0 {
+			bound = strconv.FormatInt(int64(t.MaxSeconds), 10)
+		}
+		_ = cw.Write([]string{"ttl", "", "", "", bound, strconv.FormatInt(t.Grants, 10), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssuanceStats(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+		ledger  = "spiffe://cluster.local/ns/default/sa/ledger"
+	)
+	now := time.Date(2026, 1, 2, 12, 30, 0, 0, time.UTC)
+	s := NewIssuanceStats(24 * time.Hour)
+	s.now = func() time.Time { return now }
+
+	// Two hours ago: one grant that a one-hour report leaves out.
+	now = now.Add(-2 * time.Hour)
+	s.Deliver(ExchangeEvent{Subject: order, Target: ledger, Granted: true, ScopesGranted: []string{"ledger:read"}, TTL: 3600})
+	now = now.Add(2 * time.Hour)
+
+	s.Deliver(ExchangeEvent{Subject: order, Target: payment, Granted: true, ScopesGranted: []string{"payments:charge", "payments:read"}, TTL: 60})
+	s.Deliver(ExchangeEvent{Subject: order, Target: payment, Granted: true, ScopesGranted: []string{"payments:read"}, TTL: 300})
+	s.Deliver(ExchangeEvent{Subject: order, Target: payment, Granted: true, ScopesGranted: []string{"payments:read"}, TTL: 90000})
+	s.Deliver(ExchangeEvent{Subject: ledger, Target: payment, Granted: false, SuppressedDenials: 2})
+	s.Deliver(ExchangeEvent{Subject: ledger, Target: payment, Granted: true, ScopesGranted: []string{"payments:read"}, TTL: 60, Preflight: true})
+
+	t.Run("window", func(t *testing.T) {
+		r := s.Report(time.Hour, 10)
+		if want := time.Date(2026, 1, 2, 11, 0, 0, 0, time.UTC); !r.Start.Equal(want) || !r.End.Equal(now) {
+			t.Errorf("report covers %v–%v, want %v–%v", r.Start, r.End, want, now)
+		}
+		wantPairs := []PairStats{
+			{Subject: ledger, Target: payment, Denials: 3},
+			{Subject: order, Target: payment, Grants: 3},
+		}
+		if len(r.Pairs) != len(wantPairs) {
+			t.Fatalf("pairs = %+v, want %+v", r.Pairs, wantPairs)
+		}
+		for i, p := range r.Pairs {
+			if p != wantPairs[i] {
+				t.Errorf("pairs[%d] = %+v, want %+v", i, p, wantPairs[i])
+			}
+		}
+		if len(r.TopScopes) != 2 || r.TopScopes[0] != (ScopeCount{"payments:read", 3}) || r.TopScopes[1] != (ScopeCount{"payments:charge", 1}) {
+			t.Errorf("top scopes = %+v", r.TopScopes)
+		}
+		wantTTLs := map[int32]int64{60: 1, 300: 1, 0: 1}
+		for _, c := range r.TTLs {
+			if c.Grants != wantTTLs[c.MaxSeconds] {
+				t.Errorf("TTL bucket %d = %d grants, want %d", c.MaxSeconds, c.Grants, wantTTLs[c.MaxSeconds])
+			}
+		}
+	})
+
+	t.Run("whole retention", func(t *testing.T) {
+		r := s.Report(0, 1)
+		if len(r.Pairs) != 3 {
+			t.Errorf("pairs = %+v, want 3", r.Pairs)
+		}
+		if len(r.TopScopes) != 1 || r.TopScopes[0].Scope != "payments:read" {
+			t.Errorf("top scopes = %+v, want payments:read only", r.TopScopes)
+		}
+	})
+
+	t.Run("expired buckets are dropped", func(t *testing.T) {
+		now = now.Add(23 * time.Hour)
+		s.Deliver(ExchangeEvent{Subject: order, Target: payment, Granted: true, TTL: 60})
+		r := s.Report(0, 10)
+		for _, p := range r.Pairs {
+			if p.Target == ledger {
+				t.Errorf("pair %+v outlived the retention period", p)
+			}
+		}
+	})
+}
+
+func TestIssuanceStatsPairLimit(t *testing.T) {
+	s := NewIssuanceStats(time.Hour)
+	s.maxPairs = 1
+	s.Deliver(ExchangeEvent{Subject: "a", Target: "b", Granted: true, TTL: 60})
+	s.Deliver(ExchangeEvent{Subject: "c", Target: "d", Granted: true, TTL: 60})
+	r := s.Report(time.Hour, 10)
+	if len(r.Pairs) != 1 || r.Overflow != 1 {
+		t.Errorf("pairs = %+v, overflow = %d; want one pair and overflow 1", r.Pairs, r.Overflow)
+	}
+	if r.TTLs[0].Grants != 2 {
+		t.Errorf("TTL bucket 60 = %d grants, want 2", r.TTLs[0].Grants)
+	}
+}
+
+func TestIssuanceReportExport(t *testing.T) {
+	r := IssuanceReport{
+		Start:     time.Unix(0, 0).UTC(),
+		End:       time.Unix(3600, 0).UTC(),
+		Pairs:     []PairStats{{Subject: "spiffe://td/a", Target: "spiffe://td/b", Grants: 2, Denials: 1}},
+		TopScopes: []ScopeCount{{Scope: "read", Grants: 2}},
+		TTLs:      []TTLCount{{MaxSeconds: 60, Grants: 2}, {Grants: 0}},
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := strings.Join([]string{
+		"section,subject,target,scope,ttl_max_seconds,grants,denials",
+		"pair,spiffe://td/a,spiffe://td/b,,,2,1",
+		"scope,,,read,,2,",
+		"ttl,,,,60,2,",
+		"ttl,,,,,0,",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got IssuanceReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if !got.End.Equal(r.End) || len(got.Pairs) != 1 || got.Pairs[0] != r.Pairs[0] || len(got.TTLs) != 2 {
+		t.Errorf("JSON round trip = %+v, want %+v", got, r)
+	}
+}
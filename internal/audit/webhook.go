@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Webhook request headers. The signature is "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of "<timestamp>.<body>" under the shared secret;
+// receivers should recompute it and reject stale timestamps to stop replays.
+const (
+	WebhookSignatureHeader = "X-Svid-Exchange-Signature"
+	WebhookTimestampHeader = "X-Svid-Exchange-Timestamp"
+)
+
+// Sink receives every exchange event after it has been logged. Deliver is
+// called on the request path, so it must be safe for concurrent use and must
+// not block.
+type Sink interface {
+	Deliver(e ExchangeEvent)
+}
+
+// WebhookConfig configures a WebhookSink.
+type WebhookConfig struct {
+	// URL receives one POST per denied exchange.
+	URL string
+	// Secret keys the HMAC-SHA256 request signature.
+	Secret []byte
+	// MaxAttempts bounds delivery attempts per event, including the first.
+	MaxAttempts int
+	// Timeout bounds each attempt.
+	Timeout time.Duration
+	// QueueSize is the number of events buffered while the endpoint is slow
+	// or down. Events arriving at a full queue are dropped and logged.
+	QueueSize int
+}
+
+// webhookPayload is the JSON body POSTed for a denied exchange.
+type webhookPayload struct {
+	Event           string   `json:"event"`
+	Time            string   `json:"time"`
+	RequestID       string   `json:"request_id,omitempty"`
+	AuthMethod      string   `json:"auth_method,omitempty"`
+	Subject         string   `json:"subject"`
+	Target          string   `json:"target"`
+	ScopesRequested []string `json:"scopes_requested"`
+	DenialReason    string   `json:"denial_reason"`
+}
+
+// WebhookSink POSTs denied exchanges to an alerting endpoint. Events are
+// queued and sent by a single background worker started with Start, so a
+// slow endpoint never delays an exchange. Network errors, 429, and 5xx
+// responses are retried with exponential backoff; other responses end the
+// attempt.
+type WebhookSink struct {
+	cfg     WebhookConfig
+	client  *http.Client
+	queue   chan webhookPayload
+	log     zerolog.Logger
+	backoff time.Duration // delay before the first retry; doubles per attempt
+}
+
+// NewWebhookSink returns a WebhookSink for cfg. Zero MaxAttempts, Timeout, and
+// QueueSize default to 5, 5s, and 1000. log receives delivery failures.
+func NewWebhookSink(cfg WebhookConfig, log zerolog.Logger) *WebhookSink {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	return &WebhookSink{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		queue:   make(chan webhookPayload, cfg.QueueSize),
+		log:     log,
+		backoff: 500 * time.Millisecond,
+	}
+}
+
+// Deliver queues e if it is a denial. Granted exchanges are ignored.
+func (w *WebhookSink) Deliver(e ExchangeEvent) {
+	if e.Granted {
+		return
+	}
+	p := webhookPayload{
+		Event:           "token.exchange.denied",
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		RequestID:       e.RequestID,
+		AuthMethod:      e.AuthMethod,
+		Subject:         e.Subject,
+		Target:          e.Target,
+		ScopesRequested: e.ScopesRequested,
+		DenialReason:    e.DenialReason,
+	}
+	select {
+	case w.queue <- p:
+	default:
+		w.log.Warn().Str("subject", e.Subject).Str("request_id", e.RequestID).Msg("denial webhook queue full; event dropped")
+	}
+}
+
+// Start sends queued events in a background goroutine until ctx is
+// cancelled. Events still queued at that point are not sent.
+func (w *WebhookSink) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p := <-w.queue:
+				if err := w.send(ctx, p); err != nil {
+					w.log.Error().Err(err).Str("subject", p.Subject).Str("request_id", p.RequestID).Msg("denial webhook delivery failed")
+				}
+			}
+		}
+	}()
+}
+
+// send POSTs p, retrying transient failures up to MaxAttempts times.
+func (w *WebhookSink) send(ctx context.Context, p webhookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	delay := w.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == w.cfg.MaxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post makes one signed delivery attempt and reports whether a failure is
+// worth retrying.
+func (w *WebhookSink) post(ctx context.Context, body []byte) (retry bool, err error) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, ts)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(w.cfg.Secret, ts, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body sent at
+// timestamp ts (Unix seconds) under secret. Receivers can use it to verify
+// deliveries.
+func SignWebhook(secret []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
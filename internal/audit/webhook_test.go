@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWebhookSink(t *testing.T) {
+	secret := []byte("webhook-secret")
+	denied := ExchangeEvent{
+		RequestID:       "req-9",
+		AuthMethod:      "k8s-sa-token",
+		Subject:         "spiffe://cluster.local/ns/default/sa/order",
+		Target:          "spiffe://cluster.local/ns/default/sa/admin",
+		ScopesRequested: []string{"admin:delete"},
+		DenialReason:    "no policy permits order → admin",
+	}
+
+	tests := []struct {
+		name         string
+		statuses     []int // response per attempt; the last repeats
+		wantAttempts int32
+		wantPayload  bool
+	}{
+		{name: "delivered first time", statuses: []int{http.StatusNoContent}, wantAttempts: 1, wantPayload: true},
+		{name: "5xx is retried", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 3, wantPayload: true},
+		{name: "4xx is not retried", statuses: []int{http.StatusBadRequest}, wantAttempts: 1},
+		{name: "gives up after max attempts", statuses: []int{http.StatusServiceUnavailable}, wantAttempts: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			received := make(chan webhookPayload, 1)
+			done := make(chan struct{}, 8)
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				defer func() { done <- struct{}{} }()
+				n := int(attempts.Add(1))
+				body, _ := io.ReadAll(r.Body)
+				ts := r.Header.Get(WebhookTimestampHeader)
+				if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook(secret, ts, body); got != want {
+					t.Errorf("signature = %q, want %q", got, want)
+				}
+				code := tc.statuses[min(n, len(tc.statuses))-1]
+				if code < 300 {
+					var p webhookPayload
+					if err := json.Unmarshal(body, &p); err != nil {
+						t.Errorf("payload is not JSON: %v", err)
+					}
+					received <- p
+				}
+				rw.WriteHeader(code)
+			}))
+			t.Cleanup(srv.Close)
+
+			sink := NewWebhookSink(WebhookConfig{URL: srv.URL, Secret: secret, MaxAttempts: 3}, zerolog.Nop())
+			sink.backoff = time.Millisecond
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			sink.Start(ctx)
+
+			sink.Deliver(ExchangeEvent{Granted: true, Subject: "ignored"})
+			sink.Deliver(denied)
+
+			for range tc.wantAttempts {
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out after %d attempts, want %d", attempts.Load(), tc.wantAttempts)
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			if got := attempts.Load(); got != tc.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tc.wantAttempts)
+			}
+			if !tc.wantPayload {
+				return
+			}
+			p := <-received
+			if p.Event != "token.exchange.denied" || p.Subject != denied.Subject || p.RequestID != denied.RequestID || p.AuthMethod != denied.AuthMethod || p.DenialReason != denied.DenialReason {
+				t.Errorf("payload = %+v", p)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkQueueFull(t *testing.T) {
+	sink := NewWebhookSink(WebhookConfig{URL: "http://127.0.0.1:0", QueueSize: 1}, zerolog.Nop())
+	// Not started: the first event fills the queue and the second must be
+	// dropped rather than block the caller.
+	finished := make(chan struct{})
+	go func() {
+		sink.Deliver(ExchangeEvent{Subject: "a"})
+		sink.Deliver(ExchangeEvent{Subject: "b"})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Deliver blocked on a full queue")
+	}
+	if len(sink.queue) != 1 {
+		t.Errorf("queue length = %d, want 1", len(sink.queue))
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// Independently computed: printf '1700000000.{}' | openssl dgst -sha256 -hmac key
+	got := SignWebhook([]byte("key"), "1700000000", []byte("{}"))
+	want := "sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae"
+	if got != want {
+		t.Errorf("SignWebhook = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+// Package authorizer delegates exchange policy decisions to an external
+// authorization service implementing authorizer.v1.Authorizer, over gRPC or
+// as JSON over HTTP, for organizations that decide access centrally rather
+// than in svid-exchange's policy file.
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	authorizerv1 "github.com/ngaddam369/svid-exchange/proto/authorizer/v1"
+)
+
+// maxResponseBytes bounds an HTTP authorizer's response body.
+const maxResponseBytes = 1 << 20
+
+// Client is a PolicyEvaluator backed by an external authorizer.
+type Client struct {
+	timeout time.Duration
+	// Exactly one of httpURL and grpcClient is set, by the URL scheme.
+	httpURL    string
+	http       *http.Client
+	conn       *grpc.ClientConn
+	grpcClient authorizerv1.AuthorizerClient
+}
+
+// New returns a Client for the authorizer at rawURL. An http or https URL is
+// called by POSTing an AuthorizeRequest as JSON, with proto field names, and
+// must answer 200 with an AuthorizeResponse; a grpc://host:port URL is called
+// over gRPC. tlsCfg, when non-nil, secures the connection, typically with
+// mTLS; it is required for https and, when nil, a grpc URL is dialled in
+// plaintext. Each call is bounded by timeout.
+func New(rawURL string, tlsCfg *tls.Config, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse authorizer url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("authorizer url %q has no host", rawURL)
+	}
+	c := &Client{timeout: timeout}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Scheme == "http" && tlsCfg != nil {
+			return nil, errors.New("an http authorizer url cannot use TLS; use https")
+		}
+		c.httpURL = rawURL
+		c.http = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+	case "grpc":
+		creds := insecure.NewCredentials()
+		if tlsCfg != nil {
+			creds = credentials.NewTLS(tlsCfg)
+		}
+		conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("create authorizer client: %w", err)
+		}
+		c.conn = conn
+		c.grpcClient = authorizerv1.NewAuthorizerClient(conn)
+	default:
+		return nil, fmt.Errorf("authorizer url scheme %q is not http, https, or grpc", u.Scheme)
+	}
+	return c, nil
+}
+
+// Close releases the Client's connections.
+func (c *Client) Close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Evaluate asks the authorizer whether subject may exchange for target. An
+// error means no decision was reached: the authorizer was unreachable, timed
+// out, failed, or granted more than was asked for.
+func (c *Client) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	req := &authorizerv1.AuthorizeRequest{Subject: subject, Target: target, Scopes: scopes, TtlSeconds: ttlSeconds}
+	var resp *authorizerv1.AuthorizeResponse
+	var err error
+	if c.grpcClient != nil {
+		resp, err = c.grpcClient.Authorize(ctx, req)
+	} else {
+		resp, err = c.postJSON(ctx, req)
+	}
+	if err != nil {
+		return policy.EvalResult{}, fmt.Errorf("external authorizer: %w", err)
+	}
+	res, err := decision(req, resp)
+	if err != nil {
+		return policy.EvalResult{}, fmt.Errorf("external authorizer: %w", err)
+	}
+	return res, nil
+}
+
+// postJSON performs an HTTP Authorize call.
+func (c *Client) postJSON(ctx context.Context, req *authorizerv1.AuthorizeRequest) (*authorizerv1.AuthorizeResponse, error) {
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.httpURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	hresp, err := c.http.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = hresp.Body.Close() }()
+	if hresp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", hresp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(hresp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	resp := &authorizerv1.AuthorizeResponse{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// decision converts resp to an EvalResult, rejecting a grant the local
+// policy could not have produced: scopes that were not requested, a TTL
+// above the one requested, or an unknown token format. A malformed grant is
+// an error rather than a denial, so failure_mode applies to it. The granted
+// scopes are returned in request order, as the local policy grants them,
+// whatever order the authorizer lists them in.
+func decision(req *authorizerv1.AuthorizeRequest, resp *authorizerv1.AuthorizeResponse) (policy.EvalResult, error) {
+	if !resp.Allowed {
+		return policy.EvalResult{}, nil
+	}
+	if len(resp.GrantedScopes) == 0 {
+		return policy.EvalResult{}, errors.New("grant has no scopes")
+	}
+	for _, s := range resp.GrantedScopes {
+		if !slices.Contains(req.Scopes, s) {
+			return policy.EvalResult{}, fmt.Errorf("granted scope %q was not requested", s)
+		}
+	}
+	if resp.TtlSeconds <= 0 {
+		return policy.EvalResult{}, fmt.Errorf("granted ttl %d is not positive", resp.TtlSeconds)
+	}
+	if req.TtlSeconds > 0 && resp.TtlSeconds > req.TtlSeconds {
+		return policy.EvalResult{}, fmt.Errorf("granted ttl %d exceeds the requested %d", resp.TtlSeconds, req.TtlSeconds)
+	}
+	format := resp.TokenFormat
+	if format == "" {
+		format = policy.FormatJWT
+	}
+	if !slices.Contains(policy.TokenFormats, format) {
+		return policy.EvalResult{}, fmt.Errorf("unknown token format %q", resp.TokenFormat)
+	}
+	granted := make([]string, 0, len(resp.GrantedScopes))
+	for _, s := range req.Scopes {
+		if slices.Contains(resp.GrantedScopes, s) {
+			granted = append(granted, s)
+		}
+	}
+	return policy.EvalResult{
+		Allowed:       true,
+		GrantedScopes: granted,
+		GrantedTTL:    resp.TtlSeconds,
+		TokenFormat:   format,
+		MatchedRules:  resp.Rules,
+		RequireNonce:  resp.RequireNonce,
+	}, nil
+}
@@ -0,0 +1,200 @@
+package authorizer
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	authorizerv1 "github.com/ngaddam369/svid-exchange/proto/authorizer/v1"
+)
+
+// fakeAuthorizer answers every Authorize call with resp, recording the
+// request.
+type fakeAuthorizer struct {
+	authorizerv1.UnimplementedAuthorizerServer
+	resp *authorizerv1.AuthorizeResponse
+	got  *authorizerv1.AuthorizeRequest
+}
+
+func (f *fakeAuthorizer) Authorize(_ context.Context, req *authorizerv1.AuthorizeRequest) (*authorizerv1.AuthorizeResponse, error) {
+	f.got = req
+	return f.resp, nil
+}
+
+// serveGRPC starts f on a loopback listener and returns its grpc:// URL.
+func serveGRPC(t *testing.T, f *fakeAuthorizer) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	authorizerv1.RegisterAuthorizerServer(srv, f)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return "grpc://" + lis.Addr().String()
+}
+
+// serveHTTP serves f's responses as JSON and returns the server's URL.
+func serveHTTP(t *testing.T, f *fakeAuthorizer) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := &authorizerv1.AuthorizeRequest{}
+		if err := protojson.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, _ := f.Authorize(r.Context(), req)
+		out, _ := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestEvaluate(t *testing.T) {
+	transports := map[string]func(*testing.T, *fakeAuthorizer) string{
+		"grpc": serveGRPC,
+		"http": serveHTTP,
+	}
+	for name, serve := range transports {
+		t.Run(name, func(t *testing.T) {
+			f := &fakeAuthorizer{resp: &authorizerv1.AuthorizeResponse{
+				Allowed:       true,
+				GrantedScopes: []string{"payments:read"},
+				TtlSeconds:    60,
+				Rules:         []string{"central/orders"},
+			}}
+			c, err := New(serve(t, f), nil, time.Second)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer func() { _ = c.Close() }()
+
+			res, err := c.Evaluate(context.Background(), "spiffe://td/order", "spiffe://td/payment", []string{"payments:read", "payments:write"}, 300)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !res.Allowed || !slices.Equal(res.GrantedScopes, []string{"payments:read"}) || res.GrantedTTL != 60 ||
+				res.TokenFormat != "jwt" || !slices.Equal(res.MatchedRules, []string{"central/orders"}) {
+				t.Errorf("result = %+v", res)
+			}
+			if f.got.GetSubject() != "spiffe://td/order" || f.got.GetTarget() != "spiffe://td/payment" ||
+				len(f.got.GetScopes()) != 2 || f.got.GetTtlSeconds() != 300 {
+				t.Errorf("authorizer received %v", f.got)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	t.Run("http status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		c, err := New(srv.URL, nil, time.Second)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if _, err := c.Evaluate(context.Background(), "a", "b", []string{"s"}, 0); err == nil {
+			t.Error("503 response yielded a decision")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+		c, err := New(srv.URL, nil, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if _, err := c.Evaluate(context.Background(), "a", "b", []string{"s"}, 0); err == nil {
+			t.Error("hung authorizer yielded a decision")
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		c, err := New("grpc://127.0.0.1:1", nil, time.Second)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		defer func() { _ = c.Close() }()
+		if _, err := c.Evaluate(context.Background(), "a", "b", []string{"s"}, 0); err == nil {
+			t.Error("unreachable authorizer yielded a decision")
+		}
+	})
+}
+
+func TestDecision(t *testing.T) {
+	req := &authorizerv1.AuthorizeRequest{Scopes: []string{"read", "write"}, TtlSeconds: 300}
+	tests := []struct {
+		name    string
+		resp    *authorizerv1.AuthorizeResponse
+		allowed bool
+		wantErr bool
+	}{
+		{"denied", &authorizerv1.AuthorizeResponse{}, false, false},
+		{"denied ignores grant fields", &authorizerv1.AuthorizeResponse{GrantedScopes: []string{"admin"}}, false, false},
+		{"granted", &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}, TtlSeconds: 300, TokenFormat: "paseto"}, true, false},
+		{"no scopes", &authorizerv1.AuthorizeResponse{Allowed: true, TtlSeconds: 60}, false, true},
+		{"unrequested scope", &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read", "admin"}, TtlSeconds: 60}, false, true},
+		{"zero ttl", &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}}, false, true},
+		{"ttl above request", &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}, TtlSeconds: 301}, false, true},
+		{"unknown format", &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}, TtlSeconds: 60, TokenFormat: "saml"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := decision(req, tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if res.Allowed != tt.allowed {
+				t.Errorf("Allowed = %t, want %t", res.Allowed, tt.allowed)
+			}
+		})
+	}
+
+	t.Run("zero requested ttl accepts any positive grant", func(t *testing.T) {
+		res, err := decision(&authorizerv1.AuthorizeRequest{Scopes: []string{"read"}},
+			&authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}, TtlSeconds: 3600})
+		if err != nil || res.GrantedTTL != 3600 {
+			t.Errorf("decision = %+v, %v", res, err)
+		}
+	})
+
+	t.Run("granted scopes follow request order", func(t *testing.T) {
+		res, err := decision(req, &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"write", "read", "write"}, TtlSeconds: 60})
+		if err != nil || !slices.Equal(res.GrantedScopes, []string{"read", "write"}) {
+			t.Errorf("decision = %+v, %v; want scopes [read write]", res, err)
+		}
+	})
+
+	t.Run("require_nonce carries into the result", func(t *testing.T) {
+		res, err := decision(req, &authorizerv1.AuthorizeResponse{Allowed: true, GrantedScopes: []string{"read"}, TtlSeconds: 60, RequireNonce: true})
+		if err != nil || !res.RequireNonce {
+			t.Errorf("decision = %+v, %v", res, err)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"ftp://authz:21", "http://", "://bad"} {
+		if _, err := New(u, nil, time.Second); err == nil {
+			t.Errorf("New(%q) succeeded", u)
+		}
+	}
+}
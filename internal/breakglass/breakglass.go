@@ -0,0 +1,142 @@
+// Package breakglass verifies signed emergency override documents. An
+// override grants access the regular policy does not, for a short time, so
+// that operators can restore service during an incident without editing the
+// policy file.
+package breakglass
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// Document is an emergency override as an operator writes and signs it, in
+// YAML.
+type Document struct {
+	// ID names the override in audit entries and in the names of its rules.
+	ID string `yaml:"id"`
+	// Reason records why the override was issued, e.g. an incident number.
+	Reason string `yaml:"reason"`
+	// IssuedBy names who signed the override.
+	IssuedBy string `yaml:"issued_by"`
+	// ExpiresAt is when the override stops granting.
+	ExpiresAt time.Time       `yaml:"expires_at"`
+	Policies  []policy.Policy `yaml:"policies"`
+}
+
+// Override is a verified Document. A zero Override is not usable; use Parse.
+type Override struct {
+	Document
+	loader *policy.Loader
+}
+
+// Parse verifies signature over doc with key, then parses doc and checks
+// that it is in force at now and expires no later than maxDuration after
+// now. key is either an *ecdsa.PublicKey, for an ASN.1 ECDSA signature over
+// the SHA-256 of doc, or an ed25519.PublicKey. Each rule is renamed
+// "break-glass/<id>/<name>", so grants made under the override are
+// recognizable wherever rule names are reported.
+func Parse(doc, signature []byte, key crypto.PublicKey, now time.Time, maxDuration time.Duration) (*Override, error) {
+	if err := verify(doc, signature, key); err != nil {
+		return nil, err
+	}
+	var d Document
+	dec := yaml.NewDecoder(bytes.NewReader(doc))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("parse override: %w", err)
+	}
+	switch {
+	case d.ID == "":
+		return nil, errors.New("override id must not be empty")
+	case d.Reason == "":
+		return nil, errors.New("override reason must not be empty")
+	case !now.Before(d.ExpiresAt):
+		return nil, fmt.Errorf("override expired at %s", d.ExpiresAt.Format(time.RFC3339))
+	case d.ExpiresAt.Sub(now) > maxDuration:
+		return nil, fmt.Errorf("override expires_at %s is more than %s away", d.ExpiresAt.Format(time.RFC3339), maxDuration)
+	case len(d.Policies) == 0:
+		return nil, errors.New("override contains no policies")
+	}
+	for i := range d.Policies {
+		d.Policies[i].Name = "break-glass/" + d.ID + "/" + d.Policies[i].Name
+	}
+	l, err := policy.NewLoader(d.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("override policies: %w", err)
+	}
+	return &Override{Document: d, loader: l}, nil
+}
+
+// verify checks signature over doc with key.
+func verify(doc, signature []byte, key crypto.PublicKey) error {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(doc)
+		if !ecdsa.VerifyASN1(k, sum[:], signature) {
+			return errors.New("override signature is invalid")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, doc, signature) {
+			return errors.New("override signature is invalid")
+		}
+	default:
+		return fmt.Errorf("unsupported override signing key type %T", key)
+	}
+	return nil
+}
+
+// Evaluate evaluates a request against the override's policies at now. The
+// granted TTL is capped so that no token outlives the override, and nothing
+// is granted once it has expired.
+func (o *Override) Evaluate(now time.Time, subject, target string, scopes []string, ttlSeconds int32) policy.EvalResult {
+	left := int32(o.ExpiresAt.Sub(now) / time.Second)
+	if left <= 0 {
+		return policy.EvalResult{}
+	}
+	res := o.loader.Evaluate(subject, target, scopes, ttlSeconds)
+	if res.Allowed {
+		res.GrantedTTL = min(res.GrantedTTL, left)
+	}
+	return res
+}
+
+// LoadPublicKey reads the PEM-encoded PKIX public key that override
+// documents are verified with from path. It must be an ECDSA P-256 or an
+// Ed25519 key.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read break-glass public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("break-glass public key: no PEM PUBLIC KEY block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse break-glass public key: %w", err)
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().Name != "P-256" {
+			return nil, fmt.Errorf("break-glass public key: ECDSA curve must be P-256, got %s", k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("break-glass public key: unsupported key type %T", key)
+	}
+	return key, nil
+}
@@ -0,0 +1,176 @@
+package breakglass
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	order   = "spiffe://cluster.local/ns/default/sa/order"
+	payment = "spiffe://cluster.local/ns/default/sa/payment"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testDoc returns an override document expiring at exp.
+func testDoc(exp time.Time) []byte {
+	return fmt.Appendf(nil, `
+id: inc-4711
+reason: "payments outage, INC-4711"
+issued_by: "on-call security"
+expires_at: %s
+policies:
+  - name: order-to-payment
+    subject: %q
+    target:  %q
+    allowed_scopes: [payments:charge]
+    max_ttl: 3600
+`, exp.Format(time.RFC3339), order, payment)
+}
+
+func signECDSA(t *testing.T, k *ecdsa.PrivateKey, doc []byte) []byte {
+	t.Helper()
+	sum := sha256.Sum256(doc)
+	sig, err := ecdsa.SignASN1(rand.Reader, k, sum[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return sig
+}
+
+func TestParse(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	valid := testDoc(now.Add(30 * time.Minute))
+
+	tests := []struct {
+		name    string
+		doc     []byte
+		sig     []byte
+		key     crypto.PublicKey
+		wantErr string
+	}{
+		{name: "ECDSA signature", doc: valid, sig: signECDSA(t, ecKey, valid), key: &ecKey.PublicKey},
+		{name: "Ed25519 signature", doc: valid, sig: ed25519.Sign(edPriv, valid), key: edPub},
+		{name: "signature over another document", doc: valid, sig: signECDSA(t, ecKey, testDoc(now.Add(time.Minute))), key: &ecKey.PublicKey, wantErr: "signature is invalid"},
+		{name: "signed by another key", doc: valid, sig: ed25519.Sign(edPriv, valid), key: &ecKey.PublicKey, wantErr: "signature is invalid"},
+		{name: "expired", doc: testDoc(now.Add(-time.Minute)), key: edPub, wantErr: "expired"},
+		{name: "beyond the maximum duration", doc: testDoc(now.Add(2 * time.Hour)), key: edPub, wantErr: "more than 1h0m0s away"},
+		{name: "no reason", doc: []byte(strings.Replace(string(valid), `reason: "payments outage, INC-4711"`, "", 1)), key: edPub, wantErr: "reason"},
+		{name: "unknown field", doc: append(valid, "grant_everything: true\n"...), key: edPub, wantErr: "parse override"},
+		{name: "invalid policy", doc: []byte(strings.Replace(string(valid), "max_ttl: 3600", "max_ttl: 0", 1)), key: edPub, wantErr: "max_ttl"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sig := tc.sig
+			if sig == nil {
+				sig = ed25519.Sign(edPriv, tc.doc)
+			}
+			o, err := Parse(tc.doc, sig, tc.key, now, time.Hour)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if o.ID != "inc-4711" || o.IssuedBy != "on-call security" || !o.ExpiresAt.Equal(now.Add(30*time.Minute)) {
+				t.Errorf("document = %+v", o.Document)
+			}
+			if o.Policies[0].Name != "break-glass/inc-4711/order-to-payment" {
+				t.Errorf("rule name = %q, want it prefixed with the override id", o.Policies[0].Name)
+			}
+		})
+	}
+}
+
+func TestOverrideEvaluate(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	doc := testDoc(now.Add(10 * time.Minute))
+	o, err := Parse(doc, ed25519.Sign(priv, doc), priv.Public(), now, time.Hour)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	scopes := []string{"payments:charge"}
+
+	if res := o.Evaluate(now, order, payment, scopes, 60); !res.Allowed || res.GrantedTTL != 60 {
+		t.Errorf("result = %+v, want a 60s grant", res)
+	}
+	if res := o.Evaluate(now.Add(9*time.Minute), order, payment, scopes, 300); !res.Allowed || res.GrantedTTL != 60 {
+		t.Errorf("result = %+v, want the TTL capped to the 60s left", res)
+	}
+	if res := o.Evaluate(now.Add(10*time.Minute), order, payment, scopes, 0); res.Allowed {
+		t.Error("expired override still grants")
+	}
+	if res := o.Evaluate(now, payment, order, scopes, 0); res.Allowed {
+		t.Error("override grants a pair it has no rule for")
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	write := func(t *testing.T, key any) string {
+		t.Helper()
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "break-glass.pub")
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, tc := range []struct {
+		name    string
+		key     any
+		wantErr bool
+	}{
+		{name: "P-256", key: &p256.PublicKey},
+		{name: "Ed25519", key: edPub},
+		{name: "P-384 is rejected", key: &p384.PublicKey, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadPublicKey(write(t, tc.key))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("not PEM", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key")
+		if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, err := LoadPublicKey(path); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	got := System{}.Now()
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("System.Now() = %v, not between %v and now", got, before)
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	if got := f.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+	f.Advance(90 * time.Second)
+	if got, want := f.Now(), start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("after Advance, Now() = %v, want %v", got, want)
+	}
+	f.Set(start.Add(-time.Hour))
+	if got, want := f.Now(), start.Add(-time.Hour); !got.Equal(want) {
+		t.Errorf("after Set, Now() = %v, want %v", got, want)
+	}
+}
+
+func TestFakeConcurrent(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(2)
+		go func() { defer wg.Done(); f.Advance(time.Second) }()
+		go func() { defer wg.Done(); _ = f.Now() }()
+	}
+	wg.Wait()
+	if got, want := f.Now(), start.Add(50*time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+}
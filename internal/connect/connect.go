@@ -0,0 +1,305 @@
+// Package connect serves gRPC services over the Connect protocol's unary
+// HTTP encoding (https://connectrpc.com/docs/protocol), so that clients
+// without an HTTP/2 gRPC stack — browsers, curl, and Connect-native
+// libraries — can call them with the same protobuf definitions. Services
+// are registered with their generated Register functions, exactly as on a
+// *grpc.Server, and every call runs through the same method handler and
+// unary interceptor chain that gRPC would use.
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Connect unary content types.
+const (
+	contentTypeProto = "application/proto"
+	contentTypeJSON  = "application/json"
+)
+
+// MaxMessageSize is the largest request body the handler reads, after
+// decompression. It matches gRPC's default receive limit.
+const MaxMessageSize = 4 << 20
+
+// maxTimeout bounds Connect-Timeout-Ms, which the protocol limits to ten
+// digits.
+const maxTimeout = 9_999_999_999 * time.Millisecond
+
+// Handler serves the unary methods of the services registered with it at
+// POST /<service>/<method>. It implements grpc.ServiceRegistrar; streaming
+// methods are not served.
+type Handler struct {
+	unary   grpc.UnaryServerInterceptor
+	methods map[string]method
+}
+
+type method struct {
+	impl    any
+	handler grpc.MethodHandler
+}
+
+var _ grpc.ServiceRegistrar = (*Handler)(nil)
+
+// New returns an empty Handler whose calls all run through unary, which may
+// be nil.
+func New(unary grpc.UnaryServerInterceptor) *Handler {
+	return &Handler{unary: unary, methods: make(map[string]method)}
+}
+
+// RegisterService registers the unary methods of desc, implemented by impl.
+// It panics if impl does not implement desc.HandlerType, as grpc.Server
+// does.
+func (h *Handler) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	if impl != nil {
+		if ht := reflect.TypeOf(desc.HandlerType).Elem(); !reflect.TypeOf(impl).Implements(ht) {
+			panic(fmt.Sprintf("connect: %T does not implement %v", impl, ht))
+		}
+	}
+	for _, md := range desc.Methods {
+		h.methods["/"+desc.ServiceName+"/"+md.MethodName] = method{impl: impl, handler: md.Handler}
+	}
+}
+
+// ServeHTTP handles one Connect unary call.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.methods[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeProto && contentType != contentTypeJSON {
+		w.Header().Set("Accept-Post", contentTypeJSON+", "+contentTypeProto)
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	stream := &transportStream{method: r.URL.Path}
+	resp, err := h.call(r, m, contentType, stream)
+	writeMetadata(w.Header(), stream.header, "")
+	writeMetadata(w.Header(), stream.trailer, "Trailer-")
+	if err != nil {
+		writeError(w, status.Convert(err))
+		return
+	}
+
+	var body []byte
+	if contentType == contentTypeJSON {
+		body, err = protojson.Marshal(resp)
+	} else {
+		body, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		writeError(w, status.Newf(codes.Internal, "marshal response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body) // writes to a bytes.Buffer do not fail
+		_ = zw.Close()
+		body = buf.Bytes()
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.Write(body)
+}
+
+// call decodes the request and runs the method handler with the request's
+// deadline, metadata, and a transport stream collecting response metadata.
+func (h *Handler) call(r *http.Request, m method, contentType string, stream *transportStream) (proto.Message, error) {
+	if v := r.Header.Get("Connect-Protocol-Version"); v != "" && v != "1" {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported Connect-Protocol-Version %q", v)
+	}
+	ctx := r.Context()
+	if v := r.Header.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 || len(v) > 10 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", v)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, min(time.Duration(ms)*time.Millisecond, maxTimeout))
+		defer cancel()
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.NewIncomingContext(ctx, incomingMetadata(r.Header))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	dec := func(v any) error {
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "request type %T is not a protobuf message", v)
+		}
+		var err error
+		if contentType == contentTypeJSON {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+		} else {
+			err = proto.Unmarshal(body, msg)
+		}
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "unmarshal request: %v", err)
+		}
+		return nil
+	}
+	resp, err := m.handler(m.impl, ctx, dec, h.unary)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "response type %T is not a protobuf message", resp)
+	}
+	return msg, nil
+}
+
+// readBody returns the request body, decompressed, refusing bodies larger
+// than MaxMessageSize.
+func readBody(r *http.Request) ([]byte, error) {
+	var src io.Reader = r.Body
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "decompress request: %v", err)
+		}
+		defer func() { _ = zr.Close() }()
+		src = zr
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported Content-Encoding %q; supported: gzip, identity", enc)
+	}
+	body, err := io.ReadAll(io.LimitReader(src, MaxMessageSize+1))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "read request: %v", err)
+	}
+	if len(body) > MaxMessageSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "request larger than %d bytes", MaxMessageSize)
+	}
+	return body, nil
+}
+
+// acceptsGzip reports whether an Accept-Encoding value lists gzip.
+func acceptsGzip(accept string) bool {
+	for enc := range strings.SplitSeq(accept, ",") {
+		name, _, _ := strings.Cut(enc, ";")
+		if strings.TrimSpace(name) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// incomingMetadata converts request headers to gRPC metadata. Binary
+// (-bin) values are base64 on the wire in both protocols, but gRPC metadata
+// carries them decoded.
+func incomingMetadata(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, vals := range h {
+		k = strings.ToLower(k)
+		for _, v := range vals {
+			if strings.HasSuffix(k, "-bin") {
+				b, err := decodeBinaryHeader(v)
+				if err != nil {
+					continue
+				}
+				v = string(b)
+			}
+			md[k] = append(md[k], v)
+		}
+	}
+	return md
+}
+
+// writeMetadata adds md to h, each key prefixed with prefix.
+func writeMetadata(h http.Header, md metadata.MD, prefix string) {
+	for k, vals := range md {
+		for _, v := range vals {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.RawStdEncoding.EncodeToString([]byte(v))
+			}
+			h.Add(prefix+k, v)
+		}
+	}
+}
+
+// decodeBinaryHeader decodes a -bin header value, with or without padding.
+func decodeBinaryHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// transportStream collects the response metadata a handler and its
+// interceptors set with grpc.SetHeader, grpc.SendHeader, and
+// grpc.SetTrailer. A unary Connect response carries it all in its headers,
+// written when the call returns.
+type transportStream struct {
+	method string
+
+	mu         sync.Mutex
+	header     metadata.MD
+	trailer    metadata.MD
+	headerSent bool
+}
+
+var errHeaderSent = errors.New("connect: headers already sent")
+
+func (s *transportStream) Method() string { return s.method }
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return errHeaderSent
+	}
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return errHeaderSent
+	}
+	s.header = metadata.Join(s.header, md)
+	s.headerSent = true
+	return nil
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
@@ -0,0 +1,280 @@
+package connect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+const exchangePath = "/exchange.v1.TokenExchange/Exchange"
+
+// stubExchange grants the requested target, or fails with err, after
+// setting a response header and trailer.
+type stubExchange struct {
+	exchangev1.UnimplementedTokenExchangeServer
+	err error
+}
+
+func (s stubExchange) Exchange(ctx context.Context, req *exchangev1.ExchangeRequest) (*exchangev1.ExchangeResponse, error) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "req-1"))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("x-retry-pushback-ms", "250"))
+	if s.err != nil {
+		return nil, s.err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &exchangev1.ExchangeResponse{
+		Token:         "tok-for-" + req.GetTargetService(),
+		ExpiresAt:     1700000000,
+		GrantedScopes: req.GetScopes(),
+		TokenId:       strings.Join(md.Get("x-client"), ","),
+	}, nil
+}
+
+func newHandler(t *testing.T, svc exchangev1.TokenExchangeServer, unary grpc.UnaryServerInterceptor) *Handler {
+	t.Helper()
+	h := New(unary)
+	exchangev1.RegisterTokenExchangeServer(h, svc)
+	return h
+}
+
+func TestHandlerJSON(t *testing.T) {
+	var gotMethod string
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		gotMethod = info.FullMethod
+		return handler(ctx, req)
+	}
+	h := newHandler(t, stubExchange{}, unary)
+
+	req := httptest.NewRequest(http.MethodPost, exchangePath, strings.NewReader(`{"targetService":"spiffe://cluster.local/ns/default/sa/payment","scopes":["payments:charge"],"unknownField":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	req.Header.Set("X-Client", "cli")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", rec.Code, rec.Body)
+	}
+	if gotMethod != exchangev1.TokenExchange_Exchange_FullMethodName {
+		t.Errorf("interceptor FullMethod = %q, want %q", gotMethod, exchangev1.TokenExchange_Exchange_FullMethodName)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if got := rec.Header().Get("X-Request-Id"); got != "req-1" {
+		t.Errorf("X-Request-Id = %q, want req-1", got)
+	}
+	if got := rec.Header().Get("Trailer-X-Retry-Pushback-Ms"); got != "250" {
+		t.Errorf("Trailer-X-Retry-Pushback-Ms = %q, want 250", got)
+	}
+	var resp struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expiresAt"`
+		TokenID   string `json:"tokenId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %s: %v", rec.Body, err)
+	}
+	if resp.Token != "tok-for-spiffe://cluster.local/ns/default/sa/payment" || resp.ExpiresAt != "1700000000" || resp.TokenID != "cli" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestHandlerProtoGzip(t *testing.T) {
+	h := newHandler(t, stubExchange{}, nil)
+
+	in, err := proto.Marshal(&exchangev1.ExchangeRequest{TargetService: "spiffe://cluster.local/ns/default/sa/payment"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	_, _ = zw.Write(in)
+	_ = zw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, exchangePath, &zbuf)
+	req.Header.Set("Content-Type", "application/proto")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", rec.Code, rec.Body)
+	}
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	var resp exchangev1.ExchangeResponse
+	if err := proto.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.GetToken() != "tok-for-spiffe://cluster.local/ns/default/sa/payment" {
+		t.Errorf("token = %q", resp.GetToken())
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	denied, err := status.New(codes.PermissionDenied, "policy denied").WithDetails(&errdetails.ErrorInfo{Reason: "POLICY_DENIED"})
+	if err != nil {
+		t.Fatalf("WithDetails: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		svc         stubExchange
+		method      string
+		contentType string
+		header      map[string]string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantDetail  string
+	}{
+		{
+			name:       "permission denied with details",
+			svc:        stubExchange{err: denied.Err()},
+			wantStatus: http.StatusForbidden,
+			wantCode:   "permission_denied",
+			wantDetail: "google.rpc.ErrorInfo",
+		},
+		{
+			name:       "resource exhausted",
+			svc:        stubExchange{err: status.Error(codes.ResourceExhausted, "rate limited")},
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   "resource_exhausted",
+		},
+		{
+			name:       "non-status error is unknown",
+			svc:        stubExchange{err: io.ErrUnexpectedEOF},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "unknown",
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"targetService":`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "unsupported protocol version",
+			header:     map[string]string{"Connect-Protocol-Version": "2"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "invalid timeout",
+			header:     map[string]string{"Connect-Timeout-Ms": "soon"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_argument",
+		},
+		{
+			name:       "unsupported compression",
+			header:     map[string]string{"Content-Encoding": "br"},
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   "unimplemented",
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "GET",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHandler(t, tc.svc, nil)
+			method, contentType, body := http.MethodPost, "application/json", `{"targetService":"spiffe://cluster.local/ns/default/sa/payment"}`
+			if tc.method != "" {
+				method = tc.method
+			}
+			if tc.contentType != "" {
+				contentType = tc.contentType
+			}
+			if tc.body != "" {
+				body = tc.body
+			}
+			req := httptest.NewRequest(method, exchangePath, strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantCode == "" {
+				return
+			}
+			var got wireError
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error body %s: %v", rec.Body, err)
+			}
+			if got.Code != tc.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tc.wantCode)
+			}
+			if tc.wantDetail != "" && (len(got.Details) != 1 || got.Details[0].Type != tc.wantDetail || got.Details[0].Value == "") {
+				t.Errorf("details = %+v, want one %s", got.Details, tc.wantDetail)
+			}
+		})
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	var deadline time.Time
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		deadline, _ = ctx.Deadline()
+		return handler(ctx, req)
+	}
+	h := newHandler(t, stubExchange{}, unary)
+
+	req := httptest.NewRequest(http.MethodPost, exchangePath, strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Timeout-Ms", "5000")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if remaining := time.Until(deadline); remaining <= 0 || remaining > 5*time.Second {
+		t.Errorf("handler deadline in %v, want within 5s", remaining)
+	}
+}
+
+func TestHandlerUnknownMethod(t *testing.T) {
+	h := newHandler(t, stubExchange{}, nil)
+	req := httptest.NewRequest(http.MethodPost, "/exchange.v1.TokenExchange/Mint", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package connect
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodes gives each gRPC code's Connect name and HTTP status, from the
+// protocol specification's error code table.
+var errorCodes = map[codes.Code]struct {
+	name   string
+	status int
+}{
+	codes.Canceled:           {"canceled", 499},
+	codes.Unknown:            {"unknown", http.StatusInternalServerError},
+	codes.InvalidArgument:    {"invalid_argument", http.StatusBadRequest},
+	codes.DeadlineExceeded:   {"deadline_exceeded", http.StatusGatewayTimeout},
+	codes.NotFound:           {"not_found", http.StatusNotFound},
+	codes.AlreadyExists:      {"already_exists", http.StatusConflict},
+	codes.PermissionDenied:   {"permission_denied", http.StatusForbidden},
+	codes.ResourceExhausted:  {"resource_exhausted", http.StatusTooManyRequests},
+	codes.FailedPrecondition: {"failed_precondition", http.StatusBadRequest},
+	codes.Aborted:            {"aborted", http.StatusConflict},
+	codes.OutOfRange:         {"out_of_range", http.StatusBadRequest},
+	codes.Unimplemented:      {"unimplemented", http.StatusNotImplemented},
+	codes.Internal:           {"internal", http.StatusInternalServerError},
+	codes.Unavailable:        {"unavailable", http.StatusServiceUnavailable},
+	codes.DataLoss:           {"data_loss", http.StatusInternalServerError},
+	codes.Unauthenticated:    {"unauthenticated", http.StatusUnauthorized},
+}
+
+// wireError is the JSON body of a Connect unary error.
+type wireError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message,omitempty"`
+	Details []wireDetail `json:"details,omitempty"`
+}
+
+// wireDetail is one error detail: the fully-qualified message name and its
+// binary encoding, base64 without padding.
+type wireDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// writeError writes st as a Connect error response.
+func writeError(w http.ResponseWriter, st *status.Status) {
+	ec, ok := errorCodes[st.Code()]
+	if !ok {
+		ec = errorCodes[codes.Unknown]
+	}
+	body := wireError{Code: ec.name, Message: st.Message()}
+	for _, d := range st.Proto().GetDetails() {
+		body.Details = append(body.Details, wireDetail{
+			Type:  d.GetTypeUrl()[strings.LastIndexByte(d.GetTypeUrl(), '/')+1:],
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(ec.status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,70 @@
+package extauthz
+
+import (
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+// Reuse is a token presented by a second source. An exchanged token is
+// minted for one workload, so the same jti arriving from another identity or
+// address usually means the token leaked.
+type Reuse struct {
+	JTI       string
+	Subject   string
+	Audience  string
+	ExpiresAt time.Time
+	// FirstSource is the source the token was first presented from and
+	// Source the one presenting it now, each the peer's SPIFFE ID when
+	// Envoy reports one and its IP address otherwise.
+	FirstSource string
+	Source      string
+}
+
+// SourceStore remembers the source each token was first presented from; see
+// policy.Store.ObserveTokenSource.
+type SourceStore interface {
+	ObserveTokenSource(jti, source string, expiresAt int64) (string, error)
+}
+
+// ReuseDetector reports tokens presented from a source other than the one
+// they were first presented from. First sources are kept in a SourceStore
+// until the token expires, so every endpoint and replica sharing the store
+// sees the same first source, and a restart does not forget it.
+type ReuseDetector struct {
+	store   SourceStore
+	onReuse func(Reuse) bool
+}
+
+// NewReuseDetector returns a ReuseDetector that records first sources in
+// store and passes every reuse to onReuse, which reports whether it revoked
+// the token.
+func NewReuseDetector(store SourceStore, onReuse func(Reuse) bool) *ReuseDetector {
+	return &ReuseDetector{store: store, onReuse: onReuse}
+}
+
+// Observe records that the token r describes was presented from r.Source.
+// When it was first presented from another source, Observe fills in
+// r.FirstSource, hands r to onReuse, and reports whether the token was
+// revoked.
+func (d *ReuseDetector) Observe(r Reuse) (revoked bool, err error) {
+	first, err := d.store.ObserveTokenSource(r.JTI, r.Source, r.ExpiresAt.Unix())
+	if err != nil {
+		return false, err
+	}
+	if first == r.Source {
+		return false, nil
+	}
+	r.FirstSource = first
+	return d.onReuse(r), nil
+}
+
+// source identifies the peer presenting a token: the SPIFFE ID Envoy
+// verified for it, or its IP address when the connection was not mTLS.
+func source(attrs *authv3.AttributeContext) string {
+	peer := attrs.GetSource()
+	if p := peer.GetPrincipal(); p != "" {
+		return p
+	}
+	return peer.GetAddress().GetSocketAddress().GetAddress()
+}
@@ -0,0 +1,130 @@
+package extauthz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+)
+
+// fromSource sets the downstream peer of req to principal, or to the IP
+// address ip when principal is empty.
+func fromSource(req *authv3.CheckRequest, principal, ip string) *authv3.CheckRequest {
+	req.Attributes.Source = &authv3.AttributeContext_Peer{
+		Principal: principal,
+		Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+			SocketAddress: &corev3.SocketAddress{Address: ip},
+		}},
+	}
+	return req
+}
+
+func TestCheckReuse(t *testing.T) {
+	m := newMinter(t)
+	var reuses []Reuse
+	revoke := false
+	srv := New(m, func(string) bool { return false })
+	srv.SetReuseDetector(NewReuseDetector(memSources{}, func(r Reuse) bool {
+		reuses = append(reuses, r)
+		return revoke
+	}))
+
+	tok := mint(t, m, target, "payments:charge")
+	check := func(principal, ip string) codes.Code {
+		t.Helper()
+		resp, err := srv.Check(context.Background(), fromSource(checkReq("Bearer "+tok.Token, target, nil), principal, ip))
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return codes.Code(resp.GetStatus().GetCode())
+	}
+
+	if code := check(subject, "10.0.0.1"); code != codes.OK {
+		t.Fatalf("first presentation: code %v, want OK", code)
+	}
+	if code := check(subject, "10.0.0.2"); code != codes.OK || len(reuses) != 0 {
+		t.Fatalf("same principal from another address: code %v, %d reuses; want OK, none", code, len(reuses))
+	}
+	if code := check("spiffe://cluster.local/ns/default/sa/rogue", "10.0.0.3"); code != codes.OK {
+		t.Errorf("reuse without revocation: code %v, want OK", code)
+	}
+	if len(reuses) != 1 {
+		t.Fatalf("got %d reuses, want 1", len(reuses))
+	}
+	r := reuses[0]
+	if r.JTI != tok.TokenID || r.Subject != subject || r.Audience != target || r.FirstSource != subject ||
+		r.Source != "spiffe://cluster.local/ns/default/sa/rogue" || r.ExpiresAt.IsZero() {
+		t.Errorf("reuse = %+v", r)
+	}
+
+	revoke = true
+	if code := check("", "192.0.2.10"); code != codes.Unauthenticated {
+		t.Errorf("reuse with revocation: code %v, want Unauthenticated", code)
+	}
+	if len(reuses) != 2 || reuses[1].Source != "192.0.2.10" {
+		t.Errorf("reuses = %+v, want a second from 192.0.2.10", reuses)
+	}
+}
+
+func TestCheckReuseStoreFailure(t *testing.T) {
+	m := newMinter(t)
+	srv := New(m, func(string) bool { return false })
+	srv.SetReuseDetector(NewReuseDetector(failingSources{}, func(Reuse) bool { return false }))
+	tok := mint(t, m, target, "payments:charge")
+	resp, err := srv.Check(context.Background(), fromSource(checkReq("Bearer "+tok.Token, target, nil), subject, "10.0.0.1"))
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if code := codes.Code(resp.GetStatus().GetCode()); code != codes.Unavailable {
+		t.Errorf("code = %v, want Unavailable", code)
+	}
+}
+
+func TestReuseDetector(t *testing.T) {
+	var reuses []Reuse
+	d := NewReuseDetector(memSources{}, func(r Reuse) bool {
+		reuses = append(reuses, r)
+		return true
+	})
+	exp := time.Now().Add(time.Minute)
+	observe := func(jti, src string) bool {
+		t.Helper()
+		revoked, err := d.Observe(Reuse{JTI: jti, Source: src, ExpiresAt: exp})
+		if err != nil {
+			t.Fatalf("Observe: %v", err)
+		}
+		return revoked
+	}
+
+	if observe("a", "src-1") || observe("a", "src-1") || observe("b", "src-2") {
+		t.Error("presentations from the first source reported as reuse")
+	}
+	if !observe("a", "src-2") {
+		t.Error("reuse did not report the handler's revocation")
+	}
+	if len(reuses) != 1 || reuses[0].JTI != "a" || reuses[0].FirstSource != "src-1" || reuses[0].Source != "src-2" {
+		t.Errorf("reuses = %+v", reuses)
+	}
+}
+
+// memSources is an in-memory SourceStore whose records never expire.
+type memSources map[string]string
+
+func (m memSources) ObserveTokenSource(jti, source string, _ int64) (string, error) {
+	if first, ok := m[jti]; ok {
+		return first, nil
+	}
+	m[jti] = source
+	return source, nil
+}
+
+// failingSources is a SourceStore that cannot record anything.
+type failingSources struct{}
+
+func (failingSources) ObserveTokenSource(string, string, int64) (string, error) {
+	return "", errors.New("disk full")
+}
@@ -0,0 +1,212 @@
+// Package extauthz implements the Envoy ext_authz gRPC service so that target
+// services can enforce exchanged tokens at the sidecar. Envoy forwards each
+// inbound request's headers; the service verifies the bearer token's
+// signature, issuer, expiry, audience, required scopes, and revocation status
+// before Envoy lets the request through.
+package extauthz
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// Context extension keys read from the ext_authz per-route configuration.
+// Setting them on a route overrides the defaults described on Check.
+const (
+	// ExtAudience is the SPIFFE ID the token's aud claim must contain.
+	ExtAudience = "audience"
+	// ExtScopes is a space-separated list of scopes the token must carry.
+	ExtScopes = "scopes"
+)
+
+// Headers added to the upstream request when a token is accepted, so the
+// target service can read the verified caller without re-parsing the JWT.
+const (
+	HeaderSubject = "x-svid-exchange-subject"
+	HeaderScopes  = "x-svid-exchange-scopes"
+	// HeaderSourceIP and HeaderSourcePod carry the src claim of a token
+	// bound to its caller's network identity. They are set empty for any
+	// other token, so that a downstream client cannot supply its own.
+	HeaderSourceIP  = "x-svid-exchange-source-ip"
+	HeaderSourcePod = "x-svid-exchange-source-pod"
+)
+
+// KeyProvider returns the currently active public signing keys.
+type KeyProvider interface {
+	PublicKeys() []crypto.PublicKey
+}
+
+// Server implements the Envoy Authorization service.
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	keys      KeyProvider
+	isRevoked func(jti string) bool
+
+	// reuse, when set, reports tokens presented from more than one source;
+	// see SetReuseDetector.
+	reuse *ReuseDetector
+
+	// observeKey, when set, receives the key ID of every token that
+	// verifies; see SetKeyObserver.
+	observeKey func(kid string)
+
+	// consume, when set, records single-use tokens as consumed; see
+	// SetSingleUseStore.
+	consume func(jti string, expiresAt time.Time) (bool, error)
+}
+
+// New returns a Server that verifies tokens against the keys from kp and
+// rejects any token whose jti isRevoked reports as revoked.
+func New(kp KeyProvider, isRevoked func(jti string) bool) *Server {
+	return &Server{keys: kp, isRevoked: isRevoked}
+}
+
+// SetReuseDetector makes Check pass d the source of every token it accepts.
+// When d reports the token revoked, the request is denied; when d cannot
+// record the source, it is denied with 503. It must be called before the
+// server starts handling requests.
+func (s *Server) SetReuseDetector(d *ReuseDetector) {
+	s.reuse = d
+}
+
+// SetKeyObserver makes Check pass observe the kid header of every token whose
+// signature verifies, revoked or not, so the use of each signing key can be
+// tracked across a key transition. It must be called before the server
+// starts handling requests.
+func (s *Server) SetKeyObserver(observe func(kid string)) {
+	s.observeKey = observe
+}
+
+// SetSingleUseStore makes Check consume every single-use token it would
+// allow by passing its jti and expiry to consume, and deny it when consume
+// reports the token already consumed. Without it, single-use tokens are
+// denied, since their one use cannot be enforced. It must be called before
+// the server starts handling requests.
+func (s *Server) SetSingleUseStore(consume func(jti string, expiresAt time.Time) (bool, error)) {
+	s.consume = consume
+}
+
+// Check verifies the Authorization: Bearer token on the request Envoy is
+// asking about. The expected audience is the ExtAudience context extension
+// when set, otherwise the destination principal Envoy reports for its own
+// SVID. Missing or invalid tokens are denied with 401; tokens that verify but
+// lack a required scope are denied with 403.
+func (s *Server) Check(_ context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	attrs := req.GetAttributes()
+	ext := attrs.GetContextExtensions()
+
+	audience := ext[ExtAudience]
+	if audience == "" {
+		audience = attrs.GetDestination().GetPrincipal()
+	}
+	if audience == "" {
+		return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "no audience configured for this route"), nil
+	}
+
+	raw, ok := strings.CutPrefix(attrs.GetRequest().GetHttp().GetHeaders()["authorization"], "Bearer ")
+	if !ok || raw == "" {
+		return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "missing bearer token"), nil
+	}
+
+	claims, err := token.VerifyClaims(raw, s.keys.PublicKeys(), audience)
+	if err != nil {
+		return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, fmt.Sprintf("invalid token: %v", err)), nil
+	}
+	if s.observeKey != nil {
+		s.observeKey(token.HeaderKeyID(raw))
+	}
+	jti, _ := claims["jti"].(string)
+	if jti != "" && s.isRevoked(jti) {
+		return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "token has been revoked"), nil
+	}
+
+	sub, _ := claims["sub"].(string)
+	if src := source(attrs); s.reuse != nil && jti != "" && src != "" {
+		exp, _ := claims.GetExpirationTime()
+		revoked, err := s.reuse.Observe(Reuse{JTI: jti, Subject: sub, Audience: audience, ExpiresAt: exp.Time, Source: src})
+		if err != nil {
+			return deny(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, fmt.Sprintf("record token source: %v", err)), nil
+		}
+		if revoked {
+			return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "token has been revoked"), nil
+		}
+	}
+
+	granted, _ := claims["scope"].(string)
+	for _, want := range strings.Fields(ext[ExtScopes]) {
+		if !hasScope(granted, want) {
+			return deny(codes.PermissionDenied, typev3.StatusCode_Forbidden, fmt.Sprintf("token lacks required scope %q", want)), nil
+		}
+	}
+
+	// Consumed last, so that a request denied for another reason does not
+	// use up the token.
+	if once, _ := claims[token.ClaimSingleUse].(bool); once {
+		if s.consume == nil {
+			return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "single-use tokens are not accepted here"), nil
+		}
+		exp, _ := claims.GetExpirationTime()
+		fresh, err := s.consume(jti, exp.Time)
+		if err != nil {
+			return deny(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, fmt.Sprintf("consume single-use token: %v", err)), nil
+		}
+		if !fresh {
+			return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, "single-use token has already been used"), nil
+		}
+	}
+
+	headers := []*corev3.HeaderValueOption{
+		header(HeaderSubject, sub),
+		header(HeaderScopes, granted),
+	}
+	src, _ := claims["src"].(map[string]any)
+	ip, _ := src["ip"].(string)
+	pod, _ := src["pod"].(string)
+	headers = append(headers, header(HeaderSourceIP, ip), header(HeaderSourcePod, pod))
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{
+			Headers: headers,
+		}},
+	}, nil
+}
+
+func hasScope(granted, scope string) bool {
+	for _, s := range strings.Fields(granted) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
+
+// deny builds a denial. The message is returned to Envoy in the gRPC status
+// but not in the HTTP body, so token parsing details never reach the
+// downstream caller.
+func deny(code codes.Code, httpCode typev3.StatusCode, msg string) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code), Message: msg},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status: &typev3.HttpStatus{Code: httpCode},
+			Body:   httpCode.String(),
+		}},
+	}
+}
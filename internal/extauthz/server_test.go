@@ -0,0 +1,253 @@
+package extauthz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+const (
+	subject = "spiffe://cluster.local/ns/default/sa/order"
+	target  = "spiffe://cluster.local/ns/default/sa/payment"
+)
+
+func newMinter(t *testing.T) *token.Minter {
+	t.Helper()
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	return m
+}
+
+func mint(t *testing.T, m *token.Minter, aud string, scopes ...string) token.MintResult {
+	t.Helper()
+	res, err := m.Mint(context.Background(), subject, aud, scopes, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	return res
+}
+
+func checkReq(authz, principal string, ext map[string]string) *authv3.CheckRequest {
+	headers := map[string]string{}
+	if authz != "" {
+		headers["authorization"] = authz
+	}
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Destination:       &authv3.AttributeContext_Peer{Principal: principal},
+		Request:           &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{Headers: headers}},
+		ContextExtensions: ext,
+	}}
+}
+
+func TestCheck(t *testing.T) {
+	m := newMinter(t)
+	other := newMinter(t)
+	revoked := map[string]bool{}
+	srv := New(m, func(jti string) bool { return revoked[jti] })
+
+	good := mint(t, m, target, "payments:charge", "payments:refund")
+	wrongAud := mint(t, m, "spiffe://cluster.local/ns/default/sa/other", "payments:charge")
+	foreign := mint(t, other, target, "payments:charge")
+	rev := mint(t, m, target, "payments:charge")
+	revoked[rev.TokenID] = true
+
+	tests := []struct {
+		name     string
+		req      *authv3.CheckRequest
+		wantCode codes.Code
+		wantHTTP typev3.StatusCode // checked on denial only
+	}{
+		{
+			name:     "valid token with destination principal",
+			req:      checkReq("Bearer "+good.Token, target, nil),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "audience context extension overrides principal",
+			req:      checkReq("Bearer "+good.Token, "spiffe://cluster.local/ns/default/sa/envoy", map[string]string{ExtAudience: target}),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "required scopes present",
+			req:      checkReq("Bearer "+good.Token, target, map[string]string{ExtScopes: "payments:charge payments:refund"}),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "missing required scope is forbidden",
+			req:      checkReq("Bearer "+good.Token, target, map[string]string{ExtScopes: "payments:admin"}),
+			wantCode: codes.PermissionDenied,
+			wantHTTP: typev3.StatusCode_Forbidden,
+		},
+		{
+			name:     "missing header",
+			req:      checkReq("", target, nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+		{
+			name:     "non-bearer scheme",
+			req:      checkReq("Basic Zm9vOmJhcg==", target, nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+		{
+			name:     "wrong audience",
+			req:      checkReq("Bearer "+wrongAud.Token, target, nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+		{
+			name:     "signed by unknown key",
+			req:      checkReq("Bearer "+foreign.Token, target, nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+		{
+			name:     "revoked token",
+			req:      checkReq("Bearer "+rev.Token, target, nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+		{
+			name:     "no audience available",
+			req:      checkReq("Bearer "+good.Token, "", nil),
+			wantCode: codes.Unauthenticated,
+			wantHTTP: typev3.StatusCode_Unauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := srv.Check(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if got := codes.Code(resp.GetStatus().GetCode()); got != tc.wantCode {
+				t.Fatalf("code = %v (%s), want %v", got, resp.GetStatus().GetMessage(), tc.wantCode)
+			}
+			if tc.wantCode == codes.OK {
+				hdrs := map[string]string{}
+				for _, h := range resp.GetOkResponse().GetHeaders() {
+					hdrs[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+				}
+				if hdrs[HeaderSubject] != subject {
+					t.Errorf("%s = %q, want %q", HeaderSubject, hdrs[HeaderSubject], subject)
+				}
+				if hdrs[HeaderScopes] != "payments:charge payments:refund" {
+					t.Errorf("%s = %q", HeaderScopes, hdrs[HeaderScopes])
+				}
+				return
+			}
+			if got := resp.GetDeniedResponse().GetStatus().GetCode(); got != tc.wantHTTP {
+				t.Errorf("HTTP status = %v, want %v", got, tc.wantHTTP)
+			}
+		})
+	}
+}
+
+func TestCheckKeyObserver(t *testing.T) {
+	m := newMinter(t)
+	srv := New(m, func(string) bool { return false })
+	var kids []string
+	srv.SetKeyObserver(func(kid string) { kids = append(kids, kid) })
+
+	good := mint(t, m, target, "payments:charge")
+	if _, err := srv.Check(context.Background(), checkReq("Bearer "+good.Token, target, nil)); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	foreign := mint(t, newMinter(t), target, "payments:charge")
+	if _, err := srv.Check(context.Background(), checkReq("Bearer "+foreign.Token, target, nil)); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	want, _ := token.KeyID(m.PublicKey())
+	if len(kids) != 1 || kids[0] != want {
+		t.Errorf("observed %q, want only [%q]", kids, want)
+	}
+}
+
+func TestCheckSourceHeaders(t *testing.T) {
+	m := newMinter(t)
+	srv := New(m, func(string) bool { return false })
+	ctx := token.WithSource(context.Background(), token.Source{IP: "10.4.0.17", Pod: "default/order-1"})
+	bound, err := m.Mint(ctx, subject, target, []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name            string
+		token           string
+		wantIP, wantPod string
+	}{
+		{name: "bound token", token: bound.Token, wantIP: "10.4.0.17", wantPod: "default/order-1"},
+		{name: "unbound token", token: mint(t, m, target, "payments:charge").Token},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := srv.Check(context.Background(), checkReq("Bearer "+tc.token, target, nil))
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			got := map[string]string{}
+			for _, h := range resp.GetOkResponse().GetHeaders() {
+				got[h.GetHeader().GetKey()] = h.GetHeader().GetValue()
+			}
+			ip, ipSet := got[HeaderSourceIP]
+			pod, podSet := got[HeaderSourcePod]
+			if !ipSet || !podSet || ip != tc.wantIP || pod != tc.wantPod {
+				t.Errorf("source headers = %q, %q; want %q, %q", ip, pod, tc.wantIP, tc.wantPod)
+			}
+		})
+	}
+}
+
+func TestCheckSingleUse(t *testing.T) {
+	m := newMinter(t)
+	once, err := m.Mint(token.WithSingleUse(context.Background()), subject, target, []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	check := func(srv *Server, ext map[string]string) codes.Code {
+		t.Helper()
+		resp, err := srv.Check(context.Background(), checkReq("Bearer "+once.Token, target, ext))
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return codes.Code(resp.GetStatus().GetCode())
+	}
+
+	t.Run("without a store the token is denied", func(t *testing.T) {
+		if got := check(New(m, func(string) bool { return false }), nil); got != codes.Unauthenticated {
+			t.Errorf("code = %v, want Unauthenticated", got)
+		}
+	})
+
+	t.Run("the token is allowed once", func(t *testing.T) {
+		srv := New(m, func(string) bool { return false })
+		consumed := map[string]bool{}
+		srv.SetSingleUseStore(func(jti string, _ time.Time) (bool, error) {
+			if consumed[jti] {
+				return false, nil
+			}
+			consumed[jti] = true
+			return true, nil
+		})
+		// A request denied for a missing scope leaves the token unused.
+		if got := check(srv, map[string]string{ExtScopes: "payments:refund"}); got != codes.PermissionDenied {
+			t.Fatalf("code = %v, want PermissionDenied", got)
+		}
+		if got := check(srv, nil); got != codes.OK {
+			t.Fatalf("first use: code = %v, want OK", got)
+		}
+		if got := check(srv, nil); got != codes.Unauthenticated {
+			t.Errorf("second use: code = %v, want Unauthenticated", got)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package httpserv
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Per-endpoint access modes accepted by WithEndpointAuth.
+const (
+	// AuthNone serves the endpoint to anyone who can reach it.
+	AuthNone = "none"
+	// AuthBearer requires "Authorization: Bearer <token>".
+	AuthBearer = "bearer"
+	// AuthMTLS requires a client certificate verified against the listener's
+	// client CA. The listener must have one configured.
+	AuthMTLS = "mtls"
+)
+
+// ValidAuthMode reports whether mode is an access mode WithEndpointAuth
+// understands. The empty string is treated as AuthNone.
+func ValidAuthMode(mode string) bool {
+	switch mode {
+	case "", AuthNone, AuthBearer, AuthMTLS:
+		return true
+	}
+	return false
+}
+
+// WithEndpointAuth wraps h so that it only serves requests satisfying mode.
+// bearerToken is the expected token for AuthBearer. Rejected requests get 401
+// and never reach h.
+func WithEndpointAuth(mode, bearerToken string, h http.Handler) http.Handler {
+	switch mode {
+	case AuthBearer:
+		want := []byte("Bearer " + bearerToken)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	case AuthMTLS:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// VerifiedChains is only populated when the certificate chained
+			// to the client CA during the handshake.
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				http.Error(w, "client certificate required", http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	default:
+		return h
+	}
+}
+
+// WithCORS wraps h with CORS headers so browser clients on allowedOrigins
+// can read the response — typically JWKS fetched by a single-page app. "*"
+// allows any origin. Preflight requests are answered directly, so WithCORS
+// must wrap WithEndpointAuth: preflights carry no credentials. With no
+// allowed origins h is returned unchanged.
+func WithCORS(allowedOrigins []string, h http.Handler) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return h
+	}
+	wildcard := slices.Contains(allowedOrigins, "*")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!wildcard && !slices.Contains(allowedOrigins, origin)) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if wildcard {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{http.MethodGet, http.MethodOptions}, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
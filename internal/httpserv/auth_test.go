@@ -0,0 +1,87 @@
+package httpserv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestWithEndpointAuth(t *testing.T) {
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+	tests := []struct {
+		name     string
+		mode     string
+		header   string
+		tls      *tls.ConnectionState
+		wantCode int
+	}{
+		{name: "none allows anonymous", mode: AuthNone, wantCode: http.StatusOK},
+		{name: "unset mode allows anonymous", mode: "", wantCode: http.StatusOK},
+		{name: "bearer with token", mode: AuthBearer, header: "Bearer s3cret", wantCode: http.StatusOK},
+		{name: "bearer with wrong token", mode: AuthBearer, header: "Bearer nope", wantCode: http.StatusUnauthorized},
+		{name: "bearer without header", mode: AuthBearer, wantCode: http.StatusUnauthorized},
+		{name: "mtls with verified chain", mode: AuthMTLS, tls: verified, wantCode: http.StatusOK},
+		{name: "mtls without client cert", mode: AuthMTLS, tls: &tls.ConnectionState{}, wantCode: http.StatusUnauthorized},
+		{name: "mtls over plaintext", mode: AuthMTLS, wantCode: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			req.TLS = tc.tls
+			rec := httptest.NewRecorder()
+			WithEndpointAuth(tc.mode, "s3cret", okHandler).ServeHTTP(rec, req)
+			if rec.Code != tc.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		origins    []string
+		method     string
+		origin     string
+		preflight  bool
+		wantCode   int
+		wantOrigin string
+	}{
+		{name: "allowed origin", origins: []string{"https://app.example.com"}, method: http.MethodGet, origin: "https://app.example.com", wantCode: http.StatusOK, wantOrigin: "https://app.example.com"},
+		{name: "other origin gets no header", origins: []string{"https://app.example.com"}, method: http.MethodGet, origin: "https://evil.example.com", wantCode: http.StatusOK},
+		{name: "wildcard", origins: []string{"*"}, method: http.MethodGet, origin: "https://any.example.com", wantCode: http.StatusOK, wantOrigin: "*"},
+		{name: "preflight answered directly", origins: []string{"https://app.example.com"}, method: http.MethodOptions, origin: "https://app.example.com", preflight: true, wantCode: http.StatusNoContent, wantOrigin: "https://app.example.com"},
+		{name: "disabled", method: http.MethodGet, origin: "https://app.example.com", wantCode: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/jwks", nil)
+			req.Header.Set("Origin", tc.origin)
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			} else {
+				req.Header.Set("Authorization", "Bearer s3cret")
+			}
+			rec := httptest.NewRecorder()
+			// Auth sits inside CORS as documented: a preflight carries no
+			// credentials and must be answered before auth runs.
+			WithCORS(tc.origins, WithEndpointAuth(AuthBearer, "s3cret", okHandler)).ServeHTTP(rec, req)
+			if rec.Code != tc.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantCode)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+		})
+	}
+}
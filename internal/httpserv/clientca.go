@@ -0,0 +1,117 @@
+package httpserv
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// clientCAs holds a listener's client CA pool and reloads it from disk.
+// Handshakes read the pool through GetConfigForClient, so a reload applies to
+// the next connection without restarting the listener.
+type clientCAs struct {
+	path string
+	pool atomic.Pointer[x509.CertPool]
+	// last is the file content behind pool; only the refresh goroutine
+	// touches it after construction.
+	last []byte
+}
+
+// newClientCAs loads the bundle at path.
+func newClientCAs(path string) (*clientCAs, error) {
+	c := &clientCAs{path: path}
+	if _, _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload re-reads the bundle and swaps in the new pool when its content
+// changed. It reports whether it swapped and how many certificates the new
+// pool holds. On error the previous pool stays in place.
+func (c *clientCAs) reload() (changed bool, n int, err error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return false, 0, fmt.Errorf("read client CA: %w", err)
+	}
+	if c.last != nil && bytes.Equal(data, c.last) {
+		return false, 0, nil
+	}
+	pool, n, err := parseClientCAs(data)
+	if err != nil {
+		return false, 0, fmt.Errorf("client CA %q: %w", c.path, err)
+	}
+	c.pool.Store(pool)
+	c.last = data
+	return true, n, nil
+}
+
+// refresh reloads the bundle every interval until done is closed, logging
+// each swap and each failure. A failed reload keeps serving the previous
+// pool, so a half-written file during rotation does not lock clients out.
+func (c *clientCAs) refresh(interval time.Duration, done <-chan struct{}, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			changed, n, err := c.reload()
+			switch {
+			case err != nil:
+				log.Error().Err(err).Msg("client CA reload failed; keeping previous bundle")
+			case changed:
+				log.Info().Str("path", c.path).Int("certificates", n).Msg("client CA bundle reloaded")
+			}
+		}
+	}
+}
+
+// configForClient returns a GetConfigForClient callback that serves base
+// with the current pool.
+func (c *clientCAs) configForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		tc := base.Clone()
+		tc.ClientCAs = c.pool.Load()
+		return tc, nil
+	}
+}
+
+// parseClientCAs builds a pool from every CERTIFICATE block in data. Unlike
+// x509.CertPool.AppendCertsFromPEM, which skips blocks it cannot parse, a
+// malformed certificate is an error: silently dropping an intermediate would
+// surface only as handshake failures for the clients it issued. Every
+// certificate in the bundle, root or intermediate, is a trust anchor, so a
+// client whose chain reaches any of them is accepted.
+func parseClientCAs(data []byte) (*x509.CertPool, int, error) {
+	pool := x509.NewCertPool()
+	n := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("certificate %d: %w", n+1, err)
+		}
+		pool.AddCert(cert)
+		n++
+	}
+	if n == 0 {
+		return nil, 0, fmt.Errorf("contains no PEM certificates")
+	}
+	return pool, n, nil
+}
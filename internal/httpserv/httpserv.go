@@ -0,0 +1,247 @@
+// Package httpserv runs the server's auxiliary HTTP listeners — health,
+// metrics, and key distribution — each on its own address with its own TLS
+// and client-certificate requirements, and provides the per-endpoint access
+// and CORS wrappers shared by all of them.
+package httpserv
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Listener-level client certificate modes.
+const (
+	// ClientAuthNone never asks for a client certificate.
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies a client certificate against the client
+	// CA when one is presented, but accepts connections without one. Use it
+	// when only some endpoints on the listener require AuthMTLS.
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects any connection without a client certificate
+	// that chains to the client CA, before a request is read.
+	ClientAuthRequire = "require"
+)
+
+// Config describes one listener.
+type Config struct {
+	// Name identifies the listener in logs.
+	Name string
+	Addr string
+	// ExtraAddrs are further addresses served with the same handlers and
+	// TLS settings, e.g. an IPv6 or pod-IP binding beside Addr.
+	ExtraAddrs []string
+	// CertFile and KeyFile enable HTTPS. Both empty means plain HTTP.
+	CertFile string
+	KeyFile  string
+	// CertRefresh re-reads CertFile and KeyFile at this interval so a renewed
+	// certificate takes effect without a restart. Zero loads them once.
+	CertRefresh time.Duration
+	// TLSConfig enables HTTPS with a configuration the caller keeps current,
+	// such as one backed by the SPIFFE Workload API, in place of CertFile,
+	// KeyFile, and the client CA settings.
+	TLSConfig *tls.Config
+	// ClientCAFile is the PEM bundle client certificates must chain to. It
+	// is required unless ClientAuth is empty or ClientAuthNone. It may hold
+	// several roots and intermediates; each is trusted on its own.
+	ClientCAFile string
+	ClientAuth   string
+	// ClientCARefresh re-reads ClientCAFile at this interval so a rotated
+	// bundle takes effect without a restart. Zero loads it once.
+	ClientCARefresh time.Duration
+	// WriteTimeout overrides the default 10s response deadline, for
+	// listeners with long-running endpoints such as CPU profiles.
+	WriteTimeout time.Duration
+}
+
+// TLS reports whether the listener serves HTTPS.
+func (c Config) TLS() bool {
+	return c.CertFile != "" || c.TLSConfig != nil
+}
+
+// Addrs returns Addr followed by ExtraAddrs.
+func (c Config) Addrs() []string {
+	return append([]string{c.Addr}, c.ExtraAddrs...)
+}
+
+// VerifiesClients reports whether the listener verifies client
+// certificates, which AuthMTLS endpoints depend on.
+func (c Config) VerifiesClients() bool {
+	return c.ClientAuth == ClientAuthOptional || c.ClientAuth == ClientAuthRequire
+}
+
+// Validate checks that c is internally consistent without touching the
+// filesystem.
+func (c Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("%s listener: addr is required", c.Name)
+	}
+	seen := map[string]bool{c.Addr: true}
+	for _, a := range c.ExtraAddrs {
+		if a == "" {
+			return fmt.Errorf("%s listener: extra addresses must not be empty", c.Name)
+		}
+		if seen[a] {
+			return fmt.Errorf("%s listener: address %q is listed twice", c.Name, a)
+		}
+		seen[a] = true
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("%s listener: certificate and key must be set together", c.Name)
+	}
+	if c.TLSConfig != nil && (c.CertFile != "" || c.ClientCAFile != "" || c.ClientAuth != "") {
+		return fmt.Errorf("%s listener: a TLS configuration excludes certificate and client CA settings", c.Name)
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if !c.TLS() {
+			return fmt.Errorf("%s listener: client_auth %q requires TLS", c.Name, c.ClientAuth)
+		}
+		if c.ClientCAFile == "" {
+			return fmt.Errorf("%s listener: client_auth %q requires a client CA", c.Name, c.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("%s listener: invalid client_auth %q (must be %s, %s, or %s)", c.Name, c.ClientAuth, ClientAuthNone, ClientAuthOptional, ClientAuthRequire)
+	}
+	if c.ClientCARefresh < 0 {
+		return fmt.Errorf("%s listener: client CA refresh interval must not be negative", c.Name)
+	}
+	if c.CertRefresh < 0 {
+		return fmt.Errorf("%s listener: certificate refresh interval must not be negative", c.Name)
+	}
+	return nil
+}
+
+// Server is one HTTP listener and its mux. Register handlers with Handle
+// before calling Start.
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+	srv *http.Server
+	// kp is the serving key pair; nil unless the listener serves HTTPS.
+	kp *keyPair
+	// cas is the client CA pool; nil unless the listener verifies clients.
+	cas  *clientCAs
+	done chan struct{}
+}
+
+// New validates cfg, loads its certificate and client CA, and returns an
+// unstarted Server.
+// The server uses fixed connection timeouts sized for small GET endpoints,
+// which also guard against slow-client attacks; only WriteTimeout can be
+// raised.
+func New(cfg Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	writeTimeout := 10 * time.Second
+	if cfg.WriteTimeout > 0 {
+		writeTimeout = cfg.WriteTimeout
+	}
+	mux := http.NewServeMux()
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       60 * time.Second,
+	}
+	s := &Server{cfg: cfg, mux: mux, srv: srv, done: make(chan struct{})}
+	if cfg.TLSConfig != nil {
+		srv.TLSConfig = cfg.TLSConfig.Clone()
+		return s, nil
+	}
+	if cfg.TLS() {
+		kp, err := newKeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s listener: %w", cfg.Name, err)
+		}
+		tlsCfg, cas, err := tlsConfig(cfg, kp)
+		if err != nil {
+			return nil, fmt.Errorf("%s listener: %w", cfg.Name, err)
+		}
+		srv.TLSConfig, s.kp, s.cas = tlsCfg, kp, cas
+	}
+	return s, nil
+}
+
+// tlsConfig serves kp and, when the listener verifies clients, loads its
+// client CA pool. The key pair and pool are served through GetCertificate and
+// GetConfigForClient so that Start can refresh them in place.
+func tlsConfig(cfg Config, kp *keyPair) (*tls.Config, *clientCAs, error) {
+	tc := &tls.Config{MinVersion: tls.VersionTLS13, GetCertificate: kp.getCertificate}
+	if !cfg.VerifiesClients() {
+		return tc, nil, nil
+	}
+	cas, err := newClientCAs(cfg.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tc.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.ClientAuth == ClientAuthRequire {
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	tc.GetConfigForClient = cas.configForClient(tc.Clone())
+	return tc, cas, nil
+}
+
+// Config returns the listener's configuration.
+func (s *Server) Config() Config {
+	return s.cfg
+}
+
+// Handle registers h for path.
+func (s *Server) Handle(path string, h http.Handler) {
+	s.mux.Handle(path, h)
+}
+
+// Start serves each of the listener's addresses in a background goroutine,
+// and refreshes the key pair and client CA bundle in others when CertRefresh
+// and ClientCARefresh are set. Listen and serve errors other than a clean
+// shutdown, and failed refreshes, are logged to log.
+func (s *Server) Start(log zerolog.Logger) {
+	llog := log.With().Str("listener", s.cfg.Name).Logger()
+	if s.kp != nil && s.cfg.CertRefresh > 0 {
+		go s.kp.refresh(s.cfg.CertRefresh, s.done, llog)
+	}
+	if s.cas != nil && s.cfg.ClientCARefresh > 0 {
+		go s.cas.refresh(s.cfg.ClientCARefresh, s.done, llog)
+	}
+	for _, addr := range s.cfg.Addrs() {
+		go func() {
+			llog.Info().Str("addr", addr).Bool("tls", s.cfg.TLS()).Msg("HTTP listening")
+			if err := s.serve(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				llog.Error().Err(err).Str("addr", addr).Msg("HTTP serve error")
+			}
+		}()
+	}
+}
+
+// serve listens on addr and serves until Shutdown. One http.Server serves
+// every address, so Shutdown closes them all.
+func (s *Server) serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS() {
+		// The key pair is already in TLSConfig.
+		return s.srv.ServeTLS(ln, "", "")
+	}
+	return s.srv.Serve(ln)
+}
+
+// Shutdown gracefully stops the listener and its client CA refresh; see
+// http.Server.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.done)
+	return s.srv.Shutdown(ctx)
+}
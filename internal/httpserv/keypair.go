@@ -0,0 +1,84 @@
+package httpserv
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// keyPair holds a serving certificate and reloads it from disk. Handshakes
+// read it through GetCertificate, so a reload applies to the next connection
+// without restarting the listener — as when cert-manager or a CSI driver
+// renews the files behind a mounted secret.
+type keyPair struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+	// lastCert and lastKey are the file contents behind cert; only the
+	// refresh goroutine touches them after construction.
+	lastCert, lastKey []byte
+}
+
+// newKeyPair loads the PEM certificate and key at certFile and keyFile.
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	k := &keyPair{certFile: certFile, keyFile: keyFile}
+	if _, err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// getCertificate returns the current certificate; it has the signature of
+// tls.Config.GetCertificate.
+func (k *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return k.cert.Load(), nil
+}
+
+// reload re-reads both files and swaps in the new certificate when either
+// changed. On error the previous certificate stays in place.
+func (k *keyPair) reload() (changed bool, err error) {
+	certPEM, err := os.ReadFile(k.certFile)
+	if err != nil {
+		return false, fmt.Errorf("read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(k.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("read key: %w", err)
+	}
+	if k.lastCert != nil && bytes.Equal(certPEM, k.lastCert) && bytes.Equal(keyPEM, k.lastKey) {
+		return false, nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("load certificate %q: %w", k.certFile, err)
+	}
+	k.cert.Store(&cert)
+	k.lastCert, k.lastKey = certPEM, keyPEM
+	return true, nil
+}
+
+// refresh reloads the key pair every interval until done is closed, logging
+// each swap and each failure. A failed reload — a certificate renewed before
+// its key, say — keeps serving the previous pair.
+func (k *keyPair) refresh(interval time.Duration, done <-chan struct{}, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			changed, err := k.reload()
+			switch {
+			case err != nil:
+				log.Error().Err(err).Msg("certificate reload failed; keeping previous certificate")
+			case changed:
+				log.Info().Str("path", k.certFile).Msg("serving certificate reloaded")
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package httpserv
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewKeyPair(t *testing.T) {
+	ca := newTestCA(t)
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "valid pair", certFile: ca.serverCert, keyFile: ca.serverKey},
+		{name: "missing certificate", certFile: ca.serverCert + ".missing", keyFile: ca.serverKey, wantErr: true},
+		{name: "missing key", certFile: ca.serverCert, keyFile: ca.serverKey + ".missing", wantErr: true},
+		{name: "key does not match", certFile: ca.serverCert, keyFile: newTestCA(t).serverKey, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kp, err := newKeyPair(tc.certFile, tc.keyFile)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newKeyPair() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil {
+				if cert, _ := kp.getCertificate(nil); cert == nil {
+					t.Error("getCertificate returned nil")
+				}
+			}
+		})
+	}
+}
+
+func TestServerRefreshesKeyPair(t *testing.T) {
+	ca := newTestCA(t)
+	renewed := newTestCA(t)
+	addr := freeAddr(t)
+	srv, err := New(Config{
+		Name:        "keys",
+		Addr:        addr,
+		CertFile:    ca.serverCert,
+		KeyFile:     ca.serverKey,
+		CertRefresh: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	srv.Handle("/metrics", okHandler)
+	srv.Start(zerolog.Nop())
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	if err := getTLS(addr, ca.pool); err != nil {
+		t.Fatalf("request before renewal: %v", err)
+	}
+
+	// A certificate renewed before its key does not load; the old pair
+	// stays in service.
+	copyFile(t, renewed.serverCert, ca.serverCert)
+	time.Sleep(50 * time.Millisecond)
+	if err := getTLS(addr, ca.pool); err != nil {
+		t.Fatalf("old certificate not served after a mismatched write: %v", err)
+	}
+
+	copyFile(t, renewed.serverKey, ca.serverKey)
+	var lastErr error
+	for range 50 {
+		if lastErr = getTLS(addr, renewed.pool); lastErr == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if lastErr != nil {
+		t.Errorf("renewed certificate not served: %v", lastErr)
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	data, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mustKeyPair loads ca's server certificate.
+func mustKeyPair(t *testing.T, ca testCA) *keyPair {
+	t.Helper()
+	kp, err := newKeyPair(ca.serverCert, ca.serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp
+}
@@ -0,0 +1,273 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+	"github.com/ngaddam369/svid-exchange/pkg/client"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// trustDomain is an X.509 trust domain for the bufconn suite: a CA and the
+// go-spiffe bundle built from it.
+type trustDomain struct {
+	td     spiffeid.TrustDomain
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	bundle *x509bundle.Bundle
+	serial int64
+}
+
+func newTrustDomain(t *testing.T, name string) *trustDomain {
+	t.Helper()
+	_, cert, key := newCA(t)
+	td := spiffeid.RequireTrustDomainFromString(name)
+	return &trustDomain{td: td, cert: cert, key: key, bundle: x509bundle.FromX509Authorities(td, []*x509.Certificate{cert}), serial: 100}
+}
+
+// svid issues an X.509 SVID for path in the trust domain, with the SPIFFE ID
+// as its only URI SAN, as SPIRE issues them.
+func (d *trustDomain) svid(t *testing.T, path string) *x509svid.SVID {
+	t.Helper()
+	id := spiffeid.RequireFromPath(d.td, path)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	u, _ := url.Parse(id.String())
+	d.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(d.serial),
+		Subject:      pkix.Name{Organization: []string{"SPIRE"}},
+		URIs:         []*url.URL{u},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, d.cert, &key.PublicKey, d.key)
+	if err != nil {
+		t.Fatalf("create SVID: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse SVID: %v", err)
+	}
+	return &x509svid.SVID{ID: id, Certificates: []*x509.Certificate{leaf}, PrivateKey: key}
+}
+
+// bufconnEnv is an exchange server on an in-memory listener, with the TLS
+// configuration main builds from the Workload API, and a JWKS endpoint.
+type bufconnEnv struct {
+	lis      *bufconn.Listener
+	serverID spiffeid.ID
+	minter   *token.Minter
+	jwksURL  string
+}
+
+func newBufconnEnv(t *testing.T, td *trustDomain, policies []policy.Policy) *bufconnEnv {
+	t.Helper()
+	serverSVID := td.svid(t, "/svid-exchange")
+	loader, err := policy.NewLoader(policies)
+	if err != nil {
+		t.Fatalf("new policy loader: %v", err)
+	}
+	minter, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("new minter: %v", err)
+	}
+	// main wraps each configured authentication method in a chain, which
+	// is what reports the method to WhoAmI and the audit log.
+	extractor := server.ExtractorChain{{Method: "x509-svid", IDExtractor: spiffe.Extractor{}}}
+	svc := server.New(extractor, loaderEvaluator{loader}, minter, audit.New(io.Discard))
+
+	tlsCfg := tlsconfig.MTLSServerConfig(serverSVID, td.bundle, tlsconfig.AuthorizeMemberOf(td.td))
+	lis := bufconn.Listen(1 << 20)
+	grpcSrv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)))
+	exchangev1.RegisterTokenExchangeServer(grpcSrv, svc)
+	exchangev2.RegisterTokenExchangeServer(grpcSrv, svc.V2())
+	go func() { _ = grpcSrv.Serve(lis) }()
+	t.Cleanup(grpcSrv.Stop)
+
+	jwks := httptest.NewServer(jwksHandler(t, minter))
+	t.Cleanup(jwks.Close)
+	return &bufconnEnv{lis: lis, serverID: serverSVID.ID, minter: minter, jwksURL: jwks.URL}
+}
+
+// jwksHandler serves minter's public keys as the server's /jwks does.
+func jwksHandler(t *testing.T, minter *token.Minter) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		set := jwk.Set{}
+		for _, pub := range minter.PublicKeys() {
+			k, err := jwk.FromPublicKey(pub)
+			if err != nil {
+				t.Errorf("jwks: %v", err)
+				return
+			}
+			alg, err := token.AlgorithmFor(pub)
+			if err != nil {
+				t.Errorf("jwks: %v", err)
+				return
+			}
+			if k.Kid, err = token.KeyID(pub); err != nil {
+				t.Errorf("jwks: %v", err)
+				return
+			}
+			k.Alg, k.Use = string(alg), "sig"
+			set.Keys = append(set.Keys, k)
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}
+}
+
+// dial connects to the server as svid, authorizing the server's SPIFFE ID
+// the way the client library does.
+func (e *bufconnEnv) dial(t *testing.T, svid *x509svid.SVID, bundle x509bundle.Source) *grpc.ClientConn {
+	t.Helper()
+	return e.dialTLS(t, tlsconfig.MTLSClientConfig(svid, bundle, tlsconfig.AuthorizeID(e.serverID)))
+}
+
+// dialTLS connects to the server with tlsCfg.
+func (e *bufconnEnv) dialTLS(t *testing.T, tlsCfg *tls.Config) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return e.lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestBufconnExchange(t *testing.T) {
+	const (
+		orderPath   = "/ns/default/sa/order"
+		paymentPath = "/ns/default/sa/payment"
+	)
+	td := newTrustDomain(t, "cluster.local")
+	order := spiffeid.RequireFromPath(td.td, orderPath).String()
+	payment := spiffeid.RequireFromPath(td.td, paymentPath).String()
+	env := newBufconnEnv(t, td, []policy.Policy{{
+		Name:          "order-to-payment",
+		Subject:       order,
+		Target:        payment,
+		AllowedScopes: []string{"payments:charge", "payments:refund"},
+		MaxTTL:        300,
+	}})
+	ctx := context.Background()
+
+	t.Run("token verifies against the JWKS", func(t *testing.T) {
+		c := client.NewFromConn(env.dial(t, td.svid(t, orderPath), td.bundle), client.Options{
+			TargetService: payment,
+			Scopes:        []string{"payments:charge"},
+			TTLSeconds:    60,
+		})
+		defer func() { _ = c.Close() }()
+		tok, err := c.Token(ctx)
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		v, err := client.NewVerifier(ctx, env.jwksURL)
+		if err != nil {
+			t.Fatalf("NewVerifier: %v", err)
+		}
+		claims, err := v.Verify(tok, payment)
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if claims["sub"] != order || !client.HasScope(claims, "payments:charge") || client.HasScope(claims, "payments:refund") {
+			t.Errorf("claims = %v", claims)
+		}
+
+		// A token minted before a rotation still verifies once the
+		// verifier picks up the new JWKS, which serves both keys.
+		if err := env.minter.Rotate(); err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		if err := v.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		if _, err := v.Verify(tok, payment); err != nil {
+			t.Errorf("Verify after rotation: %v", err)
+		}
+	})
+
+	t.Run("caller identity comes from the SPIFFE SAN", func(t *testing.T) {
+		resp, err := exchangev2.NewTokenExchangeClient(env.dial(t, td.svid(t, orderPath), td.bundle)).WhoAmI(ctx, &exchangev2.WhoAmIRequest{})
+		if err != nil {
+			t.Fatalf("WhoAmI: %v", err)
+		}
+		if resp.SpiffeId != order || resp.AuthMethod != "x509-svid" {
+			t.Errorf("WhoAmI = %q via %q, want %q via x509-svid", resp.SpiffeId, resp.AuthMethod, order)
+		}
+	})
+
+	t.Run("policy denies an unlisted pair", func(t *testing.T) {
+		_, err := exchangev1.NewTokenExchangeClient(env.dial(t, td.svid(t, paymentPath), td.bundle)).Exchange(ctx, &exchangev1.ExchangeRequest{
+			TargetService: order,
+			Scopes:        []string{"payments:charge"},
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Exchange: got %v, want PermissionDenied", err)
+		}
+	})
+
+	t.Run("handshake rejects a foreign trust domain", func(t *testing.T) {
+		other := newTrustDomain(t, "other.example")
+		bundles := x509bundle.NewSet(td.bundle, other.bundle)
+		_, err := exchangev1.NewTokenExchangeClient(env.dial(t, other.svid(t, orderPath), bundles)).Exchange(ctx, &exchangev1.ExchangeRequest{
+			TargetService: payment,
+			Scopes:        []string{"payments:charge"},
+		})
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("Exchange: got %v, want Unavailable from the failed handshake", err)
+		}
+	})
+
+	t.Run("handshake rejects a certificate without a SPIFFE ID", func(t *testing.T) {
+		tlsCfg := tlsconfig.TLSClientConfig(td.bundle, tlsconfig.AuthorizeID(env.serverID))
+		tlsCfg.Certificates = []tls.Certificate{newClientCert(t, td.cert, td.key, "")}
+		_, err := exchangev1.NewTokenExchangeClient(env.dialTLS(t, tlsCfg)).Exchange(ctx, &exchangev1.ExchangeRequest{
+			TargetService: payment,
+			Scopes:        []string{"payments:charge"},
+		})
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("Exchange: got %v, want Unavailable from the failed handshake", err)
+		}
+	})
+}
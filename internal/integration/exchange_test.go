@@ -22,6 +22,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/ngaddam369/svid-exchange/internal/audit"
@@ -113,6 +114,15 @@ func newClientCert(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateK
 	return tlsCert
 }
 
+// loaderEvaluator adapts a policy.Loader to server.PolicyEvaluator.
+type loaderEvaluator struct {
+	*policy.Loader
+}
+
+func (l loaderEvaluator) Evaluate(_ context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	return l.Loader.Evaluate(subject, target, scopes, ttlSeconds), nil
+}
+
 // newTestEnv starts an in-process gRPC server wired with real dependencies:
 // spiffe.Extractor, policy.Loader, token.Minter, and audit.Logger. The server
 // enforces mTLS — clients must present a certificate signed by the test CA.
@@ -165,7 +175,7 @@ func newTestEnv(t *testing.T, policies []policy.Policy) *testEnv {
 		t.Fatalf("new minter: %v", err)
 	}
 
-	svc := server.New(spiffe.Extractor{}, loader, minter, audit.New(io.Discard))
+	svc := server.New(spiffe.Extractor{}, loaderEvaluator{loader}, minter, audit.New(io.Discard))
 
 	lis, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -324,16 +334,25 @@ func TestExchange(t *testing.T) {
 			defer cancel()
 
 			client := clientAs(t, env.addr, tc.cert, env.rootCA)
-			resp, err := client.Exchange(ctx, tc.req)
+			const reqID = "integration-req-1"
+			ctx = metadata.AppendToOutgoingContext(ctx, server.RequestIDHeader, reqID)
+			var header metadata.MD
+			resp, err := client.Exchange(ctx, tc.req, grpc.Header(&header))
 			if status.Code(err) != tc.wantCode {
 				t.Fatalf("code = %v, want %v: %v", status.Code(err), tc.wantCode, err)
 			}
+			if got := header.Get(server.RequestIDHeader); len(got) != 1 || got[0] != reqID {
+				t.Errorf("%s response header = %v, want [%s]", server.RequestIDHeader, got, reqID)
+			}
 			if tc.wantCode != codes.OK {
 				return
 			}
 			if resp.Token == "" {
 				t.Error("token is empty")
 			}
+			if got := header.Get(server.PolicyRuleHeader); len(got) == 0 {
+				t.Errorf("%s response header is missing", server.PolicyRuleHeader)
+			}
 			if tc.check != nil {
 				tc.check(t, resp)
 			}
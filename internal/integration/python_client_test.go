@@ -0,0 +1,124 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// pythonClientGen is where make proto-python writes the generated stubs,
+// relative to this package.
+const pythonClientGen = "../../clients/python/gen"
+
+// writePEM writes the client certificate, its key, and the CA certificate
+// as PEM files in a temporary directory and returns their paths.
+func writePEM(t *testing.T, cert tls.Certificate, caCert *x509.Certificate) (caPath, certPath, keyPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("marshal client key: %v", err)
+	}
+	caPath = filepath.Join(dir, "ca.pem")
+	certPath = filepath.Join(dir, "svid.pem")
+	keyPath = filepath.Join(dir, "svid.key")
+	for path, block := range map[string]*pem.Block{
+		caPath:   {Type: "CERTIFICATE", Bytes: caCert.Raw},
+		certPath: {Type: "CERTIFICATE", Bytes: cert.Certificate[0]},
+		keyPath:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	return caPath, certPath, keyPath
+}
+
+// TestPythonClient runs Exchange through the generated Python client
+// against the in-process server. It is skipped unless the stubs have been
+// generated (make proto-python) and python3 is on the PATH.
+func TestPythonClient(t *testing.T) {
+	if _, err := os.Stat(pythonClientGen); err != nil {
+		t.Skip("Python client not generated; run 'make proto-python'")
+	}
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	const (
+		subjectID = "spiffe://test.local/order"
+		targetID  = "spiffe://test.local/payment"
+	)
+	env := newTestEnv(t, []policy.Policy{{
+		Name:          "order-to-payment",
+		Subject:       subjectID,
+		Target:        targetID,
+		AllowedScopes: []string{"read", "write"},
+		MaxTTL:        300,
+	}})
+	caPath, certPath, keyPath := writePEM(t, newClientCert(t, env.caCert, env.caKey, subjectID), env.caCert)
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode string
+	}{
+		{name: "permitted exchange", target: targetID},
+		{name: "no policy for pair", target: "spiffe://test.local/inventory", wantCode: "PERMISSION_DENIED"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			out, err := exec.CommandContext(ctx, python, "testdata/python_exchange.py",
+				env.addr, caPath, certPath, keyPath, tc.target, "read").Output()
+			if err != nil {
+				if ee, ok := err.(*exec.ExitError); ok {
+					t.Fatalf("python client: %v\n%s", err, ee.Stderr)
+				}
+				t.Fatalf("python client: %v", err)
+			}
+			var resp struct {
+				Code          string   `json:"code"`
+				Token         string   `json:"token"`
+				GrantedScopes []string `json:"grantedScopes"`
+			}
+			if err := json.Unmarshal(out, &resp); err != nil {
+				t.Fatalf("decode client output %q: %v", out, err)
+			}
+			if resp.Code != tc.wantCode {
+				t.Fatalf("code = %q, want %q", resp.Code, tc.wantCode)
+			}
+			if tc.wantCode != "" {
+				return
+			}
+			if len(resp.GrantedScopes) != 1 || resp.GrantedScopes[0] != "read" {
+				t.Errorf("grantedScopes = %v, want [read]", resp.GrantedScopes)
+			}
+			if _, err := jwt.Parse(resp.Token,
+				func(_ *jwt.Token) (any, error) { return env.minter.PublicKey(), nil },
+				jwt.WithValidMethods([]string{"ES256"}),
+				jwt.WithAudience(targetID),
+			); err != nil {
+				t.Errorf("parse JWT: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+"""Drives one Exchange through the generated Python client.
+
+Usage: python_exchange.py ADDR CA CERT KEY TARGET SCOPE...
+
+Prints the response as protobuf JSON, or {"code": "<STATUS>"} when the call
+fails, so the Go test can check both outcomes.
+"""
+
+import json
+import os
+import sys
+
+client_dir = os.path.join(os.path.dirname(__file__), "..", "..", "..", "clients", "python")
+sys.path.insert(0, client_dir)
+sys.path.insert(0, os.path.join(client_dir, "gen"))
+
+import grpc  # noqa: E402
+from google.protobuf import json_format  # noqa: E402
+
+import exchange_client  # noqa: E402
+
+
+def read(path):
+    with open(path, "rb") as f:
+        return f.read()
+
+
+def main():
+    addr, ca, cert, key, target, *scopes = sys.argv[1:]
+    stub = exchange_client.connect(addr, read(ca), read(cert), read(key))
+    try:
+        resp = exchange_client.exchange(stub, target, scopes, ttl_seconds=60)
+    except grpc.RpcError as e:
+        print(json.dumps({"code": e.code().name}))
+        return
+    print(json_format.MessageToJson(resp))
+
+
+if __name__ == "__main__":
+    main()
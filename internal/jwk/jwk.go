@@ -0,0 +1,180 @@
+// Package jwk converts between Go public keys and RFC 7517 JSON Web Keys.
+//
+// It supports the key types svid-exchange can sign with: EC P-256 and P-384
+// (ES256, ES384), RSA (RS256), and Ed25519 (EdDSA). The server uses it to
+// build the /jwks document; pkg/client and pkg/verifier use it to decode that
+// document back into verification keys.
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// Key is a single JSON Web Key. Only the members relevant to the key type are
+// populated; the rest are omitted from the JSON encoding.
+type Key struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Set is a JWKS document.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// FromPublicKey encodes pub as a JWK. Only the key material members (kty,
+// crv, x, y, n, e) are set; the caller fills in alg, use, and kid.
+func FromPublicKey(pub crypto.PublicKey) (Key, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		crv, size, err := curveParams(k.Curve)
+		if err != nil {
+			return Key{}, err
+		}
+		// Bytes returns the uncompressed point 0x04 || X || Y.
+		raw, err := k.Bytes()
+		if err != nil {
+			return Key{}, fmt.Errorf("encode public key: %w", err)
+		}
+		if len(raw) != 1+2*size || raw[0] != 0x04 {
+			return Key{}, fmt.Errorf("unexpected %s point: got %d bytes with prefix 0x%02x", crv, len(raw), raw[0])
+		}
+		return Key{Kty: "EC", Crv: crv, X: b64.EncodeToString(raw[1 : 1+size]), Y: b64.EncodeToString(raw[1+size:])}, nil
+	case *rsa.PublicKey:
+		return Key{Kty: "RSA", N: b64.EncodeToString(k.N.Bytes()), E: b64.EncodeToString(big.NewInt(int64(k.E)).Bytes())}, nil
+	case ed25519.PublicKey:
+		return Key{Kty: "OKP", Crv: "Ed25519", X: b64.EncodeToString(k)}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// PublicKey decodes k into *ecdsa.PublicKey, *rsa.PublicKey, or
+// ed25519.PublicKey. EC points are validated to lie on the curve.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		point := make([]byte, 0, 1+len(x)+len(y))
+		point = append(point, 0x04)
+		point = append(point, x...)
+		point = append(point, y...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		return pub, nil
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 key is %d bytes, want %d", len(x), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of pub, base64url
+// encoded. svid-exchange uses it as the kid of every signing key.
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	k, err := FromPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	// RFC 7638 §3.2: only the required members, in lexicographic order.
+	// encoding/json emits struct fields in declaration order.
+	var input any
+	switch k.Kty {
+	case "EC":
+		input = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	case "RSA":
+		input = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	case "OKP":
+		input = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	}
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("marshal thumbprint: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return b64.EncodeToString(sum[:]), nil
+}
+
+func curveParams(c elliptic.Curve) (name string, coordLen int, err error) {
+	switch c {
+	case elliptic.P256():
+		return "P-256", 32, nil
+	case elliptic.P384():
+		return "P-384", 48, nil
+	default:
+		return "", 0, fmt.Errorf("unsupported EC curve %s", c.Params().Name)
+	}
+}
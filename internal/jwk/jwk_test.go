@@ -0,0 +1,128 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate P-256: %v", err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate P-384: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA: %v", err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pub     crypto.PublicKey
+		wantKty string
+		wantCrv string
+	}{
+		{name: "P-256", pub: &p256.PublicKey, wantKty: "EC", wantCrv: "P-256"},
+		{name: "P-384", pub: &p384.PublicKey, wantKty: "EC", wantCrv: "P-384"},
+		{name: "RSA", pub: &rsaKey.PublicKey, wantKty: "RSA"},
+		{name: "Ed25519", pub: edPub, wantKty: "OKP", wantCrv: "Ed25519"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			k, err := FromPublicKey(tc.pub)
+			if err != nil {
+				t.Fatalf("FromPublicKey: %v", err)
+			}
+			if k.Kty != tc.wantKty || k.Crv != tc.wantCrv {
+				t.Errorf("kty/crv = %q/%q, want %q/%q", k.Kty, k.Crv, tc.wantKty, tc.wantCrv)
+			}
+
+			// Round-trip through JSON, as a verifier would see it.
+			raw, err := json.Marshal(k)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var decoded Key
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, err := decoded.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey: %v", err)
+			}
+			eq, ok := got.(interface{ Equal(crypto.PublicKey) bool })
+			if !ok || !eq.Equal(tc.pub) {
+				t.Errorf("decoded key does not equal original")
+			}
+		})
+	}
+}
+
+func TestPublicKeyRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{name: "unknown kty", key: Key{Kty: "oct"}},
+		{name: "unknown EC curve", key: Key{Kty: "EC", Crv: "P-521"}},
+		{name: "point not on curve", key: Key{Kty: "EC", Crv: "P-256", X: "AAAA", Y: "AAAA"}},
+		{name: "bad base64", key: Key{Kty: "EC", Crv: "P-256", X: "!!", Y: "AAAA"}},
+		{name: "RSA without modulus", key: Key{Kty: "RSA", E: "AQAB"}},
+		{name: "short Ed25519 key", key: Key{Kty: "OKP", Crv: "Ed25519", X: "AAAA"}},
+		{name: "unknown OKP curve", key: Key{Kty: "OKP", Crv: "X25519", X: "AAAA"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.key.PublicKey(); err == nil {
+				t.Error("PublicKey succeeded, want error")
+			}
+		})
+	}
+}
+
+// TestThumbprintRFC7638 checks the worked example in RFC 7638 §3.1.
+func TestThumbprintRFC7638(t *testing.T) {
+	k := Key{
+		Kty: "RSA",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn" +
+			"64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n9" +
+			"1CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E: "AQAB",
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	got, err := Thumbprint(pub)
+	if err != nil {
+		t.Fatalf("Thumbprint: %v", err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("Thumbprint = %q, want %q", got, want)
+	}
+}
+
+func TestFromPublicKeyUnsupported(t *testing.T) {
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate P-521: %v", err)
+	}
+	for _, pub := range []crypto.PublicKey{&p521.PublicKey, "not a key"} {
+		if _, err := FromPublicKey(pub); err == nil {
+			t.Errorf("FromPublicKey(%T) succeeded, want error", pub)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseTiming holds the leader election intervals. The defaults match those
+// of the Kubernetes core controllers.
+type leaseTiming struct {
+	duration, renewDeadline, retryPeriod time.Duration
+}
+
+var defaultLeaseTiming = leaseTiming{duration: 15 * time.Second, renewDeadline: 10 * time.Second, retryPeriod: 2 * time.Second}
+
+// Leader elects one replica at a time to run jobs that must not run on
+// every replica at once, such as rotating the shared signing key. It holds a
+// coordination.k8s.io Lease; a replica that stops renewing it, because it
+// crashed or lost the API server, is replaced after the lease expires.
+type Leader struct {
+	elector *leaderelection.LeaderElector
+	leading atomic.Bool
+}
+
+// NewLeader returns a Leader campaigning for the Lease called name in
+// namespace as identity, which must be unique per replica (the pod name).
+// Call Run to start campaigning.
+func NewLeader(leases coordinationv1client.LeasesGetter, namespace, name, identity string, log zerolog.Logger) (*Leader, error) {
+	return newLeader(leases, namespace, name, identity, log, defaultLeaseTiming)
+}
+
+func newLeader(leases coordinationv1client.LeasesGetter, namespace, name, identity string, log zerolog.Logger, timing leaseTiming) (*Leader, error) {
+	l := &Leader{}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Client:     leases,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration: timing.duration,
+		RenewDeadline: timing.renewDeadline,
+		RetryPeriod:   timing.retryPeriod,
+		// Hand the lease over on shutdown instead of making the other
+		// replicas wait for it to expire.
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				l.leading.Store(true)
+				log.Info().Str("lease", name).Msg("became leader")
+			},
+			OnStoppedLeading: func() {
+				if l.leading.Swap(false) {
+					log.Warn().Str("lease", name).Msg("lost leadership")
+				}
+			},
+			OnNewLeader: func(id string) {
+				if id != identity {
+					log.Info().Str("lease", name).Str("leader", id).Msg("following leader")
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("leader election: %w", err)
+	}
+	l.elector = elector
+	return l, nil
+}
+
+// Run campaigns for the lease until ctx is done, campaigning again whenever
+// leadership is lost. On return the lease has been released if it was held.
+func (l *Leader) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		l.elector.Run(ctx)
+	}
+}
+
+// IsLeader reports whether this replica currently holds the lease.
+func (l *Leader) IsLeader() bool {
+	return l.leading.Load()
+}
@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var fastLeaseTiming = leaseTiming{duration: time.Second, renewDeadline: 500 * time.Millisecond, retryPeriod: 100 * time.Millisecond}
+
+// waitFor polls cond until it holds or the deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestLeader(t *testing.T) {
+	client := fake.NewClientset()
+	start := func(identity string) (*Leader, context.CancelFunc, <-chan struct{}) {
+		t.Helper()
+		l, err := newLeader(client.CoordinationV1(), "svid-exchange", "svid-exchange", identity, zerolog.Nop(), fastLeaseTiming)
+		if err != nil {
+			t.Fatalf("newLeader: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			l.Run(ctx)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+		return l, cancel, done
+	}
+
+	a, cancelA, doneA := start("replica-a")
+	waitFor(t, "replica-a to lead", a.IsLeader)
+	b, _, _ := start("replica-b")
+	time.Sleep(3 * fastLeaseTiming.retryPeriod)
+	if b.IsLeader() {
+		t.Fatal("two replicas lead at once")
+	}
+
+	// Shutting down the leader releases the lease to the other replica.
+	cancelA()
+	<-doneA
+	if a.IsLeader() {
+		t.Error("replica-a still leads after Run returned")
+	}
+	waitFor(t, "replica-b to take over", b.IsLeader)
+}
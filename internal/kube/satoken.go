@@ -0,0 +1,182 @@
+package kube
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/metadata"
+	authnv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authnv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// SATokenHeader is the gRPC request metadata key carrying a projected
+// ServiceAccount token.
+const SATokenHeader = "x-serviceaccount-token"
+
+// ErrNoSAToken is returned by SATokenExtractor when the request carries no
+// ServiceAccount token. It matches server.ErrNoCredentials.
+var ErrNoSAToken = server.NoCredentials("no ServiceAccount token in request metadata")
+
+const (
+	// saUsernamePrefix prefixes the username the API server reports for a
+	// ServiceAccount token: system:serviceaccount:<namespace>:<name>.
+	saUsernamePrefix = "system:serviceaccount:"
+	// reviewCacheTTL is how long a successful TokenReview is reused. It keeps
+	// a busy caller from costing one API server round trip per RPC, at the
+	// price of honouring a deleted pod's token for up to this long.
+	reviewCacheTTL = 30 * time.Second
+	// reviewCacheSize bounds the cache; expired entries are swept when it is
+	// reached, and the cache is cleared if none had expired.
+	reviewCacheSize = 10000
+	// podNameExtra and podUIDExtra are the TokenReview user extra keys
+	// naming the pod a bound ServiceAccount token was issued for.
+	podNameExtra = "authentication.kubernetes.io/pod-name"
+	podUIDExtra  = "authentication.kubernetes.io/pod-uid"
+)
+
+// SATokenExtractor implements server.IDExtractor for callers that present a
+// Kubernetes ServiceAccount token instead of an X.509 SVID. The token is
+// validated with a TokenReview against the API server, which checks its
+// signature, expiry, audience, and that the bound pod still exists. The
+// ServiceAccount is mapped to spiffe://<trust domain>/ns/<namespace>/sa/<name>,
+// the ID SPIRE's Kubernetes registrar would give the same workload, so
+// policies are written the same way for both kinds of caller.
+type SATokenExtractor struct {
+	reviews     authnv1client.TokenReviewInterface
+	trustDomain spiffeid.TrustDomain
+	audiences   []string
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedReview
+	now   func() time.Time
+}
+
+type cachedReview struct {
+	ident   server.Identity
+	expires time.Time
+}
+
+// NewSATokenExtractor returns an extractor that validates tokens with reviews.
+// A token must be issued for at least one of audiences; with no audiences the
+// API server's own audience is required instead, which also accepts tokens
+// meant for the API server itself and is not recommended.
+func NewSATokenExtractor(reviews authnv1client.TokenReviewInterface, trustDomain spiffeid.TrustDomain, audiences []string) *SATokenExtractor {
+	return &SATokenExtractor{
+		reviews:     reviews,
+		trustDomain: trustDomain,
+		audiences:   audiences,
+		cache:       make(map[[sha256.Size]byte]cachedReview),
+		now:         time.Now,
+	}
+}
+
+// ExtractID implements server.IDExtractor.
+func (e *SATokenExtractor) ExtractID(ctx context.Context) (string, error) {
+	ident, err := e.ExtractIdentity(ctx)
+	return ident.ID, err
+}
+
+// ExtractIdentity implements server.IdentityExtractor. For a token bound to
+// a pod, the identity also names the pod.
+func (e *SATokenExtractor) ExtractIdentity(ctx context.Context) (server.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get(SATokenHeader)
+	if len(vals) == 0 || vals[0] == "" {
+		return server.Identity{}, ErrNoSAToken
+	}
+	tok := vals[0]
+	key := sha256.Sum256([]byte(tok))
+	if ident, ok := e.cached(key); ok {
+		return ident, nil
+	}
+
+	review, err := e.reviews.Create(ctx, &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{Token: tok, Audiences: e.audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return server.Identity{}, fmt.Errorf("review ServiceAccount token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return server.Identity{}, fmt.Errorf("ServiceAccount token rejected: %s", review.Status.Error)
+		}
+		return server.Identity{}, errors.New("ServiceAccount token rejected")
+	}
+	// An API server whose authenticator ignores audiences answers with none;
+	// the TokenReview API leaves this check to the client.
+	if len(e.audiences) > 0 && !slices.ContainsFunc(review.Status.Audiences, func(a string) bool { return slices.Contains(e.audiences, a) }) {
+		return server.Identity{}, fmt.Errorf("ServiceAccount token audiences %v do not include any of %v", review.Status.Audiences, e.audiences)
+	}
+	id, err := e.saToID(review.Status.User.Username)
+	if err != nil {
+		return server.Identity{}, err
+	}
+	ident := server.Identity{ID: id}
+	if name := firstExtra(review.Status.User.Extra, podNameExtra); name != "" {
+		// saToID has checked the username's form.
+		ns, _, _ := strings.Cut(strings.TrimPrefix(review.Status.User.Username, saUsernamePrefix), ":")
+		ident.Pod = ns + "/" + name
+		ident.PodUID = firstExtra(review.Status.User.Extra, podUIDExtra)
+	}
+	e.store(key, ident)
+	return ident, nil
+}
+
+// firstExtra returns the first value of the user extra key, or "".
+func firstExtra(extra map[string]authnv1.ExtraValue, key string) string {
+	if v := extra[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// saToID maps a ServiceAccount username to its SPIFFE-style ID.
+func (e *SATokenExtractor) saToID(username string) (string, error) {
+	rest, isSA := strings.CutPrefix(username, saUsernamePrefix)
+	ns, name, ok := strings.Cut(rest, ":")
+	if !isSA || !ok {
+		return "", fmt.Errorf("token user %q is not a ServiceAccount", username)
+	}
+	id, err := spiffeid.FromSegments(e.trustDomain, "ns", ns, "sa", name)
+	if err != nil {
+		return "", fmt.Errorf("map ServiceAccount %s/%s to a SPIFFE ID: %w", ns, name, err)
+	}
+	return id.String(), nil
+}
+
+func (e *SATokenExtractor) cached(key [sha256.Size]byte) (server.Identity, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.cache[key]
+	if !ok || !e.now().Before(c.expires) {
+		return server.Identity{}, false
+	}
+	return c.ident, true
+}
+
+func (e *SATokenExtractor) store(key [sha256.Size]byte, ident server.Identity) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	if len(e.cache) >= reviewCacheSize {
+		for k, c := range e.cache {
+			if !now.Before(c.expires) {
+				delete(e.cache, k)
+			}
+		}
+		if len(e.cache) >= reviewCacheSize {
+			clear(e.cache)
+		}
+	}
+	e.cache[key] = cachedReview{ident: ident, expires: now.Add(reviewCacheTTL)}
+}
@@ -0,0 +1,144 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/metadata"
+	authnv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeReviews returns a TokenReview client whose API server answers every
+// review with status, or fails with err, counting the reviews made.
+func fakeReviews(status authnv1.TokenReviewStatus, err error, calls *int) *fake.Clientset {
+	c := fake.NewClientset()
+	c.PrependReactor("create", "tokenreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		*calls++
+		if err != nil {
+			return true, nil, err
+		}
+		review := a.(k8stesting.CreateAction).GetObject().(*authnv1.TokenReview).DeepCopy()
+		review.Status = status
+		return true, review, nil
+	})
+	return c
+}
+
+func ctxWithSAToken(tok string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(SATokenHeader, tok))
+}
+
+func TestSATokenExtractor(t *testing.T) {
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+	authenticated := func(username string, audiences ...string) authnv1.TokenReviewStatus {
+		return authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{Username: username}, Audiences: audiences}
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		status  authnv1.TokenReviewStatus
+		apiErr  error
+		wantID  string
+		wantErr error // set when a specific sentinel error is expected
+	}{
+		{
+			name:   "ServiceAccount maps to a SPIFFE ID",
+			ctx:    ctxWithSAToken("tok"),
+			status: authenticated("system:serviceaccount:default:order", "svid-exchange"),
+			wantID: subOrder,
+		},
+		{
+			name:    "no token",
+			ctx:     context.Background(),
+			wantErr: ErrNoSAToken,
+		},
+		{
+			name:   "token rejected",
+			ctx:    ctxWithSAToken("tok"),
+			status: authnv1.TokenReviewStatus{Error: "token has expired"},
+		},
+		{
+			name:   "wrong audience",
+			ctx:    ctxWithSAToken("tok"),
+			status: authenticated("system:serviceaccount:default:order", "https://kubernetes.default.svc"),
+		},
+		{
+			name:   "user is not a ServiceAccount",
+			ctx:    ctxWithSAToken("tok"),
+			status: authenticated("jane@example.com", "svid-exchange"),
+		},
+		{
+			name:   "API server unavailable",
+			ctx:    ctxWithSAToken("tok"),
+			apiErr: errors.New("connection refused"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			e := NewSATokenExtractor(fakeReviews(tc.status, tc.apiErr, &calls).AuthenticationV1().TokenReviews(), td, []string{"svid-exchange"})
+			got, err := e.ExtractID(tc.ctx)
+			switch {
+			case tc.wantID != "":
+				if err != nil || got != tc.wantID {
+					t.Errorf("ExtractID() = %q, %v; want %q", got, err, tc.wantID)
+				}
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("ExtractID() error = %v, want %v", err, tc.wantErr)
+				}
+			default:
+				if err == nil {
+					t.Errorf("ExtractID() = %q, want error", got)
+				}
+			}
+		})
+	}
+
+	t.Run("successful reviews are cached", func(t *testing.T) {
+		var calls int
+		e := NewSATokenExtractor(fakeReviews(authenticated("system:serviceaccount:default:order", "svid-exchange"), nil, &calls).AuthenticationV1().TokenReviews(), td, []string{"svid-exchange"})
+		now := time.Now()
+		e.now = func() time.Time { return now }
+		for range 3 {
+			if _, err := e.ExtractID(ctxWithSAToken("tok")); err != nil {
+				t.Fatalf("ExtractID: %v", err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("TokenReviews = %d, want 1 within the cache TTL", calls)
+		}
+		now = now.Add(reviewCacheTTL)
+		if _, err := e.ExtractID(ctxWithSAToken("tok")); err != nil {
+			t.Fatalf("ExtractID: %v", err)
+		}
+		if calls != 2 {
+			t.Errorf("TokenReviews = %d, want 2 after the cache TTL", calls)
+		}
+	})
+	t.Run("bound pod is part of the identity", func(t *testing.T) {
+		status := authenticated("system:serviceaccount:default:order", "svid-exchange")
+		status.User.Extra = map[string]authnv1.ExtraValue{
+			podNameExtra: {"order-7d9f8-x2x4p"},
+			podUIDExtra:  {"0d3b8a5e-2f1c-4b7e-9a51-6c2f0e4b1d77"},
+		}
+		var calls int
+		e := NewSATokenExtractor(fakeReviews(status, nil, &calls).AuthenticationV1().TokenReviews(), td, []string{"svid-exchange"})
+		for range 2 { // the second call is answered from the cache
+			ident, err := e.ExtractIdentity(ctxWithSAToken("tok"))
+			if err != nil {
+				t.Fatalf("ExtractIdentity: %v", err)
+			}
+			if ident.ID != subOrder || ident.Pod != "default/order-7d9f8-x2x4p" || ident.PodUID != "0d3b8a5e-2f1c-4b7e-9a51-6c2f0e4b1d77" {
+				t.Errorf("ExtractIdentity() = %+v", ident)
+			}
+		}
+	})
+}
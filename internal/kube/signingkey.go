@@ -0,0 +1,198 @@
+package kube
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+const (
+	// Secret data keys holding the PKCS #8 PEM signing keys.
+	signingKeyCurrent  = "current.pem"
+	signingKeyPrevious = "previous.pem"
+	// RotatedAtAnnotation records, in RFC 3339, when the Secret's current
+	// key was generated.
+	RotatedAtAnnotation = "svid-exchange.io/rotated-at"
+)
+
+// SyncResult reports what SigningKeySecret.Sync did.
+type SyncResult int
+
+const (
+	// SyncUnchanged means the Secret had not changed since the last Sync.
+	SyncUnchanged SyncResult = iota
+	// SyncLoaded means keys created or rotated elsewhere were loaded.
+	SyncLoaded
+	// SyncRotated means this Sync created or rotated the keys.
+	SyncRotated
+)
+
+// SigningKeySecret keeps the JWT signing keys of several replicas in one
+// Kubernetes Secret, so a token minted by any replica verifies against every
+// replica's JWKS. Each replica calls Sync periodically. The first replica to
+// find the current key older than the rotation interval rotates it; the
+// Secret's resourceVersion makes that update fail for every other replica,
+// which then loads the winner's keys instead of rotating again.
+//
+// A SigningKeySecret is not safe for concurrent use.
+type SigningKeySecret struct {
+	secrets corev1client.SecretInterface
+	name    string
+	alg     token.Algorithm
+	now     func() time.Time
+	// isLeader, when set, gates rotation; see SetLeader.
+	isLeader func() bool
+	// loaded is the key material last loaded into the Minter.
+	loaded string
+}
+
+// NewSigningKeySecret returns a SigningKeySecret for the Secret called name.
+// New keys are generated for alg, and a Secret holding a key of another
+// algorithm is rotated on the next Sync.
+func NewSigningKeySecret(secrets corev1client.SecretInterface, name string, alg token.Algorithm) *SigningKeySecret {
+	return &SigningKeySecret{secrets: secrets, name: name, alg: alg, now: time.Now}
+}
+
+// SetLeader restricts rotation to replicas for which isLeader reports true,
+// typically Leader.IsLeader. The others only load keys, and create the
+// Secret if it is missing. It must be called before the first Sync.
+func (s *SigningKeySecret) SetLeader(isLeader func() bool) {
+	s.isLeader = isLeader
+}
+
+// Sync loads the Secret's keys into m, creating the Secret if it does not
+// exist. When rotateAfter is positive and the current key is at least that
+// old, Sync first rotates it, keeping the outgoing key as previous.
+func (s *SigningKeySecret) Sync(ctx context.Context, m *token.Minter, rotateAfter time.Duration) (SyncResult, error) {
+	result := SyncLoaded
+	sec, err := s.secrets.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if sec, err = s.create(ctx); err == nil {
+			result = SyncRotated
+		} else if apierrors.IsAlreadyExists(err) {
+			// Another replica created it first; use its key.
+			sec, err = s.secrets.Get(ctx, s.name, metav1.GetOptions{})
+		}
+	}
+	if err != nil {
+		return SyncUnchanged, fmt.Errorf("get signing key secret %q: %w", s.name, err)
+	}
+
+	current, previous, err := parseSigningKeys(sec)
+	if err != nil {
+		return SyncUnchanged, fmt.Errorf("signing key secret %q: %w", s.name, err)
+	}
+	if s.rotationDue(sec, current, rotateAfter) {
+		rotated, err := s.rotate(ctx, sec)
+		switch {
+		case err == nil:
+			sec, result = rotated, SyncRotated
+		case apierrors.IsConflict(err):
+			// Another replica rotated first; adopt its keys.
+			if sec, err = s.secrets.Get(ctx, s.name, metav1.GetOptions{}); err != nil {
+				return SyncUnchanged, fmt.Errorf("get signing key secret %q: %w", s.name, err)
+			}
+		default:
+			return SyncUnchanged, fmt.Errorf("rotate signing key secret %q: %w", s.name, err)
+		}
+		if current, previous, err = parseSigningKeys(sec); err != nil {
+			return SyncUnchanged, fmt.Errorf("signing key secret %q: %w", s.name, err)
+		}
+	}
+
+	keys := string(sec.Data[signingKeyCurrent]) + string(sec.Data[signingKeyPrevious])
+	if keys == s.loaded {
+		return SyncUnchanged, nil
+	}
+	m.SetSigners(current, previous)
+	s.loaded = keys
+	return result, nil
+}
+
+// rotationDue reports whether the Secret's current key should be replaced:
+// it is older than rotateAfter, or of an algorithm other than s.alg. It is
+// never due on a replica that is not the leader.
+func (s *SigningKeySecret) rotationDue(sec *corev1.Secret, current token.AlgorithmSigner, rotateAfter time.Duration) bool {
+	if s.isLeader != nil && !s.isLeader() {
+		return false
+	}
+	if current.Algorithm() != s.alg {
+		return true
+	}
+	if rotateAfter <= 0 {
+		return false
+	}
+	rotatedAt, err := time.Parse(time.RFC3339, sec.Annotations[RotatedAtAnnotation])
+	return err != nil || s.now().Sub(rotatedAt) >= rotateAfter
+}
+
+func (s *SigningKeySecret) create(ctx context.Context) (*corev1.Secret, error) {
+	key, err := s.newKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	return s.secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.name,
+			Annotations: map[string]string{RotatedAtAnnotation: s.now().UTC().Format(time.RFC3339)},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{signingKeyCurrent: key},
+	}, metav1.CreateOptions{})
+}
+
+// rotate generates a new current key and updates sec with it. The update
+// carries sec's resourceVersion, so it fails with a Conflict if another
+// replica changed the Secret since sec was read.
+func (s *SigningKeySecret) rotate(ctx context.Context, sec *corev1.Secret) (*corev1.Secret, error) {
+	key, err := s.newKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	next := sec.DeepCopy()
+	if next.Annotations == nil {
+		next.Annotations = make(map[string]string)
+	}
+	next.Annotations[RotatedAtAnnotation] = s.now().UTC().Format(time.RFC3339)
+	next.Data = map[string][]byte{
+		signingKeyCurrent:  key,
+		signingKeyPrevious: sec.Data[signingKeyCurrent],
+	}
+	return s.secrets.Update(ctx, next, metav1.UpdateOptions{})
+}
+
+func (s *SigningKeySecret) newKeyPEM() ([]byte, error) {
+	key, err := token.GenerateKey(s.alg)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signing key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// parseSigningKeys returns signers for the Secret's current key and, when
+// present, its previous key.
+func parseSigningKeys(sec *corev1.Secret) (current, previous token.AlgorithmSigner, err error) {
+	current, err = token.ParseSigningKey(sec.Data[signingKeyCurrent])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", signingKeyCurrent, err)
+	}
+	if data, ok := sec.Data[signingKeyPrevious]; ok && len(data) > 0 {
+		if previous, err = token.ParseSigningKey(data); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", signingKeyPrevious, err)
+		}
+	}
+	return current, previous, nil
+}
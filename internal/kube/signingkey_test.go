@@ -0,0 +1,167 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+const (
+	keyNamespace  = "svid-exchange"
+	keySecretName = "svid-exchange-signing-key"
+)
+
+// newKeyReplica returns a Minter and a SigningKeySecret for it sharing
+// client, standing in for one replica, with a clock read from now.
+func newKeyReplica(t *testing.T, client *fake.Clientset, alg token.Algorithm, now *time.Time) (*token.Minter, *SigningKeySecret) {
+	t.Helper()
+	m, err := token.NewMinterWithAlgorithm(alg)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	s := NewSigningKeySecret(client.CoreV1().Secrets(keyNamespace), keySecretName, alg)
+	s.now = func() time.Time { return *now }
+	return m, s
+}
+
+func mustSync(t *testing.T, s *SigningKeySecret, m *token.Minter, rotateAfter time.Duration, want SyncResult) {
+	t.Helper()
+	got, err := s.Sync(context.Background(), m, rotateAfter)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got != want {
+		t.Errorf("Sync() = %d, want %d", got, want)
+	}
+}
+
+func kid(t *testing.T, m *token.Minter) string {
+	t.Helper()
+	k, err := token.KeyID(m.PublicKey())
+	if err != nil {
+		t.Fatalf("KeyID: %v", err)
+	}
+	return k
+}
+
+func TestSigningKeySecret(t *testing.T) {
+	const interval = time.Hour
+
+	t.Run("replicas share one key", func(t *testing.T) {
+		client := fake.NewClientset()
+		now := time.Now()
+		a, sa := newKeyReplica(t, client, token.ES256, &now)
+		b, sb := newKeyReplica(t, client, token.ES256, &now)
+
+		mustSync(t, sa, a, interval, SyncRotated) // creates the Secret
+		mustSync(t, sb, b, interval, SyncLoaded)
+		if kid(t, a) != kid(t, b) {
+			t.Fatal("replicas sign with different keys")
+		}
+		mustSync(t, sa, a, interval, SyncUnchanged)
+
+		res, err := a.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		if _, err := token.VerifyClaims(res.Token, b.PublicKeys(), "spiffe://b"); err != nil {
+			t.Errorf("token from replica A does not verify with replica B's keys: %v", err)
+		}
+	})
+
+	t.Run("one replica rotates and the others follow", func(t *testing.T) {
+		client := fake.NewClientset()
+		now := time.Now()
+		a, sa := newKeyReplica(t, client, token.ES256, &now)
+		b, sb := newKeyReplica(t, client, token.ES256, &now)
+		mustSync(t, sa, a, interval, SyncRotated)
+		mustSync(t, sb, b, interval, SyncLoaded)
+		before := kid(t, a)
+
+		now = now.Add(interval)
+		mustSync(t, sa, a, interval, SyncRotated)
+		mustSync(t, sb, b, interval, SyncLoaded)
+		if kid(t, a) == before || kid(t, a) != kid(t, b) {
+			t.Errorf("after rotation: kids %s, %s; want a shared key other than %s", kid(t, a), kid(t, b), before)
+		}
+		if keys := b.PublicKeys(); len(keys) != 2 {
+			t.Errorf("PublicKeys() = %d keys, want current and previous", len(keys))
+		}
+	})
+
+	t.Run("a conflicting rotation is not retried", func(t *testing.T) {
+		client := fake.NewClientset()
+		now := time.Now()
+		a, sa := newKeyReplica(t, client, token.ES256, &now)
+		mustSync(t, sa, a, interval, SyncRotated)
+		b, sb := newKeyReplica(t, client, token.ES256, &now)
+		mustSync(t, sb, b, interval, SyncLoaded)
+
+		// B's update fails as if another replica had changed the Secret
+		// since B read it; B must re-read rather than retry the rotation.
+		now = now.Add(interval)
+		client.PrependReactor("update", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, keySecretName, nil)
+		})
+		mustSync(t, sb, b, interval, SyncUnchanged)
+		if kid(t, a) != kid(t, b) {
+			t.Error("replicas diverged after a lost rotation race")
+		}
+	})
+
+	t.Run("only the leader rotates", func(t *testing.T) {
+		client := fake.NewClientset()
+		now := time.Now()
+		a, sa := newKeyReplica(t, client, token.ES256, &now)
+		leading := false
+		sa.SetLeader(func() bool { return leading })
+		mustSync(t, sa, a, interval, SyncRotated) // followers still create the Secret
+		before := kid(t, a)
+
+		now = now.Add(interval)
+		mustSync(t, sa, a, interval, SyncUnchanged)
+		if kid(t, a) != before {
+			t.Error("follower rotated the shared key")
+		}
+		leading = true
+		mustSync(t, sa, a, interval, SyncRotated)
+	})
+
+	t.Run("algorithm change rotates immediately", func(t *testing.T) {
+		client := fake.NewClientset()
+		now := time.Now()
+		a, sa := newKeyReplica(t, client, token.ES256, &now)
+		mustSync(t, sa, a, 0, SyncRotated)
+		b, sb := newKeyReplica(t, client, token.ES384, &now)
+		mustSync(t, sb, b, 0, SyncRotated)
+		if b.Algorithm() != token.ES384 {
+			t.Errorf("Algorithm() = %q, want ES384", b.Algorithm())
+		}
+	})
+
+	t.Run("malformed key is an error", func(t *testing.T) {
+		client := fake.NewClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: keySecretName, Namespace: keyNamespace},
+			Data:       map[string][]byte{signingKeyCurrent: []byte("not a key")},
+		})
+		now := time.Now()
+		m, s := newKeyReplica(t, client, token.ES256, &now)
+		before := kid(t, m)
+		if _, err := s.Sync(context.Background(), m, interval); err == nil {
+			t.Error("Sync() error = nil, want error")
+		}
+		if kid(t, m) != before {
+			t.Error("Minter key changed despite a failed Sync")
+		}
+	})
+}
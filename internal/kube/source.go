@@ -0,0 +1,282 @@
+// Package kube sources exchange policies from ExchangePolicy custom resources
+// so that teams can manage policy with kubectl or GitOps instead of a mounted
+// YAML file. Each resource is validated individually and its outcome is
+// reported back as an "Accepted" status condition on the object.
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// GVR identifies the ExchangePolicy custom resource.
+var GVR = schema.GroupVersionResource{
+	Group:    "svid-exchange.io",
+	Version:  "v1alpha1",
+	Resource: "exchangepolicies",
+}
+
+// ConditionAccepted is the status condition type written to every
+// ExchangePolicy. It is True when the policy is part of the active set.
+const ConditionAccepted = "Accepted"
+
+// Reasons reported on the Accepted condition.
+const (
+	ReasonValid    = "Valid"
+	ReasonInvalid  = "Invalid"
+	ReasonConflict = "Conflict"
+	ReasonRejected = "Rejected"
+)
+
+// resyncPeriod forces a periodic full reconcile so that status conditions
+// converge even if an update event is missed.
+const resyncPeriod = 10 * time.Minute
+
+// Spec is the spec of an ExchangePolicy resource. Field names follow
+// Kubernetes camelCase conventions; they map one-to-one onto policy.Policy.
+type Spec struct {
+	Subject          string              `json:"subject"`
+	Target           string              `json:"target"`
+	AllowedScopes    []string            `json:"allowedScopes"`
+	MaxTTL           int32               `json:"maxTTL"`
+	TokenFormat      string              `json:"tokenFormat,omitempty"`
+	RequireNonce     bool                `json:"requireNonce,omitempty"`
+	RequiresApproval bool                `json:"requiresApproval,omitempty"`
+	SingleUse        bool                `json:"singleUse,omitempty"`
+	Audiences        map[string][]string `json:"audiences,omitempty"`
+	Issuer           string              `json:"issuer,omitempty"`
+	Condition        string              `json:"condition,omitempty"`
+}
+
+// PolicyName returns the policy name used for a resource in audit logs and
+// conflict messages. Namespacing the name keeps resources in different
+// namespaces from colliding.
+func PolicyName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// ToPolicy converts an ExchangePolicy object into a policy.Policy.
+// It does not validate the result; call policy.ValidateOne for that.
+func ToPolicy(u *unstructured.Unstructured) (policy.Policy, error) {
+	raw, ok := u.Object["spec"].(map[string]any)
+	if !ok {
+		return policy.Policy{}, errors.New("spec is missing")
+	}
+	var spec Spec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &spec); err != nil {
+		return policy.Policy{}, fmt.Errorf("decode spec: %w", err)
+	}
+	return policy.Policy{
+		Name:             PolicyName(u.GetNamespace(), u.GetName()),
+		Subject:          spec.Subject,
+		Target:           spec.Target,
+		AllowedScopes:    spec.AllowedScopes,
+		MaxTTL:           spec.MaxTTL,
+		TokenFormat:      spec.TokenFormat,
+		RequireNonce:     spec.RequireNonce,
+		RequiresApproval: spec.RequiresApproval,
+		SingleUse:        spec.SingleUse,
+		Audiences:        spec.Audiences,
+		Issuer:           spec.Issuer,
+		Condition:        spec.Condition,
+	}, nil
+}
+
+// Source watches ExchangePolicy resources and pushes the accepted set to the
+// caller every time it changes. A zero Source is not usable; use NewSource.
+type Source struct {
+	client    dynamic.Interface
+	namespace string
+	reserved  func() []policy.Policy
+	onChange  func([]policy.Policy) error
+	log       zerolog.Logger
+	trigger   chan struct{}
+}
+
+// NewSource returns a Source that watches namespace (all namespaces when
+// empty). reserved must return the policies defined outside the cluster (YAML
+// file and admin API); a resource that collides with one of them is rejected
+// with a Conflict condition. onChange receives the accepted policies after
+// every reconcile.
+func NewSource(
+	client dynamic.Interface,
+	namespace string,
+	reserved func() []policy.Policy,
+	onChange func([]policy.Policy) error,
+	log zerolog.Logger,
+) *Source {
+	return &Source{
+		client:    client,
+		namespace: namespace,
+		reserved:  reserved,
+		onChange:  onChange,
+		log:       log,
+		trigger:   make(chan struct{}, 1),
+	}
+}
+
+// Start runs the informer, waits for the initial list to sync, reconciles
+// once, and then keeps reconciling in a background goroutine until ctx is
+// cancelled. Returning only after the first reconcile means the server never
+// serves traffic with a partially loaded cluster policy set.
+func (s *Source) Start(ctx context.Context) error {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(s.client, resyncPeriod, s.namespace, nil)
+	inf := factory.ForResource(GVR).Informer()
+	if _, err := inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { s.enqueue() },
+		UpdateFunc: func(any, any) { s.enqueue() },
+		DeleteFunc: func(any) { s.enqueue() },
+	}); err != nil {
+		return fmt.Errorf("add event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), inf.HasSynced) {
+		factory.Shutdown()
+		return errors.New("wait for ExchangePolicy cache sync")
+	}
+	s.Reconcile(ctx, inf.GetStore().List())
+
+	go func() {
+		defer factory.Shutdown()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.trigger:
+				s.Reconcile(ctx, inf.GetStore().List())
+			}
+		}
+	}()
+	return nil
+}
+
+// enqueue requests a reconcile. Bursts of events collapse into a single
+// pending reconcile because every reconcile reads the full informer store.
+func (s *Source) enqueue() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Reconcile evaluates objs, hands the accepted policies to onChange, and
+// writes the resulting Accepted condition back to each resource whose
+// condition changed. Resources are processed in namespace/name order so that
+// when two resources conflict the same one wins on every replica and restart.
+func (s *Source) Reconcile(ctx context.Context, objs []any) {
+	items := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, o := range objs {
+		if u, ok := o.(*unstructured.Unstructured); ok {
+			items = append(items, u)
+		}
+	}
+	slices.SortFunc(items, func(a, b *unstructured.Unstructured) int {
+		return strings.Compare(PolicyName(a.GetNamespace(), a.GetName()), PolicyName(b.GetNamespace(), b.GetName()))
+	})
+
+	names := make(map[string]struct{})
+	targets := make(map[string]string) // "subject\x00target" → owning policy name
+	for _, p := range s.reserved() {
+		names[p.Name] = struct{}{}
+		targets[p.Subject+"\x00"+p.Target] = p.Name
+	}
+
+	accepted := make([]policy.Policy, 0, len(items))
+	acceptedIdx := make([]int, 0, len(items))
+	conds := make([]metav1.Condition, len(items))
+	for i, u := range items {
+		p, err := ToPolicy(u)
+		if err == nil {
+			err = validatePolicy(p)
+		}
+		if err != nil {
+			conds[i] = condition(u, metav1.ConditionFalse, ReasonInvalid, err.Error())
+			continue
+		}
+		if _, dup := names[p.Name]; dup {
+			conds[i] = condition(u, metav1.ConditionFalse, ReasonConflict, fmt.Sprintf("policy name %q is already defined", p.Name))
+			continue
+		}
+		key := p.Subject + "\x00" + p.Target
+		if owner, dup := targets[key]; dup {
+			conds[i] = condition(u, metav1.ConditionFalse, ReasonConflict, fmt.Sprintf("subject %s → target %s is already granted by policy %q", p.Subject, p.Target, owner))
+			continue
+		}
+		names[p.Name] = struct{}{}
+		targets[key] = p.Name
+		accepted = append(accepted, p)
+		acceptedIdx = append(acceptedIdx, i)
+		conds[i] = condition(u, metav1.ConditionTrue, ReasonValid, "policy is active")
+	}
+
+	// onChange may still refuse the set as a whole (for example when a policy
+	// violates a server-wide invariant). Report that on every resource that
+	// would otherwise claim to be active.
+	if err := s.onChange(accepted); err != nil {
+		s.log.Error().Err(err).Msg("apply ExchangePolicy resources")
+		for _, i := range acceptedIdx {
+			conds[i] = condition(items[i], metav1.ConditionFalse, ReasonRejected, err.Error())
+		}
+	}
+
+	for i, u := range items {
+		if err := s.setCondition(ctx, u, conds[i]); err != nil {
+			s.log.Warn().Err(err).Str("policy", PolicyName(u.GetNamespace(), u.GetName())).Msg("update ExchangePolicy status")
+		}
+	}
+}
+
+func condition(u *unstructured.Unstructured, st metav1.ConditionStatus, reason, msg string) metav1.Condition {
+	return metav1.Condition{
+		Type:               ConditionAccepted,
+		Status:             st,
+		Reason:             reason,
+		Message:            msg,
+		ObservedGeneration: u.GetGeneration(),
+	}
+}
+
+// setCondition writes c to u's status subresource. The write is skipped when
+// the condition is unchanged so that the resulting update event does not
+// trigger an endless reconcile loop.
+func (s *Source) setCondition(ctx context.Context, u *unstructured.Unstructured, c metav1.Condition) error {
+	var st struct {
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+	}
+	if raw, ok := u.Object["status"].(map[string]any); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &st); err != nil {
+			return fmt.Errorf("decode status: %w", err)
+		}
+	}
+	if !meta.SetStatusCondition(&st.Conditions, c) {
+		return nil
+	}
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&st)
+	if err != nil {
+		return fmt.Errorf("encode status: %w", err)
+	}
+	out := u.DeepCopy()
+	out.Object["status"] = raw
+	_, err = s.client.Resource(GVR).Namespace(u.GetNamespace()).UpdateStatus(ctx, out, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,307 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+const (
+	subOrder   = "spiffe://cluster.local/ns/default/sa/order"
+	subCart    = "spiffe://cluster.local/ns/default/sa/cart"
+	tgtPayment = "spiffe://cluster.local/ns/default/sa/payment"
+)
+
+func newExchangePolicy(ns, name, subject, target string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": GVR.GroupVersion().String(),
+		"kind":       "ExchangePolicy",
+		"metadata":   map[string]any{"namespace": ns, "name": name, "generation": int64(1)},
+		"spec": map[string]any{
+			"subject":       subject,
+			"target":        target,
+			"allowedScopes": []any{"payments:charge"},
+			"maxTTL":        int64(300),
+		},
+	}}
+}
+
+func newFakeClient(objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{GVR: "ExchangePolicyList"},
+		objs...,
+	)
+}
+
+// acceptedCondition fetches the resource from the fake API server and returns
+// its Accepted condition, or nil if none has been written.
+func acceptedCondition(t *testing.T, c *dynamicfake.FakeDynamicClient, ns, name string) *metav1.Condition {
+	t.Helper()
+	u, err := c.Resource(GVR).Namespace(ns).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get %s/%s: %v", ns, name, err)
+	}
+	var st struct {
+		Conditions []metav1.Condition `json:"conditions"`
+	}
+	if raw, ok := u.Object["status"].(map[string]any); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &st); err != nil {
+			t.Fatalf("decode status: %v", err)
+		}
+	}
+	return meta.FindStatusCondition(st.Conditions, ConditionAccepted)
+}
+
+func TestToPolicy(t *testing.T) {
+	t.Run("spec maps onto policy fields", func(t *testing.T) {
+		p, err := ToPolicy(newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment))
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if p.Name != "default/order-to-payment" {
+			t.Errorf("Name = %q, want default/order-to-payment", p.Name)
+		}
+		if p.Subject != subOrder || p.Target != tgtPayment {
+			t.Errorf("Subject/Target = %q/%q", p.Subject, p.Target)
+		}
+		if len(p.AllowedScopes) != 1 || p.AllowedScopes[0] != "payments:charge" {
+			t.Errorf("AllowedScopes = %v", p.AllowedScopes)
+		}
+		if p.MaxTTL != 300 {
+			t.Errorf("MaxTTL = %d, want 300", p.MaxTTL)
+		}
+		if p.RequireNonce {
+			t.Error("RequireNonce = true, want false when unset")
+		}
+	})
+
+	t.Run("requireNonce maps onto policy", func(t *testing.T) {
+		u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+		u.Object["spec"].(map[string]any)["requireNonce"] = true
+		p, err := ToPolicy(u)
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if !p.RequireNonce {
+			t.Error("RequireNonce = false, want true")
+		}
+	})
+
+	t.Run("requiresApproval maps onto policy", func(t *testing.T) {
+		u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+		u.Object["spec"].(map[string]any)["requiresApproval"] = true
+		p, err := ToPolicy(u)
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if !p.RequiresApproval {
+			t.Error("RequiresApproval = false, want true")
+		}
+	})
+
+	t.Run("singleUse maps onto policy", func(t *testing.T) {
+		u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+		u.Object["spec"].(map[string]any)["singleUse"] = true
+		p, err := ToPolicy(u)
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if !p.SingleUse {
+			t.Error("SingleUse = false, want true")
+		}
+	})
+
+	t.Run("audiences and issuer map onto policy", func(t *testing.T) {
+		u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+		spec := u.Object["spec"].(map[string]any)
+		spec["audiences"] = map[string]any{tgtPayment: []any{"payments.example.com"}}
+		spec["issuer"] = "https://issuer.example.com"
+		p, err := ToPolicy(u)
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if got := p.Audiences[tgtPayment]; len(got) != 1 || got[0] != "payments.example.com" || p.Issuer != "https://issuer.example.com" {
+			t.Errorf("Audiences = %v, Issuer = %q", p.Audiences, p.Issuer)
+		}
+	})
+
+	t.Run("condition maps onto policy", func(t *testing.T) {
+		u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+		u.Object["spec"].(map[string]any)["condition"] = "ttl <= 60"
+		p, err := ToPolicy(u)
+		if err != nil {
+			t.Fatalf("ToPolicy: %v", err)
+		}
+		if p.Condition != "ttl <= 60" {
+			t.Errorf("Condition = %q, want ttl <= 60", p.Condition)
+		}
+	})
+
+	t.Run("missing spec is an error", func(t *testing.T) {
+		u := newExchangePolicy("default", "x", subOrder, tgtPayment)
+		delete(u.Object, "spec")
+		if _, err := ToPolicy(u); err == nil {
+			t.Error("expected error for missing spec, got nil")
+		}
+	})
+
+	t.Run("wrongly typed field is an error", func(t *testing.T) {
+		u := newExchangePolicy("default", "x", subOrder, tgtPayment)
+		u.Object["spec"].(map[string]any)["maxTTL"] = "five minutes"
+		if _, err := ToPolicy(u); err == nil {
+			t.Error("expected error for string maxTTL, got nil")
+		}
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	valid := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+	invalid := newExchangePolicy("default", "broken", "not-a-spiffe-id", tgtPayment)
+	// Sorts after valid, so it loses the (subject, target) conflict.
+	dup := newExchangePolicy("team-b", "order-to-payment", subOrder, tgtPayment)
+	reservedClash := newExchangePolicy("default", "cart-to-payment", subCart, tgtPayment)
+
+	client := newFakeClient(valid, invalid, dup, reservedClash)
+
+	var got []policy.Policy
+	src := NewSource(client, "",
+		func() []policy.Policy {
+			return []policy.Policy{{Name: "yaml-cart", Subject: subCart, Target: tgtPayment, AllowedScopes: []string{"a"}, MaxTTL: 60}}
+		},
+		func(ps []policy.Policy) error { got = ps; return nil },
+		zerolog.Nop(),
+	)
+	src.Reconcile(context.Background(), []any{dup, reservedClash, invalid, valid})
+
+	if len(got) != 1 || got[0].Name != "default/order-to-payment" {
+		t.Fatalf("accepted = %v, want only default/order-to-payment", got)
+	}
+
+	tests := []struct {
+		ns, name   string
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{"default", "order-to-payment", metav1.ConditionTrue, ReasonValid},
+		{"default", "broken", metav1.ConditionFalse, ReasonInvalid},
+		{"team-b", "order-to-payment", metav1.ConditionFalse, ReasonConflict},
+		{"default", "cart-to-payment", metav1.ConditionFalse, ReasonConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ns+"/"+tc.name, func(t *testing.T) {
+			c := acceptedCondition(t, client, tc.ns, tc.name)
+			if c == nil {
+				t.Fatal("Accepted condition not written")
+			}
+			if c.Status != tc.wantStatus || c.Reason != tc.wantReason {
+				t.Errorf("condition = %s/%s (%s), want %s/%s", c.Status, c.Reason, c.Message, tc.wantStatus, tc.wantReason)
+			}
+			if c.ObservedGeneration != 1 {
+				t.Errorf("ObservedGeneration = %d, want 1", c.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func TestReconcileOnChangeError(t *testing.T) {
+	valid := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+	client := newFakeClient(valid)
+
+	src := NewSource(client, "",
+		func() []policy.Policy { return nil },
+		func([]policy.Policy) error { return errors.New("max_ttl exceeds key_rotation_interval") },
+		zerolog.Nop(),
+	)
+	src.Reconcile(context.Background(), []any{valid})
+
+	c := acceptedCondition(t, client, "default", "order-to-payment")
+	if c == nil || c.Status != metav1.ConditionFalse || c.Reason != ReasonRejected {
+		t.Errorf("condition = %+v, want False/%s", c, ReasonRejected)
+	}
+}
+
+func TestReconcileSkipsUnchangedStatus(t *testing.T) {
+	valid := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+	client := newFakeClient(valid)
+	src := NewSource(client, "",
+		func() []policy.Policy { return nil },
+		func([]policy.Policy) error { return nil },
+		zerolog.Nop(),
+	)
+
+	src.Reconcile(context.Background(), []any{valid})
+	updated, err := client.Resource(GVR).Namespace("default").Get(context.Background(), "order-to-payment", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	client.ClearActions()
+
+	src.Reconcile(context.Background(), []any{updated})
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" {
+			t.Errorf("unexpected %s on %s; unchanged condition must not be rewritten", a.GetVerb(), a.GetSubresource())
+		}
+	}
+}
+
+func TestSourceStart(t *testing.T) {
+	client := newFakeClient(newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment))
+
+	var (
+		mu  sync.Mutex
+		got []policy.Policy
+	)
+	src := NewSource(client, "default",
+		func() []policy.Policy { return nil },
+		func(ps []policy.Policy) error {
+			mu.Lock()
+			got = ps
+			mu.Unlock()
+			return nil
+		},
+		zerolog.Nop(),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := src.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	mu.Lock()
+	n := len(got)
+	mu.Unlock()
+	if n != 1 {
+		t.Fatalf("after Start: %d policies applied, want 1", n)
+	}
+
+	// A newly created resource must be picked up by the informer.
+	if _, err := client.Resource(GVR).Namespace("default").Create(ctx,
+		newExchangePolicy("default", "cart-to-payment", subCart, tgtPayment), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n = len(got)
+		mu.Unlock()
+		if n == 2 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("after create: %d policies applied, want 2", n)
+}
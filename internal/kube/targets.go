@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+const (
+	// targetCacheTTL is how long a ServiceAccount lookup is reused, whether
+	// it found the account or not, so a busy target costs one API server
+	// round trip per interval rather than one per exchange.
+	targetCacheTTL = 30 * time.Second
+	// targetCacheSize bounds the lookup cache, swept like the review cache.
+	targetCacheSize = 10000
+)
+
+// ServiceAccountTargets implements server.TargetValidator for workloads
+// identified by spiffe://<trust domain>/ns/<namespace>/sa/<name>, the IDs
+// SPIRE's Kubernetes registrar issues and SATokenExtractor maps callers to.
+// A target is known when its ServiceAccount exists. IDs outside the trust
+// domain or of any other shape are unknown.
+type ServiceAccountTargets struct {
+	accounts    corev1client.ServiceAccountsGetter
+	trustDomain spiffeid.TrustDomain
+
+	mu    sync.Mutex
+	cache map[string]cachedLookup
+	now   func() time.Time
+}
+
+type cachedLookup struct {
+	known   bool
+	expires time.Time
+}
+
+// NewServiceAccountTargets returns a validator that looks ServiceAccounts up
+// with accounts. The server's ServiceAccount needs get on serviceaccounts in
+// every namespace targets live in.
+func NewServiceAccountTargets(accounts corev1client.ServiceAccountsGetter, trustDomain spiffeid.TrustDomain) *ServiceAccountTargets {
+	return &ServiceAccountTargets{
+		accounts:    accounts,
+		trustDomain: trustDomain,
+		cache:       make(map[string]cachedLookup),
+		now:         time.Now,
+	}
+}
+
+// ValidateTarget implements server.TargetValidator.
+func (v *ServiceAccountTargets) ValidateTarget(ctx context.Context, target string) error {
+	id, err := spiffeid.FromString(target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", server.ErrUnknownTarget, err)
+	}
+	if id.TrustDomain() != v.trustDomain {
+		return fmt.Errorf("%w: not in trust domain %s", server.ErrUnknownTarget, v.trustDomain.Name())
+	}
+	seg := strings.Split(strings.TrimPrefix(id.Path(), "/"), "/")
+	if len(seg) != 4 || seg[0] != "ns" || seg[2] != "sa" {
+		return fmt.Errorf("%w: not a ServiceAccount ID", server.ErrUnknownTarget)
+	}
+	ns, name := seg[1], seg[3]
+
+	known, ok := v.cached(target)
+	if !ok {
+		_, err := v.accounts.ServiceAccounts(ns).Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			known = false
+		case err != nil:
+			return fmt.Errorf("look up ServiceAccount %s/%s: %w", ns, name, err)
+		default:
+			known = true
+		}
+		v.store(target, known)
+	}
+	if !known {
+		return fmt.Errorf("%w: no ServiceAccount %s/%s", server.ErrUnknownTarget, ns, name)
+	}
+	return nil
+}
+
+func (v *ServiceAccountTargets) cached(target string) (known, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.cache[target]
+	if !ok || !v.now().Before(c.expires) {
+		return false, false
+	}
+	return c.known, true
+}
+
+func (v *ServiceAccountTargets) store(target string, known bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := v.now()
+	if len(v.cache) >= targetCacheSize {
+		for k, c := range v.cache {
+			if !now.Before(c.expires) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= targetCacheSize {
+			clear(v.cache)
+		}
+	}
+	v.cache[target] = cachedLookup{known: known, expires: now.Add(targetCacheTTL)}
+}
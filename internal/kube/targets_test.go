@@ -0,0 +1,100 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestServiceAccountTargets(t *testing.T) {
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+	tests := []struct {
+		name       string
+		target     string
+		apiErr     error
+		wantErr    error // nil for a known target
+		wantLookup bool
+	}{
+		{name: "existing ServiceAccount", target: "spiffe://cluster.local/ns/default/sa/payment", wantLookup: true},
+		{name: "missing ServiceAccount", target: "spiffe://cluster.local/ns/default/sa/paymnet", wantErr: server.ErrUnknownTarget, wantLookup: true},
+		{name: "other trust domain", target: "spiffe://partner.example/ns/default/sa/payment", wantErr: server.ErrUnknownTarget},
+		{name: "not a ServiceAccount ID", target: "spiffe://cluster.local/payment", wantErr: server.ErrUnknownTarget},
+		{name: "extra path segments", target: "spiffe://cluster.local/ns/default/sa/payment/v2", wantErr: server.ErrUnknownTarget},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "payment"}})
+			var lookups int
+			c.PrependReactor("get", "serviceaccounts", func(k8stesting.Action) (bool, runtime.Object, error) {
+				lookups++
+				return false, nil, nil
+			})
+			v := NewServiceAccountTargets(c.CoreV1(), td)
+
+			err := v.ValidateTarget(context.Background(), tc.target)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if got := lookups == 1; got != tc.wantLookup {
+				t.Errorf("lookups = %d, want lookup %v", lookups, tc.wantLookup)
+			}
+		})
+	}
+}
+
+func TestServiceAccountTargetsCache(t *testing.T) {
+	c := fake.NewClientset()
+	var lookups int
+	c.PrependReactor("get", "serviceaccounts", func(k8stesting.Action) (bool, runtime.Object, error) {
+		lookups++
+		return false, nil, nil
+	})
+	v := NewServiceAccountTargets(c.CoreV1(), spiffeid.RequireTrustDomainFromString("cluster.local"))
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+
+	for range 2 {
+		if err := v.ValidateTarget(context.Background(), target); !errors.Is(err, server.ErrUnknownTarget) {
+			t.Fatalf("err = %v, want ErrUnknownTarget", err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("lookups = %d, want 1: a cached miss is reused", lookups)
+	}
+
+	// Creating the account takes effect once the cached miss expires.
+	if _, err := c.CoreV1().ServiceAccounts("default").Create(context.Background(),
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "payment"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create ServiceAccount: %v", err)
+	}
+	now = now.Add(targetCacheTTL)
+	if err := v.ValidateTarget(context.Background(), target); err != nil {
+		t.Errorf("after expiry: %v", err)
+	}
+}
+
+func TestServiceAccountTargetsAPIError(t *testing.T) {
+	c := fake.NewClientset()
+	c.PrependReactor("get", "serviceaccounts", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	v := NewServiceAccountTargets(c.CoreV1(), spiffeid.RequireTrustDomainFromString("cluster.local"))
+	err := v.ValidateTarget(context.Background(), "spiffe://cluster.local/ns/default/sa/payment")
+	if err == nil || errors.Is(err, server.ErrUnknownTarget) {
+		t.Errorf("err = %v, want a lookup failure", err)
+	}
+}
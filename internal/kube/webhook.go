@@ -0,0 +1,137 @@
+package kube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// admissionBodyLimit caps the size of an AdmissionReview body. The API server
+// sends the full object, and an ExchangePolicy is a few hundred bytes; 1 MiB
+// leaves generous headroom while bounding memory for a misbehaving client.
+const admissionBodyLimit = 1 << 20
+
+// ValidatePolicyObject runs the same checks as the policy file loader against
+// an ExchangePolicy object and returns a message that names the offending
+// spec field, so that kubectl users can fix the manifest without reading the
+// server source.
+func ValidatePolicyObject(u *unstructured.Unstructured) error {
+	p, err := ToPolicy(u)
+	if err != nil {
+		return err
+	}
+	return validatePolicy(p)
+}
+
+// validatePolicy wraps policy.ValidateOne, prefixing any error with the spec
+// field it refers to.
+func validatePolicy(p policy.Policy) error {
+	if err := policy.ValidateOne(p); err != nil {
+		return fmt.Errorf("%s: %w", specField(err), err)
+	}
+	return nil
+}
+
+// specFields maps policy file keys onto the ExchangePolicy spec fields
+// they come from.
+var specFields = map[string]string{
+	"subject":        "spec.subject",
+	"target":         "spec.target",
+	"allowed_scopes": "spec.allowedScopes",
+	"max_ttl":        "spec.maxTTL",
+	"token_format":   "spec.tokenFormat",
+	"single_use":     "spec.singleUse",
+	"audiences":      "spec.audiences",
+	"issuer":         "spec.issuer",
+	"condition":      "spec.condition",
+}
+
+// specField maps a policy.ValidateOne error onto the ExchangePolicy spec
+// field it refers to. Errors about no one field are reported on the
+// resource name.
+func specField(err error) string {
+	var fe *policy.FieldError
+	if errors.As(err, &fe) {
+		if f, ok := specFields[fe.Field]; ok {
+			return f
+		}
+	}
+	return "metadata.name"
+}
+
+// NewWebhookHandler returns an http.Handler implementing a Kubernetes
+// validating admission webhook for ExchangePolicy resources. CREATE and UPDATE
+// requests are checked with ValidatePolicyObject; every other operation is
+// allowed. Conflicts with other policies are not checked here — they depend on
+// state the API server cannot see and are reported on the resource's Accepted
+// status condition instead.
+func NewWebhookHandler(log zerolog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var review admissionv1.AdmissionReview
+		if err := json.NewDecoder(io.LimitReader(r.Body, admissionBodyLimit)).Decode(&review); err != nil {
+			http.Error(w, "decode AdmissionReview: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+			return
+		}
+
+		resp := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+		if err := admit(review.Request); err != nil {
+			resp.Allowed = false
+			resp.Result = &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusUnprocessableEntity,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: err.Error(),
+			}
+			log.Info().
+				Str("name", PolicyName(review.Request.Namespace, review.Request.Name)).
+				Str("operation", string(review.Request.Operation)).
+				Err(err).
+				Msg("ExchangePolicy rejected at admission")
+		}
+
+		review.Request = nil
+		review.Response = resp
+		body, err := json.Marshal(review)
+		if err != nil {
+			log.Error().Err(err).Msg("webhook: marshal AdmissionReview")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(body); err != nil {
+			log.Error().Err(err).Msg("webhook: write response")
+		}
+	})
+}
+
+// admit returns a non-nil error if req must be denied.
+func admit(req *admissionv1.AdmissionRequest) error {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return nil
+	}
+	var u unstructured.Unstructured
+	if err := u.UnmarshalJSON(req.Object.Raw); err != nil {
+		return fmt.Errorf("decode object: %w", err)
+	}
+	if err := ValidatePolicyObject(&u); err != nil {
+		return fmt.Errorf("ExchangePolicy %s is invalid: %w", PolicyName(u.GetNamespace(), u.GetName()), err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestValidatePolicyObject(t *testing.T) {
+	audiences := map[string]any{tgtPayment: []any{"payments"}}
+	tests := []struct {
+		name      string
+		mutate    func(spec map[string]any)
+		wantField string // empty means valid
+	}{
+		{name: "valid", mutate: func(map[string]any) {}},
+		{name: "bad subject", mutate: func(s map[string]any) { s["subject"] = "https://example.com" }, wantField: "spec.subject"},
+		{name: "bad target", mutate: func(s map[string]any) { s["target"] = "spiffe://" }, wantField: "spec.target"},
+		{name: "no scopes", mutate: func(s map[string]any) { s["allowedScopes"] = []any{} }, wantField: "spec.allowedScopes"},
+		{name: "zero ttl", mutate: func(s map[string]any) { s["maxTTL"] = int64(0) }, wantField: "spec.maxTTL"},
+		{name: "unknown token format", mutate: func(s map[string]any) { s["tokenFormat"] = "saml" }, wantField: "spec.tokenFormat"},
+		{name: "condition does not compile", mutate: func(s map[string]any) { s["condition"] = "ttl ==" }, wantField: "spec.condition"},
+		{name: "condition is not a bool", mutate: func(s map[string]any) { s["condition"] = "ttl + 1" }, wantField: "spec.condition"},
+		{name: "bad scope pattern", mutate: func(s map[string]any) { s["allowedScopes"] = []any{"payments:{id"} }, wantField: "spec.allowedScopes"},
+		{name: "single use macaroon", mutate: func(s map[string]any) { s["singleUse"], s["tokenFormat"] = true, "macaroon" }, wantField: "spec.singleUse"},
+		{name: "single use with issuer", mutate: func(s map[string]any) { s["singleUse"], s["issuer"] = true, "https://issuer.example.com" }, wantField: "spec.singleUse"},
+		{name: "issuer with macaroon", mutate: func(s map[string]any) { s["issuer"], s["tokenFormat"] = "https://issuer.example.com", "macaroon" }, wantField: "spec.issuer"},
+		{name: "audiences with paseto", mutate: func(s map[string]any) { s["audiences"], s["tokenFormat"] = audiences, "paseto" }, wantField: "spec.audiences"},
+		{name: "issuer with spaces", mutate: func(s map[string]any) { s["issuer"] = "svid exchange" }, wantField: "spec.issuer"},
+		{name: "bad audiences key", mutate: func(s map[string]any) { s["audiences"] = map[string]any{"https://example.com": []any{"payments"}} }, wantField: "spec.audiences"},
+		{name: "audiences key outside target", mutate: func(s map[string]any) { s["audiences"] = map[string]any{subOrder: []any{"payments"}} }, wantField: "spec.audiences"},
+		{name: "empty audiences", mutate: func(s map[string]any) { s["audiences"] = map[string]any{tgtPayment: []any{}} }, wantField: "spec.audiences"},
+		{name: "bad audience", mutate: func(s map[string]any) { s["audiences"] = map[string]any{tgtPayment: []any{"pay ments"}} }, wantField: "spec.audiences"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)
+			tc.mutate(u.Object["spec"].(map[string]any))
+			err := ValidatePolicyObject(u)
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.HasPrefix(err.Error(), tc.wantField+":") {
+				t.Errorf("error %q does not name field %s", err, tc.wantField)
+			}
+		})
+	}
+}
+
+func review(t *testing.T, op admissionv1.Operation, obj runtime.Object) []byte {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("marshal object: %v", err)
+	}
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("req-1"),
+			Operation: op,
+			Namespace: "default",
+			Name:      "order-to-payment",
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal review: %v", err)
+	}
+	return body
+}
+
+func TestWebhookHandler(t *testing.T) {
+	h := NewWebhookHandler(zerolog.Nop())
+
+	invalid := newExchangePolicy("default", "order-to-payment", "not-a-spiffe-id", tgtPayment)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		wantAllowed bool
+		wantMsg     string
+	}{
+		{
+			name:        "valid create is allowed",
+			body:        review(t, admissionv1.Create, newExchangePolicy("default", "order-to-payment", subOrder, tgtPayment)),
+			wantAllowed: true,
+		},
+		{
+			name:    "invalid create is denied with field path",
+			body:    review(t, admissionv1.Create, invalid),
+			wantMsg: "ExchangePolicy default/order-to-payment is invalid: spec.subject:",
+		},
+		{
+			name:    "invalid update is denied",
+			body:    review(t, admissionv1.Update, invalid),
+			wantMsg: "spec.subject",
+		},
+		{
+			name:        "delete is always allowed",
+			body:        review(t, admissionv1.Delete, invalid),
+			wantAllowed: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate-exchangepolicy", bytes.NewReader(tc.body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var got admissionv1.AdmissionReview
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got.Response == nil {
+				t.Fatal("response is nil")
+			}
+			if got.Response.UID != "req-1" {
+				t.Errorf("UID = %q, want req-1", got.Response.UID)
+			}
+			if got.Response.Allowed != tc.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", got.Response.Allowed, tc.wantAllowed)
+			}
+			if tc.wantMsg != "" {
+				if got.Response.Result == nil || !strings.Contains(got.Response.Result.Message, tc.wantMsg) {
+					t.Errorf("Result = %+v, want message containing %q", got.Response.Result, tc.wantMsg)
+				}
+			}
+		})
+	}
+
+	t.Run("malformed body is a bad request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate-exchangepolicy", strings.NewReader("{")))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+
+	t.Run("GET is rejected", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/validate-exchangepolicy", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want 405", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// conditionCostLimit bounds the work one condition may do per evaluation,
+// in CEL cost units, so that a pathological expression cannot stall the
+// exchange path. Ordinary conditions cost a few dozen units.
+const conditionCostLimit = 10_000
+
+// conditionEnv declares the variables a condition can use:
+//
+//   - subject and target: maps with "id", the full SPIFFE ID,
+//     "trust_domain", and "path".
+//   - scopes: the requested scopes, in request order.
+//   - ttl: the requested TTL in seconds; 0 when the caller left it to the
+//     policy.
+//   - now: the time of the request, a timestamp. now.getHours("UTC") is the
+//     hour of day.
+var conditionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("target", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("scopes", cel.ListType(cel.StringType)),
+		cel.Variable("ttl", cel.IntType),
+		cel.Variable("now", cel.TimestampType),
+	)
+})
+
+// compileCondition type-checks the CEL expression expr, which must be a
+// bool, and returns the program that evaluates it.
+func compileCondition(expr string) (cel.Program, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, errors.New("must not be blank")
+	}
+	env, err := conditionEnv()
+	if err != nil {
+		return nil, fmt.Errorf("condition environment: %w", err)
+	}
+	ast, iss := env.Compile(expr)
+	if err := iss.Err(); err != nil {
+		return nil, err
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("must evaluate to bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(conditionCostLimit))
+}
+
+// request is an exchange request as conditions see it.
+type request struct {
+	subject, target string
+	scopes          []string
+	ttl             int32
+	now             time.Time
+}
+
+// activation returns r's condition variables.
+func (r request) activation() map[string]any {
+	return map[string]any{
+		"subject": idFields(r.subject),
+		"target":  idFields(r.target),
+		"scopes":  r.scopes,
+		"ttl":     int64(r.ttl),
+		"now":     r.now,
+	}
+}
+
+// idFields splits a SPIFFE ID into the fields of the subject and target
+// condition variables.
+func idFields(id string) map[string]string {
+	rest := strings.TrimPrefix(id, "spiffe://")
+	td, path, _ := strings.Cut(rest, "/")
+	return map[string]string{"id": id, "trust_domain": td, "path": "/" + path}
+}
+
+// holds reports whether prg, a compiled condition, is true for vars. An
+// expression that fails at run time, for example by exceeding the cost
+// limit, does not hold: conditions fail closed.
+func holds(prg cel.Program, vars map[string]any) bool {
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		return false
+	}
+	b, ok := out.Value().(bool)
+	return ok && b
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{name: "scope count", expr: "size(scopes) <= 2"},
+		{name: "requested ttl", expr: "ttl > 0 && ttl <= 60"},
+		{name: "time of day", expr: `now.getHours("UTC") >= 9 && now.getHours("UTC") < 17`},
+		{name: "identity fields", expr: `subject.trust_domain == target.trust_domain && subject.path.startsWith("/ns/batch/")`},
+		{name: "scope membership", expr: `!("payments:refund" in scopes)`},
+		{name: "not a bool", expr: "size(scopes)", wantErr: "must evaluate to bool"},
+		{name: "syntax error", expr: "ttl >", wantErr: "Syntax error"},
+		{name: "unknown variable", expr: "caller == 'x'", wantErr: "undeclared reference"},
+		{name: "type error", expr: "ttl == 'sixty'", wantErr: "no matching overload"},
+		{name: "blank", expr: "  ", wantErr: "must not be blank"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compileCondition(tc.expr)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("compileCondition(%q): %v", tc.expr, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("compileCondition(%q) error = %v, want it to contain %q", tc.expr, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	noon := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		condition string
+		scopes    []string
+		ttl       int32
+		now       time.Time
+		want      bool
+	}{
+		{name: "holds", condition: "size(scopes) == 1", scopes: []string{"payments:charge"}, want: true},
+		{name: "too many scopes", condition: "size(scopes) == 1", scopes: []string{"payments:charge", "payments:refund"}},
+		{name: "ttl left to the policy", condition: "ttl == 0", scopes: []string{"payments:charge"}, want: true},
+		{name: "ttl too long", condition: "ttl != 0 && ttl <= 60", scopes: []string{"payments:charge"}, ttl: 120},
+		{name: "business hours", condition: `now.getHours("UTC") < 18`, scopes: []string{"payments:charge"}, now: noon, want: true},
+		{name: "outside business hours", condition: `now.getHours("UTC") < 18`, scopes: []string{"payments:charge"}, now: night},
+		{name: "identity fields", condition: `subject.path == "/ns/default/sa/order" && target.id == "` + payment + `"`, scopes: []string{"payments:charge"}, want: true},
+		{name: "runtime error fails closed", condition: `subject["missing"] == ""`, scopes: []string{"payments:charge"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := NewLoader([]Policy{{
+				Name:          "order-to-payment",
+				Subject:       order,
+				Target:        payment,
+				AllowedScopes: []string{"payments:charge", "payments:refund"},
+				MaxTTL:        300,
+				Condition:     tc.condition,
+			}})
+			if err != nil {
+				t.Fatalf("NewLoader: %v", err)
+			}
+			l.now = func() time.Time { return tc.now }
+			r := l.Evaluate(order, payment, tc.scopes, tc.ttl)
+			if r.Allowed != tc.want {
+				t.Errorf("Allowed = %t, want %t", r.Allowed, tc.want)
+			}
+			if !r.Conditional {
+				t.Error("Conditional = false for a policy with a condition")
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionPrecedence(t *testing.T) {
+	const (
+		nightly = "spiffe://cluster.local/ns/batch/sa/nightly"
+		reports = "spiffe://cluster.local/ns/default/sa/reports"
+	)
+	policies := []Policy{
+		{
+			Name:          "nightly-to-reports",
+			Subject:       nightly,
+			Target:        reports,
+			AllowedScopes: []string{"reports:read", "reports:write"},
+			MaxTTL:        60,
+			Condition:     "ttl <= 30",
+		},
+		{
+			Name:          "batch-to-reports",
+			Subject:       "spiffe://cluster.local/ns/batch/sa/*",
+			Target:        reports,
+			AllowedScopes: []string{"reports:read"},
+			MaxTTL:        120,
+		},
+	}
+	scopes := []string{"reports:read", "reports:write"}
+
+	tests := []struct {
+		mode       ConflictMode
+		wantScopes []string
+	}{
+		// The literal rule applies and denies; the pattern is not tried.
+		{mode: ConflictWarn},
+		// The literal rule contributes nothing to the union.
+		{mode: ConflictMergeUnion, wantScopes: []string{"reports:read"}},
+		// The literal rule empties the intersection.
+		{mode: ConflictMergeIntersection},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			l, err := NewLoaderWithConflictMode(policies, tc.mode)
+			if err != nil {
+				t.Fatalf("NewLoaderWithConflictMode: %v", err)
+			}
+			res := l.Evaluate(nightly, reports, scopes, 90)
+			if res.Allowed != (tc.wantScopes != nil) || strings.Join(res.GrantedScopes, " ") != strings.Join(tc.wantScopes, " ") {
+				t.Errorf("Evaluate = %t %v, want %v", res.Allowed, res.GrantedScopes, tc.wantScopes)
+			}
+			if res := l.Evaluate(nightly, reports, scopes, 30); !res.Allowed {
+				t.Errorf("Evaluate with the condition holding denied")
+			}
+		})
+	}
+}
+
+func TestLoaderRejectsInvalidCondition(t *testing.T) {
+	p := Policy{
+		Name:          "bad-condition",
+		Subject:       "spiffe://cluster.local/ns/default/sa/order",
+		Target:        "spiffe://cluster.local/ns/default/sa/payment",
+		AllowedScopes: []string{"payments:charge"},
+		MaxTTL:        60,
+		Condition:     "size(scopes)",
+	}
+	if _, err := NewLoader([]Policy{p}); err == nil || !strings.Contains(err.Error(), "invalid condition") {
+		t.Errorf("NewLoader error = %v, want an invalid condition", err)
+	}
+	if err := ValidateOne(p); err == nil {
+		t.Error("ValidateOne accepted a non-bool condition")
+	}
+}
@@ -0,0 +1,323 @@
+package policy
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ConflictMode selects how a Loader handles conflicting rules: two policies
+// that can both match the same (subject, target) pair but grant different
+// scopes, TTLs, or token formats. Only pattern rules can conflict, since two
+// literal rules for the same pair are rejected as duplicates.
+type ConflictMode string
+
+// Conflict modes accepted by NewLoaderWithConflictMode.
+const (
+	// ConflictWarn loads the set and records conflicts for the caller to
+	// report. Evaluation uses the first matching rule, literal rules first.
+	ConflictWarn ConflictMode = "warn"
+	// ConflictError rejects a policy set containing any conflict.
+	ConflictError ConflictMode = "error"
+	// ConflictMergeUnion evaluates every matching rule: the caller receives
+	// every requested scope at least one of them allows, for the longest TTL
+	// among the rules that grant something.
+	ConflictMergeUnion ConflictMode = "merge-union"
+	// ConflictMergeIntersection evaluates every matching rule together: the
+	// caller may only receive scopes all of them allow, capped to the
+	// smallest max_ttl among them.
+	ConflictMergeIntersection ConflictMode = "merge-intersection"
+)
+
+// ConflictModes lists every accepted ConflictMode.
+var ConflictModes = []ConflictMode{ConflictWarn, ConflictError, ConflictMergeUnion, ConflictMergeIntersection}
+
+// ParseConflictMode returns the ConflictMode named by s. The empty string
+// selects ConflictWarn.
+func ParseConflictMode(s string) (ConflictMode, error) {
+	if s == "" {
+		return ConflictWarn, nil
+	}
+	if m := ConflictMode(s); slices.Contains(ConflictModes, m) {
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown conflict mode %q (must be one of %v)", s, ConflictModes)
+}
+
+func (m ConflictMode) merges() bool {
+	return m == ConflictMergeUnion || m == ConflictMergeIntersection
+}
+
+// Conflict describes two policies that overlap with different grants.
+type Conflict struct {
+	// First and Second are the positions of the two policies in the loaded
+	// set, First < Second.
+	First, Second int
+	// FirstName and SecondName are the policies' names.
+	FirstName, SecondName string
+	// Differences lists what the two policies disagree on, e.g.
+	// "max_ttl 300 vs 60".
+	Differences []string
+}
+
+// String implements fmt.Stringer.
+func (c Conflict) String() string {
+	return fmt.Sprintf("policies %d (%q) and %d (%q) overlap with different %s",
+		c.First, c.FirstName, c.Second, c.SecondName, strings.Join(c.Differences, ", "))
+}
+
+// FindConflicts returns every pair of policies in ps that can match the same
+// (subject, target) pair but differ in allowed_scopes, max_ttl, or
+// token_format. Overlap between patterns is decided exactly for path.Match
+// syntax, so a reported pair always has at least one SPIFFE ID pair in
+// common. Identical duplicates are not conflicts; NewLoader rejects them
+// separately.
+func FindConflicts(ps []Policy) []Conflict {
+	var out []Conflict
+	for i, a := range ps {
+		aPattern := IsPattern(a.Subject) || IsPattern(a.Target)
+		for j := i + 1; j < len(ps); j++ {
+			b := ps[j]
+			if !aPattern && !IsPattern(b.Subject) && !IsPattern(b.Target) {
+				continue // two literal rules overlap only as duplicates
+			}
+			if a.Subject == b.Subject && a.Target == b.Target {
+				continue // duplicate, reported by NewLoader
+			}
+			if !idsOverlap(a.Subject, b.Subject) || !idsOverlap(a.Target, b.Target) {
+				continue
+			}
+			if diffs := grantDifferences(a, b); len(diffs) > 0 {
+				out = append(out, Conflict{First: i, Second: j, FirstName: a.Name, SecondName: b.Name, Differences: diffs})
+			}
+		}
+	}
+	return out
+}
+
+// grantDifferences lists the grant fields on which a and b disagree.
+func grantDifferences(a, b Policy) []string {
+	var diffs []string
+	if !sameScopes(a.AllowedScopes, b.AllowedScopes) {
+		diffs = append(diffs, fmt.Sprintf("allowed_scopes %v vs %v", a.AllowedScopes, b.AllowedScopes))
+	}
+	if a.MaxTTL != b.MaxTTL {
+		diffs = append(diffs, fmt.Sprintf("max_ttl %d vs %d", a.MaxTTL, b.MaxTTL))
+	}
+	if fa, fb := formatOf(a), formatOf(b); fa != fb {
+		diffs = append(diffs, fmt.Sprintf("token_format %s vs %s", fa, fb))
+	}
+	if a.RequireNonce != b.RequireNonce {
+		diffs = append(diffs, fmt.Sprintf("require_nonce %t vs %t", a.RequireNonce, b.RequireNonce))
+	}
+	if a.RequiresApproval != b.RequiresApproval {
+		diffs = append(diffs, fmt.Sprintf("requires_approval %t vs %t", a.RequiresApproval, b.RequiresApproval))
+	}
+	if a.SingleUse != b.SingleUse {
+		diffs = append(diffs, fmt.Sprintf("single_use %t vs %t", a.SingleUse, b.SingleUse))
+	}
+	if a.Issuer != b.Issuer {
+		diffs = append(diffs, fmt.Sprintf("issuer %q vs %q", a.Issuer, b.Issuer))
+	}
+	if !sameAudiences(a.Audiences, b.Audiences) {
+		diffs = append(diffs, fmt.Sprintf("audiences %v vs %v", a.Audiences, b.Audiences))
+	}
+	if a.Condition != b.Condition {
+		diffs = append(diffs, fmt.Sprintf("condition %q vs %q", a.Condition, b.Condition))
+	}
+	return diffs
+}
+
+// sameTokenShape reports whether a and b mint alike: the same token format,
+// issuer, and audiences. Rules that do not cannot be merged.
+func sameTokenShape(a, b Policy) bool {
+	return formatOf(a) == formatOf(b) && a.Issuer == b.Issuer && sameAudiences(a.Audiences, b.Audiences)
+}
+
+// sameAudiences reports whether a and b map the same targets to the same
+// audiences, in the same order.
+func sameAudiences(a, b map[string][]string) bool {
+	return maps.EqualFunc(a, b, slices.Equal)
+}
+
+// sameScopes reports whether a and b hold the same set of scopes.
+func sameScopes(a, b []string) bool {
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			return false
+		}
+	}
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatOf returns p's token format with the default applied.
+func formatOf(p Policy) string {
+	if p.TokenFormat == "" {
+		return FormatJWT
+	}
+	return p.TokenFormat
+}
+
+// intersectPolicies combines the rules in ps, which all match one request,
+// into the single rule ConflictMergeIntersection evaluates: only scopes every
+// rule allows, capped to the smallest max_ttl, requiring a nonce or an
+// approval, or minting single-use tokens, if any rule does. A scope of the
+// first rule is kept when the others list it or have a template that permits
+// it. ps must share a token format, issuer, and audiences, which
+// NewLoaderWithConflictMode guarantees for merge modes.
+func intersectPolicies(ps []Policy) Policy {
+	merged := Policy{
+		Name:             ps[0].Name,
+		TokenFormat:      ps[0].TokenFormat,
+		AllowedScopes:    slices.Clone(ps[0].AllowedScopes),
+		MaxTTL:           ps[0].MaxTTL,
+		RequireNonce:     ps[0].RequireNonce,
+		RequiresApproval: ps[0].RequiresApproval,
+		SingleUse:        ps[0].SingleUse,
+		Audiences:        ps[0].Audiences,
+		Issuer:           ps[0].Issuer,
+	}
+	for _, p := range ps[1:] {
+		merged.AllowedScopes = slices.DeleteFunc(merged.AllowedScopes, func(s string) bool {
+			return !slices.Contains(p.AllowedScopes, s) && !scopeAllowed(s, p.AllowedScopes)
+		})
+		merged.MaxTTL = min(merged.MaxTTL, p.MaxTTL)
+		merged.RequireNonce = merged.RequireNonce || p.RequireNonce
+		merged.RequiresApproval = merged.RequiresApproval || p.RequiresApproval
+		merged.SingleUse = merged.SingleUse || p.SingleUse
+	}
+	return merged
+}
+
+// idsOverlap reports whether some SPIFFE ID matches both a and b, each a
+// literal ID or a pattern. Since no wildcard matches "/", the two must have
+// the same number of path segments and every segment pair must overlap.
+func idsOverlap(a, b string) bool {
+	if !IsPattern(a) && !IsPattern(b) {
+		return a == b
+	}
+	if !IsPattern(a) {
+		return matchID(b, a)
+	}
+	if !IsPattern(b) {
+		return matchID(a, b)
+	}
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if !globsOverlap(tokenize(as[i]), tokenize(bs[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// globToken is one element of a path.Match pattern segment.
+type globToken struct {
+	kind  byte   // 'c' literal, '?' any character, '[' class, '*' star
+	c     byte   // literal character
+	class string // full "[...]" class expression
+}
+
+// tokenize splits a validated pattern segment into tokens.
+func tokenize(seg string) []globToken {
+	var toks []globToken
+	for i := 0; i < len(seg); i++ {
+		switch seg[i] {
+		case '*':
+			toks = append(toks, globToken{kind: '*'})
+		case '?':
+			toks = append(toks, globToken{kind: '?'})
+		case '\\':
+			if i+1 < len(seg) {
+				i++
+			}
+			toks = append(toks, globToken{kind: 'c', c: seg[i]})
+		case '[':
+			end := i + 1
+			for end < len(seg) && seg[end] != ']' {
+				if seg[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(seg)-1)
+			toks = append(toks, globToken{kind: '[', class: seg[i : end+1]})
+			i = end
+		default:
+			toks = append(toks, globToken{kind: 'c', c: seg[i]})
+		}
+	}
+	return toks
+}
+
+// tokenMatches reports whether the single-character token t matches c.
+func tokenMatches(t globToken, c byte) bool {
+	switch t.kind {
+	case '?':
+		return true
+	case '[':
+		ok, _ := path.Match(t.class, string(c))
+		return ok
+	default:
+		return t.c == c
+	}
+}
+
+// charsOverlap reports whether two single-character tokens can match the
+// same character. SPIFFE ID paths are printable ASCII, so classes are
+// compared over that range.
+func charsOverlap(a, b globToken) bool {
+	switch {
+	case a.kind == '?' || b.kind == '?':
+		return true
+	case a.kind == 'c':
+		return tokenMatches(b, a.c)
+	case b.kind == 'c':
+		return tokenMatches(a, b.c)
+	}
+	for c := byte(0x21); c < 0x7f; c++ {
+		if tokenMatches(a, c) && tokenMatches(b, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// globsOverlap reports whether some string matches both token sequences.
+func globsOverlap(a, b []globToken) bool {
+	memo := make(map[[2]int]bool)
+	var walk func(i, j int) bool
+	walk = func(i, j int) bool {
+		key := [2]int{i, j}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+		var res bool
+		switch {
+		case i == len(a) && j == len(b):
+			res = true
+		case i < len(a) && a[i].kind == '*':
+			// The star matches nothing, or absorbs whatever b[j] matches.
+			res = walk(i+1, j) || (j < len(b) && walk(i, j+1))
+		case j < len(b) && b[j].kind == '*':
+			res = walk(i, j+1) || (i < len(a) && walk(i+1, j))
+		case i == len(a) || j == len(b):
+			res = false
+		default:
+			res = charsOverlap(a[i], b[j]) && walk(i+1, j+1)
+		}
+		memo[key] = res
+		return res
+	}
+	return walk(0, 0)
+}
@@ -0,0 +1,358 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIDsOverlap(t *testing.T) {
+	const td = "spiffe://cluster.local"
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: td + "/ns/a/sa/x", b: td + "/ns/a/sa/x", want: true},
+		{a: td + "/ns/a/sa/x", b: td + "/ns/a/sa/y", want: false},
+		{a: td + "/ns/a/sa/*", b: td + "/ns/a/sa/x", want: true},
+		{a: td + "/ns/a/sa/x", b: td + "/ns/*/sa/x", want: true},
+		{a: td + "/ns/a/sa/*", b: td + "/ns/b/sa/*", want: false},
+		{a: td + "/ns/*/sa/x", b: td + "/ns/a/sa/*", want: true},
+		{a: td + "/ns/a/sa/*", b: td + "/ns/a/sa/*/extra", want: false},
+		{a: td + "/ns/a/sa/web-*", b: td + "/ns/a/sa/*-canary", want: true},
+		{a: td + "/ns/a/sa/web-*", b: td + "/ns/a/sa/api-*", want: false},
+		{a: td + "/ns/a/sa/job-?", b: td + "/ns/a/sa/job-??", want: false},
+		{a: td + "/ns/a/sa/job-[0-4]", b: td + "/ns/a/sa/job-[5-9]", want: false},
+		{a: td + "/ns/a/sa/job-[0-5]", b: td + "/ns/a/sa/job-[5-9]", want: true},
+		{a: td + "/ns/a/sa/job-[^0-9]", b: td + "/ns/a/sa/job-7", want: false},
+		{a: td + "/ns/a/sa/\\*", b: td + "/ns/a/sa/x", want: false},
+		{a: "spiffe://other.example/ns/a/sa/*", b: td + "/ns/a/sa/x", want: false},
+	}
+	for _, tc := range tests {
+		if got := idsOverlap(tc.a, tc.b); got != tc.want {
+			t.Errorf("idsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+		if got := idsOverlap(tc.b, tc.a); got != tc.want {
+			t.Errorf("idsOverlap(%q, %q) = %v, want %v", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+// conflictingPolicies overlap on batch/sa/nightly → reports.
+func conflictingPolicies() []Policy {
+	return []Policy{
+		{
+			Name:          "batch-to-reports",
+			Subject:       "spiffe://cluster.local/ns/batch/sa/*",
+			Target:        "spiffe://cluster.local/ns/default/sa/reports",
+			AllowedScopes: []string{"reports:read"},
+			MaxTTL:        120,
+		},
+		{
+			Name:          "nightly-to-reports",
+			Subject:       "spiffe://cluster.local/ns/batch/sa/nightly",
+			Target:        "spiffe://cluster.local/ns/default/sa/reports",
+			AllowedScopes: []string{"reports:read", "reports:write"},
+			MaxTTL:        60,
+		},
+		{
+			Name:          "web-to-reports",
+			Subject:       "spiffe://cluster.local/ns/web/sa/*",
+			Target:        "spiffe://cluster.local/ns/default/sa/reports",
+			AllowedScopes: []string{"reports:write"},
+			MaxTTL:        30,
+		},
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	t.Run("overlapping rules with different grants", func(t *testing.T) {
+		got := FindConflicts(conflictingPolicies())
+		if len(got) != 1 {
+			t.Fatalf("got %d conflicts, want 1: %v", len(got), got)
+		}
+		c := got[0]
+		if c.First != 0 || c.Second != 1 || c.FirstName != "batch-to-reports" || c.SecondName != "nightly-to-reports" {
+			t.Errorf("conflict = %+v", c)
+		}
+		if len(c.Differences) != 2 || !strings.HasPrefix(c.Differences[0], "allowed_scopes") || !strings.HasPrefix(c.Differences[1], "max_ttl") {
+			t.Errorf("Differences = %v", c.Differences)
+		}
+	})
+
+	t.Run("overlapping rules with identical grants", func(t *testing.T) {
+		ps := conflictingPolicies()[:2]
+		ps[1].AllowedScopes = []string{"reports:read"}
+		ps[1].MaxTTL = 120
+		if got := FindConflicts(ps); len(got) != 0 {
+			t.Errorf("got conflicts %v, want none", got)
+		}
+	})
+
+	t.Run("token format difference", func(t *testing.T) {
+		ps := conflictingPolicies()[:2]
+		ps[1].AllowedScopes = []string{"reports:read"}
+		ps[1].MaxTTL = 120
+		ps[1].TokenFormat = FormatPASETO
+		got := FindConflicts(ps)
+		if len(got) != 1 || got[0].Differences[0] != "token_format jwt vs paseto" {
+			t.Errorf("got %v, want a token_format conflict", got)
+		}
+	})
+
+	t.Run("require_nonce difference", func(t *testing.T) {
+		ps := conflictingPolicies()[:2]
+		ps[1].AllowedScopes = []string{"reports:read"}
+		ps[1].MaxTTL = 120
+		ps[1].RequireNonce = true
+		got := FindConflicts(ps)
+		if len(got) != 1 || got[0].Differences[0] != "require_nonce false vs true" {
+			t.Errorf("got %v, want a require_nonce conflict", got)
+		}
+	})
+
+	t.Run("condition difference", func(t *testing.T) {
+		ps := conflictingPolicies()[:2]
+		ps[1].AllowedScopes = []string{"reports:read"}
+		ps[1].MaxTTL = 120
+		ps[1].Condition = "ttl <= 60"
+		got := FindConflicts(ps)
+		if len(got) != 1 || got[0].Differences[0] != `condition "" vs "ttl <= 60"` {
+			t.Errorf("got %v, want a condition conflict", got)
+		}
+	})
+}
+
+func TestParseConflictMode(t *testing.T) {
+	for _, in := range []string{"", "warn", "error", "merge-union", "merge-intersection"} {
+		if _, err := ParseConflictMode(in); err != nil {
+			t.Errorf("ParseConflictMode(%q): %v", in, err)
+		}
+	}
+	if m, _ := ParseConflictMode(""); m != ConflictWarn {
+		t.Errorf("default mode = %q, want warn", m)
+	}
+	if _, err := ParseConflictMode("first-wins"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestConflictModes(t *testing.T) {
+	const (
+		nightly = "spiffe://cluster.local/ns/batch/sa/nightly"
+		reports = "spiffe://cluster.local/ns/default/sa/reports"
+	)
+	all := []string{"reports:read", "reports:write"}
+
+	tests := []struct {
+		mode        ConflictMode
+		wantLoadErr bool
+		wantScopes  []string
+		wantTTL     int32
+		wantRules   []string
+	}{
+		// warn: the literal rule takes precedence.
+		{mode: ConflictWarn, wantScopes: all, wantTTL: 60, wantRules: []string{"nightly-to-reports"}},
+		{mode: ConflictError, wantLoadErr: true},
+		{mode: ConflictMergeUnion, wantScopes: all, wantTTL: 120, wantRules: []string{"nightly-to-reports", "batch-to-reports"}},
+		{mode: ConflictMergeIntersection, wantScopes: []string{"reports:read"}, wantTTL: 60, wantRules: []string{"nightly-to-reports", "batch-to-reports"}},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			l, err := NewLoaderWithConflictMode(conflictingPolicies(), tc.mode)
+			if tc.wantLoadErr {
+				if err == nil {
+					t.Fatal("expected load error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewLoaderWithConflictMode: %v", err)
+			}
+			if n := len(l.Conflicts()); n != 1 {
+				t.Errorf("Conflicts() has %d entries, want 1", n)
+			}
+			res := l.Evaluate(nightly, reports, all, 600)
+			if !res.Allowed {
+				t.Fatal("expected allow")
+			}
+			if !slices.Equal(res.GrantedScopes, tc.wantScopes) || res.GrantedTTL != tc.wantTTL {
+				t.Errorf("granted %v for %ds, want %v for %ds", res.GrantedScopes, res.GrantedTTL, tc.wantScopes, tc.wantTTL)
+			}
+			if !slices.Equal(res.MatchedRules, tc.wantRules) {
+				t.Errorf("MatchedRules = %v, want %v", res.MatchedRules, tc.wantRules)
+			}
+			// A caller matching only one rule is unaffected by merging.
+			res = l.Evaluate("spiffe://cluster.local/ns/batch/sa/hourly", reports, all, 600)
+			if !slices.Equal(res.GrantedScopes, []string{"reports:read"}) || res.GrantedTTL != 120 {
+				t.Errorf("single match granted %v for %ds", res.GrantedScopes, res.GrantedTTL)
+			}
+			if !slices.Equal(res.MatchedRules, []string{"batch-to-reports"}) {
+				t.Errorf("single match MatchedRules = %v", res.MatchedRules)
+			}
+		})
+	}
+
+	t.Run("merge rejects differing token formats", func(t *testing.T) {
+		ps := conflictingPolicies()
+		ps[1].TokenFormat = FormatMacaroon
+		if _, err := NewLoaderWithConflictMode(ps, ConflictMergeUnion); err == nil {
+			t.Error("expected error merging rules with different token formats")
+		}
+	})
+
+	t.Run("merge rejects differing issuers or audiences", func(t *testing.T) {
+		ps := conflictingPolicies()
+		ps[1].Issuer = "https://issuer.example.com"
+		if _, err := NewLoaderWithConflictMode(ps, ConflictMergeIntersection); err == nil {
+			t.Error("expected error merging rules with different issuers")
+		}
+		ps = conflictingPolicies()
+		ps[1].Audiences = map[string][]string{reports: {"reports.example.com"}}
+		if _, err := NewLoaderWithConflictMode(ps, ConflictMergeUnion); err == nil {
+			t.Error("expected error merging rules with different audiences")
+		}
+	})
+
+	t.Run("merged rules require a nonce if any rule does", func(t *testing.T) {
+		for _, mode := range []ConflictMode{ConflictMergeUnion, ConflictMergeIntersection} {
+			ps := conflictingPolicies()
+			ps[1].RequireNonce = true
+			l, err := NewLoaderWithConflictMode(ps, mode)
+			if err != nil {
+				t.Fatalf("NewLoaderWithConflictMode(%s): %v", mode, err)
+			}
+			if res := l.Evaluate(nightly, reports, all, 0); !res.RequireNonce {
+				t.Errorf("%s: RequireNonce = false, want true", mode)
+			}
+		}
+	})
+
+	t.Run("merged rules require approval if any rule does", func(t *testing.T) {
+		for _, mode := range []ConflictMode{ConflictMergeUnion, ConflictMergeIntersection} {
+			ps := conflictingPolicies()
+			ps[1].RequiresApproval = true
+			l, err := NewLoaderWithConflictMode(ps, mode)
+			if err != nil {
+				t.Fatalf("NewLoaderWithConflictMode(%s): %v", mode, err)
+			}
+			if res := l.Evaluate(nightly, reports, all, 0); !res.RequiresApproval {
+				t.Errorf("%s: RequiresApproval = false, want true", mode)
+			}
+		}
+	})
+
+	t.Run("merged scopes do not leak into source policies", func(t *testing.T) {
+		ps := conflictingPolicies()
+		l, err := NewLoaderWithConflictMode(ps, ConflictMergeUnion)
+		if err != nil {
+			t.Fatalf("NewLoaderWithConflictMode: %v", err)
+		}
+		l.Evaluate(nightly, reports, all, 0)
+		if got := l.Policies()[0].AllowedScopes; !slices.Equal(got, []string{"reports:read"}) {
+			t.Errorf("policy 0 scopes mutated to %v", got)
+		}
+	})
+}
+
+func TestEvaluateUnion(t *testing.T) {
+	const (
+		caller = "spiffe://cluster.local/ns/batch/sa/nightly"
+		target = "spiffe://cluster.local/ns/default/sa/reports"
+	)
+	l, err := NewLoaderWithConflictMode([]Policy{
+		{Name: "read", Subject: "spiffe://cluster.local/ns/batch/sa/*", Target: target, AllowedScopes: []string{"reports:read"}, MaxTTL: 600},
+		{Name: "write", Subject: caller, Target: target, AllowedScopes: []string{"reports:write"}, MaxTTL: 60},
+		{Name: "export", Subject: "spiffe://cluster.local/ns/*/sa/nightly", Target: target, AllowedScopes: []string{"reports:export"}, MaxTTL: 120},
+	}, ConflictMergeUnion)
+	if err != nil {
+		t.Fatalf("NewLoaderWithConflictMode: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		scopes      []string
+		ttl         int32
+		wantAllowed bool
+		wantScopes  []string
+		wantTTL     int32
+		wantRules   []string
+	}{
+		{
+			name:        "scopes from every contributing rule in request order",
+			scopes:      []string{"reports:export", "reports:write", "reports:read"},
+			wantAllowed: true,
+			wantScopes:  []string{"reports:export", "reports:write", "reports:read"},
+			wantTTL:     600,
+			wantRules:   []string{"write", "read", "export"},
+		},
+		{
+			name:        "non-contributing rule does not raise the TTL cap",
+			scopes:      []string{"reports:write"},
+			wantAllowed: true,
+			wantScopes:  []string{"reports:write"},
+			wantTTL:     60,
+			wantRules:   []string{"write"},
+		},
+		{
+			name:        "TTL is the largest among contributing rules",
+			scopes:      []string{"reports:write", "reports:export"},
+			wantAllowed: true,
+			wantScopes:  []string{"reports:write", "reports:export"},
+			wantTTL:     120,
+			wantRules:   []string{"write", "export"},
+		},
+		{
+			name:        "requested TTL below every cap is kept",
+			scopes:      []string{"reports:read", "reports:write"},
+			ttl:         30,
+			wantAllowed: true,
+			wantScopes:  []string{"reports:read", "reports:write"},
+			wantTTL:     30,
+			wantRules:   []string{"write", "read"},
+		},
+		{
+			name:   "no rule grants a requested scope",
+			scopes: []string{"reports:delete"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := l.Evaluate(caller, target, tc.scopes, tc.ttl)
+			if res.Allowed != tc.wantAllowed {
+				t.Fatalf("Allowed = %v, want %v", res.Allowed, tc.wantAllowed)
+			}
+			if !slices.Equal(res.GrantedScopes, tc.wantScopes) || res.GrantedTTL != tc.wantTTL {
+				t.Errorf("granted %v for %ds, want %v for %ds", res.GrantedScopes, res.GrantedTTL, tc.wantScopes, tc.wantTTL)
+			}
+			if !slices.Equal(res.MatchedRules, tc.wantRules) {
+				t.Errorf("MatchedRules = %v, want %v", res.MatchedRules, tc.wantRules)
+			}
+		})
+	}
+}
+
+// FuzzIDsOverlap checks conflict detection against matching: whenever one
+// SPIFFE ID matches two valid subjects or targets, idsOverlap must report
+// them as overlapping, in either order. A miss would let a conflicting rule
+// pair load unreported.
+func FuzzIDsOverlap(f *testing.F) {
+	const td = "spiffe://cluster.local"
+	f.Add(td+"/ns/a/sa/web-*", td+"/ns/a/sa/*-canary", td+"/ns/a/sa/web-canary")
+	f.Add(td+"/ns/a/sa/job-[0-5]", td+"/ns/a/sa/job-[5-9]", td+"/ns/a/sa/job-5")
+	f.Add(td+"/ns/*/sa/x", td+"/ns/a/sa/?", td+"/ns/a/sa/x")
+	f.Add(td+"/ns/a/sa/\\*", td+"/ns/a/sa/[\\*]", td+"/ns/a/sa/*")
+	f.Add(td+"/ns/a/sa/[^a-z]", td+"/ns/a/sa/[", td+"/ns/a/sa/[")
+	f.Fuzz(func(t *testing.T, a, b, id string) {
+		if validateIDOrPattern(a) != nil || validateIDOrPattern(b) != nil {
+			return
+		}
+		ab, ba := idsOverlap(a, b), idsOverlap(b, a)
+		if ab != ba {
+			t.Fatalf("idsOverlap(%q, %q) = %v but reversed = %v", a, b, ab, ba)
+		}
+		if matchID(a, id) && matchID(b, id) && !ab {
+			t.Fatalf("%q matches %q and %q, which idsOverlap reports as disjoint", id, a, b)
+		}
+	})
+}
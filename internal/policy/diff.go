@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Kinds of GrantChange.
+const (
+	GrantAdded   = "added"
+	GrantRemoved = "removed"
+	GrantChanged = "changed"
+)
+
+// GrantChange describes how a new policy set changes what one subject may
+// obtain for one target. Subject and target are as written in the rules, so
+// either may be a pattern.
+type GrantChange struct {
+	Kind    string // GrantAdded, GrantRemoved, or GrantChanged
+	Subject string
+	Target  string
+	// OldName and NewName name the rule in the old and new sets; one is
+	// empty for an added or removed grant.
+	OldName, NewName string
+	// Scopes lists the allowed scopes of an added or removed grant.
+	Scopes []string
+	// AddedScopes and RemovedScopes list the allowed scopes a changed grant
+	// gains and loses.
+	AddedScopes, RemovedScopes []string
+	// Details lists the changes to a changed grant's other fields, e.g.
+	// "max_ttl 300 → 60".
+	Details []string
+}
+
+// DiffPolicies compares two policy sets rule by rule, pairing the rules of
+// the same (subject, target) pair, and returns the differences ordered by
+// subject and target. A rule that is only renamed is reported as changed.
+// Each set must hold at most one rule per pair, as NewLoader ensures.
+func DiffPolicies(oldSet, newSet []Policy) []GrantChange {
+	olds := make(map[pair]Policy, len(oldSet))
+	for _, p := range oldSet {
+		olds[pair{p.Subject, p.Target}] = p
+	}
+	var out []GrantChange
+	for _, n := range newSet {
+		key := pair{n.Subject, n.Target}
+		o, ok := olds[key]
+		if !ok {
+			out = append(out, GrantChange{Kind: GrantAdded, Subject: n.Subject, Target: n.Target, NewName: n.Name, Scopes: n.AllowedScopes})
+			continue
+		}
+		delete(olds, key)
+		c := GrantChange{
+			Kind:          GrantChanged,
+			Subject:       n.Subject,
+			Target:        n.Target,
+			OldName:       o.Name,
+			NewName:       n.Name,
+			AddedScopes:   scopesMissing(n.AllowedScopes, o.AllowedScopes),
+			RemovedScopes: scopesMissing(o.AllowedScopes, n.AllowedScopes),
+			Details:       fieldChanges(o, n),
+		}
+		if o.Name != n.Name || len(c.AddedScopes) > 0 || len(c.RemovedScopes) > 0 || len(c.Details) > 0 {
+			out = append(out, c)
+		}
+	}
+	for _, o := range olds {
+		out = append(out, GrantChange{Kind: GrantRemoved, Subject: o.Subject, Target: o.Target, OldName: o.Name, Scopes: o.AllowedScopes})
+	}
+	slices.SortFunc(out, func(a, b GrantChange) int {
+		return cmp.Or(cmp.Compare(a.Subject, b.Subject), cmp.Compare(a.Target, b.Target))
+	})
+	return out
+}
+
+// scopesMissing returns the scopes of a that b does not list, in a's order.
+func scopesMissing(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// fieldChanges lists the fields other than name and allowed_scopes that
+// differ from o to n.
+func fieldChanges(o, n Policy) []string {
+	var out []string
+	if o.MaxTTL != n.MaxTTL {
+		out = append(out, fmt.Sprintf("max_ttl %d → %d", o.MaxTTL, n.MaxTTL))
+	}
+	if fo, fn := formatOf(o), formatOf(n); fo != fn {
+		out = append(out, fmt.Sprintf("token_format %s → %s", fo, fn))
+	}
+	if o.RequireNonce != n.RequireNonce {
+		out = append(out, fmt.Sprintf("require_nonce %t → %t", o.RequireNonce, n.RequireNonce))
+	}
+	if o.RequiresApproval != n.RequiresApproval {
+		out = append(out, fmt.Sprintf("requires_approval %t → %t", o.RequiresApproval, n.RequiresApproval))
+	}
+	if o.SingleUse != n.SingleUse {
+		out = append(out, fmt.Sprintf("single_use %t → %t", o.SingleUse, n.SingleUse))
+	}
+	if o.Issuer != n.Issuer {
+		out = append(out, fmt.Sprintf("issuer %q → %q", o.Issuer, n.Issuer))
+	}
+	if !sameAudiences(o.Audiences, n.Audiences) {
+		out = append(out, fmt.Sprintf("audiences %v → %v", o.Audiences, n.Audiences))
+	}
+	if o.Condition != n.Condition {
+		out = append(out, fmt.Sprintf("condition %q → %q", o.Condition, n.Condition))
+	}
+	return out
+}
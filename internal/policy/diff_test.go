@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+		ledger  = "spiffe://cluster.local/ns/default/sa/ledger"
+		batch   = "spiffe://cluster.local/ns/batch/sa/*"
+	)
+	oldSet := []Policy{
+		{Name: "order-payment", Subject: order, Target: payment, AllowedScopes: []string{"payments:charge", "payments:read"}, MaxTTL: 300},
+		{Name: "order-ledger", Subject: order, Target: ledger, AllowedScopes: []string{"ledger:read"}, MaxTTL: 60},
+		{Name: "batch-ledger", Subject: batch, Target: ledger, AllowedScopes: []string{"ledger:read"}, MaxTTL: 60},
+		{Name: "payment-ledger", Subject: payment, Target: ledger, AllowedScopes: []string{"ledger:write"}, MaxTTL: 60},
+	}
+	newSet := []Policy{
+		{Name: "order-payment", Subject: order, Target: payment, AllowedScopes: []string{"payments:read", "payments:refund"}, MaxTTL: 60, RequireNonce: true},
+		{Name: "order-ledger", Subject: order, Target: ledger, AllowedScopes: []string{"ledger:read"}, MaxTTL: 60},
+		{Name: "batch-ledger-ro", Subject: batch, Target: ledger, AllowedScopes: []string{"ledger:read"}, MaxTTL: 60},
+		{Name: "ledger-payment", Subject: ledger, Target: payment, AllowedScopes: []string{"payments:read"}, MaxTTL: 60},
+	}
+
+	got := DiffPolicies(oldSet, newSet)
+	want := []GrantChange{
+		{Kind: GrantChanged, Subject: batch, Target: ledger, OldName: "batch-ledger", NewName: "batch-ledger-ro"},
+		{Kind: GrantAdded, Subject: ledger, Target: payment, NewName: "ledger-payment", Scopes: []string{"payments:read"}},
+		{
+			Kind: GrantChanged, Subject: order, Target: payment, OldName: "order-payment", NewName: "order-payment",
+			AddedScopes: []string{"payments:refund"}, RemovedScopes: []string{"payments:charge"},
+			Details: []string{"max_ttl 300 → 60", "require_nonce false → true"},
+		},
+		{Kind: GrantRemoved, Subject: payment, Target: ledger, OldName: "payment-ledger", Scopes: []string{"ledger:write"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Kind != w.Kind || g.Subject != w.Subject || g.Target != w.Target || g.OldName != w.OldName || g.NewName != w.NewName ||
+			!slices.Equal(g.Scopes, w.Scopes) || !slices.Equal(g.AddedScopes, w.AddedScopes) ||
+			!slices.Equal(g.RemovedScopes, w.RemovedScopes) || !slices.Equal(g.Details, w.Details) {
+			t.Errorf("change %d = %+v, want %+v", i, g, w)
+		}
+	}
+
+	if d := DiffPolicies(oldSet, oldSet); len(d) != 0 {
+		t.Errorf("diff of a set with itself = %+v, want none", d)
+	}
+}
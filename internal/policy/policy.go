@@ -1,15 +1,25 @@
 // Package policy loads and evaluates YAML-based exchange policies.
 // Each policy grants a subject SPIFFE ID permission to obtain a token
-// targeting a specific service with a bounded scope set and TTL.
+// targeting a specific service with a bounded scope set and TTL. Subject and
+// target may also be glob patterns over the SPIFFE ID path, such as
+// "spiffe://cluster.local/ns/batch/sa/*".
 package policy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
 	"os"
+	"path"
 	"slices"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/google/cel-go/cel"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,20 +30,100 @@ type Policy struct {
 	Target        string   `yaml:"target"`
 	AllowedScopes []string `yaml:"allowed_scopes"`
 	MaxTTL        int32    `yaml:"max_ttl"`
+	// TokenFormat selects how granted tokens are encoded. Empty means
+	// FormatJWT.
+	TokenFormat string `yaml:"token_format"`
+	// RequireNonce refuses exchanges the policy grants unless the request
+	// carries a nonce, so that a captured request cannot be replayed.
+	RequireNonce bool `yaml:"require_nonce"`
+	// RequiresApproval parks the exchanges the policy grants until an
+	// approver grants or denies them, for scopes that need a human in the
+	// loop.
+	RequiresApproval bool `yaml:"requires_approval"`
+	// SingleUse marks the tokens the policy grants as one-time: the first
+	// introspection or ext_authz check consumes the token ID, and every
+	// later one reports the token inactive. Only JWT formats support it.
+	SingleUse bool `yaml:"single_use"`
+	// Audiences maps target SPIFFE IDs the policy's target matches to the aud
+	// values granted tokens carry in place of the target ID, for targets that
+	// validate aud as a DNS name or URL. A target without an entry gets its
+	// SPIFFE ID. Only JWT formats support it.
+	Audiences map[string][]string `yaml:"audiences"`
+	// Issuer replaces "svid-exchange" as the iss of granted tokens, for
+	// targets that expect a specific issuer. Only JWT formats support it.
+	Issuer string `yaml:"issuer"`
+	// Condition is a CEL expression over the request that must hold for the
+	// rule to grant anything, for constraints the fields above cannot
+	// express. Empty always holds. See conditionEnv for its variables.
+	Condition string `yaml:"condition"`
 }
 
+// Token formats accepted in Policy.TokenFormat.
+const (
+	// FormatJWT is the default svid-exchange JWT.
+	FormatJWT = "jwt"
+	// FormatJWTSVID is a JWT laid out per the SPIFFE JWT-SVID specification
+	// so that SPIFFE-native validators accept it.
+	FormatJWTSVID = "jwt-svid"
+	// FormatMacaroon is an HMAC macaroon that holders can attenuate offline
+	// before passing it on.
+	FormatMacaroon = "macaroon"
+	// FormatPASETO is a PASETO v4.public token.
+	FormatPASETO = "paseto"
+)
+
+// TokenFormats lists every accepted Policy.TokenFormat value.
+var TokenFormats = []string{FormatJWT, FormatJWTSVID, FormatMacaroon, FormatPASETO}
+
 // File is the top-level YAML structure.
 type File struct {
 	Policies []Policy `yaml:"policies"`
 }
 
-// Loader holds the loaded policy set.
+// Loader holds the loaded policy set. Policies whose subject and target are
+// both literal SPIFFE IDs are indexed by (subject, target), so Evaluate costs
+// the same with five of them or five thousand. Policies using a pattern on
+// either side form a second tier that is only scanned, in order, when no
+// literal policy matches.
 type Loader struct {
-	policies []Policy
+	policies  []Policy
+	index     map[pair]int // (subject, target) → index into policies
+	patterns  []int        // indexes into policies of pattern rules, in order
+	mode      ConflictMode
+	conflicts []Conflict
+	built     time.Duration
+	digest    string
+	// conditions holds the compiled Condition of each policy, by index; nil
+	// for a policy without one.
+	conditions []cel.Program
+	now        func() time.Time
+}
+
+// Stats describes a Loader's policy set and index.
+type Stats struct {
+	// Rules is the total number of policies.
+	Rules int
+	// ExactRules are policies served from the (subject, target) index.
+	ExactRules int
+	// PatternRules are policies with a pattern subject or target, matched by
+	// scanning after an index miss.
+	PatternRules int
+	// Conflicts is the number of conflicting rule pairs; see FindConflicts.
+	Conflicts int
+	// IndexBuildTime is how long NewLoader took to validate and index the
+	// set and detect conflicts.
+	IndexBuildTime time.Duration
 }
 
-// LoadFile reads and parses the policy YAML at path.
+// LoadFile reads and parses the policy YAML at path. Conflicting rules are
+// handled with ConflictWarn.
 func LoadFile(path string) (*Loader, error) {
+	return LoadFileWithConflictMode(path, ConflictWarn)
+}
+
+// LoadFileWithConflictMode reads and parses the policy YAML at path,
+// handling conflicting rules according to mode.
+func LoadFileWithConflictMode(path string, mode ConflictMode) (*Loader, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read policy file: %w", err)
@@ -45,47 +135,218 @@ func LoadFile(path string) (*Loader, error) {
 	if len(f.Policies) == 0 {
 		return nil, errors.New("policy file contains no policies")
 	}
-	return NewLoader(f.Policies)
+	return NewLoaderWithConflictMode(f.Policies, mode)
 }
 
 // NewLoader validates policies and returns a Loader backed by them.
 // Unlike LoadFile it accepts an empty slice (all requests will be denied).
+// Conflicting rules are handled with ConflictWarn.
 func NewLoader(policies []Policy) (*Loader, error) {
-	seen := make(map[string]int) // "subject\x00target" → first index
+	return NewLoaderWithConflictMode(policies, ConflictWarn)
+}
+
+// NewLoaderWithConflictMode is NewLoader with conflicting rules handled
+// according to mode. ConflictError fails on any conflict; the merge modes
+// fail on conflicts over token_format, issuer, or audiences, which cannot be
+// merged.
+func NewLoaderWithConflictMode(policies []Policy, mode ConflictMode) (*Loader, error) {
+	start := time.Now()
+	// Rules are matched in canonical form, so that a subject or target
+	// spelled with an upper-case trust domain still matches, and two
+	// spellings of one rule are caught as duplicates.
+	policies = slices.Clone(policies)
+	for i := range policies {
+		policies[i].Subject = normalizeIDOrPattern(policies[i].Subject)
+		policies[i].Target = normalizeIDOrPattern(policies[i].Target)
+		policies[i].Audiences = normalizeAudienceKeys(policies[i].Audiences)
+	}
+	l := &Loader{
+		policies:   policies,
+		index:      make(map[pair]int, len(policies)),
+		mode:       mode,
+		conditions: make([]cel.Program, len(policies)),
+		now:        time.Now,
+	}
+	seen := make(map[pair]int, len(policies)) // (subject, target) → first index
 	for i, p := range policies {
-		if err := ValidateOne(p); err != nil {
+		if err := validateFields(p); err != nil {
 			return nil, fmt.Errorf("policy %d (%q): %w", i, p.Name, err)
 		}
-		key := p.Subject + "\x00" + p.Target
+		if p.Condition != "" {
+			prg, err := compileCondition(p.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("policy %d (%q): invalid condition: %w", i, p.Name, err)
+			}
+			l.conditions[i] = prg
+		}
+		key := pair{p.Subject, p.Target}
 		if first, dup := seen[key]; dup {
 			return nil, fmt.Errorf("policy %d (%q): duplicate (subject, target) pair already defined by policy %d", i, p.Name, first)
 		}
 		seen[key] = i
+		if IsPattern(p.Subject) || IsPattern(p.Target) {
+			l.patterns = append(l.patterns, i)
+		} else {
+			l.index[key] = i
+		}
+	}
+	if len(l.patterns) > 0 {
+		l.conflicts = FindConflicts(policies)
 	}
-	return &Loader{policies: policies}, nil
+	for _, c := range l.conflicts {
+		if mode == ConflictError {
+			return nil, fmt.Errorf("conflicting rules: %s", c)
+		}
+		if mode.merges() && !sameTokenShape(policies[c.First], policies[c.Second]) {
+			return nil, fmt.Errorf("conflicting rules cannot be merged: %s", c)
+		}
+	}
+	data, err := json.Marshal(policies)
+	if err != nil {
+		return nil, fmt.Errorf("digest policies: %w", err)
+	}
+	sum := sha256.Sum256(append(data, mode...))
+	l.digest = hex.EncodeToString(sum[:])
+	l.built = time.Since(start)
+	return l, nil
+}
+
+// pair is the Loader index key. A struct rather than a concatenated string
+// keeps lookups allocation-free.
+type pair struct {
+	subject, target string
+}
+
+// IsPattern reports whether a policy subject or target is a glob pattern
+// rather than a literal SPIFFE ID.
+func IsPattern(id string) bool {
+	return strings.ContainsAny(id, `*?[\`)
+}
+
+// matchID reports whether the SPIFFE ID id matches the policy subject or
+// target want, which is either a literal ID or a pattern. Patterns use
+// path.Match syntax, so "*" never crosses a "/".
+func matchID(want, id string) bool {
+	if !IsPattern(want) {
+		return want == id
+	}
+	ok, _ := path.Match(want, id) // syntax was checked by ValidateOne
+	return ok
 }
 
-// ValidateOne checks that a single policy has valid fields.
-// It does not check for duplicates across a set of policies.
+// ValidateOne checks that a single policy has valid fields and that its
+// condition compiles. It does not check for duplicates across a set of
+// policies.
 func ValidateOne(p Policy) error {
+	if err := validateFields(p); err != nil {
+		return err
+	}
+	if p.Condition != "" {
+		if _, err := compileCondition(p.Condition); err != nil {
+			return fieldError("condition", fmt.Errorf("invalid condition: %w", err))
+		}
+	}
+	return nil
+}
+
+// FieldError is a ValidateOne error about one policy field, named by its
+// policy file key, so that callers with another schema, such as the
+// ExchangePolicy admission webhook, can point at their own field.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// fieldError returns err as a FieldError for field.
+func fieldError(field string, err error) error {
+	return &FieldError{Field: field, Err: err}
+}
+
+// validateFields is ValidateOne without compiling the condition, for
+// NewLoader, which keeps the compiled program.
+func validateFields(p Policy) error {
 	if p.Name == "" {
-		return errors.New("name must not be empty")
+		return fieldError("name", errors.New("name must not be empty"))
 	}
-	if err := validateSPIFFEID(p.Subject); err != nil {
-		return fmt.Errorf("invalid subject: %w", err)
+	if err := validateIDOrPattern(p.Subject); err != nil {
+		return fieldError("subject", fmt.Errorf("invalid subject: %w", err))
 	}
-	if err := validateSPIFFEID(p.Target); err != nil {
-		return fmt.Errorf("invalid target: %w", err)
+	if err := validateIDOrPattern(p.Target); err != nil {
+		return fieldError("target", fmt.Errorf("invalid target: %w", err))
 	}
 	if len(p.AllowedScopes) == 0 {
-		return errors.New("allowed_scopes must not be empty")
+		return fieldError("allowed_scopes", errors.New("allowed_scopes must not be empty"))
+	}
+	for _, s := range p.AllowedScopes {
+		if err := validateScope(s); err != nil {
+			return fieldError("allowed_scopes", fmt.Errorf("invalid allowed_scopes: %w", err))
+		}
 	}
 	if p.MaxTTL <= 0 {
-		return errors.New("max_ttl must be greater than zero")
+		return fieldError("max_ttl", errors.New("max_ttl must be greater than zero"))
+	}
+	if p.TokenFormat != "" && !slices.Contains(TokenFormats, p.TokenFormat) {
+		return fieldError("token_format", fmt.Errorf("token_format must be one of %v, got %q", TokenFormats, p.TokenFormat))
+	}
+	jwtFormat := p.TokenFormat != FormatMacaroon && p.TokenFormat != FormatPASETO
+	if p.SingleUse && !jwtFormat {
+		return fieldError("single_use", fmt.Errorf("single_use is not supported with token_format %q", p.TokenFormat))
+	}
+	if (p.Issuer != "" || len(p.Audiences) > 0) && !jwtFormat {
+		field := "audiences"
+		if p.Issuer != "" {
+			field = "issuer"
+		}
+		return fieldError(field, fmt.Errorf("issuer and audiences are not supported with token_format %q", p.TokenFormat))
+	}
+	if p.Issuer != "" {
+		if strings.IndexFunc(p.Issuer, unicode.IsSpace) >= 0 {
+			return fieldError("issuer", fmt.Errorf("invalid issuer %q: must not contain spaces", p.Issuer))
+		}
+		// Consuming a single-use token needs svid-exchange's own verifiers,
+		// which accept only its issuer.
+		if p.SingleUse {
+			return fieldError("single_use", errors.New("single_use cannot be combined with issuer"))
+		}
+	}
+	for id, auds := range p.Audiences {
+		if err := validateSPIFFEID(id); err != nil {
+			return fieldError("audiences", fmt.Errorf("invalid audiences key %q: %w", id, err))
+		}
+		if !matchID(p.Target, normalizeIDOrPattern(id)) {
+			return fieldError("audiences", fmt.Errorf("audiences key %q is not matched by target %q", id, p.Target))
+		}
+		if len(auds) == 0 {
+			return fieldError("audiences", fmt.Errorf("audiences for %q must not be empty", id))
+		}
+		for _, aud := range auds {
+			if aud == "" || strings.IndexFunc(aud, unicode.IsSpace) >= 0 {
+				return fieldError("audiences", fmt.Errorf("invalid audience %q for %q: must be non-empty without spaces", aud, id))
+			}
+		}
 	}
 	return nil
 }
 
+// normalizeAudienceKeys returns audiences with its keys in canonical form,
+// as NewLoader puts subjects and targets, so that they match the normalised
+// target of a request. Keys that are not valid SPIFFE IDs are kept for
+// validation to reject.
+func normalizeAudienceKeys(audiences map[string][]string) map[string][]string {
+	if len(audiences) == 0 {
+		return audiences
+	}
+	out := make(map[string][]string, len(audiences))
+	for id, auds := range audiences {
+		out[normalizeIDOrPattern(id)] = auds
+	}
+	return out
+}
+
 // Policies returns a copy of the loaded policy slice.
 func (l *Loader) Policies() []Policy {
 	out := make([]Policy, len(l.policies))
@@ -93,59 +354,295 @@ func (l *Loader) Policies() []Policy {
 	return out
 }
 
-// validateSPIFFEID checks that id is a well-formed SPIFFE ID (spiffe://<trust-domain>/...).
-func validateSPIFFEID(id string) error {
-	u, err := url.Parse(id)
+// Stats reports the size of l's policy set and how it is indexed.
+func (l *Loader) Stats() Stats {
+	return Stats{
+		Rules:          len(l.policies),
+		ExactRules:     len(l.index),
+		PatternRules:   len(l.patterns),
+		Conflicts:      len(l.conflicts),
+		IndexBuildTime: l.built,
+	}
+}
+
+// Digest returns the hex SHA-256 of l's policies, in order, and conflict
+// mode. Two Loaders with the same digest make the same decisions.
+func (l *Loader) Digest() string {
+	return l.digest
+}
+
+// ConflictMode returns the mode l was built with.
+func (l *Loader) ConflictMode() ConflictMode {
+	return l.mode
+}
+
+// Conflicts returns the conflicting rule pairs found when l was built.
+func (l *Loader) Conflicts() []Conflict {
+	return slices.Clone(l.conflicts)
+}
+
+// validateIDOrPattern checks that id is a SPIFFE ID or a pattern over one.
+// A pattern may only use wildcards in the path: the trust domain must be
+// literal so that no rule can span trust domains.
+func validateIDOrPattern(id string) error {
+	if !IsPattern(id) {
+		return validateSPIFFEID(id)
+	}
+	if _, err := path.Match(id, ""); err != nil {
+		return fmt.Errorf("malformed pattern %q", id)
+	}
+	if len(id) > maxSPIFFEIDLength {
+		return fmt.Errorf("pattern is %d bytes, exceeding the maximum of %d", len(id), maxSPIFFEIDLength)
+	}
+	td, _, err := splitSPIFFEID(id)
 	if err != nil {
-		return fmt.Errorf("parse URI: %w", err)
+		return err
 	}
-	if u.Scheme != "spiffe" {
-		return fmt.Errorf("scheme must be \"spiffe\", got %q", u.Scheme)
+	if IsPattern(td) {
+		return fmt.Errorf("pattern trust domain %q must not contain wildcards", td)
 	}
-	if u.Host == "" {
-		return errors.New("missing trust domain")
+	if _, err := spiffeid.TrustDomainFromString(td); err != nil {
+		return fmt.Errorf("pattern trust domain %q: %w", td, err)
 	}
 	return nil
 }
 
+// validateSPIFFEID checks that id is a SPIFFE ID as NormalizeSPIFFEID
+// accepts it.
+func validateSPIFFEID(id string) error {
+	_, err := NormalizeSPIFFEID(id)
+	return err
+}
+
 // EvalResult is returned by Evaluate.
 type EvalResult struct {
-	Allowed       bool
+	Allowed bool
+	// GrantedScopes are the requested scopes the policy permits, in the
+	// order they were requested.
 	GrantedScopes []string
 	GrantedTTL    int32
+	// TokenFormat is the matching policy's token_format, normalised so that
+	// an unset value reads as FormatJWT.
+	TokenFormat string
+	// MatchedRules names the policies that authorized the grant: the single
+	// matching rule, or in the merge conflict modes every rule the result
+	// was combined from, literal rule first. Empty when Allowed is false.
+	MatchedRules []string
+	// PolicyDigest is the Digest of the Loader that granted the result.
+	// Empty when Allowed is false.
+	PolicyDigest string
+	// RequireNonce is set when a policy the result was combined from sets
+	// require_nonce.
+	RequireNonce bool
+	// RequiresApproval is set when a policy the result was combined from
+	// sets requires_approval.
+	RequiresApproval bool
+	// SingleUse is set when a policy the result was combined from sets
+	// single_use.
+	SingleUse bool
+	// Audience is the aud the token carries in place of the target ID, from
+	// the matching policy's audiences. Empty means the target ID.
+	Audience []string
+	// Issuer is the matching policy's issuer. Empty means "svid-exchange".
+	Issuer string
+	// Conditional is set when a policy condition was evaluated to reach the
+	// result, allowed or not. A condition may depend on now, so such a
+	// result holds only for the moment it was evaluated and must not be
+	// cached.
+	Conditional bool
 }
 
 // Evaluate checks whether subject may exchange for target with the given
 // scopes and TTL. It returns the permitted subset of the requested scopes,
-// capped to max_ttl.
+// capped to max_ttl. A literal (subject, target) policy takes precedence;
+// otherwise the first pattern policy matching both IDs applies. In the merge
+// conflict modes every matching policy applies instead, combined as the mode
+// describes: see evaluateUnion and intersectPolicies. A policy whose
+// condition does not hold grants nothing: it denies the request on its own,
+// and contributes nothing to a union or empties an intersection.
+//
+// The result depends only on the request, the policies in load order, and,
+// for conditions that read it, the time, so replicas loading the same set
+// return identical results, down to the order of GrantedScopes and
+// MatchedRules.
 func (l *Loader) Evaluate(subject, target string, scopes []string, ttlSeconds int32) EvalResult {
-	for _, p := range l.policies {
-		if p.Subject != subject || p.Target != target {
+	// An ID the spec rejects is denied outright rather than offered to the
+	// patterns, which would match "spiffe://td/*" against "spiffe://td/a:b".
+	subject, err := NormalizeSPIFFEID(subject)
+	if err != nil {
+		return EvalResult{Allowed: false}
+	}
+	target, err = NormalizeSPIFFEID(target)
+	if err != nil {
+		return EvalResult{Allowed: false}
+	}
+	c := &conditionCheck{l: l, req: request{subject: subject, target: target, scopes: scopes, ttl: ttlSeconds}}
+	r := l.evaluate(c)
+	r.Conditional = c.vars != nil
+	if r.Allowed {
+		r.PolicyDigest = l.digest
+	}
+	return r
+}
+
+func (l *Loader) evaluate(c *conditionCheck) EvalResult {
+	req := c.req
+	if l.mode.merges() {
+		return l.evaluateMerged(c)
+	}
+	if i, ok := l.index[pair{req.subject, req.target}]; ok {
+		if !c.holds(i) {
+			return EvalResult{Allowed: false}
+		}
+		return evaluateOne(l.policies[i], req)
+	}
+	for _, i := range l.patterns {
+		p := l.policies[i]
+		if matchID(p.Subject, req.subject) && matchID(p.Target, req.target) {
+			if !c.holds(i) {
+				return EvalResult{Allowed: false}
+			}
+			return evaluateOne(p, req)
+		}
+	}
+	return EvalResult{Allowed: false}
+}
+
+// evaluateMerged is Evaluate for the merge conflict modes.
+func (l *Loader) evaluateMerged(c *conditionCheck) EvalResult {
+	req := c.req
+	var matches []Policy
+	held := true
+	match := func(i int) {
+		switch {
+		case c.holds(i):
+			matches = append(matches, l.policies[i])
+		case l.mode == ConflictMergeIntersection:
+			held = false
+		}
+	}
+	if i, ok := l.index[pair{req.subject, req.target}]; ok {
+		match(i)
+	}
+	for _, i := range l.patterns {
+		p := l.policies[i]
+		if matchID(p.Subject, req.subject) && matchID(p.Target, req.target) {
+			match(i)
+		}
+	}
+	switch {
+	case !held || len(matches) == 0:
+		return EvalResult{Allowed: false}
+	case len(matches) == 1:
+		return evaluateOne(matches[0], req)
+	case l.mode == ConflictMergeUnion:
+		return evaluateUnion(matches, req)
+	default:
+		res := evaluateOne(intersectPolicies(matches), req)
+		if res.Allowed {
+			res.MatchedRules = make([]string, len(matches))
+			for i, p := range matches {
+				res.MatchedRules[i] = p.Name
+			}
+		}
+		return res
+	}
+}
+
+// conditionCheck evaluates policy conditions for one request, building the
+// condition variables only once a matching policy has a condition.
+type conditionCheck struct {
+	l    *Loader
+	req  request
+	vars map[string]any
+}
+
+// holds reports whether the condition of policy i, if it has one, holds for
+// the request.
+func (c *conditionCheck) holds(i int) bool {
+	prg := c.l.conditions[i]
+	if prg == nil {
+		return true
+	}
+	if c.vars == nil {
+		c.req.now = c.l.now()
+		c.vars = c.req.activation()
+	}
+	return holds(prg, c.vars)
+}
+
+// evaluateUnion evaluates each matching policy on its own and combines the
+// ones that allow the request: the granted scopes are every scope any of
+// them grants, in request order, and the TTL is the largest they grant. A
+// nonce or an approval is required if any of them requires one. A rule that
+// grants none of the requested scopes does not contribute its max_ttl,
+// require_nonce, or requires_approval, and is not listed in MatchedRules.
+func evaluateUnion(matches []Policy, req request) EvalResult {
+	scopes := req.scopes
+	var res EvalResult
+	var granted map[string]struct{}
+	for _, p := range matches {
+		r := evaluateOne(p, req)
+		if !r.Allowed {
 			continue
 		}
-		granted := allowedSubset(scopes, p.AllowedScopes)
-		if len(granted) == 0 {
-			return EvalResult{Allowed: false}
+		if !res.Allowed {
+			res = r
+			granted = make(map[string]struct{}, len(scopes))
+		} else {
+			res.MatchedRules = append(res.MatchedRules, p.Name)
+			res.RequireNonce = res.RequireNonce || r.RequireNonce
+			res.RequiresApproval = res.RequiresApproval || r.RequiresApproval
+			res.SingleUse = res.SingleUse || r.SingleUse
 		}
-		grantedTTL := ttlSeconds
-		if grantedTTL <= 0 || grantedTTL > p.MaxTTL {
-			grantedTTL = p.MaxTTL
+		for _, s := range r.GrantedScopes {
+			granted[s] = struct{}{}
 		}
-		return EvalResult{
-			Allowed:       true,
-			GrantedScopes: granted,
-			GrantedTTL:    grantedTTL,
+		res.GrantedTTL = max(res.GrantedTTL, r.GrantedTTL)
+	}
+	if !res.Allowed {
+		return res
+	}
+	res.GrantedScopes = res.GrantedScopes[:0]
+	for _, s := range scopes {
+		if _, ok := granted[s]; ok {
+			res.GrantedScopes = append(res.GrantedScopes, s)
 		}
 	}
-	return EvalResult{Allowed: false}
+	return res
+}
+
+// evaluateOne applies the matching policy p to req.
+func evaluateOne(p Policy, req request) EvalResult {
+	granted := allowedSubset(req.scopes, p.AllowedScopes)
+	if len(granted) == 0 {
+		return EvalResult{Allowed: false}
+	}
+	grantedTTL := req.ttl
+	if grantedTTL <= 0 || grantedTTL > p.MaxTTL {
+		grantedTTL = p.MaxTTL
+	}
+	return EvalResult{
+		Allowed:          true,
+		GrantedScopes:    granted,
+		GrantedTTL:       grantedTTL,
+		TokenFormat:      formatOf(p),
+		MatchedRules:     []string{p.Name},
+		RequireNonce:     p.RequireNonce,
+		RequiresApproval: p.RequiresApproval,
+		SingleUse:        p.SingleUse,
+		Audience:         p.Audiences[req.target],
+		Issuer:           p.Issuer,
+	}
 }
 
 // allowedSubset returns the scopes from requested that the policy permits,
-// preserving the order of requested.
+// preserving the order of requested. A scope permitted by a template is
+// returned as requested, with its parameters filled in.
 func allowedSubset(requested, allowed []string) []string {
-	var out []string
+	out := make([]string, 0, len(requested))
 	for _, scope := range requested {
-		if slices.Contains(allowed, scope) {
+		if scopeAllowed(scope, allowed) {
 			out = append(out, scope)
 		}
 	}
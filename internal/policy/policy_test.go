@@ -1,7 +1,12 @@
 package policy
 
 import (
+	"fmt"
+	"net/url"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -21,6 +26,8 @@ policies:
     allowed_scopes:
       - inventory:read
     max_ttl: 60
+    token_format: jwt-svid
+    require_nonce: true
 `
 
 func newTestLoader(t *testing.T) *Loader {
@@ -54,6 +61,8 @@ func TestEvaluate(t *testing.T) {
 		wantAllowed bool
 		wantScopes  []string
 		wantTTL     int32
+		wantFormat  string // checked when non-empty
+		wantNonce   bool
 	}{
 		{
 			name:        "allow exact scopes",
@@ -64,6 +73,7 @@ func TestEvaluate(t *testing.T) {
 			wantAllowed: true,
 			wantScopes:  []string{"payments:charge", "payments:refund"},
 			wantTTL:     300,
+			wantFormat:  FormatJWT,
 		},
 		{
 			name:        "allow subset of scopes",
@@ -138,6 +148,8 @@ func TestEvaluate(t *testing.T) {
 			wantAllowed: true,
 			wantScopes:  []string{"inventory:read"},
 			wantTTL:     60,
+			wantFormat:  FormatJWTSVID,
+			wantNonce:   true,
 		},
 	}
 
@@ -162,8 +174,248 @@ func TestEvaluate(t *testing.T) {
 			if result.GrantedTTL != tc.wantTTL {
 				t.Errorf("GrantedTTL = %d, want %d", result.GrantedTTL, tc.wantTTL)
 			}
+			if tc.wantFormat != "" && result.TokenFormat != tc.wantFormat {
+				t.Errorf("TokenFormat = %q, want %q", result.TokenFormat, tc.wantFormat)
+			}
+			if result.RequireNonce != tc.wantNonce {
+				t.Errorf("RequireNonce = %v, want %v", result.RequireNonce, tc.wantNonce)
+			}
+			if len(result.MatchedRules) != 1 {
+				t.Errorf("MatchedRules = %v, want one rule", result.MatchedRules)
+			}
+		})
+	}
+}
+
+func TestEvaluatePatterns(t *testing.T) {
+	l, err := NewLoader([]Policy{
+		{
+			Name:          "batch-to-reports",
+			Subject:       "spiffe://cluster.local/ns/batch/sa/*",
+			Target:        "spiffe://cluster.local/ns/default/sa/reports",
+			AllowedScopes: []string{"reports:read"},
+			MaxTTL:        120,
+		},
+		{
+			Name:          "nightly-to-reports",
+			Subject:       "spiffe://cluster.local/ns/batch/sa/nightly",
+			Target:        "spiffe://cluster.local/ns/default/sa/reports",
+			AllowedScopes: []string{"reports:read", "reports:write"},
+			MaxTTL:        60,
+		},
+		{
+			Name:          "order-to-any-payment",
+			Subject:       "spiffe://cluster.local/ns/default/sa/order",
+			Target:        "spiffe://cluster.local/ns/payments-?/sa/api",
+			AllowedScopes: []string{"payments:charge"},
+			MaxTTL:        30,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		subject     string
+		target      string
+		scopes      []string
+		wantAllowed bool
+		wantTTL     int32
+	}{
+		{
+			name:        "subject pattern matches",
+			subject:     "spiffe://cluster.local/ns/batch/sa/hourly",
+			target:      "spiffe://cluster.local/ns/default/sa/reports",
+			scopes:      []string{"reports:read"},
+			wantAllowed: true,
+			wantTTL:     120,
+		},
+		{
+			name:        "literal rule takes precedence over pattern",
+			subject:     "spiffe://cluster.local/ns/batch/sa/nightly",
+			target:      "spiffe://cluster.local/ns/default/sa/reports",
+			scopes:      []string{"reports:write"},
+			wantAllowed: true,
+			wantTTL:     60,
+		},
+		{
+			name:    "star does not cross path segments",
+			subject: "spiffe://cluster.local/ns/batch/sa/hourly/extra",
+			target:  "spiffe://cluster.local/ns/default/sa/reports",
+			scopes:  []string{"reports:read"},
+		},
+		{
+			name:    "pattern does not match another trust domain",
+			subject: "spiffe://other.example/ns/batch/sa/hourly",
+			target:  "spiffe://cluster.local/ns/default/sa/reports",
+			scopes:  []string{"reports:read"},
+		},
+		{
+			name:    "question mark matches exactly one character",
+			subject: "spiffe://cluster.local/ns/default/sa/order",
+			target:  "spiffe://cluster.local/ns/payments-eu/sa/api",
+			scopes:  []string{"payments:charge"},
+		},
+		{
+			name:        "target pattern matches",
+			subject:     "spiffe://cluster.local/ns/default/sa/order",
+			target:      "spiffe://cluster.local/ns/payments-1/sa/api",
+			scopes:      []string{"payments:charge"},
+			wantAllowed: true,
+			wantTTL:     30,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := l.Evaluate(tc.subject, tc.target, tc.scopes, 0)
+			if result.Allowed != tc.wantAllowed {
+				t.Fatalf("Allowed = %v, want %v", result.Allowed, tc.wantAllowed)
+			}
+			if result.GrantedTTL != tc.wantTTL {
+				t.Errorf("GrantedTTL = %d, want %d", result.GrantedTTL, tc.wantTTL)
+			}
+		})
+	}
+}
+
+func TestEvaluateAudienceAndIssuer(t *testing.T) {
+	l, err := NewLoader([]Policy{{
+		Name:          "order-to-any-payment",
+		Subject:       "spiffe://cluster.local/ns/default/sa/order",
+		Target:        "spiffe://cluster.local/ns/payments-?/sa/api",
+		AllowedScopes: []string{"payments:charge"},
+		MaxTTL:        30,
+		Audiences: map[string][]string{
+			"spiffe://Cluster.Local/ns/payments-1/sa/api": {"https://payments-1.example.com"},
+		},
+		Issuer: "https://issuer.example.com",
+	}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	res := l.Evaluate("spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/payments-1/sa/api", []string{"payments:charge"}, 0)
+	if !slices.Equal(res.Audience, []string{"https://payments-1.example.com"}) || res.Issuer != "https://issuer.example.com" {
+		t.Errorf("Audience = %v, Issuer = %q", res.Audience, res.Issuer)
+	}
+	// A target without an entry keeps its SPIFFE ID as the audience.
+	res = l.Evaluate("spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/payments-2/sa/api", []string{"payments:charge"}, 0)
+	if !res.Allowed || res.Audience != nil {
+		t.Errorf("Allowed = %v, Audience = %v; want allowed without an audience", res.Allowed, res.Audience)
+	}
+}
+
+// TestEvaluateDeterministic checks that results do not depend on map
+// iteration or anything else that varies between replicas: granted scopes
+// keep request order, matched rules keep load order, and loaders rebuilt
+// from the same policies return identical results.
+func TestEvaluateDeterministic(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/batch/sa/nightly"
+		target  = "spiffe://cluster.local/ns/default/sa/reports"
+	)
+	policies := []Policy{
+		{Name: "batch-any", Subject: "spiffe://cluster.local/ns/batch/sa/*", Target: target, AllowedScopes: []string{"d", "c", "b"}, MaxTTL: 120},
+		{Name: "any-nightly", Subject: "spiffe://cluster.local/ns/*/sa/nightly", Target: target, AllowedScopes: []string{"a", "b", "c"}, MaxTTL: 60},
+		{Name: "nightly", Subject: subject, Target: target, AllowedScopes: []string{"e", "c", "b", "a"}, MaxTTL: 90},
+	}
+	scopes := []string{"e", "c", "a", "d", "b"}
+	tests := []struct {
+		mode       ConflictMode
+		wantScopes []string
+		wantRules  []string
+	}{
+		{ConflictWarn, []string{"e", "c", "a", "b"}, []string{"nightly"}},
+		{ConflictMergeUnion, []string{"e", "c", "a", "d", "b"}, []string{"nightly", "batch-any", "any-nightly"}},
+		{ConflictMergeIntersection, []string{"c", "b"}, []string{"nightly", "batch-any", "any-nightly"}},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			var first EvalResult
+			for i := range 50 {
+				l, err := NewLoaderWithConflictMode(slices.Clone(policies), tc.mode)
+				if err != nil {
+					t.Fatalf("NewLoaderWithConflictMode: %v", err)
+				}
+				res := l.Evaluate(subject, target, scopes, 0)
+				if i == 0 {
+					first = res
+					if !slices.Equal(res.GrantedScopes, tc.wantScopes) || !slices.Equal(res.MatchedRules, tc.wantRules) {
+						t.Fatalf("scopes %v from rules %v, want %v from %v", res.GrantedScopes, res.MatchedRules, tc.wantScopes, tc.wantRules)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(res, first) {
+					t.Fatalf("rebuild %d returned %+v, first returned %+v", i, res, first)
+				}
+			}
 		})
 	}
+
+	t.Run("first matching pattern follows load order", func(t *testing.T) {
+		for _, order := range [][]int{{0, 1}, {1, 0}} {
+			ps := []Policy{policies[order[0]], policies[order[1]]}
+			l, err := NewLoader(ps)
+			if err != nil {
+				t.Fatalf("NewLoader: %v", err)
+			}
+			res := l.Evaluate(subject, target, scopes, 0)
+			if want := ps[0].Name; !slices.Equal(res.MatchedRules, []string{want}) {
+				t.Errorf("order %v matched %v, want [%s]", order, res.MatchedRules, want)
+			}
+		}
+	})
+}
+
+func TestLoaderStats(t *testing.T) {
+	l, err := NewLoader([]Policy{
+		{Name: "a", Subject: "spiffe://td/a", Target: "spiffe://td/t", AllowedScopes: []string{"s"}, MaxTTL: 60},
+		{Name: "b", Subject: "spiffe://td/b", Target: "spiffe://td/t", AllowedScopes: []string{"s"}, MaxTTL: 60},
+		{Name: "c", Subject: "spiffe://td/*", Target: "spiffe://td/t", AllowedScopes: []string{"s"}, MaxTTL: 60},
+	})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	st := l.Stats()
+	if st.Rules != 3 || st.ExactRules != 2 || st.PatternRules != 1 {
+		t.Errorf("Stats = %+v, want 3 rules, 2 exact, 1 pattern", st)
+	}
+	if st.IndexBuildTime <= 0 {
+		t.Errorf("IndexBuildTime = %v, want > 0", st.IndexBuildTime)
+	}
+}
+
+func TestLoaderDigest(t *testing.T) {
+	rules := []Policy{
+		{Name: "a", Subject: "spiffe://td/a", Target: "spiffe://td/t", AllowedScopes: []string{"s"}, MaxTTL: 60},
+	}
+	build := func(ps []Policy, mode ConflictMode) *Loader {
+		t.Helper()
+		l, err := NewLoaderWithConflictMode(ps, mode)
+		if err != nil {
+			t.Fatalf("NewLoader: %v", err)
+		}
+		return l
+	}
+	l := build(rules, ConflictWarn)
+	if l.Digest() != build(slices.Clone(rules), ConflictWarn).Digest() {
+		t.Error("identical policy sets have different digests")
+	}
+	changed := slices.Clone(rules)
+	changed[0].MaxTTL = 120
+	if l.Digest() == build(changed, ConflictWarn).Digest() {
+		t.Error("changing a rule kept the digest")
+	}
+	if l.Digest() == build(rules, ConflictMergeUnion).Digest() {
+		t.Error("changing the conflict mode kept the digest")
+	}
+
+	if r := l.Evaluate("spiffe://td/a", "spiffe://td/t", []string{"s"}, 0); r.PolicyDigest != l.Digest() {
+		t.Errorf("grant PolicyDigest = %q, want %q", r.PolicyDigest, l.Digest())
+	}
+	if r := l.Evaluate("spiffe://td/b", "spiffe://td/t", []string{"s"}, 0); r.PolicyDigest != "" {
+		t.Errorf("denial PolicyDigest = %q, want empty", r.PolicyDigest)
+	}
 }
 
 func writeTemp(t *testing.T, content string) string {
@@ -289,6 +541,122 @@ policies:
     target:  "spiffe://cluster.local/ns/default/sa/payment"
     allowed_scopes: ["payments:charge"]
     max_ttl: -1
+`)
+			},
+		},
+		{
+			name: "unknown token_format",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: bad-format
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    token_format: saml
+`)
+			},
+		},
+		{
+			name: "single_use with a macaroon token_format",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: one-time-macaroon
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    token_format: macaroon
+    single_use: true
+`)
+			},
+		},
+		{
+			name: "audiences with a paseto token_format",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: paseto-audience
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    token_format: paseto
+    audiences:
+      "spiffe://cluster.local/ns/default/sa/payment": ["payments.example.com"]
+`)
+			},
+		},
+		{
+			name: "audiences key the target does not match",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: stray-audience
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    audiences:
+      "spiffe://cluster.local/ns/default/sa/inventory": ["inventory.example.com"]
+`)
+			},
+		},
+		{
+			name: "empty audience list",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: empty-audience
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    audiences:
+      "spiffe://cluster.local/ns/default/sa/payment": []
+`)
+			},
+		},
+		{
+			name: "single_use with an issuer",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: one-time-foreign-issuer
+    subject: "spiffe://cluster.local/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+    single_use: true
+    issuer: "https://issuer.example.com"
+`)
+			},
+		},
+		{
+			name: "wildcard trust domain in pattern",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: any-domain
+    subject: "spiffe://*/ns/default/sa/order"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
+`)
+			},
+		},
+		{
+			name: "malformed pattern",
+			setup: func(t *testing.T) string {
+				return writeTemp(t, `
+policies:
+  - name: bad-pattern
+    subject: "spiffe://cluster.local/ns/[default/sa/*"
+    target:  "spiffe://cluster.local/ns/default/sa/payment"
+    allowed_scopes: ["payments:charge"]
+    max_ttl: 60
 `)
 			},
 		},
@@ -321,3 +689,91 @@ policies:
 		})
 	}
 }
+
+// BenchmarkEvaluate measures Evaluate against a policy set the size of a
+// large deployment; with the (subject, target) index the cost should not
+// depend on the number of policies.
+func BenchmarkEvaluate(b *testing.B) {
+	for _, n := range []int{10, 5000} {
+		b.Run(fmt.Sprintf("policies=%d", n), func(b *testing.B) {
+			policies := make([]Policy, n)
+			for i := range policies {
+				policies[i] = Policy{
+					Name:          fmt.Sprintf("p%d", i),
+					Subject:       fmt.Sprintf("spiffe://cluster.local/ns/default/sa/caller-%d", i),
+					Target:        "spiffe://cluster.local/ns/default/sa/payment",
+					AllowedScopes: []string{"payments:charge", "payments:refund"},
+					MaxTTL:        300,
+				}
+			}
+			l, err := NewLoader(policies)
+			if err != nil {
+				b.Fatalf("NewLoader: %v", err)
+			}
+			last := policies[n-1]
+			scopes := []string{"payments:charge"}
+			b.ReportAllocs()
+			for b.Loop() {
+				if !l.Evaluate(last.Subject, last.Target, scopes, 60).Allowed {
+					b.Fatal("expected allow")
+				}
+			}
+		})
+	}
+}
+
+// FuzzMatchID checks that pattern matching, which runs on the caller's
+// SPIFFE ID, never panics and never lets a valid rule match an ID in
+// another trust domain.
+func FuzzMatchID(f *testing.F) {
+	f.Add("spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/order")
+	f.Add("spiffe://cluster.local/ns/*/sa/order", "spiffe://cluster.local/ns/prod/sa/order")
+	f.Add("spiffe://cluster.local/ns/[a-z]/sa/?", "spiffe://cluster.local/ns/a/sa/b")
+	f.Add("spiffe://cluster.local/ns/\\[", "spiffe://cluster.local/ns/[")
+	f.Add("spiffe://cluster.local/[", "spiffe://cluster.local/[")
+	f.Add("spiffe://cluster.local*/x", "spiffe://cluster.local.evil/x")
+	f.Fuzz(func(t *testing.T, want, id string) {
+		if validateIDOrPattern(want) != nil {
+			return
+		}
+		if !matchID(want, id) {
+			return
+		}
+		if !IsPattern(want) {
+			if want != id {
+				t.Fatalf("literal %q matched %q", want, id)
+			}
+			return
+		}
+		wantTD, _, _ := strings.Cut(strings.TrimPrefix(want, "spiffe://"), "/")
+		rest, ok := strings.CutPrefix(id, "spiffe://")
+		if td, _, _ := strings.Cut(rest, "/"); !ok || td != wantTD {
+			t.Fatalf("pattern %q for trust domain %q matched %q", want, wantTD, id)
+		}
+	})
+}
+
+// FuzzValidateOne checks that validating a policy file entry never panics on
+// arbitrary subjects and targets, and that an accepted literal is a
+// spiffe:// URI with a trust domain.
+func FuzzValidateOne(f *testing.F) {
+	f.Add("spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/*/sa/payment")
+	f.Add("spiffe://cluster.local/ns/[", "spiffe://")
+	f.Add("spiffe://*/ns/a", "spiffe:opaque")
+	f.Add("SPIFFE://cluster.local/x", "spiffe://%zz/x")
+	f.Fuzz(func(t *testing.T, subject, target string) {
+		p := Policy{Name: "fuzz", Subject: subject, Target: target, AllowedScopes: []string{"s"}, MaxTTL: 60}
+		if ValidateOne(p) != nil {
+			return
+		}
+		for _, id := range []string{subject, target} {
+			if IsPattern(id) {
+				continue
+			}
+			u, err := url.Parse(id)
+			if err != nil || u.Scheme != "spiffe" || u.Host == "" {
+				t.Fatalf("accepted literal %q is not spiffe://<trust-domain>/...", id)
+			}
+		}
+	})
+}
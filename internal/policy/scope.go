@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scopeSep separates the segments of a scope. An allowed scope may be a
+// template in which whole segments are parameters, such as
+// "orders:read:{order_id}". A template permits any requested scope that fills
+// each parameter with a concrete resource identifier, and that scope, not the
+// template, is what the token carries: "orders:read:8812" lets the holder
+// act on order 8812 and nothing else.
+const scopeSep = ":"
+
+// isScopeTemplate reports whether the allowed scope s has parameters.
+func isScopeTemplate(s string) bool {
+	return strings.ContainsAny(s, "{}")
+}
+
+// validateScope checks an allowed_scopes entry: every parameter must be a
+// whole segment of the form {name}, with name a lower-case identifier used
+// at most once in the scope.
+func validateScope(s string) error {
+	if !isScopeTemplate(s) {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, seg := range strings.Split(s, scopeSep) {
+		if !strings.ContainsAny(seg, "{}") {
+			continue
+		}
+		name, ok := paramName(seg)
+		if !ok {
+			return fmt.Errorf("scope %q: parameter %q must be a whole segment of the form {name}, with name in [a-z0-9_] starting with a letter", s, seg)
+		}
+		if seen[name] {
+			return fmt.Errorf("scope %q: parameter {%s} is used more than once", s, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// paramName returns the parameter name of the template segment seg.
+func paramName(seg string) (string, bool) {
+	name, ok := strings.CutPrefix(seg, "{")
+	if !ok {
+		return "", false
+	}
+	if name, ok = strings.CutSuffix(name, "}"); !ok || name == "" || name[0] < 'a' || name[0] > 'z' {
+		return "", false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// matchScope reports whether the requested scope is permitted by the
+// allowed scope, a literal or a template. A parameter matches one non-empty
+// segment that holds no braces, so a request cannot pass a template off as
+// a concrete scope.
+func matchScope(allowed, scope string) bool {
+	if !isScopeTemplate(allowed) {
+		return allowed == scope
+	}
+	want, got := strings.Split(allowed, scopeSep), strings.Split(scope, scopeSep)
+	if len(want) != len(got) {
+		return false
+	}
+	for i, w := range want {
+		switch {
+		case !strings.HasPrefix(w, "{"):
+			if w != got[i] {
+				return false
+			}
+		case got[i] == "" || strings.ContainsAny(got[i], "{}"):
+			return false
+		}
+	}
+	return true
+}
+
+// scopeAllowed reports whether scope is permitted by any of allowed.
+func scopeAllowed(scope string, allowed []string) bool {
+	for _, a := range allowed {
+		if matchScope(a, scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateScope(t *testing.T) {
+	tests := []struct {
+		scope   string
+		wantErr string
+	}{
+		{scope: "orders:read"},
+		{scope: "orders:read:{order_id}"},
+		{scope: "tenants:{tenant}:orders:{order_id2}:read"},
+		{scope: "orders:read:{order_id", wantErr: "whole segment"},
+		{scope: "orders:read:id-{order_id}", wantErr: "whole segment"},
+		{scope: "orders:read:{}", wantErr: "whole segment"},
+		{scope: "orders:read:{OrderID}", wantErr: "whole segment"},
+		{scope: "orders:read:{1st}", wantErr: "whole segment"},
+		{scope: "orders:{id}:items:{id}", wantErr: "more than once"},
+	}
+	for _, tc := range tests {
+		err := validateScope(tc.scope)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateScope(%q): %v", tc.scope, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("validateScope(%q) error = %v, want it to contain %q", tc.scope, err, tc.wantErr)
+		}
+	}
+}
+
+func TestMatchScope(t *testing.T) {
+	tests := []struct {
+		allowed, scope string
+		want           bool
+	}{
+		{allowed: "orders:read", scope: "orders:read", want: true},
+		{allowed: "orders:read", scope: "orders:read:8812"},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:8812", want: true},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:ord_7f3a-b.1", want: true},
+		{allowed: "orders:read:{order_id}", scope: "orders:read"},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:"},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:8812:items"},
+		{allowed: "orders:read:{order_id}", scope: "orders:write:8812"},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:{order_id}"},
+		{allowed: "orders:read:{order_id}", scope: "orders:read:{8812}"},
+		{allowed: "tenants:{tenant}:orders:{order_id}", scope: "tenants:acme:orders:8812", want: true},
+		{allowed: "tenants:{tenant}:orders:{order_id}", scope: "tenants:acme:invoices:8812"},
+	}
+	for _, tc := range tests {
+		if got := matchScope(tc.allowed, tc.scope); got != tc.want {
+			t.Errorf("matchScope(%q, %q) = %t, want %t", tc.allowed, tc.scope, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluateScopeTemplates(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	l, err := NewLoader([]Policy{{
+		Name:          "order-to-payment",
+		Subject:       order,
+		Target:        payment,
+		AllowedScopes: []string{"payments:read", "payments:refund:{payment_id}"},
+		MaxTTL:        300,
+	}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	res := l.Evaluate(order, payment, []string{"payments:refund:pay_123", "payments:refund", "payments:read", "payments:refund:{payment_id}"}, 60)
+	if want := []string{"payments:refund:pay_123", "payments:read"}; !res.Allowed || !slices.Equal(res.GrantedScopes, want) {
+		t.Errorf("GrantedScopes = %v (allowed %t), want %v", res.GrantedScopes, res.Allowed, want)
+	}
+
+	if _, err := NewLoader([]Policy{{
+		Name:          "bad-template",
+		Subject:       order,
+		Target:        payment,
+		AllowedScopes: []string{"payments:refund:{id"},
+		MaxTTL:        300,
+	}}); err == nil || !strings.Contains(err.Error(), "invalid allowed_scopes") {
+		t.Errorf("NewLoader error = %v, want invalid allowed_scopes", err)
+	}
+}
+
+func TestIntersectScopeTemplates(t *testing.T) {
+	merged := intersectPolicies([]Policy{
+		{Name: "literal", AllowedScopes: []string{"orders:read:8812", "orders:read:{order_id}", "orders:write:8812"}, MaxTTL: 60},
+		{Name: "pattern", AllowedScopes: []string{"orders:read:{order_id}"}, MaxTTL: 60},
+	})
+	if want := []string{"orders:read:8812", "orders:read:{order_id}"}; !slices.Equal(merged.AllowedScopes, want) {
+		t.Errorf("AllowedScopes = %v, want %v", merged.AllowedScopes, want)
+	}
+}
+
+// FuzzMatchScope checks that a template never grants a scope with a
+// different number of segments or a segment still holding a parameter.
+func FuzzMatchScope(f *testing.F) {
+	f.Add("orders:read:{order_id}", "orders:read:8812")
+	f.Add("tenants:{t}:orders:{o}", "tenants:a:orders:{o}")
+	f.Add("orders:read", "orders:read")
+	f.Fuzz(func(t *testing.T, allowed, scope string) {
+		if validateScope(allowed) != nil || !matchScope(allowed, scope) {
+			return
+		}
+		if isScopeTemplate(allowed) && strings.ContainsAny(scope, "{}") {
+			t.Fatalf("template %q matched %q, which holds a parameter", allowed, scope)
+		}
+		if strings.Count(allowed, scopeSep) != strings.Count(scope, scopeSep) {
+			t.Fatalf("%q matched %q, with a different number of segments", allowed, scope)
+		}
+	})
+}
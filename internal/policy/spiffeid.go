@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// Length limits from the SPIFFE ID specification (section 2.3).
+const (
+	maxSPIFFEIDLength    = 2048
+	maxTrustDomainLength = 255
+)
+
+// NormalizeSPIFFEID validates id against the SPIFFE ID specification and
+// returns its canonical form, with the scheme and trust domain lowercased.
+// Trust domains are case-insensitive while paths are not, so
+// "spiffe://Example.ORG/a" and "spiffe://example.org/a" name the same
+// workload; policy matching only ever sees the canonical form.
+//
+// Beyond the case fold it is strict: a port, userinfo, query, fragment,
+// percent-encoding, empty or dot path segment, trailing slash, or a character
+// outside the spec's set is rejected, as is an ID over 2048 bytes or a trust
+// domain over 255.
+func NormalizeSPIFFEID(id string) (string, error) {
+	if len(id) > maxSPIFFEIDLength {
+		return "", fmt.Errorf("SPIFFE ID is %d bytes, exceeding the maximum of %d", len(id), maxSPIFFEIDLength)
+	}
+	td, path, err := splitSPIFFEID(id)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := spiffeid.FromString("spiffe://" + td + path)
+	if err != nil {
+		return "", err
+	}
+	return parsed.String(), nil
+}
+
+// splitSPIFFEID returns id's lowercased trust domain and its path, with the
+// leading slash. It checks the scheme and the trust domain's length only.
+func splitSPIFFEID(id string) (td, path string, err error) {
+	scheme, rest, ok := strings.Cut(id, "://")
+	if !ok || !strings.EqualFold(scheme, "spiffe") {
+		return "", "", errors.New("scheme must be \"spiffe\"")
+	}
+	td, path = rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		td, path = rest[:i], rest[i:]
+	}
+	if td == "" {
+		return "", "", errors.New("missing trust domain")
+	}
+	if len(td) > maxTrustDomainLength {
+		return "", "", fmt.Errorf("trust domain is %d bytes, exceeding the maximum of %d", len(td), maxTrustDomainLength)
+	}
+	return asciiLower(td), path, nil
+}
+
+// asciiLower lowercases ASCII letters only. strings.ToLower would also fold
+// non-ASCII letters such as U+212A KELVIN SIGN to "k", letting a trust domain
+// the spec rejects pass as one it accepts.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// normalizeIDOrPattern returns the canonical form of a policy subject or
+// target: a literal ID via NormalizeSPIFFEID, or a pattern with its scheme
+// and trust domain lowercased. The pattern's path is left to path.Match.
+func normalizeIDOrPattern(id string) string {
+	if !IsPattern(id) {
+		if n, err := NormalizeSPIFFEID(id); err == nil {
+			return n
+		}
+		return id
+	}
+	td, path, err := splitSPIFFEID(id)
+	if err != nil {
+		return id
+	}
+	return "spiffe://" + td + path
+}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSPIFFEID(t *testing.T) {
+	longPath := "spiffe://cluster.local/" + strings.Repeat("a", maxSPIFFEIDLength)
+	longTD := "spiffe://" + strings.Repeat("a", maxTrustDomainLength+1) + "/x"
+
+	tests := []struct {
+		name    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{name: "canonical ID unchanged", id: "spiffe://cluster.local/ns/default/sa/order", want: "spiffe://cluster.local/ns/default/sa/order"},
+		{name: "trust domain only", id: "spiffe://cluster.local", want: "spiffe://cluster.local"},
+		{name: "trust domain lowercased", id: "spiffe://Cluster.LOCAL/ns/default", want: "spiffe://cluster.local/ns/default"},
+		{name: "scheme lowercased", id: "SPIFFE://cluster.local/x", want: "spiffe://cluster.local/x"},
+		{name: "path case preserved", id: "spiffe://cluster.local/NS/Default", want: "spiffe://cluster.local/NS/Default"},
+		{name: "wrong scheme", id: "https://cluster.local/x", wantErr: true},
+		{name: "missing scheme separator", id: "spiffe:/cluster.local/x", wantErr: true},
+		{name: "missing trust domain", id: "spiffe:///x", wantErr: true},
+		{name: "port", id: "spiffe://cluster.local:8443/x", wantErr: true},
+		{name: "userinfo", id: "spiffe://admin@cluster.local/x", wantErr: true},
+		{name: "query", id: "spiffe://cluster.local/x?y=z", wantErr: true},
+		{name: "fragment", id: "spiffe://cluster.local/x#y", wantErr: true},
+		{name: "percent-encoding", id: "spiffe://cluster.local/x%2Fy", wantErr: true},
+		{name: "dot segment", id: "spiffe://cluster.local/ns/../admin", wantErr: true},
+		{name: "empty segment", id: "spiffe://cluster.local/ns//x", wantErr: true},
+		{name: "trailing slash", id: "spiffe://cluster.local/x/", wantErr: true},
+		{name: "non-ASCII letter that folds to ASCII", id: "spiffe://\u212aube.local/x", wantErr: true},
+		{name: "ID too long", id: longPath, wantErr: true},
+		{name: "trust domain too long", id: longTD, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeSPIFFEID(tc.id)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NormalizeSPIFFEID(%q) error = %v, wantErr %v", tc.id, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeSPIFFEID(%q) = %q, want %q", tc.id, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoaderNormalizesIDs(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	l, err := NewLoader([]Policy{
+		{Name: "literal", Subject: "spiffe://Cluster.Local/ns/default/sa/order", Target: target, AllowedScopes: []string{"pay"}, MaxTTL: 60},
+		{Name: "pattern", Subject: "SPIFFE://CLUSTER.LOCAL/ns/batch/*", Target: target, AllowedScopes: []string{"pay"}, MaxTTL: 60},
+	})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		target  string
+		want    bool
+	}{
+		{name: "canonical request matches upper-case rule", subject: subject, target: target, want: true},
+		{name: "upper-case request matches", subject: "spiffe://CLUSTER.local/ns/default/sa/order", target: "spiffe://cluster.LOCAL/ns/default/sa/payment", want: true},
+		{name: "pattern rule matches", subject: "spiffe://cluster.local/ns/batch/job", target: target, want: true},
+		{name: "path case is significant", subject: "spiffe://cluster.local/ns/default/sa/ORDER", target: target},
+		{name: "invalid ID not offered to patterns", subject: "spiffe://cluster.local/ns/batch/a:b", target: target},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := l.Evaluate(tc.subject, tc.target, []string{"pay"}, 0).Allowed; got != tc.want {
+				t.Errorf("Evaluate(%q, %q).Allowed = %v, want %v", tc.subject, tc.target, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("spellings of one rule are duplicates", func(t *testing.T) {
+		_, err := NewLoader([]Policy{
+			{Name: "a", Subject: subject, Target: target, AllowedScopes: []string{"pay"}, MaxTTL: 60},
+			{Name: "b", Subject: "spiffe://CLUSTER.LOCAL/ns/default/sa/order", Target: target, AllowedScopes: []string{"pay"}, MaxTTL: 60},
+		})
+		if err == nil || !strings.Contains(err.Error(), "duplicate") {
+			t.Errorf("NewLoader error = %v, want a duplicate pair", err)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +15,18 @@ var bucketName = []byte("policies")
 
 var revocationsBucket = []byte("revocations")
 
+var issuedBucket = []byte("issued")
+
+var grantsBucket = []byte("grants")
+
+var noncesBucket = []byte("nonces")
+
+var consumedBucket = []byte("consumed")
+
+var tokenSourcesBucket = []byte("token_sources")
+
+var ruleUsageBucket = []byte("rule_usage")
+
 // Store is a BoltDB-backed persistent store for dynamic policies.
 // Dynamic policies supplement the YAML file and survive server restarts.
 type Store struct {
@@ -35,7 +49,25 @@ func OpenStore(path string) (*Store, error) {
 		if _, err := tx.CreateBucketIfNotExists(bucketName); err != nil {
 			return err
 		}
-		_, err := tx.CreateBucketIfNotExists(revocationsBucket)
+		if _, err := tx.CreateBucketIfNotExists(revocationsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(issuedBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(grantsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(noncesBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(consumedBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(tokenSourcesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(ruleUsageBucket)
 		return err
 	}); err != nil {
 		return nil, errors.Join(fmt.Errorf("init policy bucket: %w", err), db.Close())
@@ -48,6 +80,16 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// Ping checks that the database is open and readable.
+func (s *Store) Ping() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketName) == nil {
+			return errors.New("policies bucket missing")
+		}
+		return nil
+	})
+}
+
 // Save creates or replaces the policy with the given name.
 func (s *Store) Save(p Policy) error {
 	data, err := json.Marshal(p)
@@ -123,3 +165,467 @@ func (s *Store) ListRevocations() ([]RevokedEntry, error) {
 	})
 	return out, err
 }
+
+// issuedPrefix is the key prefix shared by every issued-token record for a
+// subject and target. NUL cannot appear in a SPIFFE ID, so prefixes of
+// distinct pairs never overlap.
+func issuedPrefix(subject, target string) []byte {
+	return []byte(subject + "\x00" + target + "\x00")
+}
+
+// ReserveToken records jti as an outstanding token held by subject for
+// target until expiresAt (a Unix timestamp), unless subject already holds
+// limit or more unexpired tokens for target, in which case nothing is
+// recorded and ReserveToken returns false. Expired records for the pair are
+// removed in the same transaction, so the count and the insert are atomic.
+func (s *Store) ReserveToken(subject, target, jti string, expiresAt int64, limit int) (bool, error) {
+	prefix := issuedPrefix(subject, target)
+	now := time.Now().Unix()
+	reserved := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(issuedBucket)
+		var expired [][]byte
+		outstanding := 0
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if len(v) != 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+				continue
+			}
+			outstanding++
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		if outstanding >= limit {
+			return nil
+		}
+		reserved = true
+		return b.Put(append(prefix, jti...), binary.BigEndian.AppendUint64(nil, uint64(expiresAt)))
+	})
+	if err != nil {
+		return false, fmt.Errorf("reserve token: %w", err)
+	}
+	return reserved, nil
+}
+
+// ReleaseToken removes the record of jti as a token held by subject for
+// target, so that a token that was reserved but never issued stops counting
+// against the quota. Releasing a token that is not recorded is a no-op.
+func (s *Store) ReleaseToken(subject, target, jti string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(issuedBucket).Delete(append(issuedPrefix(subject, target), jti...))
+	})
+	if err != nil {
+		return fmt.Errorf("release token: %w", err)
+	}
+	return nil
+}
+
+// PruneIssuedTokens removes issued-token records that expired at or before
+// now (a Unix timestamp) and returns how many were removed. ReserveToken
+// only prunes the pair it is counting, so records for pairs that stop
+// exchanging are left behind until this runs.
+func (s *Store) PruneIssuedTokens(now int64) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(issuedBucket)
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			if len(v) != 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// GrantRecord holds a persisted record of an issued token.
+type GrantRecord struct {
+	JTI       string
+	Target    string
+	Scopes    []string
+	ExpiresAt int64 // Unix timestamp
+}
+
+// grantPrefix is the key prefix shared by every grant record for subject.
+func grantPrefix(subject string) []byte {
+	return []byte(subject + "\x00")
+}
+
+// SaveGrant records g as a token issued to subject.
+func (s *Store) SaveGrant(subject string, g GrantRecord) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return fmt.Errorf("marshal grant: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(grantsBucket).Put(append(grantPrefix(subject), g.JTI...), data)
+	})
+}
+
+// ListGrants returns the grant records of subject that expire after now (a
+// Unix timestamp), ordered by JTI. The subject's expired records are removed
+// in the same transaction.
+func (s *Store) ListGrants(subject string, now int64) ([]GrantRecord, error) {
+	prefix := grantPrefix(subject)
+	var out []GrantRecord
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(grantsBucket)
+		var expired [][]byte
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var g GrantRecord
+			if err := json.Unmarshal(v, &g); err != nil {
+				return fmt.Errorf("unmarshal grant: %w", err)
+			}
+			if g.ExpiresAt <= now {
+				expired = append(expired, bytes.Clone(k))
+				continue
+			}
+			out = append(out, g)
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+	return out, nil
+}
+
+// Grant returns subject's grant record for jti and whether one exists. A
+// token issued to another subject is reported as absent.
+func (s *Store) Grant(subject, jti string) (GrantRecord, bool, error) {
+	var g GrantRecord
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(grantsBucket).Get(append(grantPrefix(subject), jti...))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &g)
+	})
+	if err != nil {
+		return GrantRecord{}, false, fmt.Errorf("get grant: %w", err)
+	}
+	return g, found, nil
+}
+
+// DeleteGrant removes subject's grant record for jti. It is not an error to
+// delete a record that does not exist.
+func (s *Store) DeleteGrant(subject, jti string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(grantsBucket).Delete(append(grantPrefix(subject), jti...))
+	})
+}
+
+// PruneGrants removes grant records that expired at or before now (a Unix
+// timestamp) and returns how many were removed. ListGrants only prunes the
+// subject it lists, so records of subjects that never list are left behind
+// until this runs.
+func (s *Store) PruneGrants(now int64) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(grantsBucket)
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			var g GrantRecord
+			if err := json.Unmarshal(v, &g); err != nil || g.ExpiresAt <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// noncePrefix is the key prefix shared by every nonce record for subject.
+func noncePrefix(subject string) []byte {
+	return []byte(subject + "\x00")
+}
+
+// UseNonce records nonce as used by subject until expiresAt (a Unix
+// timestamp) and returns true, unless subject has already used nonce and
+// the record has not expired, in which case it returns false. Nonces are
+// scoped to the subject, so two callers cannot burn each other's nonces.
+// The subject's expired records are removed in the same transaction, so the
+// check and the insert are atomic.
+func (s *Store) UseNonce(subject, nonce string, expiresAt int64) (bool, error) {
+	prefix := noncePrefix(subject)
+	key := append(noncePrefix(subject), nonce...)
+	now := time.Now().Unix()
+	fresh := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(noncesBucket)
+		var expired [][]byte
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if len(v) != 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		if b.Get(key) != nil {
+			return nil
+		}
+		fresh = true
+		return b.Put(key, binary.BigEndian.AppendUint64(nil, uint64(expiresAt)))
+	})
+	if err != nil {
+		return false, fmt.Errorf("use nonce: %w", err)
+	}
+	return fresh, nil
+}
+
+// PruneNonces removes nonce records that expired at or before now (a Unix
+// timestamp) and returns how many were removed. UseNonce only prunes the
+// subject it records for, so records of subjects that stop exchanging are
+// left behind until this runs.
+func (s *Store) PruneNonces(now int64) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(noncesBucket)
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			if len(v) != 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// ConsumeToken records the single-use token jti as consumed until expiresAt
+// (a Unix timestamp) and returns true, unless it was already consumed, in
+// which case it returns false. The check and the insert happen in one
+// transaction, so of two concurrent uses exactly one succeeds.
+func (s *Store) ConsumeToken(jti string, expiresAt int64) (bool, error) {
+	fresh := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(consumedBucket)
+		if b.Get([]byte(jti)) != nil {
+			return nil
+		}
+		fresh = true
+		return b.Put([]byte(jti), binary.BigEndian.AppendUint64(nil, uint64(expiresAt)))
+	})
+	if err != nil {
+		return false, fmt.Errorf("consume token: %w", err)
+	}
+	return fresh, nil
+}
+
+// PruneConsumedTokens removes the records of consumed tokens that expired at
+// or before now (a Unix timestamp) and returns how many were removed. An
+// expired token fails verification anyway, so its record is no longer needed.
+func (s *Store) PruneConsumedTokens(now int64) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(consumedBucket)
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			if len(v) != 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// ObserveTokenSource records source as the first source the token jti was
+// presented from, until expiresAt (a Unix timestamp), and returns it. When
+// an unexpired record for jti exists, it is kept and its source returned
+// instead. The check and the insert happen in one transaction, so
+// concurrent first presentations agree on which came first.
+func (s *Store) ObserveTokenSource(jti, source string, expiresAt int64) (string, error) {
+	now := time.Now().Unix()
+	first, ok := "", false
+	// Most presentations repeat a recorded source; look them up without
+	// taking the write lock.
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		first, ok = tokenSource(tx.Bucket(tokenSourcesBucket).Get([]byte(jti)), now)
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("observe token source: %w", err)
+	}
+	if ok {
+		return first, nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tokenSourcesBucket)
+		if first, ok = tokenSource(b.Get([]byte(jti)), now); ok {
+			return nil
+		}
+		first = source
+		return b.Put([]byte(jti), append(binary.BigEndian.AppendUint64(nil, uint64(expiresAt)), source...))
+	})
+	if err != nil {
+		return "", fmt.Errorf("observe token source: %w", err)
+	}
+	return first, nil
+}
+
+// tokenSource decodes a token source record, reporting false when there is
+// none or it expired at or before now.
+func tokenSource(v []byte, now int64) (string, bool) {
+	if len(v) < 8 || int64(binary.BigEndian.Uint64(v)) <= now {
+		return "", false
+	}
+	return string(v[8:]), true
+}
+
+// PruneTokenSources removes the token source records that expired at or
+// before now (a Unix timestamp) and returns how many were removed.
+// ObserveTokenSource replaces an expired record it finds, so this only
+// reclaims the records of tokens that stopped being presented.
+func (s *Store) PruneTokenSources(now int64) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tokenSourcesBucket)
+		var expired [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			if _, ok := tokenSource(v, now); !ok {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// RuleUsage holds the persisted match counters of a policy rule.
+type RuleUsage struct {
+	Matches      int64
+	LastMatched  int64 // Unix timestamp; zero if the rule has never matched
+	TrackedSince int64 // Unix timestamp
+}
+
+// UpdateRuleUsage adds matches, keyed by rule name, to the counters of the
+// rules named in active and sets their LastMatched to now (a Unix timestamp).
+// Active rules without counters start being tracked at now. Counters of
+// rules not in active are removed, so a rule that is deleted and later
+// re-added starts afresh; matches of such rules are dropped.
+func (s *Store) UpdateRuleUsage(matches map[string]int64, active []string, now int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(ruleUsageBucket)
+		keep := make(map[string]bool, len(active))
+		for _, name := range active {
+			keep[name] = true
+		}
+		var stale [][]byte
+		if err := b.ForEach(func(k, _ []byte) error {
+			if !keep[string(k)] {
+				stale = append(stale, bytes.Clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		for name := range keep {
+			n := matches[name]
+			u := RuleUsage{TrackedSince: now}
+			if v := b.Get([]byte(name)); v != nil {
+				if n == 0 {
+					continue
+				}
+				if err := json.Unmarshal(v, &u); err != nil {
+					return fmt.Errorf("unmarshal rule usage: %w", err)
+				}
+			}
+			if n > 0 {
+				u.Matches += n
+				u.LastMatched = now
+			}
+			data, err := json.Marshal(u)
+			if err != nil {
+				return fmt.Errorf("marshal rule usage: %w", err)
+			}
+			if err := b.Put([]byte(name), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListRuleUsage returns the counters of every tracked rule, keyed by rule
+// name.
+func (s *Store) ListRuleUsage() (map[string]RuleUsage, error) {
+	out := make(map[string]RuleUsage)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ruleUsageBucket).ForEach(func(k, v []byte) error {
+			var u RuleUsage
+			if err := json.Unmarshal(v, &u); err != nil {
+				return fmt.Errorf("unmarshal rule usage: %w", err)
+			}
+			out[string(k)] = u
+			return nil
+		})
+	})
+	return out, err
+}
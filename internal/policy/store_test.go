@@ -80,6 +80,22 @@ func TestStore(t *testing.T) {
 	})
 }
 
+func TestStorePing(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := store.Ping(); err != nil {
+		t.Errorf("Ping() on open store = %v, want nil", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := store.Ping(); err == nil {
+		t.Error("Ping() on closed store = nil, want error")
+	}
+}
+
 func TestRevocationStore(t *testing.T) {
 	dbPath := filepath.Join(t.TempDir(), "policy.db")
 	store, err := OpenStore(dbPath)
@@ -145,3 +161,359 @@ func TestRevocationStore(t *testing.T) {
 		}
 	})
 }
+
+func TestIssuedTokenStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "policy.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	const (
+		a = "spiffe://cluster.local/ns/default/sa/a"
+		b = "spiffe://cluster.local/ns/default/sa/b"
+		c = "spiffe://cluster.local/ns/default/sa/c"
+	)
+	live := time.Now().Add(time.Minute).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	reserve := func(t *testing.T, subject, target, jti string, exp int64) bool {
+		t.Helper()
+		ok, err := store.ReserveToken(subject, target, jti, exp, 2)
+		if err != nil {
+			t.Fatalf("reserve %s: %v", jti, err)
+		}
+		return ok
+	}
+
+	t.Run("reserves up to the limit", func(t *testing.T) {
+		if !reserve(t, a, b, "jti-1", live) || !reserve(t, a, b, "jti-2", live) {
+			t.Fatal("expected the first two reservations to succeed")
+		}
+		if reserve(t, a, b, "jti-3", live) {
+			t.Error("expected the third reservation to be refused")
+		}
+	})
+
+	t.Run("limit is per target", func(t *testing.T) {
+		if !reserve(t, a, c, "jti-4", live) {
+			t.Error("expected a reservation for another target to succeed")
+		}
+	})
+
+	t.Run("limit is per subject", func(t *testing.T) {
+		if !reserve(t, c, b, "jti-5", live) {
+			t.Error("expected a reservation for another subject to succeed")
+		}
+	})
+
+	t.Run("expired tokens do not count", func(t *testing.T) {
+		if !reserve(t, b, c, "jti-old-1", past) || !reserve(t, b, c, "jti-old-2", past) {
+			t.Fatal("expected reservations to succeed")
+		}
+		if !reserve(t, b, c, "jti-6", live) {
+			t.Error("expected expired records to be ignored")
+		}
+	})
+
+	t.Run("released tokens do not count", func(t *testing.T) {
+		if err := store.ReleaseToken(a, b, "jti-1"); err != nil {
+			t.Fatalf("release: %v", err)
+		}
+		if !reserve(t, a, b, "jti-7", live) {
+			t.Error("expected a reservation to succeed after a release")
+		}
+	})
+
+	t.Run("prune removes expired records", func(t *testing.T) {
+		if !reserve(t, c, a, "jti-old-3", past) {
+			t.Fatal("expected reservation to succeed")
+		}
+		n, err := store.PruneIssuedTokens(time.Now().Unix())
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("pruned %d records, want 1", n)
+		}
+	})
+}
+
+func TestGrantStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "policy.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		billing = "spiffe://cluster.local/ns/default/sa/billing"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	now := time.Now().Unix()
+	for _, g := range []struct {
+		subject string
+		rec     GrantRecord
+	}{
+		{order, GrantRecord{JTI: "jti-2", Target: payment, Scopes: []string{"payments:charge"}, ExpiresAt: now + 60}},
+		{order, GrantRecord{JTI: "jti-1", Target: payment, Scopes: []string{"payments:read"}, ExpiresAt: now + 60}},
+		{order, GrantRecord{JTI: "jti-old", Target: payment, ExpiresAt: now - 60}},
+		{billing, GrantRecord{JTI: "jti-3", Target: payment, ExpiresAt: now + 60}},
+		{billing, GrantRecord{JTI: "jti-old-2", Target: payment, ExpiresAt: now - 60}},
+	} {
+		if err := store.SaveGrant(g.subject, g.rec); err != nil {
+			t.Fatalf("save %s: %v", g.rec.JTI, err)
+		}
+	}
+
+	t.Run("lists a subject's live grants in order", func(t *testing.T) {
+		got, err := store.ListGrants(order, now)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(got) != 2 || got[0].JTI != "jti-1" || got[1].JTI != "jti-2" || got[0].Scopes[0] != "payments:read" {
+			t.Errorf("grants = %+v, want jti-1 and jti-2", got)
+		}
+	})
+
+	t.Run("lookup is scoped to the subject", func(t *testing.T) {
+		if _, ok, err := store.Grant(order, "jti-1"); err != nil || !ok {
+			t.Errorf("Grant(order, jti-1) = %v, %v; want found", ok, err)
+		}
+		if _, ok, err := store.Grant(billing, "jti-1"); err != nil || ok {
+			t.Errorf("Grant(billing, jti-1) = %v, %v; want absent", ok, err)
+		}
+	})
+
+	t.Run("delete removes the record", func(t *testing.T) {
+		if err := store.DeleteGrant(order, "jti-1"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, ok, _ := store.Grant(order, "jti-1"); ok {
+			t.Error("deleted grant still present")
+		}
+	})
+
+	t.Run("prune removes the remaining expired records", func(t *testing.T) {
+		// ListGrants already removed order's expired record.
+		n, err := store.PruneGrants(now)
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("pruned %d records, want 1", n)
+		}
+	})
+}
+
+func TestNonceStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "policy.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	const (
+		a = "spiffe://cluster.local/ns/default/sa/a"
+		b = "spiffe://cluster.local/ns/default/sa/b"
+	)
+	live := time.Now().Add(time.Minute).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	use := func(t *testing.T, subject, nonce string, exp int64) bool {
+		t.Helper()
+		ok, err := store.UseNonce(subject, nonce, exp)
+		if err != nil {
+			t.Fatalf("use %s: %v", nonce, err)
+		}
+		return ok
+	}
+
+	t.Run("a nonce is accepted once", func(t *testing.T) {
+		if !use(t, a, "nonce-1", live) {
+			t.Fatal("expected the first use to succeed")
+		}
+		if use(t, a, "nonce-1", live) {
+			t.Error("expected the replayed nonce to be refused")
+		}
+	})
+
+	t.Run("nonces are per subject", func(t *testing.T) {
+		if !use(t, b, "nonce-1", live) {
+			t.Error("expected another subject's use of the nonce to succeed")
+		}
+	})
+
+	t.Run("an expired nonce may be reused", func(t *testing.T) {
+		if !use(t, a, "nonce-old", past) {
+			t.Fatal("expected the first use to succeed")
+		}
+		if !use(t, a, "nonce-old", live) {
+			t.Error("expected the expired record to be ignored")
+		}
+	})
+
+	t.Run("prune removes expired records", func(t *testing.T) {
+		if !use(t, b, "nonce-old", past) {
+			t.Fatal("expected the use to succeed")
+		}
+		n, err := store.PruneNonces(time.Now().Unix())
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("pruned %d records, want 1", n)
+		}
+	})
+}
+
+func TestConsumedTokenStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "policy.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	consume := func(t *testing.T, jti string, exp int64) bool {
+		t.Helper()
+		ok, err := store.ConsumeToken(jti, exp)
+		if err != nil {
+			t.Fatalf("consume %s: %v", jti, err)
+		}
+		return ok
+	}
+
+	t.Run("a token is consumed once", func(t *testing.T) {
+		exp := time.Now().Add(time.Minute).Unix()
+		if !consume(t, "jti-1", exp) {
+			t.Fatal("expected the first use to succeed")
+		}
+		if consume(t, "jti-1", exp) {
+			t.Error("expected the second use to be refused")
+		}
+	})
+
+	t.Run("prune removes expired records", func(t *testing.T) {
+		if !consume(t, "jti-old", time.Now().Add(-time.Minute).Unix()) {
+			t.Fatal("expected the use to succeed")
+		}
+		n, err := store.PruneConsumedTokens(time.Now().Unix())
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("pruned %d records, want 1", n)
+		}
+		if consume(t, "jti-1", time.Now().Add(time.Minute).Unix()) {
+			t.Error("prune removed a live record")
+		}
+	})
+}
+
+func TestTokenSourceStore(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	observe := func(t *testing.T, jti, source string, exp int64) string {
+		t.Helper()
+		first, err := store.ObserveTokenSource(jti, source, exp)
+		if err != nil {
+			t.Fatalf("observe %s: %v", jti, err)
+		}
+		return first
+	}
+	live := time.Now().Add(time.Minute).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	t.Run("the first source is kept", func(t *testing.T) {
+		if first := observe(t, "jti-1", "src-1", live); first != "src-1" {
+			t.Errorf("first presentation = %q, want src-1", first)
+		}
+		if first := observe(t, "jti-1", "src-2", live); first != "src-1" {
+			t.Errorf("second presentation = %q, want src-1", first)
+		}
+	})
+
+	t.Run("an expired record is replaced", func(t *testing.T) {
+		observe(t, "jti-old", "src-1", past)
+		if first := observe(t, "jti-old", "src-2", live); first != "src-2" {
+			t.Errorf("presentation after expiry = %q, want src-2", first)
+		}
+	})
+
+	t.Run("prune removes expired records", func(t *testing.T) {
+		observe(t, "jti-gone", "src-1", past)
+		n, err := store.PruneTokenSources(time.Now().Unix())
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("pruned %d records, want 1", n)
+		}
+		if first := observe(t, "jti-1", "src-3", live); first != "src-1" {
+			t.Errorf("prune removed a live record: first = %q", first)
+		}
+	})
+}
+
+func TestRuleUsageStore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "policy.db")
+	store, err := OpenStore(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	list := func(t *testing.T) map[string]RuleUsage {
+		t.Helper()
+		got, err := store.ListRuleUsage()
+		if err != nil {
+			t.Fatalf("list rule usage: %v", err)
+		}
+		return got
+	}
+
+	t.Run("active rules start being tracked", func(t *testing.T) {
+		if err := store.UpdateRuleUsage(map[string]int64{"a": 2}, []string{"a", "b"}, 100); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		got := list(t)
+		if got["a"] != (RuleUsage{Matches: 2, LastMatched: 100, TrackedSince: 100}) {
+			t.Errorf("a = %+v", got["a"])
+		}
+		if got["b"] != (RuleUsage{TrackedSince: 100}) {
+			t.Errorf("b = %+v", got["b"])
+		}
+	})
+
+	t.Run("matches accumulate", func(t *testing.T) {
+		if err := store.UpdateRuleUsage(map[string]int64{"a": 1}, []string{"a", "b"}, 200); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		got := list(t)
+		if got["a"] != (RuleUsage{Matches: 3, LastMatched: 200, TrackedSince: 100}) {
+			t.Errorf("a = %+v", got["a"])
+		}
+		if got["b"] != (RuleUsage{TrackedSince: 100}) {
+			t.Errorf("b = %+v, want it unchanged", got["b"])
+		}
+	})
+
+	t.Run("inactive rules are dropped", func(t *testing.T) {
+		if err := store.UpdateRuleUsage(map[string]int64{"a": 1, "override": 1}, []string{"b"}, 300); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		got := list(t)
+		if len(got) != 1 || got["b"] != (RuleUsage{TrackedSince: 100}) {
+			t.Errorf("usage = %+v, want only b", got)
+		}
+	})
+}
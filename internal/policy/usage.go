@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"sync"
+	"time"
+)
+
+// UsageTracker counts the matches of policy rules in memory and periodically
+// adds them to the counters persisted in a Store, so that rules nobody uses
+// can be found and pruned.
+type UsageTracker struct {
+	store  *Store
+	active func() []string
+
+	mu      sync.Mutex
+	pending map[string]int64
+	last    map[string]int64 // Unix time of each pending rule's latest match
+}
+
+// NewUsageTracker returns a UsageTracker that persists to store. active
+// returns the names of the rules currently in effect; only those are
+// tracked.
+func NewUsageTracker(store *Store, active func() []string) *UsageTracker {
+	return &UsageTracker{
+		store:   store,
+		active:  active,
+		pending: make(map[string]int64),
+		last:    make(map[string]int64),
+	}
+}
+
+// Record counts one match of each named rule. It never blocks on the store.
+func (t *UsageTracker) Record(rules []string) {
+	if len(rules) == 0 {
+		return
+	}
+	now := time.Now().Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range rules {
+		t.pending[name]++
+		t.last[name] = now
+	}
+}
+
+// Flush adds the matches recorded since the last flush to the store, stamped
+// with now, and starts tracking rules that have become active. If the store
+// update fails the matches are kept for the next flush.
+func (t *UsageTracker) Flush(now time.Time) error {
+	t.mu.Lock()
+	pending, last := t.pending, t.last
+	t.pending, t.last = make(map[string]int64), make(map[string]int64)
+	t.mu.Unlock()
+
+	err := t.store.UpdateRuleUsage(pending, t.active(), now.Unix())
+	if err != nil {
+		t.mu.Lock()
+		for name, n := range pending {
+			t.pending[name] += n
+			t.last[name] = max(t.last[name], last[name])
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// Usage returns the counters of every tracked rule, keyed by rule name,
+// including matches not yet flushed.
+func (t *UsageTracker) Usage() (map[string]RuleUsage, error) {
+	usage, err := t.store.ListRuleUsage()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, n := range t.pending {
+		u, ok := usage[name]
+		if !ok {
+			continue
+		}
+		u.Matches += n
+		u.LastMatched = max(u.LastMatched, t.last[name])
+		usage[name] = u
+	}
+	return usage, nil
+}
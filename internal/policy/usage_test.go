@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	active := []string{"a", "b"}
+	tr := NewUsageTracker(store, func() []string { return active })
+	start := time.Unix(1700000000, 0)
+	if err := tr.Flush(start); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	tr.Record([]string{"a"})
+	tr.Record([]string{"a", "override"})
+
+	t.Run("usage includes unflushed matches", func(t *testing.T) {
+		got, err := tr.Usage()
+		if err != nil {
+			t.Fatalf("usage: %v", err)
+		}
+		if got["a"].Matches != 2 || got["a"].LastMatched == 0 || got["a"].TrackedSince != start.Unix() {
+			t.Errorf("a = %+v", got["a"])
+		}
+		if got["b"] != (RuleUsage{TrackedSince: start.Unix()}) {
+			t.Errorf("b = %+v", got["b"])
+		}
+		if _, ok := got["override"]; ok {
+			t.Error("inactive rule override is tracked")
+		}
+	})
+
+	t.Run("flush persists matches", func(t *testing.T) {
+		at := start.Add(time.Minute)
+		if err := tr.Flush(at); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		got, err := store.ListRuleUsage()
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if got["a"] != (RuleUsage{Matches: 2, LastMatched: at.Unix(), TrackedSince: start.Unix()}) {
+			t.Errorf("a = %+v", got["a"])
+		}
+	})
+
+	t.Run("failed flush keeps matches", func(t *testing.T) {
+		tr.Record([]string{"b"})
+		store.Close()
+		if err := tr.Flush(start.Add(2 * time.Minute)); err == nil {
+			t.Fatal("expected flush to a closed store to fail")
+		}
+		if tr.pending["b"] != 1 {
+			t.Errorf("pending b = %d, want 1", tr.pending["b"])
+		}
+	})
+}
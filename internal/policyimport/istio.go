@@ -0,0 +1,259 @@
+package policyimport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// IstioOptions configures FromIstio.
+type IstioOptions struct {
+	// TrustDomain qualifies source namespaces and target workloads, as
+	// Istio's own trust domain does. Principals carry their own.
+	TrustDomain string
+	// ServiceAccountLabel is the selector label whose value names a target
+	// workload's service account. A policy selecting workloads without it
+	// is skipped.
+	ServiceAccountLabel string
+	// DefaultScope is granted for a rule without operations, which Istio
+	// applies to every request.
+	DefaultScope string
+	// MaxTTL is the max_ttl of every generated policy.
+	MaxTTL int32
+}
+
+// authorizationPolicy is the part of an Istio AuthorizationPolicy, or a List
+// of them, that FromIstio reads.
+type authorizationPolicy struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Selector *struct {
+			MatchLabels map[string]string `yaml:"matchLabels"`
+		} `yaml:"selector"`
+		Action string         `yaml:"action"`
+		Rules  []istioRule    `yaml:"rules"`
+		Other  map[string]any `yaml:",inline"`
+	} `yaml:"spec"`
+	Items []authorizationPolicy `yaml:"items"`
+}
+
+type istioRule struct {
+	From []struct {
+		Source istioSource `yaml:"source"`
+	} `yaml:"from"`
+	To []struct {
+		Operation istioOperation `yaml:"operation"`
+	} `yaml:"to"`
+	When []any `yaml:"when"`
+}
+
+type istioSource struct {
+	Principals []string       `yaml:"principals"`
+	Namespaces []string       `yaml:"namespaces"`
+	Other      map[string]any `yaml:",inline"`
+}
+
+type istioOperation struct {
+	Methods []string       `yaml:"methods"`
+	Paths   []string       `yaml:"paths"`
+	Other   map[string]any `yaml:",inline"`
+}
+
+// FromIstio converts the ALLOW AuthorizationPolicies in r, a stream of YAML
+// documents such as the output of `kubectl get authorizationpolicies -A -o
+// yaml`, into policies, one per source → workload pair. Sources are the
+// rules' principals and namespaces; the target is the selected workload's
+// service account, or every service account in the namespace without a
+// selector. Each operation becomes the scope "METHOD:path", or the method or
+// path alone. Anything without an equivalent, such as DENY policies, when
+// conditions, and not* or ip fields, is skipped and described in warnings.
+func FromIstio(r io.Reader, opts IstioOptions) ([]policy.Policy, []string, error) {
+	b := newBuilder(opts.MaxTTL)
+	var warnings []string
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc authorizationPolicy
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse AuthorizationPolicy: %w", err)
+		}
+		docs := []authorizationPolicy{doc}
+		if doc.Kind == "List" {
+			docs = doc.Items
+		}
+		for _, ap := range docs {
+			if ap.Kind != "AuthorizationPolicy" {
+				continue
+			}
+			warnings = append(warnings, convertIstio(b, ap, opts)...)
+		}
+	}
+	return b.policies, warnings, nil
+}
+
+// convertIstio adds the grants of ap to b and returns warnings for what it
+// skips.
+func convertIstio(b *builder, ap authorizationPolicy, opts IstioOptions) []string {
+	ns := ap.Metadata.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	ref := ns + "/" + ap.Metadata.Name
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, ref+": "+fmt.Sprintf(format, args...))
+	}
+
+	if a := ap.Spec.Action; a != "" && a != "ALLOW" {
+		warn("action %s has no equivalent; skipped", a)
+		return warnings
+	}
+	if len(ap.Spec.Other) > 0 {
+		warn("spec fields %s are not supported; skipped", fieldNames(ap.Spec.Other))
+		return warnings
+	}
+	sa := "*"
+	if sel := ap.Spec.Selector; sel != nil && len(sel.MatchLabels) > 0 {
+		v, ok := sel.MatchLabels[opts.ServiceAccountLabel]
+		if !ok || strings.ContainsAny(v, "*?[") {
+			warn("selector has no %s label naming a service account; skipped", opts.ServiceAccountLabel)
+			return warnings
+		}
+		sa = v
+	}
+	target := "spiffe://" + opts.TrustDomain + "/ns/" + ns + "/sa/" + sa
+
+	for i, rule := range ap.Spec.Rules {
+		if len(rule.When) > 0 {
+			warn("rule %d: when conditions have no equivalent; skipped", i)
+			continue
+		}
+		subjects, err := istioSubjects(rule, opts.TrustDomain)
+		if err != nil {
+			warn("rule %d: %v; skipped", i, err)
+			continue
+		}
+		scopes, err := istioScopes(rule, opts.DefaultScope)
+		if err != nil {
+			warn("rule %d: %v; skipped", i, err)
+			continue
+		}
+		for _, subject := range subjects {
+			b.add(ns+"-"+ap.Metadata.Name, subject, target, scopes)
+		}
+	}
+	return warnings
+}
+
+// istioSubjects returns the SPIFFE IDs or patterns of the sources rule
+// allows.
+func istioSubjects(rule istioRule, trustDomain string) ([]string, error) {
+	if len(rule.From) == 0 {
+		return nil, errors.New("a rule without sources allows any caller")
+	}
+	var out []string
+	for _, f := range rule.From {
+		src := f.Source
+		if len(src.Other) > 0 {
+			return nil, fmt.Errorf("source fields %s are not supported", fieldNames(src.Other))
+		}
+		for _, p := range src.Principals {
+			// A principal is a SPIFFE ID without its scheme. Istio's prefix
+			// and suffix wildcards only translate when they stand for a
+			// whole service account name.
+			id := "spiffe://" + p
+			if _, err := policy.NormalizeSPIFFEID(id); err != nil {
+				base, ok := strings.CutSuffix(id, "/sa/*")
+				if !ok || strings.Contains(base, "*") {
+					return nil, fmt.Errorf("principal %q cannot be expressed as a SPIFFE ID pattern", p)
+				}
+			}
+			// Fields of one source must all match, so principals are
+			// narrowed to the source's namespaces.
+			if len(src.Namespaces) == 0 || slices.ContainsFunc(src.Namespaces, func(ns string) bool {
+				return strings.Contains(id, "/ns/"+ns+"/")
+			}) {
+				out = append(out, id)
+			}
+		}
+		if len(src.Principals) > 0 {
+			continue
+		}
+		for _, ns := range src.Namespaces {
+			if strings.Contains(ns, "*") {
+				return nil, fmt.Errorf("namespace %q cannot be expressed as a SPIFFE ID pattern", ns)
+			}
+			out = append(out, "spiffe://"+trustDomain+"/ns/"+ns+"/sa/*")
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("sources name no principals or namespaces")
+	}
+	return out, nil
+}
+
+// istioScopes returns the scopes of the operations rule allows.
+func istioScopes(rule istioRule, defaultScope string) ([]string, error) {
+	if len(rule.To) == 0 {
+		return []string{defaultScope}, nil
+	}
+	var out []string
+	add := func(s string) {
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	for _, t := range rule.To {
+		op := t.Operation
+		if len(op.Other) > 0 {
+			return nil, fmt.Errorf("operation fields %s are not supported", fieldNames(op.Other))
+		}
+		for _, p := range op.Paths {
+			if strings.ContainsAny(p, "{}") {
+				return nil, fmt.Errorf("path template %q is not supported", p)
+			}
+		}
+		switch {
+		case len(op.Methods) > 0 && len(op.Paths) > 0:
+			for _, m := range op.Methods {
+				for _, p := range op.Paths {
+					add(m + ":" + p)
+				}
+			}
+		case len(op.Methods) > 0:
+			for _, m := range op.Methods {
+				add(m)
+			}
+		case len(op.Paths) > 0:
+			for _, p := range op.Paths {
+				add(p)
+			}
+		default:
+			add(defaultScope)
+		}
+	}
+	return out, nil
+}
+
+// fieldNames lists the keys of m in sorted order.
+func fieldNames(m map[string]any) string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
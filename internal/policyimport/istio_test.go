@@ -0,0 +1,154 @@
+package policyimport
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+const authorizationPolicies = `apiVersion: v1
+kind: List
+items:
+  - apiVersion: security.istio.io/v1
+    kind: AuthorizationPolicy
+    metadata:
+      name: payment-allow
+      namespace: shop
+    spec:
+      selector:
+        matchLabels:
+          app: payment
+      action: ALLOW
+      rules:
+        - from:
+            - source:
+                principals: ["cluster.local/ns/shop/sa/order"]
+          to:
+            - operation:
+                methods: ["POST"]
+                paths: ["/charge", "/refund"]
+        - from:
+            - source:
+                principals: ["cluster.local/ns/shop/sa/order"]
+          to:
+            - operation:
+                methods: ["GET"]
+        - from:
+            - source:
+                namespaces: ["billing"]
+        - from:
+            - source:
+                principals: ["cluster.local/ns/shop/sa/*", "cluster.local/ns/ops/sa/admin"]
+                namespaces: ["shop"]
+          when:
+            - key: request.headers[x-debug]
+              values: ["1"]
+---
+apiVersion: security.istio.io/v1
+kind: AuthorizationPolicy
+metadata:
+  name: ledger
+  namespace: shop
+spec:
+  rules:
+    - from:
+        - source:
+            principals: ["cluster.local/ns/shop/sa/*", "cluster.local/ns/ops/sa/admin"]
+            namespaces: ["shop"]
+    - from:
+        - source:
+            notPrincipals: ["cluster.local/ns/shop/sa/intruder"]
+    - to:
+        - operation:
+            paths: ["/public"]
+---
+apiVersion: security.istio.io/v1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-debug
+  namespace: shop
+spec:
+  action: DENY
+  rules:
+    - to:
+        - operation:
+            paths: ["/debug"]
+---
+apiVersion: security.istio.io/v1
+kind: AuthorizationPolicy
+metadata:
+  name: by-version
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      version: v2
+  rules:
+    - from:
+        - source:
+            principals: ["cluster.local/ns/shop/sa/order"]
+`
+
+func TestFromIstio(t *testing.T) {
+	got, warnings, err := FromIstio(strings.NewReader(authorizationPolicies), IstioOptions{
+		TrustDomain:         "cluster.local",
+		ServiceAccountLabel: "app",
+		DefaultScope:        "all",
+		MaxTTL:              300,
+	})
+	if err != nil {
+		t.Fatalf("FromIstio: %v", err)
+	}
+	const td = "spiffe://cluster.local"
+	want := []policy.Policy{
+		{Name: "shop-payment-allow", Subject: td + "/ns/shop/sa/order", Target: td + "/ns/shop/sa/payment", AllowedScopes: []string{"POST:/charge", "POST:/refund", "GET"}, MaxTTL: 300},
+		{Name: "shop-payment-allow-2", Subject: td + "/ns/billing/sa/*", Target: td + "/ns/shop/sa/payment", AllowedScopes: []string{"all"}, MaxTTL: 300},
+		{Name: "shop-ledger", Subject: td + "/ns/shop/sa/*", Target: td + "/ns/shop/sa/*", AllowedScopes: []string{"all"}, MaxTTL: 300},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d policies, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Subject != w.Subject || g.Target != w.Target || g.MaxTTL != w.MaxTTL || !slices.Equal(g.AllowedScopes, w.AllowedScopes) {
+			t.Errorf("policy %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if _, err := policy.NewLoader(got); err != nil {
+		t.Errorf("generated policies do not load: %v", err)
+	}
+
+	wantWarnings := []string{
+		"shop/payment-allow: rule 3: when conditions",
+		"shop/ledger: rule 1: source fields notPrincipals",
+		"shop/ledger: rule 2: a rule without sources",
+		"shop/deny-debug: action DENY",
+		"shop/by-version: selector has no app label",
+	}
+	if len(warnings) != len(wantWarnings) {
+		t.Fatalf("warnings = %q, want %d", warnings, len(wantWarnings))
+	}
+	for i, w := range wantWarnings {
+		if !strings.HasPrefix(warnings[i], w) {
+			t.Errorf("warning %d = %q, want prefix %q", i, warnings[i], w)
+		}
+	}
+}
+
+func TestFromIstioErrors(t *testing.T) {
+	if _, _, err := FromIstio(strings.NewReader("kind: ["), IstioOptions{}); err == nil {
+		t.Error("malformed YAML: expected error")
+	}
+	for _, principal := range []string{"*", "cluster.local/ns/shop/*", "*/ns/shop/sa/order"} {
+		doc := "kind: AuthorizationPolicy\nmetadata: {name: p, namespace: shop}\nspec:\n  rules:\n    - from:\n        - source:\n            principals: [\"" + principal + "\"]\n"
+		got, warnings, err := FromIstio(strings.NewReader(doc), IstioOptions{TrustDomain: "cluster.local", DefaultScope: "all", MaxTTL: 60})
+		if err != nil {
+			t.Fatalf("FromIstio: %v", err)
+		}
+		if len(got) != 0 || len(warnings) != 1 {
+			t.Errorf("principal %q: policies %+v, warnings %q; want it skipped", principal, got, warnings)
+		}
+	}
+}
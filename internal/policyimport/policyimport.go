@@ -0,0 +1,122 @@
+// Package policyimport generates svid-exchange policies from the access
+// rules of systems a cluster already runs, such as Istio AuthorizationPolicy
+// resources and SPIRE registration entries, to bootstrap a policy file.
+// Converters never widen access to make a rule fit: a rule whose
+// constraints have no svid-exchange equivalent is skipped with a warning.
+// The output is a starting point to review, not a drop-in replacement.
+package policyimport
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// builder collects the policies a converter generates, merging the scopes of
+// rules for the same (subject, target) pair into one policy, as NewLoader
+// requires, and keeping names unique.
+type builder struct {
+	maxTTL   int32
+	policies []policy.Policy
+	index    map[[2]string]int // (subject, target) → index into policies
+	names    map[string]bool
+}
+
+func newBuilder(maxTTL int32) *builder {
+	return &builder{maxTTL: maxTTL, index: make(map[[2]string]int), names: make(map[string]bool)}
+}
+
+// add grants scopes to subject for target under a name derived from name.
+func (b *builder) add(name, subject, target string, scopes []string) {
+	key := [2]string{subject, target}
+	if i, ok := b.index[key]; ok {
+		p := &b.policies[i]
+		for _, s := range scopes {
+			if !slices.Contains(p.AllowedScopes, s) {
+				p.AllowedScopes = append(p.AllowedScopes, s)
+			}
+		}
+		return
+	}
+	unique := name
+	for n := 2; b.names[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	b.names[unique] = true
+	b.index[key] = len(b.policies)
+	b.policies = append(b.policies, policy.Policy{
+		Name:          unique,
+		Subject:       subject,
+		Target:        target,
+		AllowedScopes: slices.Clone(scopes),
+		MaxTTL:        b.maxTTL,
+	})
+}
+
+// label returns a short name for a SPIFFE ID or pattern for use in policy
+// names: its last path segment without wildcards, skipping the "sa" and "ns"
+// of Kubernetes IDs, so ".../ns/ledger/sa/*" becomes "ledger".
+func label(id string) string {
+	segs := strings.Split(id, "/")
+	for i := len(segs) - 1; i > 2; i-- {
+		if s := segs[i]; s != "sa" && s != "ns" && !strings.ContainsAny(s, "*?[") {
+			return s
+		}
+	}
+	return "any"
+}
+
+// yamlPolicy is the layout of a generated policy, leaving out the fields a
+// converter never sets.
+type yamlPolicy struct {
+	Name          string   `yaml:"name"`
+	Subject       string   `yaml:"subject"`
+	Target        string   `yaml:"target"`
+	AllowedScopes []string `yaml:"allowed_scopes"`
+	MaxTTL        int32    `yaml:"max_ttl"`
+}
+
+// WriteYAML writes ps as a policy file, preceded by header as a comment.
+func WriteYAML(w io.Writer, header string, ps []policy.Policy) error {
+	for _, line := range strings.Split(header, "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight("# "+line, " ")); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	out := struct {
+		Policies []yamlPolicy `yaml:"policies"`
+	}{Policies: make([]yamlPolicy, len(ps))}
+	for i, p := range ps {
+		out.Policies[i] = yamlPolicy{Name: p.Name, Subject: p.Subject, Target: p.Target, AllowedScopes: p.AllowedScopes, MaxTTL: p.MaxTTL}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// AllPairs returns one policy per ordered pair of distinct ids, letting
+// every identity exchange for every other with scopes for up to maxTTL
+// seconds. It is meant for development setups, where the identities are
+// few and trust among them is not the point.
+func AllPairs(ids, scopes []string, maxTTL int32) []policy.Policy {
+	b := newBuilder(maxTTL)
+	for _, subject := range ids {
+		for _, target := range ids {
+			if subject != target {
+				b.add(label(subject)+"-to-"+label(target), subject, target, scopes)
+			}
+		}
+	}
+	return b.policies
+}
@@ -0,0 +1,54 @@
+package policyimport
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+)
+
+// SPIREOptions configures FromSPIRE.
+type SPIREOptions struct {
+	// Targets are the SPIFFE IDs or patterns every registered workload may
+	// exchange for. Registration entries say who a workload is, not whom it
+	// may call, so the targets must be given.
+	Targets []string
+	// Scopes are the allowed scopes of every generated policy.
+	Scopes []string
+	// MaxTTL is the max_ttl of every generated policy.
+	MaxTTL int32
+}
+
+// FromSPIRE converts the registration entries in data, the output of
+// `spire-server entry show -output json`, into one policy per workload and
+// target. Node entries, whose parent is the SPIRE server itself, are
+// skipped, as is a workload's pair with a target it matches itself.
+func FromSPIRE(data []byte, opts SPIREOptions) ([]policy.Policy, error) {
+	entries, err := spiffe.ParseEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse SPIRE entries: %w", err)
+	}
+	b := newBuilder(opts.MaxTTL)
+	for _, e := range entries {
+		if isSPIREServer(e.ParentID) {
+			continue
+		}
+		for _, target := range opts.Targets {
+			if ok, _ := path.Match(target, e.SPIFFEID); ok {
+				continue
+			}
+			b.add(label(e.SPIFFEID)+"-to-"+label(target), e.SPIFFEID, target, opts.Scopes)
+		}
+	}
+	return b.policies, nil
+}
+
+// isSPIREServer reports whether id is the ID of a SPIRE server, the parent
+// of node entries.
+func isSPIREServer(id string) bool {
+	parsed, err := spiffeid.FromString(id)
+	return err == nil && parsed.Path() == "/spire/server"
+}
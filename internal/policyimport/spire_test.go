@@ -0,0 +1,111 @@
+package policyimport
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+const spireEntries = `{
+  "entries": [
+    {
+      "spiffe_id": {"trust_domain": "cluster.local", "path": "/spire/agent/k8s_psat/demo"},
+      "parent_id": {"trust_domain": "cluster.local", "path": "/spire/server"}
+    },
+    {
+      "spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/order"},
+      "parent_id": {"trust_domain": "cluster.local", "path": "/spire/agent/k8s_psat/demo"}
+    },
+    {
+      "spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/payment"},
+      "parent_id": {"trust_domain": "cluster.local", "path": "/spire/agent/k8s_psat/demo"}
+    },
+    {
+      "spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/order"},
+      "parent_id": {"trust_domain": "cluster.local", "path": "/spire/agent/k8s_psat/other"}
+    }
+  ]
+}`
+
+func TestFromSPIRE(t *testing.T) {
+	const (
+		order   = "spiffe://cluster.local/ns/default/sa/order"
+		payment = "spiffe://cluster.local/ns/default/sa/payment"
+		ledger  = "spiffe://cluster.local/ns/ledger/sa/*"
+	)
+	got, err := FromSPIRE([]byte(spireEntries), SPIREOptions{Targets: []string{payment, ledger}, Scopes: []string{"read"}, MaxTTL: 60})
+	if err != nil {
+		t.Fatalf("FromSPIRE: %v", err)
+	}
+	want := []policy.Policy{
+		{Name: "order-to-payment", Subject: order, Target: payment},
+		{Name: "order-to-ledger", Subject: order, Target: ledger},
+		{Name: "payment-to-ledger", Subject: payment, Target: ledger},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d policies, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Name != w.Name || g.Subject != w.Subject || g.Target != w.Target || g.MaxTTL != 60 || !slices.Equal(g.AllowedScopes, []string{"read"}) {
+			t.Errorf("policy %d = %+v, want %+v", i, g, w)
+		}
+	}
+	if _, err := policy.NewLoader(got); err != nil {
+		t.Errorf("generated policies do not load: %v", err)
+	}
+
+	if _, err := FromSPIRE([]byte(`{"entries": [`), SPIREOptions{}); err == nil {
+		t.Error("malformed entries: expected error")
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	ps := []policy.Policy{{Name: "a", Subject: "spiffe://td/a", Target: "spiffe://td/b", AllowedScopes: []string{"read", "write"}, MaxTTL: 60}}
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, "Generated.\n\nReview before use.", ps); err != nil {
+		t.Fatalf("WriteYAML: %v", err)
+	}
+	want := strings.Join([]string{
+		"# Generated.",
+		"#",
+		"# Review before use.",
+		"",
+		"policies:",
+		"  - name: a",
+		"    subject: spiffe://td/a",
+		"    target: spiffe://td/b",
+		"    allowed_scopes:",
+		"      - read",
+		"      - write",
+		"    max_ttl: 60",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("YAML =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestAllPairs(t *testing.T) {
+	const (
+		order   = "spiffe://dev.local/order"
+		payment = "spiffe://dev.local/payment"
+		ledger  = "spiffe://dev.local/ledger"
+	)
+	got := AllPairs([]string{order, payment, ledger}, []string{"all"}, 300)
+	if len(got) != 6 {
+		t.Fatalf("got %d policies, want 6: %+v", len(got), got)
+	}
+	if g := got[0]; g.Name != "order-to-payment" || g.Subject != order || g.Target != payment || g.MaxTTL != 300 {
+		t.Errorf("first policy = %+v", g)
+	}
+	l, err := policy.NewLoader(got)
+	if err != nil {
+		t.Fatalf("generated policies do not load: %v", err)
+	}
+	if r := l.Evaluate(ledger, order, []string{"all"}, 0); !r.Allowed {
+		t.Errorf("ledger → order denied: %+v", r)
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrApprovalNotFound is returned by Approvals.Decide for an id that is not
+// pending: it was never parked, was already decided, timed out, or its
+// caller gave up.
+var ErrApprovalNotFound = errors.New("no pending approval with this id")
+
+// errApprovalTimeout is returned by Approvals.wait when no decision arrives
+// within the timeout.
+var errApprovalTimeout = errors.New("approval timed out")
+
+// PendingApproval is an exchange parked by a requires_approval policy until
+// an approver decides it.
+type PendingApproval struct {
+	// ID identifies the exchange to Decide.
+	ID        string
+	RequestID string
+	Subject   string
+	Target    string
+	// Scopes and TTL are what the token will carry if approved.
+	Scopes      []string
+	TTL         int32
+	PolicyRules []string
+	Requested   time.Time
+	// Expires is when the exchange is denied if still undecided.
+	Expires time.Time
+}
+
+// ApprovalDecision is an approver's verdict on a PendingApproval.
+type ApprovalDecision struct {
+	Approved bool
+	// Approver identifies who decided, for the audit log.
+	Approver string
+	// Reason is recorded in the audit log and, for a denial, returned to
+	// the caller.
+	Reason string
+}
+
+// Approvals parks exchanges that a requires_approval policy grants until an
+// approver decides them or the timeout passes. A zero Approvals is not
+// usable; use NewApprovals.
+type Approvals struct {
+	timeout time.Duration
+	notify  func(PendingApproval)
+
+	mu      sync.Mutex
+	pending map[string]*parkedExchange
+}
+
+// parkedExchange is a PendingApproval and the channel its decision is
+// delivered on.
+type parkedExchange struct {
+	req      PendingApproval
+	decision chan ApprovalDecision
+}
+
+// NewApprovals returns an Approvals that denies an exchange left undecided
+// for timeout. notify, if not nil, is called on its own goroutine with every
+// exchange as it is parked, so that it can alert an approver or call Decide
+// itself.
+func NewApprovals(timeout time.Duration, notify func(PendingApproval)) *Approvals {
+	return &Approvals{timeout: timeout, notify: notify, pending: make(map[string]*parkedExchange)}
+}
+
+// Pending returns the exchanges awaiting a decision, oldest first.
+func (a *Approvals) Pending() []PendingApproval {
+	a.mu.Lock()
+	out := make([]PendingApproval, 0, len(a.pending))
+	for _, p := range a.pending {
+		out = append(out, p.req)
+	}
+	a.mu.Unlock()
+	slices.SortFunc(out, func(x, y PendingApproval) int { return x.Requested.Compare(y.Requested) })
+	return out
+}
+
+// Decide delivers d to the pending exchange id, which is then no longer
+// pending. It returns ErrApprovalNotFound when id is not pending.
+func (a *Approvals) Decide(id string, d ApprovalDecision) error {
+	a.mu.Lock()
+	p, ok := a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+	if !ok {
+		return ErrApprovalNotFound
+	}
+	p.decision <- d
+	return nil
+}
+
+// wait parks req until it is decided, the timeout passes, or ctx is done,
+// and returns the decision. It fails with errApprovalTimeout on timeout and
+// ctx's error when ctx is done first.
+func (a *Approvals) wait(ctx context.Context, req PendingApproval) (ApprovalDecision, error) {
+	req.ID = uuid.New().String()
+	req.Requested = time.Now()
+	req.Expires = req.Requested.Add(a.timeout)
+	p := &parkedExchange{req: req, decision: make(chan ApprovalDecision, 1)}
+	a.mu.Lock()
+	a.pending[req.ID] = p
+	a.mu.Unlock()
+	if a.notify != nil {
+		go a.notify(req)
+	}
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case d := <-p.decision:
+		return d, nil
+	case <-timer.C:
+		err = errApprovalTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	a.mu.Lock()
+	_, stillPending := a.pending[req.ID]
+	delete(a.pending, req.ID)
+	a.mu.Unlock()
+	if !stillPending {
+		// Decide took it as the wait ended; honour the decision.
+		return <-p.decision, nil
+	}
+	return ApprovalDecision{}, err
+}
+
+// SetApprovals parks every exchange granted by a requires_approval policy in
+// a until an approver decides it, then mints the token or fails with
+// PermissionDenied. Both outcomes are audited with the approver. A
+// preflight reports the grant without parking. Without approvals, an
+// exchange such a policy grants fails with FailedPrecondition. It must be
+// called before the server starts handling requests.
+func (s *TokenExchangeServer) SetApprovals(a *Approvals) {
+	s.approvals = a
+}
@@ -0,0 +1,143 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+func TestApprovals(t *testing.T) {
+	p := allowedPolicy([]string{"payments:charge"}, 300)
+	p.result.RequiresApproval = true
+
+	// decideEach decides every exchange as it is parked.
+	decideEach := func(approvals **server.Approvals, d server.ApprovalDecision) func(server.PendingApproval) {
+		return func(pa server.PendingApproval) {
+			if err := (*approvals).Decide(pa.ID, d); err != nil {
+				t.Errorf("Decide: %v", err)
+			}
+		}
+	}
+
+	t.Run("approved exchange is granted", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec)
+		var a *server.Approvals
+		a = server.NewApprovals(time.Minute, decideEach(&a, server.ApprovalDecision{Approved: true, Approver: "spiffe://example.org/admin"}))
+		svc.SetApprovals(a)
+		if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if len(rec.events) != 1 || !rec.events[0].Granted || rec.events[0].Approver != "spiffe://example.org/admin" {
+			t.Errorf("audit events = %+v, want one grant by the approver", rec.events)
+		}
+		if n := len(a.Pending()); n != 0 {
+			t.Errorf("%d approvals still pending", n)
+		}
+	})
+
+	t.Run("denied exchange returns the reason", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec)
+		var a *server.Approvals
+		a = server.NewApprovals(time.Minute, decideEach(&a, server.ApprovalDecision{Approver: "webhook", Reason: "outside change window"}))
+		svc.SetApprovals(a)
+		_, err := svc.Exchange(context.Background(), newValidReq())
+		if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "outside change window") {
+			t.Fatalf("err = %v, want PermissionDenied with the reason", err)
+		}
+		if len(rec.events) != 1 || rec.events[0].Granted || rec.events[0].Approver != "webhook" {
+			t.Errorf("audit events = %+v, want one denial by the webhook", rec.events)
+		}
+	})
+
+	t.Run("undecided exchange times out", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec)
+		a := server.NewApprovals(10*time.Millisecond, nil)
+		svc.SetApprovals(a)
+		_, err := svc.Exchange(context.Background(), newValidReq())
+		if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "no approval decision") {
+			t.Fatalf("err = %v, want PermissionDenied for the timeout", err)
+		}
+		if len(rec.events) != 1 || rec.events[0].Granted {
+			t.Errorf("audit events = %+v, want one denial", rec.events)
+		}
+		if n := len(a.Pending()); n != 0 {
+			t.Errorf("%d approvals still pending after the timeout", n)
+		}
+	})
+
+	t.Run("caller giving up abandons the exchange", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{})
+		a := server.NewApprovals(time.Minute, nil)
+		svc.SetApprovals(a)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := svc.Exchange(ctx, newValidReq()); status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("err = %v, want DeadlineExceeded", err)
+		}
+		if n := len(a.Pending()); n != 0 {
+			t.Errorf("%d approvals still pending after the caller gave up", n)
+		}
+	})
+
+	t.Run("pending exchange is listed until decided", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{})
+		a := server.NewApprovals(time.Minute, nil)
+		svc.SetApprovals(a)
+		done := make(chan error, 1)
+		go func() {
+			_, err := svc.Exchange(context.Background(), newValidReq())
+			done <- err
+		}()
+		var pending []server.PendingApproval
+		for deadline := time.Now().Add(5 * time.Second); len(pending) == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+			pending = a.Pending()
+		}
+		if len(pending) != 1 {
+			t.Fatalf("pending = %+v, want one exchange", pending)
+		}
+		pa := pending[0]
+		if pa.Subject != okExtractor().id || pa.TTL != 300 || len(pa.Scopes) != 1 || !pa.Expires.After(pa.Requested) {
+			t.Errorf("pending = %+v, want the granted exchange", pa)
+		}
+		if err := a.Decide(pa.ID, server.ApprovalDecision{Approved: true}); err != nil {
+			t.Fatalf("Decide: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Exchange: %v", err)
+		}
+		if err := a.Decide(pa.ID, server.ApprovalDecision{Approved: true}); !errors.Is(err, server.ErrApprovalNotFound) {
+			t.Errorf("second Decide = %v, want ErrApprovalNotFound", err)
+		}
+	})
+
+	t.Run("without approvals the exchange fails", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{})
+		if _, err := svc.Exchange(context.Background(), newValidReq()); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("err = %v, want FailedPrecondition", err)
+		}
+	})
+
+	t.Run("preflight is not parked", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{})
+		svc.SetApprovals(server.NewApprovals(time.Minute, func(server.PendingApproval) {
+			t.Error("preflight was parked")
+		}))
+		req := newValidV2Req()
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+		if _, err := svc.V2().Exchange(context.Background(), req); err != nil {
+			t.Errorf("Exchange: %v", err)
+		}
+	})
+}
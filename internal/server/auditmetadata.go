@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// maxAuditMetadataLen bounds each recorded metadata value, as
+// maxRequestIDLen does request IDs, so callers cannot bloat audit lines.
+const maxAuditMetadataLen = 128
+
+// SetAuditMetadataKeys makes the server record the caller's value of each of
+// keys, gRPC metadata keys such as a client build version, in the audit
+// event of every Exchange call, so exchanges can be attributed to a release.
+// Keys must be lowercase. Only the first value of a key is recorded; a value
+// that is longer than 128 bytes or not printable ASCII is dropped. It must be
+// called before the server starts handling requests.
+func (s *TokenExchangeServer) SetAuditMetadataKeys(keys []string) {
+	s.auditMetadataKeys = keys
+}
+
+// auditMetadata returns the caller's values of keys from ctx's incoming
+// metadata, or nil when it sent none of them.
+func auditMetadata(ctx context.Context, keys []string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var out map[string]string
+	for _, k := range keys {
+		vals := md.Get(k)
+		if len(vals) == 0 || !validAuditMetadata(vals[0]) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(keys))
+		}
+		out[k] = vals[0]
+	}
+	return out
+}
+
+// validAuditMetadata accepts values validRequestID would, also allowing
+// spaces.
+func validAuditMetadata(v string) bool {
+	if v == "" || len(v) > maxAuditMetadataLen {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if v[i] < ' ' || v[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
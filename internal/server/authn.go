@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoCredentials is matched, via errors.Is, by IDExtractor errors meaning
+// the request carries no credential of the extractor's kind at all, as
+// opposed to one that failed verification. ExtractorChain only moves on to
+// the next extractor for these.
+var ErrNoCredentials = errors.New("no credentials presented")
+
+// NoCredentials returns an error with text msg that matches
+// ErrNoCredentials. Extractors use it for their "nothing presented"
+// sentinels so that errors.Is works against both.
+func NoCredentials(msg string) error {
+	return noCredentialsError(msg)
+}
+
+type noCredentialsError string
+
+func (e noCredentialsError) Error() string { return string(e) }
+
+func (noCredentialsError) Is(target error) bool { return target == ErrNoCredentials }
+
+// Identity describes an authenticated caller.
+type Identity struct {
+	// ID is the caller's SPIFFE ID.
+	ID string
+	// Method names the authentication method that identified the caller.
+	Method string
+	// Cert is the leaf certificate the caller authenticated with, or nil
+	// for token methods.
+	Cert *x509.Certificate
+	// Pod and PodUID identify the Kubernetes pod the caller's credential is
+	// bound to, as <namespace>/<name>, when the method reports one.
+	Pod    string
+	PodUID string
+}
+
+// IdentityExtractor is implemented by an IDExtractor that can also return
+// the credential behind the ID. Method is left for the caller to fill in.
+type IdentityExtractor interface {
+	ExtractIdentity(ctx context.Context) (Identity, error)
+}
+
+// Authenticator is implemented by an IDExtractor that can also report which
+// authentication method identified the caller. The method is recorded in
+// audit events.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (Identity, error)
+}
+
+// ChainedExtractor is one entry of an ExtractorChain.
+type ChainedExtractor struct {
+	// Method names the authentication method in audit events and errors.
+	Method string
+	IDExtractor
+}
+
+// ExtractorChain is an IDExtractor that tries each extractor in order and
+// returns the first identity found. A later extractor is tried only when an
+// earlier one finds no credential of its kind; a credential that is present
+// but invalid fails the call, so a caller cannot fall back past a bad
+// certificate by adding a token. Reordering the chain lets a deployment
+// migrate between identity mechanisms gradually.
+type ExtractorChain []ChainedExtractor
+
+// ExtractID implements IDExtractor.
+func (c ExtractorChain) ExtractID(ctx context.Context) (string, error) {
+	ident, err := c.Authenticate(ctx)
+	return ident.ID, err
+}
+
+// Authenticate implements Authenticator. When no extractor finds a
+// credential the error matches ErrNoCredentials.
+func (c ExtractorChain) Authenticate(ctx context.Context) (Identity, error) {
+	methods := make([]string, 0, len(c))
+	for _, e := range c {
+		ident, err := extractIdentity(ctx, e.IDExtractor)
+		if err == nil {
+			ident.Method = e.Method
+			return ident, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return Identity{Method: e.Method}, fmt.Errorf("%s: %w", e.Method, err)
+		}
+		methods = append(methods, e.Method)
+	}
+	return Identity{}, fmt.Errorf("%w (tried %s)", ErrNoCredentials, strings.Join(methods, ", "))
+}
+
+// extractIdentity calls e's ExtractIdentity when it has one, falling back
+// to an Identity holding only the ID.
+func extractIdentity(ctx context.Context, e IDExtractor) (Identity, error) {
+	if ie, ok := e.(IdentityExtractor); ok {
+		return ie.ExtractIdentity(ctx)
+	}
+	id, err := e.ExtractID(ctx)
+	return Identity{ID: id}, err
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// countingExtractor is a mockExtractor that counts its calls.
+type countingExtractor struct {
+	id    string
+	err   error
+	calls int
+}
+
+func (c *countingExtractor) ExtractID(context.Context) (string, error) {
+	c.calls++
+	return c.id, c.err
+}
+
+// certExtractor is an IdentityExtractor returning a fixed certificate.
+type certExtractor struct {
+	id   string
+	cert *x509.Certificate
+}
+
+func (c certExtractor) ExtractID(context.Context) (string, error) { return c.id, nil }
+
+func (c certExtractor) ExtractIdentity(context.Context) (Identity, error) {
+	return Identity{ID: c.id, Cert: c.cert}, nil
+}
+
+func TestExtractorChain(t *testing.T) {
+	const (
+		svidID  = "spiffe://cluster.local/ns/default/sa/order"
+		tokenID = "spiffe://cluster.local/ns/default/sa/cart"
+	)
+	var (
+		noCert  = NoCredentials("peer presented no certificates")
+		noToken = NoCredentials("no token in request metadata")
+		badCert = errors.New("certificate contains no SPIFFE SAN URI")
+	)
+
+	tests := []struct {
+		name       string
+		svid       *countingExtractor
+		token      *countingExtractor
+		wantID     string
+		wantMethod string
+		wantNoCred bool // error matches ErrNoCredentials
+		wantErr    bool
+		wantTokens int // calls to the token extractor
+	}{
+		{name: "first method wins", svid: &countingExtractor{id: svidID}, token: &countingExtractor{id: tokenID}, wantID: svidID, wantMethod: "x509-svid"},
+		{name: "falls back when no certificate", svid: &countingExtractor{err: noCert}, token: &countingExtractor{id: tokenID}, wantID: tokenID, wantMethod: "k8s-sa-token", wantTokens: 1},
+		{name: "invalid credential stops the chain", svid: &countingExtractor{err: badCert}, token: &countingExtractor{id: tokenID}, wantMethod: "x509-svid", wantErr: true},
+		{name: "no credentials at all", svid: &countingExtractor{err: noCert}, token: &countingExtractor{err: noToken}, wantErr: true, wantNoCred: true, wantTokens: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ExtractorChain{
+				{Method: "x509-svid", IDExtractor: tc.svid},
+				{Method: "k8s-sa-token", IDExtractor: tc.token},
+			}
+			ident, err := c.Authenticate(context.Background())
+			if (err != nil) != tc.wantErr || ident.ID != tc.wantID || ident.Method != tc.wantMethod {
+				t.Errorf("Authenticate() = %+v, %v; want %q, %q, error %v", ident, err, tc.wantID, tc.wantMethod, tc.wantErr)
+			}
+			if got := errors.Is(err, ErrNoCredentials); got != tc.wantNoCred {
+				t.Errorf("errors.Is(err, ErrNoCredentials) = %v, want %v", got, tc.wantNoCred)
+			}
+			if tc.token.calls != tc.wantTokens {
+				t.Errorf("token extractor calls = %d, want %d", tc.token.calls, tc.wantTokens)
+			}
+		})
+	}
+
+	t.Run("identity extractor keeps its certificate", func(t *testing.T) {
+		leaf := &x509.Certificate{SerialNumber: big.NewInt(42)}
+		c := ExtractorChain{{Method: "x509-svid", IDExtractor: certExtractor{id: svidID, cert: leaf}}}
+		ident, err := c.Authenticate(context.Background())
+		if err != nil || ident.ID != svidID || ident.Method != "x509-svid" || ident.Cert != leaf {
+			t.Errorf("Authenticate() = %+v, %v; want %q with its certificate", ident, err, svidID)
+		}
+	})
+
+	t.Run("sentinels stay distinct", func(t *testing.T) {
+		if !errors.Is(noCert, noCert) || errors.Is(noCert, noToken) {
+			t.Error("NoCredentials sentinels should match themselves and not each other")
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPushbackTrailer is the gRPC trailer through which a denied Exchange
+// tells retrying clients how long to wait, in milliseconds. gRPC clients with
+// a retry policy honour it natively.
+const RetryPushbackTrailer = "grpc-retry-pushback-ms"
+
+// denialKey identifies a denied request. The requested TTL never changes
+// whether a request is allowed, so it is not part of the key.
+type denialKey struct {
+	subject, target, scopes string
+}
+
+// denialEntry tracks the consecutive denials of one request.
+type denialEntry struct {
+	count      int       // consecutive denials, including cached ones
+	until      time.Time // served from the cache until then
+	suppressed int       // cached denials not yet audited
+}
+
+// denialCache serves repeated denials of the same request without
+// re-evaluating policy. Each denial doubles how long the next identical
+// request is refused outright, from base up to maxDelay; the wait is
+// returned to the caller as a retry hint. A request that has not been denied
+// for maxDelay after its hold expired starts again from base. maxEntries
+// bounds the map size; when the cap is reached new denials are not cached.
+type denialCache struct {
+	mu             sync.Mutex
+	entries        map[denialKey]*denialEntry
+	base, maxDelay time.Duration
+	maxEntries     int
+	now            func() time.Time
+}
+
+func newDenialCache(base, maxDelay time.Duration, maxEntries int) *denialCache {
+	return &denialCache{
+		entries:    make(map[denialKey]*denialEntry),
+		base:       base,
+		maxDelay:   maxDelay,
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func newDenialKey(subject, target string, scopes []string) denialKey {
+	return denialKey{subject: subject, target: target, scopes: strings.Join(scopes, "\x00")}
+}
+
+// cached reports whether k is still held after an earlier denial and, if so,
+// how long the caller should wait. A cached denial is counted towards the
+// next audited one.
+func (c *denialCache) cached(k denialKey) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return 0, false
+	}
+	wait := e.until.Sub(c.now())
+	if wait <= 0 {
+		return 0, false
+	}
+	e.count++
+	e.suppressed++
+	return wait, true
+}
+
+// deny records an evaluated denial of k and returns how long the caller
+// should wait before retrying, along with the number of cached denials
+// served since the last one was audited.
+func (c *denialCache) deny(k denialKey) (wait time.Duration, suppressed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	e, ok := c.entries[k]
+	if ok && now.Sub(e.until) > c.maxDelay {
+		e.count = 0
+	}
+	if !ok {
+		if len(c.entries) >= c.maxEntries {
+			c.sweep(now)
+		}
+		if len(c.entries) >= c.maxEntries {
+			return c.base, 0
+		}
+		e = &denialEntry{}
+		c.entries[k] = e
+	}
+	e.count++
+	wait = c.base
+	for i := 1; i < e.count && wait < c.maxDelay; i++ {
+		wait *= 2
+	}
+	wait = min(wait, c.maxDelay)
+	e.until = now.Add(wait)
+	suppressed, e.suppressed = e.suppressed, 0
+	return wait, suppressed
+}
+
+// reset drops every entry, so that a policy change granting a cached request
+// takes effect immediately.
+func (c *denialCache) reset() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// sweep removes entries whose hold has expired and whose count would reset.
+// Must be called with c.mu held.
+func (c *denialCache) sweep(now time.Time) {
+	for k, e := range c.entries {
+		if now.Sub(e.until) > c.maxDelay {
+			delete(c.entries, k)
+		}
+	}
+}
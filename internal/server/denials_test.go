@@ -0,0 +1,84 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDenialCache(t *testing.T) {
+	k := newDenialKey("spiffe://td/order", "spiffe://td/payment", []string{"payments:charge"})
+	setup := func(maxEntries int) (*denialCache, *time.Time) {
+		c := newDenialCache(time.Second, 8*time.Second, maxEntries)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		return c, &now
+	}
+
+	t.Run("hold doubles with each denial up to the maximum", func(t *testing.T) {
+		c, now := setup(10)
+		for _, want := range []time.Duration{1, 2, 4, 8, 8} {
+			wait, _ := c.deny(k)
+			if wait != want*time.Second {
+				t.Fatalf("wait = %v, want %v", wait, want*time.Second)
+			}
+			*now = now.Add(wait)
+		}
+	})
+
+	t.Run("repeats within the hold are cached and counted", func(t *testing.T) {
+		c, now := setup(10)
+		c.deny(k)
+		for range 3 {
+			if wait, ok := c.cached(k); !ok || wait != time.Second {
+				t.Fatalf("cached() = %v, %v; want 1s, true", wait, ok)
+			}
+		}
+		*now = now.Add(time.Second)
+		if _, ok := c.cached(k); ok {
+			t.Fatal("cached() = true after the hold expired")
+		}
+		// The cached repeats count towards the backoff.
+		if wait, suppressed := c.deny(k); wait != 8*time.Second || suppressed != 3 {
+			t.Errorf("deny() = %v, %d; want 8s, 3", wait, suppressed)
+		}
+	})
+
+	t.Run("a quiet request starts over", func(t *testing.T) {
+		c, now := setup(10)
+		c.deny(k)
+		c.deny(k)
+		*now = now.Add(time.Minute)
+		if wait, _ := c.deny(k); wait != time.Second {
+			t.Errorf("wait = %v, want 1s", wait)
+		}
+	})
+
+	t.Run("other requests are not held", func(t *testing.T) {
+		c, _ := setup(10)
+		c.deny(k)
+		if _, ok := c.cached(newDenialKey("spiffe://td/order", "spiffe://td/payment", []string{"payments:refund"})); ok {
+			t.Error("a request for other scopes was held")
+		}
+	})
+
+	t.Run("reset forgets denials", func(t *testing.T) {
+		c, _ := setup(10)
+		c.deny(k)
+		c.reset()
+		if _, ok := c.cached(k); ok {
+			t.Error("cached() = true after reset")
+		}
+	})
+
+	t.Run("full cache stops recording", func(t *testing.T) {
+		c, _ := setup(1)
+		c.deny(k)
+		other := newDenialKey("spiffe://td/other", "spiffe://td/payment", []string{"payments:charge"})
+		if wait, _ := c.deny(other); wait != time.Second {
+			t.Errorf("wait = %v, want the base hold as a hint", wait)
+		}
+		if _, ok := c.cached(other); ok {
+			t.Error("denial recorded past the cap")
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// GrantStore records the tokens a server issues, keyed by subject, so that a
+// caller can list and revoke its own. SaveRevocation persists a revocation
+// made through RevokeGrant, as the admin RevokeToken RPC does.
+type GrantStore interface {
+	SaveGrant(subject string, g policy.GrantRecord) error
+	ListGrants(subject string, now int64) ([]policy.GrantRecord, error)
+	Grant(subject, jti string) (policy.GrantRecord, bool, error)
+	DeleteGrant(subject, jti string) error
+	SaveRevocation(jti string, expiresAt int64) error
+}
+
+// SetGrantStore makes the server record every token it issues in g, enabling
+// the v2 ListGrants and RevokeGrant RPCs. A failure to record a grant fails
+// the exchange with Internal. A nil g disables tracking. It must be called
+// before the server starts handling requests.
+func (s *TokenExchangeServer) SetGrantStore(g GrantStore) {
+	s.grants = g
+}
+
+// ListGrants lists the caller's unexpired tokens, leaving out any revoked
+// since they were issued.
+func (v *v2Server) ListGrants(ctx context.Context, _ *exchangev2.ListGrantsRequest) (*exchangev2.ListGrantsResponse, error) {
+	subject, err := v.grantCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := v.s.grants.ListGrants(subject, v.s.now().Unix())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	resp := &exchangev2.ListGrantsResponse{}
+	for _, g := range records {
+		if v.s.IsRevoked(g.JTI) {
+			continue
+		}
+		resp.Grants = append(resp.Grants, &exchangev2.Grant{
+			TokenId:       g.JTI,
+			TargetService: g.Target,
+			Scopes:        g.Scopes,
+			ExpiresAt:     g.ExpiresAt,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeGrant revokes one of the caller's own tokens. The revocation is
+// persisted and applied immediately, exactly as the admin RevokeToken RPC
+// does, and the grant record is removed.
+func (v *v2Server) RevokeGrant(ctx context.Context, req *exchangev2.RevokeGrantRequest) (*exchangev2.RevokeGrantResponse, error) {
+	v.s.calls.start()
+	defer v.s.calls.done()
+	subject, err := v.grantCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.TokenId == "" {
+		return nil, status.Error(codes.InvalidArgument, "token_id is required")
+	}
+	g, ok, err := v.s.grants.Grant(subject, req.TokenId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	// Another subject's token is reported exactly like an unknown one, so
+	// RevokeGrant cannot be used to probe for token IDs.
+	if !ok || g.ExpiresAt <= v.s.now().Unix() {
+		return nil, status.Errorf(codes.NotFound, "no active token %q issued to %s", req.TokenId, v.s.redact.ID(subject))
+	}
+	if err := v.s.grants.SaveRevocation(g.JTI, g.ExpiresAt); err != nil {
+		return nil, status.Errorf(codes.Internal, "save revocation: %v", err)
+	}
+	if !v.s.Revoke(g.JTI, time.Unix(g.ExpiresAt, 0)) {
+		return nil, status.Error(codes.ResourceExhausted, "revocation list is full; token was persisted but not applied in-memory — restart the server to rebuild the list")
+	}
+	if err := v.s.grants.DeleteGrant(subject, g.JTI); err != nil {
+		return nil, status.Errorf(codes.Internal, "delete grant: %v", err)
+	}
+	return &exchangev2.RevokeGrantResponse{}, nil
+}
+
+// grantCaller authenticates the caller of a grant RPC and returns its
+// canonical SPIFFE ID, under which Exchange saved its grants. It fails with
+// FailedPrecondition when grant tracking is disabled.
+func (v *v2Server) grantCaller(ctx context.Context) (string, error) {
+	if v.s.grants == nil {
+		return "", status.Error(codes.FailedPrecondition, "grant tracking is not enabled on this server")
+	}
+	caller, err := v.s.authenticate(ctx)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "extract SPIFFE ID: %v", err)
+	}
+	id, err := policy.NormalizeSPIFFEID(caller.ID)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "invalid caller SPIFFE ID: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+func TestGrants(t *testing.T) {
+	store, err := policy.OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	newSvc := func(subject string) (*server.TokenExchangeServer, exchangev2.TokenExchangeServer) {
+		svc := server.New(mockExtractor{id: subject}, allowedPolicy([]string{"payments:charge"}, 300), okMinter(), mockAudit{})
+		svc.SetGrantStore(store)
+		return svc, svc.V2()
+	}
+	orderSvc, order := newSvc("spiffe://cluster.local/ns/default/sa/order")
+	_, billing := newSvc("spiffe://cluster.local/ns/default/sa/billing")
+	ctx := context.Background()
+
+	if _, err := order.Exchange(ctx, newValidV2Req()); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	t.Run("caller lists its own grants", func(t *testing.T) {
+		resp, err := order.ListGrants(ctx, &exchangev2.ListGrantsRequest{})
+		if err != nil {
+			t.Fatalf("ListGrants: %v", err)
+		}
+		if len(resp.Grants) != 1 {
+			t.Fatalf("grants = %+v, want one", resp.Grants)
+		}
+		g := resp.Grants[0]
+		if g.TokenId != "test-jti" || g.TargetService != newValidV2Req().TargetService || len(g.Scopes) != 1 || g.ExpiresAt == 0 {
+			t.Errorf("grant = %+v", g)
+		}
+
+		resp, err = billing.ListGrants(ctx, &exchangev2.ListGrantsRequest{})
+		if err != nil || len(resp.Grants) != 0 {
+			t.Errorf("another subject's ListGrants = %+v, %v; want none", resp, err)
+		}
+	})
+
+	t.Run("mixed-case caller ID lists the same grants", func(t *testing.T) {
+		_, mixed := newSvc("SPIFFE://Cluster.Local/ns/default/sa/order")
+		resp, err := mixed.ListGrants(ctx, &exchangev2.ListGrantsRequest{})
+		if err != nil || len(resp.Grants) != 1 {
+			t.Errorf("ListGrants = %+v, %v; want the order grant", resp, err)
+		}
+	})
+
+	t.Run("another subject's token is not found", func(t *testing.T) {
+		_, err := billing.RevokeGrant(ctx, &exchangev2.RevokeGrantRequest{TokenId: "test-jti"})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("code = %v, want NotFound", status.Code(err))
+		}
+	})
+
+	t.Run("caller revokes its own token", func(t *testing.T) {
+		if _, err := order.RevokeGrant(ctx, &exchangev2.RevokeGrantRequest{TokenId: "test-jti"}); err != nil {
+			t.Fatalf("RevokeGrant: %v", err)
+		}
+		if !orderSvc.IsRevoked("test-jti") {
+			t.Error("token not revoked in memory")
+		}
+		revs, err := store.ListRevocations()
+		if err != nil || len(revs) != 1 || revs[0].JTI != "test-jti" {
+			t.Errorf("persisted revocations = %+v, %v; want test-jti", revs, err)
+		}
+		resp, err := order.ListGrants(ctx, &exchangev2.ListGrantsRequest{})
+		if err != nil || len(resp.Grants) != 0 {
+			t.Errorf("ListGrants after revoke = %+v, %v; want none", resp, err)
+		}
+	})
+
+	t.Run("missing token ID", func(t *testing.T) {
+		_, err := order.RevokeGrant(ctx, &exchangev2.RevokeGrantRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+		}
+	})
+
+	t.Run("tracking disabled", func(t *testing.T) {
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), mockAudit{}).V2()
+		if _, err := svc.ListGrants(ctx, &exchangev2.ListGrantsRequest{}); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("ListGrants code = %v, want FailedPrecondition", status.Code(err))
+		}
+		if _, err := svc.RevokeGrant(ctx, &exchangev2.RevokeGrantRequest{TokenId: "test-jti"}); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("RevokeGrant code = %v, want FailedPrecondition", status.Code(err))
+		}
+	})
+}
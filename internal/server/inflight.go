@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// inflight counts calls in progress. The zero value is ready to use.
+type inflight struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops back to zero; nil while it is zero
+}
+
+// start records that a call has begun. done must be called when it ends.
+func (f *inflight) start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.n == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.n++
+}
+
+// done records that a call has ended.
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.n == 0 {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// count returns the number of calls in progress.
+func (f *inflight) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+// InFlight returns the number of Exchange and RevokeGrant calls in progress,
+// over every transport.
+func (s *TokenExchangeServer) InFlight() int {
+	return s.calls.count()
+}
+
+// Wait blocks until no Exchange or RevokeGrant call is in progress, so that
+// their audit events, grant records, and revocations have been written, or
+// until ctx is done. Calls started while waiting are waited for too. Callers
+// served in-process, such as the REST gateway, are not drained by stopping
+// the gRPC server, so shutdown calls Wait before closing the stores those
+// calls write to.
+func (s *TokenExchangeServer) Wait(ctx context.Context) error {
+	for {
+		s.calls.mu.Lock()
+		idle := s.calls.idle
+		s.calls.mu.Unlock()
+		if idle == nil {
+			return nil
+		}
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return fmt.Errorf("%d calls still in flight: %w", s.calls.count(), ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+func TestWait(t *testing.T) {
+	svc := server.New(okExtractor(), mockPolicy{block: true}, okMinter(), &recordingAudit{})
+	if err := svc.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait with nothing in flight: %v", err)
+	}
+
+	callCtx, endCall := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_, _ = svc.Exchange(callCtx, newValidReq())
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.InFlight() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Exchange never counted as in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := svc.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait with an exchange in flight = %v, want DeadlineExceeded", err)
+	}
+
+	endCall()
+	if err := svc.Wait(context.Background()); err != nil {
+		t.Errorf("Wait after the exchange ended: %v", err)
+	}
+	<-done
+	if n := svc.InFlight(); n != 0 {
+		t.Errorf("InFlight = %d after the exchange ended, want 0", n)
+	}
+}
@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// mockNonces is an in-memory NonceStore that ignores expiry.
+type mockNonces struct {
+	used map[string]bool
+	err  error
+}
+
+func (m *mockNonces) UseNonce(subject, nonce string, _ int64) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if m.used == nil {
+		m.used = make(map[string]bool)
+	}
+	k := subject + "\x00" + nonce
+	if m.used[k] {
+		return false, nil
+	}
+	m.used[k] = true
+	return true, nil
+}
+
+func TestRequestNonce(t *testing.T) {
+	const nonce = "c2VjcmV0LW5vbmNlLTAx"
+	p := allowedPolicy([]string{"payments:charge"}, 300)
+	strict := allowedPolicy([]string{"payments:charge"}, 300)
+	strict.result.RequireNonce = true
+
+	tests := []struct {
+		name       string
+		policy     server.PolicyEvaluator
+		nonces     server.NonceStore
+		nonce      string
+		wantCode   codes.Code
+		wantDenial string // substring of the audited denial reason
+	}{
+		{name: "no nonce when none is required", policy: p, nonces: &mockNonces{}, wantCode: codes.OK},
+		{name: "fresh nonce is accepted", policy: p, nonces: &mockNonces{}, nonce: nonce, wantCode: codes.OK},
+		{name: "required nonce is accepted", policy: strict, nonces: &mockNonces{}, nonce: nonce, wantCode: codes.OK},
+		{name: "missing required nonce is denied", policy: strict, nonces: &mockNonces{}, wantCode: codes.PermissionDenied, wantDenial: "requires a request nonce"},
+		{name: "used nonce is denied", policy: p, nonces: &mockNonces{used: map[string]bool{okExtractor().id + "\x00" + nonce: true}}, nonce: nonce, wantCode: codes.PermissionDenied, wantDenial: "already used"},
+		{name: "short nonce is rejected", policy: p, nonces: &mockNonces{}, nonce: "abc", wantCode: codes.InvalidArgument},
+		{name: "nonce outside the alphabet is rejected", policy: p, nonces: &mockNonces{}, nonce: "not/a+base64url=nonce", wantCode: codes.InvalidArgument},
+		{name: "nonce without a store fails", policy: p, nonce: nonce, wantCode: codes.FailedPrecondition},
+		{name: "store failure returns Internal", policy: p, nonces: &mockNonces{err: errors.New("disk full")}, nonce: nonce, wantCode: codes.Internal},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(okExtractor(), tc.policy, okMinter(), rec)
+			svc.SetNonceStore(tc.nonces, time.Minute)
+			req := newValidReq()
+			req.Nonce = tc.nonce
+			_, err := svc.Exchange(context.Background(), req)
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			if tc.wantDenial != "" && (len(rec.events) != 1 || rec.events[0].Granted || !strings.Contains(rec.events[0].DenialReason, tc.wantDenial)) {
+				t.Errorf("audit events = %+v, want one denial for %q", rec.events, tc.wantDenial)
+			}
+		})
+	}
+
+	t.Run("replayed request is refused", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{})
+		svc.SetNonceStore(&mockNonces{}, time.Minute)
+		req := newValidReq()
+		req.Nonce = nonce
+		if _, err := svc.Exchange(context.Background(), req); err != nil {
+			t.Fatalf("first Exchange: %v", err)
+		}
+		if _, err := svc.Exchange(context.Background(), req); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("replay code = %v, want PermissionDenied", status.Code(err))
+		}
+	})
+
+	t.Run("preflight checks but does not use the nonce", func(t *testing.T) {
+		svc := server.New(okExtractor(), strict, okMinter(), mockAudit{})
+		svc.SetNonceStore(&mockNonces{}, time.Minute)
+		v2 := svc.V2()
+		req := newValidV2Req()
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+		if _, err := v2.Exchange(context.Background(), req); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("preflight without nonce code = %v, want PermissionDenied", status.Code(err))
+		}
+		req.Nonce = nonce
+		if _, err := v2.Exchange(context.Background(), req); err != nil {
+			t.Fatalf("preflight: %v", err)
+		}
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_FULL
+		if _, err := v2.Exchange(context.Background(), req); err != nil {
+			t.Errorf("exchange after preflight: %v", err)
+		}
+	})
+}
@@ -13,10 +13,11 @@ type jtiCache struct {
 	mu         sync.Mutex
 	entries    map[string]time.Time // JTI → expiry
 	maxEntries int
+	now        func() time.Time
 }
 
 func newJTICache(maxEntries int) *jtiCache {
-	return &jtiCache{entries: make(map[string]time.Time), maxEntries: maxEntries}
+	return &jtiCache{entries: make(map[string]time.Time), maxEntries: maxEntries, now: time.Now}
 }
 
 // alreadyIssued records jti with the given expiry and returns false if jti is new.
@@ -25,7 +26,7 @@ func (c *jtiCache) alreadyIssued(jti string, expiry time.Time) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.sweep()
-	if exp, ok := c.entries[jti]; ok && time.Now().Before(exp) {
+	if exp, ok := c.entries[jti]; ok && c.now().Before(exp) {
 		return true
 	}
 	if len(c.entries) >= c.maxEntries {
@@ -39,7 +40,7 @@ func (c *jtiCache) alreadyIssued(jti string, expiry time.Time) bool {
 
 // sweep removes expired entries. Must be called with c.mu held.
 func (c *jtiCache) sweep() {
-	now := time.Now()
+	now := c.now()
 	for jti, exp := range c.entries {
 		if now.After(exp) {
 			delete(c.entries, jti)
@@ -58,10 +59,11 @@ type revocationList struct {
 	mu         sync.Mutex
 	set        map[string]time.Time // JTI → token expiry
 	maxEntries int
+	now        func() time.Time
 }
 
 func newRevocationList(maxEntries int) *revocationList {
-	return &revocationList{set: make(map[string]time.Time), maxEntries: maxEntries}
+	return &revocationList{set: make(map[string]time.Time), maxEntries: maxEntries, now: time.Now}
 }
 
 // Revoke adds jti to the revocation list with its natural token expiry.
@@ -89,7 +91,7 @@ func (r *revocationList) isRevoked(jti string) bool {
 
 // sweep removes entries whose token expiry has passed. Must be called with r.mu held.
 func (r *revocationList) sweep() {
-	now := time.Now()
+	now := r.now()
 	for jti, exp := range r.set {
 		if now.After(exp) {
 			delete(r.set, jti)
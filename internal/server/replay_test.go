@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
 )
 
 func TestRevocationList(t *testing.T) {
@@ -24,12 +26,14 @@ func TestRevocationList(t *testing.T) {
 	})
 
 	t.Run("expired entry is evicted and no longer reported as revoked", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
 		r := newRevocationList(5_000)
-		r.Revoke("jti-expired", time.Now().Add(50*time.Millisecond))
+		r.now = fake.Now
+		r.Revoke("jti-expired", fake.Now().Add(50*time.Millisecond))
 		if !r.isRevoked("jti-expired") {
 			t.Fatal("expected entry to be revoked before expiry")
 		}
-		time.Sleep(100 * time.Millisecond)
+		fake.Advance(100 * time.Millisecond)
 		if r.isRevoked("jti-expired") {
 			t.Error("expected expired entry to be evicted")
 		}
@@ -39,10 +43,12 @@ func TestRevocationList(t *testing.T) {
 	})
 
 	t.Run("non-expired entries survive a sweep", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
 		r := newRevocationList(5_000)
-		r.Revoke("jti-keep", time.Now().Add(time.Minute))
-		r.Revoke("jti-expire", time.Now().Add(50*time.Millisecond))
-		time.Sleep(100 * time.Millisecond)
+		r.now = fake.Now
+		r.Revoke("jti-keep", fake.Now().Add(time.Minute))
+		r.Revoke("jti-expire", fake.Now().Add(50*time.Millisecond))
+		fake.Advance(100 * time.Millisecond)
 		// trigger sweep via isRevoked
 		r.isRevoked("any")
 		if !r.isRevoked("jti-keep") {
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the gRPC metadata key carrying the request ID. A caller
+// may set it to correlate its own logs with the server's; the server echoes
+// the ID it used in the response header of the same name.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLen bounds caller-supplied IDs so they cannot bloat audit lines.
+const maxRequestIDLen = 128
+
+// requestID returns the caller-supplied request ID from ctx's incoming
+// metadata when it is well-formed, otherwise a fresh UUID.
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(RequestIDHeader); len(vals) > 0 && validRequestID(vals[0]) {
+		return vals[0]
+	}
+	return uuid.New().String()
+}
+
+// validRequestID accepts non-empty IDs of at most maxRequestIDLen printable,
+// non-space ASCII characters, so an ID can be embedded in error messages and
+// log lines verbatim.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming []string // x-request-id values sent by the caller
+		wantEcho bool
+	}{
+		{name: "caller-supplied ID is kept", incoming: []string{"trace-abc-123"}, wantEcho: true},
+		{name: "no metadata generates an ID"},
+		{name: "empty ID is replaced", incoming: []string{""}},
+		{name: "ID with whitespace is replaced", incoming: []string{"bad id"}},
+		{name: "ID with control characters is replaced", incoming: []string{"bad\nid"}},
+		{name: "overlong ID is replaced", incoming: []string{strings.Repeat("a", maxRequestIDLen+1)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.incoming != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDHeader, tc.incoming[0]))
+			}
+			got := requestID(ctx)
+			if tc.wantEcho && got != tc.incoming[0] {
+				t.Errorf("requestID = %q, want caller's %q", got, tc.incoming[0])
+			}
+			if !tc.wantEcho {
+				if len(tc.incoming) > 0 && got == tc.incoming[0] {
+					t.Errorf("requestID kept invalid caller ID %q", got)
+				}
+				if !validRequestID(got) {
+					t.Errorf("generated ID %q is not valid", got)
+				}
+			}
+		})
+	}
+}
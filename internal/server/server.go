@@ -3,23 +3,48 @@ package server
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"crypto"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/clock"
 	"github.com/ngaddam369/svid-exchange/internal/policy"
 	"github.com/ngaddam369/svid-exchange/internal/token"
 	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
 )
 
-// maxScopes is the maximum number of scopes a single exchange request may carry.
-// Requests exceeding this are rejected with InvalidArgument before any policy
-// evaluation runs, bounding the cost of scope intersection for malformed inputs.
-const maxScopes = 50
+// Limits bounds the shape of an ExchangeRequest. Requests exceeding any
+// limit are rejected with InvalidArgument before any policy evaluation runs,
+// bounding the cost of scope intersection and the size of audit entries for
+// malformed or abusive inputs. Lengths are in bytes.
+type Limits struct {
+	MaxScopes       int
+	MaxScopeLength  int
+	MaxTargetLength int
+}
+
+// DefaultLimits are the limits a server applies unless SetLimits overrides
+// them. MaxTargetLength matches the SPIFFE ID length limit.
+var DefaultLimits = Limits{MaxScopes: 50, MaxScopeLength: 256, MaxTargetLength: 2048}
+
+// PolicyRuleHeader is the gRPC response metadata key naming the policy rules
+// that authorized a granted exchange, one value per rule, so a caller can
+// trace its token back to the configuration that allowed it.
+const PolicyRuleHeader = "x-policy-rule"
 
 // IDExtractor extracts the caller's SPIFFE ID from the request context.
 type IDExtractor interface {
@@ -27,16 +52,24 @@ type IDExtractor interface {
 }
 
 // PolicyEvaluator evaluates whether an exchange is permitted and returns the
-// granted scopes and TTL.
+// granted scopes and TTL. A denial is a result with Allowed false; an error
+// means the evaluator could not decide, e.g. because ctx expired while a
+// remote policy engine was consulted.
 type PolicyEvaluator interface {
-	Evaluate(subject, target string, scopes []string, ttlSeconds int32) policy.EvalResult
+	Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error)
 }
 
 // TokenMinter mints a signed JWT for an authorised exchange and exposes the
-// active public keys so that on_behalf_of tokens can be verified.
+// active public keys so that on_behalf_of tokens can be verified. Mint must
+// return promptly once ctx is done.
 type TokenMinter interface {
-	Mint(subject, target string, scopes []string, ttlSeconds int32, actSubject string) (token.MintResult, error)
-	PublicKeys() []*ecdsa.PublicKey
+	Mint(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (token.MintResult, error)
+	PublicKeys() []crypto.PublicKey
+}
+
+// ReceiptSigner signs decision receipts; see token.Receipt.
+type ReceiptSigner interface {
+	SignReceipt(ctx context.Context, r token.Receipt) (string, error)
 }
 
 // AuditLogger records exchange events for the audit trail.
@@ -44,15 +77,128 @@ type AuditLogger interface {
 	LogExchange(e audit.ExchangeEvent)
 }
 
+// DurableAuditLogger is an AuditLogger that reports whether an event was
+// durably recorded; see SetAuditRequired.
+type DurableAuditLogger interface {
+	AuditLogger
+	RecordExchange(e audit.ExchangeEvent) error
+}
+
+// TokenQuota records outstanding tokens per subject and target. ReserveToken
+// records jti until expiresAt (a Unix timestamp) and reports false, without
+// recording it, when subject already holds limit unexpired tokens for target.
+// ReleaseToken removes the record of a reserved token that was not issued.
+type TokenQuota interface {
+	ReserveToken(subject, target, jti string, expiresAt int64, limit int) (bool, error)
+	ReleaseToken(subject, target, jti string) error
+}
+
 // TokenExchangeServer implements the exchangev1.TokenExchangeServer interface.
 type TokenExchangeServer struct {
 	exchangev1.UnimplementedTokenExchangeServer
 	extractor IDExtractor
 	policy    PolicyEvaluator
 	minter    TokenMinter
+	formats   map[string]TokenMinter
 	audit     AuditLogger
 	cache     *jtiCache
 	revoked   *revocationList
+	calls     inflight // Exchange and RevokeGrant calls; see Wait
+
+	// evalTimeout and mintTimeout bound each stage independently of the
+	// caller's deadline. Zero means the stage is bounded only by the caller.
+	evalTimeout time.Duration
+	mintTimeout time.Duration
+
+	// quota caps outstanding tokens per subject and target at quotaLimit.
+	// Nil disables the cap.
+	quota      TokenQuota
+	quotaLimit int
+
+	limits Limits
+
+	// denials serves repeated denials without evaluating policy. Nil
+	// disables it.
+	denials *denialCache
+
+	// grants records issued tokens for ListGrants and RevokeGrant. Nil
+	// disables tracking.
+	grants GrantStore
+
+	// receipts signs the decision receipts callers ask for. Nil disables
+	// them.
+	receipts ReceiptSigner
+
+	// observeLatency receives the stage timings of every exchange. Nil
+	// disables it.
+	observeLatency func(ExchangeLatency)
+
+	// nonces records request nonces for nonceWindow. Nil disables them.
+	nonces      NonceStore
+	nonceWindow time.Duration
+
+	// targets checks granted targets against a workload registry. Nil
+	// disables the check.
+	targets TargetValidator
+
+	// approvals parks exchanges that requires_approval policies grant. Nil
+	// fails them instead.
+	approvals *Approvals
+
+	// redact rewrites SPIFFE IDs in error messages. Nil leaves them as is.
+	redact *audit.Redactor
+
+	// maxTTL caps every granted TTL, in seconds, whatever the policy
+	// allows. Zero leaves the policy's max_ttl as the only cap.
+	maxTTL int32
+
+	// deadlineTTL caps every requested TTL to the time left before the
+	// Exchange call's deadline; see SetDeadlineTTL.
+	deadlineTTL bool
+
+	// bindSource binds minted tokens to the caller's network identity; see
+	// SetSourceBinding.
+	bindSource bool
+
+	// auditMetadataKeys are the gRPC metadata keys recorded in audit
+	// events; see SetAuditMetadataKeys.
+	auditMetadataKeys []string
+
+	// auditRequired withholds tokens whose grant was not durably audited;
+	// see SetAuditRequired.
+	auditRequired atomic.Bool
+
+	// now is the time source for nonce, grant, replay, revocation, and
+	// denial expiry; see SetClock. Stage latencies always use the system
+	// clock.
+	now func() time.Time
+}
+
+// ExchangeLatency is the timing of one exchange, by stage.
+type ExchangeLatency struct {
+	RequestID string
+	// Subject is empty when the caller was not authenticated.
+	Subject string
+	Target  string
+	// Code is the status code the exchange returned.
+	Code   codes.Code
+	Stages StageTimings
+}
+
+// StageTimings breaks an exchange's duration down by stage. A stage the
+// exchange did not reach is zero.
+type StageTimings struct {
+	// Extract is authenticating the caller.
+	Extract time.Duration
+	// Evaluate is policy evaluation.
+	Evaluate time.Duration
+	// Mint is minting the token and, when one was requested, signing its
+	// decision receipt: the calls a remote signer serves.
+	Mint time.Duration
+	// Audit is the sum of the exchange's audit log writes.
+	Audit time.Duration
+	// Total is the whole exchange, including the stages above.
+	Total time.Duration
 }
 
 // New creates a TokenExchangeServer from its dependencies.
@@ -61,10 +207,193 @@ func New(e IDExtractor, p PolicyEvaluator, m TokenMinter, a AuditLogger) *TokenE
 		extractor: e,
 		policy:    p,
 		minter:    m,
+		formats:   map[string]TokenMinter{policy.FormatJWT: m},
 		audit:     a,
 		cache:     newJTICache(10_000),
 		revoked:   newRevocationList(5_000),
+		limits:    DefaultLimits,
+		now:       time.Now,
+	}
+}
+
+// RegisterFormat makes m the minter for policies whose token_format is
+// format. The minter passed to New serves policy.FormatJWT. It must be called
+// before the server starts handling requests.
+func (s *TokenExchangeServer) RegisterFormat(format string, m TokenMinter) {
+	s.formats[format] = m
+}
+
+// SetStageTimeouts bounds policy evaluation and minting to evaluate and mint
+// respectively, on top of any deadline the caller set. A stage that runs out
+// of time fails the exchange with DeadlineExceeded. Zero leaves a stage
+// bounded only by the caller's deadline. It must be called before the server
+// starts handling requests.
+func (s *TokenExchangeServer) SetStageTimeouts(evaluate, mint time.Duration) {
+	s.evalTimeout = evaluate
+	s.mintTimeout = mint
+}
+
+// SetLimits replaces the request limits. A zero field keeps its value from
+// DefaultLimits. It must be called before the server starts handling
+// requests.
+func (s *TokenExchangeServer) SetLimits(l Limits) {
+	if l.MaxScopes <= 0 {
+		l.MaxScopes = DefaultLimits.MaxScopes
+	}
+	if l.MaxScopeLength <= 0 {
+		l.MaxScopeLength = DefaultLimits.MaxScopeLength
+	}
+	if l.MaxTargetLength <= 0 {
+		l.MaxTargetLength = DefaultLimits.MaxTargetLength
+	}
+	s.limits = l
+}
+
+// SetTokenQuota caps the number of unexpired tokens a subject may hold for a
+// single target at limit, tracked in q. An exchange that would exceed the cap
+// fails with ResourceExhausted. A nil q or a non-positive limit disables the
+// cap. It must be called before the server starts handling requests.
+func (s *TokenExchangeServer) SetTokenQuota(q TokenQuota, limit int) {
+	if q == nil || limit <= 0 {
+		s.quota, s.quotaLimit = nil, 0
+		return
+	}
+	s.quota, s.quotaLimit = q, limit
+}
+
+// SetReceiptSigner makes the server sign a decision receipt, with r, for
+// every exchange that asks for one. A failure to sign fails the exchange
+// before the token is counted against a quota or audited. A nil r disables
+// receipts. It must be called before the server starts handling requests.
+func (s *TokenExchangeServer) SetReceiptSigner(r ReceiptSigner) {
+	s.receipts = r
+}
+
+// SetRedactor makes the server rewrite SPIFFE IDs with r in the error
+// messages it returns, so that callers logging them do not leak identities
+// outside the security boundary. Denial reasons in the audit log are then
+// redacted too; the audit logger applies its own redactor to everything
+// else. A nil r disables it. It must be called before the server starts
+// handling requests.
+func (s *TokenExchangeServer) SetRedactor(r *audit.Redactor) {
+	s.redact = r
+}
+
+// SetMaxTTL caps the TTL of every token the server grants at ceiling, on top
+// of each policy's max_ttl, so that one environment can enforce a shorter
+// lifetime than the shared policy set allows. A grant cut down by the
+// ceiling is audited with the TTL policy allowed. A ceiling under one
+// second removes the cap. It must be called before the server starts
+// handling requests.
+func (s *TokenExchangeServer) SetMaxTTL(ceiling time.Duration) {
+	s.maxTTL = int32(min(ceiling/time.Second, math.MaxInt32))
+}
+
+// SetDeadlineTTL makes the server treat the deadline of an Exchange call as
+// a hint of how long the caller needs its token: the requested TTL is capped
+// to the time left before the deadline, so a batch job that propagates its
+// own deadline gets a right-sized token instead of the policy max_ttl. It
+// must be called before the server starts handling requests.
+func (s *TokenExchangeServer) SetDeadlineTTL(enabled bool) {
+	s.deadlineTTL = enabled
+}
+
+// SetAuditRequired makes the server fail an Exchange with Unavailable,
+// discarding the minted token, when the audit logger is a
+// DurableAuditLogger that cannot record the grant. Denials are still
+// returned as denials. Unlike the other setters it may be called while the
+// server is handling requests, to fail open during an audit sink outage.
+func (s *TokenExchangeServer) SetAuditRequired(required bool) {
+	s.auditRequired.Store(required)
+}
+
+// AuditRequired reports whether grants that cannot be durably audited are
+// withheld; see SetAuditRequired.
+func (s *TokenExchangeServer) AuditRequired() bool {
+	return s.auditRequired.Load()
+}
+
+// SetClock makes the server read the time from c when it expires nonces,
+// grants, replay records, revocations, and cached denials, so that tests
+// can advance time instead of sleeping. It must be called before the server
+// starts handling requests.
+func (s *TokenExchangeServer) SetClock(c clock.Clock) {
+	s.now = c.Now
+	s.cache.now = c.Now
+	s.revoked.now = c.Now
+	if s.denials != nil {
+		s.denials.now = c.Now
+	}
+}
+
+// SetLatencyObserver makes the server call observe with the stage timings of
+// every exchange once it returns, successful or not. observe runs on the
+// request path, so it must be fast. A nil observe disables it. It must be
+// called before the server starts handling requests.
+func (s *TokenExchangeServer) SetLatencyObserver(observe func(ExchangeLatency)) {
+	s.observeLatency = observe
+}
+
+// SetDenialCache makes a denied request be refused outright, without
+// evaluating policy or writing an audit entry, when it is repeated within
+// base of the denial. Each further denial doubles the hold, up to maxDelay.
+// Every denial carries the hold as a retry hint: a RetryInfo error detail and
+// the RetryPushbackTrailer trailer. The next audited denial reports how many
+// were served from the cache. A denial reached by evaluating a policy
+// condition is not cached, since the condition may hold a moment later. A
+// non-positive base disables the cache. It must
+// be called before the server starts handling requests; call
+// ResetDenialCache whenever the policy changes.
+func (s *TokenExchangeServer) SetDenialCache(base, maxDelay time.Duration) {
+	if base <= 0 {
+		s.denials = nil
+		return
+	}
+	s.denials = newDenialCache(base, max(base, maxDelay), 10_000)
+	s.denials.now = s.now
+}
+
+// ResetDenialCache forgets every cached denial, so that a policy change
+// granting a previously denied request takes effect immediately.
+func (s *TokenExchangeServer) ResetDenialCache() {
+	if s.denials != nil {
+		s.denials.reset()
+	}
+}
+
+// permissionDenied returns a PermissionDenied status for reason. A positive
+// wait is attached as a RetryInfo detail and sent in the
+// RetryPushbackTrailer trailer.
+func permissionDenied(ctx context.Context, reason string, wait time.Duration) error {
+	st := status.New(codes.PermissionDenied, reason)
+	if wait <= 0 {
+		return st.Err()
+	}
+	// SetTrailer only fails outside a gRPC transport; the detail still
+	// carries the hint.
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryPushbackTrailer, strconv.FormatInt(wait.Milliseconds(), 10)))
+	if d, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = d
 	}
+	return st.Err()
+}
+
+// stageContext derives the context for one stage of Exchange.
+func stageContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// stageError converts a stage failure into a gRPC status. Context errors keep
+// their own code (DeadlineExceeded or Canceled) so callers can tell a slow
+// backend from a broken one; anything else gets fallback.
+func stageError(stage string, err error, fallback codes.Code) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.Errorf(status.FromContextError(err).Code(), "%s: %v", stage, err)
+	}
+	return status.Errorf(fallback, "%s: %v", stage, err)
 }
 
 // Revoke adds jti to the server's revocation list with its natural token expiry.
@@ -75,84 +404,547 @@ func (s *TokenExchangeServer) Revoke(jti string, expiresAt time.Time) bool {
 	return s.revoked.Revoke(jti, expiresAt)
 }
 
+// IsRevoked reports whether jti has been explicitly revoked and has not yet
+// reached its natural expiry.
+func (s *TokenExchangeServer) IsRevoked(jti string) bool {
+	return s.revoked.isRevoked(jti)
+}
+
 // Exchange validates the caller's SVID, applies policy, and mints a token.
+// Every call is tagged with a request ID (see RequestIDHeader) that is
+// returned in the response header, appended to error messages, and recorded
+// in the audit entry, so the three can be joined during incident review.
 func (s *TokenExchangeServer) Exchange(ctx context.Context, req *exchangev1.ExchangeRequest) (*exchangev1.ExchangeResponse, error) {
-	subjectID, err := s.extractor.ExtractID(ctx)
+	reqID := requestID(ctx)
+	// SetHeader only fails outside a gRPC transport (direct calls in tests)
+	// or after headers were sent; neither affects the exchange itself.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, reqID))
+
+	out, err := s.exchange(ctx, exchangeInput{
+		target:          req.TargetService,
+		scopes:          req.Scopes,
+		ttlSeconds:      req.TtlSeconds,
+		onBehalfOf:      req.OnBehalfOf,
+		onBehalfOfField: "on_behalf_of",
+		nonce:           req.Nonce,
+		useUntil:        req.UseUntil,
+	}, reqID)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "extract SPIFFE ID: %v", err)
+		return nil, withRequestID(err, reqID)
 	}
+	return &exchangev1.ExchangeResponse{
+		Token:         out.minted.Token,
+		ExpiresAt:     out.minted.ExpiresAt.Unix(),
+		GrantedScopes: out.result.GrantedScopes,
+		TokenId:       out.minted.TokenID,
+	}, nil
+}
 
-	if req.TargetService == "" {
-		return nil, status.Error(codes.InvalidArgument, "target_service is required")
+// capRequestedTTL caps the requested TTL ttl, where zero asks for the policy
+// max_ttl, to left rounded up to a whole second.
+func capRequestedTTL(ttl int32, left time.Duration) int32 {
+	secs := int32(min((left+time.Second-1)/time.Second, math.MaxInt32))
+	if ttl == 0 || secs < ttl {
+		return max(secs, 1)
 	}
-	if len(req.Scopes) == 0 {
-		return nil, status.Error(codes.InvalidArgument, "at least one scope is required")
+	return ttl
+}
+
+// withRequestID appends reqID to err's message. The message is rewritten on
+// the status proto so details survive.
+func withRequestID(err error, reqID string) error {
+	p := status.Convert(err).Proto()
+	p.Message = fmt.Sprintf("%s [request_id=%s]", p.Message, reqID)
+	return status.ErrorProto(p)
+}
+
+// exchangeInput is an Exchange request independent of the API version it
+// arrived through.
+type exchangeInput struct {
+	target     string
+	scopes     []string
+	ttlSeconds int32
+	onBehalfOf string
+	// onBehalfOfField names onBehalfOf's request field in error messages.
+	onBehalfOfField string
+	// preflight stops a granted exchange before minting; see
+	// exchangeOutput.
+	preflight bool
+	// receipt asks for a decision receipt. The caller checks that receipts
+	// are enabled and that preflight is not also set.
+	receipt bool
+	// nonce is the optional client-generated request nonce; see
+	// SetNonceStore.
+	nonce string
+	// useUntil is the optional Unix time after which the caller no longer
+	// needs the token. Zero means unset.
+	useUntil int64
+	// audiences are the aud values the caller requires the token to carry.
+	// Empty means no requirement.
+	audiences []string
+}
+
+// exchangeOutput is a granted exchange, for the API version to encode.
+// minted is zero for a preflight, and receipt is empty unless one was asked
+// for.
+type exchangeOutput struct {
+	minted  token.MintResult
+	format  string
+	result  policy.EvalResult
+	receipt string
+}
+
+// authenticate identifies the caller, also reporting the authentication
+// method when the extractor is an Authenticator and the certificate when it
+// is an IdentityExtractor.
+func (s *TokenExchangeServer) authenticate(ctx context.Context) (Identity, error) {
+	if a, ok := s.extractor.(Authenticator); ok {
+		return a.Authenticate(ctx)
 	}
-	if len(req.Scopes) > maxScopes {
-		return nil, status.Errorf(codes.InvalidArgument, "too many scopes: %d exceeds maximum of %d", len(req.Scopes), maxScopes)
+	return extractIdentity(ctx, s.extractor)
+}
+
+func (s *TokenExchangeServer) exchange(ctx context.Context, req exchangeInput, reqID string) (_ exchangeOutput, err error) {
+	s.calls.start()
+	defer s.calls.done()
+	start := time.Now()
+	lat := ExchangeLatency{RequestID: reqID, Target: req.target}
+	if s.observeLatency != nil {
+		defer func() {
+			lat.Stages.Total = time.Since(start)
+			lat.Code = status.Code(err)
+			s.observeLatency(lat)
+		}()
 	}
-	if req.TtlSeconds < 0 {
-		return nil, status.Error(codes.InvalidArgument, "ttl_seconds must be non-negative")
+	var traceID string
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		traceID = sc.TraceID().String()
+	}
+	callerMetadata := auditMetadata(ctx, s.auditMetadataKeys)
+	durable, _ := s.audit.(DurableAuditLogger)
+	logExchange := func(e audit.ExchangeEvent) error {
+		t := time.Now()
+		defer func() { lat.Stages.Audit += time.Since(t) }()
+		e.TraceID = traceID
+		e.Metadata = callerMetadata
+		if durable == nil {
+			s.audit.LogExchange(e)
+			return nil
+		}
+		// Recorded the same way in either mode, so that the logger sees
+		// and reports the failures it tolerates.
+		if err := durable.RecordExchange(e); err != nil && s.auditRequired.Load() {
+			return err
+		}
+		return nil
+	}
+
+	caller, err := s.authenticate(ctx)
+	lat.Stages.Extract = time.Since(start)
+	if err != nil {
+		return exchangeOutput{}, status.Errorf(codes.Unauthenticated, "extract SPIFFE ID: %v", err)
+	}
+	// IDs are canonicalised before policy, audit, and minting see them, so
+	// that spellings differing only in trust-domain case cannot diverge.
+	subjectID, err := policy.NormalizeSPIFFEID(caller.ID)
+	if err != nil {
+		return exchangeOutput{}, status.Errorf(codes.Unauthenticated, "invalid caller SPIFFE ID: %v", err)
+	}
+	certInfo := audit.NewCertInfo(caller.Cert)
+	lat.Subject = subjectID
+
+	if req.target == "" {
+		return exchangeOutput{}, status.Error(codes.InvalidArgument, "target_service is required")
+	}
+	if n := len(req.target); n > s.limits.MaxTargetLength {
+		return exchangeOutput{}, status.Errorf(codes.InvalidArgument, "target_service too long: %d bytes exceeds maximum of %d", n, s.limits.MaxTargetLength)
+	}
+	if req.target, err = policy.NormalizeSPIFFEID(req.target); err != nil {
+		return exchangeOutput{}, status.Errorf(codes.InvalidArgument, "invalid target_service: %v", err)
+	}
+	lat.Target = req.target
+	if len(req.scopes) == 0 {
+		return exchangeOutput{}, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+	if len(req.scopes) > s.limits.MaxScopes {
+		return exchangeOutput{}, status.Errorf(codes.InvalidArgument, "too many scopes: %d exceeds maximum of %d", len(req.scopes), s.limits.MaxScopes)
+	}
+	for i, scope := range req.scopes {
+		if len(scope) > s.limits.MaxScopeLength {
+			return exchangeOutput{}, status.Errorf(codes.InvalidArgument, "scope %d too long: %d bytes exceeds maximum of %d", i, len(scope), s.limits.MaxScopeLength)
+		}
+	}
+	if req.ttlSeconds < 0 {
+		return exchangeOutput{}, status.Error(codes.InvalidArgument, "ttl_seconds must be non-negative")
+	}
+	if req.useUntil != 0 {
+		left := time.Unix(req.useUntil, 0).Sub(s.now())
+		if left <= 0 {
+			return exchangeOutput{}, status.Error(codes.InvalidArgument, "use_until must be in the future")
+		}
+		req.ttlSeconds = capRequestedTTL(req.ttlSeconds, left)
+	}
+	if deadline, ok := ctx.Deadline(); ok && s.deadlineTTL {
+		req.ttlSeconds = capRequestedTTL(req.ttlSeconds, deadline.Sub(s.now()))
+	}
+	if req.nonce != "" {
+		if err := validateNonce(req.nonce); err != nil {
+			return exchangeOutput{}, err
+		}
+		if s.nonces == nil {
+			return exchangeOutput{}, status.Error(codes.FailedPrecondition, "request nonces are not enabled on this server")
+		}
 	}
 
 	var actSubject string
-	if req.OnBehalfOf != "" {
-		actSubject, err = token.VerifyJWT(req.OnBehalfOf, s.minter.PublicKeys())
+	if req.onBehalfOf != "" {
+		actSubject, err = token.VerifyJWT(req.onBehalfOf, s.minter.PublicKeys())
 		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "on_behalf_of: %v", err)
+			return exchangeOutput{}, status.Errorf(codes.InvalidArgument, "%s: %v", req.onBehalfOfField, err)
 		}
 	}
 
 	if err := ctx.Err(); err != nil {
-		return nil, status.FromContextError(err).Err()
+		return exchangeOutput{}, status.FromContextError(err).Err()
+	}
+
+	var dk denialKey
+	if s.denials != nil {
+		dk = newDenialKey(subjectID, req.target, req.scopes)
+		if wait, ok := s.denials.cached(dk); ok {
+			return exchangeOutput{}, permissionDenied(ctx, fmt.Sprintf("no policy permits %s → %s", s.redact.ID(subjectID), s.redact.ID(req.target)), wait)
+		}
 	}
 
-	result := s.policy.Evaluate(subjectID, req.TargetService, req.Scopes, req.TtlSeconds)
+	evalStart := time.Now()
+	evalCtx, cancel := stageContext(ctx, s.evalTimeout)
+	result, err := s.policy.Evaluate(evalCtx, subjectID, req.target, req.scopes, req.ttlSeconds)
+	cancel()
+	lat.Stages.Evaluate = time.Since(evalStart)
+	if err != nil {
+		return exchangeOutput{}, stageError("evaluate policy", err, codes.Unavailable)
+	}
 	if !result.Allowed {
-		s.audit.LogExchange(audit.ExchangeEvent{
+		reason := fmt.Sprintf("no policy permits %s → %s", s.redact.ID(subjectID), s.redact.ID(req.target))
+		var wait time.Duration
+		var suppressed int
+		if s.denials != nil && !result.Conditional {
+			wait, suppressed = s.denials.deny(dk)
+		}
+		logExchange(audit.ExchangeEvent{
+			RequestID:         reqID,
+			AuthMethod:        caller.Method,
+			Cert:              certInfo,
+			Subject:           subjectID,
+			Target:            req.target,
+			ScopesRequested:   req.scopes,
+			Granted:           false,
+			DenialReason:      reason,
+			SuppressedDenials: suppressed,
+			Preflight:         req.preflight,
+		})
+		return exchangeOutput{}, permissionDenied(ctx, reason, wait)
+	}
+
+	if s.targets != nil {
+		validateStart := time.Now()
+		validateCtx, cancel := stageContext(ctx, s.evalTimeout)
+		err := s.targets.ValidateTarget(validateCtx, req.target)
+		cancel()
+		lat.Stages.Evaluate += time.Since(validateStart)
+		if errors.Is(err, ErrUnknownTarget) {
+			reason := fmt.Sprintf("target_service %s is not a known workload", s.redact.ID(req.target))
+			logExchange(audit.ExchangeEvent{
+				RequestID:       reqID,
+				AuthMethod:      caller.Method,
+				Cert:            certInfo,
+				Subject:         subjectID,
+				Target:          req.target,
+				ScopesRequested: req.scopes,
+				Granted:         false,
+				DenialReason:    reason,
+				PolicyRules:     result.MatchedRules,
+				Preflight:       req.preflight,
+			})
+			return exchangeOutput{}, status.Error(codes.NotFound, reason)
+		}
+		if err != nil {
+			return exchangeOutput{}, stageError("validate target", err, codes.Unavailable)
+		}
+	}
+
+	var clampedFrom int32
+	if s.maxTTL > 0 && result.GrantedTTL > s.maxTTL {
+		clampedFrom, result.GrantedTTL = result.GrantedTTL, s.maxTTL
+	}
+
+	if result.RequireNonce && req.nonce == "" {
+		reason := fmt.Sprintf("policy for %s → %s requires a request nonce", s.redact.ID(subjectID), s.redact.ID(req.target))
+		logExchange(audit.ExchangeEvent{
+			RequestID:       reqID,
+			AuthMethod:      caller.Method,
+			Cert:            certInfo,
 			Subject:         subjectID,
-			Target:          req.TargetService,
-			ScopesRequested: req.Scopes,
+			Target:          req.target,
+			ScopesRequested: req.scopes,
 			Granted:         false,
-			DenialReason:    fmt.Sprintf("no policy permits %s → %s", subjectID, req.TargetService),
+			DenialReason:    reason,
+			PolicyRules:     result.MatchedRules,
+			Preflight:       req.preflight,
 		})
-		return nil, status.Errorf(codes.PermissionDenied, "no policy permits %s → %s", subjectID, req.TargetService)
+		return exchangeOutput{}, status.Error(codes.PermissionDenied, reason)
 	}
 
 	if err := ctx.Err(); err != nil {
-		return nil, status.FromContextError(err).Err()
+		return exchangeOutput{}, status.FromContextError(err).Err()
 	}
 
-	minted, err := s.minter.Mint(subjectID, req.TargetService, result.GrantedScopes, result.GrantedTTL, actSubject)
+	format := result.TokenFormat
+	if format == "" {
+		format = policy.FormatJWT
+	}
+	minter, ok := s.formats[format]
+	if !ok {
+		return exchangeOutput{}, status.Errorf(codes.FailedPrecondition, "token format %q is not enabled on this server", format)
+	}
+	if result.RequiresApproval && s.approvals == nil {
+		return exchangeOutput{}, status.Error(codes.FailedPrecondition, "policy requires approval, but approvals are not enabled on this server")
+	}
+	if len(result.Audience) > 0 {
+		for _, aud := range req.audiences {
+			if !slices.Contains(result.Audience, aud) {
+				return exchangeOutput{}, status.Errorf(codes.FailedPrecondition, "policy issues tokens for audiences %v, not %q", result.Audience, aud)
+			}
+		}
+	}
+
+	if req.preflight {
+		logExchange(audit.ExchangeEvent{
+			RequestID:       reqID,
+			AuthMethod:      caller.Method,
+			Cert:            certInfo,
+			Subject:         subjectID,
+			Target:          req.target,
+			ScopesRequested: req.scopes,
+			ScopesGranted:   result.GrantedScopes,
+			Granted:         true,
+			TTL:             result.GrantedTTL,
+			TTLClampedFrom:  clampedFrom,
+			PolicyRules:     result.MatchedRules,
+			Preflight:       true,
+		})
+		if len(result.MatchedRules) > 0 {
+			_ = grpc.SetHeader(ctx, metadata.MD{PolicyRuleHeader: result.MatchedRules})
+		}
+		return exchangeOutput{format: format, result: result}, nil
+	}
+
+	// The nonce is used before minting, so that two concurrent copies of a
+	// request cannot both be issued a token. A mint that then fails burns
+	// the nonce; the client retries with a new one.
+	if req.nonce != "" {
+		ok, err := s.nonces.UseNonce(subjectID, req.nonce, s.now().Add(s.nonceWindow).Unix())
+		if err != nil {
+			return exchangeOutput{}, status.Errorf(codes.Internal, "request nonce: %v", err)
+		}
+		if !ok {
+			reason := fmt.Sprintf("request nonce already used by %s", s.redact.ID(subjectID))
+			logExchange(audit.ExchangeEvent{
+				RequestID:       reqID,
+				AuthMethod:      caller.Method,
+				Cert:            certInfo,
+				Subject:         subjectID,
+				Target:          req.target,
+				ScopesRequested: req.scopes,
+				Granted:         false,
+				DenialReason:    reason,
+				PolicyRules:     result.MatchedRules,
+			})
+			return exchangeOutput{}, status.Error(codes.PermissionDenied, reason)
+		}
+	}
+
+	// Approval comes after the nonce is used, so that a request cannot be
+	// parked twice; a denied or timed-out request burns its nonce.
+	var approver string
+	if result.RequiresApproval {
+		d, err := s.approvals.wait(ctx, PendingApproval{
+			RequestID:   reqID,
+			Subject:     subjectID,
+			Target:      req.target,
+			Scopes:      result.GrantedScopes,
+			TTL:         result.GrantedTTL,
+			PolicyRules: result.MatchedRules,
+		})
+		if err != nil && !errors.Is(err, errApprovalTimeout) {
+			return exchangeOutput{}, status.FromContextError(err).Err()
+		}
+		if err != nil || !d.Approved {
+			reason := fmt.Sprintf("no approval decision within %s", s.approvals.timeout)
+			if err == nil {
+				reason = "approval denied"
+				if d.Reason != "" {
+					reason += ": " + d.Reason
+				}
+			}
+			logExchange(audit.ExchangeEvent{
+				RequestID:       reqID,
+				AuthMethod:      caller.Method,
+				Cert:            certInfo,
+				Subject:         subjectID,
+				Target:          req.target,
+				ScopesRequested: req.scopes,
+				Granted:         false,
+				DenialReason:    reason,
+				PolicyRules:     result.MatchedRules,
+				Approver:        d.Approver,
+			})
+			return exchangeOutput{}, status.Error(codes.PermissionDenied, reason)
+		}
+		approver = d.Approver
+	}
+
+	mintStart := time.Now()
+	mintCtx, cancel := stageContext(ctx, s.mintTimeout)
+	if s.bindSource {
+		mintCtx = token.WithSource(mintCtx, callerSource(ctx, caller))
+	}
+	if result.SingleUse {
+		mintCtx = token.WithSingleUse(mintCtx)
+	}
+	if len(result.Audience) > 0 {
+		mintCtx = token.WithAudience(mintCtx, result.Audience)
+	}
+	if result.Issuer != "" {
+		mintCtx = token.WithIssuer(mintCtx, result.Issuer)
+	}
+	minted, err := minter.Mint(mintCtx, subjectID, req.target, result.GrantedScopes, result.GrantedTTL, actSubject)
+	cancel()
+	lat.Stages.Mint = time.Since(mintStart)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "mint token: %v", err)
+		code := codes.Internal
+		if errors.Is(err, token.ErrSigningQueueFull) {
+			// Backpressure from the signing pipeline; the call may be retried.
+			code = codes.Unavailable
+		}
+		return exchangeOutput{}, stageError("mint token", err, code)
 	}
 
 	if s.revoked.isRevoked(minted.TokenID) {
-		return nil, status.Error(codes.PermissionDenied, "token id has been revoked")
+		return exchangeOutput{}, status.Error(codes.PermissionDenied, "token id has been revoked")
 	}
 	// alreadyIssued is belt-and-suspenders: Mint() generates a UUID v4 JTI on
 	// every call so a collision is statistically impossible in normal operation.
 	// The check guards against hypothetical minter bugs or future non-UUID JTI
 	// schemes that might reuse IDs.
 	if s.cache.alreadyIssued(minted.TokenID, minted.ExpiresAt) {
-		return nil, status.Error(codes.Aborted, "token id already issued")
+		return exchangeOutput{}, status.Error(codes.Aborted, "token id already issued")
 	}
 
-	s.audit.LogExchange(audit.ExchangeEvent{
+	var receipt string
+	if req.receipt {
+		signStart := time.Now()
+		rctx, cancel := stageContext(ctx, s.mintTimeout)
+		receipt, err = s.receipts.SignReceipt(rctx, token.Receipt{
+			TokenID:         minted.TokenID,
+			TokenHash:       token.ReceiptTokenHash(minted.Token),
+			RequestID:       reqID,
+			Subject:         subjectID,
+			Target:          req.target,
+			ScopesRequested: req.scopes,
+			ScopesGranted:   result.GrantedScopes,
+			TTL:             result.GrantedTTL,
+			TokenFormat:     format,
+			Rules:           result.MatchedRules,
+			PolicyDigest:    result.PolicyDigest,
+		})
+		cancel()
+		lat.Stages.Mint += time.Since(signStart)
+		if err != nil {
+			return exchangeOutput{}, stageError("sign receipt", err, codes.Internal)
+		}
+	}
+
+	// The quota is charged after minting because the record needs the token's
+	// jti and expiry; a refused token is discarded without being returned.
+	if s.quota != nil {
+		ok, err := s.quota.ReserveToken(subjectID, req.target, minted.TokenID, minted.ExpiresAt.Unix(), s.quotaLimit)
+		if err != nil {
+			return exchangeOutput{}, status.Errorf(codes.Internal, "token quota: %v", err)
+		}
+		if !ok {
+			reason := fmt.Sprintf("token quota exceeded: %s already holds %d unexpired tokens for %s", s.redact.ID(subjectID), s.quotaLimit, s.redact.ID(req.target))
+			logExchange(audit.ExchangeEvent{
+				RequestID:       reqID,
+				AuthMethod:      caller.Method,
+				Cert:            certInfo,
+				Subject:         subjectID,
+				Target:          req.target,
+				ScopesRequested: req.scopes,
+				Granted:         false,
+				DenialReason:    reason,
+				PolicyRules:     result.MatchedRules,
+			})
+			return exchangeOutput{}, status.Error(codes.ResourceExhausted, reason)
+		}
+	}
+
+	if s.grants != nil {
+		err := s.grants.SaveGrant(subjectID, policy.GrantRecord{
+			JTI:       minted.TokenID,
+			Target:    req.target,
+			Scopes:    result.GrantedScopes,
+			ExpiresAt: minted.ExpiresAt.Unix(),
+		})
+		if err != nil {
+			if rerr := s.discardToken(subjectID, req.target, minted.TokenID); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+			return exchangeOutput{}, status.Errorf(codes.Internal, "record grant: %v", err)
+		}
+	}
+
+	err = logExchange(audit.ExchangeEvent{
+		RequestID:       reqID,
+		AuthMethod:      caller.Method,
+		Cert:            certInfo,
 		Subject:         subjectID,
-		Target:          req.TargetService,
-		ScopesRequested: req.Scopes,
+		Target:          req.target,
+		ScopesRequested: req.scopes,
 		ScopesGranted:   result.GrantedScopes,
 		Granted:         true,
 		TTL:             result.GrantedTTL,
+		TTLClampedFrom:  clampedFrom,
 		TokenID:         minted.TokenID,
+		PolicyRules:     result.MatchedRules,
+		Approver:        approver,
 	})
+	if err != nil {
+		// A token nobody can account for must not leave the server, nor
+		// count against the caller's quota or be listed as its grant.
+		if rerr := s.discardToken(subjectID, req.target, minted.TokenID); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		return exchangeOutput{}, status.Errorf(codes.Unavailable, "record audit event: %v", err)
+	}
+	if len(result.MatchedRules) > 0 {
+		_ = grpc.SetHeader(ctx, metadata.MD{PolicyRuleHeader: result.MatchedRules})
+	}
 
-	return &exchangev1.ExchangeResponse{
-		Token:         minted.Token,
-		ExpiresAt:     minted.ExpiresAt.Unix(),
-		GrantedScopes: result.GrantedScopes,
-		TokenId:       minted.TokenID,
-	}, nil
+	return exchangeOutput{minted: minted, format: format, result: result, receipt: receipt}, nil
+}
+
+// discardToken undoes the quota reservation and grant record of a minted
+// token that is not being issued.
+func (s *TokenExchangeServer) discardToken(subject, target, jti string) error {
+	var errs []error
+	if s.quota != nil {
+		errs = append(errs, s.quota.ReleaseToken(subject, target, jti))
+	}
+	if s.grants != nil {
+		if err := s.grants.DeleteGrant(subject, jti); err != nil {
+			errs = append(errs, fmt.Errorf("delete grant: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
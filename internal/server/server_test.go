@@ -2,17 +2,30 @@ package server_test
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/clock"
 	"github.com/ngaddam369/svid-exchange/internal/policy"
 	"github.com/ngaddam369/svid-exchange/internal/server"
 	"github.com/ngaddam369/svid-exchange/internal/token"
@@ -32,25 +45,37 @@ func (m mockExtractor) ExtractID(_ context.Context) (string, error) {
 
 type mockPolicy struct {
 	result policy.EvalResult
+	err    error
+	block  bool // wait for ctx to be done and return its error
 }
 
-func (m mockPolicy) Evaluate(_, _ string, _ []string, _ int32) policy.EvalResult {
-	return m.result
+func (m mockPolicy) Evaluate(ctx context.Context, _, _ string, _ []string, _ int32) (policy.EvalResult, error) {
+	if m.block {
+		<-ctx.Done()
+		return policy.EvalResult{}, ctx.Err()
+	}
+	return m.result, m.err
 }
 
 type mockMinter struct {
 	result     token.MintResult
 	err        error
 	lastAct    string             // actSubject passed to the most recent Mint call
-	publicKeys []*ecdsa.PublicKey // returned by PublicKeys(); nil means no keys
+	lastTTL    int32              // ttlSeconds passed to the most recent Mint call
+	publicKeys []crypto.PublicKey // returned by PublicKeys(); nil means no keys
+	block      bool               // wait for ctx to be done and return its error
 }
 
-func (m *mockMinter) Mint(_, _ string, _ []string, _ int32, actSubject string) (token.MintResult, error) {
-	m.lastAct = actSubject
+func (m *mockMinter) Mint(ctx context.Context, _, _ string, _ []string, ttlSeconds int32, actSubject string) (token.MintResult, error) {
+	m.lastAct, m.lastTTL = actSubject, ttlSeconds
+	if m.block {
+		<-ctx.Done()
+		return token.MintResult{}, ctx.Err()
+	}
 	return m.result, m.err
 }
 
-func (m *mockMinter) PublicKeys() []*ecdsa.PublicKey {
+func (m *mockMinter) PublicKeys() []crypto.PublicKey {
 	return m.publicKeys
 }
 
@@ -58,6 +83,31 @@ type mockAudit struct{}
 
 func (mockAudit) LogExchange(_ audit.ExchangeEvent) {}
 
+// recordingAudit keeps every event it is given.
+type recordingAudit struct {
+	events []audit.ExchangeEvent
+}
+
+func (r *recordingAudit) LogExchange(e audit.ExchangeEvent) {
+	r.events = append(r.events, e)
+}
+
+// mockQuota reports a fixed reservation outcome and records the last call.
+type mockQuota struct {
+	ok      bool
+	err     error
+	subject string
+	target  string
+	limit   int
+}
+
+func (m *mockQuota) ReserveToken(subject, target, _ string, _ int64, limit int) (bool, error) {
+	m.subject, m.target, m.limit = subject, target, limit
+	return m.ok, m.err
+}
+
+func (m *mockQuota) ReleaseToken(string, string, string) error { return nil }
+
 // --- test helpers ---
 
 func okExtractor() mockExtractor {
@@ -102,6 +152,765 @@ func newValidReq() *exchangev1.ExchangeRequest {
 	}
 }
 
+func TestStageTimeouts(t *testing.T) {
+	blockingPolicy := allowedPolicy([]string{"payments:charge"}, 300)
+	blockingPolicy.block = true
+	blockingMinter := okMinter()
+	blockingMinter.block = true
+
+	tests := []struct {
+		name     string
+		policy   mockPolicy
+		minter   *mockMinter
+		evalTO   time.Duration
+		mintTO   time.Duration
+		wantCode codes.Code
+	}{
+		{
+			name:     "slow policy engine hits evaluate timeout",
+			policy:   blockingPolicy,
+			minter:   okMinter(),
+			evalTO:   10 * time.Millisecond,
+			wantCode: codes.DeadlineExceeded,
+		},
+		{
+			name:     "slow signer hits mint timeout",
+			policy:   allowedPolicy([]string{"payments:charge"}, 300),
+			minter:   blockingMinter,
+			mintTO:   10 * time.Millisecond,
+			wantCode: codes.DeadlineExceeded,
+		},
+		{
+			name:     "policy engine error returns Unavailable",
+			policy:   mockPolicy{err: errors.New("opa unreachable")},
+			minter:   okMinter(),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "fast stages are unaffected",
+			policy:   allowedPolicy([]string{"payments:charge"}, 300),
+			minter:   okMinter(),
+			evalTO:   time.Second,
+			mintTO:   time.Second,
+			wantCode: codes.OK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := server.New(okExtractor(), tc.policy, tc.minter, mockAudit{})
+			svc.SetStageTimeouts(tc.evalTO, tc.mintTO)
+			_, err := svc.Exchange(context.Background(), newValidReq())
+			if status.Code(err) != tc.wantCode {
+				t.Errorf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestLatencyObserver(t *testing.T) {
+	blockingMinter := okMinter()
+	blockingMinter.block = true
+	const id = "incident-4711"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(server.RequestIDHeader, id))
+
+	tests := []struct {
+		name      string
+		extractor mockExtractor
+		minter    *mockMinter
+		wantCode  codes.Code
+		check     func(t *testing.T, lat server.ExchangeLatency)
+	}{
+		{
+			name:      "granted exchange records every stage",
+			extractor: okExtractor(),
+			minter:    okMinter(),
+			wantCode:  codes.OK,
+			check: func(t *testing.T, lat server.ExchangeLatency) {
+				if lat.Subject != okExtractor().id || lat.Target != newValidReq().TargetService {
+					t.Errorf("subject, target = %q, %q", lat.Subject, lat.Target)
+				}
+			},
+		},
+		{
+			name:      "slow signer shows in the mint stage",
+			extractor: okExtractor(),
+			minter:    blockingMinter,
+			wantCode:  codes.DeadlineExceeded,
+			check: func(t *testing.T, lat server.ExchangeLatency) {
+				if lat.Stages.Mint < 20*time.Millisecond {
+					t.Errorf("Mint = %v, want at least the 20ms mint timeout", lat.Stages.Mint)
+				}
+				if lat.Stages.Audit != 0 {
+					t.Errorf("Audit = %v for an exchange that was never audited", lat.Stages.Audit)
+				}
+			},
+		},
+		{
+			name:      "unauthenticated caller stops after extract",
+			extractor: mockExtractor{err: errors.New("no peer certificate")},
+			minter:    okMinter(),
+			wantCode:  codes.Unauthenticated,
+			check: func(t *testing.T, lat server.ExchangeLatency) {
+				if lat.Subject != "" || lat.Stages.Evaluate != 0 || lat.Stages.Mint != 0 {
+					t.Errorf("latency = %+v, want no subject and no later stages", lat)
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []server.ExchangeLatency
+			svc := server.New(tc.extractor, allowedPolicy([]string{"payments:charge"}, 300), tc.minter, mockAudit{})
+			svc.SetStageTimeouts(0, 20*time.Millisecond)
+			svc.SetLatencyObserver(func(lat server.ExchangeLatency) { got = append(got, lat) })
+			if _, err := svc.Exchange(ctx, newValidReq()); status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			if len(got) != 1 {
+				t.Fatalf("observed %d exchanges, want 1", len(got))
+			}
+			lat := got[0]
+			if lat.RequestID != id || lat.Code != tc.wantCode {
+				t.Errorf("request ID, code = %q, %v; want %q, %v", lat.RequestID, lat.Code, id, tc.wantCode)
+			}
+			st := lat.Stages
+			if st.Total < st.Extract+st.Evaluate+st.Mint+st.Audit {
+				t.Errorf("Total %v is less than the sum of stages %+v", st.Total, st)
+			}
+			tc.check(t, lat)
+		})
+	}
+}
+
+func TestExchangeRequestID(t *testing.T) {
+	const id = "incident-4711"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(server.RequestIDHeader, id))
+
+	t.Run("granted exchange is audited with the request ID", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+		if _, err := svc.Exchange(ctx, newValidReq()); err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if len(rec.events) != 1 || rec.events[0].RequestID != id {
+			t.Errorf("audit events = %+v, want one with RequestID %q", rec.events, id)
+		}
+	})
+
+	t.Run("denial carries the request ID in error and audit", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), deniedPolicy(), okMinter(), rec)
+		_, err := svc.Exchange(ctx, newValidReq())
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+		}
+		if !strings.Contains(status.Convert(err).Message(), "request_id="+id) {
+			t.Errorf("error %q does not contain request_id=%s", status.Convert(err).Message(), id)
+		}
+		if len(rec.events) != 1 || rec.events[0].RequestID != id {
+			t.Errorf("audit events = %+v, want one with RequestID %q", rec.events, id)
+		}
+	})
+
+	t.Run("validation error gets a generated request ID", func(t *testing.T) {
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), mockAudit{})
+		_, err := svc.Exchange(context.Background(), &exchangev1.ExchangeRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("code = %v, want InvalidArgument", status.Code(err))
+		}
+		if !strings.Contains(status.Convert(err).Message(), "[request_id=") {
+			t.Errorf("error %q has no request ID", status.Convert(err).Message())
+		}
+	})
+}
+
+func TestExchangeAuditMetadata(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-client-version", "1.4.2",
+		"x-deployment-id", "bad\nvalue",
+		"x-unlisted", "ignored",
+	))
+	rec := &recordingAudit{}
+	svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+	svc.SetAuditMetadataKeys([]string{"x-client-version", "x-deployment-id", "x-build"})
+	if _, err := svc.Exchange(ctx, newValidReq()); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(rec.events))
+	}
+	want := map[string]string{"x-client-version": "1.4.2"}
+	if got := rec.events[0].Metadata; !maps.Equal(got, want) {
+		t.Errorf("Metadata = %v, want %v", got, want)
+	}
+}
+
+// countingPolicy wraps a mockPolicy and counts evaluations.
+type countingPolicy struct {
+	mockPolicy
+	calls int
+}
+
+func (c *countingPolicy) Evaluate(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	c.calls++
+	return c.mockPolicy.Evaluate(ctx, subject, target, scopes, ttlSeconds)
+}
+
+func TestDenialCache(t *testing.T) {
+	retryDelay := func(t *testing.T, err error) time.Duration {
+		t.Helper()
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+		}
+		for _, d := range status.Convert(err).Details() {
+			if ri, ok := d.(*errdetails.RetryInfo); ok {
+				return ri.RetryDelay.AsDuration()
+			}
+		}
+		t.Fatalf("error %v has no RetryInfo detail", err)
+		return 0
+	}
+
+	t.Run("repeated denial is served from the cache", func(t *testing.T) {
+		p := &countingPolicy{mockPolicy: deniedPolicy()}
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec)
+		svc.SetDenialCache(time.Minute, time.Hour)
+
+		_, err := svc.Exchange(context.Background(), newValidReq())
+		if d := retryDelay(t, err); d != time.Minute {
+			t.Errorf("first retry delay = %v, want 1m", d)
+		}
+		_, err = svc.Exchange(context.Background(), newValidReq())
+		if d := retryDelay(t, err); d <= 0 || d > time.Minute {
+			t.Errorf("cached retry delay = %v, want the rest of the hold", d)
+		}
+		if !strings.Contains(status.Convert(err).Message(), "[request_id=") {
+			t.Errorf("cached denial %q has no request ID", status.Convert(err).Message())
+		}
+		if p.calls != 1 || len(rec.events) != 1 {
+			t.Errorf("evaluations = %d, audit events = %d; want 1 each", p.calls, len(rec.events))
+		}
+
+		svc.ResetDenialCache()
+		if _, err = svc.Exchange(context.Background(), newValidReq()); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+		}
+		if p.calls != 2 || len(rec.events) != 2 {
+			t.Errorf("after reset: evaluations = %d, audit events = %d; want 2 each", p.calls, len(rec.events))
+		}
+	})
+
+	t.Run("conditional denial is evaluated every time", func(t *testing.T) {
+		denied := deniedPolicy()
+		denied.result.Conditional = true
+		p := &countingPolicy{mockPolicy: denied}
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec)
+		svc.SetDenialCache(time.Minute, time.Hour)
+
+		for range 2 {
+			if _, err := svc.Exchange(context.Background(), newValidReq()); status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+			}
+		}
+		if p.calls != 2 || len(rec.events) != 2 {
+			t.Errorf("evaluations = %d, audit events = %d; want 2 each", p.calls, len(rec.events))
+		}
+	})
+
+	t.Run("disabled cache gives no hint", func(t *testing.T) {
+		svc := server.New(okExtractor(), deniedPolicy(), okMinter(), mockAudit{})
+		_, err := svc.Exchange(context.Background(), newValidReq())
+		if len(status.Convert(err).Details()) != 0 {
+			t.Errorf("details = %v, want none", status.Convert(err).Details())
+		}
+	})
+}
+
+func TestExchangePolicyRules(t *testing.T) {
+	rec := &recordingAudit{}
+	p := allowedPolicy([]string{"payments:charge"}, 300)
+	p.result.MatchedRules = []string{"order-to-payment", "order-to-any"}
+	svc := server.New(okExtractor(), p, okMinter(), rec)
+	if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if len(rec.events) != 1 || !slices.Equal(rec.events[0].PolicyRules, p.result.MatchedRules) {
+		t.Errorf("audit events = %+v, want one with PolicyRules %v", rec.events, p.result.MatchedRules)
+	}
+}
+
+func TestExchangeAuthMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		extractor  server.IDExtractor
+		wantMethod string
+	}{
+		{name: "plain extractor records no method", extractor: okExtractor()},
+		{name: "chain records the method that matched", extractor: server.ExtractorChain{
+			{Method: "x509-svid", IDExtractor: mockExtractor{err: server.NoCredentials("peer presented no certificates")}},
+			{Method: "k8s-sa-token", IDExtractor: okExtractor()},
+		}, wantMethod: "k8s-sa-token"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(tc.extractor, allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+			if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if len(rec.events) != 1 || rec.events[0].AuthMethod != tc.wantMethod {
+				t.Errorf("audit events = %+v, want one with AuthMethod %q", rec.events, tc.wantMethod)
+			}
+		})
+	}
+}
+
+// certExtractor is a server.IdentityExtractor authenticating with a fixed
+// certificate.
+type certExtractor struct {
+	id   string
+	cert *x509.Certificate
+}
+
+func (c certExtractor) ExtractID(context.Context) (string, error) { return c.id, nil }
+
+func (c certExtractor) ExtractIdentity(context.Context) (server.Identity, error) {
+	return server.Identity{ID: c.id, Cert: c.cert}, nil
+}
+
+func TestExchangeCertInfo(t *testing.T) {
+	leaf := &x509.Certificate{
+		Raw:          []byte("der"),
+		SerialNumber: big.NewInt(42),
+		NotAfter:     time.Now().Add(time.Hour),
+		Issuer:       pkix.Name{Organization: []string{"SPIRE"}},
+	}
+	tests := []struct {
+		name      string
+		extractor server.IDExtractor
+		wantCert  *audit.CertInfo
+	}{
+		{name: "token callers record no certificate", extractor: okExtractor()},
+		{name: "certificate callers record their leaf", extractor: server.ExtractorChain{
+			{Method: "x509-svid", IDExtractor: certExtractor{id: "spiffe://cluster.local/ns/default/sa/order", cert: leaf}},
+		}, wantCert: audit.NewCertInfo(leaf)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(tc.extractor, allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+			if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if len(rec.events) != 1 {
+				t.Fatalf("audit events = %d, want 1", len(rec.events))
+			}
+			if got := rec.events[0].Cert; (got == nil) != (tc.wantCert == nil) || (got != nil && !reflect.DeepEqual(*got, *tc.wantCert)) {
+				t.Errorf("Cert = %+v, want %+v", got, tc.wantCert)
+			}
+		})
+	}
+}
+
+func TestExchangeNormalizesIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		caller      string
+		target      string
+		wantCode    codes.Code
+		wantSubject string
+		wantTarget  string
+	}{
+		{
+			name:        "trust domains lowercased",
+			caller:      "spiffe://Cluster.Local/ns/default/sa/order",
+			target:      "SPIFFE://CLUSTER.LOCAL/ns/default/sa/payment",
+			wantSubject: "spiffe://cluster.local/ns/default/sa/order",
+			wantTarget:  "spiffe://cluster.local/ns/default/sa/payment",
+		},
+		{
+			name:     "target with a port rejected",
+			caller:   "spiffe://cluster.local/ns/default/sa/order",
+			target:   "spiffe://cluster.local:443/ns/default/sa/payment",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "caller with a dot segment rejected",
+			caller:   "spiffe://cluster.local/ns/../sa/order",
+			target:   "spiffe://cluster.local/ns/default/sa/payment",
+			wantCode: codes.Unauthenticated,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(mockExtractor{id: tc.caller}, allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+			req := newValidReq()
+			req.TargetService = tc.target
+			_, err := svc.Exchange(context.Background(), req)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("code = %v, want %v (err %v)", got, tc.wantCode, err)
+			}
+			if tc.wantCode != codes.OK {
+				return
+			}
+			if len(rec.events) != 1 {
+				t.Fatalf("audit events = %d, want 1", len(rec.events))
+			}
+			if e := rec.events[0]; e.Subject != tc.wantSubject || e.Target != tc.wantTarget {
+				t.Errorf("audited %q -> %q, want %q -> %q", e.Subject, e.Target, tc.wantSubject, tc.wantTarget)
+			}
+		})
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+	custom := server.Limits{MaxScopes: 2, MaxScopeLength: 16, MaxTargetLength: 64}
+
+	tests := []struct {
+		name    string
+		limits  *server.Limits // nil keeps the defaults
+		req     *exchangev1.ExchangeRequest
+		wantMsg string // empty means the request is granted
+	}{
+		{
+			name: "defaults admit a typical request",
+			req:  &exchangev1.ExchangeRequest{TargetService: target, Scopes: []string{"payments:charge"}},
+		},
+		{
+			name:    "default scope count",
+			req:     &exchangev1.ExchangeRequest{TargetService: target, Scopes: make([]string, server.DefaultLimits.MaxScopes+1)},
+			wantMsg: "too many scopes: 51 exceeds maximum of 50",
+		},
+		{
+			name:    "default scope length",
+			req:     &exchangev1.ExchangeRequest{TargetService: target, Scopes: []string{"payments:charge", strings.Repeat("s", 257)}},
+			wantMsg: "scope 1 too long: 257 bytes exceeds maximum of 256",
+		},
+		{
+			name:    "default target length",
+			req:     &exchangev1.ExchangeRequest{TargetService: "spiffe://td/" + strings.Repeat("t", 2048), Scopes: []string{"payments:charge"}},
+			wantMsg: "target_service too long: 2060 bytes exceeds maximum of 2048",
+		},
+		{
+			name:    "custom scope count",
+			limits:  &custom,
+			req:     &exchangev1.ExchangeRequest{TargetService: target, Scopes: []string{"a", "b", "c"}},
+			wantMsg: "too many scopes: 3 exceeds maximum of 2",
+		},
+		{
+			name:    "custom scope length",
+			limits:  &custom,
+			req:     &exchangev1.ExchangeRequest{TargetService: target, Scopes: []string{"payments:charge:all"}},
+			wantMsg: "scope 0 too long: 19 bytes exceeds maximum of 16",
+		},
+		{
+			name:    "custom target length",
+			limits:  &custom,
+			req:     &exchangev1.ExchangeRequest{TargetService: target + "/extra/segments/here/x", Scopes: []string{"payments:charge"}},
+			wantMsg: "target_service too long: 66 bytes exceeds maximum of 64",
+		},
+		{
+			name:   "zero fields keep the defaults",
+			limits: &server.Limits{},
+			req:    &exchangev1.ExchangeRequest{TargetService: target, Scopes: make([]string, server.DefaultLimits.MaxScopes)},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scopes := slices.Clone(tc.req.Scopes)
+			svc := server.New(okExtractor(), allowedPolicy(scopes, 300), okMinter(), mockAudit{})
+			if tc.limits != nil {
+				svc.SetLimits(*tc.limits)
+			}
+			_, err := svc.Exchange(context.Background(), tc.req)
+			if tc.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("Exchange: %v", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(status.Convert(err).Message(), tc.wantMsg) {
+				t.Errorf("err = %v, want InvalidArgument containing %q", err, tc.wantMsg)
+			}
+		})
+	}
+}
+
+func TestTokenQuota(t *testing.T) {
+	tests := []struct {
+		name       string
+		quota      *mockQuota
+		wantCode   codes.Code
+		wantDenial bool
+	}{
+		{name: "under quota is granted", quota: &mockQuota{ok: true}, wantCode: codes.OK},
+		{name: "quota exceeded returns ResourceExhausted", quota: &mockQuota{ok: false}, wantCode: codes.ResourceExhausted, wantDenial: true},
+		{name: "quota store failure returns Internal", quota: &mockQuota{err: errors.New("disk full")}, wantCode: codes.Internal},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), rec)
+			svc.SetTokenQuota(tc.quota, 3)
+			_, err := svc.Exchange(context.Background(), newValidReq())
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			if tc.quota.subject != okExtractor().id || tc.quota.target != newValidReq().TargetService || tc.quota.limit != 3 {
+				t.Errorf("ReserveToken(%q, %q, limit %d), want caller, target, and limit 3", tc.quota.subject, tc.quota.target, tc.quota.limit)
+			}
+			if tc.wantDenial && (len(rec.events) != 1 || rec.events[0].Granted || !strings.Contains(rec.events[0].DenialReason, "quota")) {
+				t.Errorf("audit events = %+v, want one quota denial", rec.events)
+			}
+		})
+	}
+
+	t.Run("zero limit disables the quota", func(t *testing.T) {
+		q := &mockQuota{ok: false}
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), mockAudit{})
+		svc.SetTokenQuota(q, 0)
+		if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+			t.Errorf("Exchange: %v", err)
+		}
+	})
+}
+
+func TestRedactor(t *testing.T) {
+	r, err := audit.NewRedactor(audit.RedactRemove, audit.RedactNone, nil)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	rec := &recordingAudit{}
+	svc := server.New(okExtractor(), deniedPolicy(), okMinter(), rec)
+	svc.SetRedactor(r)
+	_, err = svc.Exchange(context.Background(), newValidReq())
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+	}
+	msg := status.Convert(err).Message()
+	if strings.Contains(msg, okExtractor().id) || !strings.Contains(msg, "spiffe://cluster.local/[redacted]") {
+		t.Errorf("message = %q, want the caller redacted", msg)
+	}
+	if len(rec.events) != 1 || !strings.HasPrefix(msg, rec.events[0].DenialReason) || rec.events[0].Subject != okExtractor().id {
+		t.Errorf("audit events = %+v, want the redacted reason and the raw subject", rec.events)
+	}
+
+	// A denial served from the denial cache is redacted the same way.
+	svc.SetDenialCache(time.Minute, time.Minute)
+	for range 2 {
+		_, err = svc.Exchange(context.Background(), newValidReq())
+	}
+	if msg := status.Convert(err).Message(); strings.Contains(msg, okExtractor().id) {
+		t.Errorf("cached denial message = %q, want the caller redacted", msg)
+	}
+}
+
+func TestMaxTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		ceiling     time.Duration
+		policyTTL   int32
+		wantTTL     int32
+		wantClamped int32
+	}{
+		{name: "under the ceiling", ceiling: time.Hour, policyTTL: 300, wantTTL: 300},
+		{name: "clamped to the ceiling", ceiling: 2 * time.Minute, policyTTL: 300, wantTTL: 120, wantClamped: 300},
+		{name: "no ceiling", policyTTL: 7200, wantTTL: 7200},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			minter := okMinter()
+			svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, tc.policyTTL), minter, rec)
+			svc.SetMaxTTL(tc.ceiling)
+			if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if minter.lastTTL != tc.wantTTL {
+				t.Errorf("minted TTL %d, want %d", minter.lastTTL, tc.wantTTL)
+			}
+			if len(rec.events) != 1 || rec.events[0].TTL != tc.wantTTL || rec.events[0].TTLClampedFrom != tc.wantClamped {
+				t.Errorf("audit events = %+v, want ttl %d clamped from %d", rec.events, tc.wantTTL, tc.wantClamped)
+			}
+		})
+	}
+}
+
+func TestAuditTraceID(t *testing.T) {
+	rec := &recordingAudit{}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	for _, ctx := range []context.Context{trace.ContextWithSpanContext(context.Background(), sc), context.Background()} {
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 60), okMinter(), rec)
+		if _, err := svc.Exchange(ctx, newValidReq()); err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+	}
+	if len(rec.events) != 2 {
+		t.Fatalf("got %d audit events, want 2", len(rec.events))
+	}
+	if got := rec.events[0].TraceID; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q, want the call's trace ID", got)
+	}
+	if got := rec.events[1].TraceID; got != "" {
+		t.Errorf("untraced call has TraceID %q", got)
+	}
+}
+
+// durableAudit is a DurableAuditLogger whose RecordExchange fails with err.
+type durableAudit struct {
+	recordingAudit
+	err error
+}
+
+func (d *durableAudit) RecordExchange(e audit.ExchangeEvent) error {
+	d.LogExchange(e)
+	return d.err
+}
+
+func TestAuditRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		required bool
+		err      error
+		policy   server.PolicyEvaluator
+		wantCode codes.Code
+	}{
+		{"recorded", true, nil, allowedPolicy([]string{"payments:charge"}, 60), codes.OK},
+		{"spool failure withholds token", true, errors.New("disk full"), allowedPolicy([]string{"payments:charge"}, 60), codes.Unavailable},
+		{"spool failure ignored when not required", false, errors.New("disk full"), allowedPolicy([]string{"payments:charge"}, 60), codes.OK},
+		{"denial unaffected", true, errors.New("disk full"), deniedPolicy(), codes.PermissionDenied},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &durableAudit{err: tc.err}
+			svc := server.New(okExtractor(), tc.policy, okMinter(), a)
+			svc.SetAuditRequired(tc.required)
+			resp, err := svc.Exchange(context.Background(), newValidReq())
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			if err != nil && resp != nil {
+				t.Error("failed Exchange returned a response")
+			}
+			if len(a.events) != 1 {
+				t.Errorf("got %d audit events, want 1", len(a.events))
+			}
+		})
+	}
+
+	t.Run("withheld token leaves quota and grants unchanged", func(t *testing.T) {
+		store, err := policy.OpenStore(filepath.Join(t.TempDir(), "policy.db"))
+		if err != nil {
+			t.Fatalf("open store: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 60), okMinter(), &durableAudit{err: errors.New("disk full")})
+		svc.SetAuditRequired(true)
+		svc.SetTokenQuota(store, 1)
+		svc.SetGrantStore(store)
+		if _, err := svc.Exchange(context.Background(), newValidReq()); status.Code(err) != codes.Unavailable {
+			t.Fatalf("code = %v, want Unavailable", status.Code(err))
+		}
+		grants, err := store.ListGrants(okExtractor().id, time.Now().Unix())
+		if err != nil || len(grants) != 0 {
+			t.Errorf("grants = %+v, %v; want none", grants, err)
+		}
+		ok, err := store.ReserveToken(okExtractor().id, newValidReq().TargetService, "other-jti", time.Now().Add(time.Minute).Unix(), 1)
+		if err != nil || !ok {
+			t.Errorf("ReserveToken = %t, %v; want the quota unused", ok, err)
+		}
+	})
+
+	t.Run("switched at runtime", func(t *testing.T) {
+		m, err := token.NewMinter()
+		if err != nil {
+			t.Fatalf("create minter: %v", err)
+		}
+		a := &durableAudit{err: errors.New("disk full")}
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 60), m, a)
+		svc.SetAuditRequired(true)
+		if _, err := svc.Exchange(context.Background(), newValidReq()); status.Code(err) != codes.Unavailable {
+			t.Fatalf("fail closed: code = %v, want Unavailable", status.Code(err))
+		}
+		svc.SetAuditRequired(false)
+		if svc.AuditRequired() {
+			t.Error("AuditRequired = true after switching to fail open")
+		}
+		if _, err := svc.Exchange(context.Background(), newValidReq()); err != nil {
+			t.Errorf("fail open: %v", err)
+		}
+	})
+}
+
+// ttlPolicy grants every request the TTL it asks for, or max when it asks
+// for none or more, like a policy with max_ttl max.
+type ttlPolicy struct{ max int32 }
+
+func (p ttlPolicy) Evaluate(_ context.Context, _, _ string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	if ttlSeconds <= 0 || ttlSeconds > p.max {
+		ttlSeconds = p.max
+	}
+	return policy.EvalResult{Allowed: true, GrantedScopes: scopes, GrantedTTL: ttlSeconds}, nil
+}
+
+func TestRequestedTTLCaps(t *testing.T) {
+	// use_until is in whole seconds; the clock sits half a second past one
+	// so that rounding up is observable.
+	base := time.Now().Truncate(time.Second)
+	now := base.Add(500 * time.Millisecond)
+	tests := []struct {
+		name        string
+		ttl         int32
+		useUntil    time.Time
+		deadline    time.Duration
+		deadlineTTL bool
+		wantTTL     int32
+		wantCode    codes.Code
+	}{
+		{name: "use_until caps the policy max", useUntil: base.Add(90 * time.Second), wantTTL: 90},
+		{name: "use_until rounds up", useUntil: base.Add(2 * time.Second), wantTTL: 2},
+		{name: "shorter requested TTL wins", ttl: 30, useUntil: base.Add(90 * time.Second), wantTTL: 30},
+		{name: "use_until beyond the policy max", useUntil: base.Add(time.Hour), wantTTL: 300},
+		{name: "use_until in the past", useUntil: base, wantCode: codes.InvalidArgument},
+		{name: "deadline ignored by default", deadline: time.Minute, wantTTL: 300},
+		{name: "deadline caps the TTL", deadline: time.Minute, deadlineTTL: true, wantTTL: 60},
+		{name: "earlier of deadline and use_until", deadline: time.Minute, deadlineTTL: true, useUntil: base.Add(45 * time.Second), wantTTL: 45},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			minter := okMinter()
+			svc := server.New(okExtractor(), ttlPolicy{max: 300}, minter, mockAudit{})
+			svc.SetClock(clock.NewFake(now))
+			svc.SetDeadlineTTL(tc.deadlineTTL)
+			ctx := context.Background()
+			if tc.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tc.deadline))
+				defer cancel()
+			}
+			req := newValidReq()
+			req.TtlSeconds = tc.ttl
+			if !tc.useUntil.IsZero() {
+				req.UseUntil = tc.useUntil.Unix()
+			}
+			_, err := svc.Exchange(ctx, req)
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", status.Code(err), tc.wantCode, err)
+			}
+			if tc.wantCode == codes.OK && minter.lastTTL != tc.wantTTL {
+				t.Errorf("minted TTL %d, want %d", minter.lastTTL, tc.wantTTL)
+			}
+		})
+	}
+}
+
 func TestContextCancellation(t *testing.T) {
 	t.Run("cancelled before policy eval returns Canceled", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -152,22 +961,36 @@ func TestReplayAndRevocation(t *testing.T) {
 		}
 	})
 
+	t.Run("IsRevoked reflects Revoke", func(t *testing.T) {
+		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), okMinter(), mockAudit{})
+
+		if svc.IsRevoked("test-jti") {
+			t.Error("IsRevoked = true before Revoke")
+		}
+		svc.Revoke("test-jti", time.Now().Add(time.Minute))
+		if !svc.IsRevoked("test-jti") {
+			t.Error("IsRevoked = false after Revoke")
+		}
+	})
+
 	t.Run("expired JTI is not treated as a replay", func(t *testing.T) {
 		// Mint with TTL=1; after expiry the cache entry is swept and a second
 		// exchange with the same JTI is allowed again.
+		fake := clock.NewFake(time.Now())
 		shortMinter := &mockMinter{result: token.MintResult{
 			Token:     "signed-jwt",
 			TokenID:   "short-lived-jti",
-			ExpiresAt: time.Now().Add(1 * time.Second),
+			ExpiresAt: fake.Now().Add(1 * time.Second),
 		}}
 		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 1), shortMinter, mockAudit{})
+		svc.SetClock(fake)
 
 		_, err := svc.Exchange(context.Background(), newValidReq())
 		if err != nil {
 			t.Fatalf("first exchange failed: %v", err)
 		}
 
-		time.Sleep(1100 * time.Millisecond)
+		fake.Advance(1100 * time.Millisecond)
 
 		_, err = svc.Exchange(context.Background(), newValidReq())
 		if err != nil {
@@ -292,6 +1115,18 @@ func TestExchange(t *testing.T) {
 			},
 			wantCode: codes.Internal,
 		},
+		{
+			name:      "signing queue full returns Unavailable",
+			extractor: okExtractor(),
+			policy:    allowedPolicy([]string{"payments:charge"}, 60),
+			minter:    &mockMinter{err: fmt.Errorf("sign token: %w", token.ErrSigningQueueFull)},
+			req: &exchangev1.ExchangeRequest{
+				TargetService: "spiffe://cluster.local/ns/default/sa/payment",
+				Scopes:        []string{"payments:charge"},
+				TtlSeconds:    60,
+			},
+			wantCode: codes.Unavailable,
+		},
 		{
 			name:      "delegation: malformed on_behalf_of rejected",
 			extractor: okExtractor(),
@@ -359,6 +1194,7 @@ func TestOnBehalfOf(t *testing.T) {
 
 	// Mint a valid delegate token (sub = "user-xyz").
 	delegateResult, err := delegateMinter.Mint(
+		context.Background(),
 		"user-xyz",
 		"spiffe://cluster.local/ns/default/sa/payment",
 		[]string{"read"}, 300, "")
@@ -407,14 +1243,17 @@ func TestOnBehalfOf(t *testing.T) {
 	})
 
 	t.Run("expired on_behalf_of is rejected", func(t *testing.T) {
+		// Issued two seconds ago with a one-second TTL.
+		delegateMinter.SetClock(clock.NewFake(time.Now().Add(-2 * time.Second)))
 		expiredResult, err := delegateMinter.Mint(
+			context.Background(),
 			"user-xyz",
 			"spiffe://cluster.local/ns/default/sa/payment",
 			[]string{"read"}, 1, "")
+		delegateMinter.SetClock(nil)
 		if err != nil {
 			t.Fatalf("mint expired token: %v", err)
 		}
-		time.Sleep(1100 * time.Millisecond)
 
 		svc := server.New(okExtractor(), allowedPolicy([]string{"payments:charge"}, 300), exchangeMinter, mockAudit{})
 		_, err = svc.Exchange(context.Background(), &exchangev1.ExchangeRequest{
@@ -428,3 +1267,40 @@ func TestOnBehalfOf(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenFormat(t *testing.T) {
+	withFormat := func(format string) mockPolicy {
+		p := allowedPolicy([]string{"payments:charge"}, 300)
+		p.result.TokenFormat = format
+		return p
+	}
+	svidResult := token.MintResult{Token: "svid-jwt", TokenID: "svid-jti", ExpiresAt: time.Now().Add(time.Minute)}
+
+	tests := []struct {
+		name      string
+		format    string
+		register  bool
+		wantCode  codes.Code
+		wantToken string
+	}{
+		{name: "unset format uses default minter", format: "", wantToken: "signed-jwt"},
+		{name: "jwt format uses default minter", format: policy.FormatJWT, wantToken: "signed-jwt"},
+		{name: "registered format uses its minter", format: policy.FormatJWTSVID, register: true, wantToken: "svid-jwt"},
+		{name: "unregistered format is refused", format: policy.FormatJWTSVID, wantCode: codes.FailedPrecondition},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := server.New(okExtractor(), withFormat(tc.format), okMinter(), mockAudit{})
+			if tc.register {
+				svc.RegisterFormat(policy.FormatJWTSVID, &mockMinter{result: svidResult})
+			}
+			resp, err := svc.Exchange(context.Background(), newValidReq())
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			if err == nil && resp.Token != tc.wantToken {
+				t.Errorf("token = %q, want %q", resp.Token, tc.wantToken)
+			}
+		})
+	}
+}
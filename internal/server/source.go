@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// SetSourceBinding makes the server bind every token it mints to the
+// caller's network identity: the peer address of the Exchange call and, for
+// callers whose credential names one, their pod. See token.Source. It must
+// be called before the server starts handling requests.
+func (s *TokenExchangeServer) SetSourceBinding(enabled bool) {
+	s.bindSource = enabled
+}
+
+// callerSource returns the network identity of the caller of ctx.
+func callerSource(ctx context.Context, caller Identity) token.Source {
+	src := token.Source{Pod: caller.Pod, PodUID: caller.PodUID}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		src.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(src.IP); err == nil {
+			src.IP = host
+		}
+	}
+	return src
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// podExtractor identifies every caller as order, running in a pod.
+type podExtractor struct{}
+
+func (podExtractor) ExtractID(ctx context.Context) (string, error) {
+	ident, err := podExtractor{}.ExtractIdentity(ctx)
+	return ident.ID, err
+}
+
+func (podExtractor) ExtractIdentity(context.Context) (server.Identity, error) {
+	return server.Identity{ID: "spiffe://cluster.local/ns/default/sa/order", Pod: "default/order-7d9f8-x2x4p", PodUID: "uid-1"}, nil
+}
+
+func TestSourceBinding(t *testing.T) {
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("create minter: %v", err)
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.4.0.17"), Port: 53122}})
+
+	for _, bind := range []bool{false, true} {
+		svc := server.New(podExtractor{}, allowedPolicy([]string{"payments:charge"}, 60), m, mockAudit{})
+		svc.SetSourceBinding(bind)
+		resp, err := svc.Exchange(ctx, newValidReq())
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		claims, err := token.VerifyClaims(resp.Token, m.PublicKeys(), "")
+		if err != nil {
+			t.Fatalf("verify token: %v", err)
+		}
+		src, _ := claims["src"].(map[string]any)
+		if !bind {
+			if src != nil {
+				t.Errorf("unbound token carries src %v", src)
+			}
+			continue
+		}
+		if src["ip"] != "10.4.0.17" || src["pod"] != "default/order-7d9f8-x2x4p" || src["pod_uid"] != "uid-1" {
+			t.Errorf("src = %v, want ip 10.4.0.17, pod default/order-7d9f8-x2x4p, pod_uid uid-1", src)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownTarget is returned by a TargetValidator for a target SPIFFE ID
+// that no known workload holds.
+var ErrUnknownTarget = errors.New("target is not a known workload")
+
+// TargetValidator checks an exchange's target against a registry of
+// workloads. ValidateTarget returns nil when target, a canonical SPIFFE ID,
+// names a known workload, an error wrapping ErrUnknownTarget when it does
+// not, and any other error when the registry could not be consulted. It must
+// return promptly once ctx is done.
+type TargetValidator interface {
+	ValidateTarget(ctx context.Context, target string) error
+}
+
+// StaticTargets is a TargetValidator that knows a fixed set of SPIFFE IDs.
+type StaticTargets map[string]bool
+
+// NewStaticTargets returns a StaticTargets knowing ids, which must be
+// canonical SPIFFE IDs (see policy.NormalizeSPIFFEID).
+func NewStaticTargets(ids []string) StaticTargets {
+	t := make(StaticTargets, len(ids))
+	for _, id := range ids {
+		t[id] = true
+	}
+	return t
+}
+
+// ValidateTarget implements TargetValidator.
+func (t StaticTargets) ValidateTarget(_ context.Context, target string) error {
+	if !t[target] {
+		return ErrUnknownTarget
+	}
+	return nil
+}
+
+// SetTargetValidator makes the server check the target of every exchange
+// policy grants with v, before minting. An exchange for an unknown target
+// fails with NotFound and is audited as a denial, so that a mistyped target
+// matched by a pattern rule does not yield a token no service accepts; an
+// exchange whose target could not be checked fails with Unavailable. The
+// check runs under the policy evaluation timeout. A nil v disables it. It
+// must be called before the server starts handling requests.
+func (s *TokenExchangeServer) SetTargetValidator(v TargetValidator) {
+	s.targets = v
+}
@@ -0,0 +1,109 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// targetsFunc adapts a function to server.TargetValidator.
+type targetsFunc func(ctx context.Context, target string) error
+
+func (f targetsFunc) ValidateTarget(ctx context.Context, target string) error { return f(ctx, target) }
+
+func TestStaticTargets(t *testing.T) {
+	v := server.NewStaticTargets([]string{"spiffe://cluster.local/ns/default/sa/payment"})
+	if err := v.ValidateTarget(context.Background(), "spiffe://cluster.local/ns/default/sa/payment"); err != nil {
+		t.Errorf("known target: %v", err)
+	}
+	if err := v.ValidateTarget(context.Background(), "spiffe://cluster.local/ns/default/sa/paymnet"); !errors.Is(err, server.ErrUnknownTarget) {
+		t.Errorf("unknown target: err = %v, want ErrUnknownTarget", err)
+	}
+}
+
+func TestExchangeTargetValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		validator  server.TargetValidator
+		policy     server.PolicyEvaluator
+		wantCode   codes.Code
+		wantMinted bool
+		wantDenial bool
+	}{
+		{
+			name:       "no validator mints",
+			policy:     allowedPolicy([]string{"payments:charge"}, 300),
+			wantMinted: true,
+		},
+		{
+			name:       "known target mints",
+			validator:  server.NewStaticTargets([]string{"spiffe://cluster.local/ns/default/sa/payment"}),
+			policy:     allowedPolicy([]string{"payments:charge"}, 300),
+			wantMinted: true,
+		},
+		{
+			name:       "unknown target is denied and audited",
+			validator:  server.NewStaticTargets([]string{"spiffe://cluster.local/ns/default/sa/inventory"}),
+			policy:     allowedPolicy([]string{"payments:charge"}, 300),
+			wantCode:   codes.NotFound,
+			wantDenial: true,
+		},
+		{
+			name: "registry failure is unavailable",
+			validator: targetsFunc(func(context.Context, string) error {
+				return errors.New("registry down")
+			}),
+			policy:   allowedPolicy([]string{"payments:charge"}, 300),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name: "wrapped unknown target is denied",
+			validator: targetsFunc(func(_ context.Context, target string) error {
+				return fmt.Errorf("%s: %w", target, server.ErrUnknownTarget)
+			}),
+			policy:     allowedPolicy([]string{"payments:charge"}, 300),
+			wantCode:   codes.NotFound,
+			wantDenial: true,
+		},
+		{
+			name: "policy denial comes first",
+			validator: targetsFunc(func(context.Context, string) error {
+				t.Error("validator consulted for a denied exchange")
+				return nil
+			}),
+			policy:     deniedPolicy(),
+			wantCode:   codes.PermissionDenied,
+			wantDenial: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			m := okMinter()
+			m.block = !tc.wantMinted // a mint that was not expected hangs
+			svc := server.New(okExtractor(), tc.policy, m, rec)
+			svc.SetTargetValidator(tc.validator)
+			svc.SetStageTimeouts(0, time.Millisecond)
+
+			resp, err := svc.Exchange(context.Background(), newValidReq())
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("code = %v, want %v (err %v)", got, tc.wantCode, err)
+			}
+			if tc.wantMinted && resp.GetToken() == "" {
+				t.Error("no token minted")
+			}
+			if tc.wantDenial {
+				if len(rec.events) != 1 || rec.events[0].Granted || rec.events[0].DenialReason == "" {
+					t.Errorf("audit events = %+v, want one denial", rec.events)
+				}
+			}
+		})
+	}
+}
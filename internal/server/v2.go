@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// v2Server serves exchange.v2.TokenExchange through the same exchange core
+// as the v1 service.
+type v2Server struct {
+	exchangev2.UnimplementedTokenExchangeServer
+	s *TokenExchangeServer
+}
+
+// V2 returns the exchange.v2 TokenExchange service backed by s. Both
+// versions share s's policy, minters, limits, caches, and audit logger.
+func (s *TokenExchangeServer) V2() exchangev2.TokenExchangeServer {
+	return &v2Server{s: s}
+}
+
+// Exchange is the v2 form of TokenExchangeServer.Exchange. A well-formed
+// request_id takes precedence over RequestIDHeader metadata.
+func (v *v2Server) Exchange(ctx context.Context, req *exchangev2.ExchangeRequest) (*exchangev2.ExchangeResponse, error) {
+	reqID := req.RequestId
+	if !validRequestID(reqID) {
+		reqID = requestID(ctx)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, reqID))
+
+	if err := checkV2Unsupported(req); err != nil {
+		return nil, withRequestID(err, reqID)
+	}
+	view := req.View
+	switch view {
+	case exchangev2.ResponseView_RESPONSE_VIEW_UNSPECIFIED:
+		view = exchangev2.ResponseView_RESPONSE_VIEW_FULL
+	case exchangev2.ResponseView_RESPONSE_VIEW_FULL,
+		exchangev2.ResponseView_RESPONSE_VIEW_TOKEN,
+		exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT:
+	default:
+		return nil, withRequestID(status.Errorf(codes.InvalidArgument, "unknown view %d", view), reqID)
+	}
+	preflight := view == exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+	if req.IncludeReceipt {
+		if v.s.receipts == nil {
+			return nil, withRequestID(status.Error(codes.FailedPrecondition, "decision receipts are not enabled on this server"), reqID)
+		}
+		if preflight {
+			return nil, withRequestID(status.Error(codes.InvalidArgument, "include_receipt cannot be combined with a preflight"), reqID)
+		}
+	}
+	out, err := v.s.exchange(ctx, exchangeInput{
+		target:          req.TargetService,
+		scopes:          req.Scopes,
+		ttlSeconds:      req.TtlSeconds,
+		onBehalfOf:      req.GetDelegation().GetToken(),
+		onBehalfOfField: "delegation.token",
+		preflight:       preflight,
+		receipt:         req.IncludeReceipt,
+		nonce:           req.Nonce,
+		useUntil:        req.UseUntil,
+		audiences:       req.Audiences,
+	}, reqID)
+	if err != nil {
+		return nil, withRequestID(err, reqID)
+	}
+	return v2Response(out, reqID, view), nil
+}
+
+// v2Response encodes out with the fields view selects.
+func v2Response(out exchangeOutput, reqID string, view exchangev2.ResponseView) *exchangev2.ExchangeResponse {
+	resp := &exchangev2.ExchangeResponse{Receipt: out.receipt}
+	if view != exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT {
+		resp.Token = out.minted.Token
+		resp.ExpiresAt = out.minted.ExpiresAt.Unix()
+	}
+	if view == exchangev2.ResponseView_RESPONSE_VIEW_TOKEN {
+		return resp
+	}
+	if view == exchangev2.ResponseView_RESPONSE_VIEW_FULL {
+		resp.TokenId = out.minted.TokenID
+	}
+	resp.GrantedScopes = out.result.GrantedScopes
+	resp.TokenFormat = out.format
+	resp.GrantedTtlSeconds = out.result.GrantedTTL
+	resp.RequestId = reqID
+	resp.PolicyRules = out.result.MatchedRules
+	return resp
+}
+
+// WhoAmI reports the identity Exchange would authenticate the caller as, in
+// the canonical form Exchange uses. An
+// authentication failure is returned as Unauthenticated with the same detail
+// Exchange gives, so a caller can diagnose it without attempting an exchange.
+func (v *v2Server) WhoAmI(ctx context.Context, _ *exchangev2.WhoAmIRequest) (*exchangev2.WhoAmIResponse, error) {
+	caller, err := v.s.authenticate(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "extract SPIFFE ID: %v", err)
+	}
+	id, err := policy.NormalizeSPIFFEID(caller.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid caller SPIFFE ID: %v", err)
+	}
+	resp := &exchangev2.WhoAmIResponse{SpiffeId: id, AuthMethod: caller.Method}
+	if c := audit.NewCertInfo(caller.Cert); c != nil {
+		resp.Certificate = &exchangev2.Certificate{
+			Serial:      c.Serial,
+			Fingerprint: c.Fingerprint,
+			NotAfter:    c.NotAfter.Unix(),
+			Issuer:      c.Issuer,
+		}
+	}
+	return resp, nil
+}
+
+// checkV2Unsupported rejects requests for token properties the minters
+// cannot yet provide, so a caller never receives a token lacking a binding
+// or audience it asked for. Claim hints are advisory and are not checked.
+func checkV2Unsupported(req *exchangev2.ExchangeRequest) error {
+	for _, aud := range req.Audiences {
+		if aud != req.TargetService {
+			return status.Errorf(codes.Unimplemented, "audiences: only target_service is supported, got %q", aud)
+		}
+	}
+	if req.GetProofOfPossession().GetKey() != nil {
+		return status.Error(codes.Unimplemented, "proof_of_possession is not supported")
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package server_test
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+func newValidV2Req() *exchangev2.ExchangeRequest {
+	return &exchangev2.ExchangeRequest{
+		TargetService: "spiffe://cluster.local/ns/default/sa/payment",
+		Scopes:        []string{"payments:charge"},
+		TtlSeconds:    300,
+	}
+}
+
+func TestExchangeV2(t *testing.T) {
+	p := allowedPolicy([]string{"payments:charge"}, 120)
+	p.result.TokenFormat = policy.FormatJWT
+	p.result.MatchedRules = []string{"order-to-payment"}
+
+	t.Run("grant reports format, TTL, request ID, and rules", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), p, okMinter(), rec).V2()
+		req := newValidV2Req()
+		req.RequestId = "incident-4711"
+		req.Audiences = []string{req.TargetService}
+		req.ClaimHints = map[string]string{"tenant": "acme"}
+
+		resp, err := svc.Exchange(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if resp.Token != "signed-jwt" || resp.TokenId != "test-jti" || !slices.Equal(resp.GrantedScopes, []string{"payments:charge"}) {
+			t.Errorf("response = %+v", resp)
+		}
+		if resp.TokenFormat != policy.FormatJWT || resp.GrantedTtlSeconds != 120 || resp.RequestId != "incident-4711" ||
+			!slices.Equal(resp.PolicyRules, p.result.MatchedRules) {
+			t.Errorf("response = %+v", resp)
+		}
+		if len(rec.events) != 1 || rec.events[0].RequestID != "incident-4711" {
+			t.Errorf("audit events = %+v, want one with the body's request ID", rec.events)
+		}
+	})
+
+	t.Run("request ID falls back to metadata", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{}).V2()
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(server.RequestIDHeader, "from-header"))
+		req := newValidV2Req()
+		req.RequestId = "has space"
+		resp, err := svc.Exchange(ctx, req)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if resp.RequestId != "from-header" {
+			t.Errorf("RequestId = %q, want from-header", resp.RequestId)
+		}
+	})
+
+	t.Run("token view returns only the token", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, okMinter(), mockAudit{}).V2()
+		req := newValidV2Req()
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_TOKEN
+		resp, err := svc.Exchange(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if resp.Token != "signed-jwt" || resp.ExpiresAt == 0 {
+			t.Errorf("response = %+v, want the token and its expiry", resp)
+		}
+		if resp.TokenId != "" || resp.GrantedScopes != nil || resp.TokenFormat != "" || resp.RequestId != "" || resp.PolicyRules != nil {
+			t.Errorf("response = %+v, want no metadata", resp)
+		}
+	})
+
+	t.Run("preflight evaluates policy without minting", func(t *testing.T) {
+		rec := &recordingAudit{}
+		// A failing minter proves the preflight never reaches it.
+		svc := server.New(okExtractor(), p, &mockMinter{err: errors.New("mint called")}, rec).V2()
+		req := newValidV2Req()
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+		resp, err := svc.Exchange(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if resp.Token != "" || resp.ExpiresAt != 0 || resp.TokenId != "" {
+			t.Errorf("response = %+v, want no token", resp)
+		}
+		if !slices.Equal(resp.GrantedScopes, []string{"payments:charge"}) || resp.GrantedTtlSeconds != 120 || resp.TokenFormat != policy.FormatJWT {
+			t.Errorf("response = %+v, want the policy decision", resp)
+		}
+		if len(rec.events) != 1 || !rec.events[0].Preflight || !rec.events[0].Granted {
+			t.Errorf("audit events = %+v, want one granted preflight", rec.events)
+		}
+	})
+
+	t.Run("denied preflight fails like an exchange", func(t *testing.T) {
+		rec := &recordingAudit{}
+		svc := server.New(okExtractor(), deniedPolicy(), okMinter(), rec).V2()
+		req := newValidV2Req()
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+		_, err := svc.Exchange(context.Background(), req)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("code = %v, want PermissionDenied", status.Code(err))
+		}
+		if len(rec.events) != 1 || !rec.events[0].Preflight || rec.events[0].Granted {
+			t.Errorf("audit events = %+v, want one denied preflight", rec.events)
+		}
+	})
+
+	t.Run("receipt describes the decision", func(t *testing.T) {
+		signer, err := token.NewMinter()
+		if err != nil {
+			t.Fatalf("NewMinter: %v", err)
+		}
+		digested := p
+		digested.result.PolicyDigest = "digest"
+		srv := server.New(okExtractor(), digested, okMinter(), mockAudit{})
+		srv.SetReceiptSigner(signer)
+		svc := srv.V2()
+
+		req := newValidV2Req()
+		req.RequestId = "incident-4711"
+		req.IncludeReceipt = true
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_TOKEN
+		resp, err := svc.Exchange(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		r, err := token.VerifyReceipt(resp.Receipt, signer.PublicKeys())
+		if err != nil {
+			t.Fatalf("VerifyReceipt: %v", err)
+		}
+		if r.TokenID != "test-jti" || r.TokenHash != token.ReceiptTokenHash(resp.Token) || r.RequestID != "incident-4711" ||
+			r.Subject != okExtractor().id || !slices.Equal(r.Rules, p.result.MatchedRules) || r.PolicyDigest != "digest" {
+			t.Errorf("receipt = %+v", r)
+		}
+
+		req = newValidV2Req()
+		req.IncludeReceipt = true
+		req.View = exchangev2.ResponseView_RESPONSE_VIEW_PREFLIGHT
+		if _, err := svc.Exchange(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("receipt for a preflight: code = %v, want InvalidArgument", status.Code(err))
+		}
+	})
+
+	tests := []struct {
+		name     string
+		modify   func(*exchangev2.ExchangeRequest)
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{
+			name: "invalid delegation token",
+			modify: func(r *exchangev2.ExchangeRequest) {
+				r.Delegation = &exchangev2.Delegation{Token: makeTestJWT("spiffe://td/user")}
+			},
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "delegation.token",
+		},
+		{
+			name: "audience other than the target",
+			modify: func(r *exchangev2.ExchangeRequest) {
+				r.Audiences = []string{r.TargetService, "https://payments.example.com"}
+			},
+			wantCode: codes.Unimplemented,
+			wantMsg:  "audiences",
+		},
+		{
+			name: "proof of possession",
+			modify: func(r *exchangev2.ExchangeRequest) {
+				r.ProofOfPossession = &exchangev2.ProofOfPossession{Key: &exchangev2.ProofOfPossession_JwkThumbprint{JwkThumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"}}
+			},
+			wantCode: codes.Unimplemented,
+			wantMsg:  "proof_of_possession",
+		},
+		{
+			name:     "shared validation applies",
+			modify:   func(r *exchangev2.ExchangeRequest) { r.Scopes = nil },
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "at least one scope",
+		},
+		{
+			name:     "unknown view",
+			modify:   func(r *exchangev2.ExchangeRequest) { r.View = 42 },
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "view",
+		},
+		{
+			name:     "receipt when receipts are disabled",
+			modify:   func(r *exchangev2.ExchangeRequest) { r.IncludeReceipt = true },
+			wantCode: codes.FailedPrecondition,
+			wantMsg:  "receipts",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recordingAudit{}
+			svc := server.New(okExtractor(), p, okMinter(), rec).V2()
+			req := newValidV2Req()
+			tc.modify(req)
+			_, err := svc.Exchange(context.Background(), req)
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.wantCode)
+			}
+			msg := status.Convert(err).Message()
+			if !strings.Contains(msg, tc.wantMsg) || !strings.Contains(msg, "[request_id=") {
+				t.Errorf("message %q, want it to mention %q and the request ID", msg, tc.wantMsg)
+			}
+			if len(rec.events) != 0 {
+				t.Errorf("audit events = %+v, want none for a rejected request", rec.events)
+			}
+		})
+	}
+}
+
+// leafExtractor is an IdentityExtractor returning a fixed certificate.
+type leafExtractor struct {
+	id   string
+	cert *x509.Certificate
+}
+
+func (l leafExtractor) ExtractID(context.Context) (string, error) { return l.id, nil }
+
+func (l leafExtractor) ExtractIdentity(context.Context) (server.Identity, error) {
+	return server.Identity{ID: l.id, Cert: l.cert}, nil
+}
+
+func TestWhoAmI(t *testing.T) {
+	const order = "spiffe://cluster.local/ns/default/sa/order"
+	leaf := &x509.Certificate{
+		Raw:          []byte("der"),
+		SerialNumber: big.NewInt(42),
+		NotAfter:     time.Unix(1767225600, 0),
+		Issuer:       pkix.Name{Organization: []string{"SPIRE"}},
+	}
+
+	t.Run("certificate caller", func(t *testing.T) {
+		chain := server.ExtractorChain{{Method: "x509-svid", IDExtractor: leafExtractor{id: order, cert: leaf}}}
+		svc := server.New(chain, allowedPolicy(nil, 0), okMinter(), mockAudit{}).V2()
+		resp, err := svc.WhoAmI(context.Background(), &exchangev2.WhoAmIRequest{})
+		if err != nil {
+			t.Fatalf("WhoAmI: %v", err)
+		}
+		if resp.SpiffeId != order || resp.AuthMethod != "x509-svid" {
+			t.Errorf("response = %+v", resp)
+		}
+		c := resp.Certificate
+		if c == nil || c.Serial != "2a" || c.NotAfter != 1767225600 || c.Issuer != "O=SPIRE" || len(c.Fingerprint) != 64 {
+			t.Errorf("certificate = %+v", c)
+		}
+	})
+
+	t.Run("token caller has no certificate", func(t *testing.T) {
+		chain := server.ExtractorChain{{Method: "k8s-sa-token", IDExtractor: mockExtractor{id: order}}}
+		svc := server.New(chain, allowedPolicy(nil, 0), okMinter(), mockAudit{}).V2()
+		resp, err := svc.WhoAmI(context.Background(), &exchangev2.WhoAmIRequest{})
+		if err != nil {
+			t.Fatalf("WhoAmI: %v", err)
+		}
+		if resp.SpiffeId != order || resp.AuthMethod != "k8s-sa-token" || resp.Certificate != nil {
+			t.Errorf("response = %+v", resp)
+		}
+	})
+
+	t.Run("caller ID is canonicalised", func(t *testing.T) {
+		svc := server.New(mockExtractor{id: "SPIFFE://Cluster.Local/ns/default/sa/order"}, allowedPolicy(nil, 0), okMinter(), mockAudit{}).V2()
+		resp, err := svc.WhoAmI(context.Background(), &exchangev2.WhoAmIRequest{})
+		if err != nil {
+			t.Fatalf("WhoAmI: %v", err)
+		}
+		if resp.SpiffeId != order {
+			t.Errorf("SpiffeId = %q, want %q", resp.SpiffeId, order)
+		}
+	})
+
+	t.Run("authentication failure", func(t *testing.T) {
+		svc := server.New(mockExtractor{err: errors.New("certificate expired")}, allowedPolicy(nil, 0), okMinter(), mockAudit{}).V2()
+		_, err := svc.WhoAmI(context.Background(), &exchangev2.WhoAmIRequest{})
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "certificate expired") {
+			t.Errorf("err = %v, want Unauthenticated with the extractor's reason", err)
+		}
+	})
+}
+
+func TestExchangePolicyAudience(t *testing.T) {
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("create minter: %v", err)
+	}
+	p := allowedPolicy([]string{"payments:charge"}, 60)
+	p.result.Audience = []string{"https://payments.example.com"}
+
+	t.Run("token carries the policy audience", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, m, mockAudit{})
+		resp, err := svc.Exchange(context.Background(), newValidReq())
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if _, err := token.VerifyClaims(resp.Token, m.PublicKeys(), "https://payments.example.com"); err != nil {
+			t.Errorf("token does not verify for the policy audience: %v", err)
+		}
+	})
+
+	t.Run("v2 caller requiring the target audience is refused", func(t *testing.T) {
+		svc := server.New(okExtractor(), p, m, mockAudit{}).V2()
+		req := newValidV2Req()
+		req.Audiences = []string{req.TargetService}
+		_, err := svc.Exchange(context.Background(), req)
+		if status.Code(err) != codes.FailedPrecondition || !strings.Contains(status.Convert(err).Message(), "audiences") {
+			t.Errorf("err = %v, want FailedPrecondition naming the audiences", err)
+		}
+	})
+}
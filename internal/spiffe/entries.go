@@ -0,0 +1,119 @@
+package spiffe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// entryID is a SPIFFE ID as `spire-server entry show -output json` writes
+// it.
+type entryID struct {
+	TrustDomain string `json:"trust_domain"`
+	Path        string `json:"path"`
+}
+
+// entryList is the part of `spire-server entry show -output json` that
+// ParseEntries reads.
+type entryList struct {
+	Entries []struct {
+		SPIFFEID entryID `json:"spiffe_id"`
+		ParentID entryID `json:"parent_id"`
+	} `json:"entries"`
+}
+
+// Entry is a SPIRE registration entry.
+type Entry struct {
+	// SPIFFEID is the ID the entry issues, normalized.
+	SPIFFEID string
+	// ParentID is the ID of the agent or entry the workload is registered
+	// under, normalized, or empty if the export omits it.
+	ParentID string
+}
+
+// ParseEntries parses the output of `spire-server entry show -output json`.
+func ParseEntries(data []byte) ([]Entry, error) {
+	var list entryList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	out := make([]Entry, len(list.Entries))
+	for i, e := range list.Entries {
+		id, err := policy.NormalizeSPIFFEID("spiffe://" + e.SPIFFEID.TrustDomain + e.SPIFFEID.Path)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		out[i].SPIFFEID = id
+		if e.ParentID.TrustDomain == "" {
+			continue
+		}
+		if out[i].ParentID, err = policy.NormalizeSPIFFEID("spiffe://" + e.ParentID.TrustDomain + e.ParentID.Path); err != nil {
+			return nil, fmt.Errorf("entry %d: parent: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// EntryTargets implements server.TargetValidator from a SPIRE server's
+// registration entries: a target is known when some entry issues its
+// SPIFFE ID. The entries are read from a file holding the output of
+// `spire-server entry show -output json`, which an operator or sidecar keeps
+// current; Reload picks up a new export.
+type EntryTargets struct {
+	path string
+
+	mu  sync.RWMutex
+	ids map[string]bool
+}
+
+// LoadEntryTargets reads the registration entries exported to path.
+func LoadEntryTargets(path string) (*EntryTargets, error) {
+	t := &EntryTargets{path: path}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Reload re-reads the entries file. On failure the entries loaded before
+// stay in use.
+func (t *EntryTargets) Reload() error {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return fmt.Errorf("read SPIRE entries: %w", err)
+	}
+	entries, err := ParseEntries(data)
+	if err != nil {
+		return fmt.Errorf("parse SPIRE entries %s: %w", t.path, err)
+	}
+	ids := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		ids[e.SPIFFEID] = true
+	}
+	t.mu.Lock()
+	t.ids = ids
+	t.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of distinct SPIFFE IDs loaded.
+func (t *EntryTargets) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.ids)
+}
+
+// ValidateTarget implements server.TargetValidator.
+func (t *EntryTargets) ValidateTarget(_ context.Context, target string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.ids[target] {
+		return fmt.Errorf("%w: no SPIRE registration entry", server.ErrUnknownTarget)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package spiffe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+const entriesJSON = `{
+  "entries": [
+    {
+      "id": "2b8c8f0e-0d55-4c4e-8f0e-4a6f0d0b6c11",
+      "spiffe_id": {"trust_domain": "cluster.local", "path": "/ns/default/sa/payment"},
+      "parent_id": {"trust_domain": "cluster.local", "path": "/spire/agent/k8s_psat/demo"},
+      "selectors": [{"type": "k8s", "value": "sa:payment"}]
+    },
+    {
+      "id": "5e0e3a1c-7f4b-4a0e-9b4e-1c2d3e4f5a6b",
+      "spiffe_id": {"trust_domain": "Cluster.Local", "path": "/ns/default/sa/order"}
+    }
+  ],
+  "next_page_token": ""
+}`
+
+func writeEntries(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write entries: %v", err)
+	}
+}
+
+func TestEntryTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	writeEntries(t, path, entriesJSON)
+	v, err := LoadEntryTargets(path)
+	if err != nil {
+		t.Fatalf("LoadEntryTargets: %v", err)
+	}
+	if v.Len() != 2 {
+		t.Errorf("Len = %d, want 2", v.Len())
+	}
+
+	tests := []struct {
+		target  string
+		wantErr error
+	}{
+		{target: "spiffe://cluster.local/ns/default/sa/payment"},
+		{target: "spiffe://cluster.local/ns/default/sa/order"}, // entry's trust domain canonicalised
+		{target: "spiffe://cluster.local/ns/default/sa/paymnet", wantErr: server.ErrUnknownTarget},
+		{target: "spiffe://cluster.local/spire/agent/k8s_psat/demo", wantErr: server.ErrUnknownTarget}, // a parent, not an entry
+	}
+	for _, tc := range tests {
+		if err := v.ValidateTarget(context.Background(), tc.target); !errors.Is(err, tc.wantErr) {
+			t.Errorf("ValidateTarget(%q) = %v, want %v", tc.target, err, tc.wantErr)
+		}
+	}
+}
+
+func TestEntryTargetsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	writeEntries(t, path, `{"entries": []}`)
+	v, err := LoadEntryTargets(path)
+	if err != nil {
+		t.Fatalf("LoadEntryTargets: %v", err)
+	}
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+	if err := v.ValidateTarget(context.Background(), target); !errors.Is(err, server.ErrUnknownTarget) {
+		t.Fatalf("before reload: err = %v, want ErrUnknownTarget", err)
+	}
+
+	writeEntries(t, path, entriesJSON)
+	if err := v.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := v.ValidateTarget(context.Background(), target); err != nil {
+		t.Errorf("after reload: %v", err)
+	}
+
+	// A broken export keeps the last good entries.
+	writeEntries(t, path, `{"entries": [`)
+	if err := v.Reload(); err == nil {
+		t.Error("Reload of malformed JSON succeeded")
+	}
+	if err := v.ValidateTarget(context.Background(), target); err != nil {
+		t.Errorf("after failed reload: %v", err)
+	}
+}
+
+func TestLoadEntryTargetsErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadEntryTargets(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file: expected error")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	writeEntries(t, bad, `{"entries": [{"spiffe_id": {"trust_domain": "cluster local", "path": "/x"}}]}`)
+	if _, err := LoadEntryTargets(bad); err == nil {
+		t.Error("invalid SPIFFE ID: expected error")
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	entries, err := ParseEntries([]byte(entriesJSON))
+	if err != nil {
+		t.Fatalf("ParseEntries: %v", err)
+	}
+	want := []Entry{
+		{SPIFFEID: "spiffe://cluster.local/ns/default/sa/payment", ParentID: "spiffe://cluster.local/spire/agent/k8s_psat/demo"},
+		{SPIFFEID: "spiffe://cluster.local/ns/default/sa/order"},
+	}
+	if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+}
@@ -0,0 +1,57 @@
+package spiffe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+)
+
+// JWTSVIDHeader is the gRPC request metadata key carrying a JWT-SVID, as
+// "Bearer <token>" following the JWT-SVID specification's HTTP transport.
+const JWTSVIDHeader = "authorization"
+
+// ErrNoJWTSVID is returned by JWTSVIDExtractor when the request carries no
+// bearer token. It matches server.ErrNoCredentials.
+var ErrNoJWTSVID = server.NoCredentials("no JWT-SVID bearer token in request metadata")
+
+// JWTSVIDExtractor implements server.IDExtractor for workloads that hold
+// only a JWT-SVID. The token's signature is verified against the JWT bundle
+// for the trust domain in its sub claim, and it must be unexpired and issued
+// for one of the extractor's audiences. A JWT-SVID is a bearer token, so the
+// audience check is what stops a token meant for another service from being
+// replayed here.
+type JWTSVIDExtractor struct {
+	bundles   jwtbundle.Source
+	audiences []string
+}
+
+// NewJWTSVIDExtractor returns an extractor verifying tokens against bundles,
+// typically a workloadapi.JWTSource, and accepting those whose aud claim
+// contains any of audiences.
+func NewJWTSVIDExtractor(bundles jwtbundle.Source, audiences []string) *JWTSVIDExtractor {
+	return &JWTSVIDExtractor{bundles: bundles, audiences: audiences}
+}
+
+// ExtractID implements server.IDExtractor.
+func (e *JWTSVIDExtractor) ExtractID(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get(JWTSVIDHeader)
+	if len(vals) == 0 {
+		return "", ErrNoJWTSVID
+	}
+	scheme, tok, ok := strings.Cut(vals[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || tok == "" {
+		return "", ErrNoJWTSVID
+	}
+	svid, err := jwtsvid.ParseAndValidate(tok, e.bundles, e.audiences)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT-SVID: %w", err)
+	}
+	return svid.ID.String(), nil
+}
@@ -0,0 +1,114 @@
+package spiffe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// mintJWTSVID returns a JWT-SVID for subject with audience aud, signed by m.
+func mintJWTSVID(t *testing.T, m *token.Minter, subject, aud string) string {
+	t.Helper()
+	res, err := token.NewSVIDMinter(m).Mint(context.Background(), subject, aud, nil, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	return res.Token
+}
+
+func ctxWithAuthorization(v string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(JWTSVIDHeader, v))
+}
+
+func TestJWTSVIDExtractor(t *testing.T) {
+	const (
+		subject  = "spiffe://cluster.local/ns/default/sa/order"
+		audience = "spiffe://cluster.local/ns/default/sa/svid-exchange"
+	)
+	trusted, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	untrusted, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	kid, err := token.KeyID(trusted.PublicKey())
+	if err != nil {
+		t.Fatalf("KeyID: %v", err)
+	}
+	bundle := jwtbundle.New(spiffeid.RequireTrustDomainFromString("cluster.local"))
+	if err := bundle.AddJWTAuthority(kid, trusted.PublicKey()); err != nil {
+		t.Fatalf("AddJWTAuthority: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantID  string // set on success
+		wantErr error  // set when a specific sentinel error is expected
+	}{
+		{
+			name:   "valid JWT-SVID",
+			ctx:    ctxWithAuthorization("Bearer " + mintJWTSVID(t, trusted, subject, audience)),
+			wantID: subject,
+		},
+		{
+			name:   "scheme is case-insensitive",
+			ctx:    ctxWithAuthorization("bearer " + mintJWTSVID(t, trusted, subject, audience)),
+			wantID: subject,
+		},
+		{
+			name:    "no metadata",
+			ctx:     context.Background(),
+			wantErr: ErrNoJWTSVID,
+		},
+		{
+			name:    "not a bearer token",
+			ctx:     ctxWithAuthorization("Basic b3JkZXI6c2VjcmV0"),
+			wantErr: ErrNoJWTSVID,
+		},
+		{
+			name: "issued for another audience",
+			ctx:  ctxWithAuthorization("Bearer " + mintJWTSVID(t, trusted, subject, "spiffe://cluster.local/ns/default/sa/payment")),
+		},
+		{
+			name: "signed by a key outside the bundle",
+			ctx:  ctxWithAuthorization("Bearer " + mintJWTSVID(t, untrusted, subject, audience)),
+		},
+		{
+			name: "subject in a trust domain without a bundle",
+			ctx:  ctxWithAuthorization("Bearer " + mintJWTSVID(t, trusted, "spiffe://partner.example/ns/default/sa/order", audience)),
+		},
+		{
+			name: "malformed token",
+			ctx:  ctxWithAuthorization("Bearer not-a-jwt"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewJWTSVIDExtractor(bundle, []string{audience}).ExtractID(tc.ctx)
+			switch {
+			case tc.wantID != "":
+				if err != nil || got != tc.wantID {
+					t.Errorf("ExtractID() = %q, %v; want %q", got, err, tc.wantID)
+				}
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) || !errors.Is(err, server.ErrNoCredentials) {
+					t.Errorf("ExtractID() error = %v, want %v", err, tc.wantErr)
+				}
+			default:
+				if err == nil || errors.Is(err, server.ErrNoCredentials) {
+					t.Errorf("ExtractID() = %q, %v; want a verification error", got, err)
+				}
+			}
+		})
+	}
+}
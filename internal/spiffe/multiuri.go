@@ -0,0 +1,147 @@
+package spiffe
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+)
+
+// MultiURIMode selects how an Extractor treats a leaf certificate carrying
+// more than one SPIFFE URI SAN. The X.509-SVID specification allows exactly
+// one, but a misissued or cross-signed certificate may carry several.
+type MultiURIMode string
+
+// Multi-URI modes accepted by Extractor.
+const (
+	// MultiURIReject rejects a certificate with more than one SPIFFE URI SAN.
+	MultiURIReject MultiURIMode = "reject"
+	// MultiURIFirst authenticates the caller as the first SPIFFE URI SAN
+	// and ignores the rest.
+	MultiURIFirst MultiURIMode = "first"
+	// MultiURITrustDomain authenticates the caller as the one SPIFFE URI SAN
+	// in Extractor.TrustDomain, rejecting the certificate when there is none
+	// or more than one.
+	MultiURITrustDomain MultiURIMode = "trust-domain"
+)
+
+// MultiURIModes lists every accepted MultiURIMode.
+var MultiURIModes = []MultiURIMode{MultiURIReject, MultiURIFirst, MultiURITrustDomain}
+
+// ParseMultiURIMode returns the MultiURIMode named by s. The empty string
+// selects MultiURIReject.
+func ParseMultiURIMode(s string) (MultiURIMode, error) {
+	if s == "" {
+		return MultiURIReject, nil
+	}
+	if m := MultiURIMode(s); slices.Contains(MultiURIModes, m) {
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown multi-URI mode %q (must be one of %v)", s, MultiURIModes)
+}
+
+// selectID returns the SPIFFE ID leaf authenticates its holder as under
+// e.MultiURI. URI SANs with other schemes are ignored; a malformed SPIFFE
+// URI rejects the certificate whichever mode is set.
+func (e Extractor) selectID(leaf *x509.Certificate) (string, error) {
+	var ids []string
+	for _, uri := range leaf.URIs {
+		if uri.Scheme != spiffeScheme {
+			continue
+		}
+		id, err := policy.NormalizeSPIFFEID(uri.String())
+		if err != nil {
+			return "", fmt.Errorf("invalid SPIFFE ID %q: %w", uri.String(), err)
+		}
+		ids = append(ids, id)
+	}
+	switch e.MultiURI {
+	case MultiURIFirst:
+		if len(ids) > 0 {
+			return ids[0], nil
+		}
+	case MultiURITrustDomain:
+		ids = slices.DeleteFunc(ids, func(id string) bool {
+			parsed, err := spiffeid.FromString(id)
+			return err != nil || parsed.TrustDomain() != e.TrustDomain
+		})
+		if len(ids) == 0 {
+			return "", fmt.Errorf("%w in trust domain %q", ErrNoSPIFFEID, e.TrustDomain.Name())
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", ErrNoSPIFFEID
+	case 1:
+		return ids[0], nil
+	}
+	return "", ErrMultipleSPIFFEIDs
+}
+
+// VerifyPeerCertificate returns a tls.Config VerifyPeerCertificate hook that
+// verifies the peer's X.509-SVID against bundles and then calls authorize,
+// like go-spiffe's tlsconfig.VerifyPeerCertificate. go-spiffe refuses any
+// leaf with more than one URI SAN; this hook instead authenticates the peer
+// as the SPIFFE ID e selects and verifies the chain against that ID's trust
+// domain, so the handshake admits the same certificates ExtractID does.
+func (e Extractor) VerifyPeerCertificate(bundles x509bundle.Source, authorize tlsconfig.Authorizer) func([][]byte, [][]*x509.Certificate) error {
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw) == 0 {
+			return ErrNoCerts
+		}
+		certs := make([]*x509.Certificate, len(raw))
+		for i, der := range raw {
+			c, err := x509.ParseCertificate(der)
+			if err != nil {
+				return fmt.Errorf("parse peer certificate: %w", err)
+			}
+			certs[i] = c
+		}
+		leaf := certs[0]
+		s, err := e.selectID(leaf)
+		if err != nil {
+			return err
+		}
+		id, err := spiffeid.FromString(s)
+		if err != nil {
+			return err
+		}
+
+		// The X.509-SVID leaf constraints go-spiffe enforces.
+		switch {
+		case leaf.IsCA:
+			return errors.New("leaf certificate with CA flag set to true")
+		case leaf.KeyUsage&x509.KeyUsageCertSign != 0:
+			return errors.New("leaf certificate with KeyCertSign key usage")
+		case leaf.KeyUsage&x509.KeyUsageCRLSign != 0:
+			return errors.New("leaf certificate with KeyCrlSign key usage")
+		}
+		bundle, err := bundles.GetX509BundleForTrustDomain(id.TrustDomain())
+		if err != nil {
+			return fmt.Errorf("get X.509 bundle: %w", err)
+		}
+		chains, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         certPool(bundle.X509Authorities()),
+			Intermediates: certPool(certs[1:]),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return fmt.Errorf("verify leaf certificate: %w", err)
+		}
+		return authorize(id, chains)
+	}
+}
+
+func certPool(certs []*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
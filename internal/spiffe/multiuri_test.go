@@ -0,0 +1,258 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+func TestExtractorMultiURI(t *testing.T) {
+	const (
+		local   = "spiffe://cluster.local/ns/default/sa/order"
+		partner = "spiffe://partner.example/order"
+	)
+	leaf := func(ids ...string) *x509.Certificate {
+		c := &x509.Certificate{URIs: []*url.URL{{Scheme: "https", Host: "order.example"}}}
+		for _, id := range ids {
+			c.URIs = append(c.URIs, certWithURI(t, id).URIs...)
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		ext     Extractor
+		cert    *x509.Certificate
+		wantID  string
+		wantErr error
+	}{
+		{name: "zero value rejects several", cert: leaf(partner, local), wantErr: ErrMultipleSPIFFEIDs},
+		{name: "first takes the first", ext: Extractor{MultiURI: MultiURIFirst}, cert: leaf(local, partner), wantID: local},
+		{name: "reject accepts one", ext: Extractor{MultiURI: MultiURIReject}, cert: leaf(local), wantID: local},
+		{name: "reject refuses several", ext: Extractor{MultiURI: MultiURIReject}, cert: leaf(local, partner), wantErr: ErrMultipleSPIFFEIDs},
+		{name: "reject with none", ext: Extractor{MultiURI: MultiURIReject}, cert: leaf(), wantErr: ErrNoSPIFFEID},
+		{
+			name:   "trust domain selects its ID",
+			ext:    Extractor{MultiURI: MultiURITrustDomain, TrustDomain: spiffeid.RequireTrustDomainFromString("cluster.local")},
+			cert:   leaf(partner, local),
+			wantID: local,
+		},
+		{
+			name:    "trust domain with no match",
+			ext:     Extractor{MultiURI: MultiURITrustDomain, TrustDomain: spiffeid.RequireTrustDomainFromString("other.example")},
+			cert:    leaf(partner, local),
+			wantErr: ErrNoSPIFFEID,
+		},
+		{
+			name:    "trust domain with two matches",
+			ext:     Extractor{MultiURI: MultiURITrustDomain, TrustDomain: spiffeid.RequireTrustDomainFromString("cluster.local")},
+			cert:    leaf(local, "spiffe://cluster.local/ns/default/sa/payment"),
+			wantErr: ErrMultipleSPIFFEIDs,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.ext.ExtractID(ctxWithTLS(tc.cert))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("err = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.wantID {
+				t.Errorf("got %q, want %q", got, tc.wantID)
+			}
+		})
+	}
+}
+
+func TestParseMultiURIMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    MultiURIMode
+		wantErr bool
+	}{
+		{in: "", want: MultiURIReject},
+		{in: "first", want: MultiURIFirst},
+		{in: "reject", want: MultiURIReject},
+		{in: "trust-domain", want: MultiURITrustDomain},
+		{in: "last", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := ParseMultiURIMode(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseMultiURIMode(%q) = %q, %v; want %q, error %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+// testCA is a self-signed CA that issues leaf certificates with arbitrary
+// URI SANs, as a misbehaving issuer might.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns the DER of a leaf certificate with ids as its URI SANs.
+func (ca *testCA) issue(t *testing.T, ids ...string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, id := range ids {
+		u, err := url.Parse(id)
+		if err != nil {
+			t.Fatalf("parse URI %q: %v", id, err)
+		}
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create leaf: %v", err)
+	}
+	return der
+}
+
+func TestExtractorVerifyPeerCertificate(t *testing.T) {
+	const (
+		local   = "spiffe://cluster.local/ns/default/sa/order"
+		partner = "spiffe://partner.example/order"
+	)
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	localTD := spiffeid.RequireTrustDomainFromString("cluster.local")
+	partnerTD := spiffeid.RequireTrustDomainFromString("partner.example")
+	// Only cluster.local's CA is trusted for cluster.local; partner.example
+	// is bundled with a different CA than the one issuing the test leaves.
+	bundles := x509bundle.NewSet(
+		x509bundle.FromX509Authorities(localTD, []*x509.Certificate{ca.cert}),
+		x509bundle.FromX509Authorities(partnerTD, []*x509.Certificate{other.cert}),
+	)
+	tests := []struct {
+		name    string
+		ext     Extractor
+		raw     [][]byte
+		wantID  string
+		wantErr bool
+	}{
+		{
+			name:   "trust domain selects the verified ID",
+			ext:    Extractor{MultiURI: MultiURITrustDomain, TrustDomain: localTD},
+			raw:    [][]byte{ca.issue(t, partner, local)},
+			wantID: local,
+		},
+		{
+			name:   "first selects the leading ID",
+			ext:    Extractor{MultiURI: MultiURIFirst},
+			raw:    [][]byte{ca.issue(t, local, partner)},
+			wantID: local,
+		},
+		{
+			name:    "chain verified against the selected ID's trust domain",
+			ext:     Extractor{MultiURI: MultiURIFirst},
+			raw:     [][]byte{ca.issue(t, partner, local)},
+			wantErr: true,
+		},
+		{
+			name:    "reject refuses several IDs",
+			ext:     Extractor{MultiURI: MultiURIReject},
+			raw:     [][]byte{ca.issue(t, local, partner)},
+			wantErr: true,
+		},
+		{
+			name:    "untrusted issuer",
+			ext:     Extractor{MultiURI: MultiURIFirst},
+			raw:     [][]byte{other.issue(t, local)},
+			wantErr: true,
+		},
+		{
+			name:    "CA certificate as leaf",
+			ext:     Extractor{MultiURI: MultiURIFirst},
+			raw:     [][]byte{ca.cert.Raw},
+			wantErr: true,
+		},
+		{
+			name:    "no certificates",
+			ext:     Extractor{MultiURI: MultiURIFirst},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var authorized spiffeid.ID
+			verify := tc.ext.VerifyPeerCertificate(bundles, func(id spiffeid.ID, _ [][]*x509.Certificate) error {
+				authorized = id
+				return nil
+			})
+			err := verify(tc.raw, nil)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("accepted as %q, want error", authorized)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if authorized.String() != tc.wantID {
+				t.Errorf("authorized %q, want %q", authorized, tc.wantID)
+			}
+		})
+	}
+
+	t.Run("authorizer decides", func(t *testing.T) {
+		errDenied := errors.New("denied")
+		verify := Extractor{MultiURI: MultiURIFirst}.VerifyPeerCertificate(bundles, func(spiffeid.ID, [][]*x509.Certificate) error {
+			return errDenied
+		})
+		if err := verify([][]byte{ca.issue(t, local)}, nil); !errors.Is(err, errDenied) {
+			t.Errorf("err = %v, want the authorizer's", err)
+		}
+	})
+}
@@ -5,11 +5,12 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
-	"net/url"
 
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
+
+	"github.com/ngaddam369/svid-exchange/internal/server"
 )
 
 const spiffeScheme = "spiffe"
@@ -17,60 +18,87 @@ const spiffeScheme = "spiffe"
 var (
 	ErrNoPeerInfo = errors.New("no peer info in context")
 	ErrNoTLSInfo  = errors.New("peer has no TLS auth info")
-	ErrNoCerts    = errors.New("peer presented no certificates")
+	// ErrNoCerts matches server.ErrNoCredentials, so an ExtractorChain
+	// falls through to its next extractor for a peer without a certificate.
+	ErrNoCerts    = server.NoCredentials("peer presented no certificates")
 	ErrNoSPIFFEID = errors.New("peer certificate contains no SPIFFE SAN URI")
+	// ErrMultipleSPIFFEIDs is returned when the peer certificate carries
+	// more SPIFFE URI SANs than the Extractor's MultiURIMode accepts.
+	ErrMultipleSPIFFEIDs = errors.New("peer certificate contains more than one SPIFFE SAN URI")
 )
 
-// ExtractID pulls the SPIFFE ID from the first URI SAN on the peer's leaf
-// certificate. It returns an error if no SPIFFE URI is found.
+// ExtractID pulls the SPIFFE ID from the SPIFFE URI SAN on the peer's leaf
+// certificate. It returns an error if there is none, or more than one; use an
+// Extractor to select among several.
 //
 // Cert authenticity is guaranteed by the mTLS handshake at the transport layer
 // (workloadapi.X509Source in cmd/server/main.go) — only certs signed by the
 // SPIRE trust bundle reach this point. This function performs structural
 // SPIFFE ID validation only.
 func ExtractID(ctx context.Context) (string, error) {
+	state, err := peerTLSState(ctx)
+	if err != nil {
+		return "", err
+	}
+	return extractFromTLSState(state)
+}
+
+func peerTLSState(ctx context.Context) (tls.ConnectionState, error) {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
-		return "", ErrNoPeerInfo
+		return tls.ConnectionState{}, ErrNoPeerInfo
 	}
 
 	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
 	if !ok {
-		return "", ErrNoTLSInfo
+		return tls.ConnectionState{}, ErrNoTLSInfo
 	}
-
-	return extractFromTLSState(tlsInfo.State)
+	return tlsInfo.State, nil
 }
 
 func extractFromTLSState(state tls.ConnectionState) (string, error) {
+	return Extractor{}.fromTLSState(state)
+}
+
+// Extractor implements server.IDExtractor using the mTLS peer certificate.
+// Its zero value is ready to use and behaves as MultiURIReject.
+type Extractor struct {
+	// MultiURI selects how a leaf certificate with more than one SPIFFE URI
+	// SAN is treated. Empty means MultiURIReject.
+	MultiURI MultiURIMode
+	// TrustDomain is the trust domain MultiURITrustDomain selects the
+	// caller's ID from. Unused by the other modes.
+	TrustDomain spiffeid.TrustDomain
+}
+
+// fromTLSState returns the caller's SPIFFE ID from the leaf certificate in
+// state.
+func (e Extractor) fromTLSState(state tls.ConnectionState) (string, error) {
 	if len(state.PeerCertificates) == 0 {
 		return "", ErrNoCerts
 	}
-	leaf := state.PeerCertificates[0]
-	for _, uri := range leaf.URIs {
-		if uri.Scheme == spiffeScheme {
-			if err := validateSPIFFEID(uri); err != nil {
-				return "", fmt.Errorf("invalid SPIFFE ID %q: %w", uri.String(), err)
-			}
-			return uri.String(), nil
-		}
-	}
-	return "", ErrNoSPIFFEID
+	return e.selectID(state.PeerCertificates[0])
 }
 
-// Extractor implements server.IDExtractor using the mTLS peer certificate.
-// Its zero value is ready to use.
-type Extractor struct{}
-
 // ExtractID implements server.IDExtractor.
-func (Extractor) ExtractID(ctx context.Context) (string, error) {
-	return ExtractID(ctx)
+func (e Extractor) ExtractID(ctx context.Context) (string, error) {
+	state, err := peerTLSState(ctx)
+	if err != nil {
+		return "", err
+	}
+	return e.fromTLSState(state)
 }
 
-// validateSPIFFEID enforces the SPIFFE ID format: spiffe://<trust-domain>/...
-func validateSPIFFEID(u *url.URL) error {
-	if u.Host == "" {
-		return errors.New("missing trust domain")
+// ExtractIdentity implements server.IdentityExtractor. The Identity carries
+// the leaf certificate so audit entries can record which issuance was used.
+func (e Extractor) ExtractIdentity(ctx context.Context) (server.Identity, error) {
+	state, err := peerTLSState(ctx)
+	if err != nil {
+		return server.Identity{}, err
+	}
+	id, err := e.fromTLSState(state)
+	if err != nil {
+		return server.Identity{}, err
 	}
-	return nil
+	return server.Identity{ID: id, Cert: state.PeerCertificates[0]}, nil
 }
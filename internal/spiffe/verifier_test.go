@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"net/url"
 	"testing"
 
@@ -48,6 +49,24 @@ func TestExtractFromTLSState(t *testing.T) {
 			},
 			wantID: "spiffe://cluster.local/ns/default/sa/order",
 		},
+		{
+			name: "upper-case trust domain normalized",
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{
+					certWithURI(t, "spiffe://Cluster.LOCAL/ns/default/sa/order"),
+				},
+			},
+			wantID: "spiffe://cluster.local/ns/default/sa/order",
+		},
+		{
+			name: "trust domain with a port",
+			state: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{
+					certWithURI(t, "spiffe://cluster.local:8443/ns/default/sa/order"),
+				},
+			},
+			// not a valid SPIFFE ID — any error accepted
+		},
 		{
 			name:    "no certs",
 			state:   tls.ConnectionState{},
@@ -117,6 +136,27 @@ func TestExtractorExtractID(t *testing.T) {
 	})
 }
 
+func TestExtractorExtractIdentity(t *testing.T) {
+	var e Extractor
+
+	t.Run("returns the leaf certificate", func(t *testing.T) {
+		leaf := certWithURI(t, "spiffe://cluster.local/ns/default/sa/order")
+		got, err := e.ExtractIdentity(ctxWithTLS(leaf, certWithURI(t, "spiffe://cluster.local")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.ID != "spiffe://cluster.local/ns/default/sa/order" || got.Cert != leaf {
+			t.Errorf("got %+v, want the leaf's ID and certificate", got)
+		}
+	})
+
+	t.Run("no certificates", func(t *testing.T) {
+		if _, err := e.ExtractIdentity(ctxWithTLS()); !errors.Is(err, ErrNoCerts) {
+			t.Errorf("err = %v, want ErrNoCerts", err)
+		}
+	})
+}
+
 func TestExtractID(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -164,3 +204,40 @@ func TestExtractID(t *testing.T) {
 		})
 	}
 }
+
+// FuzzExtractFromTLSState feeds arbitrary URI SANs through extraction. The
+// SAN comes from the peer's certificate, so any input must be handled
+// without panicking, and an accepted ID must be a spiffe:// URI with a trust
+// domain.
+func FuzzExtractFromTLSState(f *testing.F) {
+	for _, s := range []string{
+		"spiffe://cluster.local/ns/default/sa/order",
+		"spiffe://cluster.local",
+		"spiffe:///no-trust-domain",
+		"spiffe:opaque",
+		"spiffe://user@cluster.local:8443/a?b#c",
+		"https://cluster.local/ns/default",
+		"spiffe://%zz/x",
+		"",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return // x509 parsing rejects a SAN that is not a URI
+		}
+		cert := &x509.Certificate{URIs: []*url.URL{u}}
+		id, err := extractFromTLSState(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+		if err != nil {
+			return
+		}
+		got, err := url.Parse(id)
+		if err != nil {
+			t.Fatalf("accepted ID %q does not parse: %v", id, err)
+		}
+		if got.Scheme != spiffeScheme || got.Host == "" {
+			t.Fatalf("accepted ID %q is not spiffe://<trust-domain>/...", id)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Package systemd implements the sd_notify protocol, through which a service
+// started by systemd with Type=notify reports that it is ready, that it is
+// stopping, and that it is still alive when the watchdog is enabled.
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// States sent with Notify. See sd_notify(3).
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns the state that sets the free-form status line systemctl
+// status shows for the service.
+func Status(s string) string {
+	return "STATUS=" + s
+}
+
+// Notifier sends states to systemd's notification socket.
+type Notifier struct {
+	addr *net.UnixAddr
+}
+
+// FromEnv returns a Notifier for the socket named by $NOTIFY_SOCKET, or nil
+// when the variable is unset because the process was not started by systemd
+// with notify access. A name starting with "@" is an abstract socket.
+func FromEnv() *Notifier {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	return &Notifier{addr: &net.UnixAddr{Name: path, Net: "unixgram"}}
+}
+
+// Notify sends states to systemd in a single datagram.
+func (n *Notifier) Notify(states ...string) error {
+	conn, err := net.DialUnix("unixgram", nil, n.addr)
+	if err != nil {
+		return fmt.Errorf("dial notify socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("write notify socket: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns the watchdog timeout systemd set for this process
+// in $WATCHDOG_USEC, or 0 when the watchdog is disabled or, per
+// $WATCHDOG_PID, meant for another process. Watchdog must be sent well
+// within the timeout; half of it is customary.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid WATCHDOG_USEC " + strconv.Quote(usec))
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
@@ -0,0 +1,84 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("unset socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		if n := FromEnv(); n != nil {
+			t.Errorf("FromEnv = %v, want nil", n)
+		}
+	})
+
+	t.Run("sends states in one datagram", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		if err != nil {
+			t.Skipf("unixgram sockets unavailable: %v", err)
+		}
+		defer conn.Close()
+		t.Setenv("NOTIFY_SOCKET", path)
+
+		n := FromEnv()
+		if n == nil {
+			t.Fatal("FromEnv = nil")
+		}
+		if err := n.Notify(Ready, Status("serving")); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+		buf := make([]byte, 256)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		nr, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if got := string(buf[:nr]); got != "READY=1\nSTATUS=serving" {
+			t.Errorf("datagram = %q", got)
+		}
+	})
+
+	t.Run("missing socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "absent.sock"))
+		if err := FromEnv().Notify(Ready); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	self := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name    string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "disabled"},
+		{name: "enabled", usec: "30000000", want: 30 * time.Second},
+		{name: "enabled for this process", usec: "2000000", pid: self, want: 2 * time.Second},
+		{name: "meant for another process", usec: "2000000", pid: "1"},
+		{name: "invalid", usec: "soon", wantErr: true},
+		{name: "zero", usec: "0", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tc.usec)
+			t.Setenv("WATCHDOG_PID", tc.pid)
+			got, err := WatchdogInterval()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("interval = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
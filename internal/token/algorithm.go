@@ -0,0 +1,59 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+)
+
+// Algorithm is a JWS signing algorithm ("alg" header value).
+type Algorithm string
+
+// Supported signing algorithms. ES256 is the default.
+const (
+	ES256 Algorithm = "ES256" // ECDSA P-256 with SHA-256
+	ES384 Algorithm = "ES384" // ECDSA P-384 with SHA-384
+	RS256 Algorithm = "RS256" // RSASSA-PKCS1-v1_5 with SHA-256, 2048-bit keys
+	EdDSA Algorithm = "EdDSA" // Ed25519
+)
+
+// Algorithms lists every supported algorithm, in the order they are
+// documented.
+var Algorithms = []Algorithm{ES256, ES384, RS256, EdDSA}
+
+// ParseAlgorithm returns the Algorithm named s. An empty string selects ES256.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	if s == "" {
+		return ES256, nil
+	}
+	for _, a := range Algorithms {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported signing algorithm %q (want one of %v)", s, Algorithms)
+}
+
+// AlgorithmFor returns the algorithm svid-exchange uses with pub. The mapping
+// is fixed by key type, so a JWKS entry's alg can be derived from its key.
+func AlgorithmFor(pub crypto.PublicKey) (Algorithm, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ES256, nil
+		case elliptic.P384():
+			return ES384, nil
+		}
+		return "", fmt.Errorf("unsupported EC curve %s", k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return RS256, nil
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
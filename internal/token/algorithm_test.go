@@ -0,0 +1,145 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Algorithm
+		wantErr bool
+	}{
+		{in: "", want: ES256},
+		{in: "ES256", want: ES256},
+		{in: "ES384", want: ES384},
+		{in: "RS256", want: RS256},
+		{in: "EdDSA", want: EdDSA},
+		{in: "HS256", wantErr: true},
+		{in: "es256", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseAlgorithm(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseAlgorithm(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseAlgorithm(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewAlgorithmSigner(t *testing.T) {
+	for _, alg := range Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			key, err := GenerateKey(alg)
+			if err != nil {
+				t.Fatalf("GenerateKey: %v", err)
+			}
+			s, err := NewAlgorithmSigner(key)
+			if err != nil {
+				t.Fatalf("NewAlgorithmSigner: %v", err)
+			}
+			if s.Algorithm() != alg {
+				t.Errorf("Algorithm() = %q, want %q", s.Algorithm(), alg)
+			}
+			m := NewMinterFromAlgorithmSigner(s)
+			res, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			if _, err := VerifyClaims(res.Token, []crypto.PublicKey{key.Public()}, "spiffe://b"); err != nil {
+				t.Errorf("VerifyClaims with the loaded key: %v", err)
+			}
+		})
+	}
+
+	t.Run("unsupported key", func(t *testing.T) {
+		if _, err := NewAlgorithmSigner("not a key"); err == nil {
+			t.Error("NewAlgorithmSigner(string) error = nil, want error")
+		}
+	})
+}
+
+func TestMintAlgorithms(t *testing.T) {
+	for _, alg := range Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			m, err := NewMinterWithAlgorithm(alg)
+			if err != nil {
+				t.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			if got := m.Algorithm(); got != alg {
+				t.Errorf("Algorithm() = %q, want %q", got, alg)
+			}
+			if got, err := AlgorithmFor(m.PublicKey()); err != nil || got != alg {
+				t.Errorf("AlgorithmFor(PublicKey()) = %q, %v; want %q", got, err, alg)
+			}
+
+			res, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			if got := headerAlg(t, res.Token); got != string(alg) {
+				t.Errorf("header alg = %q, want %q", got, alg)
+			}
+			if _, err := VerifyClaims(res.Token, m.PublicKeys(), "spiffe://b"); err != nil {
+				t.Errorf("VerifyClaims: %v", err)
+			}
+
+			if err := m.Rotate(); err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+			if got := m.Algorithm(); got != alg {
+				t.Errorf("Algorithm() after Rotate = %q, want %q", got, alg)
+			}
+			// The pre-rotation token still verifies against the rotation window.
+			if _, err := VerifyClaims(res.Token, m.PublicKeys(), ""); err != nil {
+				t.Errorf("VerifyClaims after Rotate: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyClaimsPinsAlgorithmToKey(t *testing.T) {
+	es256, err := NewMinterWithAlgorithm(ES256)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	eddsa, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	res, err := eddsa.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if _, err := VerifyClaims(res.Token, es256.PublicKeys(), ""); err == nil {
+		t.Error("EdDSA token verified against an ES256 key set")
+	}
+	mixed := []crypto.PublicKey{es256.PublicKey(), eddsa.PublicKey()}
+	if _, err := VerifyClaims(res.Token, mixed, ""); err != nil {
+		t.Errorf("VerifyClaims with mixed key set: %v", err)
+	}
+}
+
+func headerAlg(t *testing.T, tok string) string {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.SplitN(tok, ".", 2)[0])
+	if err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(raw, &h); err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	return h.Alg
+}
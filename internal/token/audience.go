@@ -0,0 +1,39 @@
+package token
+
+import "context"
+
+type audienceKey struct{}
+
+type issuerKey struct{}
+
+// WithAudience returns a copy of ctx that makes Mint set the aud claim of
+// the JWT or JWT-SVID it mints to aud instead of the target, for targets
+// that validate aud as a DNS name or URL. Other formats ignore it, and
+// policy validation rejects the combination.
+func WithAudience(ctx context.Context, aud []string) context.Context {
+	return context.WithValue(ctx, audienceKey{}, aud)
+}
+
+// WithIssuer returns a copy of ctx that makes Mint set the iss claim of the
+// JWT or JWT-SVID it mints to iss instead of "svid-exchange". VerifyClaims
+// and the verifiers built on it reject such tokens; they are for targets
+// that validate tokens against the JWKS themselves.
+func WithIssuer(ctx context.Context, iss string) context.Context {
+	return context.WithValue(ctx, issuerKey{}, iss)
+}
+
+// audienceClaim returns the aud claim for a token minted for target.
+func audienceClaim(ctx context.Context, target string) []string {
+	if aud, _ := ctx.Value(audienceKey{}).([]string); len(aud) > 0 {
+		return aud
+	}
+	return []string{target}
+}
+
+// issuerClaim returns the iss claim for ctx.
+func issuerClaim(ctx context.Context) string {
+	if iss, _ := ctx.Value(issuerKey{}).(string); iss != "" {
+		return iss
+	}
+	return issuer
+}
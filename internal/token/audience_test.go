@@ -0,0 +1,48 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMintAudienceAndIssuer(t *testing.T) {
+	m, err := NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	aud := []string{"https://payments.example.com", "payments.internal"}
+	ctx := WithIssuer(WithAudience(context.Background(), aud), "https://issuer.example.com")
+	res, err := m.Mint(ctx, "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(res.Token, ".")[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("unmarshal claims: %v", err)
+	}
+	if !slices.Equal(claims.Aud, aud) || claims.Iss != "https://issuer.example.com" {
+		t.Errorf("aud = %v, iss = %q", claims.Aud, claims.Iss)
+	}
+	if _, err := VerifyClaims(res.Token, m.PublicKeys(), ""); err == nil {
+		t.Error("VerifyClaims accepted a token with a foreign issuer")
+	}
+
+	res, err = m.Mint(WithAudience(context.Background(), aud), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if _, err := VerifyClaims(res.Token, m.PublicKeys(), "payments.internal"); err != nil {
+		t.Errorf("VerifyClaims with a mapped audience: %v", err)
+	}
+	if _, err := VerifyClaims(res.Token, m.PublicKeys(), "spiffe://td/b"); err == nil {
+		t.Error("VerifyClaims accepted the target ID the audience replaced")
+	}
+}
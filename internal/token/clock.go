@@ -0,0 +1,43 @@
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+)
+
+// MaxNotBeforeSkew is the largest backdating SetNotBeforeSkew accepts. A
+// consumer whose clock is further off than this should fix its clock.
+const MaxNotBeforeSkew = 5 * time.Minute
+
+// SetClock makes m, and the SVIDMinter and PASETOMinter built on it, stamp
+// iat, nbf, and exp from c. A nil c restores the system clock. It must be
+// called before m mints its first token.
+func (m *Minter) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// SetNotBeforeSkew backdates the nbf claim of every JWT and PASETO token m
+// mints by skew, so that a consumer whose clock runs up to skew behind the
+// server still accepts a token the moment it is issued. iat and exp are not
+// moved. skew must be between zero and MaxNotBeforeSkew. It must be called
+// before m mints its first token.
+func (m *Minter) SetNotBeforeSkew(skew time.Duration) error {
+	if skew < 0 || skew > MaxNotBeforeSkew {
+		return fmt.Errorf("not-before skew %v must be between 0 and %v", skew, MaxNotBeforeSkew)
+	}
+	m.skew = skew
+	return nil
+}
+
+// claimTimes returns the issue time and the not-before time of a token
+// minted now.
+func (m *Minter) claimTimes() (now, notBefore time.Time) {
+	var c clock.Clock = clock.System{}
+	if m.clock != nil {
+		c = m.clock
+	}
+	now = c.Now().UTC().Truncate(time.Second)
+	return now, now.Add(-m.skew)
+}
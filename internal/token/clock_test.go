@@ -0,0 +1,98 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+)
+
+func TestClaimTimes(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	// A minute in the past, so the tokens also verify against the real clock.
+	issued := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		skew    time.Duration
+		wantNbf int64
+	}{
+		{name: "no skew", wantNbf: issued.Unix()},
+		{name: "backdated", skew: 30 * time.Second, wantNbf: issued.Unix() - 30},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestMinter(t)
+			m.SetClock(clock.NewFake(issued))
+			if err := m.SetNotBeforeSkew(tc.skew); err != nil {
+				t.Fatalf("SetNotBeforeSkew: %v", err)
+			}
+
+			res, err := m.Mint(context.Background(), subject, target, []string{"payments:charge"}, 600, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			claims := parseClaims(t, m, res.Token)
+			if claims["iat"] != float64(issued.Unix()) || claims["nbf"] != float64(tc.wantNbf) || claims["exp"] != float64(issued.Unix()+600) {
+				t.Errorf("iat, nbf, exp = %v, %v, %v; want %d, %d, %d", claims["iat"], claims["nbf"], claims["exp"], issued.Unix(), tc.wantNbf, issued.Unix()+600)
+			}
+			if !res.ExpiresAt.Equal(issued.Add(600 * time.Second)) {
+				t.Errorf("ExpiresAt = %v, want %v", res.ExpiresAt, issued.Add(600*time.Second))
+			}
+
+			// JWT-SVIDs carry no nbf, whatever the skew.
+			res, err = NewSVIDMinter(m).Mint(context.Background(), subject, target, []string{"payments:charge"}, 600, "")
+			if err != nil {
+				t.Fatalf("SVID Mint: %v", err)
+			}
+			claims = parseClaims(t, m, res.Token)
+			if _, ok := claims["nbf"]; ok || claims["iat"] != float64(issued.Unix()) {
+				t.Errorf("JWT-SVID claims = %v, want iat %d and no nbf", claims, issued.Unix())
+			}
+		})
+	}
+}
+
+func TestPASETOClaimTimes(t *testing.T) {
+	keys, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := time.Now().Add(-time.Minute).Truncate(time.Second)
+	keys.SetClock(clock.NewFake(issued))
+	if err := keys.SetNotBeforeSkew(30 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPASETOMinter(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := p.Mint(context.Background(), "spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/payment", []string{"payments:charge"}, 600, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	claims, err := VerifyPASETO(res.Token, p.PublicKeys(), "")
+	if err != nil {
+		t.Fatalf("VerifyPASETO: %v", err)
+	}
+	if claims["iat"] != float64(issued.Unix()) || claims["nbf"] != float64(issued.Unix()-30) {
+		t.Errorf("iat, nbf = %v, %v; want %d, %d", claims["iat"], claims["nbf"], issued.Unix(), issued.Unix()-30)
+	}
+}
+
+func TestSetNotBeforeSkew(t *testing.T) {
+	m := newTestMinter(t)
+	for _, skew := range []time.Duration{-time.Second, MaxNotBeforeSkew + time.Second} {
+		if err := m.SetNotBeforeSkew(skew); err == nil {
+			t.Errorf("SetNotBeforeSkew(%v) accepted an out-of-range skew", skew)
+		}
+	}
+	if err := m.SetNotBeforeSkew(MaxNotBeforeSkew); err != nil {
+		t.Errorf("SetNotBeforeSkew(%v): %v", MaxNotBeforeSkew, err)
+	}
+}
@@ -0,0 +1,34 @@
+package token
+
+import (
+	crand "crypto/rand"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// jtiSources holds ChaCha8 generators, each seeded from crypto/rand when it
+// is created. A token ID draws its bytes from a pooled generator instead of
+// reading the operating system's entropy source on every mint, which
+// serializes concurrent mints under load. ChaCha8 is a cryptographically
+// strong generator, so IDs stay unpredictable; the pool drops generators
+// during garbage collection, which also reseeds over time.
+var jtiSources = sync.Pool{
+	New: func() any {
+		var seed [32]byte
+		_, _ = crand.Read(seed[:]) // crypto/rand.Read never fails
+		return rand.NewChaCha8(seed)
+	},
+}
+
+// newJTI returns a random (version 4) UUID string for the jti claim.
+func newJTI() string {
+	var id uuid.UUID
+	src := jtiSources.Get().(*rand.ChaCha8)
+	_, _ = src.Read(id[:]) // ChaCha8.Read never fails
+	jtiSources.Put(src)
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return id.String()
+}
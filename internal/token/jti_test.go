@@ -0,0 +1,46 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewJTI(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 10000 {
+		jti := newJTI()
+		id, err := uuid.Parse(jti)
+		if err != nil {
+			t.Fatalf("newJTI() = %q: %v", jti, err)
+		}
+		if id.Version() != 4 || id.Variant() != uuid.RFC4122 {
+			t.Fatalf("newJTI() = %q: version %d variant %v, want version 4 RFC 4122", jti, id.Version(), id.Variant())
+		}
+		if seen[jti] {
+			t.Fatalf("newJTI() repeated %q", jti)
+		}
+		seen[jti] = true
+	}
+}
+
+// BenchmarkJTI compares the pooled generator with uuid.New, which reads
+// crypto/rand on every call.
+func BenchmarkJTI(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = newJTI()
+			}
+		})
+	})
+	b.Run("uuid.New", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = uuid.New().String()
+			}
+		})
+	})
+}
@@ -0,0 +1,294 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinMacaroonKeySize is the minimum root key length accepted by
+// NewMacaroonMinter and VerifyMacaroon.
+const MinMacaroonKeySize = 32
+
+// macaroonKeyGen is the HMAC key libmacaroons uses to derive the signing key
+// from a root key. Using the same derivation keeps minted macaroons
+// verifiable by any macaroon v2 library given the root key.
+var macaroonKeyGen = []byte("macaroons-key-generator")
+
+// Caveat names understood by VerifyMacaroon. Each first-party caveat has the
+// form "<name> = <value>". A caveat with any other name makes the macaroon
+// invalid, as the macaroon model requires verifiers to reject caveats they
+// cannot check.
+const (
+	CaveatIssuer   = "iss"
+	CaveatSubject  = "sub"
+	CaveatAudience = "aud"
+	CaveatActor    = "act"
+	CaveatIssuedAt = "iat"
+	// CaveatExpiry is a Unix timestamp. When repeated, the earliest wins.
+	CaveatExpiry = "exp"
+	// CaveatScope is a space-separated scope list. When repeated, the
+	// granted scopes are the intersection of every list.
+	CaveatScope = "scope"
+)
+
+// Macaroon is a first-party-only macaroon in the libmacaroons v2 JSON
+// layout. The zero value is not useful; obtain one from ParseMacaroon.
+type Macaroon struct {
+	Location string
+	ID       string
+	Caveats  []string
+	sig      []byte
+}
+
+type macaroonJSON struct {
+	V   int              `json:"v"`
+	L   string           `json:"l,omitempty"`
+	I   string           `json:"i"`
+	C   []macaroonCaveat `json:"c,omitempty"`
+	S64 string           `json:"s64"`
+}
+
+type macaroonCaveat struct {
+	I string `json:"i"`
+}
+
+// ParseMacaroon decodes a macaroon produced by MacaroonMinter or
+// AttenuateMacaroon. It does not verify the signature.
+func ParseMacaroon(raw string) (*Macaroon, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode macaroon: %w", err)
+	}
+	var mj macaroonJSON
+	if err := json.Unmarshal(b, &mj); err != nil {
+		return nil, fmt.Errorf("decode macaroon: %w", err)
+	}
+	if mj.V != 2 {
+		return nil, fmt.Errorf("unsupported macaroon version %d", mj.V)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(mj.S64)
+	if err != nil || len(sig) != sha256.Size {
+		return nil, errors.New("decode macaroon: malformed signature")
+	}
+	m := &Macaroon{Location: mj.L, ID: mj.I, sig: sig}
+	for _, c := range mj.C {
+		m.Caveats = append(m.Caveats, c.I)
+	}
+	return m, nil
+}
+
+// Encode returns the base64url-encoded v2 JSON form of m.
+func (m *Macaroon) Encode() (string, error) {
+	mj := macaroonJSON{V: 2, L: m.Location, I: m.ID, S64: base64.RawURLEncoding.EncodeToString(m.sig)}
+	for _, c := range m.Caveats {
+		mj.C = append(mj.C, macaroonCaveat{I: c})
+	}
+	b, err := json.Marshal(mj)
+	if err != nil {
+		return "", fmt.Errorf("marshal macaroon: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// addCaveat appends caveat and chains the signature over it. Anyone holding
+// the macaroon can do this; nobody can remove a caveat without the root key.
+func (m *Macaroon) addCaveat(caveat string) {
+	m.Caveats = append(m.Caveats, caveat)
+	m.sig = macaroonMAC(m.sig, caveat)
+}
+
+func macaroonMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func newMacaroon(rootKey []byte, location, id string) *Macaroon {
+	return &Macaroon{Location: location, ID: id, sig: macaroonMAC(macaroonMAC(macaroonKeyGen, string(rootKey)), id)}
+}
+
+// MacaroonMinter mints HMAC macaroons whose first-party caveats carry the
+// same information as the JWT claims. Holders can attenuate a macaroon
+// offline with AttenuateMacaroon — narrowing its scopes, audience, or expiry
+// — before passing it on. Verification needs the root key, so targets either
+// share it or delegate to a service that holds it.
+type MacaroonMinter struct {
+	rootKey []byte
+}
+
+// NewMacaroonMinter returns a MacaroonMinter signing with rootKey, which must
+// be at least MinMacaroonKeySize bytes.
+func NewMacaroonMinter(rootKey []byte) (*MacaroonMinter, error) {
+	if len(rootKey) < MinMacaroonKeySize {
+		return nil, fmt.Errorf("macaroon root key must be at least %d bytes, got %d", MinMacaroonKeySize, len(rootKey))
+	}
+	return &MacaroonMinter{rootKey: slices.Clone(rootKey)}, nil
+}
+
+// Mint issues a macaroon identified by a fresh jti with one caveat per claim.
+// HMAC signing is local and cannot block, so ctx is unused.
+func (m *MacaroonMinter) Mint(_ context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
+	jti := newJTI()
+	now := time.Now().UTC()
+	exp := now.Add(time.Duration(ttlSeconds) * time.Second)
+
+	mac := newMacaroon(m.rootKey, issuer, jti)
+	mac.addCaveat(caveat(CaveatIssuer, issuer))
+	mac.addCaveat(caveat(CaveatSubject, subject))
+	mac.addCaveat(caveat(CaveatAudience, target))
+	mac.addCaveat(caveat(CaveatScope, strings.Join(scopes, " ")))
+	mac.addCaveat(caveat(CaveatIssuedAt, strconv.FormatInt(now.Unix(), 10)))
+	mac.addCaveat(caveat(CaveatExpiry, strconv.FormatInt(exp.Unix(), 10)))
+	if actSubject != "" {
+		mac.addCaveat(caveat(CaveatActor, actSubject))
+	}
+	raw, err := mac.Encode()
+	if err != nil {
+		return MintResult{}, err
+	}
+	return MintResult{Token: raw, TokenID: jti, ExpiresAt: exp, GrantedScopes: scopes}, nil
+}
+
+// PublicKeys returns nil: macaroons are HMAC-authenticated and have no
+// public verification key.
+func (m *MacaroonMinter) PublicKeys() []crypto.PublicKey {
+	return nil
+}
+
+// Verify is VerifyMacaroon with m's root key.
+func (m *MacaroonMinter) Verify(raw, audience string) (jwt.MapClaims, error) {
+	return VerifyMacaroon(raw, m.rootKey, audience)
+}
+
+func caveat(name, value string) string {
+	return name + " = " + value
+}
+
+func splitCaveat(c string) (name, value string, err error) {
+	name, value, ok := strings.Cut(c, " = ")
+	if !ok {
+		return "", "", fmt.Errorf("malformed caveat %q", c)
+	}
+	switch name {
+	case CaveatIssuer, CaveatSubject, CaveatAudience, CaveatActor, CaveatScope:
+	case CaveatIssuedAt, CaveatExpiry:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", "", fmt.Errorf("caveat %q: value must be a Unix timestamp", c)
+		}
+	default:
+		return "", "", fmt.Errorf("unknown caveat %q", c)
+	}
+	return name, value, nil
+}
+
+// AttenuateMacaroon appends caveats to raw and returns the re-encoded
+// macaroon. Each caveat must be "<name> = <value>" with a name listed among
+// the Caveat constants. Caveats can only narrow a macaroon: a repeated scope
+// caveat intersects, a repeated exp caveat can only move expiry earlier, and
+// a conflicting sub, aud, iss, or act caveat makes the macaroon unusable.
+func AttenuateMacaroon(raw string, caveats ...string) (string, error) {
+	m, err := ParseMacaroon(raw)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range caveats {
+		if _, _, err := splitCaveat(c); err != nil {
+			return "", err
+		}
+		m.addCaveat(c)
+	}
+	return m.Encode()
+}
+
+// VerifyMacaroon checks raw's signature chain against rootKey, folds its
+// caveats into a claim set shaped like a JWT's (iss, sub, aud, scope, iat,
+// exp, jti, act), and validates it: the issuer must be "svid-exchange", the
+// macaroon must not be expired, and when audience is non-empty aud must
+// equal it.
+func VerifyMacaroon(raw string, rootKey []byte, audience string) (jwt.MapClaims, error) {
+	if len(rootKey) < MinMacaroonKeySize {
+		return nil, errors.New("macaroon root key too short")
+	}
+	m, err := ParseMacaroon(raw)
+	if err != nil {
+		return nil, err
+	}
+	sig := macaroonMAC(macaroonMAC(macaroonKeyGen, string(rootKey)), m.ID)
+	for _, c := range m.Caveats {
+		sig = macaroonMAC(sig, c)
+	}
+	if !hmac.Equal(sig, m.sig) {
+		return nil, errors.New("macaroon signature is invalid")
+	}
+
+	claims := jwt.MapClaims{"jti": m.ID}
+	var (
+		scopes    []string
+		haveScope bool
+		exp       int64
+	)
+	for _, c := range m.Caveats {
+		name, value, err := splitCaveat(c)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case CaveatScope:
+			fields := strings.Fields(value)
+			if !haveScope {
+				scopes, haveScope = fields, true
+				continue
+			}
+			scopes = slices.DeleteFunc(scopes, func(s string) bool { return !slices.Contains(fields, s) })
+		case CaveatExpiry:
+			t, _ := strconv.ParseInt(value, 10, 64)
+			if exp == 0 || t < exp {
+				exp = t
+			}
+		case CaveatIssuedAt:
+			t, _ := strconv.ParseInt(value, 10, 64)
+			claims["iat"] = float64(t)
+		default:
+			if prev, ok := claims[name]; ok && prev != value {
+				return nil, fmt.Errorf("conflicting %s caveats", name)
+			}
+			claims[name] = value
+		}
+	}
+
+	if claims["iss"] != issuer {
+		return nil, fmt.Errorf("macaroon issuer must be %q", issuer)
+	}
+	if exp == 0 {
+		return nil, errors.New("macaroon has no exp caveat")
+	}
+	if time.Now().Unix() >= exp {
+		return nil, errors.New("macaroon is expired")
+	}
+	aud, _ := claims["aud"].(string)
+	if aud == "" {
+		return nil, errors.New("macaroon has no aud caveat")
+	}
+	if audience != "" && aud != audience {
+		return nil, fmt.Errorf("macaroon audience %q does not match %q", aud, audience)
+	}
+	claims["aud"] = []any{aud}
+	claims["exp"] = float64(exp)
+	claims["scope"] = strings.Join(scopes, " ")
+	if act, ok := claims["act"].(string); ok {
+		claims["act"] = map[string]any{"sub": act}
+	}
+	return claims, nil
+}
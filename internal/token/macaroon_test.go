@@ -0,0 +1,140 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMacaroonMinter(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	rootKey := bytes.Repeat([]byte{7}, MinMacaroonKeySize)
+	m, err := NewMacaroonMinter(rootKey)
+	if err != nil {
+		t.Fatalf("NewMacaroonMinter: %v", err)
+	}
+	res, err := m.Mint(context.Background(), subject, target, []string{"payments:charge", "payments:refund"}, 60, "spiffe://cluster.local/ns/default/sa/user")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	soon := strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10)
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		caveats   []string
+		audience  string
+		key       []byte
+		wantErr   bool
+		wantScope string
+		wantExp   string
+	}{
+		{name: "unattenuated", audience: target, key: rootKey, wantScope: "payments:charge payments:refund"},
+		{name: "any audience", key: rootKey, wantScope: "payments:charge payments:refund"},
+		{name: "scope narrowed", caveats: []string{"scope = payments:charge"}, audience: target, key: rootKey, wantScope: "payments:charge"},
+		{name: "scope cannot widen", caveats: []string{"scope = payments:charge admin:all"}, audience: target, key: rootKey, wantScope: "payments:charge"},
+		{name: "expiry shortened", caveats: []string{"exp = " + soon}, audience: target, key: rootKey, wantScope: "payments:charge payments:refund", wantExp: soon},
+		{name: "expiry in the past", caveats: []string{"exp = " + past}, audience: target, key: rootKey, wantErr: true},
+		{name: "conflicting audience", caveats: []string{"aud = spiffe://cluster.local/ns/default/sa/other"}, audience: target, key: rootKey, wantErr: true},
+		{name: "wrong audience", audience: "spiffe://cluster.local/ns/default/sa/other", key: rootKey, wantErr: true},
+		{name: "wrong root key", audience: target, key: bytes.Repeat([]byte{8}, MinMacaroonKeySize), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := res.Token
+			if len(tc.caveats) > 0 {
+				if raw, err = AttenuateMacaroon(raw, tc.caveats...); err != nil {
+					t.Fatalf("AttenuateMacaroon: %v", err)
+				}
+			}
+			claims, err := VerifyMacaroon(raw, tc.key, tc.audience)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("VerifyMacaroon() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if claims["sub"] != subject {
+				t.Errorf("sub = %v, want %s", claims["sub"], subject)
+			}
+			if claims["jti"] != res.TokenID {
+				t.Errorf("jti = %v, want %s", claims["jti"], res.TokenID)
+			}
+			if claims["scope"] != tc.wantScope {
+				t.Errorf("scope = %v, want %q", claims["scope"], tc.wantScope)
+			}
+			if act, _ := claims["act"].(map[string]any); act["sub"] != "spiffe://cluster.local/ns/default/sa/user" {
+				t.Errorf("act = %v, want sub of the delegating user", claims["act"])
+			}
+			exp, err := claims.GetExpirationTime()
+			if err != nil {
+				t.Fatalf("GetExpirationTime: %v", err)
+			}
+			if tc.wantExp != "" && strconv.FormatInt(exp.Unix(), 10) != tc.wantExp {
+				t.Errorf("exp = %d, want %s", exp.Unix(), tc.wantExp)
+			}
+			if tc.wantExp == "" && !exp.Equal(res.ExpiresAt.Truncate(time.Second)) {
+				t.Errorf("exp = %v, want %v", exp, res.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestMacaroonTampering(t *testing.T) {
+	rootKey := bytes.Repeat([]byte{7}, MinMacaroonKeySize)
+	m, err := NewMacaroonMinter(rootKey)
+	if err != nil {
+		t.Fatalf("NewMacaroonMinter: %v", err)
+	}
+	res, err := m.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	attenuated, err := AttenuateMacaroon(res.Token, "scope = none")
+	if err != nil {
+		t.Fatalf("AttenuateMacaroon: %v", err)
+	}
+
+	// Dropping the attenuating caveat while keeping the attenuated signature
+	// must be rejected.
+	mac, err := ParseMacaroon(attenuated)
+	if err != nil {
+		t.Fatalf("ParseMacaroon: %v", err)
+	}
+	mac.Caveats = mac.Caveats[:len(mac.Caveats)-1]
+	stripped, err := mac.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := m.Verify(stripped, ""); err == nil {
+		t.Error("Verify accepted a macaroon with a removed caveat")
+	}
+}
+
+func TestAttenuateMacaroonRejectsUnknownCaveats(t *testing.T) {
+	m, err := NewMacaroonMinter(bytes.Repeat([]byte{7}, MinMacaroonKeySize))
+	if err != nil {
+		t.Fatalf("NewMacaroonMinter: %v", err)
+	}
+	res, err := m.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	for _, c := range []string{"ip = 10.0.0.1", "scope=read", "exp = tomorrow"} {
+		if _, err := AttenuateMacaroon(res.Token, c); err == nil || !strings.Contains(err.Error(), "caveat") {
+			t.Errorf("AttenuateMacaroon(%q) error = %v, want caveat error", c, err)
+		}
+	}
+}
+
+func TestNewMacaroonMinterKeySize(t *testing.T) {
+	if _, err := NewMacaroonMinter(make([]byte, MinMacaroonKeySize-1)); err == nil {
+		t.Error("NewMacaroonMinter accepted a short root key")
+	}
+}
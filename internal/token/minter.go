@@ -1,9 +1,10 @@
-// Package token mints ES256 JWTs for granted exchange results.
+// Package token mints signed JWTs (ES256 by default; ES384, RS256, or EdDSA
+// when configured) for granted exchange results.
 package token
 
 import (
-	"crypto/ecdsa"
-	"crypto/sha256"
+	"context"
+	"crypto"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -12,46 +13,51 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
 )
 
 const issuer = "svid-exchange"
 
+// validMethods is the alg allow-list for tokens this service verifies.
+var validMethods = []string{string(ES256), string(ES384), string(RS256), string(EdDSA)}
+
+// tokenParser verifies tokens when no audience is required. A jwt.Parser
+// holds only its options, so one is shared rather than built per call.
+var tokenParser = jwt.NewParser(
+	jwt.WithValidMethods(validMethods),
+	jwt.WithIssuer(issuer),
+	jwt.WithExpirationRequired(),
+)
+
 // KeyID returns the RFC 7638 SHA-256 thumbprint of pub encoded as a base64url
 // string. This is used as the "kid" header in minted JWTs and as the key ID
 // in the JWKS document.
-func KeyID(pub *ecdsa.PublicKey) (string, error) {
-	raw, err := pub.Bytes()
-	if err != nil {
-		return "", fmt.Errorf("encode public key: %w", err)
-	}
-	const p256Len = 65
-	if len(raw) != p256Len || raw[0] != 0x04 {
-		return "", fmt.Errorf("unexpected P-256 point: got %d bytes with prefix 0x%02x", len(raw), raw[0])
-	}
-	byteLen := (len(raw) - 1) / 2
-	x := base64.RawURLEncoding.EncodeToString(raw[1 : 1+byteLen])
-	y := base64.RawURLEncoding.EncodeToString(raw[1+byteLen:])
-	type thumbInput struct {
-		Crv string `json:"crv"`
-		Kty string `json:"kty"`
-		X   string `json:"x"`
-		Y   string `json:"y"`
-	}
-	thumbJSON, err := json.Marshal(thumbInput{Crv: "P-256", Kty: "EC", X: x, Y: y})
-	if err != nil {
-		return "", fmt.Errorf("marshal thumbprint: %w", err)
-	}
-	sum := sha256.Sum256(thumbJSON)
-	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+func KeyID(pub crypto.PublicKey) (string, error) {
+	return jwk.Thumbprint(pub)
 }
 
-// Minter signs JWTs using a Signer and supports key rotation.
-// The zero value is not usable; use NewMinter or NewMinterFromSigner.
+// Minter signs JWTs using an AlgorithmSigner and supports key rotation.
+// The zero value is not usable; use NewMinter, NewMinterWithAlgorithm,
+// NewMinterFromSigner, or NewMinterFromAlgorithmSigner.
 type Minter struct {
 	mu       sync.RWMutex
-	current  Signer
-	previous Signer
+	current  AlgorithmSigner
+	previous AlgorithmSigner
+	// next replaces current at cutover; see SetNextSigner.
+	next    AlgorithmSigner
+	cutover time.Time
+	// header is the encoded JWT header for current, computed on first use
+	// and cleared on rotation so the key thumbprint is not recomputed on
+	// every mint.
+	header string
+	// clock and skew set the claim times; see SetClock and
+	// SetNotBeforeSkew. A nil clock is the system clock.
+	clock clock.Clock
+	skew  time.Duration
+	// pipeline, when set, signs on a worker pool; see SetSigningPipeline.
+	pipeline *SigningPipeline
 }
 
 // NewMinter creates a Minter backed by a freshly generated ephemeral ES256
@@ -59,48 +65,76 @@ type Minter struct {
 // require the private key to never leave a hardware boundary, use
 // NewMinterFromSigner with a KMS-backed Signer implementation instead.
 func NewMinter() (*Minter, error) {
-	s, err := newECDSASigner()
+	return NewMinterWithAlgorithm(ES256)
+}
+
+// NewMinterWithAlgorithm creates a Minter backed by a freshly generated
+// ephemeral key pair for alg. Rotate keeps generating keys of the same
+// algorithm.
+func NewMinterWithAlgorithm(alg Algorithm) (*Minter, error) {
+	s, err := newSigner(alg)
 	if err != nil {
 		return nil, err
 	}
-	return NewMinterFromSigner(s), nil
+	return NewMinterFromAlgorithmSigner(s), nil
 }
 
-// NewMinterFromSigner creates a Minter that signs JWTs with the provided
-// Signer. Use this to plug in an AWS KMS, GCP Cloud KMS, or Vault Transit
-// backend — the rest of the service (JWKS, rotation, Exchange) is unaffected.
+// NewMinterFromSigner creates a Minter that signs ES256 JWTs with the
+// provided Signer. Use this to plug in an AWS KMS, GCP Cloud KMS, or Vault
+// Transit backend — the rest of the service (JWKS, rotation, Exchange) is
+// unaffected.
 func NewMinterFromSigner(s Signer) *Minter {
+	return NewMinterFromAlgorithmSigner(asAlgorithmSigner(s))
+}
+
+// NewMinterFromAlgorithmSigner creates a Minter that signs JWTs with s using
+// s.Algorithm().
+func NewMinterFromAlgorithmSigner(s AlgorithmSigner) *Minter {
 	return &Minter{current: s}
 }
 
+// Algorithm returns the algorithm of the current signing key.
+func (m *Minter) Algorithm() Algorithm {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current.Algorithm()
+}
+
 // PublicKey returns the current signing public key.
-func (m *Minter) PublicKey() *ecdsa.PublicKey {
+func (m *Minter) PublicKey() crypto.PublicKey {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.current.PublicKey()
+	return m.current.Public()
 }
 
 // PublicKeys returns all currently active public keys. During a rotation
 // window both the current key and the immediately preceding key are returned
-// so that tokens signed before the rotation remain verifiable.
-func (m *Minter) PublicKeys() []*ecdsa.PublicKey {
+// so that tokens signed before the rotation remain verifiable. A key
+// scheduled with SetNextSigner is returned last, ahead of its cutover.
+func (m *Minter) PublicKeys() []crypto.PublicKey {
+	m.cutOver()
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	if m.previous == nil {
-		return []*ecdsa.PublicKey{m.current.PublicKey()}
+	keys := []crypto.PublicKey{m.current.Public()}
+	if m.previous != nil {
+		keys = append(keys, m.previous.Public())
 	}
-	return []*ecdsa.PublicKey{m.current.PublicKey(), m.previous.PublicKey()}
+	if m.next != nil {
+		keys = append(keys, m.next.Public())
+	}
+	return keys
 }
 
-// Rotate generates a new ephemeral ES256 signing key and promotes the current
-// key to previous. Intended for in-process signing; for KMS-backed signers use
-// RotateTo with the new signer pointing at the new key version.
+// Rotate generates a new ephemeral signing key of the current algorithm and
+// promotes the current key to previous. Intended for in-process signing; for
+// KMS-backed signers use RotateTo with the new signer pointing at the new key
+// version.
 func (m *Minter) Rotate() error {
-	s, err := newECDSASigner()
+	s, err := newSigner(m.Algorithm())
 	if err != nil {
 		return err
 	}
-	m.RotateTo(s)
+	m.rotateTo(s)
 	return nil
 }
 
@@ -109,12 +143,80 @@ func (m *Minter) Rotate() error {
 // remain verifiable via PublicKeys. Use this for KMS-managed key rotation:
 // create a Signer pointing at the new KMS key version, then call RotateTo.
 func (m *Minter) RotateTo(s Signer) {
+	m.rotateTo(asAlgorithmSigner(s))
+}
+
+// SetSigners replaces both the current and previous signers. previous may
+// be nil. Use it when the key set is managed outside the process, for
+// example shared between replicas, and this Minter only follows it.
+func (m *Minter) SetSigners(current, previous AlgorithmSigner) {
+	m.mu.Lock()
+	m.current = current
+	m.previous = previous
+	m.header = ""
+	m.mu.Unlock()
+}
+
+func (m *Minter) rotateTo(s AlgorithmSigner) {
 	m.mu.Lock()
 	m.previous = m.current
 	m.current = s
+	m.header = ""
 	m.mu.Unlock()
 }
 
+// signer returns the current signer and its encoded JWT header.
+func (m *Minter) signer() (AlgorithmSigner, string, error) {
+	m.cutOver()
+	m.mu.RLock()
+	s, header := m.current, m.header
+	m.mu.RUnlock()
+	if header != "" {
+		return m.pooled(s), header, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.header == "" {
+		h, err := jwtHeader(m.current)
+		if err != nil {
+			return nil, "", err
+		}
+		m.header = h
+	}
+	return m.pooled(m.current), m.header, nil
+}
+
+// Ping signs a fixed input with the current signer and discards the
+// result, to check that a remote signing backend is reachable. ctx bounds
+// the call as it does for Mint.
+func (m *Minter) Ping(ctx context.Context) error {
+	m.cutOver()
+	m.mu.RLock()
+	s := m.current
+	m.mu.RUnlock()
+	if _, err := s.SignJWS(ctx, []byte("svid-exchange readiness probe")); err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+	return nil
+}
+
+// jwtHeader returns the base64url-encoded JWT header for tokens signed by s.
+func jwtHeader(s AlgorithmSigner) (string, error) {
+	kid, err := KeyID(s.Public())
+	if err != nil {
+		return "", fmt.Errorf("compute key id: %w", err)
+	}
+	headerBytes, err := json.Marshal(struct {
+		Alg Algorithm `json:"alg"`
+		Typ string    `json:"typ"`
+		Kid string    `json:"kid"`
+	}{s.Algorithm(), "JWT", kid})
+	if err != nil {
+		return "", fmt.Errorf("marshal jwt header: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(headerBytes), nil
+}
+
 // MintResult holds the signed token and its metadata.
 type MintResult struct {
 	Token         string
@@ -126,82 +228,147 @@ type MintResult struct {
 // Mint signs a JWT for the given subject/target/scopes/ttl.
 // The JWT is constructed manually so that any Signer backend — local key or
 // KMS — can provide the signature without access to the private key bytes.
-// ttlSeconds must be positive; the policy layer enforces the ceiling.
-func (m *Minter) Mint(subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
-	m.mu.RLock()
-	signer := m.current
-	m.mu.RUnlock()
-
-	kid, err := KeyID(signer.PublicKey())
+// ttlSeconds must be positive; the policy layer enforces the ceiling. ctx is
+// passed to the signer so a remote backend can abandon the call when the
+// exchange deadline passes.
+func (m *Minter) Mint(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
+	signer, header, err := m.signer()
 	if err != nil {
-		return MintResult{}, fmt.Errorf("compute key id: %w", err)
+		return MintResult{}, err
 	}
-	headerBytes, err := json.Marshal(struct {
-		Alg string `json:"alg"`
-		Typ string `json:"typ"`
-		Kid string `json:"kid"`
-	}{"ES256", "JWT", kid})
-	if err != nil {
-		return MintResult{}, fmt.Errorf("marshal jwt header: %w", err)
+	now, notBefore := m.claimTimes()
+	return mintWith(ctx, signer, header, now, notBefore, subject, target, scopes, ttlSeconds, actSubject)
+}
+
+// jwtClaims is the JWT payload. Fields are in lexical order so the encoding
+// matches what marshalling an equivalent map would produce.
+type jwtClaims struct {
+	Act       *actClaim `json:"act,omitempty"`
+	Aud       []string  `json:"aud"`
+	Exp       int64     `json:"exp"`
+	Iat       int64     `json:"iat"`
+	Iss       string    `json:"iss"`
+	Jti       string    `json:"jti"`
+	Nbf       int64     `json:"nbf,omitempty"`
+	Scope     string    `json:"scope"`
+	SingleUse bool      `json:"single_use,omitempty"`
+	Src       *Source   `json:"src,omitempty"`
+	Sub       string    `json:"sub"`
+}
+
+// actClaim is the RFC 8693 actor claim naming the delegating caller.
+type actClaim struct {
+	Sub string `json:"sub"`
+}
+
+// mintWith builds and signs the JWT with signer, whose encoded header is
+// header, issued at now. Minter and SVIDMinter share it, so both formats
+// have the same claim layout; SVIDMinter only adds checks before calling it
+// and passes a zero notBefore, which leaves out the nbf claim.
+func mintWith(ctx context.Context, signer AlgorithmSigner, header string, now, notBefore time.Time, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
+	if err := ctx.Err(); err != nil {
+		return MintResult{}, err
 	}
-	header := base64.RawURLEncoding.EncodeToString(headerBytes)
 
-	jti := uuid.New().String()
-	now := time.Now().UTC()
+	jti := newJTI()
 	exp := now.Add(time.Duration(ttlSeconds) * time.Second)
 
-	claims := map[string]any{
-		"iss":   issuer,
-		"sub":   subject,
-		"aud":   []string{target},
-		"scope": strings.Join(scopes, " "),
-		"iat":   now.Unix(),
-		"exp":   exp.Unix(),
-		"jti":   jti,
+	claims := jwtClaims{
+		Iss:       issuerClaim(ctx),
+		Sub:       subject,
+		Aud:       audienceClaim(ctx, target),
+		Scope:     strings.Join(scopes, " "),
+		Iat:       now.Unix(),
+		Exp:       exp.Unix(),
+		Jti:       jti,
+		SingleUse: singleUse(ctx),
+		Src:       sourceClaim(ctx),
+	}
+	if !notBefore.IsZero() {
+		claims.Nbf = notBefore.Unix()
 	}
 	if actSubject != "" {
-		claims["act"] = map[string]any{"sub": actSubject}
+		claims.Act = &actClaim{Sub: actSubject}
 	}
 	payloadBytes, err := json.Marshal(claims)
 	if err != nil {
 		return MintResult{}, fmt.Errorf("marshal claims: %w", err)
 	}
 
-	payload := base64.RawURLEncoding.EncodeToString(payloadBytes)
-	signingString := header + "." + payload
-	digest := sha256.Sum256([]byte(signingString))
+	// Assemble header.payload.signature in one buffer sized for the largest
+	// supported signature (RS256, 256 bytes), so the signing input is never
+	// copied.
+	enc := base64.RawURLEncoding
+	buf := make([]byte, 0, len(header)+1+enc.EncodedLen(len(payloadBytes))+1+enc.EncodedLen(256))
+	buf = append(buf, header...)
+	buf = append(buf, '.')
+	buf = enc.AppendEncode(buf, payloadBytes)
 
-	sig, err := signer.Sign(digest[:])
+	sig, err := signer.SignJWS(ctx, buf)
 	if err != nil {
 		return MintResult{}, fmt.Errorf("sign token: %w", err)
 	}
 
-	signed := signingString + "." + base64.RawURLEncoding.EncodeToString(sig)
+	buf = append(buf, '.')
+	buf = enc.AppendEncode(buf, sig)
 	return MintResult{
-		Token:         signed,
+		Token:         string(buf),
 		TokenID:       jti,
 		ExpiresAt:     exp,
 		GrantedScopes: scopes,
 	}, nil
 }
 
-// VerifyJWT validates an ES256 JWT produced by this service and returns its
+// VerifyJWT validates a JWT produced by this service and returns its
 // sub claim. The signature must match at least one of the provided public keys,
 // the token must not be expired, and its issuer must be "svid-exchange".
 // Audience is intentionally not checked: on_behalf_of tokens were issued for
 // an intermediate service, not for svid-exchange.
-func VerifyJWT(raw string, keys []*ecdsa.PublicKey) (string, error) {
+func VerifyJWT(raw string, keys []crypto.PublicKey) (string, error) {
+	claims, err := VerifyClaims(raw, keys, "")
+	if err != nil {
+		return "", err
+	}
+	sub, ok := claims["sub"].(string)
+	if !ok || sub == "" {
+		return "", fmt.Errorf("JWT has no sub claim")
+	}
+	return sub, nil
+}
+
+// VerifyClaims validates a JWT produced by this service and returns its
+// claims. The signature must match at least one of the provided public keys
+// under the algorithm AlgorithmFor assigns to that key, the token must not be
+// expired, and its issuer must be "svid-exchange". When audience is non-empty
+// the aud claim must contain it.
+func VerifyClaims(raw string, keys []crypto.PublicKey, audience string) (jwt.MapClaims, error) {
 	if len(keys) == 0 {
-		return "", fmt.Errorf("no signing keys available")
+		return nil, fmt.Errorf("no signing keys available")
+	}
+	parser := tokenParser
+	if audience != "" {
+		parser = jwt.NewParser(
+			jwt.WithValidMethods(validMethods),
+			jwt.WithIssuer(issuer),
+			jwt.WithExpirationRequired(),
+			jwt.WithAudience(audience),
+		)
 	}
 	var lastErr error
 	for _, key := range keys {
-		tok, err := jwt.Parse(raw, func(t *jwt.Token) (any, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodECDSA); !ok {
+		// Pin the algorithm to the key type so that, e.g., an ES256 key can
+		// never be used to check an ES384 signature.
+		alg, err := AlgorithmFor(key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		tok, err := parser.Parse(raw, func(t *jwt.Token) (any, error) {
+			if t.Method.Alg() != string(alg) {
 				return nil, fmt.Errorf("unexpected signing method %q", t.Header["alg"])
 			}
 			return key, nil
-		}, jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
+		})
 		if err != nil {
 			lastErr = err
 			continue
@@ -211,11 +378,7 @@ func VerifyJWT(raw string, keys []*ecdsa.PublicKey) (string, error) {
 			lastErr = fmt.Errorf("invalid token claims")
 			continue
 		}
-		sub, ok := claims["sub"].(string)
-		if !ok || sub == "" {
-			return "", fmt.Errorf("JWT has no sub claim")
-		}
-		return sub, nil
+		return claims, nil
 	}
-	return "", lastErr
+	return nil, lastErr
 }
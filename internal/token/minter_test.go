@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
 )
 
 func newTestMinter(t *testing.T) *Minter {
@@ -24,7 +26,7 @@ func newTestMinter(t *testing.T) *Minter {
 	if err != nil {
 		t.Fatalf("generate key: %v", err)
 	}
-	return &Minter{current: &ecdsaSigner{key: key}}
+	return &Minter{current: newECDSASigner(key)}
 }
 
 func parseClaims(t *testing.T, m *Minter, tokenStr string) jwt.MapClaims {
@@ -66,7 +68,7 @@ func TestMint(t *testing.T) {
 		scopes := []string{"payments:charge", "payments:refund"}
 
 		before := time.Now().Unix()
-		result, err := m.Mint(subject, target, scopes, 300, "")
+		result, err := m.Mint(context.Background(), subject, target, scopes, 300, "")
 		after := time.Now().Unix()
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
@@ -116,7 +118,7 @@ func TestMint(t *testing.T) {
 	})
 
 	t.Run("scope claim lists all granted scopes", func(t *testing.T) {
-		result, err := m.Mint("spiffe://a", "spiffe://b", []string{"payments:charge"}, 60, "")
+		result, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"payments:charge"}, 60, "")
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
 		}
@@ -130,7 +132,7 @@ func TestMint(t *testing.T) {
 	t.Run("JTI is unique across mints", func(t *testing.T) {
 		seen := make(map[string]bool)
 		for i := 0; i < 100; i++ {
-			r, err := m.Mint("spiffe://a", "spiffe://b", []string{"s:r"}, 60, "")
+			r, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"s:r"}, 60, "")
 			if err != nil {
 				t.Fatalf("Mint: %v", err)
 			}
@@ -147,14 +149,14 @@ func TestNewMinterFromSigner(t *testing.T) {
 	if err != nil {
 		t.Fatalf("generate key: %v", err)
 	}
-	s := &ecdsaSigner{key: key}
+	s := newECDSASigner(key)
 	m := NewMinterFromSigner(s)
 
 	if m.PublicKey() != &key.PublicKey {
 		t.Error("PublicKey should match the injected signer's key")
 	}
 	// Verify a token minted by the injected signer is valid.
-	result, err := m.Mint("spiffe://a", "spiffe://b", []string{"r:w"}, 60, "")
+	result, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r:w"}, 60, "")
 	if err != nil {
 		t.Fatalf("Mint: %v", err)
 	}
@@ -169,7 +171,7 @@ func TestRotateTo(t *testing.T) {
 	if err != nil {
 		t.Fatalf("generate key2: %v", err)
 	}
-	s2 := &ecdsaSigner{key: key2}
+	s2 := newECDSASigner(key2)
 	m.RotateTo(s2)
 
 	if m.PublicKey() == prevPub {
@@ -196,7 +198,7 @@ func TestRotateTo(t *testing.T) {
 	}
 
 	// Tokens minted before RotateTo must still verify with the old key.
-	result, err := NewMinterFromSigner(&ecdsaSigner{key: key2}).Mint("spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+	result, err := NewMinterFromSigner(newECDSASigner(key2)).Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
 	if err != nil {
 		t.Fatalf("Mint after RotateTo: %v", err)
 	}
@@ -237,7 +239,7 @@ func TestRotate(t *testing.T) {
 
 	// Tokens minted before rotation must still be verifiable with the old key.
 	m2 := newTestMinter(t)
-	result, err := m2.Mint("spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+	result, err := m2.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
 	if err != nil {
 		t.Fatalf("Mint: %v", err)
 	}
@@ -267,11 +269,43 @@ func TestRotate(t *testing.T) {
 	}
 }
 
+func TestSetSigners(t *testing.T) {
+	m := newTestMinter(t)
+	cur, err := newSigner(ES384)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+	prev, err := newSigner(ES256)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+
+	m.SetSigners(cur, prev)
+	if m.Algorithm() != ES384 || m.PublicKey() != cur.Public() {
+		t.Errorf("current key not replaced: alg %q", m.Algorithm())
+	}
+	if keys := m.PublicKeys(); len(keys) != 2 || keys[1] != prev.Public() {
+		t.Errorf("PublicKeys() = %v, want current and previous", keys)
+	}
+	res, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if got := headerAlg(t, res.Token); got != string(ES384) {
+		t.Errorf("header alg = %q, want ES384 after SetSigners", got)
+	}
+
+	m.SetSigners(cur, nil)
+	if keys := m.PublicKeys(); len(keys) != 1 {
+		t.Errorf("PublicKeys() = %d keys, want 1 with no previous", len(keys))
+	}
+}
+
 func TestTokenValidation(t *testing.T) {
 	m := newTestMinter(t)
 
 	t.Run("tampered payload is rejected", func(t *testing.T) {
-		result, err := m.Mint("spiffe://cluster.local/caller", "spiffe://cluster.local/target", []string{"r:w"}, 60, "")
+		result, err := m.Mint(context.Background(), "spiffe://cluster.local/caller", "spiffe://cluster.local/target", []string{"r:w"}, 60, "")
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
 		}
@@ -308,7 +342,7 @@ func TestTokenValidation(t *testing.T) {
 	})
 
 	t.Run("token for wrong audience is rejected", func(t *testing.T) {
-		result, err := m.Mint("spiffe://cluster.local/caller", "spiffe://cluster.local/service-a", []string{"r:w"}, 60, "")
+		result, err := m.Mint(context.Background(), "spiffe://cluster.local/caller", "spiffe://cluster.local/service-a", []string{"r:w"}, 60, "")
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
 		}
@@ -322,13 +356,14 @@ func TestTokenValidation(t *testing.T) {
 	})
 
 	t.Run("expired token is rejected", func(t *testing.T) {
-		result, err := m.Mint("spiffe://cluster.local/caller", "spiffe://cluster.local/target", []string{"r:w"}, 1, "")
+		// Issued two seconds ago with a one-second TTL.
+		m.SetClock(clock.NewFake(time.Now().Add(-2 * time.Second)))
+		defer m.SetClock(nil)
+		result, err := m.Mint(context.Background(), "spiffe://cluster.local/caller", "spiffe://cluster.local/target", []string{"r:w"}, 1, "")
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
 		}
 
-		time.Sleep(1100 * time.Millisecond)
-
 		_, err = jwt.Parse(result.Token, func(tok *jwt.Token) (any, error) {
 			return m.PublicKey(), nil
 		})
@@ -397,7 +432,7 @@ type errSigner struct {
 	pub *ecdsa.PublicKey
 }
 
-func (e *errSigner) Sign(_ []byte) ([]byte, error) {
+func (e *errSigner) Sign(_ context.Context, _ []byte) ([]byte, error) {
 	return nil, errors.New("kms unavailable")
 }
 
@@ -405,13 +440,25 @@ func (e *errSigner) PublicKey() *ecdsa.PublicKey {
 	return e.pub
 }
 
+func TestMintCancelledContext(t *testing.T) {
+	m, err := NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Mint(ctx, "spiffe://a", "spiffe://b", []string{"r"}, 60, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Mint error = %v, want context.Canceled", err)
+	}
+}
+
 func TestMintSignerError(t *testing.T) {
 	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		t.Fatalf("generate key: %v", err)
 	}
 	m := NewMinterFromSigner(&errSigner{pub: &key.PublicKey})
-	_, err = m.Mint("spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+	_, err = m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
 	if err == nil {
 		t.Fatal("expected error from Mint, got nil")
 	}
@@ -420,6 +467,20 @@ func TestMintSignerError(t *testing.T) {
 	}
 }
 
+func TestMinterPing(t *testing.T) {
+	if err := newTestMinter(t).Ping(context.Background()); err != nil {
+		t.Errorf("Ping() = %v, want nil", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	err = NewMinterFromSigner(&errSigner{pub: &key.PublicKey}).Ping(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "kms unavailable") {
+		t.Errorf("Ping() = %v, want the signer's error", err)
+	}
+}
+
 func TestMinterConcurrentMintRotate(t *testing.T) {
 	m, err := NewMinter()
 	if err != nil {
@@ -446,7 +507,7 @@ func TestMinterConcurrentMintRotate(t *testing.T) {
 					return
 				default:
 				}
-				r, err := m.Mint("spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+				r, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
 				if err != nil {
 					t.Errorf("Mint: %v", err)
 					return
@@ -488,3 +549,89 @@ func TestMinterConcurrentMintRotate(t *testing.T) {
 		}
 	}
 }
+
+func TestMintHeaderFollowsRotation(t *testing.T) {
+	m := newTestMinter(t)
+	kidOf := func(tok string) string {
+		t.Helper()
+		raw, err := base64.RawURLEncoding.DecodeString(strings.SplitN(tok, ".", 2)[0])
+		if err != nil {
+			t.Fatalf("decode header: %v", err)
+		}
+		var h struct {
+			Kid string `json:"kid"`
+		}
+		if err := json.Unmarshal(raw, &h); err != nil {
+			t.Fatalf("unmarshal header: %v", err)
+		}
+		return h.Kid
+	}
+
+	for range 2 {
+		r, err := m.Mint(context.Background(), "spiffe://a", "spiffe://b", []string{"r"}, 60, "")
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		want, _ := KeyID(m.PublicKey())
+		if got := kidOf(r.Token); got != want {
+			t.Fatalf("kid = %q, want %q", got, want)
+		}
+		if err := m.Rotate(); err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+	}
+}
+
+func BenchmarkMint(b *testing.B) {
+	for _, alg := range []Algorithm{ES256, ES384, RS256, EdDSA} {
+		b.Run(string(alg), func(b *testing.B) {
+			m, err := NewMinterWithAlgorithm(alg)
+			if err != nil {
+				b.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			ctx := context.Background()
+			scopes := []string{"payments:charge"}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := m.Mint(ctx, "spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/payment", scopes, 300, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMintParallel(b *testing.B) {
+	m, err := NewMinter()
+	if err != nil {
+		b.Fatalf("NewMinter: %v", err)
+	}
+	ctx := context.Background()
+	scopes := []string{"payments:charge"}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := m.Mint(ctx, "spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/payment", scopes, 300, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkVerifyClaims(b *testing.B) {
+	m, err := NewMinter()
+	if err != nil {
+		b.Fatalf("NewMinter: %v", err)
+	}
+	res, err := m.Mint(context.Background(), "spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/payment", []string{"payments:charge"}, 300, "")
+	if err != nil {
+		b.Fatalf("Mint: %v", err)
+	}
+	keys := m.PublicKeys()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := VerifyClaims(res.Token, keys, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
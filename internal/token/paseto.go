@@ -0,0 +1,187 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// pasetoHeader is the PASETO v4.public protocol header.
+const pasetoHeader = "v4.public."
+
+// PASERKPublicPrefix prefixes a PASERK-encoded v4 public key.
+const PASERKPublicPrefix = "k4.public."
+
+// PASETOMinter mints PASETO v4.public tokens (Ed25519 over the PASETO
+// pre-authentication encoding) carrying the same claims as the JWT format.
+// Times are RFC 3339 strings and aud is a single string, as PASETO's
+// registered claims require. The footer carries the kid of the signing key.
+//
+// The underlying Minter must use EdDSA. It should not be the JWT Minter:
+// PASETO forbids reusing a key across protocols, so callers keep a dedicated
+// Minter and rotate it alongside the JWT one.
+type PASETOMinter struct {
+	m *Minter
+}
+
+// NewPASETOMinter returns a PASETOMinter that signs with m's current key. It
+// fails if m does not use EdDSA.
+func NewPASETOMinter(m *Minter) (*PASETOMinter, error) {
+	if alg := m.Algorithm(); alg != EdDSA {
+		return nil, fmt.Errorf("paseto v4.public requires an EdDSA minter, got %s", alg)
+	}
+	return &PASETOMinter{m: m}, nil
+}
+
+type pasetoFooter struct {
+	Kid string `json:"kid"`
+}
+
+// Mint signs a v4.public token for subject/target/scopes/ttl.
+func (p *PASETOMinter) Mint(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
+	p.m.cutOver()
+	p.m.mu.RLock()
+	signer := p.m.pooled(p.m.current)
+	p.m.mu.RUnlock()
+
+	kid, err := KeyID(signer.Public())
+	if err != nil {
+		return MintResult{}, fmt.Errorf("compute key id: %w", err)
+	}
+	footer, err := json.Marshal(pasetoFooter{Kid: kid})
+	if err != nil {
+		return MintResult{}, fmt.Errorf("marshal paseto footer: %w", err)
+	}
+
+	jti := newJTI()
+	now, notBefore := p.m.claimTimes()
+	exp := now.Add(time.Duration(ttlSeconds) * time.Second)
+	claims := map[string]any{
+		"iss":   issuer,
+		"sub":   subject,
+		"aud":   target,
+		"scope": strings.Join(scopes, " "),
+		"iat":   now.Format(time.RFC3339),
+		"nbf":   notBefore.Format(time.RFC3339),
+		"exp":   exp.Format(time.RFC3339),
+		"jti":   jti,
+	}
+	if actSubject != "" {
+		claims["act"] = map[string]any{"sub": actSubject}
+	}
+	if src := sourceClaim(ctx); src != nil {
+		claims["src"] = src
+	}
+	msg, err := json.Marshal(claims)
+	if err != nil {
+		return MintResult{}, fmt.Errorf("marshal claims: %w", err)
+	}
+
+	sig, err := signer.SignJWS(ctx, pae([]byte(pasetoHeader), msg, footer, nil))
+	if err != nil {
+		return MintResult{}, fmt.Errorf("sign token: %w", err)
+	}
+	raw := pasetoHeader + base64.RawURLEncoding.EncodeToString(append(msg, sig...)) +
+		"." + base64.RawURLEncoding.EncodeToString(footer)
+	return MintResult{Token: raw, TokenID: jti, ExpiresAt: exp, GrantedScopes: scopes}, nil
+}
+
+// PublicKeys returns the active Ed25519 verification keys.
+func (p *PASETOMinter) PublicKeys() []crypto.PublicKey {
+	return p.m.PublicKeys()
+}
+
+// PASERKPublic encodes pub as a PASERK k4.public string.
+func PASERKPublic(pub ed25519.PublicKey) string {
+	return PASERKPublicPrefix + base64.RawURLEncoding.EncodeToString(pub)
+}
+
+// VerifyPASETO validates a v4.public token against keys and returns its
+// claims converted to the JWT claim layout (numeric iat, nbf, and exp; aud as
+// a one-element list) so callers can treat both formats alike. The issuer
+// must be "svid-exchange", the token must be within nbf/exp, and when
+// audience is non-empty aud must equal it.
+func VerifyPASETO(raw string, keys []crypto.PublicKey, audience string) (jwt.MapClaims, error) {
+	body, ok := strings.CutPrefix(raw, pasetoHeader)
+	if !ok {
+		return nil, errors.New("not a v4.public token")
+	}
+	payloadB64, footerB64, _ := strings.Cut(body, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(payloadB64)
+	if err != nil || len(payload) < ed25519.SignatureSize {
+		return nil, errors.New("malformed paseto payload")
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(footerB64)
+	if err != nil {
+		return nil, errors.New("malformed paseto footer")
+	}
+	msg, sig := payload[:len(payload)-ed25519.SignatureSize], payload[len(payload)-ed25519.SignatureSize:]
+	m2 := pae([]byte(pasetoHeader), msg, footer, nil)
+
+	verified := false
+	for _, k := range keys {
+		if pub, ok := k.(ed25519.PublicKey); ok && ed25519.Verify(pub, m2, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("paseto signature is invalid")
+	}
+
+	var claims jwt.MapClaims
+	if err := json.Unmarshal(msg, &claims); err != nil {
+		return nil, fmt.Errorf("decode paseto claims: %w", err)
+	}
+	if claims["iss"] != issuer {
+		return nil, fmt.Errorf("paseto issuer must be %q", issuer)
+	}
+	now := time.Now()
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		s, ok := claims[name].(string)
+		if !ok {
+			if name == "exp" {
+				return nil, errors.New("paseto has no exp claim")
+			}
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("paseto %s claim: %w", name, err)
+		}
+		switch {
+		case name == "exp" && !now.Before(t):
+			return nil, errors.New("paseto is expired")
+		case name == "nbf" && now.Before(t):
+			return nil, errors.New("paseto is not yet valid")
+		}
+		claims[name] = float64(t.Unix())
+	}
+	aud, _ := claims["aud"].(string)
+	if audience != "" && aud != audience {
+		return nil, fmt.Errorf("paseto audience %q does not match %q", aud, audience)
+	}
+	claims["aud"] = []any{aud}
+	return claims, nil
+}
+
+// pae is the PASETO pre-authentication encoding: the piece count followed by
+// each piece prefixed with its length, all as little-endian uint64 values
+// with the top bit cleared.
+func pae(pieces ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces))&^(1<<63))
+	for _, p := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(p))&^(1<<63))
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestPAE(t *testing.T) {
+	// Vectors from the PASETO specification, "Pre-Authentication Encoding".
+	tests := []struct {
+		pieces [][]byte
+		want   string
+	}{
+		{pieces: nil, want: "\x00\x00\x00\x00\x00\x00\x00\x00"},
+		{pieces: [][]byte{{}}, want: "\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"},
+		{pieces: [][]byte{{}, {}}, want: "\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"},
+		{pieces: [][]byte{[]byte("Paragon")}, want: "\x01\x00\x00\x00\x00\x00\x00\x00\x07\x00\x00\x00\x00\x00\x00\x00Paragon"},
+		{pieces: [][]byte{[]byte("Paragon"), []byte("Initiative")}, want: "\x02\x00\x00\x00\x00\x00\x00\x00\x07\x00\x00\x00\x00\x00\x00\x00Paragon\x0a\x00\x00\x00\x00\x00\x00\x00Initiative"},
+	}
+	for _, tc := range tests {
+		if got := pae(tc.pieces...); !bytes.Equal(got, []byte(tc.want)) {
+			t.Errorf("pae(%q) = %q, want %q", tc.pieces, got, tc.want)
+		}
+	}
+}
+
+func TestPASETOMinter(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	m, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	p, err := NewPASETOMinter(m)
+	if err != nil {
+		t.Fatalf("NewPASETOMinter: %v", err)
+	}
+	res, err := p.Mint(context.Background(), subject, target, []string{"payments:charge"}, 60, "spiffe://cluster.local/ns/default/sa/user")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if !strings.HasPrefix(res.Token, "v4.public.") {
+		t.Fatalf("token = %q, want v4.public. prefix", res.Token)
+	}
+
+	other, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	tampered := []byte(res.Token)
+	tampered[len("v4.public.")+5] ^= 1
+
+	tests := []struct {
+		name     string
+		token    string
+		audience string
+		keys     *Minter
+		wantErr  bool
+	}{
+		{name: "valid", token: res.Token, audience: target, keys: m},
+		{name: "any audience", token: res.Token, keys: m},
+		{name: "wrong audience", token: res.Token, audience: subject, keys: m, wantErr: true},
+		{name: "unknown key", token: res.Token, audience: target, keys: other, wantErr: true},
+		{name: "tampered payload", token: string(tampered), audience: target, keys: m, wantErr: true},
+		{name: "not paseto", token: "v3.public.AAAA", audience: target, keys: m, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := VerifyPASETO(tc.token, tc.keys.PublicKeys(), tc.audience)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("VerifyPASETO() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if claims["sub"] != subject || claims["jti"] != res.TokenID || claims["scope"] != "payments:charge" {
+				t.Errorf("claims = %v", claims)
+			}
+			exp, err := claims.GetExpirationTime()
+			if err != nil || !exp.Equal(res.ExpiresAt) {
+				t.Errorf("exp = %v (%v), want %v", exp, err, res.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestPASETOMinterRotation(t *testing.T) {
+	m, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	p, err := NewPASETOMinter(m)
+	if err != nil {
+		t.Fatalf("NewPASETOMinter: %v", err)
+	}
+	before, err := p.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if err := m.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := VerifyPASETO(before.Token, p.PublicKeys(), ""); err != nil {
+		t.Errorf("token minted before rotation rejected: %v", err)
+	}
+}
+
+func TestNewPASETOMinterRequiresEdDSA(t *testing.T) {
+	m, err := NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	if _, err := NewPASETOMinter(m); err == nil {
+		t.Error("NewPASETOMinter accepted an ES256 minter")
+	}
+}
+
+func TestPASERKPublic(t *testing.T) {
+	pub := ed25519.PublicKey(bytes.Repeat([]byte{0xab}, ed25519.PublicKeySize))
+	got := PASERKPublic(pub)
+	want := "k4.public." + base64.RawURLEncoding.EncodeToString(pub)
+	if got != want {
+		t.Errorf("PASERKPublic = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrSigningQueueFull is returned by a mint that finds every worker busy
+	// and the SigningPipeline queue full. It is load, not a fault: the caller
+	// may retry.
+	ErrSigningQueueFull = errors.New("signing queue full")
+	// ErrPipelineClosed is returned by a mint submitted to, or still queued
+	// in, a SigningPipeline after Close.
+	ErrPipelineClosed = errors.New("signing pipeline closed")
+)
+
+// SigningPipeline signs on a fixed set of long-lived worker goroutines fed
+// by a queue. A mint hands its signing input to the pipeline and waits for
+// the signature or for its context to end, whichever comes first.
+//
+// The pipeline bounds how many signatures are in flight. For an in-process
+// key this keeps a burst of exchanges from spreading ECDSA work across every
+// request goroutine. For a KMS backend, where each call spends most of its
+// time waiting on the network, it caps the connections and quota the service
+// uses while keeping enough calls outstanding to hide the latency; size it at
+// about the target rate times the backend's call latency.
+//
+// One pipeline may serve several Minters; each job carries its own signer.
+type SigningPipeline struct {
+	jobs chan signJob
+	// bounded makes a full queue fail with ErrSigningQueueFull instead of
+	// waiting for room.
+	bounded   bool
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type signJob struct {
+	ctx    context.Context
+	signer AlgorithmSigner
+	input  []byte
+	// result has room for one value so a worker never blocks on a caller
+	// that gave up waiting.
+	result chan signResult
+}
+
+type signResult struct {
+	sig []byte
+	err error
+}
+
+// NewSigningPipeline starts workers signing goroutines. queue is how many
+// signatures may wait for a free worker; a mint that would exceed it fails
+// at once with ErrSigningQueueFull. A zero queue does not bound waiting
+// mints: they wait for a worker until their context ends. Call Close to stop
+// the workers.
+func NewSigningPipeline(workers, queue int) (*SigningPipeline, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("signing workers %d must be positive", workers)
+	}
+	if queue < 0 {
+		return nil, fmt.Errorf("signing queue %d must not be negative", queue)
+	}
+	p := &SigningPipeline{
+		jobs:    make(chan signJob, queue),
+		bounded: queue > 0,
+		quit:    make(chan struct{}),
+	}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p, nil
+}
+
+// Close stops the workers once their current signatures finish. Mints still
+// queued or submitted afterwards fail with ErrPipelineClosed. Close is safe to
+// call more than once.
+func (p *SigningPipeline) Close() {
+	p.closeOnce.Do(func() { close(p.quit) })
+	p.wg.Wait()
+}
+
+func (p *SigningPipeline) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case j := <-p.jobs:
+			// Skip the signature for a mint that gave up while queued.
+			if err := j.ctx.Err(); err != nil {
+				j.result <- signResult{err: err}
+				continue
+			}
+			sig, err := j.signer.SignJWS(j.ctx, j.input)
+			j.result <- signResult{sig: sig, err: err}
+		}
+	}
+}
+
+// sign submits signingInput to a worker and waits for its signature.
+func (p *SigningPipeline) sign(ctx context.Context, s AlgorithmSigner, signingInput []byte) ([]byte, error) {
+	j := signJob{ctx: ctx, signer: s, input: signingInput, result: make(chan signResult, 1)}
+	if p.bounded {
+		select {
+		case p.jobs <- j:
+		case <-p.quit:
+			return nil, ErrPipelineClosed
+		default:
+			return nil, ErrSigningQueueFull
+		}
+	} else {
+		select {
+		case p.jobs <- j:
+		case <-p.quit:
+			return nil, ErrPipelineClosed
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for signing worker: %w", ctx.Err())
+		}
+	}
+	select {
+	case r := <-j.result:
+		return r.sig, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.quit:
+		return nil, ErrPipelineClosed
+	}
+}
+
+// SetSigningPipeline makes m, and the SVIDMinter and PASETOMinter built on
+// it, sign tokens and decision receipts on p. A nil p signs on the calling
+// goroutine. It must be called before m mints its first token.
+func (m *Minter) SetSigningPipeline(p *SigningPipeline) {
+	m.pipeline = p
+}
+
+// pooled returns s routed through m's signing pipeline, or s itself when m
+// has none.
+func (m *Minter) pooled(s AlgorithmSigner) AlgorithmSigner {
+	if m.pipeline == nil {
+		return s
+	}
+	return pooledSigner{AlgorithmSigner: s, pipeline: m.pipeline}
+}
+
+// pooledSigner sends each SignJWS through pipeline.
+type pooledSigner struct {
+	AlgorithmSigner
+	pipeline *SigningPipeline
+}
+
+func (s pooledSigner) SignJWS(ctx context.Context, signingInput []byte) ([]byte, error) {
+	return s.pipeline.sign(ctx, s.AlgorithmSigner, signingInput)
+}
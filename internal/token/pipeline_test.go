@@ -0,0 +1,160 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingSigner signs with the embedded signer after entered is signalled
+// and release is closed.
+type blockingSigner struct {
+	AlgorithmSigner
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSigner) SignJWS(ctx context.Context, signingInput []byte) ([]byte, error) {
+	s.entered <- struct{}{}
+	<-s.release
+	return s.AlgorithmSigner.SignJWS(ctx, signingInput)
+}
+
+func newBlockingMinter(t *testing.T, workers, queue int) (*Minter, *blockingSigner, *SigningPipeline) {
+	t.Helper()
+	inner, err := newSigner(ES256)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+	bs := &blockingSigner{AlgorithmSigner: inner, entered: make(chan struct{}, 4), release: make(chan struct{})}
+	p, err := NewSigningPipeline(workers, queue)
+	if err != nil {
+		t.Fatalf("NewSigningPipeline: %v", err)
+	}
+	m := NewMinterFromAlgorithmSigner(bs)
+	m.SetSigningPipeline(p)
+	return m, bs, p
+}
+
+func TestSigningPipeline(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	m, bs, p := newBlockingMinter(t, 1, 0)
+	defer p.Close()
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := m.Mint(context.Background(), subject, target, []string{"payments:charge"}, 60, "")
+		first <- err
+	}()
+	<-bs.entered
+
+	// The only worker is busy, so a second mint waits until its deadline
+	// without reaching the signer.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := m.Mint(ctx, subject, target, []string{"payments:charge"}, 60, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second Mint error = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := m.SignReceipt(ctx, Receipt{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SignReceipt error = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case <-bs.entered:
+		t.Fatal("second signature started while the worker was busy")
+	default:
+	}
+
+	close(bs.release)
+	if err := <-first; err != nil {
+		t.Fatalf("first Mint: %v", err)
+	}
+	// The worker is free again.
+	res, err := m.Mint(context.Background(), subject, target, []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint after release: %v", err)
+	}
+	<-bs.entered
+	if claims := parseClaims(t, m, res.Token); claims["sub"] != subject {
+		t.Errorf("sub = %v, want %s", claims["sub"], subject)
+	}
+}
+
+func TestSigningPipelineQueueFull(t *testing.T) {
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+	m, bs, p := newBlockingMinter(t, 1, 1)
+	defer p.Close()
+
+	// One mint occupies the worker and a second fills the queue.
+	done := make(chan error, 2)
+	mint := func(ctx context.Context) {
+		_, err := m.Mint(ctx, "spiffe://cluster.local/ns/default/sa/order", target, nil, 60, "")
+		done <- err
+	}
+	go mint(context.Background())
+	<-bs.entered
+	queuedCtx, cancelQueued := context.WithCancel(context.Background())
+	go mint(queuedCtx)
+	deadline := time.Now().Add(time.Second)
+	for len(p.jobs) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("second mint never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := m.Mint(context.Background(), "spiffe://cluster.local/ns/default/sa/order", target, nil, 60, ""); !errors.Is(err, ErrSigningQueueFull) {
+		t.Errorf("Mint error = %v, want ErrSigningQueueFull", err)
+	}
+
+	// The queued mint gives up; the worker skips it rather than signing.
+	cancelQueued()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("queued Mint error = %v, want context.Canceled", err)
+	}
+	close(bs.release)
+	if err := <-done; err != nil {
+		t.Errorf("first Mint: %v", err)
+	}
+	select {
+	case <-bs.entered:
+		t.Error("cancelled mint was signed")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSigningPipelineClose(t *testing.T) {
+	m, _, p := newBlockingMinter(t, 2, 0)
+	p.Close()
+	p.Close()
+	if _, err := m.Mint(context.Background(), "spiffe://cluster.local/ns/default/sa/order", "spiffe://cluster.local/ns/default/sa/payment", nil, 60, ""); !errors.Is(err, ErrPipelineClosed) {
+		t.Errorf("Mint after Close error = %v, want ErrPipelineClosed", err)
+	}
+}
+
+func TestNewSigningPipeline(t *testing.T) {
+	tests := []struct {
+		name           string
+		workers, queue int
+		wantErr        bool
+	}{
+		{name: "unbounded queue", workers: 4},
+		{name: "bounded queue", workers: 4, queue: 64},
+		{name: "no workers", workers: 0, wantErr: true},
+		{name: "negative queue", workers: 1, queue: -1, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewSigningPipeline(tc.workers, tc.queue)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewSigningPipeline(%d, %d) error = %v, wantErr %v", tc.workers, tc.queue, err, tc.wantErr)
+			}
+			if p != nil {
+				p.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ReceiptType is the typ header of a decision receipt. It differs from an
+// access token's "JWT" so that neither can be passed off as the other, and a
+// receipt carries no aud or exp, so token verifiers reject it regardless.
+const ReceiptType = "decision-receipt+jwt"
+
+// Receipt is the payload of a decision receipt: a signed record of why a
+// token was issued, which an auditor can check against the token itself
+// without trusting the audit pipeline.
+type Receipt struct {
+	Issuer   string `json:"iss"`
+	IssuedAt int64  `json:"iat"`
+	// TokenID is the jti of the token the decision issued, and TokenHash
+	// the base64url SHA-256 of the encoded token (see ReceiptTokenHash),
+	// binding the receipt to exactly one token.
+	TokenID   string `json:"token_id"`
+	TokenHash string `json:"token_hash"`
+	RequestID string `json:"request_id,omitempty"`
+	Subject   string `json:"sub"`
+	Target    string `json:"target"`
+	// ScopesRequested and ScopesGranted show which scopes policy filtered
+	// out.
+	ScopesRequested []string `json:"scopes_requested"`
+	ScopesGranted   []string `json:"scopes_granted"`
+	TTL             int32    `json:"ttl"`
+	TokenFormat     string   `json:"token_format"`
+	// Rules names the policy rules that authorized the grant, and
+	// PolicyDigest identifies the policy set they were evaluated in.
+	Rules        []string `json:"rules"`
+	PolicyDigest string   `json:"policy_digest,omitempty"`
+}
+
+// ReceiptTokenHash returns the value of Receipt.TokenHash for tok.
+func ReceiptTokenHash(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// SignReceipt signs r with the current key as a compact JWS whose typ is
+// ReceiptType, setting its issuer and, when zero, its issue time. Receipts
+// verify against the same keys as tokens, so the /jwks document serves both.
+func (m *Minter) SignReceipt(ctx context.Context, r Receipt) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	m.cutOver()
+	m.mu.RLock()
+	signer := m.pooled(m.current)
+	m.mu.RUnlock()
+
+	kid, err := KeyID(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("compute key id: %w", err)
+	}
+	header, err := json.Marshal(struct {
+		Alg Algorithm `json:"alg"`
+		Typ string    `json:"typ"`
+		Kid string    `json:"kid"`
+	}{signer.Algorithm(), ReceiptType, kid})
+	if err != nil {
+		return "", fmt.Errorf("marshal receipt header: %w", err)
+	}
+	r.Issuer = issuer
+	if r.IssuedAt == 0 {
+		r.IssuedAt = time.Now().Unix()
+	}
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("marshal receipt: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	sig, err := signer.SignJWS(ctx, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("sign receipt: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+// receiptParser verifies decision receipts. Receipts carry no exp, so it
+// does not require one.
+var receiptParser = jwt.NewParser(jwt.WithValidMethods(validMethods), jwt.WithIssuer(issuer))
+
+// VerifyReceipt checks that raw is a decision receipt signed by one of keys,
+// under the algorithm AlgorithmFor assigns to that key, and returns its
+// payload. Receipts do not expire: a receipt stays valid evidence for as long
+// as its key is trusted.
+func VerifyReceipt(raw string, keys []crypto.PublicKey) (Receipt, error) {
+	if len(keys) == 0 {
+		return Receipt{}, errors.New("no signing keys available")
+	}
+	lastErr := errors.New("no key matched")
+	for _, key := range keys {
+		alg, err := AlgorithmFor(key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		tok, err := receiptParser.Parse(raw, func(t *jwt.Token) (any, error) {
+			if typ, _ := t.Header["typ"].(string); typ != ReceiptType {
+				return nil, fmt.Errorf("typ %q is not a decision receipt", t.Header["typ"])
+			}
+			if t.Method.Alg() != string(alg) {
+				return nil, fmt.Errorf("unexpected signing method %q", t.Header["alg"])
+			}
+			return key, nil
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !tok.Valid {
+			lastErr = errors.New("invalid receipt")
+			continue
+		}
+		// The signature covers the payload, so decoding it directly yields
+		// exactly the verified claims.
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(raw, ".")[1])
+		if err != nil {
+			return Receipt{}, fmt.Errorf("decode receipt payload: %w", err)
+		}
+		var r Receipt
+		if err := json.Unmarshal(payload, &r); err != nil {
+			return Receipt{}, fmt.Errorf("unmarshal receipt: %w", err)
+		}
+		return r, nil
+	}
+	return Receipt{}, lastErr
+}
@@ -0,0 +1,64 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReceipt(t *testing.T) {
+	m := newTestMinter(t)
+	ctx := context.Background()
+	minted, err := m.Mint(ctx, "spiffe://td/order", "spiffe://td/payment", []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	in := Receipt{
+		TokenID:         minted.TokenID,
+		TokenHash:       ReceiptTokenHash(minted.Token),
+		Subject:         "spiffe://td/order",
+		Target:          "spiffe://td/payment",
+		ScopesRequested: []string{"payments:charge", "payments:refund"},
+		ScopesGranted:   []string{"payments:charge"},
+		TTL:             60,
+		TokenFormat:     "jwt",
+		Rules:           []string{"order-to-payment"},
+		PolicyDigest:    "abc123",
+	}
+	raw, err := m.SignReceipt(ctx, in)
+	if err != nil {
+		t.Fatalf("SignReceipt: %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := VerifyReceipt(raw, m.PublicKeys())
+		if err != nil {
+			t.Fatalf("VerifyReceipt: %v", err)
+		}
+		if got.Issuer != issuer || got.IssuedAt == 0 || got.TokenHash != ReceiptTokenHash(minted.Token) ||
+			!slices.Equal(got.ScopesRequested, in.ScopesRequested) || !slices.Equal(got.Rules, in.Rules) || got.PolicyDigest != "abc123" {
+			t.Errorf("receipt = %+v", got)
+		}
+	})
+
+	t.Run("receipt is not a token", func(t *testing.T) {
+		if _, err := VerifyClaims(raw, m.PublicKeys(), ""); err == nil {
+			t.Error("VerifyClaims accepted a receipt")
+		}
+	})
+
+	t.Run("token is not a receipt", func(t *testing.T) {
+		if _, err := VerifyReceipt(minted.Token, m.PublicKeys()); err == nil || !strings.Contains(err.Error(), "typ") {
+			t.Errorf("VerifyReceipt(token) err = %v, want a typ error", err)
+		}
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other := newTestMinter(t)
+		if _, err := VerifyReceipt(raw, []crypto.PublicKey{other.PublicKey()}); err == nil {
+			t.Error("VerifyReceipt accepted a receipt signed by another key")
+		}
+	})
+}
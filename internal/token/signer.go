@@ -1,9 +1,15 @@
 package token
 
 import (
+	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/asn1"
 	"fmt"
 	"math/big"
@@ -17,40 +23,186 @@ import (
 // Sign receives the SHA-256 digest of the JWT signing string and must return
 // the signature in IEEE P1363 format (r‖s, each coordinate zero-padded to
 // 32 bytes for P-256). AWS KMS and GCP KMS return DER-encoded signatures;
-// convert them with DERToP1363 before returning.
+// convert them with DERToP1363 before returning. Remote backends must honour
+// ctx: the exchange handler bounds minting with a deadline, and a call that
+// outlives it only holds the request open.
+//
+// Signer always produces ES256 tokens. For other algorithms implement
+// AlgorithmSigner instead.
 type Signer interface {
-	Sign(digest []byte) ([]byte, error)
+	Sign(ctx context.Context, digest []byte) ([]byte, error)
 	PublicKey() *ecdsa.PublicKey
 }
 
-// ecdsaSigner is the default in-process Signer backed by an ephemeral
-// ECDSA P-256 private key. For production use, replace with a KMS-backed
-// implementation so the private key never leaves the HSM boundary.
-type ecdsaSigner struct {
-	key *ecdsa.PrivateKey
+// AlgorithmSigner is a signing backend for any supported Algorithm.
+//
+// SignJWS receives the JWS signing input (base64url header "." base64url
+// payload) and returns the raw JWS signature for Algorithm: r‖s for ECDSA,
+// the PKCS #1 v1.5 signature for RS256, or the 64-byte Ed25519 signature.
+// The signer hashes the input itself because Ed25519 signs the message, not
+// a digest. As with Signer, ctx carries the mint deadline.
+type AlgorithmSigner interface {
+	Algorithm() Algorithm
+	SignJWS(ctx context.Context, signingInput []byte) ([]byte, error)
+	Public() crypto.PublicKey
 }
 
-func newECDSASigner() (*ecdsaSigner, error) {
-	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+// newSigner generates an ephemeral in-process key pair for alg.
+func newSigner(alg Algorithm) (AlgorithmSigner, error) {
+	key, err := GenerateKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	return NewAlgorithmSigner(key)
+}
+
+// GenerateKey returns a new private key for alg: a P-256 or P-384 ECDSA key,
+// a 2048-bit RSA key, or an Ed25519 key.
+func GenerateKey(alg Algorithm) (crypto.Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch alg {
+	case ES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ES384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case RS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case EdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("generate signing key: %w", err)
 	}
-	return &ecdsaSigner{key: key}, nil
+	return key, nil
+}
+
+// NewAlgorithmSigner returns an in-process AlgorithmSigner for key, which
+// must be one GenerateKey produces. Use it to sign with a key loaded from
+// outside the process, such as one shared between replicas.
+func NewAlgorithmSigner(key crypto.PrivateKey) (AlgorithmSigner, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() && k.Curve != elliptic.P384() {
+			return nil, fmt.Errorf("unsupported EC curve %s", k.Curve.Params().Name)
+		}
+		return newECDSASigner(k), nil
+	case *rsa.PrivateKey:
+		return &rsaSigner{key: k}, nil
+	case ed25519.PrivateKey:
+		return ed25519Signer(k), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
 }
 
-func (s *ecdsaSigner) Sign(digest []byte) ([]byte, error) {
-	coordLen := (s.key.Curve.Params().BitSize + 7) / 8
+// ecdsaSigner is the default in-process Signer backed by an ephemeral
+// ECDSA private key: P-256 for ES256, P-384 for ES384. For production use,
+// replace with a KMS-backed implementation so the private key never leaves
+// the HSM boundary.
+//
+// alg and coordLen are derived from the curve once, when the signer is
+// built, rather than on every signature.
+type ecdsaSigner struct {
+	key      *ecdsa.PrivateKey
+	alg      Algorithm
+	coordLen int
+}
+
+// newECDSASigner returns an ecdsaSigner for key, which must be on P-256 or
+// P-384.
+func newECDSASigner(key *ecdsa.PrivateKey) *ecdsaSigner {
+	alg := ES256
+	if key.Curve == elliptic.P384() {
+		alg = ES384
+	}
+	return &ecdsaSigner{key: key, alg: alg, coordLen: (key.Curve.Params().BitSize + 7) / 8}
+}
+
+func (s *ecdsaSigner) Sign(_ context.Context, digest []byte) ([]byte, error) {
 	der, err := ecdsa.SignASN1(rand.Reader, s.key, digest)
 	if err != nil {
 		return nil, fmt.Errorf("ecdsa sign: %w", err)
 	}
-	return DERToP1363(der, coordLen)
+	return DERToP1363(der, s.coordLen)
 }
 
 func (s *ecdsaSigner) PublicKey() *ecdsa.PublicKey {
 	return &s.key.PublicKey
 }
 
+func (s *ecdsaSigner) Algorithm() Algorithm { return s.alg }
+
+func (s *ecdsaSigner) SignJWS(ctx context.Context, signingInput []byte) ([]byte, error) {
+	if s.alg == ES384 {
+		digest := sha512.Sum384(signingInput)
+		return s.Sign(ctx, digest[:])
+	}
+	digest := sha256.Sum256(signingInput)
+	return s.Sign(ctx, digest[:])
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+// rsaSigner signs RS256 tokens with an in-process RSA key.
+type rsaSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (s *rsaSigner) Algorithm() Algorithm { return RS256 }
+
+func (s *rsaSigner) SignJWS(_ context.Context, signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("rsa sign: %w", err)
+	}
+	return sig, nil
+}
+
+func (s *rsaSigner) Public() crypto.PublicKey { return &s.key.PublicKey }
+
+// ed25519Signer signs EdDSA tokens with an in-process Ed25519 key.
+type ed25519Signer ed25519.PrivateKey
+
+func (s ed25519Signer) Algorithm() Algorithm { return EdDSA }
+
+func (s ed25519Signer) SignJWS(_ context.Context, signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), signingInput), nil
+}
+
+func (s ed25519Signer) Public() crypto.PublicKey { return ed25519.PrivateKey(s).Public() }
+
+// digestSigner adapts a Signer (ES256 over a SHA-256 digest) to
+// AlgorithmSigner.
+type digestSigner struct {
+	Signer
+}
+
+func (s digestSigner) Algorithm() Algorithm { return ES256 }
+
+func (s digestSigner) SignJWS(ctx context.Context, signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	return s.Sign(ctx, digest[:])
+}
+
+func (s digestSigner) Public() crypto.PublicKey { return s.PublicKey() }
+
+// asAlgorithmSigner returns s itself when it already implements
+// AlgorithmSigner, otherwise wraps it as an ES256 digestSigner.
+func asAlgorithmSigner(s Signer) AlgorithmSigner {
+	if as, ok := s.(AlgorithmSigner); ok {
+		return as
+	}
+	return digestSigner{s}
+}
+
 // DERToP1363 converts a DER-encoded ECDSA signature to IEEE P1363 format
 // (r‖s, each coordinate zero-padded to coordLen bytes).
 // JWT ES256 requires P1363; AWS KMS and GCP KMS return DER — use this
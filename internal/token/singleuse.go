@@ -0,0 +1,23 @@
+package token
+
+import "context"
+
+// ClaimSingleUse is the claim that marks a JWT as one-time. Whoever checks
+// such a token must consume its jti in the shared store, and treat it as
+// inactive if the jti was already consumed.
+const ClaimSingleUse = "single_use"
+
+type singleUseKey struct{}
+
+// WithSingleUse returns a copy of ctx that makes Mint mark the token it
+// mints as single-use. JWT and JWT-SVID tokens carry the single_use claim;
+// other formats ignore it, and policy validation rejects the combination.
+func WithSingleUse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, singleUseKey{}, true)
+}
+
+// singleUse reports whether ctx asks for a single-use token.
+func singleUse(ctx context.Context) bool {
+	v, _ := ctx.Value(singleUseKey{}).(bool)
+	return v
+}
@@ -0,0 +1,32 @@
+package token
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMintSingleUse(t *testing.T) {
+	m, err := NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	res, err := m.Mint(WithSingleUse(context.Background()), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	claims, err := VerifyClaims(res.Token, m.PublicKeys(), "")
+	if err != nil {
+		t.Fatalf("VerifyClaims: %v", err)
+	}
+	if claims[ClaimSingleUse] != true {
+		t.Errorf("%s = %v, want true", ClaimSingleUse, claims[ClaimSingleUse])
+	}
+
+	res, err = m.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if claims, _ = VerifyClaims(res.Token, m.PublicKeys(), ""); claims[ClaimSingleUse] != nil {
+		t.Errorf("reusable token carries %s %v", ClaimSingleUse, claims[ClaimSingleUse])
+	}
+}
@@ -0,0 +1,34 @@
+package token
+
+import "context"
+
+// Source is the network identity of the caller a token is bound to, carried
+// in the src claim so that resource servers can apply coarse network-level
+// checks. Empty fields are left out of the claim.
+type Source struct {
+	// IP is the caller's address as the server observed it, without port.
+	IP string `json:"ip,omitempty"`
+	// Pod and PodUID name the Kubernetes pod the caller's credential is
+	// bound to, as <namespace>/<name>.
+	Pod    string `json:"pod,omitempty"`
+	PodUID string `json:"pod_uid,omitempty"`
+}
+
+type sourceKey struct{}
+
+// WithSource returns a copy of ctx that makes Mint bind the token it mints
+// to src. JWT, JWT-SVID, and PASETO tokens carry it as the src claim;
+// macaroons are not bound.
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// sourceClaim returns the src claim for ctx, or nil when the token is not
+// bound.
+func sourceClaim(ctx context.Context) *Source {
+	src, ok := ctx.Value(sourceKey{}).(Source)
+	if !ok || src == (Source{}) {
+		return nil
+	}
+	return &src
+}
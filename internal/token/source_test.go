@@ -0,0 +1,55 @@
+package token
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMintSource(t *testing.T) {
+	m, err := NewMinterWithAlgorithm(EdDSA)
+	if err != nil {
+		t.Fatalf("NewMinterWithAlgorithm: %v", err)
+	}
+	p, err := NewPASETOMinter(m)
+	if err != nil {
+		t.Fatalf("NewPASETOMinter: %v", err)
+	}
+	verify := map[string]func(string) (map[string]any, error){
+		"jwt": func(raw string) (map[string]any, error) { return VerifyClaims(raw, m.PublicKeys(), "") },
+		"paseto": func(raw string) (map[string]any, error) {
+			return VerifyPASETO(raw, p.PublicKeys(), "")
+		},
+	}
+	minters := map[string]interface {
+		Mint(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error)
+	}{"jwt": m, "paseto": p}
+
+	for name, minter := range minters {
+		t.Run(name, func(t *testing.T) {
+			ctx := WithSource(context.Background(), Source{IP: "10.4.0.17", Pod: "default/order-7d9f8-x2x4p"})
+			res, err := minter.Mint(ctx, "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			claims, err := verify[name](res.Token)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			src, _ := claims["src"].(map[string]any)
+			if src["ip"] != "10.4.0.17" || src["pod"] != "default/order-7d9f8-x2x4p" {
+				t.Errorf("src = %v", claims["src"])
+			}
+			if _, ok := src["pod_uid"]; ok {
+				t.Errorf("empty pod_uid is present in %v", src)
+			}
+
+			res, err = minter.Mint(WithSource(context.Background(), Source{}), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+			if err != nil {
+				t.Fatalf("Mint: %v", err)
+			}
+			if claims, _ = verify[name](res.Token); claims["src"] != nil {
+				t.Errorf("unbound token carries src %v", claims["src"])
+			}
+		})
+	}
+}
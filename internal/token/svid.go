@@ -0,0 +1,60 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// svidAlgorithms are the JWS algorithms the SPIFFE JWT-SVID specification
+// (§3) permits. EdDSA is not among them.
+var svidAlgorithms = []Algorithm{ES256, ES384, RS256}
+
+// SVIDMinter mints tokens in SPIFFE JWT-SVID format using a Minter's keys,
+// so that SPIFFE-native validators (go-spiffe jwtsvid, Envoy's SDS-backed
+// JWT filters, SPIRE's own libraries) accept them interchangeably with
+// SPIRE-issued JWT-SVIDs. Rotation is driven by the underlying Minter.
+type SVIDMinter struct {
+	m *Minter
+}
+
+// NewSVIDMinter returns an SVIDMinter that signs with m's current key.
+func NewSVIDMinter(m *Minter) *SVIDMinter {
+	return &SVIDMinter{m: m}
+}
+
+// Mint signs a JWT-SVID: sub is the caller's SPIFFE ID, aud is the single
+// target, exp and iat are set, and no nbf is emitted. The svid-exchange
+// claims (iss, jti, scope, act) are carried as additional claims, which the
+// specification allows and validators ignore. It fails if subject is not a
+// valid SPIFFE ID or the signing algorithm is not permitted for JWT-SVIDs.
+func (s *SVIDMinter) Mint(ctx context.Context, subject, target string, scopes []string, ttlSeconds int32, actSubject string) (MintResult, error) {
+	if _, err := spiffeid.FromString(subject); err != nil {
+		return MintResult{}, fmt.Errorf("jwt-svid subject: %w", err)
+	}
+	signer, header, err := s.m.signer()
+	if err != nil {
+		return MintResult{}, err
+	}
+	if alg := signer.Algorithm(); !slices.Contains(svidAlgorithms, alg) {
+		return MintResult{}, fmt.Errorf("jwt-svid does not permit %s signing; use one of %v", alg, svidAlgorithms)
+	}
+	now, _ := s.m.claimTimes()
+	return mintWith(ctx, signer, header, now, time.Time{}, subject, target, scopes, ttlSeconds, actSubject)
+}
+
+// PublicKeys returns the underlying Minter's active public keys.
+func (s *SVIDMinter) PublicKeys() []crypto.PublicKey {
+	return s.m.PublicKeys()
+}
+
+// SVIDCompatible reports whether tokens signed with pub may be presented as
+// JWT-SVIDs. Keys that are not are left out of the SPIFFE bundle.
+func SVIDCompatible(pub crypto.PublicKey) bool {
+	alg, err := AlgorithmFor(pub)
+	return err == nil && slices.Contains(svidAlgorithms, alg)
+}
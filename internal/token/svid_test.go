@@ -0,0 +1,78 @@
+package token
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+func TestSVIDMinter(t *testing.T) {
+	const (
+		subject = "spiffe://cluster.local/ns/default/sa/order"
+		target  = "spiffe://cluster.local/ns/default/sa/payment"
+	)
+	td := spiffeid.RequireTrustDomainFromString("cluster.local")
+
+	tests := []struct {
+		alg     Algorithm
+		subject string
+		wantErr bool
+	}{
+		{alg: ES256, subject: subject},
+		{alg: ES384, subject: subject},
+		{alg: RS256, subject: subject},
+		{alg: EdDSA, subject: subject, wantErr: true},
+		{alg: ES256, subject: "not-a-spiffe-id", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.alg)+" "+tc.subject, func(t *testing.T) {
+			m, err := NewMinterWithAlgorithm(tc.alg)
+			if err != nil {
+				t.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			res, err := NewSVIDMinter(m).Mint(context.Background(), tc.subject, target, []string{"payments:charge"}, 60, "")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Mint() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			// A SPIFFE-native validator holding our key in the caller's trust
+			// domain bundle must accept the token.
+			kid, err := KeyID(m.PublicKey())
+			if err != nil {
+				t.Fatalf("KeyID: %v", err)
+			}
+			bundle := jwtbundle.New(td)
+			if err := bundle.AddJWTAuthority(kid, m.PublicKey()); err != nil {
+				t.Fatalf("AddJWTAuthority: %v", err)
+			}
+			svid, err := jwtsvid.ParseAndValidate(res.Token, bundle, []string{target})
+			if err != nil {
+				t.Fatalf("jwtsvid.ParseAndValidate: %v", err)
+			}
+			if svid.ID.String() != subject {
+				t.Errorf("SVID ID = %q, want %q", svid.ID, subject)
+			}
+			if svid.Claims["jti"] != res.TokenID {
+				t.Errorf("jti = %v, want %q", svid.Claims["jti"], res.TokenID)
+			}
+		})
+	}
+}
+
+func TestSVIDCompatible(t *testing.T) {
+	for _, alg := range Algorithms {
+		m, err := NewMinterWithAlgorithm(alg)
+		if err != nil {
+			t.Fatalf("NewMinterWithAlgorithm(%s): %v", alg, err)
+		}
+		if got, want := SVIDCompatible(m.PublicKey()), alg != EdDSA; got != want {
+			t.Errorf("SVIDCompatible(%s key) = %v, want %v", alg, got, want)
+		}
+	}
+}
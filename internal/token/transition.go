@@ -0,0 +1,111 @@
+package token
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+)
+
+// Key roles reported by KeyRole.
+const (
+	KeyCurrent  = "current"
+	KeyPrevious = "previous"
+	KeyNext     = "next"
+)
+
+// SetNextSigner schedules a move to another signing key, for example from an
+// in-process key to a KMS-backed one. Until cutover m keeps signing with its
+// current key, but PublicKeys already includes next, so verifiers that
+// refresh their JWKS in the meantime accept tokens from either key. From
+// cutover on m signs with next and keeps the outgoing key as previous. A
+// cutover already passed takes effect on the next mint.
+func (m *Minter) SetNextSigner(next AlgorithmSigner, cutover time.Time) {
+	m.mu.Lock()
+	m.next, m.cutover = next, cutover
+	m.mu.Unlock()
+}
+
+// cutOver promotes the next signer to current once its cutover has passed.
+func (m *Minter) cutOver() {
+	m.mu.RLock()
+	due := m.next != nil && !m.now().Before(m.cutover)
+	m.mu.RUnlock()
+	if !due {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.next != nil && !m.now().Before(m.cutover) {
+		m.previous, m.current, m.next = m.current, m.next, nil
+		m.header = ""
+	}
+}
+
+// now returns the time from m's clock.
+func (m *Minter) now() time.Time {
+	if m.clock != nil {
+		return m.clock.Now()
+	}
+	return clock.System{}.Now()
+}
+
+// KeyRole reports which of m's keys has key ID kid: KeyCurrent, KeyPrevious,
+// or KeyNext. It returns "" for a key m does not hold.
+func (m *Minter) KeyRole(kid string) string {
+	m.cutOver()
+	m.mu.RLock()
+	roles := []struct {
+		role string
+		s    AlgorithmSigner
+	}{{KeyCurrent, m.current}, {KeyPrevious, m.previous}, {KeyNext, m.next}}
+	m.mu.RUnlock()
+	for _, r := range roles {
+		if r.s == nil {
+			continue
+		}
+		if id, err := KeyID(r.s.Public()); err == nil && id == kid {
+			return r.role
+		}
+	}
+	return ""
+}
+
+// ParseSigningKey returns a signer for a PKCS #8 PEM private key.
+func ParseSigningKey(data []byte) (AlgorithmSigner, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PKCS #8 PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	return NewAlgorithmSigner(key)
+}
+
+// HeaderKeyID returns the kid header of the JWT raw, or "" when it has none.
+// The header is not verified; call it only on a token that has been.
+func HeaderKeyID(raw string) string {
+	seg, _, ok := strings.Cut(raw, ".")
+	if !ok {
+		return ""
+	}
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ""
+	}
+	var h struct {
+		Kid string `json:"kid"`
+	}
+	if json.Unmarshal(data, &h) != nil {
+		return ""
+	}
+	return h.Kid
+}
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/clock"
+)
+
+func TestSetNextSigner(t *testing.T) {
+	// Half a minute in the past and a cutover ten seconds later, so tokens
+	// minted on either side verify against the real clock.
+	start := time.Now().Add(-30 * time.Second)
+	m, err := NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	c := clock.NewFake(start)
+	m.SetClock(c)
+	oldKid, _ := KeyID(m.PublicKey())
+	next, err := newSigner(ES384)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+	nextKid, _ := KeyID(next.Public())
+	m.SetNextSigner(next, start.Add(10*time.Second))
+
+	mintKid := func() string {
+		t.Helper()
+		res, err := m.Mint(context.Background(), "spiffe://td/a", "spiffe://td/b", []string{"read"}, 60, "")
+		if err != nil {
+			t.Fatalf("Mint: %v", err)
+		}
+		if _, err := VerifyClaims(res.Token, m.PublicKeys(), "spiffe://td/b"); err != nil {
+			t.Errorf("token does not verify: %v", err)
+		}
+		return HeaderKeyID(res.Token)
+	}
+
+	if kid := mintKid(); kid != oldKid {
+		t.Errorf("before cutover signed with %q, want the current key %q", kid, oldKid)
+	}
+	if n := len(m.PublicKeys()); n != 2 {
+		t.Errorf("before cutover PublicKeys has %d keys, want 2", n)
+	}
+	if role := m.KeyRole(nextKid); role != KeyNext {
+		t.Errorf("KeyRole(next) = %q, want %q", role, KeyNext)
+	}
+
+	c.Advance(10 * time.Second)
+	if kid := mintKid(); kid != nextKid {
+		t.Errorf("after cutover signed with %q, want the next key %q", kid, nextKid)
+	}
+	if m.Algorithm() != ES384 {
+		t.Errorf("Algorithm = %s after cutover, want ES384", m.Algorithm())
+	}
+	if role := m.KeyRole(oldKid); role != KeyPrevious {
+		t.Errorf("KeyRole(old) = %q, want %q", role, KeyPrevious)
+	}
+	if role := m.KeyRole(nextKid); role != KeyCurrent {
+		t.Errorf("KeyRole(next) = %q, want %q", role, KeyCurrent)
+	}
+	if role := m.KeyRole("unknown"); role != "" {
+		t.Errorf("KeyRole(unknown) = %q, want empty", role)
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	key, err := GenerateKey(EdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParseSigningKey: %v", err)
+	}
+	if s.Algorithm() != EdDSA {
+		t.Errorf("Algorithm = %s, want EdDSA", s.Algorithm())
+	}
+	if _, err := ParseSigningKey([]byte("not a key")); err == nil {
+		t.Error("ParseSigningKey accepted garbage")
+	}
+}
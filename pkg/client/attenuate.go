@@ -0,0 +1,36 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	svidtoken "github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// Attenuate narrows a macaroon-format token (token_format: macaroon) without
+// contacting svid-exchange, so it can be handed to a less trusted component.
+// Build caveats with ScopeCaveat, ExpiryCaveat, and AudienceCaveat. Caveats
+// can only restrict the token; the result is still verified against the
+// original root key.
+func Attenuate(token string, caveats ...string) (string, error) {
+	return svidtoken.AttenuateMacaroon(token, caveats...)
+}
+
+// ScopeCaveat restricts a macaroon to the given scopes. The token keeps only
+// scopes that appear in every scope caveat.
+func ScopeCaveat(scopes ...string) string {
+	return svidtoken.CaveatScope + " = " + strings.Join(scopes, " ")
+}
+
+// ExpiryCaveat makes a macaroon expire at t, or at its original expiry if
+// that is earlier.
+func ExpiryCaveat(t time.Time) string {
+	return svidtoken.CaveatExpiry + " = " + strconv.FormatInt(t.Unix(), 10)
+}
+
+// AudienceCaveat pins a macaroon to audience. It is only useful as a guard:
+// a macaroon whose original audience differs becomes unusable.
+func AudienceCaveat(audience string) string {
+	return svidtoken.CaveatAudience + " = " + audience
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	svidtoken "github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestAttenuate(t *testing.T) {
+	const target = "spiffe://cluster.local/ns/default/sa/payment"
+	rootKey := bytes.Repeat([]byte{1}, svidtoken.MinMacaroonKeySize)
+	m, err := svidtoken.NewMacaroonMinter(rootKey)
+	if err != nil {
+		t.Fatalf("NewMacaroonMinter: %v", err)
+	}
+	res, err := m.Mint(context.Background(), "spiffe://cluster.local/ns/default/sa/order", target, []string{"read", "write"}, 300, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	expiry := time.Now().Add(30 * time.Second)
+
+	tests := []struct {
+		name      string
+		caveats   []string
+		wantErr   bool
+		wantScope string
+	}{
+		{name: "no caveats", wantScope: "read write"},
+		{name: "scope", caveats: []string{ScopeCaveat("read")}, wantScope: "read"},
+		{name: "scope and expiry", caveats: []string{ScopeCaveat("write"), ExpiryCaveat(expiry)}, wantScope: "write"},
+		{name: "matching audience", caveats: []string{AudienceCaveat(target)}, wantScope: "read write"},
+		{name: "other audience", caveats: []string{AudienceCaveat("spiffe://cluster.local/ns/default/sa/other")}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tok, err := Attenuate(res.Token, tc.caveats...)
+			if err != nil {
+				t.Fatalf("Attenuate: %v", err)
+			}
+			claims, err := svidtoken.VerifyMacaroon(tok, rootKey, target)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("VerifyMacaroon() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if claims["scope"] != tc.wantScope {
+				t.Errorf("scope = %v, want %q", claims["scope"], tc.wantScope)
+			}
+		})
+	}
+}
+
+func TestAttenuateRejectsJWT(t *testing.T) {
+	if _, err := Attenuate("eyJhbGciOiJFUzI1NiJ9.e30.c2ln", ScopeCaveat("read")); err == nil {
+		t.Error("Attenuate accepted a JWT")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
 	"github.com/spiffe/go-spiffe/v2/workloadapi"
 	"google.golang.org/grpc"
@@ -35,6 +36,11 @@ type Options struct {
 	// (e.g. "unix:///tmp/agent.sock"). When empty the value of the
 	// SPIFFE_ENDPOINT_SOCKET environment variable is used instead.
 	SpiffeSocket string
+	// SPIFFEID selects which X509-SVID [New] presents when the Workload API
+	// issues this workload more than one (e.g.
+	// "spiffe://cluster.local/ns/default/sa/checkout"). When empty the
+	// agent's default, the first SVID, is used.
+	SPIFFEID string
 	// TargetService is the SPIFFE ID of the service this client calls.
 	TargetService string
 	// Scopes are the permission scopes to request.
@@ -45,6 +51,17 @@ type Options struct {
 	// acting for. When set, the resulting token carries an act.sub claim
 	// (RFC 8693).
 	OnBehalfOf string
+	// Proxy is the URL of an egress proxy to reach Addr through, for hosts
+	// with no direct route to svid-exchange: http:// or https:// for an HTTP
+	// CONNECT proxy, socks5:// or socks5h:// for SOCKS5, with optional
+	// user:password credentials. Addr is then resolved by the proxy. When
+	// empty, gRPC's default applies: an HTTP CONNECT proxy named by the
+	// HTTPS_PROXY environment variable, if set.
+	Proxy string
+	// Retry, when set, retries or hedges exchanges that fail with
+	// Unavailable or DeadlineExceeded. When nil each exchange is attempted
+	// once.
+	Retry *RetryPolicy
 }
 
 // Client fetches scoped JWTs from svid-exchange and caches them until close to
@@ -54,7 +71,10 @@ type Client struct {
 	conn        *grpc.ClientConn        // non-nil only when created by New
 	src         *workloadapi.X509Source // non-nil only when created by New
 	opts        Options
-	stopRefresh func() // non-nil only when created by New; called in Close
+	stopRefresh func() // non-nil only when created by New or NewFromConn; called in Close
+
+	// sleep waits between retries; nil means a real timer. Tests override it.
+	sleep func(context.Context, time.Duration) error
 
 	cached struct {
 		mu        sync.Mutex
@@ -66,7 +86,9 @@ type Client struct {
 
 // New creates a Client that connects to svid-exchange using SPIFFE mTLS.
 // The Workload API socket is read from opts.SpiffeSocket, falling back to the
-// SPIFFE_ENDPOINT_SOCKET environment variable.
+// SPIFFE_ENDPOINT_SOCKET environment variable. New blocks until the agent has
+// issued an X509-SVID; the agent's rotations are then picked up by later
+// handshakes without any action from the caller.
 // Call [Client.Close] when done to release the underlying connection and X509Source.
 func New(ctx context.Context, opts Options) (*Client, error) {
 	socket := opts.SpiffeSocket
@@ -77,10 +99,31 @@ func New(ctx context.Context, opts Options) (*Client, error) {
 		return nil, fmt.Errorf("client: SpiffeSocket or SPIFFE_ENDPOINT_SOCKET must be set")
 	}
 
-	src, err := workloadapi.NewX509Source(
-		ctx,
+	var dialOpts []grpc.DialOption
+	target := opts.Addr
+	if opts.Proxy != "" {
+		dial, err := proxyDialer(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+		// passthrough hands Addr to the dialer unresolved, so that a name
+		// only the proxy can resolve still works.
+		dialOpts = append(dialOpts, grpc.WithContextDialer(dial))
+		target = "passthrough:///" + opts.Addr
+	}
+
+	srcOpts := []workloadapi.X509SourceOption{
 		workloadapi.WithClientOptions(workloadapi.WithAddr(socket)),
-	)
+	}
+	if opts.SPIFFEID != "" {
+		id, err := spiffeid.FromString(opts.SPIFFEID)
+		if err != nil {
+			return nil, fmt.Errorf("client: invalid SPIFFEID %q: %w", opts.SPIFFEID, err)
+		}
+		srcOpts = append(srcOpts, workloadapi.WithDefaultX509SVIDPicker(svidPicker(id)))
+	}
+
+	src, err := workloadapi.NewX509Source(ctx, srcOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("client: new X509Source: %w", err)
 	}
@@ -88,7 +131,8 @@ func New(ctx context.Context, opts Options) (*Client, error) {
 	tlsCfg := tlsconfig.MTLSClientConfig(src, src, tlsconfig.AuthorizeAny())
 	tlsCfg.MinVersion = tls.VersionTLS13
 
-	conn, err := grpc.NewClient(opts.Addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	conn, err := grpc.NewClient(target, dialOpts...)
 	if err != nil {
 		if e := src.Close(); e != nil {
 			return nil, fmt.Errorf("client: dial %q: %w; close source: %v", opts.Addr, err, e)
@@ -108,6 +152,21 @@ func New(ctx context.Context, opts Options) (*Client, error) {
 	return c, nil
 }
 
+// NewFromConn creates a Client that exchanges over conn, an established
+// connection to svid-exchange whose transport credentials identify the
+// caller. opts.Addr and opts.SpiffeSocket are ignored. [Client.Close] stops
+// background refresh but does not close conn.
+func NewFromConn(conn grpc.ClientConnInterface, opts Options) *Client {
+	stopCtx, stopCancel := context.WithCancel(context.Background())
+	c := &Client{
+		exc:         exchangev1.NewTokenExchangeClient(conn),
+		opts:        opts,
+		stopRefresh: stopCancel,
+	}
+	go c.refreshLoop(stopCtx)
+	return c
+}
+
 // Token returns a valid JWT for the configured target and scopes. Cached tokens
 // are returned immediately; a new exchange call is made when the token is within
 // 20% of its TTL (i.e. refresh triggers at 80% consumed). Concurrent callers
@@ -121,7 +180,7 @@ func (c *Client) Token(ctx context.Context) (string, error) {
 	}
 
 	mintTime := time.Now()
-	resp, err := c.exc.Exchange(ctx, &exchangev1.ExchangeRequest{
+	resp, err := c.exchange(ctx, &exchangev1.ExchangeRequest{
 		TargetService: c.opts.TargetService,
 		Scopes:        c.opts.Scopes,
 		TtlSeconds:    c.opts.TTLSeconds,
@@ -257,3 +257,38 @@ func TestClientTokenConcurrent(t *testing.T) {
 		t.Errorf("%d goroutines got empty tokens", empty.Load())
 	}
 }
+
+// fakeConn answers every Exchange invocation with a fixed token and records
+// the request.
+type fakeConn struct {
+	grpc.ClientConnInterface
+	method string
+	req    *exchangev1.ExchangeRequest
+}
+
+func (f *fakeConn) Invoke(_ context.Context, method string, args, reply any, _ ...grpc.CallOption) error {
+	f.method = method
+	f.req = args.(*exchangev1.ExchangeRequest)
+	resp := reply.(*exchangev1.ExchangeResponse)
+	resp.Token = "conn-token"
+	resp.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	return nil
+}
+
+func TestNewFromConn(t *testing.T) {
+	conn := &fakeConn{}
+	c := NewFromConn(conn, Options{TargetService: "spiffe://test.local/payment", Scopes: []string{"read"}, TTLSeconds: 60})
+	defer func() {
+		if err := c.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}()
+
+	tok, err := c.Token(context.Background())
+	if err != nil || tok != "conn-token" {
+		t.Fatalf("Token = %q, %v", tok, err)
+	}
+	if conn.method != exchangev1.TokenExchange_Exchange_FullMethodName || conn.req.TargetService != "spiffe://test.local/payment" || conn.req.TtlSeconds != 60 {
+		t.Errorf("invoked %s with %v", conn.method, conn.req)
+	}
+}
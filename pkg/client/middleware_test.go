@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
@@ -20,7 +21,7 @@ func TestMiddleware(t *testing.T) {
 	}
 
 	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		pub := minter.PublicKey()
+		pub := minter.PublicKey().(*ecdsa.PublicKey)
 		raw, err := pub.Bytes()
 		if err != nil {
 			http.Error(w, "key encode error", http.StatusInternalServerError)
@@ -57,7 +58,7 @@ func TestMiddleware(t *testing.T) {
 	}
 
 	validToken := func() string {
-		r, err := minter.Mint(subject, audience, []string{"read"}, 60, "")
+		r, err := minter.Mint(context.Background(), subject, audience, []string{"read"}, 60, "")
 		if err != nil {
 			t.Fatalf("Mint: %v", err)
 		}
@@ -98,7 +99,7 @@ func TestMiddleware(t *testing.T) {
 		{
 			name: "wrong audience rejected",
 			authHeader: func() string {
-				r, err := minter.Mint(subject, "spiffe://test.local/other", []string{"read"}, 60, "")
+				r, err := minter.Mint(context.Background(), subject, "spiffe://test.local/other", []string{"read"}, 60, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
@@ -126,7 +127,7 @@ func TestMiddleware(t *testing.T) {
 		{
 			name: "expired token rejected",
 			authHeader: func() string {
-				r, err := minter.Mint(subject, audience, []string{"read"}, 1, "")
+				r, err := minter.Mint(context.Background(), subject, audience, []string{"read"}, 1, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
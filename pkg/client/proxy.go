@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// proxyDialer returns a gRPC context dialer that reaches its address through
+// the egress proxy at rawURL: an HTTP CONNECT proxy for http:// and https://
+// URLs, or a SOCKS5 proxy for socks5:// and socks5h:// URLs. Credentials in
+// the URL are sent as Basic proxy authorization or SOCKS5
+// username/password. The TLS session with svid-exchange runs inside the
+// tunnel, so the proxy sees neither the SVID exchange nor the token.
+func proxyDialer(rawURL string) (func(context.Context, string) (net.Conn, error), error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// A *url.Error quotes the URL, credentials and all.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("parse proxy URL: %w", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", u.Redacted())
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialConnect(ctx, u, addr)
+		}, nil
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(u, &net.Dialer{})
+		if err != nil {
+			return nil, fmt.Errorf("proxy URL %q: %w", u.Redacted(), err)
+		}
+		cd, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("proxy URL %q: dialer does not support contexts", u.Redacted())
+		}
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			return cd.DialContext(ctx, "tcp", addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("proxy URL %q: unsupported scheme %q (must be http, https, socks5, or socks5h)", u.Redacted(), u.Scheme)
+	}
+}
+
+// dialConnect opens a tunnel to addr through the HTTP CONNECT proxy at
+// proxyURL, speaking TLS to the proxy itself when its scheme is https.
+func dialConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy %s: %w", proxyAddr, err)
+	}
+	// Abandon the handshake when ctx is done; the deadline is cleared once
+	// the tunnel is up.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if proxyURL.Scheme == "https" {
+		tc := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy %s TLS handshake: %w", proxyAddr, err)
+		}
+		conn = tc
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u := proxyURL.User; u != nil {
+		pw, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pw)))
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: %w", proxyAddr, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT: %w", proxyAddr, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s CONNECT %s: %s", proxyAddr, addr, resp.Status)
+	}
+	if !stop() {
+		_ = conn.Close()
+		return nil, ctx.Err()
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose first reads drain bytes the proxy sent
+// after its CONNECT response.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
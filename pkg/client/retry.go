@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+// pushbackTrailer is the gRPC trailer through which svid-exchange asks
+// clients to delay (a non-negative millisecond count) or abandon (a negative
+// value) their retries, as in gRFC A6.
+const pushbackTrailer = "grpc-retry-pushback-ms"
+
+// Defaults applied to zero [RetryPolicy] fields.
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultMultiplier     = 2.0
+)
+
+// RetryPolicy configures how [Client.Token] retries a failed exchange. Only
+// Unavailable and DeadlineExceeded are retried: the server either never saw
+// the request or its answer was lost, and a repeated exchange mints a fresh
+// token rather than replaying a side effect. Zero fields take defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the delay before the first retry.
+	// Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay bound as it grows. Defaults to 5s.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay bound after each retry. Defaults to 2.
+	Multiplier float64
+	// HedgeDelay, when positive, switches from sequential retries to hedged
+	// requests: a further attempt is sent whenever HedgeDelay passes without
+	// an answer, or at once when an attempt fails with a retryable code, up
+	// to MaxAttempts in total. The first success wins and the others are
+	// cancelled.
+	HedgeDelay time.Duration
+}
+
+func (p *RetryPolicy) initialBackoff() time.Duration {
+	if p.InitialBackoff > 0 {
+		return p.InitialBackoff
+	}
+	return defaultInitialBackoff
+}
+
+// nextBackoff returns the delay bound that follows cur.
+func (p *RetryPolicy) nextBackoff(cur time.Duration) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = defaultMultiplier
+	}
+	ceiling := p.MaxBackoff
+	if ceiling <= 0 {
+		ceiling = defaultMaxBackoff
+	}
+	next := time.Duration(float64(cur) * mult)
+	if next > ceiling || next <= 0 {
+		return ceiling
+	}
+	return next
+}
+
+// jitter returns a uniformly random delay in [0, bound), so that clients
+// failed by the same outage do not retry in lockstep.
+func jitter(bound time.Duration) time.Duration {
+	if bound <= 0 {
+		return 0
+	}
+	return rand.N(bound)
+}
+
+// retryable reports whether err carries a code that is safe to retry.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// pushback reads the server's retry pushback from trailer. ok is false when
+// the server asked for no further retries, or sent a value that cannot be
+// parsed; wait is negative when the trailer is absent.
+func pushback(trailer metadata.MD) (wait time.Duration, ok bool) {
+	vals := trailer.Get(pushbackTrailer)
+	if len(vals) == 0 {
+		return -1, true
+	}
+	ms, err := strconv.ParseInt(vals[0], 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// exchange performs the Exchange RPC under the configured retry policy.
+func (c *Client) exchange(ctx context.Context, req *exchangev1.ExchangeRequest) (*exchangev1.ExchangeResponse, error) {
+	p := c.opts.Retry
+	if p == nil || p.MaxAttempts < 2 {
+		return c.exc.Exchange(ctx, req)
+	}
+	if p.HedgeDelay > 0 {
+		return c.hedge(ctx, req, p)
+	}
+
+	backoff := p.initialBackoff()
+	for attempt := 1; ; attempt++ {
+		var trailer metadata.MD
+		resp, err := c.exc.Exchange(ctx, req, grpc.Trailer(&trailer))
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= p.MaxAttempts || !retryable(err) {
+			return nil, err
+		}
+		wait, ok := pushback(trailer)
+		if !ok {
+			return nil, err
+		}
+		if wait >= 0 {
+			// An explicit pushback restarts the backoff sequence.
+			backoff = p.initialBackoff()
+		} else {
+			wait = jitter(backoff)
+			backoff = p.nextBackoff(backoff)
+		}
+		if c.sleepCtx(ctx, wait) != nil {
+			return nil, err
+		}
+	}
+}
+
+// hedgeResult is the outcome of one hedged attempt.
+type hedgeResult struct {
+	resp    *exchangev1.ExchangeResponse
+	err     error
+	trailer metadata.MD
+}
+
+// hedge sends up to p.MaxAttempts concurrent attempts, spaced p.HedgeDelay
+// apart, and returns the first success. A non-retryable failure ends the
+// exchange; a retryable one brings the next attempt forward, subject to the
+// server's pushback.
+func (c *Client) hedge(ctx context.Context, req *exchangev1.ExchangeRequest, p *RetryPolicy) (*exchangev1.ExchangeResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so that attempts still in flight when hedge returns do not
+	// block once cancelled.
+	results := make(chan hedgeResult, p.MaxAttempts)
+	launch := func() {
+		go func() {
+			var r hedgeResult
+			r.resp, r.err = c.exc.Exchange(ctx, req, grpc.Trailer(&r.trailer))
+			results <- r
+		}()
+	}
+
+	launch()
+	sent, pending := 1, 1
+	timer := time.NewTimer(p.HedgeDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		var next <-chan time.Time
+		if sent < p.MaxAttempts {
+			next = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(ctx.Err(), lastErr)
+		case <-next:
+			launch()
+			sent++
+			pending++
+			timer.Reset(p.HedgeDelay)
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.resp, nil
+			}
+			lastErr = r.err
+			if !retryable(r.err) {
+				return nil, r.err
+			}
+			if wait, ok := pushback(r.trailer); !ok {
+				sent = p.MaxAttempts
+			} else {
+				timer.Reset(max(wait, 0))
+			}
+			if pending == 0 && sent >= p.MaxAttempts {
+				return nil, lastErr
+			}
+		}
+	}
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first.
+func (c *Client) sleepCtx(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
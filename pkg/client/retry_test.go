@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+)
+
+// attempt scripts one Exchange call of a scriptedExchanger.
+type attempt struct {
+	err      error
+	pushback string        // grpc-retry-pushback-ms trailer value; "" → none
+	delay    time.Duration // how long the call takes; cut short by cancellation
+}
+
+// scriptedExchanger replays attempts in order, then succeeds.
+type scriptedExchanger struct {
+	mu       sync.Mutex
+	attempts []attempt
+	ttl      time.Duration // lifetime of issued tokens; 0 → one minute
+	calls    int
+}
+
+func (s *scriptedExchanger) Exchange(ctx context.Context, _ *exchangev1.ExchangeRequest, opts ...grpc.CallOption) (*exchangev1.ExchangeResponse, error) {
+	s.mu.Lock()
+	var a attempt
+	if s.calls < len(s.attempts) {
+		a = s.attempts[s.calls]
+	}
+	s.calls++
+	n := s.calls
+	s.mu.Unlock()
+
+	if a.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(a.delay):
+		}
+	}
+	if a.pushback != "" {
+		for _, o := range opts {
+			if t, ok := o.(grpc.TrailerCallOption); ok {
+				*t.TrailerAddr = metadata.Pairs(pushbackTrailer, a.pushback)
+			}
+		}
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	ttl := s.ttl
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+	return &exchangev1.ExchangeResponse{
+		Token:     fmt.Sprintf("tok-%d", n),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+func (s *scriptedExchanger) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// recordSleeps returns a sleep hook that records each wait without blocking.
+func recordSleeps(waits *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+}
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "unavailable")
+	errDeadline    = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	errDenied      = status.Error(codes.PermissionDenied, "denied")
+)
+
+func TestRetry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		policy    *RetryPolicy
+		attempts  []attempt
+		wantCalls int
+		wantCode  codes.Code
+		checkWait func(t *testing.T, waits []time.Duration)
+	}{
+		{
+			name:      "nil policy makes a single attempt",
+			attempts:  []attempt{{err: errUnavailable}},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "unavailable and deadline exceeded are retried",
+			policy:    policy,
+			attempts:  []attempt{{err: errUnavailable}, {err: errDeadline}},
+			wantCalls: 3,
+			wantCode:  codes.OK,
+			checkWait: func(t *testing.T, waits []time.Duration) {
+				if len(waits) != 2 {
+					t.Fatalf("waits = %v, want 2", waits)
+				}
+				if waits[0] < 0 || waits[0] >= 100*time.Millisecond {
+					t.Errorf("first wait %v outside [0, 100ms)", waits[0])
+				}
+				if waits[1] < 0 || waits[1] >= 150*time.Millisecond {
+					t.Errorf("second wait %v outside [0, 150ms)", waits[1])
+				}
+			},
+		},
+		{
+			name:      "other codes are not retried",
+			policy:    policy,
+			attempts:  []attempt{{err: errDenied}},
+			wantCalls: 1,
+			wantCode:  codes.PermissionDenied,
+		},
+		{
+			name:   "attempts are bounded by MaxAttempts",
+			policy: policy,
+			attempts: []attempt{
+				{err: errUnavailable}, {err: errUnavailable}, {err: errUnavailable}, {err: errUnavailable}, {},
+			},
+			wantCalls: 4,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "server pushback sets the wait",
+			policy:    policy,
+			attempts:  []attempt{{err: errUnavailable, pushback: "2500"}},
+			wantCalls: 2,
+			wantCode:  codes.OK,
+			checkWait: func(t *testing.T, waits []time.Duration) {
+				if len(waits) != 1 || waits[0] != 2500*time.Millisecond {
+					t.Errorf("waits = %v, want [2.5s]", waits)
+				}
+			},
+		},
+		{
+			name:      "negative pushback stops retries",
+			policy:    policy,
+			attempts:  []attempt{{err: errUnavailable, pushback: "-1"}},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "malformed pushback stops retries",
+			policy:    policy,
+			attempts:  []attempt{{err: errUnavailable, pushback: "soon"}},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exc := &scriptedExchanger{attempts: tc.attempts}
+			var waits []time.Duration
+			c := newWithOpts(exc, Options{TargetService: "spiffe://test.local/payment", Retry: tc.policy})
+			c.sleep = recordSleeps(&waits)
+
+			_, err := c.Token(context.Background())
+			if got := status.Code(errors.Unwrap(err)); got != tc.wantCode {
+				t.Errorf("code = %v, want %v (err %v)", got, tc.wantCode, err)
+			}
+			if got := exc.callCount(); got != tc.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tc.wantCalls)
+			}
+			if tc.checkWait != nil {
+				tc.checkWait(t, waits)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	exc := &scriptedExchanger{attempts: []attempt{{err: errUnavailable}, {err: errUnavailable}}}
+	c := newWithOpts(exc, Options{Retry: &RetryPolicy{MaxAttempts: 5}})
+	ctx, cancel := context.WithCancel(context.Background())
+	c.sleep = func(ctx context.Context, _ time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	if _, err := c.Token(ctx); status.Code(errors.Unwrap(err)) != codes.Unavailable {
+		t.Errorf("err = %v, want the last Unavailable", err)
+	}
+	if got := exc.callCount(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		cur    time.Duration
+		want   time.Duration
+	}{
+		{name: "defaults double", cur: time.Second, want: 2 * time.Second},
+		{name: "defaults cap at 5s", cur: 4 * time.Second, want: 5 * time.Second},
+		{name: "custom multiplier", policy: RetryPolicy{Multiplier: 1.5}, cur: time.Second, want: 1500 * time.Millisecond},
+		{name: "custom cap", policy: RetryPolicy{MaxBackoff: time.Second}, cur: 800 * time.Millisecond, want: time.Second},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.nextBackoff(tc.cur); got != tc.want {
+				t.Errorf("nextBackoff(%v) = %v, want %v", tc.cur, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHedge(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *RetryPolicy
+		attempts  []attempt
+		wantToken string
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		{
+			name:      "slow first attempt is overtaken by the hedge",
+			policy:    &RetryPolicy{MaxAttempts: 2, HedgeDelay: 10 * time.Millisecond},
+			attempts:  []attempt{{delay: 5 * time.Second}},
+			wantToken: "tok-2",
+			wantCalls: 2,
+		},
+		{
+			name:      "fast first attempt sends no hedge",
+			policy:    &RetryPolicy{MaxAttempts: 3, HedgeDelay: 5 * time.Second},
+			wantToken: "tok-1",
+			wantCalls: 1,
+		},
+		{
+			name:      "retryable failure brings the next attempt forward",
+			policy:    &RetryPolicy{MaxAttempts: 2, HedgeDelay: 5 * time.Second},
+			attempts:  []attempt{{err: errUnavailable}},
+			wantToken: "tok-2",
+			wantCalls: 2,
+		},
+		{
+			name:      "non-retryable failure ends the exchange",
+			policy:    &RetryPolicy{MaxAttempts: 3, HedgeDelay: 5 * time.Second},
+			attempts:  []attempt{{err: errDenied}},
+			wantCode:  codes.PermissionDenied,
+			wantCalls: 1,
+		},
+		{
+			name:      "negative pushback stops further hedges",
+			policy:    &RetryPolicy{MaxAttempts: 3, HedgeDelay: 5 * time.Second},
+			attempts:  []attempt{{err: errUnavailable, pushback: "-1"}},
+			wantCode:  codes.Unavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "all attempts failing returns the last error",
+			policy:    &RetryPolicy{MaxAttempts: 2, HedgeDelay: 5 * time.Second},
+			attempts:  []attempt{{err: errUnavailable}, {err: errDeadline}},
+			wantCode:  codes.DeadlineExceeded,
+			wantCalls: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exc := &scriptedExchanger{attempts: tc.attempts}
+			c := newWithOpts(exc, Options{Retry: tc.policy})
+
+			tok, err := c.Token(context.Background())
+			if tc.wantCode != codes.OK {
+				if got := status.Code(errors.Unwrap(err)); got != tc.wantCode {
+					t.Errorf("code = %v, want %v (err %v)", got, tc.wantCode, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Token: %v", err)
+			} else if tok != tc.wantToken {
+				t.Errorf("token = %q, want %q", tok, tc.wantToken)
+			}
+			if got := exc.callCount(); got != tc.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
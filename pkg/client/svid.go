@@ -0,0 +1,21 @@
+package client
+
+import (
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// svidPicker returns a picker that selects the X509-SVID for id from those
+// the Workload API issued to this workload. It is re-run on every rotation,
+// so the client keeps presenting the same identity. When id is absent the
+// source holds no SVID and handshakes fail until the agent issues one.
+func svidPicker(id spiffeid.ID) func([]*x509svid.SVID) *x509svid.SVID {
+	return func(svids []*x509svid.SVID) *x509svid.SVID {
+		for _, s := range svids {
+			if s.ID == id {
+				return s
+			}
+		}
+		return nil
+	}
+}
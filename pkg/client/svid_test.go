@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+func TestSVIDPicker(t *testing.T) {
+	checkout := spiffeid.RequireFromString("spiffe://test.local/checkout")
+	billing := spiffeid.RequireFromString("spiffe://test.local/billing")
+	svids := []*x509svid.SVID{{ID: billing}, {ID: checkout}}
+
+	tests := []struct {
+		name  string
+		id    spiffeid.ID
+		svids []*x509svid.SVID
+		want  *x509svid.SVID
+	}{
+		{name: "first SVID selected", id: billing, svids: svids, want: svids[0]},
+		{name: "later SVID selected", id: checkout, svids: svids, want: svids[1]},
+		{name: "absent ID selects nothing", id: spiffeid.RequireFromString("spiffe://test.local/other"), svids: svids},
+		{name: "no SVIDs", id: checkout},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := svidPicker(tc.id)(tc.svids); got != tc.want {
+				t.Errorf("picked %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidSPIFFEID(t *testing.T) {
+	_, err := New(context.Background(), Options{
+		Addr:         "svid-exchange:8080",
+		SpiffeSocket: "unix:///nonexistent/agent.sock",
+		SPIFFEID:     "checkout",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid SPIFFEID") {
+		t.Errorf("New error = %v, want an invalid SPIFFEID", err)
+	}
+}
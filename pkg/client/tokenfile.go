@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tokenFileRetry is how long WriteTokenFile waits after a failed exchange,
+// matching the background refresh loop.
+const tokenFileRetry = 5 * time.Second
+
+// minTokenFileInterval bounds how often WriteTokenFile exchanges, so that a
+// token returned already inside its refresh window cannot cause a hot loop.
+const minTokenFileInterval = time.Second
+
+// WriteTokenFile keeps the file at path holding a current token for the
+// configured target and scopes, so that an application which cannot use this
+// package — or run a sidecar — reads its token from disk instead, e.g. from
+// an emptyDir volume shared within a Kubernetes pod. Each token is written
+// with mode 0600 to a temporary file in the same directory and renamed over
+// path, so readers never observe a partial token. The file is replaced at
+// the same point [Client.Token] would refresh, well before expiry.
+//
+// WriteTokenFile blocks until ctx is cancelled, then returns nil. A failed
+// exchange is retried after a short backoff, leaving the previous token in
+// place; a failure to write the file is returned.
+func (c *Client) WriteTokenFile(ctx context.Context, path string) error {
+	var written string
+	for {
+		wait := tokenFileRetry
+		tok, err := c.Token(ctx)
+		if err == nil {
+			if tok != written {
+				if err := writeFileAtomic(path, []byte(tok)); err != nil {
+					return fmt.Errorf("client: write token file: %w", err)
+				}
+				written = tok
+			}
+			c.cached.mu.Lock()
+			wait = max(time.Until(c.cached.refreshAt), minTokenFileInterval)
+			c.cached.mu.Unlock()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// into place.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForFile polls path until its content is want or the deadline passes.
+func waitForFile(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, err := os.ReadFile(path); err == nil && string(got) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, err := os.ReadFile(path)
+	t.Fatalf("token file = %q (err %v), want %q", got, err, want)
+}
+
+func TestWriteTokenFile(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "token written with owner-only permissions",
+			run: func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "token")
+				c := newWithExchanger(&mockExchanger{}, "spiffe://test.local/payment", nil, 0)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				go func() { done <- c.WriteTokenFile(ctx, path) }()
+
+				waitForFile(t, path, "mock-token-1")
+				fi, err := os.Stat(path)
+				if err != nil {
+					t.Fatalf("Stat: %v", err)
+				}
+				if perm := fi.Mode().Perm(); perm != 0o600 {
+					t.Errorf("mode = %v, want 0600", perm)
+				}
+				cancel()
+				if err := <-done; err != nil {
+					t.Errorf("WriteTokenFile returned %v after cancel, want nil", err)
+				}
+				entries, err := os.ReadDir(filepath.Dir(path))
+				if err != nil {
+					t.Fatalf("ReadDir: %v", err)
+				}
+				if len(entries) != 1 {
+					t.Errorf("directory holds %d entries, want only the token file", len(entries))
+				}
+			},
+		},
+		{
+			name: "token replaced when it reaches its refresh point",
+			run: func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "token")
+				c := newWithExchanger(&scriptedExchanger{ttl: 2 * time.Second}, "spiffe://test.local/payment", nil, 0)
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				go func() { _ = c.WriteTokenFile(ctx, path) }()
+
+				waitForFile(t, path, "tok-1")
+				waitForFile(t, path, "tok-2")
+			},
+		},
+		{
+			name: "failed exchange leaves no file",
+			run: func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "token")
+				c := newWithExchanger(&mockExchanger{err: errUnavailable}, "spiffe://test.local/payment", nil, 0)
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				if err := c.WriteTokenFile(ctx, path); err != nil {
+					t.Errorf("WriteTokenFile = %v, want nil after cancel", err)
+				}
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("Stat = %v, want not exist", err)
+				}
+			},
+		},
+		{
+			name: "unwritable directory returned as error",
+			run: func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "missing", "token")
+				c := newWithExchanger(&mockExchanger{}, "spiffe://test.local/payment", nil, 0)
+				err := c.WriteTokenFile(context.Background(), path)
+				if err == nil || !strings.Contains(err.Error(), "write token file") {
+					t.Errorf("WriteTokenFile = %v, want a write error", err)
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, tc.run)
+	}
+}
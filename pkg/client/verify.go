@@ -2,9 +2,7 @@ package client
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"encoding/base64"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +11,9 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	svidtoken "github.com/ngaddam369/svid-exchange/internal/token"
 )
 
 // jwksHTTPClient is used for all JWKS fetches. The 10 s timeout bounds how long
@@ -28,7 +29,7 @@ const jwksBodyLimit = 1 << 20 // 1 MiB
 // Verify tries all cached keys so tokens signed by either remain valid.
 type Verifier struct {
 	mu      sync.RWMutex
-	keys    []*ecdsa.PublicKey
+	keys    []crypto.PublicKey
 	jwksURL string
 }
 
@@ -63,14 +64,14 @@ func (v *Verifier) Refresh(ctx context.Context) (err error) {
 		return fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
 	}
 
-	var doc jwksResponse
+	var doc jwk.Set
 	if err = json.NewDecoder(io.LimitReader(resp.Body, jwksBodyLimit)).Decode(&doc); err != nil {
 		return fmt.Errorf("decode JWKS: %w", err)
 	}
 
-	keys := make([]*ecdsa.PublicKey, 0, len(doc.Keys))
+	keys := make([]crypto.PublicKey, 0, len(doc.Keys))
 	for i, k := range doc.Keys {
-		pub, err := jwkToPublicKey(k)
+		pub, err := k.PublicKey()
 		if err != nil {
 			return fmt.Errorf("key %d: %w", i, err)
 		}
@@ -105,9 +106,11 @@ func (v *Verifier) StartAutoRefresh(ctx context.Context, interval time.Duration)
 	}()
 }
 
-// Verify validates token as an ES256 JWT issued by svid-exchange for audience.
-// It returns the parsed claims on success. An error is returned if the
-// signature, expiry, audience, or issuer check fails.
+// Verify validates token as a JWT issued by svid-exchange for audience.
+// ES256, ES384, RS256, and EdDSA are accepted; each cached key is only tried
+// with the algorithm matching its key type. It returns the parsed claims on
+// success. An error is returned if the signature, expiry, audience, or issuer
+// check fails.
 func (v *Verifier) Verify(token, audience string) (jwt.MapClaims, error) {
 	v.mu.RLock()
 	keys := v.keys
@@ -116,74 +119,13 @@ func (v *Verifier) Verify(token, audience string) (jwt.MapClaims, error) {
 	if len(keys) == 0 {
 		return nil, fmt.Errorf("verifier: no keys loaded")
 	}
-
-	var lastErr error
-	for _, pub := range keys {
-		tok, err := jwt.Parse(token,
-			func(t *jwt.Token) (any, error) {
-				if _, ok := t.Method.(*jwt.SigningMethodECDSA); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-				}
-				return pub, nil
-			},
-			jwt.WithValidMethods([]string{"ES256"}),
-			jwt.WithExpirationRequired(),
-			jwt.WithAudience(audience),
-			jwt.WithIssuer("svid-exchange"),
-		)
-		if err == nil {
-			claims, ok := tok.Claims.(jwt.MapClaims)
-			if !ok {
-				return nil, fmt.Errorf("verifier: unexpected claims type")
-			}
-			return claims, nil
-		}
-		lastErr = err
-	}
-	return nil, fmt.Errorf("verifier: %w", lastErr)
-}
-
-// jwksResponse is the wire format of the /jwks endpoint.
-type jwksResponse struct {
-	Keys []jwkKey `json:"keys"`
-}
-
-// jwkKey mirrors the fields emitted by pubToJWK in cmd/server/jwks.go.
-type jwkKey struct {
-	Kty string `json:"kty"`
-	Crv string `json:"crv"`
-	X   string `json:"x"`
-	Y   string `json:"y"`
-	Alg string `json:"alg"`
-	Kid string `json:"kid"`
-}
-
-// jwkToPublicKey decodes a JWK into an *ecdsa.PublicKey.
-// It is the inverse of pubToJWK in cmd/server/jwks.go.
-// The uncompressed point 0x04 || X || Y is reconstructed from the base64url
-// coordinates and parsed via ecdsa.ParseUncompressedPublicKey, which performs
-// on-curve validation using the modern crypto/ecdh API.
-func jwkToPublicKey(k jwkKey) (*ecdsa.PublicKey, error) {
-	if k.Kty != "EC" || k.Crv != "P-256" {
-		return nil, fmt.Errorf("unsupported key type %q / curve %q", k.Kty, k.Crv)
-	}
-	xBytes, err := base64.RawURLEncoding.DecodeString(k.X)
-	if err != nil {
-		return nil, fmt.Errorf("decode x: %w", err)
-	}
-	yBytes, err := base64.RawURLEncoding.DecodeString(k.Y)
-	if err != nil {
-		return nil, fmt.Errorf("decode y: %w", err)
+	if audience == "" {
+		return nil, fmt.Errorf("verifier: audience is required")
 	}
-	// Reconstruct the uncompressed point: 0x04 || X || Y
-	uncompressed := make([]byte, 1+len(xBytes)+len(yBytes))
-	uncompressed[0] = 0x04
-	copy(uncompressed[1:], xBytes)
-	copy(uncompressed[1+len(xBytes):], yBytes)
 
-	pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), uncompressed)
+	claims, err := svidtoken.VerifyClaims(token, keys, audience)
 	if err != nil {
-		return nil, fmt.Errorf("parse public key: %w", err)
+		return nil, fmt.Errorf("verifier: %w", err)
 	}
-	return pub, nil
+	return claims, nil
 }
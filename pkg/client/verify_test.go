@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
@@ -22,7 +23,7 @@ func TestVerifier(t *testing.T) {
 	// Serve a JWKS document built from the minter's current public key,
 	// using the same coordinate extraction as pubToJWK in cmd/server/jwks.go.
 	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		pub := minter.PublicKey()
+		pub := minter.PublicKey().(*ecdsa.PublicKey)
 		raw, err := pub.Bytes() // 0x04 || X || Y
 		if err != nil {
 			http.Error(w, "key encode error", http.StatusInternalServerError)
@@ -63,7 +64,7 @@ func TestVerifier(t *testing.T) {
 		{
 			name: "valid token verifies",
 			token: func() string {
-				r, err := minter.Mint("spiffe://test.local/order", audience, []string{"read"}, 60, "")
+				r, err := minter.Mint(context.Background(), "spiffe://test.local/order", audience, []string{"read"}, 60, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
@@ -73,7 +74,7 @@ func TestVerifier(t *testing.T) {
 		{
 			name: "wrong audience rejected",
 			token: func() string {
-				r, err := minter.Mint("spiffe://test.local/order", "spiffe://test.local/other", []string{"read"}, 60, "")
+				r, err := minter.Mint(context.Background(), "spiffe://test.local/order", "spiffe://test.local/other", []string{"read"}, 60, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
@@ -84,7 +85,7 @@ func TestVerifier(t *testing.T) {
 		{
 			name: "expired token rejected",
 			token: func() string {
-				r, err := minter.Mint("spiffe://test.local/order", audience, []string{"read"}, 1, "")
+				r, err := minter.Mint(context.Background(), "spiffe://test.local/order", audience, []string{"read"}, 1, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
@@ -114,7 +115,7 @@ func jwksHandlerFromMinter(t *testing.T, mu *sync.Mutex, current **token.Minter)
 		m := *current
 		mu.Unlock()
 
-		pub := m.PublicKey()
+		pub := m.PublicKey().(*ecdsa.PublicKey)
 		raw, err := pub.Bytes()
 		if err != nil {
 			http.Error(w, "key encode error", http.StatusInternalServerError)
@@ -175,7 +176,7 @@ func TestStartAutoRefresh(t *testing.T) {
 				mu.Unlock()
 
 				// Token signed by rotated key fails before auto-refresh.
-				tok2, err := minter2.Mint("spiffe://test.local/order", audience, []string{"read"}, 60, "")
+				tok2, err := minter2.Mint(context.Background(), "spiffe://test.local/order", audience, []string{"read"}, 60, "")
 				if err != nil {
 					t.Fatalf("Mint (rotated): %v", err)
 				}
@@ -236,7 +237,7 @@ func TestStartAutoRefresh(t *testing.T) {
 				time.Sleep(150 * time.Millisecond)
 
 				// Original key is still cached — tokens still verify.
-				tok, err := minter1.Mint("spiffe://test.local/order", audience, []string{"read"}, 60, "")
+				tok, err := minter1.Mint(context.Background(), "spiffe://test.local/order", audience, []string{"read"}, 60, "")
 				if err != nil {
 					t.Fatalf("Mint: %v", err)
 				}
@@ -0,0 +1,279 @@
+// Package exchangetest runs an in-process svid-exchange server for
+// integration tests, with no SPIRE agent, containers, or network beyond
+// loopback.
+//
+// [NewServer] generates a CA that stands in for a SPIFFE trust domain, issues
+// the server an X.509 SVID from it, and serves the exchange gRPC API over
+// mTLS along with a /jwks endpoint. Tests then obtain connections or
+// [client.Client] values that authenticate as any SPIFFE ID, and verify the
+// tokens they receive with [client.NewVerifier] pointed at [Server.JWKSURL].
+//
+//	srv := exchangetest.NewServer(t, exchangetest.Options{Policies: []exchangetest.Policy{{
+//		Name:          "order-to-payment",
+//		Subject:       "spiffe://test.local/order",
+//		Target:        "spiffe://test.local/payment",
+//		AllowedScopes: []string{"payments:charge"},
+//		MaxTTL:        300,
+//	}}})
+//	c := srv.Client(t, "spiffe://test.local/order", client.Options{
+//		TargetService: "spiffe://test.local/payment",
+//		Scopes:        []string{"payments:charge"},
+//	})
+//	tok, err := c.Token(ctx)
+//
+// Everything is torn down by t.Cleanup.
+package exchangetest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/ngaddam369/svid-exchange/internal/audit"
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	"github.com/ngaddam369/svid-exchange/internal/policy"
+	"github.com/ngaddam369/svid-exchange/internal/server"
+	"github.com/ngaddam369/svid-exchange/internal/spiffe"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+	"github.com/ngaddam369/svid-exchange/pkg/client"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+// serverID is the SPIFFE ID in the server's own SVID.
+const serverID = "spiffe://test.local/svid-exchange"
+
+// certLifetime bounds every certificate the harness issues. Tests are
+// expected to finish well within it.
+const certLifetime = 24 * time.Hour
+
+// Policy is an exchange policy rule, with the fields of an entry in the
+// policy file.
+type Policy = policy.Policy
+
+// Options configures a [Server].
+type Options struct {
+	// Policies are the rules the server enforces. An empty set denies every
+	// exchange.
+	Policies []Policy
+	// AuditLog receives the server's audit log, one JSON object per line.
+	// Nil discards it.
+	AuditLog io.Writer
+}
+
+// Server is a running in-process exchange server. A zero Server is not
+// usable; use [NewServer].
+type Server struct {
+	// Addr is the loopback address serving the exchange v1 and v2 gRPC APIs
+	// over mTLS.
+	Addr string
+	// JWKSURL is the URL of the JWKS document holding the keys tokens are
+	// signed with.
+	JWKSURL string
+
+	roots  *x509.CertPool
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	minter *token.Minter
+	serial atomic.Int64
+}
+
+// NewServer starts a Server enforcing opts.Policies. It fails t if the
+// policies are invalid or the server cannot start, and stops the server
+// when t's test completes.
+func NewServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+	loader, err := policy.NewLoader(opts.Policies)
+	if err != nil {
+		t.Fatalf("exchangetest: policies: %v", err)
+	}
+	minter, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("exchangetest: new minter: %v", err)
+	}
+	s := &Server{minter: minter}
+	s.newCA(t)
+
+	serverCert := s.issue(t, serverID, x509.ExtKeyUsageServerAuth)
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    s.roots,
+		MinVersion:   tls.VersionTLS13,
+	}
+	auditOut := opts.AuditLog
+	if auditOut == nil {
+		auditOut = io.Discard
+	}
+	svc := server.New(spiffe.Extractor{}, loaderEvaluator{loader}, minter, audit.New(auditOut))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("exchangetest: listen: %v", err)
+	}
+	grpcSrv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)))
+	exchangev1.RegisterTokenExchangeServer(grpcSrv, svc)
+	exchangev2.RegisterTokenExchangeServer(grpcSrv, svc.V2())
+	go func() { _ = grpcSrv.Serve(lis) }()
+	t.Cleanup(grpcSrv.Stop)
+	s.Addr = lis.Addr().String()
+
+	jwks := httptest.NewServer(http.HandlerFunc(s.serveJWKS))
+	t.Cleanup(jwks.Close)
+	s.JWKSURL = jwks.URL + "/jwks"
+	return s
+}
+
+// Roots returns the trust bundle of the harness's trust domain: the CA
+// every SVID it issues chains to, the server's included.
+func (s *Server) Roots() *x509.CertPool {
+	return s.roots
+}
+
+// SVID issues an X.509 SVID for spiffeID, usable as a TLS client
+// certificate. It fails t if spiffeID is not a spiffe:// URI.
+func (s *Server) SVID(t testing.TB, spiffeID string) tls.Certificate {
+	t.Helper()
+	return s.issue(t, spiffeID, x509.ExtKeyUsageClientAuth)
+}
+
+// Dial returns a connection to the server that authenticates as spiffeID.
+// It is closed when t's test completes.
+func (s *Server) Dial(t testing.TB, spiffeID string) *grpc.ClientConn {
+	t.Helper()
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{s.SVID(t, spiffeID)},
+		RootCAs:      s.roots,
+		MinVersion:   tls.VersionTLS13,
+	}
+	conn, err := grpc.NewClient(s.Addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	if err != nil {
+		t.Fatalf("exchangetest: dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// Client returns a [client.Client] that exchanges as spiffeID with opts.
+// opts.Addr and opts.SpiffeSocket are ignored. It is closed when t's test
+// completes.
+func (s *Server) Client(t testing.TB, spiffeID string, opts client.Options) *client.Client {
+	t.Helper()
+	c := client.NewFromConn(s.Dial(t, spiffeID), opts)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// newCA generates the self-signed ECDSA P-256 CA of the harness's trust
+// domain.
+func (s *Server) newCA(t testing.TB) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("exchangetest: generate CA key: %v", err)
+	}
+	u, _ := url.Parse("spiffe://test.local")
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(s.serial.Add(1)),
+		Subject:               pkix.Name{CommonName: "exchangetest CA"},
+		URIs:                  []*url.URL{u},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(certLifetime),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("exchangetest: create CA: %v", err)
+	}
+	if s.caCert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatalf("exchangetest: parse CA: %v", err)
+	}
+	s.caKey = key
+	s.roots = x509.NewCertPool()
+	s.roots.AddCert(s.caCert)
+}
+
+// issue signs a leaf certificate for spiffeID with the CA. It is valid for
+// loopback, so the server's certificate verifies against Addr.
+func (s *Server) issue(t testing.TB, spiffeID string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	u, err := url.Parse(spiffeID)
+	if err != nil || u.Scheme != "spiffe" {
+		t.Fatalf("exchangetest: invalid SPIFFE ID %q", spiffeID)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("exchangetest: generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(s.serial.Add(1)),
+		URIs:         []*url.URL{u},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(certLifetime),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert, &key.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatalf("exchangetest: issue SVID for %s: %v", spiffeID, err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("exchangetest: parse SVID for %s: %v", spiffeID, err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// serveJWKS serves the minter's public keys as a JWKS document.
+func (s *Server) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	set := jwk.Set{Keys: make([]jwk.Key, 0, 1)}
+	for _, pub := range s.minter.PublicKeys() {
+		k, err := jwk.FromPublicKey(pub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		alg, err := token.AlgorithmFor(pub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if k.Kid, err = token.KeyID(pub); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		k.Alg, k.Use = string(alg), "sig"
+		set.Keys = append(set.Keys, k)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+// loaderEvaluator adapts a policy.Loader to server.PolicyEvaluator.
+type loaderEvaluator struct {
+	*policy.Loader
+}
+
+func (l loaderEvaluator) Evaluate(_ context.Context, subject, target string, scopes []string, ttlSeconds int32) (policy.EvalResult, error) {
+	return l.Loader.Evaluate(subject, target, scopes, ttlSeconds), nil
+}
@@ -0,0 +1,111 @@
+package exchangetest
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ngaddam369/svid-exchange/pkg/client"
+	exchangev1 "github.com/ngaddam369/svid-exchange/proto/exchange/v1"
+	exchangev2 "github.com/ngaddam369/svid-exchange/proto/exchange/v2"
+)
+
+const (
+	order   = "spiffe://test.local/order"
+	payment = "spiffe://test.local/payment"
+)
+
+// syncBuffer is a bytes.Buffer safe for the server's concurrent writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	var auditLog syncBuffer
+	srv := NewServer(t, Options{
+		Policies: []Policy{{
+			Name:          "order-to-payment",
+			Subject:       order,
+			Target:        payment,
+			AllowedScopes: []string{"payments:charge"},
+			MaxTTL:        300,
+		}},
+		AuditLog: &auditLog,
+	})
+
+	t.Run("client token verifies against the JWKS", func(t *testing.T) {
+		c := srv.Client(t, order, client.Options{TargetService: payment, Scopes: []string{"payments:charge"}, TTLSeconds: 60})
+		tok, err := c.Token(ctx)
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		v, err := client.NewVerifier(ctx, srv.JWKSURL)
+		if err != nil {
+			t.Fatalf("NewVerifier: %v", err)
+		}
+		claims, err := v.Verify(tok, payment)
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if claims["sub"] != order || !client.HasScope(claims, "payments:charge") {
+			t.Errorf("claims = %v", claims)
+		}
+		if !strings.Contains(auditLog.String(), `"granted":true`) {
+			t.Errorf("audit log has no grant: %s", auditLog.String())
+		}
+	})
+
+	t.Run("policy denies other callers", func(t *testing.T) {
+		resp, err := exchangev1.NewTokenExchangeClient(srv.Dial(t, payment)).Exchange(ctx, &exchangev1.ExchangeRequest{
+			TargetService: order,
+			Scopes:        []string{"payments:charge"},
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Exchange = %v, %v; want PermissionDenied", resp, err)
+		}
+	})
+
+	t.Run("v2 API", func(t *testing.T) {
+		resp, err := exchangev2.NewTokenExchangeClient(srv.Dial(t, order)).WhoAmI(ctx, &exchangev2.WhoAmIRequest{})
+		if err != nil {
+			t.Fatalf("WhoAmI: %v", err)
+		}
+		if resp.SpiffeId != order {
+			t.Errorf("WhoAmI = %q, want %q", resp.SpiffeId, order)
+		}
+	})
+
+	t.Run("SVIDs chain to the roots", func(t *testing.T) {
+		cert := srv.SVID(t, order)
+		leaf := cert.Leaf
+		if leaf == nil {
+			t.Fatal("SVID has no parsed leaf")
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{Roots: srv.Roots(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+			t.Errorf("verify SVID: %v", err)
+		}
+		if len(leaf.URIs) != 1 || leaf.URIs[0].String() != order {
+			t.Errorf("URI SANs = %v, want [%s]", leaf.URIs, order)
+		}
+	})
+}
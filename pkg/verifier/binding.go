@@ -0,0 +1,36 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+)
+
+// CertThumbprint returns the RFC 8705 x5t#S256 thumbprint of cert: the
+// base64url-encoded SHA-256 digest of its DER encoding.
+func CertThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CheckBinding verifies that a certificate-bound token (cnf.x5t#S256) was
+// presented over a connection authenticated with the same certificate, so a
+// stolen token cannot be replayed by a different workload. Tokens without a
+// cnf claim pass unless Options.RequireBinding is set.
+func (v *Verifier) CheckBinding(c *Claims, cert *x509.Certificate) error {
+	if c.CertThumbprint == "" {
+		if v.opts.RequireBinding {
+			return errors.New("verifier: token is not certificate-bound")
+		}
+		return nil
+	}
+	if cert == nil {
+		return errors.New("verifier: token is certificate-bound but no client certificate presented")
+	}
+	if subtle.ConstantTimeCompare([]byte(c.CertThumbprint), []byte(CertThumbprint(cert))) != 1 {
+		return errors.New("verifier: client certificate does not match token binding")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func selfSigned(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestCheckBinding(t *testing.T) {
+	cert := selfSigned(t)
+	other := selfSigned(t)
+	bound := &Claims{CertThumbprint: CertThumbprint(cert)}
+	unbound := &Claims{}
+
+	tests := []struct {
+		name           string
+		claims         *Claims
+		cert           *x509.Certificate
+		requireBinding bool
+		wantErr        bool
+	}{
+		{name: "bound token with matching cert", claims: bound, cert: cert},
+		{name: "bound token with different cert", claims: bound, cert: other, wantErr: true},
+		{name: "bound token without cert", claims: bound, wantErr: true},
+		{name: "unbound token allowed", claims: unbound, cert: cert},
+		{name: "unbound token rejected when binding required", claims: unbound, cert: cert, requireBinding: true, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &Verifier{opts: Options{RequireBinding: tc.requireBinding}}
+			if err := v.CheckBinding(tc.claims, tc.cert); (err != nil) != tc.wantErr {
+				t.Errorf("CheckBinding() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
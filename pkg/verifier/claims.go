@@ -0,0 +1,114 @@
+package verifier
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the verified content of an svid-exchange token.
+type Claims struct {
+	// Subject is the SPIFFE ID of the workload the token was issued to.
+	Subject string
+	// Audience lists the SPIFFE IDs the token is valid for.
+	Audience []string
+	// Scopes are the granted scopes, split from the space-delimited claim.
+	Scopes []string
+	// TokenID is the jti claim.
+	TokenID string
+	// Actor is the act.sub claim (RFC 8693) when the token was minted on
+	// behalf of another principal; empty otherwise.
+	Actor string
+	// CertThumbprint is the cnf.x5t#S256 claim (RFC 8705) when the token is
+	// bound to a client certificate; empty otherwise.
+	CertThumbprint string
+	// SourceIP, SourcePod, and SourcePodUID are the src claim of a token
+	// bound to the network identity of the workload it was issued to: the
+	// address the server saw the exchange come from and, for a caller
+	// authenticated with a pod-bound ServiceAccount token, its pod as
+	// <namespace>/<name>. Each is empty when not present.
+	SourceIP     string
+	SourcePod    string
+	SourcePodUID string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+	// SingleUse is set for a token minted by a single_use policy. Verify
+	// consumes such a token through the introspection endpoint, so only
+	// its first presentation verifies.
+	SingleUse bool
+	// Raw holds every claim as decoded from the token or introspection
+	// response, for callers that need non-standard fields.
+	Raw map[string]any
+}
+
+// HasScope reports whether scope was granted.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasAllScopes reports whether every scope in scopes was granted. Returns
+// true when scopes is empty.
+func (c *Claims) HasAllScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		if !c.HasScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireScopes returns an error naming the first scope in scopes that was
+// not granted, or nil if all were.
+func (c *Claims) RequireScopes(scopes ...string) error {
+	for _, s := range scopes {
+		if !c.HasScope(s) {
+			return fmt.Errorf("verifier: missing required scope %q", s)
+		}
+	}
+	return nil
+}
+
+// claimsFromMap converts a decoded claim set into Claims. Fields with an
+// unexpected type are left at their zero value; the signature and registered
+// claims have already been validated by the caller.
+func claimsFromMap(m map[string]any) *Claims {
+	c := &Claims{Raw: m}
+	c.Subject, _ = m["sub"].(string)
+	c.TokenID, _ = m["jti"].(string)
+	if s, ok := m["scope"].(string); ok {
+		c.Scopes = strings.Fields(s)
+	}
+	switch aud := m["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				c.Audience = append(c.Audience, s)
+			}
+		}
+	}
+	if act, ok := m["act"].(map[string]any); ok {
+		c.Actor, _ = act["sub"].(string)
+	}
+	if cnf, ok := m["cnf"].(map[string]any); ok {
+		c.CertThumbprint, _ = cnf["x5t#S256"].(string)
+	}
+	if src, ok := m["src"].(map[string]any); ok {
+		c.SourceIP, _ = src["ip"].(string)
+		c.SourcePod, _ = src["pod"].(string)
+		c.SourcePodUID, _ = src["pod_uid"].(string)
+	}
+	c.SingleUse, _ = m["single_use"].(bool)
+	mc := jwt.MapClaims(m)
+	if t, err := mc.GetIssuedAt(); err == nil && t != nil {
+		c.IssuedAt = t.Time
+	}
+	if t, err := mc.GetExpirationTime(); err == nil && t != nil {
+		c.ExpiresAt = t.Time
+	}
+	return c
+}
@@ -0,0 +1,70 @@
+package verifier
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClaimsFromMap(t *testing.T) {
+	c := claimsFromMap(map[string]any{
+		"sub":   subject,
+		"aud":   []any{audience, 42},
+		"jti":   "abc",
+		"scope": "a b  c",
+		"act":   map[string]any{"sub": "spiffe://cluster.local/ns/default/sa/gateway"},
+		"cnf":   map[string]any{"x5t#S256": "thumb"},
+		"src":   map[string]any{"ip": "10.4.0.17", "pod": "default/order-1"},
+		"iat":   float64(100),
+		"exp":   float64(200),
+	})
+	if c.Subject != subject || c.TokenID != "abc" {
+		t.Errorf("sub/jti = %q/%q", c.Subject, c.TokenID)
+	}
+	if !slices.Equal(c.Audience, []string{audience}) {
+		t.Errorf("Audience = %v", c.Audience)
+	}
+	if !slices.Equal(c.Scopes, []string{"a", "b", "c"}) {
+		t.Errorf("Scopes = %v", c.Scopes)
+	}
+	if c.Actor != "spiffe://cluster.local/ns/default/sa/gateway" {
+		t.Errorf("Actor = %q", c.Actor)
+	}
+	if c.CertThumbprint != "thumb" {
+		t.Errorf("CertThumbprint = %q", c.CertThumbprint)
+	}
+	if c.SourceIP != "10.4.0.17" || c.SourcePod != "default/order-1" || c.SourcePodUID != "" {
+		t.Errorf("source = %q, %q, %q", c.SourceIP, c.SourcePod, c.SourcePodUID)
+	}
+	if c.IssuedAt.Unix() != 100 || c.ExpiresAt.Unix() != 200 {
+		t.Errorf("iat/exp = %v/%v", c.IssuedAt, c.ExpiresAt)
+	}
+
+	if got := claimsFromMap(map[string]any{"aud": audience}).Audience; !slices.Equal(got, []string{audience}) {
+		t.Errorf("string aud = %v", got)
+	}
+}
+
+func TestScopeHelpers(t *testing.T) {
+	c := &Claims{Scopes: []string{"payments:charge", "payments:refund"}}
+
+	tests := []struct {
+		name    string
+		scopes  []string
+		wantAll bool
+	}{
+		{name: "none required", wantAll: true},
+		{name: "single granted", scopes: []string{"payments:charge"}, wantAll: true},
+		{name: "all granted", scopes: []string{"payments:charge", "payments:refund"}, wantAll: true},
+		{name: "one missing", scopes: []string{"payments:charge", "payments:admin"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := c.HasAllScopes(tc.scopes...); got != tc.wantAll {
+				t.Errorf("HasAllScopes = %v, want %v", got, tc.wantAll)
+			}
+			if err := c.RequireScopes(tc.scopes...); (err == nil) != tc.wantAll {
+				t.Errorf("RequireScopes error = %v, want nil=%v", err, tc.wantAll)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package verifier
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	verifierv1 "github.com/ngaddam369/svid-exchange/proto/verifier/v1"
+)
+
+// MethodScopes returns the scopes required to call fullMethod
+// ("/package.Service/Method"). A nil or empty result means any valid token
+// is accepted.
+type MethodScopes func(fullMethod string) []string
+
+// ScopeMap returns a MethodScopes backed by a map keyed on full method name.
+// Methods absent from m require no scopes beyond a valid token.
+func ScopeMap(m map[string][]string) MethodScopes {
+	return func(fullMethod string) []string { return m[fullMethod] }
+}
+
+// ScopesFromOptions returns a MethodScopes that reads the
+// (verifier.v1.required_scopes) method option from the service descriptors
+// registered in protoregistry.GlobalFiles. Import the generated package of
+// every service the interceptor guards so that its descriptor is registered.
+func ScopesFromOptions() MethodScopes {
+	return scopesFromFiles(protoregistry.GlobalFiles)
+}
+
+func scopesFromFiles(files *protoregistry.Files) MethodScopes {
+	return func(fullMethod string) []string {
+		// "/pkg.Service/Method" -> "pkg.Service.Method"
+		name := strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1)
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil
+		}
+		md, ok := d.(protoreflect.MethodDescriptor)
+		if !ok || md.Options() == nil {
+			return nil
+		}
+		scopes, _ := proto.GetExtension(md.Options(), verifierv1.E_RequiredScopes).([]string)
+		return scopes
+	}
+}
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying c.
+func NewContext(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// ClaimsFromContext returns the claims stored by the gRPC interceptors, if
+// any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
+
+// UnaryServerInterceptor verifies the bearer token in the incoming
+// "authorization" metadata, checks any certificate binding against the
+// peer's mTLS certificate, and enforces the scopes returned by scopes for
+// the called method. Verification failures return Unauthenticated; missing
+// scopes return PermissionDenied. On success the claims are available to the
+// handler via [ClaimsFromContext].
+func UnaryServerInterceptor(v *Verifier, scopes MethodScopes) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := v.authorize(ctx, info.FullMethod, scopes)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// [UnaryServerInterceptor]. The token is checked once when the stream opens.
+func StreamServerInterceptor(v *Verifier, scopes MethodScopes) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := v.authorize(ss.Context(), info.FullMethod, scopes)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &claimsStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// claimsStream overrides Context so stream handlers see the verified claims.
+type claimsStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *claimsStream) Context() context.Context { return s.ctx }
+
+func (v *Verifier) authorize(ctx context.Context, fullMethod string, scopes MethodScopes) (context.Context, error) {
+	raw := bearerFromMetadata(ctx)
+	if raw == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	c, err := v.Verify(ctx, raw)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if err := v.checkPeer(c, peerCertificates(ctx)); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "token binding check failed")
+	}
+	if scopes != nil {
+		if err := c.RequireScopes(scopes(fullMethod)...); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+	return NewContext(ctx, c), nil
+}
+
+func bearerFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if raw, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return raw
+		}
+	}
+	return ""
+}
+
+func peerCertificates(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return tlsInfo.State.PeerCertificates
+}
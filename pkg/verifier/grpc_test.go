@@ -0,0 +1,164 @@
+package verifier
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	verifierv1 "github.com/ngaddam369/svid-exchange/proto/verifier/v1"
+)
+
+const chargeMethod = "/payments.v1.Payments/Charge"
+
+func incoming(authz string) context.Context {
+	ctx := context.Background()
+	if authz == "" {
+		return ctx
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authz))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	bound, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience, RequireBinding: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	scopes := ScopeMap(map[string][]string{chargeMethod: {"payments:charge"}})
+	charge := mint(t, m, audience, "payments:charge")
+	read := mint(t, m, audience, "payments:read")
+
+	tests := []struct {
+		name     string
+		v        *Verifier
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "scope granted", v: v, ctx: incoming("Bearer " + charge), method: chargeMethod, wantCode: codes.OK},
+		{name: "unlisted method needs only a valid token", v: v, ctx: incoming("Bearer " + read), method: "/payments.v1.Payments/Get", wantCode: codes.OK},
+		{name: "scope missing", v: v, ctx: incoming("Bearer " + read), method: chargeMethod, wantCode: codes.PermissionDenied},
+		{name: "no metadata", v: v, ctx: context.Background(), method: chargeMethod, wantCode: codes.Unauthenticated},
+		{name: "wrong scheme", v: v, ctx: incoming("Basic Zm9vOmJhcg=="), method: chargeMethod, wantCode: codes.Unauthenticated},
+		{name: "invalid token", v: v, ctx: incoming("Bearer a.b.c"), method: chargeMethod, wantCode: codes.Unauthenticated},
+		{name: "binding required without peer cert", v: bound, ctx: incoming("Bearer " + charge), method: chargeMethod, wantCode: codes.Unauthenticated},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			icpt := UnaryServerInterceptor(tc.v, scopes)
+			var gotSubject string
+			handler := func(ctx context.Context, _ any) (any, error) {
+				if c, ok := ClaimsFromContext(ctx); ok {
+					gotSubject = c.Subject
+				}
+				return "ok", nil
+			}
+			_, err := icpt(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("code = %v (%v), want %v", got, err, tc.wantCode)
+			}
+			if tc.wantCode == codes.OK && gotSubject != subject {
+				t.Errorf("handler saw subject %q, want %q", gotSubject, subject)
+			}
+		})
+	}
+}
+
+// fakeStream is a grpc.ServerStream that only supplies a context.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	icpt := StreamServerInterceptor(v, ScopeMap(map[string][]string{chargeMethod: {"payments:charge"}}))
+	info := &grpc.StreamServerInfo{FullMethod: chargeMethod}
+
+	var claims *Claims
+	handler := func(_ any, ss grpc.ServerStream) error {
+		claims, _ = ClaimsFromContext(ss.Context())
+		return nil
+	}
+	if err := icpt(nil, &fakeStream{ctx: incoming("Bearer " + mint(t, m, audience, "payments:charge"))}, info, handler); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	if claims == nil || claims.Subject != subject {
+		t.Errorf("stream handler claims = %+v", claims)
+	}
+
+	err = icpt(nil, &fakeStream{ctx: incoming("Bearer " + mint(t, m, audience, "payments:read"))}, info, handler)
+	if got := status.Code(err); got != codes.PermissionDenied {
+		t.Errorf("code = %v, want PermissionDenied", got)
+	}
+}
+
+func TestScopesFromOptions(t *testing.T) {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, verifierv1.E_RequiredScopes, []string{"payments:charge", "payments:capture"})
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("payments/v1/payments.proto"),
+		Package: proto.String("payments.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Empty")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Payments"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Charge"), InputType: proto.String(".payments.v1.Empty"), OutputType: proto.String(".payments.v1.Empty"), Options: opts},
+				{Name: proto.String("Get"), InputType: proto.String(".payments.v1.Empty"), OutputType: proto.String(".payments.v1.Empty")},
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fd); err != nil {
+		t.Fatalf("RegisterFile: %v", err)
+	}
+
+	scopes := scopesFromFiles(files)
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{method: chargeMethod, want: []string{"payments:charge", "payments:capture"}},
+		{method: "/payments.v1.Payments/Get"},
+		{method: "/payments.v1.Payments/Missing"},
+		{method: "/unknown.Service/Method"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method, func(t *testing.T) {
+			if got := scopes(tc.method); !slices.Equal(got, tc.want) {
+				t.Errorf("scopes(%q) = %v, want %v", tc.method, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// introspect resolves an opaque token through the RFC 7662 endpoint and
+// applies the same issuer, audience, and expiry checks as a local JWT.
+func (v *Verifier) introspect(ctx context.Context, raw string) (_ *Claims, err error) {
+	form := url.Values{"token": {raw}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.opts.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("verifier: build introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: introspect: %w", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); err == nil && e != nil {
+			err = fmt.Errorf("verifier: close introspection response: %w", e)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verifier: introspection endpoint returned %d", resp.StatusCode)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksBodyLimit)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("verifier: decode introspection response: %w", err)
+	}
+	if active, _ := body["active"].(bool); !active {
+		return nil, ErrInactive
+	}
+
+	c := claimsFromMap(body)
+	if iss, _ := body["iss"].(string); iss != "" && iss != v.opts.Issuer {
+		return nil, fmt.Errorf("verifier: issuer %q, want %q", iss, v.opts.Issuer)
+	}
+	if !slices.Contains(c.Audience, v.opts.Audience) {
+		return nil, fmt.Errorf("verifier: token audience %v does not include %q", c.Audience, v.opts.Audience)
+	}
+	if !c.ExpiresAt.IsZero() && !time.Now().Before(c.ExpiresAt) {
+		return nil, fmt.Errorf("verifier: token expired at %s", c.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return c, nil
+}
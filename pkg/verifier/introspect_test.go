@@ -0,0 +1,145 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestIntrospect(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	jwks := jwksServer(t, &mu, &m)
+
+	exp := float64(time.Now().Add(time.Minute).Unix())
+	responses := map[string]map[string]any{
+		"active": {
+			"active": true, "iss": DefaultIssuer, "sub": subject, "aud": audience,
+			"scope": "payments:charge", "exp": exp,
+		},
+		"inactive":       {"active": false},
+		"wrong-audience": {"active": true, "sub": subject, "aud": "spiffe://cluster.local/ns/default/sa/other", "exp": exp},
+		"wrong-issuer":   {"active": true, "iss": "someone-else", "sub": subject, "aud": audience, "exp": exp},
+		"expired": {
+			"active": true, "sub": subject, "aud": []any{audience},
+			"exp": float64(time.Now().Add(-time.Minute).Unix()),
+		},
+	}
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			http.Error(w, "unknown", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Logf("write introspection response: %v", err)
+		}
+	}))
+	t.Cleanup(introspection.Close)
+
+	v, err := New(context.Background(), Options{
+		JWKSURL:          jwks.URL,
+		Audience:         audience,
+		IntrospectionURL: introspection.URL,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		token   string
+		wantErr bool
+		is      error
+	}{
+		{token: "active"},
+		{token: "inactive", wantErr: true, is: ErrInactive},
+		{token: "wrong-audience", wantErr: true},
+		{token: "wrong-issuer", wantErr: true},
+		{token: "expired", wantErr: true},
+		{token: "endpoint-error", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.token, func(t *testing.T) {
+			c, err := v.Verify(context.Background(), tc.token)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.is != nil && !errors.Is(err, tc.is) {
+				t.Errorf("Verify() error = %v, want %v", err, tc.is)
+			}
+			if err != nil {
+				return
+			}
+			if c.Subject != subject || !c.HasScope("payments:charge") {
+				t.Errorf("claims = %+v", c)
+			}
+		})
+	}
+
+	// JWTs are still verified locally even when introspection is configured.
+	if _, err := v.Verify(context.Background(), mint(t, m, audience)); err != nil {
+		t.Errorf("Verify JWT: %v", err)
+	}
+}
+
+func TestVerifySingleUse(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	jwks := jwksServer(t, &mu, &m)
+
+	res, err := m.Mint(token.WithSingleUse(context.Background()), subject, audience, []string{"payments:charge"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	// The endpoint reports each token active once, as svid-exchange does
+	// for single-use tokens.
+	var used sync.Map
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{"active": false}
+		if _, seen := used.LoadOrStore(r.PostFormValue("token"), true); !seen {
+			body = map[string]any{"active": true, "sub": subject, "aud": audience, "single_use": true}
+		}
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Logf("write introspection response: %v", err)
+		}
+	}))
+	t.Cleanup(introspection.Close)
+
+	t.Run("without an introspection endpoint", func(t *testing.T) {
+		v, err := New(context.Background(), Options{JWKSURL: jwks.URL, Audience: audience})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if _, err := v.Verify(context.Background(), res.Token); !errors.Is(err, ErrSingleUse) {
+			t.Errorf("Verify() error = %v, want ErrSingleUse", err)
+		}
+	})
+
+	t.Run("only the first use verifies", func(t *testing.T) {
+		v, err := New(context.Background(), Options{JWKSURL: jwks.URL, Audience: audience, IntrospectionURL: introspection.URL})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		c, err := v.Verify(context.Background(), res.Token)
+		if err != nil {
+			t.Fatalf("first Verify: %v", err)
+		}
+		if !c.SingleUse || c.TokenID != res.TokenID || !c.HasScope("payments:charge") {
+			t.Errorf("claims = %+v, want the token's own claims", c)
+		}
+		if _, err := v.Verify(context.Background(), res.Token); !errors.Is(err, ErrInactive) {
+			t.Errorf("second Verify() error = %v, want ErrInactive", err)
+		}
+	})
+}
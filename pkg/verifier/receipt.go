@@ -0,0 +1,39 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// Receipt is a verified decision receipt: the server's signed record of why
+// it issued a token.
+type Receipt = token.Receipt
+
+// ErrReceiptMismatch is returned by [Verifier.VerifyReceipt] when a receipt
+// was issued for a different token.
+var ErrReceiptMismatch = errors.New("verifier: receipt was not issued for this token")
+
+// VerifyReceipt checks that raw is a decision receipt signed with one of the
+// server's keys and returns its contents. When tok is non-empty it must be
+// the token the receipt was issued for. Receipts do not expire, so a receipt
+// for a token signed before a rotation verifies only while its key is still
+// in the JWKS.
+func (v *Verifier) VerifyReceipt(ctx context.Context, raw, tok string) (*Receipt, error) {
+	keys, err := v.signingKeys(ctx, jwt.NewParser(), raw)
+	if err != nil {
+		return nil, err
+	}
+	r, err := token.VerifyReceipt(raw, keys)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: %w", err)
+	}
+	if tok != "" && r.TokenHash != token.ReceiptTokenHash(tok) {
+		return nil, ErrReceiptMismatch
+	}
+	return &r, nil
+}
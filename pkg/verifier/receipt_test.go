@@ -0,0 +1,55 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+func TestVerifyReceipt(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tok := mint(t, m, audience, "payments:charge")
+	raw, err := m.SignReceipt(context.Background(), token.Receipt{
+		TokenHash: token.ReceiptTokenHash(tok),
+		Subject:   subject,
+		Rules:     []string{"order-to-payment"},
+	})
+	if err != nil {
+		t.Fatalf("SignReceipt: %v", err)
+	}
+
+	t.Run("receipt for its token", func(t *testing.T) {
+		r, err := v.VerifyReceipt(context.Background(), raw, tok)
+		if err != nil {
+			t.Fatalf("VerifyReceipt: %v", err)
+		}
+		if r.Subject != subject || len(r.Rules) != 1 {
+			t.Errorf("receipt = %+v", r)
+		}
+	})
+
+	t.Run("receipt for another token", func(t *testing.T) {
+		other := mint(t, m, audience, "payments:charge")
+		if _, err := v.VerifyReceipt(context.Background(), raw, other); !errors.Is(err, ErrReceiptMismatch) {
+			t.Errorf("err = %v, want ErrReceiptMismatch", err)
+		}
+	})
+
+	t.Run("token is neither a receipt nor accepted as one", func(t *testing.T) {
+		if _, err := v.VerifyReceipt(context.Background(), tok, ""); err == nil {
+			t.Error("VerifyReceipt accepted a token")
+		}
+		if _, err := v.Verify(context.Background(), raw); err == nil {
+			t.Error("Verify accepted a receipt as a token")
+		}
+	})
+}
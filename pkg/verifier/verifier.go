@@ -0,0 +1,464 @@
+// Package verifier validates tokens issued by svid-exchange on behalf of
+// resource servers.
+//
+// A [Verifier] fetches the signing keys from the /jwks endpoint, caches them
+// by key ID, and checks signature, issuer, expiry, and audience on every
+// token. Cached keys are refreshed in the background once they are older
+// than Options.RefreshInterval, and kept in use while the endpoint fails, so
+// a JWKS outage or a key rotation does not interrupt verification. Tokens
+// that are not JWTs can be checked locally as macaroons when the root key is
+// configured, or resolved through an optional RFC 7662 introspection
+// endpoint, which also consumes single-use tokens. The returned [Claims]
+// carry scope assertion helpers and, via [CheckBinding], an RFC 8705
+// certificate-binding check against the client certificate presented on the
+// connection.
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+// DefaultIssuer is the iss claim svid-exchange writes into every token.
+const DefaultIssuer = "svid-exchange"
+
+// jwksBodyLimit bounds the JWKS and introspection response bodies so that a
+// misconfigured endpoint cannot stream an unbounded response into memory.
+const jwksBodyLimit = 1 << 20 // 1 MiB
+
+// validMethods lists the JWS algorithms svid-exchange can be configured to
+// sign with.
+var validMethods = []string{string(token.ES256), string(token.ES384), string(token.RS256), string(token.EdDSA)}
+
+// minForcedRefresh is the default Options.MinRefreshInterval.
+const minForcedRefresh = 30 * time.Second
+
+// backgroundRefreshTimeout bounds a JWKS fetch that no caller waits on.
+const backgroundRefreshTimeout = 10 * time.Second
+
+var (
+	// ErrNoToken is returned when a request carries no bearer token.
+	ErrNoToken = errors.New("verifier: no bearer token")
+	// ErrOpaqueToken is returned for a non-JWT token when no introspection
+	// endpoint is configured.
+	ErrOpaqueToken = errors.New("verifier: opaque token and no introspection endpoint configured")
+	// ErrInactive is returned when the introspection endpoint reports the
+	// token as inactive.
+	ErrInactive = errors.New("verifier: token is not active")
+	// ErrSingleUse is returned for a single-use token when no introspection
+	// endpoint is configured to consume it.
+	ErrSingleUse = errors.New("verifier: single-use token and no introspection endpoint configured")
+	// ErrStaleKeys is returned for a JWT when the cached signing keys were
+	// last fetched longer ago than Options.MaxStaleness.
+	ErrStaleKeys = errors.New("verifier: cached signing keys are older than MaxStaleness")
+)
+
+// Options configures a [Verifier].
+type Options struct {
+	// JWKSURL is the svid-exchange /jwks endpoint (e.g.
+	// "http://svid-exchange:8081/jwks"). Required.
+	JWKSURL string
+	// Audience is the SPIFFE ID of this resource server. Every token must
+	// list it in its aud claim. Required.
+	Audience string
+	// Issuer overrides the expected iss claim. Defaults to [DefaultIssuer].
+	Issuer string
+	// IntrospectionURL is an optional RFC 7662 endpoint used for tokens that
+	// are not JWTs, and to consume single-use JWTs. When empty, such tokens
+	// fail with [ErrOpaqueToken] and [ErrSingleUse].
+	IntrospectionURL string
+	// MacaroonRootKey, when set, lets Verify check macaroon-format tokens
+	// (token_format: macaroon) locally, including any caveats added by
+	// holders. It must equal the server's MACAROON_ROOT_KEY.
+	MacaroonRootKey []byte
+	// RequireBinding makes [CheckBinding] reject tokens that carry no cnf
+	// claim. When false, unbound tokens are accepted and bound tokens are
+	// still checked.
+	RequireBinding bool
+	// HTTPClient is used for JWKS and introspection requests. Defaults to a
+	// client with a 10 s timeout.
+	HTTPClient *http.Client
+	// RefreshInterval is how long fetched keys are fresh. A JWT verified
+	// with older keys starts a background refresh and is checked against the
+	// cached keys meanwhile. Zero leaves refreshing to Refresh,
+	// StartAutoRefresh, and unknown key IDs.
+	RefreshInterval time.Duration
+	// MaxStaleness bounds how long after the last successful fetch cached
+	// keys are used while refreshes fail. Past it, JWTs fail with
+	// [ErrStaleKeys]. Zero uses them until a refresh succeeds.
+	MaxStaleness time.Duration
+	// MinRefreshInterval is the minimum time between JWKS fetches started by
+	// verification, whether for stale keys or an unknown key ID, so that a
+	// flood of tokens with random kids, or an endpoint that keeps failing,
+	// does not turn the verifier into a JWKS request amplifier. Defaults to
+	// 30 s.
+	MinRefreshInterval time.Duration
+}
+
+// Verifier validates svid-exchange tokens. It is safe for concurrent use.
+type Verifier struct {
+	opts Options
+	http *http.Client
+
+	mu    sync.RWMutex
+	byKID map[string]crypto.PublicKey
+	keys  []crypto.PublicKey // every key, including those published without a kid
+	// lastRefresh is when the JWKS was last fetched, successfully or not,
+	// and fetchedAt when it last succeeded.
+	lastRefresh time.Time
+	fetchedAt   time.Time
+	// inflight is the fetch started by verification, while it runs.
+	inflight *refreshCall
+}
+
+// refreshCall is a JWKS fetch shared by every caller that needs it.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// New creates a Verifier and fetches the JWKS once. It returns an error if
+// the endpoint is unreachable or the response is malformed.
+func New(ctx context.Context, opts Options) (*Verifier, error) {
+	if opts.JWKSURL == "" {
+		return nil, errors.New("verifier: JWKSURL is required")
+	}
+	if opts.Audience == "" {
+		return nil, errors.New("verifier: Audience is required")
+	}
+	if opts.Issuer == "" {
+		opts.Issuer = DefaultIssuer
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = minForcedRefresh
+	}
+	if opts.MaxStaleness > 0 && opts.MaxStaleness <= opts.RefreshInterval {
+		return nil, errors.New("verifier: MaxStaleness must exceed RefreshInterval")
+	}
+	hc := opts.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 10 * time.Second}
+	}
+	v := &Verifier{opts: opts, http: hc}
+	if err := v.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("verifier: initial JWKS fetch: %w", err)
+	}
+	return v, nil
+}
+
+// Refresh re-fetches the JWKS and replaces the cached key set. On error the
+// previous keys stay in place.
+func (v *Verifier) Refresh(ctx context.Context) error {
+	byKID, keys, err := v.fetch(ctx)
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastRefresh = now
+	if err != nil {
+		return err
+	}
+	v.byKID = byKID
+	v.keys = keys
+	v.fetchedAt = now
+	return nil
+}
+
+// fetch retrieves the JWKS and returns its keys, by key ID and in full.
+func (v *Verifier) fetch(ctx context.Context) (_ map[string]crypto.PublicKey, _ []crypto.PublicKey, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.JWKSURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build request: %w", err)
+	}
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer func() {
+		if e := resp.Body.Close(); err == nil {
+			err = e
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var doc jwk.Set
+	if err = json.NewDecoder(io.LimitReader(resp.Body, jwksBodyLimit)).Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	byKID := make(map[string]crypto.PublicKey, len(doc.Keys))
+	keys := make([]crypto.PublicKey, 0, len(doc.Keys))
+	for i, k := range doc.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if k.Kid != "" {
+			byKID[k.Kid] = pub
+		}
+		keys = append(keys, pub)
+	}
+	return byKID, keys, nil
+}
+
+// revalidate starts a background refresh when the cached keys are older
+// than RefreshInterval, and reports [ErrStaleKeys] once they are older than
+// MaxStaleness. Until then the cached keys stay in use.
+func (v *Verifier) revalidate() error {
+	v.mu.RLock()
+	age := time.Since(v.fetchedAt)
+	due := v.opts.RefreshInterval > 0 && age >= v.opts.RefreshInterval && v.refreshAllowedLocked()
+	v.mu.RUnlock()
+	if due {
+		v.mu.Lock()
+		if v.refreshAllowedLocked() {
+			v.startRefreshLocked()
+		}
+		v.mu.Unlock()
+	}
+	if v.opts.MaxStaleness > 0 && age > v.opts.MaxStaleness {
+		return ErrStaleKeys
+	}
+	return nil
+}
+
+// refreshForKID refreshes the JWKS for a token whose key ID the cache lacks,
+// joining a fetch already in flight rather than starting another. It fails
+// without fetching within MinRefreshInterval of the last fetch.
+func (v *Verifier) refreshForKID(ctx context.Context) error {
+	v.mu.Lock()
+	call := v.inflight
+	if call == nil {
+		if !v.refreshAllowedLocked() {
+			v.mu.Unlock()
+			return errors.New("JWKS refreshed too recently")
+		}
+		call = v.startRefreshLocked()
+	}
+	v.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refreshAllowedLocked reports whether verification may start a fetch now.
+// v.mu must be held.
+func (v *Verifier) refreshAllowedLocked() bool {
+	return v.inflight == nil && time.Since(v.lastRefresh) >= v.opts.MinRefreshInterval
+}
+
+// startRefreshLocked starts a JWKS fetch that outlives the request that
+// needed it. v.mu must be held.
+func (v *Verifier) startRefreshLocked() *refreshCall {
+	call := &refreshCall{done: make(chan struct{})}
+	v.inflight = call
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundRefreshTimeout)
+		defer cancel()
+		call.err = v.Refresh(ctx)
+		v.mu.Lock()
+		v.inflight = nil
+		v.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// StartAutoRefresh calls [Verifier.Refresh] on every interval tick until ctx
+// is cancelled. Refresh errors are ignored; cached keys stay valid until the
+// next successful refresh. Use an interval no longer than the server's
+// key_rotation_interval.
+func (v *Verifier) StartAutoRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := v.Refresh(ctx); err != nil {
+					continue // transient failure; cached keys remain valid until next tick
+				}
+			}
+		}
+	}()
+}
+
+// Verify validates raw and returns its claims. JWTs are verified locally
+// against the cached JWKS, and macaroons against Options.MacaroonRootKey; any
+// other token is resolved through the introspection endpoint when one is
+// configured. A single-use JWT is also presented to the introspection
+// endpoint, which consumes it; once consumed it fails with [ErrInactive].
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	if raw == "" {
+		return nil, ErrNoToken
+	}
+	if strings.Count(raw, ".") != 2 {
+		if v.opts.MacaroonRootKey != nil {
+			if _, err := token.ParseMacaroon(raw); err == nil {
+				return v.verifyMacaroon(raw)
+			}
+		}
+		if v.opts.IntrospectionURL == "" {
+			return nil, ErrOpaqueToken
+		}
+		return v.introspect(ctx, raw)
+	}
+	c, err := v.verifyJWT(ctx, raw)
+	if err != nil || !c.SingleUse {
+		return c, err
+	}
+	if v.opts.IntrospectionURL == "" {
+		return nil, ErrSingleUse
+	}
+	if _, err := v.introspect(ctx, raw); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifyRequest extracts the Authorization: Bearer token from r, verifies it,
+// and — when r arrived over mTLS — checks any certificate binding against the
+// client's leaf certificate.
+func (v *Verifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || raw == "" {
+		return nil, ErrNoToken
+	}
+	c, err := v.Verify(r.Context(), raw)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	if r.TLS != nil {
+		certs = r.TLS.PeerCertificates
+	}
+	if err := v.checkPeer(c, certs); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkPeer applies [Verifier.CheckBinding] to the leaf of the peer's
+// certificate chain, or enforces RequireBinding when the peer presented none.
+func (v *Verifier) checkPeer(c *Claims, certs []*x509.Certificate) error {
+	if len(certs) > 0 {
+		return v.CheckBinding(c, certs[0])
+	}
+	if v.opts.RequireBinding || c.CertThumbprint != "" {
+		return errors.New("verifier: token binding required but no client certificate presented")
+	}
+	return nil
+}
+
+func (v *Verifier) verifyJWT(ctx context.Context, raw string) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(v.opts.Audience),
+		jwt.WithIssuer(v.opts.Issuer),
+	)
+
+	candidates, err := v.signingKeys(ctx, parser, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, pub := range candidates {
+		tok, err := parser.Parse(raw, func(t *jwt.Token) (any, error) {
+			// Only try a key with the algorithm its type implies, so a
+			// mixed-algorithm JWKS cannot be used for algorithm confusion.
+			alg, err := token.AlgorithmFor(pub)
+			if err != nil {
+				return nil, err
+			}
+			if t.Method.Alg() != string(alg) {
+				return nil, fmt.Errorf("key is %s, token is %s", alg, t.Method.Alg())
+			}
+			return pub, nil
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		mc, ok := tok.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, errors.New("verifier: unexpected claims type")
+		}
+		return claimsFromMap(mc), nil
+	}
+	return nil, fmt.Errorf("verifier: %w", lastErr)
+}
+
+// signingKeys returns the keys raw's signature may be checked against,
+// refreshing the JWKS once when raw names a key ID the cache lacks.
+func (v *Verifier) signingKeys(ctx context.Context, parser *jwt.Parser, raw string) ([]crypto.PublicKey, error) {
+	// Unverified parse to read the kid; the caller checks the signature.
+	unverified, _, err := parser.ParseUnverified(raw, jwt.MapClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("verifier: %w", err)
+	}
+	kid, _ := unverified.Header["kid"].(string)
+
+	if err := v.revalidate(); err != nil {
+		return nil, err
+	}
+	candidates := v.candidates(kid)
+	if len(candidates) == 0 && kid != "" {
+		// Unknown kid: the server has probably rotated. Refresh once and
+		// retry. Look again even when the refresh is refused: another
+		// caller's may just have loaded the key.
+		_ = v.refreshForKID(ctx)
+		candidates = v.candidates(kid)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("verifier: no key for kid %q", kid)
+	}
+	return candidates, nil
+}
+
+func (v *Verifier) verifyMacaroon(raw string) (*Claims, error) {
+	mc, err := token.VerifyMacaroon(raw, v.opts.MacaroonRootKey, v.opts.Audience)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: %w", err)
+	}
+	if iss, _ := mc["iss"].(string); iss != v.opts.Issuer {
+		return nil, fmt.Errorf("verifier: issuer %q, want %q", iss, v.opts.Issuer)
+	}
+	return claimsFromMap(mc), nil
+}
+
+// candidates returns the keys to try for kid: the exact match when the JWKS
+// published one, otherwise every cached key (tokens without a kid, or a JWKS
+// that omits kids).
+func (v *Verifier) candidates(kid string) []crypto.PublicKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if kid != "" && len(v.byKID) > 0 {
+		if pub, ok := v.byKID[kid]; ok {
+			return []crypto.PublicKey{pub}
+		}
+		return nil
+	}
+	return v.keys
+}
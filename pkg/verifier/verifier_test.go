@@ -0,0 +1,495 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ngaddam369/svid-exchange/internal/jwk"
+	"github.com/ngaddam369/svid-exchange/internal/token"
+)
+
+const (
+	subject  = "spiffe://cluster.local/ns/default/sa/order"
+	audience = "spiffe://cluster.local/ns/default/sa/payment"
+)
+
+// jwksServer serves the active keys of whatever minter *current points to,
+// with the same kid the minter writes into token headers.
+func jwksServer(t *testing.T, mu *sync.Mutex, current **token.Minter) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		m := *current
+		mu.Unlock()
+
+		set := jwk.Set{}
+		for _, pub := range m.PublicKeys() {
+			k, err := jwk.FromPublicKey(pub)
+			if err != nil {
+				http.Error(w, "key encode error", http.StatusInternalServerError)
+				return
+			}
+			if k.Kid, err = token.KeyID(pub); err != nil {
+				http.Error(w, "kid error", http.StatusInternalServerError)
+				return
+			}
+			set.Keys = append(set.Keys, k)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(set); err != nil {
+			t.Logf("write JWKS: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newMinter(t *testing.T) *token.Minter {
+	t.Helper()
+	m, err := token.NewMinter()
+	if err != nil {
+		t.Fatalf("NewMinter: %v", err)
+	}
+	return m
+}
+
+func mint(t *testing.T, m *token.Minter, aud string, scopes ...string) string {
+	t.Helper()
+	res, err := m.Mint(context.Background(), subject, aud, scopes, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	return res.Token
+}
+
+func TestNew(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	down := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(down.Close)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "valid", opts: Options{JWKSURL: srv.URL, Audience: audience}},
+		{name: "missing JWKS URL", opts: Options{Audience: audience}, wantErr: true},
+		{name: "missing audience", opts: Options{JWKSURL: srv.URL}, wantErr: true},
+		{name: "JWKS endpoint error", opts: Options{JWKSURL: down.URL, Audience: audience}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	other := newMinter(t)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error // nil means success; errAny means any error
+	}{
+		{name: "valid token", token: mint(t, m, audience, "payments:charge")},
+		{name: "wrong audience", token: mint(t, m, "spiffe://cluster.local/ns/default/sa/other"), wantErr: errAny},
+		{name: "unknown signing key", token: mint(t, other, audience), wantErr: errAny},
+		{name: "malformed JWT", token: "a.b.c", wantErr: errAny},
+		{name: "empty token", token: "", wantErr: ErrNoToken},
+		{name: "opaque token without introspection", token: "opaque-handle", wantErr: ErrOpaqueToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := v.Verify(context.Background(), tc.token)
+			switch {
+			case tc.wantErr == nil && err != nil:
+				t.Fatalf("Verify: %v", err)
+			case tc.wantErr == errAny && err == nil:
+				t.Fatal("Verify succeeded, want error")
+			case tc.wantErr != nil && tc.wantErr != errAny && !errors.Is(err, tc.wantErr):
+				t.Fatalf("Verify error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if c.Subject != subject {
+				t.Errorf("Subject = %q, want %q", c.Subject, subject)
+			}
+			if !c.HasScope("payments:charge") {
+				t.Errorf("Scopes = %v, want payments:charge", c.Scopes)
+			}
+		})
+	}
+}
+
+var errAny = errors.New("any error")
+
+func TestVerifyAlgorithms(t *testing.T) {
+	for _, alg := range token.Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			var mu sync.Mutex
+			m, err := token.NewMinterWithAlgorithm(alg)
+			if err != nil {
+				t.Fatalf("NewMinterWithAlgorithm: %v", err)
+			}
+			srv := jwksServer(t, &mu, &m)
+			v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if _, err := v.Verify(context.Background(), mint(t, m, audience)); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyMacaroon(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	rootKey := bytes.Repeat([]byte{3}, token.MinMacaroonKeySize)
+	mm, err := token.NewMacaroonMinter(rootKey)
+	if err != nil {
+		t.Fatalf("NewMacaroonMinter: %v", err)
+	}
+	res, err := mm.Mint(context.Background(), subject, audience, []string{"payments:charge", "payments:refund"}, 60, "")
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	narrowed, err := token.AttenuateMacaroon(res.Token, "scope = payments:charge")
+	if err != nil {
+		t.Fatalf("AttenuateMacaroon: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		rootKey   []byte
+		token     string
+		wantErr   error
+		wantScope []string
+	}{
+		{name: "valid macaroon", rootKey: rootKey, token: res.Token, wantScope: []string{"payments:charge", "payments:refund"}},
+		{name: "attenuated macaroon", rootKey: rootKey, token: narrowed, wantScope: []string{"payments:charge"}},
+		{name: "wrong root key", rootKey: bytes.Repeat([]byte{4}, token.MinMacaroonKeySize), token: res.Token, wantErr: errAny},
+		{name: "no root key configured", token: res.Token, wantErr: ErrOpaqueToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience, MacaroonRootKey: tc.rootKey})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			c, err := v.Verify(context.Background(), tc.token)
+			switch {
+			case tc.wantErr == nil && err != nil:
+				t.Fatalf("Verify: %v", err)
+			case tc.wantErr == errAny && err == nil:
+				t.Fatal("Verify succeeded, want error")
+			case tc.wantErr != nil && tc.wantErr != errAny && !errors.Is(err, tc.wantErr):
+				t.Fatalf("Verify error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if c.Subject != subject || c.TokenID != res.TokenID {
+				t.Errorf("Subject, TokenID = %q, %q; want %q, %q", c.Subject, c.TokenID, subject, res.TokenID)
+			}
+			if !slices.Equal(c.Scopes, tc.wantScope) {
+				t.Errorf("Scopes = %v, want %v", c.Scopes, tc.wantScope)
+			}
+			if c.ExpiresAt.IsZero() {
+				t.Error("ExpiresAt is zero")
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownKIDRefreshes(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	next := newMinter(t)
+	mu.Lock()
+	m = next
+	mu.Unlock()
+	tok := mint(t, next, audience)
+
+	// Within minForcedRefresh of the last fetch the unknown kid is rejected
+	// without hitting the JWKS endpoint again.
+	if _, err := v.Verify(context.Background(), tok); err == nil {
+		t.Fatal("Verify succeeded before forced-refresh window elapsed")
+	}
+
+	v.mu.Lock()
+	v.lastRefresh = time.Now().Add(-minForcedRefresh)
+	v.mu.Unlock()
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatalf("Verify after forced refresh: %v", err)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	tok := mint(t, m, audience, "payments:charge")
+
+	tests := []struct {
+		name           string
+		authz          string
+		requireBinding bool
+		wantErr        bool
+	}{
+		{name: "bearer token", authz: "Bearer " + tok},
+		{name: "missing header", wantErr: true},
+		{name: "wrong scheme", authz: "Basic Zm9vOmJhcg==", wantErr: true},
+		{name: "binding required without client cert", authz: "Bearer " + tok, requireBinding: true, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience, RequireBinding: tc.requireBinding})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authz != "" {
+				r.Header.Set("Authorization", tc.authz)
+			}
+			if _, err := v.VerifyRequest(r); (err != nil) != tc.wantErr {
+				t.Errorf("VerifyRequest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartAutoRefresh(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	next := newMinter(t)
+	mu.Lock()
+	m = next
+	mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	v.StartAutoRefresh(ctx, 10*time.Millisecond)
+
+	kid, err := token.KeyID(next.PublicKey())
+	if err != nil {
+		t.Fatalf("KeyID: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(v.candidates(kid)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("auto-refresh did not pick up rotated key")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// flakyJWKS wraps a JWKS server, counting fetches and failing them with 503
+// while down is set.
+type flakyJWKS struct {
+	fetches atomic.Int32
+	down    atomic.Bool
+}
+
+func (f *flakyJWKS) wrap(t *testing.T, next *httptest.Server) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.fetches.Add(1)
+		if f.down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// age makes v's keys look fetched d ago, and its last fetch attempt too.
+func age(v *Verifier, d time.Duration) {
+	v.mu.Lock()
+	v.fetchedAt = time.Now().Add(-d)
+	v.lastRefresh = time.Now().Add(-d)
+	v.mu.Unlock()
+}
+
+// waitIdle waits for v's background refresh, if any, to finish.
+func waitIdle(t *testing.T, v *Verifier) {
+	t.Helper()
+	v.mu.RLock()
+	call := v.inflight
+	v.mu.RUnlock()
+	if call == nil {
+		return
+	}
+	select {
+	case <-call.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("background refresh did not finish")
+	}
+}
+
+func TestNewRejectsMaxStalenessBelowRefreshInterval(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	srv := jwksServer(t, &mu, &m)
+	_, err := New(context.Background(), Options{
+		JWKSURL: srv.URL, Audience: audience,
+		RefreshInterval: time.Hour, MaxStaleness: time.Minute,
+	})
+	if err == nil {
+		t.Fatal("New succeeded, want an error")
+	}
+}
+
+func TestVerifyUnknownKIDCoalescesRefreshes(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	var f flakyJWKS
+	srv := f.wrap(t, jwksServer(t, &mu, &m))
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	next := newMinter(t)
+	mu.Lock()
+	m = next
+	mu.Unlock()
+	tok := mint(t, next, audience)
+	age(v, minForcedRefresh)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.Verify(context.Background(), tok)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+	}
+	if got := f.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2 (New and one shared refresh)", got)
+	}
+}
+
+func TestVerifyUnknownKIDFailedRefreshIsRateLimited(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	var f flakyJWKS
+	srv := f.wrap(t, jwksServer(t, &mu, &m))
+	v, err := New(context.Background(), Options{JWKSURL: srv.URL, Audience: audience})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	f.down.Store(true)
+	tok := mint(t, newMinter(t), audience)
+	age(v, minForcedRefresh)
+	for range 3 {
+		if _, err := v.Verify(context.Background(), tok); err == nil {
+			t.Fatal("Verify succeeded with an unknown kid")
+		}
+	}
+	if got := f.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2: a failed refresh must count toward the limit", got)
+	}
+}
+
+func TestVerifyStaleWhileRevalidate(t *testing.T) {
+	var mu sync.Mutex
+	m := newMinter(t)
+	var f flakyJWKS
+	srv := f.wrap(t, jwksServer(t, &mu, &m))
+	v, err := New(context.Background(), Options{
+		JWKSURL: srv.URL, Audience: audience,
+		RefreshInterval: time.Hour, MaxStaleness: 2 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	tok := mint(t, m, audience)
+
+	// Fresh keys: no fetch.
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got := f.fetches.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times with fresh keys, want 1", got)
+	}
+
+	// Stale keys and a failing endpoint: the cached keys are still used, and
+	// a background refresh is attempted.
+	f.down.Store(true)
+	age(v, 90*time.Minute)
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatalf("Verify with stale keys: %v", err)
+	}
+	waitIdle(t, v)
+	if got := f.fetches.Load(); got != 2 {
+		t.Fatalf("JWKS fetched %d times, want a background refresh", got)
+	}
+
+	// Past MaxStaleness the keys are no longer trusted.
+	age(v, 3*time.Hour)
+	if _, err := v.Verify(context.Background(), tok); !errors.Is(err, ErrStaleKeys) {
+		t.Fatalf("Verify past MaxStaleness: err = %v, want ErrStaleKeys", err)
+	}
+	waitIdle(t, v)
+
+	// Once the endpoint recovers, the next refresh restores verification.
+	f.down.Store(false)
+	age(v, 3*time.Hour)
+	if _, err := v.Verify(context.Background(), tok); !errors.Is(err, ErrStaleKeys) {
+		t.Fatalf("Verify while refreshing: err = %v, want ErrStaleKeys", err)
+	}
+	waitIdle(t, v)
+	if _, err := v.Verify(context.Background(), tok); err != nil {
+		t.Fatalf("Verify after refresh: %v", err)
+	}
+}
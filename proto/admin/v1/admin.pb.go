@@ -33,7 +33,28 @@ type PolicyRule struct {
 	// allowed_scopes is the complete set of scopes this subject may request.
 	AllowedScopes []string `protobuf:"bytes,4,rep,name=allowed_scopes,json=allowedScopes,proto3" json:"allowed_scopes,omitempty"`
 	// max_ttl is the maximum token lifetime in seconds.
-	MaxTtl        int32 `protobuf:"varint,5,opt,name=max_ttl,json=maxTtl,proto3" json:"max_ttl,omitempty"`
+	MaxTtl int32 `protobuf:"varint,5,opt,name=max_ttl,json=maxTtl,proto3" json:"max_ttl,omitempty"`
+	// token_format selects the encoding of granted tokens: "jwt" (default when
+	// empty), "jwt-svid", "macaroon", or "paseto".
+	TokenFormat string `protobuf:"bytes,6,opt,name=token_format,json=tokenFormat,proto3" json:"token_format,omitempty"`
+	// require_nonce refuses exchanges the rule grants unless the request
+	// carries a nonce that has not been used within the nonce window.
+	RequireNonce bool `protobuf:"varint,7,opt,name=require_nonce,json=requireNonce,proto3" json:"require_nonce,omitempty"`
+	// condition is a CEL expression over the request that must hold for the
+	// rule to grant anything. Empty always holds.
+	Condition string `protobuf:"bytes,8,opt,name=condition,proto3" json:"condition,omitempty"`
+	// requires_approval parks exchanges the rule grants until an approver
+	// grants or denies them with DecideApproval or the approval webhook.
+	RequiresApproval bool `protobuf:"varint,9,opt,name=requires_approval,json=requiresApproval,proto3" json:"requires_approval,omitempty"`
+	// single_use marks the tokens the rule grants as one-time: the first
+	// introspection or ext_authz check consumes them. JWT formats only.
+	SingleUse bool `protobuf:"varint,10,opt,name=single_use,json=singleUse,proto3" json:"single_use,omitempty"`
+	// audiences maps target SPIFFE IDs the rule's target matches to the aud
+	// values granted tokens carry in place of the target ID. JWT formats only.
+	Audiences map[string]*AudienceList `protobuf:"bytes,11,rep,name=audiences,proto3" json:"audiences,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// issuer replaces "svid-exchange" as the iss of granted tokens. JWT
+	// formats only.
+	Issuer        string `protobuf:"bytes,12,opt,name=issuer,proto3" json:"issuer,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -103,6 +124,100 @@ func (x *PolicyRule) GetMaxTtl() int32 {
 	return 0
 }
 
+func (x *PolicyRule) GetTokenFormat() string {
+	if x != nil {
+		return x.TokenFormat
+	}
+	return ""
+}
+
+func (x *PolicyRule) GetRequireNonce() bool {
+	if x != nil {
+		return x.RequireNonce
+	}
+	return false
+}
+
+func (x *PolicyRule) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *PolicyRule) GetRequiresApproval() bool {
+	if x != nil {
+		return x.RequiresApproval
+	}
+	return false
+}
+
+func (x *PolicyRule) GetSingleUse() bool {
+	if x != nil {
+		return x.SingleUse
+	}
+	return false
+}
+
+func (x *PolicyRule) GetAudiences() map[string]*AudienceList {
+	if x != nil {
+		return x.Audiences
+	}
+	return nil
+}
+
+func (x *PolicyRule) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+// AudienceList is the aud values of one PolicyRule.audiences entry.
+type AudienceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AudienceList) Reset() {
+	*x = AudienceList{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AudienceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AudienceList) ProtoMessage() {}
+
+func (x *AudienceList) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AudienceList.ProtoReflect.Descriptor instead.
+func (*AudienceList) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AudienceList) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
 type CreatePolicyRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Rule          *PolicyRule            `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
@@ -112,7 +227,7 @@ type CreatePolicyRequest struct {
 
 func (x *CreatePolicyRequest) Reset() {
 	*x = CreatePolicyRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[1]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -124,7 +239,7 @@ func (x *CreatePolicyRequest) String() string {
 func (*CreatePolicyRequest) ProtoMessage() {}
 
 func (x *CreatePolicyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[1]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -137,7 +252,7 @@ func (x *CreatePolicyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreatePolicyRequest.ProtoReflect.Descriptor instead.
 func (*CreatePolicyRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{1}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CreatePolicyRequest) GetRule() *PolicyRule {
@@ -156,7 +271,7 @@ type CreatePolicyResponse struct {
 
 func (x *CreatePolicyResponse) Reset() {
 	*x = CreatePolicyResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[2]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -168,7 +283,7 @@ func (x *CreatePolicyResponse) String() string {
 func (*CreatePolicyResponse) ProtoMessage() {}
 
 func (x *CreatePolicyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[2]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -181,7 +296,7 @@ func (x *CreatePolicyResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreatePolicyResponse.ProtoReflect.Descriptor instead.
 func (*CreatePolicyResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{2}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreatePolicyResponse) GetRule() *PolicyRule {
@@ -200,7 +315,7 @@ type DeletePolicyRequest struct {
 
 func (x *DeletePolicyRequest) Reset() {
 	*x = DeletePolicyRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[3]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -212,7 +327,7 @@ func (x *DeletePolicyRequest) String() string {
 func (*DeletePolicyRequest) ProtoMessage() {}
 
 func (x *DeletePolicyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[3]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -225,7 +340,7 @@ func (x *DeletePolicyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePolicyRequest.ProtoReflect.Descriptor instead.
 func (*DeletePolicyRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{3}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *DeletePolicyRequest) GetName() string {
@@ -243,7 +358,7 @@ type DeletePolicyResponse struct {
 
 func (x *DeletePolicyResponse) Reset() {
 	*x = DeletePolicyResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[4]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -255,7 +370,7 @@ func (x *DeletePolicyResponse) String() string {
 func (*DeletePolicyResponse) ProtoMessage() {}
 
 func (x *DeletePolicyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[4]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -268,7 +383,7 @@ func (x *DeletePolicyResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePolicyResponse.ProtoReflect.Descriptor instead.
 func (*DeletePolicyResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{4}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{5}
 }
 
 type ListPoliciesRequest struct {
@@ -279,7 +394,7 @@ type ListPoliciesRequest struct {
 
 func (x *ListPoliciesRequest) Reset() {
 	*x = ListPoliciesRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[5]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -291,7 +406,7 @@ func (x *ListPoliciesRequest) String() string {
 func (*ListPoliciesRequest) ProtoMessage() {}
 
 func (x *ListPoliciesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[5]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -304,7 +419,7 @@ func (x *ListPoliciesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPoliciesRequest.ProtoReflect.Descriptor instead.
 func (*ListPoliciesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{5}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{6}
 }
 
 // PolicyEntry wraps a rule with its origin.
@@ -319,7 +434,7 @@ type PolicyEntry struct {
 
 func (x *PolicyEntry) Reset() {
 	*x = PolicyEntry{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[6]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -331,7 +446,7 @@ func (x *PolicyEntry) String() string {
 func (*PolicyEntry) ProtoMessage() {}
 
 func (x *PolicyEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[6]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -344,7 +459,7 @@ func (x *PolicyEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PolicyEntry.ProtoReflect.Descriptor instead.
 func (*PolicyEntry) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{6}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *PolicyEntry) GetRule() *PolicyRule {
@@ -370,7 +485,7 @@ type ListPoliciesResponse struct {
 
 func (x *ListPoliciesResponse) Reset() {
 	*x = ListPoliciesResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[7]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -382,7 +497,7 @@ func (x *ListPoliciesResponse) String() string {
 func (*ListPoliciesResponse) ProtoMessage() {}
 
 func (x *ListPoliciesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[7]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -395,7 +510,7 @@ func (x *ListPoliciesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPoliciesResponse.ProtoReflect.Descriptor instead.
 func (*ListPoliciesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{7}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ListPoliciesResponse) GetPolicies() []*PolicyEntry {
@@ -413,7 +528,7 @@ type ReloadPolicyRequest struct {
 
 func (x *ReloadPolicyRequest) Reset() {
 	*x = ReloadPolicyRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[8]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -425,7 +540,7 @@ func (x *ReloadPolicyRequest) String() string {
 func (*ReloadPolicyRequest) ProtoMessage() {}
 
 func (x *ReloadPolicyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[8]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -438,7 +553,7 @@ func (x *ReloadPolicyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReloadPolicyRequest.ProtoReflect.Descriptor instead.
 func (*ReloadPolicyRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{8}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{9}
 }
 
 type ReloadPolicyResponse struct {
@@ -449,7 +564,7 @@ type ReloadPolicyResponse struct {
 
 func (x *ReloadPolicyResponse) Reset() {
 	*x = ReloadPolicyResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[9]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -461,7 +576,7 @@ func (x *ReloadPolicyResponse) String() string {
 func (*ReloadPolicyResponse) ProtoMessage() {}
 
 func (x *ReloadPolicyResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[9]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -474,7 +589,7 @@ func (x *ReloadPolicyResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReloadPolicyResponse.ProtoReflect.Descriptor instead.
 func (*ReloadPolicyResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{9}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{10}
 }
 
 type RevokeTokenRequest struct {
@@ -490,7 +605,7 @@ type RevokeTokenRequest struct {
 
 func (x *RevokeTokenRequest) Reset() {
 	*x = RevokeTokenRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[10]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -502,7 +617,7 @@ func (x *RevokeTokenRequest) String() string {
 func (*RevokeTokenRequest) ProtoMessage() {}
 
 func (x *RevokeTokenRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[10]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -515,7 +630,7 @@ func (x *RevokeTokenRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RevokeTokenRequest.ProtoReflect.Descriptor instead.
 func (*RevokeTokenRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{10}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *RevokeTokenRequest) GetTokenId() string {
@@ -540,7 +655,7 @@ type RevokeTokenResponse struct {
 
 func (x *RevokeTokenResponse) Reset() {
 	*x = RevokeTokenResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[11]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -552,7 +667,7 @@ func (x *RevokeTokenResponse) String() string {
 func (*RevokeTokenResponse) ProtoMessage() {}
 
 func (x *RevokeTokenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[11]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -565,7 +680,7 @@ func (x *RevokeTokenResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RevokeTokenResponse.ProtoReflect.Descriptor instead.
 func (*RevokeTokenResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{11}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{12}
 }
 
 type ListRevokedTokensRequest struct {
@@ -576,7 +691,7 @@ type ListRevokedTokensRequest struct {
 
 func (x *ListRevokedTokensRequest) Reset() {
 	*x = ListRevokedTokensRequest{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[12]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -588,7 +703,7 @@ func (x *ListRevokedTokensRequest) String() string {
 func (*ListRevokedTokensRequest) ProtoMessage() {}
 
 func (x *ListRevokedTokensRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[12]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -601,7 +716,7 @@ func (x *ListRevokedTokensRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListRevokedTokensRequest.ProtoReflect.Descriptor instead.
 func (*ListRevokedTokensRequest) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{12}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{13}
 }
 
 // RevokedToken holds the token ID and its natural expiry.
@@ -615,7 +730,7 @@ type RevokedToken struct {
 
 func (x *RevokedToken) Reset() {
 	*x = RevokedToken{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[13]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -627,7 +742,7 @@ func (x *RevokedToken) String() string {
 func (*RevokedToken) ProtoMessage() {}
 
 func (x *RevokedToken) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[13]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -640,7 +755,7 @@ func (x *RevokedToken) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RevokedToken.ProtoReflect.Descriptor instead.
 func (*RevokedToken) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{13}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *RevokedToken) GetTokenId() string {
@@ -666,7 +781,7 @@ type ListRevokedTokensResponse struct {
 
 func (x *ListRevokedTokensResponse) Reset() {
 	*x = ListRevokedTokensResponse{}
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[14]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -678,7 +793,7 @@ func (x *ListRevokedTokensResponse) String() string {
 func (*ListRevokedTokensResponse) ProtoMessage() {}
 
 func (x *ListRevokedTokensResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_v1_admin_proto_msgTypes[14]
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -691,7 +806,7 @@ func (x *ListRevokedTokensResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListRevokedTokensResponse.ProtoReflect.Descriptor instead.
 func (*ListRevokedTokensResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{14}
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ListRevokedTokensResponse) GetTokens() []*RevokedToken {
@@ -701,52 +816,1419 @@ func (x *ListRevokedTokensResponse) GetTokens() []*RevokedToken {
 	return nil
 }
 
-var File_proto_admin_v1_admin_proto protoreflect.FileDescriptor
+type ActivateBreakGlassRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// document is the override in YAML: id, reason, issued_by, expires_at,
+	// and policies in the policy file format.
+	Document []byte `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	// signature is the signature over document by the key whose public half
+	// is break_glass_public_key: ASN.1 ECDSA over its SHA-256, or Ed25519.
+	Signature     []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_admin_v1_admin_proto_rawDesc = "" +
-	"\n" +
-	"\x1aproto/admin/v1/admin.proto\x12\badmin.v1\"\x92\x01\n" +
-	"\n" +
-	"PolicyRule\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
-	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
-	"\x06target\x18\x03 \x01(\tR\x06target\x12%\n" +
-	"\x0eallowed_scopes\x18\x04 \x03(\tR\rallowedScopes\x12\x17\n" +
-	"\amax_ttl\x18\x05 \x01(\x05R\x06maxTtl\"?\n" +
-	"\x13CreatePolicyRequest\x12(\n" +
-	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\"@\n" +
-	"\x14CreatePolicyResponse\x12(\n" +
-	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\")\n" +
-	"\x13DeletePolicyRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\"\x16\n" +
-	"\x14DeletePolicyResponse\"\x15\n" +
-	"\x13ListPoliciesRequest\"O\n" +
-	"\vPolicyEntry\x12(\n" +
-	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\x12\x16\n" +
-	"\x06source\x18\x02 \x01(\tR\x06source\"I\n" +
-	"\x14ListPoliciesResponse\x121\n" +
-	"\bpolicies\x18\x01 \x03(\v2\x15.admin.v1.PolicyEntryR\bpolicies\"\x15\n" +
-	"\x13ReloadPolicyRequest\"\x16\n" +
-	"\x14ReloadPolicyResponse\"N\n" +
-	"\x12RevokeTokenRequest\x12\x19\n" +
-	"\btoken_id\x18\x01 \x01(\tR\atokenId\x12\x1d\n" +
-	"\n" +
-	"expires_at\x18\x02 \x01(\x03R\texpiresAt\"\x15\n" +
-	"\x13RevokeTokenResponse\"\x1a\n" +
-	"\x18ListRevokedTokensRequest\"H\n" +
-	"\fRevokedToken\x12\x19\n" +
-	"\btoken_id\x18\x01 \x01(\tR\atokenId\x12\x1d\n" +
-	"\n" +
-	"expires_at\x18\x02 \x01(\x03R\texpiresAt\"K\n" +
-	"\x19ListRevokedTokensResponse\x12.\n" +
-	"\x06tokens\x18\x01 \x03(\v2\x16.admin.v1.RevokedTokenR\x06tokens2\xf3\x03\n" +
+func (x *ActivateBreakGlassRequest) Reset() {
+	*x = ActivateBreakGlassRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateBreakGlassRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateBreakGlassRequest) ProtoMessage() {}
+
+func (x *ActivateBreakGlassRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateBreakGlassRequest.ProtoReflect.Descriptor instead.
+func (*ActivateBreakGlassRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ActivateBreakGlassRequest) GetDocument() []byte {
+	if x != nil {
+		return x.Document
+	}
+	return nil
+}
+
+func (x *ActivateBreakGlassRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ActivateBreakGlassResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the activated override's id.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// expires_at is the Unix timestamp when the override stops granting.
+	ExpiresAt     int64 `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateBreakGlassResponse) Reset() {
+	*x = ActivateBreakGlassResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateBreakGlassResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateBreakGlassResponse) ProtoMessage() {}
+
+func (x *ActivateBreakGlassResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateBreakGlassResponse.ProtoReflect.Descriptor instead.
+func (*ActivateBreakGlassResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ActivateBreakGlassResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ActivateBreakGlassResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type DeactivateBreakGlassRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeactivateBreakGlassRequest) Reset() {
+	*x = DeactivateBreakGlassRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeactivateBreakGlassRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeactivateBreakGlassRequest) ProtoMessage() {}
+
+func (x *DeactivateBreakGlassRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeactivateBreakGlassRequest.ProtoReflect.Descriptor instead.
+func (*DeactivateBreakGlassRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{18}
+}
+
+type DeactivateBreakGlassResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the deactivated override's id.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeactivateBreakGlassResponse) Reset() {
+	*x = DeactivateBreakGlassResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeactivateBreakGlassResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeactivateBreakGlassResponse) ProtoMessage() {}
+
+func (x *DeactivateBreakGlassResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeactivateBreakGlassResponse.ProtoReflect.Descriptor instead.
+func (*DeactivateBreakGlassResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeactivateBreakGlassResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DrainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{20}
+}
+
+type DrainResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// exit_at is the Unix timestamp after which the server stops accepting
+	// calls, finishes in-flight ones, and exits.
+	ExitAt        int64 `protobuf:"varint,1,opt,name=exit_at,json=exitAt,proto3" json:"exit_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DrainResponse) GetExitAt() int64 {
+	if x != nil {
+		return x.ExitAt
+	}
+	return 0
+}
+
+type SetLogLevelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// level is trace, debug, info, warn, or error. Empty restores the
+	// configured level.
+	Level string `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	// duration_seconds, when positive, restores the configured level after
+	// that many seconds. Zero keeps the new level until the next call.
+	DurationSeconds int64 `protobuf:"varint,2,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SetLogLevelRequest) Reset() {
+	*x = SetLogLevelRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelRequest) ProtoMessage() {}
+
+func (x *SetLogLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelRequest.ProtoReflect.Descriptor instead.
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SetLogLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *SetLogLevelRequest) GetDurationSeconds() int64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+type SetLogLevelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// previous is the level in effect before the call.
+	Previous string `protobuf:"bytes,1,opt,name=previous,proto3" json:"previous,omitempty"`
+	// revert_at is the Unix timestamp at which the configured level is
+	// restored, or 0 if the new level stays until changed.
+	RevertAt      int64 `protobuf:"varint,2,opt,name=revert_at,json=revertAt,proto3" json:"revert_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLogLevelResponse) Reset() {
+	*x = SetLogLevelResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelResponse) ProtoMessage() {}
+
+func (x *SetLogLevelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelResponse.ProtoReflect.Descriptor instead.
+func (*SetLogLevelResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SetLogLevelResponse) GetPrevious() string {
+	if x != nil {
+		return x.Previous
+	}
+	return ""
+}
+
+func (x *SetLogLevelResponse) GetRevertAt() int64 {
+	if x != nil {
+		return x.RevertAt
+	}
+	return 0
+}
+
+type GetStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// window_seconds is how far back to report, rounded out to whole hours.
+	// Zero, or more than issuance_stats_retention, reports the whole
+	// retention period.
+	WindowSeconds int64 `protobuf:"varint,1,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	// top_scopes bounds the number of scopes listed. Zero lists 10.
+	TopScopes     int32 `protobuf:"varint,2,opt,name=top_scopes,json=topScopes,proto3" json:"top_scopes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetStatsRequest) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+func (x *GetStatsRequest) GetTopScopes() int32 {
+	if x != nil {
+		return x.TopScopes
+	}
+	return 0
+}
+
+type GetStatsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// start and end are the Unix timestamps the report covers.
+	Start int64 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End   int64 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	// pairs lists every subject→target pair with an exchange in the window,
+	// busiest first.
+	Pairs []*PairStats `protobuf:"bytes,3,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	// top_scopes lists the most granted scopes, most granted first.
+	TopScopes []*ScopeCount `protobuf:"bytes,4,rep,name=top_scopes,json=topScopes,proto3" json:"top_scopes,omitempty"`
+	// ttl_distribution counts grants by TTL, in ascending buckets.
+	TtlDistribution []*TTLCount `protobuf:"bytes,5,rep,name=ttl_distribution,json=ttlDistribution,proto3" json:"ttl_distribution,omitempty"`
+	// overflow counts exchanges not attributed to any pair because the
+	// server's pair limit was reached.
+	Overflow      int64 `protobuf:"varint,6,opt,name=overflow,proto3" json:"overflow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsResponse) Reset() {
+	*x = GetStatsResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsResponse) ProtoMessage() {}
+
+func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetStatsResponse) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *GetStatsResponse) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *GetStatsResponse) GetPairs() []*PairStats {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+func (x *GetStatsResponse) GetTopScopes() []*ScopeCount {
+	if x != nil {
+		return x.TopScopes
+	}
+	return nil
+}
+
+func (x *GetStatsResponse) GetTtlDistribution() []*TTLCount {
+	if x != nil {
+		return x.TtlDistribution
+	}
+	return nil
+}
+
+func (x *GetStatsResponse) GetOverflow() int64 {
+	if x != nil {
+		return x.Overflow
+	}
+	return 0
+}
+
+// PairStats counts the exchanges of one subject→target pair. Denials include
+// those answered from the denial cache.
+type PairStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Target        string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	Grants        int64                  `protobuf:"varint,3,opt,name=grants,proto3" json:"grants,omitempty"`
+	Denials       int64                  `protobuf:"varint,4,opt,name=denials,proto3" json:"denials,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PairStats) Reset() {
+	*x = PairStats{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PairStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PairStats) ProtoMessage() {}
+
+func (x *PairStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PairStats.ProtoReflect.Descriptor instead.
+func (*PairStats) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PairStats) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *PairStats) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PairStats) GetGrants() int64 {
+	if x != nil {
+		return x.Grants
+	}
+	return 0
+}
+
+func (x *PairStats) GetDenials() int64 {
+	if x != nil {
+		return x.Denials
+	}
+	return 0
+}
+
+// ScopeCount counts the grants that included a scope.
+type ScopeCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         string                 `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`
+	Grants        int64                  `protobuf:"varint,2,opt,name=grants,proto3" json:"grants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScopeCount) Reset() {
+	*x = ScopeCount{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScopeCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScopeCount) ProtoMessage() {}
+
+func (x *ScopeCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScopeCount.ProtoReflect.Descriptor instead.
+func (*ScopeCount) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ScopeCount) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *ScopeCount) GetGrants() int64 {
+	if x != nil {
+		return x.Grants
+	}
+	return 0
+}
+
+// TTLCount counts the grants whose TTL is at most max_seconds and above the
+// previous bucket's. max_seconds is 0 for the last, unbounded bucket.
+type TTLCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxSeconds    int32                  `protobuf:"varint,1,opt,name=max_seconds,json=maxSeconds,proto3" json:"max_seconds,omitempty"`
+	Grants        int64                  `protobuf:"varint,2,opt,name=grants,proto3" json:"grants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TTLCount) Reset() {
+	*x = TTLCount{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TTLCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TTLCount) ProtoMessage() {}
+
+func (x *TTLCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TTLCount.ProtoReflect.Descriptor instead.
+func (*TTLCount) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TTLCount) GetMaxSeconds() int32 {
+	if x != nil {
+		return x.MaxSeconds
+	}
+	return 0
+}
+
+func (x *TTLCount) GetGrants() int64 {
+	if x != nil {
+		return x.Grants
+	}
+	return 0
+}
+
+type ListUnusedPoliciesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// days is how long a policy must have gone without a match. Zero means 30.
+	Days          int32 `protobuf:"varint,1,opt,name=days,proto3" json:"days,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUnusedPoliciesRequest) Reset() {
+	*x = ListUnusedPoliciesRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUnusedPoliciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUnusedPoliciesRequest) ProtoMessage() {}
+
+func (x *ListUnusedPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUnusedPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*ListUnusedPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ListUnusedPoliciesRequest) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+type ListUnusedPoliciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Policies      []*UnusedPolicy        `protobuf:"bytes,1,rep,name=policies,proto3" json:"policies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUnusedPoliciesResponse) Reset() {
+	*x = ListUnusedPoliciesResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUnusedPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUnusedPoliciesResponse) ProtoMessage() {}
+
+func (x *ListUnusedPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUnusedPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*ListUnusedPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListUnusedPoliciesResponse) GetPolicies() []*UnusedPolicy {
+	if x != nil {
+		return x.Policies
+	}
+	return nil
+}
+
+// UnusedPolicy is an active policy with no match in the requested period.
+type UnusedPolicy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Rule  *PolicyRule            `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	// source is either "yaml" or "dynamic".
+	Source string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	// last_matched is the Unix timestamp of the policy's last match, or 0 if
+	// it has not matched since tracking began.
+	LastMatched int64 `protobuf:"varint,3,opt,name=last_matched,json=lastMatched,proto3" json:"last_matched,omitempty"`
+	// matches counts the policy's matches since tracking began.
+	Matches int64 `protobuf:"varint,4,opt,name=matches,proto3" json:"matches,omitempty"`
+	// tracked_since is the Unix timestamp at which tracking began.
+	TrackedSince  int64 `protobuf:"varint,5,opt,name=tracked_since,json=trackedSince,proto3" json:"tracked_since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnusedPolicy) Reset() {
+	*x = UnusedPolicy{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnusedPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnusedPolicy) ProtoMessage() {}
+
+func (x *UnusedPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnusedPolicy.ProtoReflect.Descriptor instead.
+func (*UnusedPolicy) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *UnusedPolicy) GetRule() *PolicyRule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+func (x *UnusedPolicy) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *UnusedPolicy) GetLastMatched() int64 {
+	if x != nil {
+		return x.LastMatched
+	}
+	return 0
+}
+
+func (x *UnusedPolicy) GetMatches() int64 {
+	if x != nil {
+		return x.Matches
+	}
+	return 0
+}
+
+func (x *UnusedPolicy) GetTrackedSince() int64 {
+	if x != nil {
+		return x.TrackedSince
+	}
+	return 0
+}
+
+type ListPendingApprovalsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPendingApprovalsRequest) Reset() {
+	*x = ListPendingApprovalsRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPendingApprovalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPendingApprovalsRequest) ProtoMessage() {}
+
+func (x *ListPendingApprovalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPendingApprovalsRequest.ProtoReflect.Descriptor instead.
+func (*ListPendingApprovalsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{32}
+}
+
+type ListPendingApprovalsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Approvals     []*PendingApproval     `protobuf:"bytes,1,rep,name=approvals,proto3" json:"approvals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPendingApprovalsResponse) Reset() {
+	*x = ListPendingApprovalsResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPendingApprovalsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPendingApprovalsResponse) ProtoMessage() {}
+
+func (x *ListPendingApprovalsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPendingApprovalsResponse.ProtoReflect.Descriptor instead.
+func (*ListPendingApprovalsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListPendingApprovalsResponse) GetApprovals() []*PendingApproval {
+	if x != nil {
+		return x.Approvals
+	}
+	return nil
+}
+
+// PendingApproval is an exchange parked until an approver decides it.
+type PendingApproval struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id identifies the exchange in DecideApproval.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// request_id is the exchange's request ID, as in the audit log.
+	RequestId string `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Subject   string `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Target    string `protobuf:"bytes,4,opt,name=target,proto3" json:"target,omitempty"`
+	// scopes and ttl_seconds are what the token will carry if approved.
+	Scopes     []string `protobuf:"bytes,5,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	TtlSeconds int32    `protobuf:"varint,6,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	// policy_rules names the rules that granted the exchange.
+	PolicyRules []string `protobuf:"bytes,7,rep,name=policy_rules,json=policyRules,proto3" json:"policy_rules,omitempty"`
+	// requested_at and expires_at are Unix timestamps; the exchange is denied
+	// if no decision arrives by expires_at.
+	RequestedAt   int64 `protobuf:"varint,8,opt,name=requested_at,json=requestedAt,proto3" json:"requested_at,omitempty"`
+	ExpiresAt     int64 `protobuf:"varint,9,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingApproval) Reset() {
+	*x = PendingApproval{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingApproval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingApproval) ProtoMessage() {}
+
+func (x *PendingApproval) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingApproval.ProtoReflect.Descriptor instead.
+func (*PendingApproval) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *PendingApproval) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PendingApproval) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *PendingApproval) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *PendingApproval) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PendingApproval) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *PendingApproval) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *PendingApproval) GetPolicyRules() []string {
+	if x != nil {
+		return x.PolicyRules
+	}
+	return nil
+}
+
+func (x *PendingApproval) GetRequestedAt() int64 {
+	if x != nil {
+		return x.RequestedAt
+	}
+	return 0
+}
+
+func (x *PendingApproval) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type DecideApprovalRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the PendingApproval id.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// approve grants the exchange; false denies it.
+	Approve bool `protobuf:"varint,2,opt,name=approve,proto3" json:"approve,omitempty"`
+	// reason is recorded in the audit log and, for a denial, returned to the
+	// caller.
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecideApprovalRequest) Reset() {
+	*x = DecideApprovalRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecideApprovalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecideApprovalRequest) ProtoMessage() {}
+
+func (x *DecideApprovalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecideApprovalRequest.ProtoReflect.Descriptor instead.
+func (*DecideApprovalRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DecideApprovalRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DecideApprovalRequest) GetApprove() bool {
+	if x != nil {
+		return x.Approve
+	}
+	return false
+}
+
+func (x *DecideApprovalRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type DecideApprovalResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecideApprovalResponse) Reset() {
+	*x = DecideApprovalResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecideApprovalResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecideApprovalResponse) ProtoMessage() {}
+
+func (x *DecideApprovalResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecideApprovalResponse.ProtoReflect.Descriptor instead.
+func (*DecideApprovalResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{36}
+}
+
+type SetAuditFailureModeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// mode is "open" or "closed". Empty restores the configured mode.
+	Mode          string `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAuditFailureModeRequest) Reset() {
+	*x = SetAuditFailureModeRequest{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAuditFailureModeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAuditFailureModeRequest) ProtoMessage() {}
+
+func (x *SetAuditFailureModeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAuditFailureModeRequest.ProtoReflect.Descriptor instead.
+func (*SetAuditFailureModeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *SetAuditFailureModeRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+type SetAuditFailureModeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// previous is the mode in effect before the call.
+	Previous      string `protobuf:"bytes,1,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAuditFailureModeResponse) Reset() {
+	*x = SetAuditFailureModeResponse{}
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAuditFailureModeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAuditFailureModeResponse) ProtoMessage() {}
+
+func (x *SetAuditFailureModeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_v1_admin_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAuditFailureModeResponse.ProtoReflect.Descriptor instead.
+func (*SetAuditFailureModeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_v1_admin_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *SetAuditFailureModeResponse) GetPrevious() string {
+	if x != nil {
+		return x.Previous
+	}
+	return ""
+}
+
+var File_proto_admin_v1_admin_proto protoreflect.FileDescriptor
+
+const file_proto_admin_v1_admin_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproto/admin/v1/admin.proto\x12\badmin.v1\"\xf5\x03\n" +
+	"\n" +
+	"PolicyRule\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\tR\x06target\x12%\n" +
+	"\x0eallowed_scopes\x18\x04 \x03(\tR\rallowedScopes\x12\x17\n" +
+	"\amax_ttl\x18\x05 \x01(\x05R\x06maxTtl\x12!\n" +
+	"\ftoken_format\x18\x06 \x01(\tR\vtokenFormat\x12#\n" +
+	"\rrequire_nonce\x18\a \x01(\bR\frequireNonce\x12\x1c\n" +
+	"\tcondition\x18\b \x01(\tR\tcondition\x12+\n" +
+	"\x11requires_approval\x18\t \x01(\bR\x10requiresApproval\x12\x1d\n" +
+	"\n" +
+	"single_use\x18\n" +
+	" \x01(\bR\tsingleUse\x12A\n" +
+	"\taudiences\x18\v \x03(\v2#.admin.v1.PolicyRule.AudiencesEntryR\taudiences\x12\x16\n" +
+	"\x06issuer\x18\f \x01(\tR\x06issuer\x1aT\n" +
+	"\x0eAudiencesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.admin.v1.AudienceListR\x05value:\x028\x01\"&\n" +
+	"\fAudienceList\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"?\n" +
+	"\x13CreatePolicyRequest\x12(\n" +
+	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\"@\n" +
+	"\x14CreatePolicyResponse\x12(\n" +
+	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\")\n" +
+	"\x13DeletePolicyRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x16\n" +
+	"\x14DeletePolicyResponse\"\x15\n" +
+	"\x13ListPoliciesRequest\"O\n" +
+	"\vPolicyEntry\x12(\n" +
+	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\"I\n" +
+	"\x14ListPoliciesResponse\x121\n" +
+	"\bpolicies\x18\x01 \x03(\v2\x15.admin.v1.PolicyEntryR\bpolicies\"\x15\n" +
+	"\x13ReloadPolicyRequest\"\x16\n" +
+	"\x14ReloadPolicyResponse\"N\n" +
+	"\x12RevokeTokenRequest\x12\x19\n" +
+	"\btoken_id\x18\x01 \x01(\tR\atokenId\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt\"\x15\n" +
+	"\x13RevokeTokenResponse\"\x1a\n" +
+	"\x18ListRevokedTokensRequest\"H\n" +
+	"\fRevokedToken\x12\x19\n" +
+	"\btoken_id\x18\x01 \x01(\tR\atokenId\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt\"K\n" +
+	"\x19ListRevokedTokensResponse\x12.\n" +
+	"\x06tokens\x18\x01 \x03(\v2\x16.admin.v1.RevokedTokenR\x06tokens\"U\n" +
+	"\x19ActivateBreakGlassRequest\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\fR\bdocument\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"K\n" +
+	"\x1aActivateBreakGlassResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt\"\x1d\n" +
+	"\x1bDeactivateBreakGlassRequest\".\n" +
+	"\x1cDeactivateBreakGlassResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x0e\n" +
+	"\fDrainRequest\"(\n" +
+	"\rDrainResponse\x12\x17\n" +
+	"\aexit_at\x18\x01 \x01(\x03R\x06exitAt\"U\n" +
+	"\x12SetLogLevelRequest\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12)\n" +
+	"\x10duration_seconds\x18\x02 \x01(\x03R\x0fdurationSeconds\"N\n" +
+	"\x13SetLogLevelResponse\x12\x1a\n" +
+	"\bprevious\x18\x01 \x01(\tR\bprevious\x12\x1b\n" +
+	"\trevert_at\x18\x02 \x01(\x03R\brevertAt\"W\n" +
+	"\x0fGetStatsRequest\x12%\n" +
+	"\x0ewindow_seconds\x18\x01 \x01(\x03R\rwindowSeconds\x12\x1d\n" +
+	"\n" +
+	"top_scopes\x18\x02 \x01(\x05R\ttopScopes\"\xf5\x01\n" +
+	"\x10GetStatsResponse\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x03R\x03end\x12)\n" +
+	"\x05pairs\x18\x03 \x03(\v2\x13.admin.v1.PairStatsR\x05pairs\x123\n" +
+	"\n" +
+	"top_scopes\x18\x04 \x03(\v2\x14.admin.v1.ScopeCountR\ttopScopes\x12=\n" +
+	"\x10ttl_distribution\x18\x05 \x03(\v2\x12.admin.v1.TTLCountR\x0fttlDistribution\x12\x1a\n" +
+	"\boverflow\x18\x06 \x01(\x03R\boverflow\"o\n" +
+	"\tPairStats\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12\x16\n" +
+	"\x06grants\x18\x03 \x01(\x03R\x06grants\x12\x18\n" +
+	"\adenials\x18\x04 \x01(\x03R\adenials\":\n" +
+	"\n" +
+	"ScopeCount\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12\x16\n" +
+	"\x06grants\x18\x02 \x01(\x03R\x06grants\"C\n" +
+	"\bTTLCount\x12\x1f\n" +
+	"\vmax_seconds\x18\x01 \x01(\x05R\n" +
+	"maxSeconds\x12\x16\n" +
+	"\x06grants\x18\x02 \x01(\x03R\x06grants\"/\n" +
+	"\x19ListUnusedPoliciesRequest\x12\x12\n" +
+	"\x04days\x18\x01 \x01(\x05R\x04days\"P\n" +
+	"\x1aListUnusedPoliciesResponse\x122\n" +
+	"\bpolicies\x18\x01 \x03(\v2\x16.admin.v1.UnusedPolicyR\bpolicies\"\xb2\x01\n" +
+	"\fUnusedPolicy\x12(\n" +
+	"\x04rule\x18\x01 \x01(\v2\x14.admin.v1.PolicyRuleR\x04rule\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12!\n" +
+	"\flast_matched\x18\x03 \x01(\x03R\vlastMatched\x12\x18\n" +
+	"\amatches\x18\x04 \x01(\x03R\amatches\x12#\n" +
+	"\rtracked_since\x18\x05 \x01(\x03R\ftrackedSince\"\x1d\n" +
+	"\x1bListPendingApprovalsRequest\"W\n" +
+	"\x1cListPendingApprovalsResponse\x127\n" +
+	"\tapprovals\x18\x01 \x03(\v2\x19.admin.v1.PendingApprovalR\tapprovals\"\x90\x02\n" +
+	"\x0fPendingApproval\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\x12\x16\n" +
+	"\x06target\x18\x04 \x01(\tR\x06target\x12\x16\n" +
+	"\x06scopes\x18\x05 \x03(\tR\x06scopes\x12\x1f\n" +
+	"\vttl_seconds\x18\x06 \x01(\x05R\n" +
+	"ttlSeconds\x12!\n" +
+	"\fpolicy_rules\x18\a \x03(\tR\vpolicyRules\x12!\n" +
+	"\frequested_at\x18\b \x01(\x03R\vrequestedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\t \x01(\x03R\texpiresAt\"Y\n" +
+	"\x15DecideApprovalRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aapprove\x18\x02 \x01(\bR\aapprove\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\x18\n" +
+	"\x16DecideApprovalResponse\"0\n" +
+	"\x1aSetAuditFailureModeRequest\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\"9\n" +
+	"\x1bSetAuditFailureModeResponse\x12\x1a\n" +
+	"\bprevious\x18\x01 \x01(\tR\bprevious2\x85\n" +
+	"\n" +
 	"\vPolicyAdmin\x12M\n" +
 	"\fCreatePolicy\x12\x1d.admin.v1.CreatePolicyRequest\x1a\x1e.admin.v1.CreatePolicyResponse\x12M\n" +
 	"\fDeletePolicy\x12\x1d.admin.v1.DeletePolicyRequest\x1a\x1e.admin.v1.DeletePolicyResponse\x12M\n" +
 	"\fListPolicies\x12\x1d.admin.v1.ListPoliciesRequest\x1a\x1e.admin.v1.ListPoliciesResponse\x12M\n" +
 	"\fReloadPolicy\x12\x1d.admin.v1.ReloadPolicyRequest\x1a\x1e.admin.v1.ReloadPolicyResponse\x12J\n" +
 	"\vRevokeToken\x12\x1c.admin.v1.RevokeTokenRequest\x1a\x1d.admin.v1.RevokeTokenResponse\x12\\\n" +
-	"\x11ListRevokedTokens\x12\".admin.v1.ListRevokedTokensRequest\x1a#.admin.v1.ListRevokedTokensResponseB<Z:github.com/ngaddam369/svid-exchange/proto/admin/v1;adminv1b\x06proto3"
+	"\x11ListRevokedTokens\x12\".admin.v1.ListRevokedTokensRequest\x1a#.admin.v1.ListRevokedTokensResponse\x12_\n" +
+	"\x12ActivateBreakGlass\x12#.admin.v1.ActivateBreakGlassRequest\x1a$.admin.v1.ActivateBreakGlassResponse\x12e\n" +
+	"\x14DeactivateBreakGlass\x12%.admin.v1.DeactivateBreakGlassRequest\x1a&.admin.v1.DeactivateBreakGlassResponse\x128\n" +
+	"\x05Drain\x12\x16.admin.v1.DrainRequest\x1a\x17.admin.v1.DrainResponse\x12J\n" +
+	"\vSetLogLevel\x12\x1c.admin.v1.SetLogLevelRequest\x1a\x1d.admin.v1.SetLogLevelResponse\x12A\n" +
+	"\bGetStats\x12\x19.admin.v1.GetStatsRequest\x1a\x1a.admin.v1.GetStatsResponse\x12_\n" +
+	"\x12ListUnusedPolicies\x12#.admin.v1.ListUnusedPoliciesRequest\x1a$.admin.v1.ListUnusedPoliciesResponse\x12e\n" +
+	"\x14ListPendingApprovals\x12%.admin.v1.ListPendingApprovalsRequest\x1a&.admin.v1.ListPendingApprovalsResponse\x12S\n" +
+	"\x0eDecideApproval\x12\x1f.admin.v1.DecideApprovalRequest\x1a .admin.v1.DecideApprovalResponse\x12b\n" +
+	"\x13SetAuditFailureMode\x12$.admin.v1.SetAuditFailureModeRequest\x1a%.admin.v1.SetAuditFailureModeResponseB<Z:github.com/ngaddam369/svid-exchange/proto/admin/v1;adminv1b\x06proto3"
 
 var (
 	file_proto_admin_v1_admin_proto_rawDescOnce sync.Once
@@ -760,47 +2242,98 @@ func file_proto_admin_v1_admin_proto_rawDescGZIP() []byte {
 	return file_proto_admin_v1_admin_proto_rawDescData
 }
 
-var file_proto_admin_v1_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_proto_admin_v1_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 40)
 var file_proto_admin_v1_admin_proto_goTypes = []any{
-	(*PolicyRule)(nil),                // 0: admin.v1.PolicyRule
-	(*CreatePolicyRequest)(nil),       // 1: admin.v1.CreatePolicyRequest
-	(*CreatePolicyResponse)(nil),      // 2: admin.v1.CreatePolicyResponse
-	(*DeletePolicyRequest)(nil),       // 3: admin.v1.DeletePolicyRequest
-	(*DeletePolicyResponse)(nil),      // 4: admin.v1.DeletePolicyResponse
-	(*ListPoliciesRequest)(nil),       // 5: admin.v1.ListPoliciesRequest
-	(*PolicyEntry)(nil),               // 6: admin.v1.PolicyEntry
-	(*ListPoliciesResponse)(nil),      // 7: admin.v1.ListPoliciesResponse
-	(*ReloadPolicyRequest)(nil),       // 8: admin.v1.ReloadPolicyRequest
-	(*ReloadPolicyResponse)(nil),      // 9: admin.v1.ReloadPolicyResponse
-	(*RevokeTokenRequest)(nil),        // 10: admin.v1.RevokeTokenRequest
-	(*RevokeTokenResponse)(nil),       // 11: admin.v1.RevokeTokenResponse
-	(*ListRevokedTokensRequest)(nil),  // 12: admin.v1.ListRevokedTokensRequest
-	(*RevokedToken)(nil),              // 13: admin.v1.RevokedToken
-	(*ListRevokedTokensResponse)(nil), // 14: admin.v1.ListRevokedTokensResponse
+	(*PolicyRule)(nil),                   // 0: admin.v1.PolicyRule
+	(*AudienceList)(nil),                 // 1: admin.v1.AudienceList
+	(*CreatePolicyRequest)(nil),          // 2: admin.v1.CreatePolicyRequest
+	(*CreatePolicyResponse)(nil),         // 3: admin.v1.CreatePolicyResponse
+	(*DeletePolicyRequest)(nil),          // 4: admin.v1.DeletePolicyRequest
+	(*DeletePolicyResponse)(nil),         // 5: admin.v1.DeletePolicyResponse
+	(*ListPoliciesRequest)(nil),          // 6: admin.v1.ListPoliciesRequest
+	(*PolicyEntry)(nil),                  // 7: admin.v1.PolicyEntry
+	(*ListPoliciesResponse)(nil),         // 8: admin.v1.ListPoliciesResponse
+	(*ReloadPolicyRequest)(nil),          // 9: admin.v1.ReloadPolicyRequest
+	(*ReloadPolicyResponse)(nil),         // 10: admin.v1.ReloadPolicyResponse
+	(*RevokeTokenRequest)(nil),           // 11: admin.v1.RevokeTokenRequest
+	(*RevokeTokenResponse)(nil),          // 12: admin.v1.RevokeTokenResponse
+	(*ListRevokedTokensRequest)(nil),     // 13: admin.v1.ListRevokedTokensRequest
+	(*RevokedToken)(nil),                 // 14: admin.v1.RevokedToken
+	(*ListRevokedTokensResponse)(nil),    // 15: admin.v1.ListRevokedTokensResponse
+	(*ActivateBreakGlassRequest)(nil),    // 16: admin.v1.ActivateBreakGlassRequest
+	(*ActivateBreakGlassResponse)(nil),   // 17: admin.v1.ActivateBreakGlassResponse
+	(*DeactivateBreakGlassRequest)(nil),  // 18: admin.v1.DeactivateBreakGlassRequest
+	(*DeactivateBreakGlassResponse)(nil), // 19: admin.v1.DeactivateBreakGlassResponse
+	(*DrainRequest)(nil),                 // 20: admin.v1.DrainRequest
+	(*DrainResponse)(nil),                // 21: admin.v1.DrainResponse
+	(*SetLogLevelRequest)(nil),           // 22: admin.v1.SetLogLevelRequest
+	(*SetLogLevelResponse)(nil),          // 23: admin.v1.SetLogLevelResponse
+	(*GetStatsRequest)(nil),              // 24: admin.v1.GetStatsRequest
+	(*GetStatsResponse)(nil),             // 25: admin.v1.GetStatsResponse
+	(*PairStats)(nil),                    // 26: admin.v1.PairStats
+	(*ScopeCount)(nil),                   // 27: admin.v1.ScopeCount
+	(*TTLCount)(nil),                     // 28: admin.v1.TTLCount
+	(*ListUnusedPoliciesRequest)(nil),    // 29: admin.v1.ListUnusedPoliciesRequest
+	(*ListUnusedPoliciesResponse)(nil),   // 30: admin.v1.ListUnusedPoliciesResponse
+	(*UnusedPolicy)(nil),                 // 31: admin.v1.UnusedPolicy
+	(*ListPendingApprovalsRequest)(nil),  // 32: admin.v1.ListPendingApprovalsRequest
+	(*ListPendingApprovalsResponse)(nil), // 33: admin.v1.ListPendingApprovalsResponse
+	(*PendingApproval)(nil),              // 34: admin.v1.PendingApproval
+	(*DecideApprovalRequest)(nil),        // 35: admin.v1.DecideApprovalRequest
+	(*DecideApprovalResponse)(nil),       // 36: admin.v1.DecideApprovalResponse
+	(*SetAuditFailureModeRequest)(nil),   // 37: admin.v1.SetAuditFailureModeRequest
+	(*SetAuditFailureModeResponse)(nil),  // 38: admin.v1.SetAuditFailureModeResponse
+	nil,                                  // 39: admin.v1.PolicyRule.AudiencesEntry
 }
 var file_proto_admin_v1_admin_proto_depIdxs = []int32{
-	0,  // 0: admin.v1.CreatePolicyRequest.rule:type_name -> admin.v1.PolicyRule
-	0,  // 1: admin.v1.CreatePolicyResponse.rule:type_name -> admin.v1.PolicyRule
-	0,  // 2: admin.v1.PolicyEntry.rule:type_name -> admin.v1.PolicyRule
-	6,  // 3: admin.v1.ListPoliciesResponse.policies:type_name -> admin.v1.PolicyEntry
-	13, // 4: admin.v1.ListRevokedTokensResponse.tokens:type_name -> admin.v1.RevokedToken
-	1,  // 5: admin.v1.PolicyAdmin.CreatePolicy:input_type -> admin.v1.CreatePolicyRequest
-	3,  // 6: admin.v1.PolicyAdmin.DeletePolicy:input_type -> admin.v1.DeletePolicyRequest
-	5,  // 7: admin.v1.PolicyAdmin.ListPolicies:input_type -> admin.v1.ListPoliciesRequest
-	8,  // 8: admin.v1.PolicyAdmin.ReloadPolicy:input_type -> admin.v1.ReloadPolicyRequest
-	10, // 9: admin.v1.PolicyAdmin.RevokeToken:input_type -> admin.v1.RevokeTokenRequest
-	12, // 10: admin.v1.PolicyAdmin.ListRevokedTokens:input_type -> admin.v1.ListRevokedTokensRequest
-	2,  // 11: admin.v1.PolicyAdmin.CreatePolicy:output_type -> admin.v1.CreatePolicyResponse
-	4,  // 12: admin.v1.PolicyAdmin.DeletePolicy:output_type -> admin.v1.DeletePolicyResponse
-	7,  // 13: admin.v1.PolicyAdmin.ListPolicies:output_type -> admin.v1.ListPoliciesResponse
-	9,  // 14: admin.v1.PolicyAdmin.ReloadPolicy:output_type -> admin.v1.ReloadPolicyResponse
-	11, // 15: admin.v1.PolicyAdmin.RevokeToken:output_type -> admin.v1.RevokeTokenResponse
-	14, // 16: admin.v1.PolicyAdmin.ListRevokedTokens:output_type -> admin.v1.ListRevokedTokensResponse
-	11, // [11:17] is the sub-list for method output_type
-	5,  // [5:11] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	39, // 0: admin.v1.PolicyRule.audiences:type_name -> admin.v1.PolicyRule.AudiencesEntry
+	0,  // 1: admin.v1.CreatePolicyRequest.rule:type_name -> admin.v1.PolicyRule
+	0,  // 2: admin.v1.CreatePolicyResponse.rule:type_name -> admin.v1.PolicyRule
+	0,  // 3: admin.v1.PolicyEntry.rule:type_name -> admin.v1.PolicyRule
+	7,  // 4: admin.v1.ListPoliciesResponse.policies:type_name -> admin.v1.PolicyEntry
+	14, // 5: admin.v1.ListRevokedTokensResponse.tokens:type_name -> admin.v1.RevokedToken
+	26, // 6: admin.v1.GetStatsResponse.pairs:type_name -> admin.v1.PairStats
+	27, // 7: admin.v1.GetStatsResponse.top_scopes:type_name -> admin.v1.ScopeCount
+	28, // 8: admin.v1.GetStatsResponse.ttl_distribution:type_name -> admin.v1.TTLCount
+	31, // 9: admin.v1.ListUnusedPoliciesResponse.policies:type_name -> admin.v1.UnusedPolicy
+	0,  // 10: admin.v1.UnusedPolicy.rule:type_name -> admin.v1.PolicyRule
+	34, // 11: admin.v1.ListPendingApprovalsResponse.approvals:type_name -> admin.v1.PendingApproval
+	1,  // 12: admin.v1.PolicyRule.AudiencesEntry.value:type_name -> admin.v1.AudienceList
+	2,  // 13: admin.v1.PolicyAdmin.CreatePolicy:input_type -> admin.v1.CreatePolicyRequest
+	4,  // 14: admin.v1.PolicyAdmin.DeletePolicy:input_type -> admin.v1.DeletePolicyRequest
+	6,  // 15: admin.v1.PolicyAdmin.ListPolicies:input_type -> admin.v1.ListPoliciesRequest
+	9,  // 16: admin.v1.PolicyAdmin.ReloadPolicy:input_type -> admin.v1.ReloadPolicyRequest
+	11, // 17: admin.v1.PolicyAdmin.RevokeToken:input_type -> admin.v1.RevokeTokenRequest
+	13, // 18: admin.v1.PolicyAdmin.ListRevokedTokens:input_type -> admin.v1.ListRevokedTokensRequest
+	16, // 19: admin.v1.PolicyAdmin.ActivateBreakGlass:input_type -> admin.v1.ActivateBreakGlassRequest
+	18, // 20: admin.v1.PolicyAdmin.DeactivateBreakGlass:input_type -> admin.v1.DeactivateBreakGlassRequest
+	20, // 21: admin.v1.PolicyAdmin.Drain:input_type -> admin.v1.DrainRequest
+	22, // 22: admin.v1.PolicyAdmin.SetLogLevel:input_type -> admin.v1.SetLogLevelRequest
+	24, // 23: admin.v1.PolicyAdmin.GetStats:input_type -> admin.v1.GetStatsRequest
+	29, // 24: admin.v1.PolicyAdmin.ListUnusedPolicies:input_type -> admin.v1.ListUnusedPoliciesRequest
+	32, // 25: admin.v1.PolicyAdmin.ListPendingApprovals:input_type -> admin.v1.ListPendingApprovalsRequest
+	35, // 26: admin.v1.PolicyAdmin.DecideApproval:input_type -> admin.v1.DecideApprovalRequest
+	37, // 27: admin.v1.PolicyAdmin.SetAuditFailureMode:input_type -> admin.v1.SetAuditFailureModeRequest
+	3,  // 28: admin.v1.PolicyAdmin.CreatePolicy:output_type -> admin.v1.CreatePolicyResponse
+	5,  // 29: admin.v1.PolicyAdmin.DeletePolicy:output_type -> admin.v1.DeletePolicyResponse
+	8,  // 30: admin.v1.PolicyAdmin.ListPolicies:output_type -> admin.v1.ListPoliciesResponse
+	10, // 31: admin.v1.PolicyAdmin.ReloadPolicy:output_type -> admin.v1.ReloadPolicyResponse
+	12, // 32: admin.v1.PolicyAdmin.RevokeToken:output_type -> admin.v1.RevokeTokenResponse
+	15, // 33: admin.v1.PolicyAdmin.ListRevokedTokens:output_type -> admin.v1.ListRevokedTokensResponse
+	17, // 34: admin.v1.PolicyAdmin.ActivateBreakGlass:output_type -> admin.v1.ActivateBreakGlassResponse
+	19, // 35: admin.v1.PolicyAdmin.DeactivateBreakGlass:output_type -> admin.v1.DeactivateBreakGlassResponse
+	21, // 36: admin.v1.PolicyAdmin.Drain:output_type -> admin.v1.DrainResponse
+	23, // 37: admin.v1.PolicyAdmin.SetLogLevel:output_type -> admin.v1.SetLogLevelResponse
+	25, // 38: admin.v1.PolicyAdmin.GetStats:output_type -> admin.v1.GetStatsResponse
+	30, // 39: admin.v1.PolicyAdmin.ListUnusedPolicies:output_type -> admin.v1.ListUnusedPoliciesResponse
+	33, // 40: admin.v1.PolicyAdmin.ListPendingApprovals:output_type -> admin.v1.ListPendingApprovalsResponse
+	36, // 41: admin.v1.PolicyAdmin.DecideApproval:output_type -> admin.v1.DecideApprovalResponse
+	38, // 42: admin.v1.PolicyAdmin.SetAuditFailureMode:output_type -> admin.v1.SetAuditFailureModeResponse
+	28, // [28:43] is the sub-list for method output_type
+	13, // [13:28] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_proto_admin_v1_admin_proto_init() }
@@ -814,7 +2347,7 @@ func file_proto_admin_v1_admin_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_admin_v1_admin_proto_rawDesc), len(file_proto_admin_v1_admin_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   40,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,12 +19,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PolicyAdmin_CreatePolicy_FullMethodName      = "/admin.v1.PolicyAdmin/CreatePolicy"
-	PolicyAdmin_DeletePolicy_FullMethodName      = "/admin.v1.PolicyAdmin/DeletePolicy"
-	PolicyAdmin_ListPolicies_FullMethodName      = "/admin.v1.PolicyAdmin/ListPolicies"
-	PolicyAdmin_ReloadPolicy_FullMethodName      = "/admin.v1.PolicyAdmin/ReloadPolicy"
-	PolicyAdmin_RevokeToken_FullMethodName       = "/admin.v1.PolicyAdmin/RevokeToken"
-	PolicyAdmin_ListRevokedTokens_FullMethodName = "/admin.v1.PolicyAdmin/ListRevokedTokens"
+	PolicyAdmin_CreatePolicy_FullMethodName         = "/admin.v1.PolicyAdmin/CreatePolicy"
+	PolicyAdmin_DeletePolicy_FullMethodName         = "/admin.v1.PolicyAdmin/DeletePolicy"
+	PolicyAdmin_ListPolicies_FullMethodName         = "/admin.v1.PolicyAdmin/ListPolicies"
+	PolicyAdmin_ReloadPolicy_FullMethodName         = "/admin.v1.PolicyAdmin/ReloadPolicy"
+	PolicyAdmin_RevokeToken_FullMethodName          = "/admin.v1.PolicyAdmin/RevokeToken"
+	PolicyAdmin_ListRevokedTokens_FullMethodName    = "/admin.v1.PolicyAdmin/ListRevokedTokens"
+	PolicyAdmin_ActivateBreakGlass_FullMethodName   = "/admin.v1.PolicyAdmin/ActivateBreakGlass"
+	PolicyAdmin_DeactivateBreakGlass_FullMethodName = "/admin.v1.PolicyAdmin/DeactivateBreakGlass"
+	PolicyAdmin_Drain_FullMethodName                = "/admin.v1.PolicyAdmin/Drain"
+	PolicyAdmin_SetLogLevel_FullMethodName          = "/admin.v1.PolicyAdmin/SetLogLevel"
+	PolicyAdmin_GetStats_FullMethodName             = "/admin.v1.PolicyAdmin/GetStats"
+	PolicyAdmin_ListUnusedPolicies_FullMethodName   = "/adminv1.PolicyAdmin/ListUnusedPolicies"
+	PolicyAdmin_ListPendingApprovals_FullMethodName = "/admin.v1.PolicyAdmin/ListPendingApprovals"
+	PolicyAdmin_DecideApproval_FullMethodName       = "/admin.v1.PolicyAdmin/DecideApproval"
+	PolicyAdmin_SetAuditFailureMode_FullMethodName  = "/admin.v1.PolicyAdmin/SetAuditFailureMode"
 )
 
 // PolicyAdminClient is the client API for PolicyAdmin service.
@@ -59,6 +68,55 @@ type PolicyAdminClient interface {
 	// ListRevokedTokens returns all tokens that have been explicitly revoked and
 	// have not yet reached their natural expiry.
 	ListRevokedTokens(ctx context.Context, in *ListRevokedTokensRequest, opts ...grpc.CallOption) (*ListRevokedTokensResponse, error)
+	// ActivateBreakGlass puts a signed emergency override in force until its
+	// expires_at, replacing any override already active. Requests the regular
+	// policy denies are then granted if the override permits them. Returns
+	// FAILED_PRECONDITION unless break_glass_public_key is configured, and
+	// INVALID_ARGUMENT if the document fails verification.
+	ActivateBreakGlass(ctx context.Context, in *ActivateBreakGlassRequest, opts ...grpc.CallOption) (*ActivateBreakGlassResponse, error)
+	// DeactivateBreakGlass ends the active override before its expiry.
+	// Returns NOT_FOUND if no override is active.
+	DeactivateBreakGlass(ctx context.Context, in *DeactivateBreakGlassRequest, opts ...grpc.CallOption) (*DeactivateBreakGlassResponse, error)
+	// Drain takes the instance out of service, as SIGTERM does: /health/ready
+	// turns not ready, new exchanges are refused with UNAVAILABLE and a retry
+	// hint, and after drain_period the in-flight calls are finished and the
+	// process exits. Calling it again while draining returns the same exit
+	// time.
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error)
+	// SetLogLevel changes the server log level at runtime, for example to
+	// debug while investigating an incident, without a restart. With
+	// duration_seconds set the configured level is restored after that long.
+	// The audit log is not affected. Returns INVALID_ARGUMENT for an unknown
+	// level or a negative duration.
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error)
+	// GetStats summarizes token issuance over a recent window for periodic
+	// access reviews: grants and denials per subject→target pair, the most
+	// granted scopes, and the distribution of granted TTLs. Counts are kept in
+	// memory in hourly buckets since the server started. Returns
+	// FAILED_PRECONDITION unless issuance_stats is enabled.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// ListUnusedPolicies lists the active policies that have not authorized a
+	// granted exchange in the last days days, so stale access can be pruned.
+	// A policy is listed only once it has been tracked for that long. Match
+	// counters are persisted in the policy store and survive restarts.
+	// Returns FAILED_PRECONDITION unless rule_usage_tracking is enabled.
+	ListUnusedPolicies(ctx context.Context, in *ListUnusedPoliciesRequest, opts ...grpc.CallOption) (*ListUnusedPoliciesResponse, error)
+	// ListPendingApprovals lists the exchanges parked by requires_approval
+	// policies that still await a decision, oldest first. Returns
+	// FAILED_PRECONDITION unless approvals are enabled.
+	ListPendingApprovals(ctx context.Context, in *ListPendingApprovalsRequest, opts ...grpc.CallOption) (*ListPendingApprovalsResponse, error)
+	// DecideApproval grants or denies a parked exchange. The caller's SPIFFE
+	// ID is recorded as the approver in the audit log. Returns NOT_FOUND if
+	// the exchange is no longer pending: it was already decided, it timed
+	// out, or its caller gave up.
+	DecideApproval(ctx context.Context, in *DecideApprovalRequest, opts ...grpc.CallOption) (*DecideApprovalResponse, error)
+	// SetAuditFailureMode switches, at runtime, what happens to a grant whose
+	// audit event cannot be written to the audit spool: "closed" fails the
+	// exchange with UNAVAILABLE, "open" returns the token and delivers the event
+	// to the sinks directly. Empty restores the configured mode. Returns
+	// FAILED_PRECONDITION unless audit_spool_dir is set, and INVALID_ARGUMENT for
+	// an unknown mode.
+	SetAuditFailureMode(ctx context.Context, in *SetAuditFailureModeRequest, opts ...grpc.CallOption) (*SetAuditFailureModeResponse, error)
 }
 
 type policyAdminClient struct {
@@ -129,6 +187,96 @@ func (c *policyAdminClient) ListRevokedTokens(ctx context.Context, in *ListRevok
 	return out, nil
 }
 
+func (c *policyAdminClient) ActivateBreakGlass(ctx context.Context, in *ActivateBreakGlassRequest, opts ...grpc.CallOption) (*ActivateBreakGlassResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ActivateBreakGlassResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_ActivateBreakGlass_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) DeactivateBreakGlass(ctx context.Context, in *DeactivateBreakGlassRequest, opts ...grpc.CallOption) (*DeactivateBreakGlassResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeactivateBreakGlassResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_DeactivateBreakGlass_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_Drain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLogLevelResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) ListUnusedPolicies(ctx context.Context, in *ListUnusedPoliciesRequest, opts ...grpc.CallOption) (*ListUnusedPoliciesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUnusedPoliciesResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_ListUnusedPolicies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) ListPendingApprovals(ctx context.Context, in *ListPendingApprovalsRequest, opts ...grpc.CallOption) (*ListPendingApprovalsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPendingApprovalsResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_ListPendingApprovals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) DecideApproval(ctx context.Context, in *DecideApprovalRequest, opts ...grpc.CallOption) (*DecideApprovalResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DecideApprovalResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_DecideApproval_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyAdminClient) SetAuditFailureMode(ctx context.Context, in *SetAuditFailureModeRequest, opts ...grpc.CallOption) (*SetAuditFailureModeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAuditFailureModeResponse)
+	err := c.cc.Invoke(ctx, PolicyAdmin_SetAuditFailureMode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PolicyAdminServer is the server API for PolicyAdmin service.
 // All implementations must embed UnimplementedPolicyAdminServer
 // for forward compatibility.
@@ -161,6 +309,55 @@ type PolicyAdminServer interface {
 	// ListRevokedTokens returns all tokens that have been explicitly revoked and
 	// have not yet reached their natural expiry.
 	ListRevokedTokens(context.Context, *ListRevokedTokensRequest) (*ListRevokedTokensResponse, error)
+	// ActivateBreakGlass puts a signed emergency override in force until its
+	// expires_at, replacing any override already active. Requests the regular
+	// policy denies are then granted if the override permits them. Returns
+	// FAILED_PRECONDITION unless break_glass_public_key is configured, and
+	// INVALID_ARGUMENT if the document fails verification.
+	ActivateBreakGlass(context.Context, *ActivateBreakGlassRequest) (*ActivateBreakGlassResponse, error)
+	// DeactivateBreakGlass ends the active override before its expiry.
+	// Returns NOT_FOUND if no override is active.
+	DeactivateBreakGlass(context.Context, *DeactivateBreakGlassRequest) (*DeactivateBreakGlassResponse, error)
+	// Drain takes the instance out of service, as SIGTERM does: /health/ready
+	// turns not ready, new exchanges are refused with UNAVAILABLE and a retry
+	// hint, and after drain_period the in-flight calls are finished and the
+	// process exits. Calling it again while draining returns the same exit
+	// time.
+	Drain(context.Context, *DrainRequest) (*DrainResponse, error)
+	// SetLogLevel changes the server log level at runtime, for example to
+	// debug while investigating an incident, without a restart. With
+	// duration_seconds set the configured level is restored after that long.
+	// The audit log is not affected. Returns INVALID_ARGUMENT for an unknown
+	// level or a negative duration.
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error)
+	// GetStats summarizes token issuance over a recent window for periodic
+	// access reviews: grants and denials per subject→target pair, the most
+	// granted scopes, and the distribution of granted TTLs. Counts are kept in
+	// memory in hourly buckets since the server started. Returns
+	// FAILED_PRECONDITION unless issuance_stats is enabled.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// ListUnusedPolicies lists the active policies that have not authorized a
+	// granted exchange in the last days days, so stale access can be pruned.
+	// A policy is listed only once it has been tracked for that long. Match
+	// counters are persisted in the policy store and survive restarts.
+	// Returns FAILED_PRECONDITION unless rule_usage_tracking is enabled.
+	ListUnusedPolicies(context.Context, *ListUnusedPoliciesRequest) (*ListUnusedPoliciesResponse, error)
+	// ListPendingApprovals lists the exchanges parked by requires_approval
+	// policies that still await a decision, oldest first. Returns
+	// FAILED_PRECONDITION unless approvals are enabled.
+	ListPendingApprovals(context.Context, *ListPendingApprovalsRequest) (*ListPendingApprovalsResponse, error)
+	// DecideApproval grants or denies a parked exchange. The caller's SPIFFE
+	// ID is recorded as the approver in the audit log. Returns NOT_FOUND if
+	// the exchange is no longer pending: it was already decided, it timed
+	// out, or its caller gave up.
+	DecideApproval(context.Context, *DecideApprovalRequest) (*DecideApprovalResponse, error)
+	// SetAuditFailureMode switches, at runtime, what happens to a grant whose
+	// audit event cannot be written to the audit spool: "closed" fails the
+	// exchange with UNAVAILABLE, "open" returns the token and delivers the event
+	// to the sinks directly. Empty restores the configured mode. Returns
+	// FAILED_PRECONDITION unless audit_spool_dir is set, and INVALID_ARGUMENT for
+	// an unknown mode.
+	SetAuditFailureMode(context.Context, *SetAuditFailureModeRequest) (*SetAuditFailureModeResponse, error)
 	mustEmbedUnimplementedPolicyAdminServer()
 }
 
@@ -189,6 +386,33 @@ func (UnimplementedPolicyAdminServer) RevokeToken(context.Context, *RevokeTokenR
 func (UnimplementedPolicyAdminServer) ListRevokedTokens(context.Context, *ListRevokedTokensRequest) (*ListRevokedTokensResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListRevokedTokens not implemented")
 }
+func (UnimplementedPolicyAdminServer) ActivateBreakGlass(context.Context, *ActivateBreakGlassRequest) (*ActivateBreakGlassResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ActivateBreakGlass not implemented")
+}
+func (UnimplementedPolicyAdminServer) DeactivateBreakGlass(context.Context, *DeactivateBreakGlassRequest) (*DeactivateBreakGlassResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeactivateBreakGlass not implemented")
+}
+func (UnimplementedPolicyAdminServer) Drain(context.Context, *DrainRequest) (*DrainResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedPolicyAdminServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedPolicyAdminServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedPolicyAdminServer) ListUnusedPolicies(context.Context, *ListUnusedPoliciesRequest) (*ListUnusedPoliciesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListUnusedPolicies not implemented")
+}
+func (UnimplementedPolicyAdminServer) ListPendingApprovals(context.Context, *ListPendingApprovalsRequest) (*ListPendingApprovalsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPendingApprovals not implemented")
+}
+func (UnimplementedPolicyAdminServer) DecideApproval(context.Context, *DecideApprovalRequest) (*DecideApprovalResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DecideApproval not implemented")
+}
+func (UnimplementedPolicyAdminServer) SetAuditFailureMode(context.Context, *SetAuditFailureModeRequest) (*SetAuditFailureModeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetAuditFailureMode not implemented")
+}
 func (UnimplementedPolicyAdminServer) mustEmbedUnimplementedPolicyAdminServer() {}
 func (UnimplementedPolicyAdminServer) testEmbeddedByValue()                     {}
 
@@ -318,6 +542,168 @@ func _PolicyAdmin_ListRevokedTokens_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PolicyAdmin_ActivateBreakGlass_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActivateBreakGlassRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).ActivateBreakGlass(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_ActivateBreakGlass_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).ActivateBreakGlass(ctx, req.(*ActivateBreakGlassRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_DeactivateBreakGlass_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeactivateBreakGlassRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).DeactivateBreakGlass(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_DeactivateBreakGlass_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).DeactivateBreakGlass(ctx, req.(*DeactivateBreakGlassRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_ListUnusedPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUnusedPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).ListUnusedPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_ListUnusedPolicies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).ListUnusedPolicies(ctx, req.(*ListUnusedPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_ListPendingApprovals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPendingApprovalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).ListPendingApprovals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_ListPendingApprovals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).ListPendingApprovals(ctx, req.(*ListPendingApprovalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_DecideApproval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecideApprovalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).DecideApproval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_DecideApproval_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).DecideApproval(ctx, req.(*DecideApprovalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyAdmin_SetAuditFailureMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAuditFailureModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyAdminServer).SetAuditFailureMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyAdmin_SetAuditFailureMode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyAdminServer).SetAuditFailureMode(ctx, req.(*SetAuditFailureModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PolicyAdmin_ServiceDesc is the grpc.ServiceDesc for PolicyAdmin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -349,6 +735,42 @@ var PolicyAdmin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListRevokedTokens",
 			Handler:    _PolicyAdmin_ListRevokedTokens_Handler,
 		},
+		{
+			MethodName: "ActivateBreakGlass",
+			Handler:    _PolicyAdmin_ActivateBreakGlass_Handler,
+		},
+		{
+			MethodName: "DeactivateBreakGlass",
+			Handler:    _PolicyAdmin_DeactivateBreakGlass_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _PolicyAdmin_Drain_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _PolicyAdmin_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _PolicyAdmin_GetStats_Handler,
+		},
+		{
+			MethodName: "ListUnusedPolicies",
+			Handler:    _PolicyAdmin_ListUnusedPolicies_Handler,
+		},
+		{
+			MethodName: "ListPendingApprovals",
+			Handler:    _PolicyAdmin_ListPendingApprovals_Handler,
+		},
+		{
+			MethodName: "DecideApproval",
+			Handler:    _PolicyAdmin_DecideApproval_Handler,
+		},
+		{
+			MethodName: "SetAuditFailureMode",
+			Handler:    _PolicyAdmin_SetAuditFailureMode_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/admin/v1/admin.proto",
@@ -0,0 +1,426 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: proto/audit/v1/audit.proto
+
+package auditv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ExchangeEvent is one token exchange decision, as written by the protobuf
+// audit sink (audit_proto_file). It carries the information of a
+// "token.exchange" audit log line, redacted the same way. Fields are only
+// ever added, never renumbered or reused, so a consumer built against an
+// older schema reads newer events and ignores what it does not know.
+// schema_version is raised only when the meaning of an existing field
+// changes.
+type ExchangeEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// schema_version is the version of this schema the event was written
+	// with. Currently 1.
+	SchemaVersion uint32 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// time is when the event was recorded, as a Unix timestamp.
+	Time int64 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	// request_id correlates the event with the server's response header and
+	// error message for the same call.
+	RequestId string `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// subject is the caller's SPIFFE ID, empty when it was not authenticated.
+	Subject string `protobuf:"bytes,4,opt,name=subject,proto3" json:"subject,omitempty"`
+	// target is the SPIFFE ID of the service the token was requested for.
+	Target          string   `protobuf:"bytes,5,opt,name=target,proto3" json:"target,omitempty"`
+	ScopesRequested []string `protobuf:"bytes,6,rep,name=scopes_requested,json=scopesRequested,proto3" json:"scopes_requested,omitempty"`
+	// scopes_granted, ttl_seconds, and token_id are set on grants. A granted
+	// preflight has no token_id.
+	ScopesGranted []string `protobuf:"bytes,7,rep,name=scopes_granted,json=scopesGranted,proto3" json:"scopes_granted,omitempty"`
+	Granted       bool     `protobuf:"varint,8,opt,name=granted,proto3" json:"granted,omitempty"`
+	TtlSeconds    int32    `protobuf:"varint,9,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	TokenId       string   `protobuf:"bytes,10,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`
+	// denial_reason explains a denial.
+	DenialReason string `protobuf:"bytes,11,opt,name=denial_reason,json=denialReason,proto3" json:"denial_reason,omitempty"`
+	// policy_rules names the policy rules that authorized the scopes.
+	PolicyRules []string `protobuf:"bytes,12,rep,name=policy_rules,json=policyRules,proto3" json:"policy_rules,omitempty"`
+	// auth_method names how the subject was authenticated, e.g. "x509-svid".
+	AuthMethod string `protobuf:"bytes,13,opt,name=auth_method,json=authMethod,proto3" json:"auth_method,omitempty"`
+	// cert identifies the certificate the subject authenticated with. Unset
+	// for token methods.
+	Cert *CertInfo `protobuf:"bytes,14,opt,name=cert,proto3" json:"cert,omitempty"`
+	// suppressed_denials counts identical requests denied from the denial
+	// cache, without their own event, since the previous event for this
+	// request.
+	SuppressedDenials int32 `protobuf:"varint,15,opt,name=suppressed_denials,json=suppressedDenials,proto3" json:"suppressed_denials,omitempty"`
+	// preflight marks an exchange evaluated without minting a token.
+	Preflight bool `protobuf:"varint,16,opt,name=preflight,proto3" json:"preflight,omitempty"`
+	// ttl_clamped_from is the TTL policy granted before max_token_ttl cut it
+	// down to ttl_seconds. Zero when the grant was not clamped.
+	TtlClampedFrom int32 `protobuf:"varint,17,opt,name=ttl_clamped_from,json=ttlClampedFrom,proto3" json:"ttl_clamped_from,omitempty"`
+	// trace_id is the hex OpenTelemetry trace ID of the Exchange call, when
+	// it was traced.
+	TraceId string `protobuf:"bytes,18,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	// approver is the SPIFFE ID, or "webhook", of whoever decided an
+	// exchange parked by a requires_approval policy.
+	Approver string `protobuf:"bytes,19,opt,name=approver,proto3" json:"approver,omitempty"`
+	// metadata holds the caller's values of the gRPC metadata keys listed in
+	// audit_metadata_keys, such as a client build version, keyed by the
+	// lowercase metadata key. Keys the caller did not send are absent.
+	Metadata      map[string]string `protobuf:"bytes,20,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExchangeEvent) Reset() {
+	*x = ExchangeEvent{}
+	mi := &file_proto_audit_v1_audit_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExchangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExchangeEvent) ProtoMessage() {}
+
+func (x *ExchangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_audit_v1_audit_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExchangeEvent.ProtoReflect.Descriptor instead.
+func (*ExchangeEvent) Descriptor() ([]byte, []int) {
+	return file_proto_audit_v1_audit_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExchangeEvent) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *ExchangeEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *ExchangeEvent) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetScopesRequested() []string {
+	if x != nil {
+		return x.ScopesRequested
+	}
+	return nil
+}
+
+func (x *ExchangeEvent) GetScopesGranted() []string {
+	if x != nil {
+		return x.ScopesGranted
+	}
+	return nil
+}
+
+func (x *ExchangeEvent) GetGranted() bool {
+	if x != nil {
+		return x.Granted
+	}
+	return false
+}
+
+func (x *ExchangeEvent) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *ExchangeEvent) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetDenialReason() string {
+	if x != nil {
+		return x.DenialReason
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetPolicyRules() []string {
+	if x != nil {
+		return x.PolicyRules
+	}
+	return nil
+}
+
+func (x *ExchangeEvent) GetAuthMethod() string {
+	if x != nil {
+		return x.AuthMethod
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetCert() *CertInfo {
+	if x != nil {
+		return x.Cert
+	}
+	return nil
+}
+
+func (x *ExchangeEvent) GetSuppressedDenials() int32 {
+	if x != nil {
+		return x.SuppressedDenials
+	}
+	return 0
+}
+
+func (x *ExchangeEvent) GetPreflight() bool {
+	if x != nil {
+		return x.Preflight
+	}
+	return false
+}
+
+func (x *ExchangeEvent) GetTtlClampedFrom() int32 {
+	if x != nil {
+		return x.TtlClampedFrom
+	}
+	return 0
+}
+
+func (x *ExchangeEvent) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetApprover() string {
+	if x != nil {
+		return x.Approver
+	}
+	return ""
+}
+
+func (x *ExchangeEvent) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// CertInfo identifies a single certificate issuance.
+type CertInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// serial is the certificate serial number in hex.
+	Serial string `protobuf:"bytes,1,opt,name=serial,proto3" json:"serial,omitempty"`
+	// fingerprint is the hex SHA-256 of the DER certificate.
+	Fingerprint string `protobuf:"bytes,2,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	// not_after is the certificate expiry as a Unix timestamp.
+	NotAfter int64 `protobuf:"varint,3,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
+	// issuer is the issuing CA's distinguished name.
+	Issuer string `protobuf:"bytes,4,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	// uri_sans lists every URI SAN on the certificate, in order.
+	UriSans       []string `protobuf:"bytes,5,rep,name=uri_sans,json=uriSans,proto3" json:"uri_sans,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CertInfo) Reset() {
+	*x = CertInfo{}
+	mi := &file_proto_audit_v1_audit_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertInfo) ProtoMessage() {}
+
+func (x *CertInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_audit_v1_audit_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CertInfo.ProtoReflect.Descriptor instead.
+func (*CertInfo) Descriptor() ([]byte, []int) {
+	return file_proto_audit_v1_audit_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CertInfo) GetSerial() string {
+	if x != nil {
+		return x.Serial
+	}
+	return ""
+}
+
+func (x *CertInfo) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *CertInfo) GetNotAfter() int64 {
+	if x != nil {
+		return x.NotAfter
+	}
+	return 0
+}
+
+func (x *CertInfo) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+func (x *CertInfo) GetUriSans() []string {
+	if x != nil {
+		return x.UriSans
+	}
+	return nil
+}
+
+var File_proto_audit_v1_audit_proto protoreflect.FileDescriptor
+
+const file_proto_audit_v1_audit_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproto/audit/v1/audit.proto\x12\baudit.v1\"\x82\x06\n" +
+	"\rExchangeEvent\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\x12\x18\n" +
+	"\asubject\x18\x04 \x01(\tR\asubject\x12\x16\n" +
+	"\x06target\x18\x05 \x01(\tR\x06target\x12)\n" +
+	"\x10scopes_requested\x18\x06 \x03(\tR\x0fscopesRequested\x12%\n" +
+	"\x0escopes_granted\x18\a \x03(\tR\rscopesGranted\x12\x18\n" +
+	"\agranted\x18\b \x01(\bR\agranted\x12\x1f\n" +
+	"\vttl_seconds\x18\t \x01(\x05R\n" +
+	"ttlSeconds\x12\x19\n" +
+	"\btoken_id\x18\n" +
+	" \x01(\tR\atokenId\x12#\n" +
+	"\rdenial_reason\x18\v \x01(\tR\fdenialReason\x12!\n" +
+	"\fpolicy_rules\x18\f \x03(\tR\vpolicyRules\x12\x1f\n" +
+	"\vauth_method\x18\r \x01(\tR\n" +
+	"authMethod\x12&\n" +
+	"\x04cert\x18\x0e \x01(\v2\x12.audit.v1.CertInfoR\x04cert\x12-\n" +
+	"\x12suppressed_denials\x18\x0f \x01(\x05R\x11suppressedDenials\x12\x1c\n" +
+	"\tpreflight\x18\x10 \x01(\bR\tpreflight\x12(\n" +
+	"\x10ttl_clamped_from\x18\x11 \x01(\x05R\x0ettlClampedFrom\x12\x19\n" +
+	"\btrace_id\x18\x12 \x01(\tR\atraceId\x12\x1a\n" +
+	"\bapprover\x18\x13 \x01(\tR\bapprover\x12A\n" +
+	"\bmetadata\x18\x14 \x03(\v2%.audit.v1.ExchangeEvent.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x94\x01\n" +
+	"\bCertInfo\x12\x16\n" +
+	"\x06serial\x18\x01 \x01(\tR\x06serial\x12 \n" +
+	"\vfingerprint\x18\x02 \x01(\tR\vfingerprint\x12\x1b\n" +
+	"\tnot_after\x18\x03 \x01(\x03R\bnotAfter\x12\x16\n" +
+	"\x06issuer\x18\x04 \x01(\tR\x06issuer\x12\x19\n" +
+	"\buri_sans\x18\x05 \x03(\tR\auriSansB<Z:github.com/ngaddam369/svid-exchange/proto/audit/v1;auditv1b\x06proto3"
+
+var (
+	file_proto_audit_v1_audit_proto_rawDescOnce sync.Once
+	file_proto_audit_v1_audit_proto_rawDescData []byte
+)
+
+func file_proto_audit_v1_audit_proto_rawDescGZIP() []byte {
+	file_proto_audit_v1_audit_proto_rawDescOnce.Do(func() {
+		file_proto_audit_v1_audit_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_audit_v1_audit_proto_rawDesc), len(file_proto_audit_v1_audit_proto_rawDesc)))
+	})
+	return file_proto_audit_v1_audit_proto_rawDescData
+}
+
+var file_proto_audit_v1_audit_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_audit_v1_audit_proto_goTypes = []any{
+	(*ExchangeEvent)(nil), // 0: audit.v1.ExchangeEvent
+	(*CertInfo)(nil),      // 1: audit.v1.CertInfo
+	nil,                   // 2: audit.v1.ExchangeEvent.MetadataEntry
+}
+var file_proto_audit_v1_audit_proto_depIdxs = []int32{
+	1, // 0: audit.v1.ExchangeEvent.cert:type_name -> audit.v1.CertInfo
+	2, // 1: audit.v1.ExchangeEvent.metadata:type_name -> audit.v1.ExchangeEvent.MetadataEntry
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_proto_audit_v1_audit_proto_init() }
+func file_proto_audit_v1_audit_proto_init() {
+	if File_proto_audit_v1_audit_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_audit_v1_audit_proto_rawDesc), len(file_proto_audit_v1_audit_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_audit_v1_audit_proto_goTypes,
+		DependencyIndexes: file_proto_audit_v1_audit_proto_depIdxs,
+		MessageInfos:      file_proto_audit_v1_audit_proto_msgTypes,
+	}.Build()
+	File_proto_audit_v1_audit_proto = out.File
+	file_proto_audit_v1_audit_proto_goTypes = nil
+	file_proto_audit_v1_audit_proto_depIdxs = nil
+}
@@ -0,0 +1,266 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: proto/authorizer/v1/authorizer.proto
+
+package authorizerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// AuthorizeRequest describes an authenticated caller's exchange request.
+type AuthorizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// subject is the caller's authenticated SPIFFE ID.
+	Subject string `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	// target is the SPIFFE ID of the service the token is for.
+	Target string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	// scopes are the scopes the caller requested.
+	Scopes []string `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// ttl_seconds is the TTL the caller requested. Zero means the caller
+	// accepts whatever TTL the authorizer grants.
+	TtlSeconds    int32 `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizeRequest) Reset() {
+	*x = AuthorizeRequest{}
+	mi := &file_proto_authorizer_v1_authorizer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizeRequest) ProtoMessage() {}
+
+func (x *AuthorizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorizer_v1_authorizer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizeRequest.ProtoReflect.Descriptor instead.
+func (*AuthorizeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorizer_v1_authorizer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuthorizeRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *AuthorizeRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *AuthorizeRequest) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *AuthorizeRequest) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+// AuthorizeResponse is the authorizer's decision.
+type AuthorizeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// allowed grants the exchange. The remaining fields are ignored when it is
+	// false.
+	Allowed bool `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	// granted_scopes must be a non-empty subset of the requested scopes.
+	GrantedScopes []string `protobuf:"bytes,2,rep,name=granted_scopes,json=grantedScopes,proto3" json:"granted_scopes,omitempty"`
+	// ttl_seconds is the granted TTL. It must be positive and, when the
+	// caller requested a TTL, no greater than it.
+	TtlSeconds int32 `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	// token_format selects the token encoding, as a policy's token_format
+	// does. Empty means "jwt".
+	TokenFormat string `protobuf:"bytes,4,opt,name=token_format,json=tokenFormat,proto3" json:"token_format,omitempty"`
+	// rules name what authorized the grant. They are reported to the caller
+	// and recorded in the audit log as policy rules.
+	Rules []string `protobuf:"bytes,5,rep,name=rules,proto3" json:"rules,omitempty"`
+	// require_nonce refuses the grant unless the request carries an unused
+	// nonce, as a policy's require_nonce does.
+	RequireNonce  bool `protobuf:"varint,6,opt,name=require_nonce,json=requireNonce,proto3" json:"require_nonce,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizeResponse) Reset() {
+	*x = AuthorizeResponse{}
+	mi := &file_proto_authorizer_v1_authorizer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizeResponse) ProtoMessage() {}
+
+func (x *AuthorizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorizer_v1_authorizer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizeResponse.ProtoReflect.Descriptor instead.
+func (*AuthorizeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorizer_v1_authorizer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AuthorizeResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *AuthorizeResponse) GetGrantedScopes() []string {
+	if x != nil {
+		return x.GrantedScopes
+	}
+	return nil
+}
+
+func (x *AuthorizeResponse) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *AuthorizeResponse) GetTokenFormat() string {
+	if x != nil {
+		return x.TokenFormat
+	}
+	return ""
+}
+
+func (x *AuthorizeResponse) GetRules() []string {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *AuthorizeResponse) GetRequireNonce() bool {
+	if x != nil {
+		return x.RequireNonce
+	}
+	return false
+}
+
+var File_proto_authorizer_v1_authorizer_proto protoreflect.FileDescriptor
+
+const file_proto_authorizer_v1_authorizer_proto_rawDesc = "" +
+	"\n" +
+	"$proto/authorizer/v1/authorizer.proto\x12\rauthorizer.v1\"}\n" +
+	"\x10AuthorizeRequest\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\x12\x1f\n" +
+	"\vttl_seconds\x18\x04 \x01(\x05R\n" +
+	"ttlSeconds\"\xd3\x01\n" +
+	"\x11AuthorizeResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12%\n" +
+	"\x0egranted_scopes\x18\x02 \x03(\tR\rgrantedScopes\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x05R\n" +
+	"ttlSeconds\x12!\n" +
+	"\ftoken_format\x18\x04 \x01(\tR\vtokenFormat\x12\x14\n" +
+	"\x05rules\x18\x05 \x03(\tR\x05rules\x12#\n" +
+	"\rrequire_nonce\x18\x06 \x01(\bR\frequireNonce2\\\n" +
+	"\n" +
+	"Authorizer\x12N\n" +
+	"\tAuthorize\x12\x1f.authorizer.v1.AuthorizeRequest\x1a .authorizer.v1.AuthorizeResponseBFZDgithub.com/ngaddam369/svid-exchange/proto/authorizer/v1;authorizerv1b\x06proto3"
+
+var (
+	file_proto_authorizer_v1_authorizer_proto_rawDescOnce sync.Once
+	file_proto_authorizer_v1_authorizer_proto_rawDescData []byte
+)
+
+func file_proto_authorizer_v1_authorizer_proto_rawDescGZIP() []byte {
+	file_proto_authorizer_v1_authorizer_proto_rawDescOnce.Do(func() {
+		file_proto_authorizer_v1_authorizer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_authorizer_v1_authorizer_proto_rawDesc), len(file_proto_authorizer_v1_authorizer_proto_rawDesc)))
+	})
+	return file_proto_authorizer_v1_authorizer_proto_rawDescData
+}
+
+var file_proto_authorizer_v1_authorizer_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_authorizer_v1_authorizer_proto_goTypes = []any{
+	(*AuthorizeRequest)(nil),  // 0: authorizer.v1.AuthorizeRequest
+	(*AuthorizeResponse)(nil), // 1: authorizer.v1.AuthorizeResponse
+}
+var file_proto_authorizer_v1_authorizer_proto_depIdxs = []int32{
+	0, // 0: authorizer.v1.Authorizer.Authorize:input_type -> authorizer.v1.AuthorizeRequest
+	1, // 1: authorizer.v1.Authorizer.Authorize:output_type -> authorizer.v1.AuthorizeResponse
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_authorizer_v1_authorizer_proto_init() }
+func file_proto_authorizer_v1_authorizer_proto_init() {
+	if File_proto_authorizer_v1_authorizer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_authorizer_v1_authorizer_proto_rawDesc), len(file_proto_authorizer_v1_authorizer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_authorizer_v1_authorizer_proto_goTypes,
+		DependencyIndexes: file_proto_authorizer_v1_authorizer_proto_depIdxs,
+		MessageInfos:      file_proto_authorizer_v1_authorizer_proto_msgTypes,
+	}.Build()
+	File_proto_authorizer_v1_authorizer_proto = out.File
+	file_proto_authorizer_v1_authorizer_proto_goTypes = nil
+	file_proto_authorizer_v1_authorizer_proto_depIdxs = nil
+}
@@ -0,0 +1,133 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             v5.28.3
+// source: proto/authorizer/v1/authorizer.proto
+
+package authorizerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Authorizer_Authorize_FullMethodName = "/authorizer.v1.Authorizer/Authorize"
+)
+
+// AuthorizerClient is the client API for Authorizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Authorizer is the contract svid-exchange expects of an external
+// authorization service configured with external_authorizer_url. svid-exchange
+// is the client: it calls Authorize once per exchange that reaches policy
+// evaluation, in place of its own policy. Over HTTP the same messages are
+// POSTed as JSON with proto field names.
+type AuthorizerClient interface {
+	Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error)
+}
+
+type authorizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthorizerClient(cc grpc.ClientConnInterface) AuthorizerClient {
+	return &authorizerClient{cc}
+}
+
+func (c *authorizerClient) Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthorizeResponse)
+	err := c.cc.Invoke(ctx, Authorizer_Authorize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizerServer is the server API for Authorizer service.
+// All implementations must embed UnimplementedAuthorizerServer
+// for forward compatibility.
+//
+// Authorizer is the contract svid-exchange expects of an external
+// authorization service configured with external_authorizer_url. svid-exchange
+// is the client: it calls Authorize once per exchange that reaches policy
+// evaluation, in place of its own policy. Over HTTP the same messages are
+// POSTed as JSON with proto field names.
+type AuthorizerServer interface {
+	Authorize(context.Context, *AuthorizeRequest) (*AuthorizeResponse, error)
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+// UnimplementedAuthorizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAuthorizerServer struct{}
+
+func (UnimplementedAuthorizerServer) Authorize(context.Context, *AuthorizeRequest) (*AuthorizeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Authorize not implemented")
+}
+func (UnimplementedAuthorizerServer) mustEmbedUnimplementedAuthorizerServer() {}
+func (UnimplementedAuthorizerServer) testEmbeddedByValue()                    {}
+
+// UnsafeAuthorizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthorizerServer will
+// result in compilation errors.
+type UnsafeAuthorizerServer interface {
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+func RegisterAuthorizerServer(s grpc.ServiceRegistrar, srv AuthorizerServer) {
+	// If the following call panics, it indicates UnimplementedAuthorizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Authorizer_ServiceDesc, srv)
+}
+
+func _Authorizer_Authorize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Authorize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authorizer_Authorize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Authorize(ctx, req.(*AuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Authorizer_ServiceDesc is the grpc.ServiceDesc for Authorizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Authorizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorizer.v1.Authorizer",
+	HandlerType: (*AuthorizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authorize",
+			Handler:    _Authorizer_Authorize_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/authorizer/v1/authorizer.proto",
+}
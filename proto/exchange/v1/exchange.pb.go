@@ -35,7 +35,18 @@ type ExchangeRequest struct {
 	// on_behalf_of is an optional JWT identifying the principal this service is
 	// acting for. When set, the resulting token carries an act.sub claim
 	// (RFC 8693) containing the subject extracted from this JWT.
-	OnBehalfOf    string `protobuf:"bytes,4,opt,name=on_behalf_of,json=onBehalfOf,proto3" json:"on_behalf_of,omitempty"`
+	OnBehalfOf string `protobuf:"bytes,4,opt,name=on_behalf_of,json=onBehalfOf,proto3" json:"on_behalf_of,omitempty"`
+	// nonce is an optional client-generated value, 16 to 128 characters of
+	// [A-Za-z0-9_-], that the server accepts once per caller within its nonce
+	// window. A request that reuses a nonce is rejected, so a captured request
+	// cannot be replayed. Policies with require_nonce reject requests without
+	// one.
+	Nonce string `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// use_until is an optional Unix timestamp after which the caller no longer
+	// needs the token, such as a batch job's deadline. The granted TTL is
+	// capped so the token expires by then, rounded up to a whole second; it
+	// must be in the future.
+	UseUntil      int64 `protobuf:"varint,6,opt,name=use_until,json=useUntil,proto3" json:"use_until,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -98,6 +109,20 @@ func (x *ExchangeRequest) GetOnBehalfOf() string {
 	return ""
 }
 
+func (x *ExchangeRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *ExchangeRequest) GetUseUntil() int64 {
+	if x != nil {
+		return x.UseUntil
+	}
+	return 0
+}
+
 type ExchangeResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// token is the signed ES256 JWT.
@@ -174,14 +199,16 @@ var File_proto_exchange_v1_exchange_proto protoreflect.FileDescriptor
 
 const file_proto_exchange_v1_exchange_proto_rawDesc = "" +
 	"\n" +
-	" proto/exchange/v1/exchange.proto\x12\vexchange.v1\"\x93\x01\n" +
+	" proto/exchange/v1/exchange.proto\x12\vexchange.v1\"\xc6\x01\n" +
 	"\x0fExchangeRequest\x12%\n" +
 	"\x0etarget_service\x18\x01 \x01(\tR\rtargetService\x12\x16\n" +
 	"\x06scopes\x18\x02 \x03(\tR\x06scopes\x12\x1f\n" +
 	"\vttl_seconds\x18\x03 \x01(\x05R\n" +
 	"ttlSeconds\x12 \n" +
 	"\fon_behalf_of\x18\x04 \x01(\tR\n" +
-	"onBehalfOf\"\x89\x01\n" +
+	"onBehalfOf\x12\x14\n" +
+	"\x05nonce\x18\x05 \x01(\tR\x05nonce\x12\x1b\n" +
+	"\tuse_until\x18\x06 \x01(\x03R\buseUntil\"\x89\x01\n" +
 	"\x10ExchangeResponse\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
 	"\n" +
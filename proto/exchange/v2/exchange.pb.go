@@ -0,0 +1,1081 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: proto/exchange/v2/exchange.proto
+
+package exchangev2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ResponseView selects the fields of an ExchangeResponse.
+type ResponseView int32
+
+const (
+	// RESPONSE_VIEW_UNSPECIFIED is treated as RESPONSE_VIEW_FULL.
+	ResponseView_RESPONSE_VIEW_UNSPECIFIED ResponseView = 0
+	// RESPONSE_VIEW_FULL populates every field.
+	ResponseView_RESPONSE_VIEW_FULL ResponseView = 1
+	// RESPONSE_VIEW_TOKEN populates only token and expires_at.
+	ResponseView_RESPONSE_VIEW_TOKEN ResponseView = 2
+	// RESPONSE_VIEW_PREFLIGHT evaluates policy without minting a token and
+	// populates every field except token, expires_at, and token_id. A denial
+	// fails the call exactly as it would for a real exchange, so a preflight
+	// answers "would this exchange be granted?" Token quotas are not checked,
+	// since no token is issued.
+	ResponseView_RESPONSE_VIEW_PREFLIGHT ResponseView = 3
+)
+
+// Enum value maps for ResponseView.
+var (
+	ResponseView_name = map[int32]string{
+		0: "RESPONSE_VIEW_UNSPECIFIED",
+		1: "RESPONSE_VIEW_FULL",
+		2: "RESPONSE_VIEW_TOKEN",
+		3: "RESPONSE_VIEW_PREFLIGHT",
+	}
+	ResponseView_value = map[string]int32{
+		"RESPONSE_VIEW_UNSPECIFIED": 0,
+		"RESPONSE_VIEW_FULL":        1,
+		"RESPONSE_VIEW_TOKEN":       2,
+		"RESPONSE_VIEW_PREFLIGHT":   3,
+	}
+)
+
+func (x ResponseView) Enum() *ResponseView {
+	p := new(ResponseView)
+	*p = x
+	return p
+}
+
+func (x ResponseView) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResponseView) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_exchange_v2_exchange_proto_enumTypes[0].Descriptor()
+}
+
+func (ResponseView) Type() protoreflect.EnumType {
+	return &file_proto_exchange_v2_exchange_proto_enumTypes[0]
+}
+
+func (x ResponseView) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResponseView.Descriptor instead.
+func (ResponseView) EnumDescriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+// ExchangeRequest carries what the caller wants — NOT who the caller is.
+// The caller's identity comes from its authenticated credential and cannot
+// be forged via the request body.
+type ExchangeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// target_service is the SPIFFE ID of the service being called.
+	TargetService string `protobuf:"bytes,1,opt,name=target_service,json=targetService,proto3" json:"target_service,omitempty"`
+	// scopes are the permission scopes requested for this token.
+	Scopes []string `protobuf:"bytes,2,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// ttl_seconds is the requested TTL; capped by the policy max_ttl. Zero
+	// requests the policy max_ttl.
+	TtlSeconds int32 `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	// audiences are the aud values the token must carry. Empty means
+	// target_service alone. Audiences other than target_service are rejected
+	// with UNIMPLEMENTED until the server can issue them.
+	Audiences []string `protobuf:"bytes,4,rep,name=audiences,proto3" json:"audiences,omitempty"`
+	// claim_hints are advisory values for claims the caller would like in the
+	// token, keyed by claim name. The server may ignore any or all of them,
+	// and never lets a hint override a claim it sets itself.
+	ClaimHints map[string]string `protobuf:"bytes,5,rep,name=claim_hints,json=claimHints,proto3" json:"claim_hints,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// proof_of_possession binds the token to a key the caller holds. Binding
+	// is rejected with UNIMPLEMENTED until the server can issue bound tokens,
+	// rather than returning an unbound token the caller believes is bound.
+	ProofOfPossession *ProofOfPossession `protobuf:"bytes,6,opt,name=proof_of_possession,json=proofOfPossession,proto3" json:"proof_of_possession,omitempty"`
+	// delegation identifies the principal this service is acting for. It
+	// replaces v1's on_behalf_of.
+	Delegation *Delegation `protobuf:"bytes,7,opt,name=delegation,proto3" json:"delegation,omitempty"`
+	// request_id correlates the call with the caller's own logs. It takes
+	// precedence over x-request-id metadata; the server generates one when
+	// neither is set or the value is malformed.
+	RequestId string `protobuf:"bytes,8,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// view selects which response fields are populated. The default is
+	// RESPONSE_VIEW_FULL.
+	View ResponseView `protobuf:"varint,9,opt,name=view,proto3,enum=exchange.v2.ResponseView" json:"view,omitempty"`
+	// include_receipt asks for a decision receipt in the response. It fails
+	// with FAILED_PRECONDITION unless the server signs receipts, and with
+	// INVALID_ARGUMENT for a preflight, which issues no token to describe.
+	IncludeReceipt bool `protobuf:"varint,10,opt,name=include_receipt,json=includeReceipt,proto3" json:"include_receipt,omitempty"`
+	// nonce is an optional client-generated value, 16 to 128 characters of
+	// [A-Za-z0-9_-], that the server accepts once per caller within its nonce
+	// window. A request that reuses a nonce is rejected, so a captured request
+	// cannot be replayed. Policies with require_nonce reject requests without
+	// one. A preflight checks that a required nonce is present but does not
+	// use it.
+	Nonce string `protobuf:"bytes,11,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// use_until is an optional Unix timestamp after which the caller no longer
+	// needs the token, such as a batch job's deadline. The granted TTL is
+	// capped so the token expires by then, rounded up to a whole second; it
+	// must be in the future.
+	UseUntil      int64 `protobuf:"varint,12,opt,name=use_until,json=useUntil,proto3" json:"use_until,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExchangeRequest) Reset() {
+	*x = ExchangeRequest{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExchangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExchangeRequest) ProtoMessage() {}
+
+func (x *ExchangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExchangeRequest.ProtoReflect.Descriptor instead.
+func (*ExchangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExchangeRequest) GetTargetService() string {
+	if x != nil {
+		return x.TargetService
+	}
+	return ""
+}
+
+func (x *ExchangeRequest) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *ExchangeRequest) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *ExchangeRequest) GetAudiences() []string {
+	if x != nil {
+		return x.Audiences
+	}
+	return nil
+}
+
+func (x *ExchangeRequest) GetClaimHints() map[string]string {
+	if x != nil {
+		return x.ClaimHints
+	}
+	return nil
+}
+
+func (x *ExchangeRequest) GetProofOfPossession() *ProofOfPossession {
+	if x != nil {
+		return x.ProofOfPossession
+	}
+	return nil
+}
+
+func (x *ExchangeRequest) GetDelegation() *Delegation {
+	if x != nil {
+		return x.Delegation
+	}
+	return nil
+}
+
+func (x *ExchangeRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExchangeRequest) GetView() ResponseView {
+	if x != nil {
+		return x.View
+	}
+	return ResponseView_RESPONSE_VIEW_UNSPECIFIED
+}
+
+func (x *ExchangeRequest) GetIncludeReceipt() bool {
+	if x != nil {
+		return x.IncludeReceipt
+	}
+	return false
+}
+
+func (x *ExchangeRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *ExchangeRequest) GetUseUntil() int64 {
+	if x != nil {
+		return x.UseUntil
+	}
+	return 0
+}
+
+// ProofOfPossession names the key a token is bound to (RFC 7800 cnf).
+type ProofOfPossession struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*ProofOfPossession_JwkThumbprint
+	//	*ProofOfPossession_X509Thumbprint
+	Key           isProofOfPossession_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProofOfPossession) Reset() {
+	*x = ProofOfPossession{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProofOfPossession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProofOfPossession) ProtoMessage() {}
+
+func (x *ProofOfPossession) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProofOfPossession.ProtoReflect.Descriptor instead.
+func (*ProofOfPossession) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ProofOfPossession) GetKey() isProofOfPossession_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *ProofOfPossession) GetJwkThumbprint() string {
+	if x != nil {
+		if x, ok := x.Key.(*ProofOfPossession_JwkThumbprint); ok {
+			return x.JwkThumbprint
+		}
+	}
+	return ""
+}
+
+func (x *ProofOfPossession) GetX509Thumbprint() string {
+	if x != nil {
+		if x, ok := x.Key.(*ProofOfPossession_X509Thumbprint); ok {
+			return x.X509Thumbprint
+		}
+	}
+	return ""
+}
+
+type isProofOfPossession_Key interface {
+	isProofOfPossession_Key()
+}
+
+type ProofOfPossession_JwkThumbprint struct {
+	// jwk_thumbprint is the base64url RFC 7638 SHA-256 thumbprint of the
+	// caller's public JWK (cnf.jkt).
+	JwkThumbprint string `protobuf:"bytes,1,opt,name=jwk_thumbprint,json=jwkThumbprint,proto3,oneof"`
+}
+
+type ProofOfPossession_X509Thumbprint struct {
+	// x509_thumbprint is the base64url SHA-256 thumbprint of the caller's
+	// DER certificate (cnf.x5t#S256).
+	X509Thumbprint string `protobuf:"bytes,2,opt,name=x509_thumbprint,json=x509Thumbprint,proto3,oneof"`
+}
+
+func (*ProofOfPossession_JwkThumbprint) isProofOfPossession_Key() {}
+
+func (*ProofOfPossession_X509Thumbprint) isProofOfPossession_Key() {}
+
+// Delegation carries the token of the principal a service acts for.
+type Delegation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token is a JWT issued by this server. The resulting token carries an
+	// act.sub claim (RFC 8693) naming its subject.
+	Token         string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Delegation) Reset() {
+	*x = Delegation{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Delegation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delegation) ProtoMessage() {}
+
+func (x *Delegation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delegation.ProtoReflect.Descriptor instead.
+func (*Delegation) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Delegation) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ExchangeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token is the signed token, encoded as token_format.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// expires_at is the unix timestamp when the token expires.
+	ExpiresAt int64 `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// granted_scopes are the scopes actually granted (subset of requested).
+	GrantedScopes []string `protobuf:"bytes,3,rep,name=granted_scopes,json=grantedScopes,proto3" json:"granted_scopes,omitempty"`
+	// token_id is the token's jti claim.
+	TokenId string `protobuf:"bytes,4,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`
+	// token_format is the encoding of token: "jwt", "jwt-svid", "macaroon",
+	// or "paseto", as selected by the matching policy.
+	TokenFormat string `protobuf:"bytes,5,opt,name=token_format,json=tokenFormat,proto3" json:"token_format,omitempty"`
+	// granted_ttl_seconds is the TTL the token was issued with.
+	GrantedTtlSeconds int32 `protobuf:"varint,6,opt,name=granted_ttl_seconds,json=grantedTtlSeconds,proto3" json:"granted_ttl_seconds,omitempty"`
+	// request_id is the ID the call was audited under, also returned in the
+	// x-request-id response header.
+	RequestId string `protobuf:"bytes,7,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// policy_rules names the policy rules that authorized the grant, also
+	// returned in x-policy-rule response metadata.
+	PolicyRules []string `protobuf:"bytes,8,rep,name=policy_rules,json=policyRules,proto3" json:"policy_rules,omitempty"`
+	// receipt is a detached decision receipt: a JWS, signed with the same
+	// keys as tokens but with typ "decision-receipt+jwt", recording the token's
+	// jti and SHA-256, the scopes requested and granted, the matching rules,
+	// and the digest of the policy set that granted it. Set only when
+	// include_receipt was, in every view.
+	Receipt       string `protobuf:"bytes,9,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExchangeResponse) Reset() {
+	*x = ExchangeResponse{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExchangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExchangeResponse) ProtoMessage() {}
+
+func (x *ExchangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExchangeResponse.ProtoReflect.Descriptor instead.
+func (*ExchangeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ExchangeResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ExchangeResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ExchangeResponse) GetGrantedScopes() []string {
+	if x != nil {
+		return x.GrantedScopes
+	}
+	return nil
+}
+
+func (x *ExchangeResponse) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *ExchangeResponse) GetTokenFormat() string {
+	if x != nil {
+		return x.TokenFormat
+	}
+	return ""
+}
+
+func (x *ExchangeResponse) GetGrantedTtlSeconds() int32 {
+	if x != nil {
+		return x.GrantedTtlSeconds
+	}
+	return 0
+}
+
+func (x *ExchangeResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExchangeResponse) GetPolicyRules() []string {
+	if x != nil {
+		return x.PolicyRules
+	}
+	return nil
+}
+
+func (x *ExchangeResponse) GetReceipt() string {
+	if x != nil {
+		return x.Receipt
+	}
+	return ""
+}
+
+type WhoAmIRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WhoAmIRequest) Reset() {
+	*x = WhoAmIRequest{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WhoAmIRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WhoAmIRequest) ProtoMessage() {}
+
+func (x *WhoAmIRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WhoAmIRequest.ProtoReflect.Descriptor instead.
+func (*WhoAmIRequest) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{4}
+}
+
+type WhoAmIResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// spiffe_id is the caller's SPIFFE ID, the subject of its tokens.
+	SpiffeId string `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// auth_method names the authentication method that identified the
+	// caller, e.g. "x509-svid" or "k8s-sa-token".
+	AuthMethod string `protobuf:"bytes,2,opt,name=auth_method,json=authMethod,proto3" json:"auth_method,omitempty"`
+	// certificate describes the X.509 SVID the caller authenticated with. It
+	// is unset for token methods.
+	Certificate   *Certificate `protobuf:"bytes,3,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WhoAmIResponse) Reset() {
+	*x = WhoAmIResponse{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WhoAmIResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WhoAmIResponse) ProtoMessage() {}
+
+func (x *WhoAmIResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WhoAmIResponse.ProtoReflect.Descriptor instead.
+func (*WhoAmIResponse) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WhoAmIResponse) GetSpiffeId() string {
+	if x != nil {
+		return x.SpiffeId
+	}
+	return ""
+}
+
+func (x *WhoAmIResponse) GetAuthMethod() string {
+	if x != nil {
+		return x.AuthMethod
+	}
+	return ""
+}
+
+func (x *WhoAmIResponse) GetCertificate() *Certificate {
+	if x != nil {
+		return x.Certificate
+	}
+	return nil
+}
+
+// Certificate identifies a single certificate issuance, with the same
+// values the audit log records for it.
+type Certificate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// serial is the serial number in hex.
+	Serial string `protobuf:"bytes,1,opt,name=serial,proto3" json:"serial,omitempty"`
+	// fingerprint is the hex SHA-256 of the DER certificate.
+	Fingerprint string `protobuf:"bytes,2,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	// not_after is the unix timestamp when the certificate expires.
+	NotAfter int64 `protobuf:"varint,3,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
+	// issuer is the issuing CA's distinguished name.
+	Issuer        string `protobuf:"bytes,4,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Certificate) Reset() {
+	*x = Certificate{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Certificate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Certificate) ProtoMessage() {}
+
+func (x *Certificate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Certificate.ProtoReflect.Descriptor instead.
+func (*Certificate) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Certificate) GetSerial() string {
+	if x != nil {
+		return x.Serial
+	}
+	return ""
+}
+
+func (x *Certificate) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Certificate) GetNotAfter() int64 {
+	if x != nil {
+		return x.NotAfter
+	}
+	return 0
+}
+
+func (x *Certificate) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+type ListGrantsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGrantsRequest) Reset() {
+	*x = ListGrantsRequest{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGrantsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGrantsRequest) ProtoMessage() {}
+
+func (x *ListGrantsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGrantsRequest.ProtoReflect.Descriptor instead.
+func (*ListGrantsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{7}
+}
+
+type ListGrantsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// grants are ordered by token_id.
+	Grants        []*Grant `protobuf:"bytes,1,rep,name=grants,proto3" json:"grants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGrantsResponse) Reset() {
+	*x = ListGrantsResponse{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGrantsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGrantsResponse) ProtoMessage() {}
+
+func (x *ListGrantsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGrantsResponse.ProtoReflect.Descriptor instead.
+func (*ListGrantsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListGrantsResponse) GetGrants() []*Grant {
+	if x != nil {
+		return x.Grants
+	}
+	return nil
+}
+
+// Grant describes a token issued to the caller.
+type Grant struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token_id is the token's jti claim.
+	TokenId string `protobuf:"bytes,1,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`
+	// target_service is the SPIFFE ID the token was issued for.
+	TargetService string `protobuf:"bytes,2,opt,name=target_service,json=targetService,proto3" json:"target_service,omitempty"`
+	// scopes are the scopes the token was granted.
+	Scopes []string `protobuf:"bytes,3,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// expires_at is the unix timestamp when the token expires.
+	ExpiresAt     int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Grant) Reset() {
+	*x = Grant{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Grant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Grant) ProtoMessage() {}
+
+func (x *Grant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Grant.ProtoReflect.Descriptor instead.
+func (*Grant) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Grant) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *Grant) GetTargetService() string {
+	if x != nil {
+		return x.TargetService
+	}
+	return ""
+}
+
+func (x *Grant) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *Grant) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type RevokeGrantRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token_id is the jti of the token to revoke.
+	TokenId       string `protobuf:"bytes,1,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeGrantRequest) Reset() {
+	*x = RevokeGrantRequest{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeGrantRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeGrantRequest) ProtoMessage() {}
+
+func (x *RevokeGrantRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeGrantRequest.ProtoReflect.Descriptor instead.
+func (*RevokeGrantRequest) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RevokeGrantRequest) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+type RevokeGrantResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeGrantResponse) Reset() {
+	*x = RevokeGrantResponse{}
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeGrantResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeGrantResponse) ProtoMessage() {}
+
+func (x *RevokeGrantResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_exchange_v2_exchange_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeGrantResponse.ProtoReflect.Descriptor instead.
+func (*RevokeGrantResponse) Descriptor() ([]byte, []int) {
+	return file_proto_exchange_v2_exchange_proto_rawDescGZIP(), []int{11}
+}
+
+var File_proto_exchange_v2_exchange_proto protoreflect.FileDescriptor
+
+const file_proto_exchange_v2_exchange_proto_rawDesc = "" +
+	"\n" +
+	" proto/exchange/v2/exchange.proto\x12\vexchange.v2\"\xd0\x04\n" +
+	"\x0fExchangeRequest\x12%\n" +
+	"\x0etarget_service\x18\x01 \x01(\tR\rtargetService\x12\x16\n" +
+	"\x06scopes\x18\x02 \x03(\tR\x06scopes\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x05R\n" +
+	"ttlSeconds\x12\x1c\n" +
+	"\taudiences\x18\x04 \x03(\tR\taudiences\x12M\n" +
+	"\vclaim_hints\x18\x05 \x03(\v2,.exchange.v2.ExchangeRequest.ClaimHintsEntryR\n" +
+	"claimHints\x12N\n" +
+	"\x13proof_of_possession\x18\x06 \x01(\v2\x1e.exchange.v2.ProofOfPossessionR\x11proofOfPossession\x127\n" +
+	"\n" +
+	"delegation\x18\a \x01(\v2\x17.exchange.v2.DelegationR\n" +
+	"delegation\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\b \x01(\tR\trequestId\x12-\n" +
+	"\x04view\x18\t \x01(\x0e2\x19.exchange.v2.ResponseViewR\x04view\x12'\n" +
+	"\x0finclude_receipt\x18\n" +
+	" \x01(\bR\x0eincludeReceipt\x12\x14\n" +
+	"\x05nonce\x18\v \x01(\tR\x05nonce\x12\x1b\n" +
+	"\tuse_until\x18\f \x01(\x03R\buseUntil\x1a=\n" +
+	"\x0fClaimHintsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"n\n" +
+	"\x11ProofOfPossession\x12'\n" +
+	"\x0ejwk_thumbprint\x18\x01 \x01(\tH\x00R\rjwkThumbprint\x12)\n" +
+	"\x0fx509_thumbprint\x18\x02 \x01(\tH\x00R\x0ex509ThumbprintB\x05\n" +
+	"\x03key\"\"\n" +
+	"\n" +
+	"Delegation\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\xb8\x02\n" +
+	"\x10ExchangeResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt\x12%\n" +
+	"\x0egranted_scopes\x18\x03 \x03(\tR\rgrantedScopes\x12\x19\n" +
+	"\btoken_id\x18\x04 \x01(\tR\atokenId\x12!\n" +
+	"\ftoken_format\x18\x05 \x01(\tR\vtokenFormat\x12.\n" +
+	"\x13granted_ttl_seconds\x18\x06 \x01(\x05R\x11grantedTtlSeconds\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\a \x01(\tR\trequestId\x12!\n" +
+	"\fpolicy_rules\x18\b \x03(\tR\vpolicyRules\x12\x18\n" +
+	"\areceipt\x18\t \x01(\tR\areceipt\"\x0f\n" +
+	"\rWhoAmIRequest\"\x8a\x01\n" +
+	"\x0eWhoAmIResponse\x12\x1b\n" +
+	"\tspiffe_id\x18\x01 \x01(\tR\bspiffeId\x12\x1f\n" +
+	"\vauth_method\x18\x02 \x01(\tR\n" +
+	"authMethod\x12:\n" +
+	"\vcertificate\x18\x03 \x01(\v2\x18.exchange.v2.CertificateR\vcertificate\"|\n" +
+	"\vCertificate\x12\x16\n" +
+	"\x06serial\x18\x01 \x01(\tR\x06serial\x12 \n" +
+	"\vfingerprint\x18\x02 \x01(\tR\vfingerprint\x12\x1b\n" +
+	"\tnot_after\x18\x03 \x01(\x03R\bnotAfter\x12\x16\n" +
+	"\x06issuer\x18\x04 \x01(\tR\x06issuer\"\x13\n" +
+	"\x11ListGrantsRequest\"@\n" +
+	"\x12ListGrantsResponse\x12*\n" +
+	"\x06grants\x18\x01 \x03(\v2\x12.exchange.v2.GrantR\x06grants\"\x80\x01\n" +
+	"\x05Grant\x12\x19\n" +
+	"\btoken_id\x18\x01 \x01(\tR\atokenId\x12%\n" +
+	"\x0etarget_service\x18\x02 \x01(\tR\rtargetService\x12\x16\n" +
+	"\x06scopes\x18\x03 \x03(\tR\x06scopes\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"/\n" +
+	"\x12RevokeGrantRequest\x12\x19\n" +
+	"\btoken_id\x18\x01 \x01(\tR\atokenId\"\x15\n" +
+	"\x13RevokeGrantResponse*{\n" +
+	"\fResponseView\x12\x1d\n" +
+	"\x19RESPONSE_VIEW_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12RESPONSE_VIEW_FULL\x10\x01\x12\x17\n" +
+	"\x13RESPONSE_VIEW_TOKEN\x10\x02\x12\x1b\n" +
+	"\x17RESPONSE_VIEW_PREFLIGHT\x10\x032\xbc\x02\n" +
+	"\rTokenExchange\x12G\n" +
+	"\bExchange\x12\x1c.exchange.v2.ExchangeRequest\x1a\x1d.exchange.v2.ExchangeResponse\x12A\n" +
+	"\x06WhoAmI\x12\x1a.exchange.v2.WhoAmIRequest\x1a\x1b.exchange.v2.WhoAmIResponse\x12M\n" +
+	"\n" +
+	"ListGrants\x12\x1e.exchange.v2.ListGrantsRequest\x1a\x1f.exchange.v2.ListGrantsResponse\x12P\n" +
+	"\vRevokeGrant\x12\x1f.exchange.v2.RevokeGrantRequest\x1a .exchange.v2.RevokeGrantResponseBBZ@github.com/ngaddam369/svid-exchange/proto/exchange/v2;exchangev2b\x06proto3"
+
+var (
+	file_proto_exchange_v2_exchange_proto_rawDescOnce sync.Once
+	file_proto_exchange_v2_exchange_proto_rawDescData []byte
+)
+
+func file_proto_exchange_v2_exchange_proto_rawDescGZIP() []byte {
+	file_proto_exchange_v2_exchange_proto_rawDescOnce.Do(func() {
+		file_proto_exchange_v2_exchange_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_exchange_v2_exchange_proto_rawDesc), len(file_proto_exchange_v2_exchange_proto_rawDesc)))
+	})
+	return file_proto_exchange_v2_exchange_proto_rawDescData
+}
+
+var file_proto_exchange_v2_exchange_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_exchange_v2_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_proto_exchange_v2_exchange_proto_goTypes = []any{
+	(ResponseView)(0),           // 0: exchange.v2.ResponseView
+	(*ExchangeRequest)(nil),     // 1: exchange.v2.ExchangeRequest
+	(*ProofOfPossession)(nil),   // 2: exchange.v2.ProofOfPossession
+	(*Delegation)(nil),          // 3: exchange.v2.Delegation
+	(*ExchangeResponse)(nil),    // 4: exchange.v2.ExchangeResponse
+	(*WhoAmIRequest)(nil),       // 5: exchange.v2.WhoAmIRequest
+	(*WhoAmIResponse)(nil),      // 6: exchange.v2.WhoAmIResponse
+	(*Certificate)(nil),         // 7: exchange.v2.Certificate
+	(*ListGrantsRequest)(nil),   // 8: exchange.v2.ListGrantsRequest
+	(*ListGrantsResponse)(nil),  // 9: exchange.v2.ListGrantsResponse
+	(*Grant)(nil),               // 10: exchange.v2.Grant
+	(*RevokeGrantRequest)(nil),  // 11: exchange.v2.RevokeGrantRequest
+	(*RevokeGrantResponse)(nil), // 12: exchange.v2.RevokeGrantResponse
+	nil,                         // 13: exchange.v2.ExchangeRequest.ClaimHintsEntry
+}
+var file_proto_exchange_v2_exchange_proto_depIdxs = []int32{
+	13, // 0: exchange.v2.ExchangeRequest.claim_hints:type_name -> exchange.v2.ExchangeRequest.ClaimHintsEntry
+	2,  // 1: exchange.v2.ExchangeRequest.proof_of_possession:type_name -> exchange.v2.ProofOfPossession
+	3,  // 2: exchange.v2.ExchangeRequest.delegation:type_name -> exchange.v2.Delegation
+	0,  // 3: exchange.v2.ExchangeRequest.view:type_name -> exchange.v2.ResponseView
+	7,  // 4: exchange.v2.WhoAmIResponse.certificate:type_name -> exchange.v2.Certificate
+	10, // 5: exchange.v2.ListGrantsResponse.grants:type_name -> exchange.v2.Grant
+	1,  // 6: exchange.v2.TokenExchange.Exchange:input_type -> exchange.v2.ExchangeRequest
+	5,  // 7: exchange.v2.TokenExchange.WhoAmI:input_type -> exchange.v2.WhoAmIRequest
+	8,  // 8: exchange.v2.TokenExchange.ListGrants:input_type -> exchange.v2.ListGrantsRequest
+	11, // 9: exchange.v2.TokenExchange.RevokeGrant:input_type -> exchange.v2.RevokeGrantRequest
+	4,  // 10: exchange.v2.TokenExchange.Exchange:output_type -> exchange.v2.ExchangeResponse
+	6,  // 11: exchange.v2.TokenExchange.WhoAmI:output_type -> exchange.v2.WhoAmIResponse
+	9,  // 12: exchange.v2.TokenExchange.ListGrants:output_type -> exchange.v2.ListGrantsResponse
+	12, // 13: exchange.v2.TokenExchange.RevokeGrant:output_type -> exchange.v2.RevokeGrantResponse
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_proto_exchange_v2_exchange_proto_init() }
+func file_proto_exchange_v2_exchange_proto_init() {
+	if File_proto_exchange_v2_exchange_proto != nil {
+		return
+	}
+	file_proto_exchange_v2_exchange_proto_msgTypes[1].OneofWrappers = []any{
+		(*ProofOfPossession_JwkThumbprint)(nil),
+		(*ProofOfPossession_X509Thumbprint)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_exchange_v2_exchange_proto_rawDesc), len(file_proto_exchange_v2_exchange_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_exchange_v2_exchange_proto_goTypes,
+		DependencyIndexes: file_proto_exchange_v2_exchange_proto_depIdxs,
+		EnumInfos:         file_proto_exchange_v2_exchange_proto_enumTypes,
+		MessageInfos:      file_proto_exchange_v2_exchange_proto_msgTypes,
+	}.Build()
+	File_proto_exchange_v2_exchange_proto = out.File
+	file_proto_exchange_v2_exchange_proto_goTypes = nil
+	file_proto_exchange_v2_exchange_proto_depIdxs = nil
+}
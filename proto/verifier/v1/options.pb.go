@@ -0,0 +1,90 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: proto/verifier/v1/options.proto
+
+package verifierv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_proto_verifier_v1_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50601,
+		Name:          "verifier.v1.required_scopes",
+		Tag:           "bytes,50601,rep,name=required_scopes",
+		Filename:      "proto/verifier/v1/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// required_scopes lists the scopes a caller's svid-exchange token must
+	// carry to invoke the annotated RPC. Enforced by the pkg/verifier gRPC
+	// interceptors when configured with verifier.ScopesFromOptions.
+	//
+	//   rpc Charge(ChargeRequest) returns (ChargeResponse) {
+	//     option (verifier.v1.required_scopes) = "payments:charge";
+	//   }
+	//
+	// repeated string required_scopes = 50601;
+	E_RequiredScopes = &file_proto_verifier_v1_options_proto_extTypes[0]
+)
+
+var File_proto_verifier_v1_options_proto protoreflect.FileDescriptor
+
+const file_proto_verifier_v1_options_proto_rawDesc = "" +
+	"\n" +
+	"\x1fproto/verifier/v1/options.proto\x12\vverifier.v1\x1a google/protobuf/descriptor.proto:I\n" +
+	"\x0frequired_scopes\x12\x1e.google.protobuf.MethodOptions\x18\xa9\x8b\x03 \x03(\tR\x0erequiredScopesBBZ@github.com/ngaddam369/svid-exchange/proto/verifier/v1;verifierv1b\x06proto3"
+
+var file_proto_verifier_v1_options_proto_goTypes = []any{
+	(*descriptorpb.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
+}
+var file_proto_verifier_v1_options_proto_depIdxs = []int32{
+	0, // 0: verifier.v1.required_scopes:extendee -> google.protobuf.MethodOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_verifier_v1_options_proto_init() }
+func file_proto_verifier_v1_options_proto_init() {
+	if File_proto_verifier_v1_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_verifier_v1_options_proto_rawDesc), len(file_proto_verifier_v1_options_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_verifier_v1_options_proto_goTypes,
+		DependencyIndexes: file_proto_verifier_v1_options_proto_depIdxs,
+		ExtensionInfos:    file_proto_verifier_v1_options_proto_extTypes,
+	}.Build()
+	File_proto_verifier_v1_options_proto = out.File
+	file_proto_verifier_v1_options_proto_goTypes = nil
+	file_proto_verifier_v1_options_proto_depIdxs = nil
+}